@@ -0,0 +1,124 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/component/s3storage"
+	"github.com/spf13/cobra"
+)
+
+type cleanupUploadsOptions struct {
+	olderThan time.Duration
+	dryRun    bool
+}
+
+var cleanupUploadsOpts cleanupUploadsOptions
+
+var cleanupUploadsCmd = &cobra.Command{
+	Use:   "cleanup-uploads",
+	Short: "Abort stale multipart uploads left in the configured S3 bucket",
+	Long: "Reads the s3storage settings from the provided config file, and aborts the incomplete multipart uploads under the configured subdirectory that were started before --older-than.\n" +
+		"Cloudfuse resumes interrupted uploads the next time it uploads the same file, so uploads of files that will not be uploaded again stay in the bucket, and are billed, until they are aborted.",
+	Args: cobra.NoArgs,
+	Example: `  # Abort the uploads started more than a day ago
+  cloudfuse cleanup-uploads --config-file=config.yaml
+
+  # List the uploads started more than a week ago, without aborting them
+  cloudfuse cleanup-uploads --config-file=config.yaml --older-than=168h --dry-run`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cleanupUploadsOpts.olderThan < 0 {
+			return fmt.Errorf("--older-than must not be negative")
+		}
+		if options.ConfigFile == "" {
+			_, err := os.Stat(common.DefaultConfigFilePath)
+			if err != nil && os.IsNotExist(err) {
+				return fmt.Errorf("config file not provided")
+			}
+			options.ConfigFile = common.DefaultConfigFilePath
+		}
+		if err := parseConfig(); err != nil {
+			return err
+		}
+
+		// Silence logging so it does not print in console
+		_ = log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+
+		comp := s3storage.News3storageComponent()
+		if err := comp.Configure(true); err != nil {
+			return fmt.Errorf("s3storage configure failed: %w", err)
+		}
+		if err := comp.Start(context.Background()); err != nil {
+			return fmt.Errorf("s3storage start failed: %w", err)
+		}
+		defer func() { _ = comp.Stop() }()
+
+		s3c, ok := comp.(*s3storage.S3Storage)
+		if !ok {
+			return fmt.Errorf("unexpected s3storage component type")
+		}
+		client, ok := s3c.Storage.(*s3storage.Client)
+		if !ok {
+			return fmt.Errorf("unexpected s3storage client type")
+		}
+
+		keys, err := client.AbortStaleUploads(
+			context.Background(),
+			time.Now().Add(-cleanupUploadsOpts.olderThan),
+			cleanupUploadsOpts.dryRun,
+		)
+		action := "Aborted"
+		if cleanupUploadsOpts.dryRun {
+			action = "Found"
+		}
+		for _, key := range keys {
+			cmd.Printf("%s upload of %s\n", action, key)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to clean up uploads: %w", err)
+		}
+		cmd.Printf("%s %d stale uploads.\n", action, len(keys))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cleanupUploadsCmd)
+	cleanupUploadsCmd.Flags().
+		StringVar(&options.ConfigFile, "config-file", "", "Path to cloudfuse config file (default: ./config.yaml)")
+	cleanupUploadsCmd.Flags().StringVar(&options.PassPhrase, "passphrase", "",
+		"Base64 encoded key to decrypt config file. Can also be specified by env-variable CLOUDFUSE_SECURE_CONFIG_PASSPHRASE.\n Decoded key length shall be 16 (AES-128), 24 (AES-192), or 32 (AES-256) bytes in length.")
+	cleanupUploadsCmd.Flags().DurationVar(&cleanupUploadsOpts.olderThan, "older-than", 24*time.Hour,
+		"Only abort uploads started at least this long ago, so uploads in progress are left alone.")
+	cleanupUploadsCmd.Flags().BoolVar(&cleanupUploadsOpts.dryRun, "dry-run", false,
+		"List the stale uploads without aborting them.")
+}
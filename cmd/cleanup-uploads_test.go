@@ -0,0 +1,103 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type cleanupUploadsCmdSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	wd     string
+}
+
+func (suite *cleanupUploadsCmdSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	// Silence logs
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic(fmt.Sprintf("Unable to set silent logger as default: %v", err))
+	}
+	wd, _ := os.Getwd()
+	suite.wd = wd
+}
+
+func (suite *cleanupUploadsCmdSuite) cleanupTest() {
+	defer resetCLIFlags(*cleanupUploadsCmd)
+	_ = os.Chdir(suite.wd)
+}
+
+func (suite *cleanupUploadsCmdSuite) TestCleanupMissingConfigFile() {
+	defer suite.cleanupTest()
+
+	td := os.TempDir() + string(os.PathSeparator) + "cloudfuse_cleanup_" + randomString(6)
+	_ = os.MkdirAll(td, 0755)
+	defer os.RemoveAll(td)
+	_ = os.Chdir(td)
+
+	out, err := executeCommandC(rootCmd, "cleanup-uploads")
+	suite.assert.Error(err)
+	suite.assert.Contains(out, "config file not provided")
+}
+
+func (suite *cleanupUploadsCmdSuite) TestCleanupNegativeAge() {
+	defer suite.cleanupTest()
+
+	out, err := executeCommandC(rootCmd, "cleanup-uploads", "--older-than=-1h")
+	suite.assert.Error(err)
+	suite.assert.Contains(out, "--older-than must not be negative")
+}
+
+func (suite *cleanupUploadsCmdSuite) TestCleanupNoS3Credentials() {
+	defer suite.cleanupTest()
+
+	cfgFile, err := os.CreateTemp("", "cloudfuse_cleanup_config_*.yaml")
+	suite.assert.NoError(err)
+	cfgPath := cfgFile.Name()
+	_, _ = cfgFile.WriteString(configNoSubdirectory)
+	_ = cfgFile.Close()
+	defer os.Remove(cfgPath)
+
+	out, err := executeCommandC(
+		rootCmd,
+		"cleanup-uploads",
+		fmt.Sprintf("--config-file=%s", cfgPath),
+	)
+	suite.assert.Error(err)
+	suite.assert.Contains(out, "s3storage configure failed")
+}
+
+func TestCleanupUploadsCmd(t *testing.T) {
+	suite.Run(t, new(cleanupUploadsCmdSuite))
+}
@@ -47,6 +47,7 @@ type monitorOptions struct {
 	CfsPollInterval int      `config:"stats-poll-interval-sec"`
 	ProcMonInterval int      `config:"process-monitor-interval-sec"`
 	OutputPath      string   `config:"output-path"`
+	MetricsAddress  string   `config:"metrics-address"`
 }
 
 var pid string
@@ -29,7 +29,10 @@ import (
 	_ "github.com/Seagate/cloudfuse/component/attr_cache"
 	_ "github.com/Seagate/cloudfuse/component/azstorage"
 	_ "github.com/Seagate/cloudfuse/component/block_cache"
+	_ "github.com/Seagate/cloudfuse/component/compression"
+	_ "github.com/Seagate/cloudfuse/component/encryption"
 	_ "github.com/Seagate/cloudfuse/component/file_cache"
+	_ "github.com/Seagate/cloudfuse/component/gcsstorage"
 	_ "github.com/Seagate/cloudfuse/component/libfuse"
 	_ "github.com/Seagate/cloudfuse/component/loopback"
 	_ "github.com/Seagate/cloudfuse/component/s3storage"
@@ -49,6 +49,7 @@ import (
 	"github.com/Seagate/cloudfuse/common/config"
 	"github.com/Seagate/cloudfuse/common/log"
 	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
 	"github.com/awnumar/memguard"
 
 	"github.com/sevlyar/go-daemon"
@@ -176,6 +177,13 @@ func (opt *mountOptions) validate(skipNonEmptyMount bool) error {
 		opt.Logging.LogFileCount = common.DefaultLogFileCount
 	}
 
+	if opt.MonitorOpt.MetricsAddress != "" {
+		_, _, err := stats_manager.ParseMetricsAddress(opt.MonitorOpt.MetricsAddress)
+		if err != nil {
+			return fmt.Errorf("invalid metrics address [%s]", err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -392,6 +400,12 @@ var mountCmd = &cobra.Command{
 			config.Set("read-only", "true") // preload is only supported in read-only mode
 		}
 
+		var asOf string
+		_ = config.UnmarshalKey("s3storage.as-of", &asOf)
+		if asOf != "" {
+			config.Set("read-only", "true") // a point-in-time mount cannot be written to
+		}
+
 		if config.IsSet("libfuse-options") {
 			for _, raw := range options.LibfuseOptions {
 				v := strings.TrimSpace(raw)
@@ -702,6 +716,12 @@ func runPipeline(pipeline *internal.Pipeline, ctx context.Context) error {
 		common.PollingPipe,
 	)
 
+	// the metrics listener must be up before the components start, to collect all their stats
+	metricsServer := startMetricsListener()
+	if metricsServer != nil {
+		defer metricsServer.Close()
+	}
+
 	go startMonitor(os.Getpid())
 
 	err := pipeline.Start(ctx)
@@ -719,6 +739,23 @@ func runPipeline(pipeline *internal.Pipeline, ctx context.Context) error {
 	return nil
 }
 
+func startMetricsListener() *http.Server {
+	if options.MonitorOpt.MetricsAddress == "" {
+		return nil
+	}
+
+	server, err := stats_manager.ListenMetrics(options.MonitorOpt.MetricsAddress)
+	if err != nil {
+		log.Err("Mount::startMetricsListener : Failed to start metrics listener [%s]", err.Error())
+		return nil
+	}
+	log.Info(
+		"Mount::startMetricsListener : Serving metrics on [%s]",
+		options.MonitorOpt.MetricsAddress,
+	)
+	return server
+}
+
 func startMonitor(pid int) {
 	if common.EnableMonitoring {
 		log.Debug("Mount::startMonitor : pid = %v, config-file = %v", pid, options.ConfigFile)
@@ -915,6 +952,10 @@ func init() {
 		Bool("read-only", false, "Mount the system in read only mode. Default value false.")
 	config.BindPFlag("read-only", mountCmd.PersistentFlags().Lookup("read-only"))
 
+	mountCmd.PersistentFlags().
+		String("as-of", "", "Mount a versioned S3 bucket read-only, as it was at the given RFC 3339 time.")
+	config.BindPFlag("s3storage.as-of", mountCmd.PersistentFlags().Lookup("as-of"))
+
 	mountCmd.Flags().BoolVar(&options.DryRun, "dry-run", false,
 		"Test mount configuration, credentials, etc., but don't make any changes to the container or the local file system. Implies foreground.")
 	config.BindPFlag("dry-run", mountCmd.Flags().Lookup("dry-run"))
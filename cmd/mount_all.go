@@ -43,6 +43,7 @@ import (
 	"github.com/awnumar/memguard"
 
 	"github.com/Seagate/cloudfuse/component/azstorage"
+	"github.com/Seagate/cloudfuse/component/gcsstorage"
 	"github.com/Seagate/cloudfuse/component/s3storage"
 
 	"github.com/spf13/cobra"
@@ -201,6 +202,11 @@ func processCommand(out io.Writer, errOut io.Writer) error {
 		if err != nil {
 			return err
 		}
+	} else if slices.Contains(options.Components, "gcsstorage") {
+		containerList, err = getBucketListGCS()
+		if err != nil {
+			return err
+		}
 	}
 
 	if len(containerList) > 0 {
@@ -286,6 +292,38 @@ func getBucketListS3() ([]string, error) {
 	return containerList, nil
 }
 
+// getBucketListGCS : Get list of buckets from the GCS project
+func getBucketListGCS() ([]string, error) {
+	var containerList []string
+
+	// Create GcsStorage component to get container list
+	gcsComponent := &gcsstorage.GcsStorage{}
+	gcsComponent.SetName("gcsstorage")
+	gcsComponent.SetNextComponent(nil)
+
+	// Configure GcsStorage component
+	err := gcsComponent.Configure(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure GcsStorage object [%s]", err.Error())
+	}
+
+	//  Start GcsStorage the component so that credentials are verified
+	err = gcsComponent.Start(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize GcsStorage object [%s]", err.Error())
+	}
+
+	// Get the list of containers from the component
+	containerList, err = gcsComponent.ListBuckets()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bucket list from storage [%s]", err.Error())
+	}
+
+	// Stop the GcsStorage component as its no more needed now
+	_ = gcsComponent.Stop()
+	return containerList, nil
+}
+
 // FiterAllowedContainer : Filter which containers are allowed to be mounted
 func filterAllowedContainerList(containers []string) []string {
 	allowListing := len(mountAllOpts.AllowList) > 0
@@ -406,6 +444,8 @@ func mountAllContainers(
 				viper.Set("azstorage.container", container)
 			} else if slices.Contains(options.Components, "s3storage") {
 				viper.Set("s3storage.bucket-name", container)
+			} else if slices.Contains(options.Components, "gcsstorage") {
+				viper.Set("gcsstorage.bucket-name", container)
 			}
 			viper.Set("file_cache.path", filepath.Join(fileCachePath, container))
 
@@ -34,6 +34,7 @@ const (
 	Argon2Threads = 4
 )
 
-func deriveKey(password []byte, salt []byte) []byte {
+// DeriveKey derives a KeyLength byte key from a password with argon2id
+func DeriveKey(password []byte, salt []byte) []byte {
 	return argon2.IDKey(password, salt, Argon2Time, Argon2Memory, Argon2Threads, KeyLength)
 }
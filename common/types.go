@@ -49,6 +49,9 @@ const (
 
 	DefaultConfigFilePath = "config.yaml"
 
+	// Directory in the file cache holding the state of resumable uploads
+	UploadStateDirName = ".cloudfuseUploads"
+
 	MaxConcurrency     = 40
 	DefaultConcurrency = 20
 
@@ -145,6 +148,58 @@ func (e NoCachedDataError) Is(target error) bool {
 	return ok
 }
 
+// ConflictError is returned when a conditional upload finds the object was changed by someone else
+type ConflictError struct {
+	Message           string
+	CloudStorageError error
+}
+
+func NewConflictError(originalError error) ConflictError {
+	return ConflictError{
+		Message:           "Object was changed in cloud storage",
+		CloudStorageError: originalError,
+	}
+}
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("%s. Here's why: %v", e.Message, e.CloudStorageError)
+}
+func (e ConflictError) Unwrap() error {
+	return e.CloudStorageError
+}
+func (e ConflictError) Is(target error) bool {
+	_, ok := target.(*ConflictError)
+	return ok
+}
+
+// ArchivedError is returned when reading an object that must be restored from an archive tier
+type ArchivedError struct {
+	Message           string
+	Restoring         bool // a restore was requested and is in progress
+	CloudStorageError error
+}
+
+func NewArchivedError(originalError error, restoring bool) ArchivedError {
+	message := "Object is archived and must be restored to be read"
+	if restoring {
+		message = "Object is archived and being restored"
+	}
+	return ArchivedError{
+		Message:           message,
+		Restoring:         restoring,
+		CloudStorageError: originalError,
+	}
+}
+func (e ArchivedError) Error() string {
+	return fmt.Sprintf("%s. Here's why: %v", e.Message, e.CloudStorageError)
+}
+func (e ArchivedError) Unwrap() error {
+	return e.CloudStorageError
+}
+func (e ArchivedError) Is(target error) bool {
+	_, ok := target.(*ArchivedError)
+	return ok
+}
+
 var DefaultWorkDir string
 var DefaultLogFilePath string
 var StatsConfigFilePath string
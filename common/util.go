@@ -248,6 +248,24 @@ func NormalizeObjectName(name string) string {
 	return strings.ReplaceAll(name, "\\", "/")
 }
 
+// MatchPath returns true if the glob pattern matches the object name. Patterns without a slash
+// match the base name. Patterns with a slash match the path or one of its parent directories,
+// so "logs/" matches everything under logs.
+func MatchPath(pattern string, name string) bool {
+	name = strings.Trim(name, "/")
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	pattern = strings.Trim(pattern, "/")
+	for dir := name; dir != "." && dir != ""; dir = path.Dir(dir) {
+		if ok, _ := path.Match(pattern, dir); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // Encrypt given data using the key provided
 func EncryptData(plainData []byte, password *memguard.Enclave) ([]byte, error) {
 	if password == nil {
@@ -264,7 +282,7 @@ func EncryptData(plainData []byte, password *memguard.Enclave) ([]byte, error) {
 	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
 		return nil, fmt.Errorf("unable to generate random salt with error: %w", err)
 	}
-	key := deriveKey(secretKey.Bytes(), salt)
+	key := DeriveKey(secretKey.Bytes(), salt)
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -321,7 +339,7 @@ func DecryptData(cipherData []byte, password *memguard.Enclave) ([]byte, error)
 		return nil, err
 	}
 
-	key := deriveKey(secretKey.Bytes(), salt)
+	key := DeriveKey(secretKey.Bytes(), salt)
 	defer clear(key)
 
 	block, err := aes.NewCipher(key)
@@ -634,6 +652,26 @@ func ValidatePipeline(pipeline []string) error {
 		return fmt.Errorf("mount: block-cache and xload cannot be used together")
 	}
 
+	// stream uploads the blocks it caches directly, so they would bypass encryption
+	if ComponentInPipeline(pipeline, "stream") &&
+		ComponentInPipeline(pipeline, "encryption") {
+		return fmt.Errorf("mount: stream and encryption cannot be used together")
+	}
+
+	// compressed objects can only be read and written whole, which only file-cache does
+	if ComponentInPipeline(pipeline, "compression") &&
+		!ComponentInPipeline(pipeline, "file_cache") {
+		return fmt.Errorf("mount: compression can only be used with file-cache")
+	}
+
+	// encrypted data does not compress, so compression has to come first. Both components have
+	// the same priority, so the pipeline does not check their order.
+	compression := slices.Index(pipeline, "compression")
+	encryption := slices.Index(pipeline, "encryption")
+	if compression >= 0 && encryption >= 0 && compression > encryption {
+		return fmt.Errorf("mount: compression must be above encryption in the pipeline")
+	}
+
 	return nil
 }
 
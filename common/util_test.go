@@ -413,6 +413,28 @@ func (suite *typesTestSuite) TestEncryptDecrypt5() {
 	suite.assert.Equal(data, d)
 }
 
+func (suite *utilTestSuite) TestMatchPath() {
+	tests := []struct {
+		pattern string
+		name    string
+		matches bool
+	}{
+		{"logs/", "logs/2024/app.log", true},
+		{"logs/", "logs", true},
+		{"logs/", "old/logs/app.log", false},
+		{"/scratch", "scratch/tmp", true},
+		{"*/cache/", "user/cache/a.bin", true},
+		{"*/cache/", "cache/a.bin", false},
+		{"*.log", "logs/2024/app.log", true},
+		{"*.log", "app.log.gz", false},
+		{"data/*.csv", "data/a.csv", true},
+		{"data/*.csv", "data/raw/a.csv", false},
+	}
+	for _, tt := range tests {
+		suite.assert.Equal(tt.matches, MatchPath(tt.pattern, tt.name), tt.pattern+" "+tt.name)
+	}
+}
+
 func (suite *utilTestSuite) TestMonitorCfs() {
 	monitor := MonitorCfs()
 	suite.assert.False(monitor)
@@ -673,6 +695,30 @@ func (suite *utilTestSuite) TestValidatePipeline() {
 
 	err = ValidatePipeline([]string{"libfuse", "xload", "attr_cache", "azstorage"})
 	suite.NoError(err)
+
+	err = ValidatePipeline([]string{"libfuse", "stream", "encryption", "azstorage"})
+	suite.Error(err)
+
+	err = ValidatePipeline(
+		[]string{"libfuse", "file_cache", "attr_cache", "encryption", "s3storage"},
+	)
+	suite.NoError(err)
+
+	err = ValidatePipeline([]string{"libfuse", "block_cache", "compression", "s3storage"})
+	suite.Error(err)
+
+	err = ValidatePipeline([]string{"libfuse", "file_cache", "compression", "s3storage"})
+	suite.NoError(err)
+
+	err = ValidatePipeline(
+		[]string{"libfuse", "file_cache", "compression", "encryption", "s3storage"},
+	)
+	suite.NoError(err)
+
+	err = ValidatePipeline(
+		[]string{"libfuse", "file_cache", "encryption", "compression", "s3storage"},
+	)
+	suite.Error(err)
 }
 
 func (suite *utilTestSuite) TestUpdatePipeline() {
@@ -32,6 +32,7 @@ import (
 	"maps"
 	"os"
 	"path"
+	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
@@ -60,6 +61,9 @@ type AttrCache struct {
 	cleanupDone    chan bool
 	cleanupCtx     context.Context
 	cleanupStop    context.CancelFunc
+
+	persistPath     string
+	persistInterval uint32
 }
 
 // Structure defining your config parameters
@@ -73,6 +77,10 @@ type AttrCacheOptions struct {
 	//maximum file attributes overall to be cached
 	MaxFiles int `config:"max-files" yaml:"max-files,omitempty"`
 
+	// directory to save the cache in, so it survives remounts
+	PersistPath     string `config:"persist-path"         yaml:"persist-path,omitempty"`
+	PersistInterval uint32 `config:"persist-interval-sec" yaml:"persist-interval-sec,omitempty"`
+
 	// support v1
 	CacheOnList bool `config:"cache-on-list"`
 }
@@ -83,6 +91,9 @@ const compName = "attr_cache"
 // caching more means increased memory usage of the process
 const defaultMaxFiles = 5000000 // 5 million max files overall to be cached
 
+// By default a persistent cache is checkpointed every 5 minutes
+const defaultPersistInterval uint32 = (300)
+
 // Verification to check satisfaction criteria with Component Interface
 var _ internal.Component = &AttrCache{}
 
@@ -110,6 +121,20 @@ func (ac *AttrCache) Start(ctx context.Context) error {
 
 	// AttrCache : start code goes here
 	ac.cache = newCacheTreeMap(ac.maxFiles)
+	if ac.persistPath != "" {
+		err := os.MkdirAll(ac.persistPath, 0700)
+		if err == nil {
+			err = ac.restoreSnapshot()
+		}
+		if err != nil {
+			// the cache is only an optimization, so start empty
+			log.Err(
+				"AttrCache::Start : Failed to restore cache from %s [%v]",
+				ac.persistPath,
+				err,
+			)
+		}
+	}
 
 	// Start background cleanup goroutine
 	ac.cleanupCtx, ac.cleanupStop = context.WithCancel(ctx)
@@ -129,6 +154,13 @@ func (ac *AttrCache) Stop() error {
 		<-ac.cleanupDone // Wait for cleanup goroutine to finish
 	}
 
+	if ac.persistPath != "" && ac.cache != nil {
+		err := ac.saveSnapshot(false)
+		if err != nil {
+			log.Err("AttrCache::Stop : Failed to save cache to %s [%v]", ac.persistPath, err)
+		}
+	}
+
 	return nil
 }
 
@@ -181,12 +213,24 @@ func (ac *AttrCache) Configure(_ bool) error {
 		ac.enableSymlinks = conf.EnableSymlinks
 	}
 
+	ac.persistPath = ""
+	if conf.PersistPath != "" {
+		ac.persistPath = filepath.Clean(common.ExpandPath(conf.PersistPath))
+	}
+	if config.IsSet(compName + ".persist-interval-sec") {
+		ac.persistInterval = conf.PersistInterval
+	} else {
+		ac.persistInterval = defaultPersistInterval
+	}
+
 	log.Crit(
-		"AttrCache::Configure : cache-timeout %d, enable-symlinks %t, cache-on-list %t, max-files %d",
+		"AttrCache::Configure : cache-timeout %d, enable-symlinks %t, cache-on-list %t, max-files %d, persist-path %s, persist-interval-sec %d",
 		ac.cacheTimeout,
 		ac.enableSymlinks,
 		ac.cacheOnList,
 		ac.maxFiles,
+		ac.persistPath,
+		ac.persistInterval,
 	)
 
 	return nil
@@ -337,7 +381,8 @@ func (ac *AttrCache) touchParentDirTimes(
 	}
 }
 
-// backgroundCleanup: runs in a separate goroutine to periodically clean up expired entries
+// backgroundCleanup: runs in a separate goroutine to periodically clean up expired entries,
+// and to checkpoint a persistent cache
 func (ac *AttrCache) backgroundCleanup() {
 	defer close(ac.cleanupDone)
 
@@ -352,6 +397,14 @@ func (ac *AttrCache) backgroundCleanup() {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// checkpoint a persistent cache, so it survives a crash
+	var checkpointTick <-chan time.Time
+	if ac.persistPath != "" && ac.persistInterval > 0 {
+		checkpointTicker := time.NewTicker(time.Duration(ac.persistInterval) * time.Second)
+		defer checkpointTicker.Stop()
+		checkpointTick = checkpointTicker.C
+	}
+
 	for {
 		select {
 		case <-ac.cleanupCtx.Done():
@@ -359,6 +412,11 @@ func (ac *AttrCache) backgroundCleanup() {
 			return
 		case <-ticker.C:
 			ac.cleanupExpiredEntries()
+		case <-checkpointTick:
+			err := ac.saveSnapshot(true)
+			if err != nil {
+				log.Err("AttrCache::backgroundCleanup : Failed to checkpoint cache [%v]", err)
+			}
 		}
 	}
 }
@@ -510,6 +568,10 @@ func (ac *AttrCache) StreamDir(
 	options internal.StreamDirOptions,
 ) ([]*internal.ObjAttr, string, error) {
 	log.Trace("AttrCache::StreamDir : %s, token=\"%s\"", options.Name, options.Token)
+	// virtual directories are not part of the cached tree
+	if internal.IsVirtualPath(options.Name) {
+		return ac.NextComponent().StreamDir(options)
+	}
 
 	// try to fetch listing from cache
 	cachedPathList, cachedToken, err := ac.fetchCachedDirList(options.Name, options.Token)
@@ -945,7 +1007,13 @@ func (ac *AttrCache) WriteFile(options *internal.WriteFileOptions) (int, error)
 		}
 	}
 	if attr != nil {
-		options.Metadata = attr.Metadata
+		// times kept in metadata describe the old contents, the write changes them
+		options.Metadata = make(map[string]*string, len(attr.Metadata))
+		for key, value := range attr.Metadata {
+			if !internal.IsTimeMetadataKey(key) {
+				options.Metadata[key] = value
+			}
+		}
 	}
 
 	size, err := ac.NextComponent().WriteFile(options)
@@ -1034,8 +1102,13 @@ func (ac *AttrCache) CopyFromFile(options internal.CopyFromFileOptions) error {
 		if options.Metadata == nil {
 			options.Metadata = attr.Metadata
 		} else {
+			// metadata keys are case-insensitive in cloud storage
+			given := make(map[string]bool, len(options.Metadata))
+			for key := range options.Metadata {
+				given[strings.ToLower(key)] = true
+			}
 			for key, value := range attr.Metadata {
-				if _, exists := options.Metadata[key]; !exists {
+				if !given[strings.ToLower(key)] {
 					options.Metadata[key] = value
 				}
 			}
@@ -1067,6 +1140,9 @@ func (ac *AttrCache) CopyFromFile(options internal.CopyFromFileOptions) error {
 		} else {
 			// replace entry
 			attr := internal.CreateObjAttr(options.Name, fileStat.Size(), fileStat.ModTime())
+			if options.NewETag != nil {
+				attr.ETag = *options.NewETag
+			}
 			entry := ac.cache.insert(insertOptions{
 				attr:     attr,
 				exists:   true,
@@ -1074,6 +1150,15 @@ func (ac *AttrCache) CopyFromFile(options internal.CopyFromFileOptions) error {
 			})
 			entry.setMode(fileStat.Mode())
 		}
+	} else if errors.Is(err, &common.ConflictError{}) {
+		// the object was changed by someone else, so what we know about it is out of date
+		ac.cacheLock.Lock()
+		defer ac.cacheLock.Unlock()
+
+		entry, found := ac.cache.get(options.Name)
+		if found {
+			entry.invalidate()
+		}
 	}
 	return err
 }
@@ -1110,6 +1195,9 @@ func (ac *AttrCache) SyncDir(options internal.SyncDirOptions) error {
 func (ac *AttrCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
 	// Don't log these by default, as it noticeably affects performance
 	// log.Trace("AttrCache::GetAttr : %s", options.Name)
+	if internal.IsVirtualPath(options.Name) {
+		return ac.NextComponent().GetAttr(options)
+	}
 
 	// is the answer in the cache?
 	respondFromCache := false
@@ -1277,6 +1365,42 @@ func (ac *AttrCache) Chown(options internal.ChownOptions) error {
 	return err
 }
 
+// Utimens : Update the file with its new access and modification times
+func (ac *AttrCache) Utimens(options internal.UtimensOptions) error {
+	log.Trace("AttrCache::Utimens : Change times of file/directory %s", options.Name)
+
+	err := ac.NextComponent().Utimens(options)
+
+	if err == nil {
+		ac.cacheLock.Lock()
+		defer ac.cacheLock.Unlock()
+
+		value, found := ac.cache.get(options.Name)
+		if found && value.exists() {
+			value.setTimes(options.Atime, options.Mtime)
+		}
+	}
+	return err
+}
+
+// SetMetadata : Update the metadata of the object and invalidate its cached attributes
+func (ac *AttrCache) SetMetadata(options internal.SetMetadataOptions) error {
+	log.Trace("AttrCache::SetMetadata : %s", options.Name)
+
+	err := ac.NextComponent().SetMetadata(options)
+	if err == nil {
+		ac.cacheLock.Lock()
+		defer ac.cacheLock.Unlock()
+
+		// cached metadata is carried over on upload, so it must not go stale
+		entry, found := ac.cache.get(options.Name)
+		if found && entry.exists() {
+			entry.invalidate()
+		}
+	}
+	return err
+}
+
 func (ac *AttrCache) CommitData(options internal.CommitDataOptions) error {
 	log.Trace("AttrCache::CommitData : %s", options.Name)
 	err := ac.NextComponent().CommitData(options)
@@ -1475,6 +1475,25 @@ func (suite *attrCacheTestSuite) TestCopyFromFileExists() {
 	suite.assert.NoError(err)
 }
 
+func (suite *attrCacheTestSuite) TestCopyFromFileConflict() {
+	defer suite.cleanupTest()
+
+	path := "a"
+	options := internal.CopyFromFileOptions{Name: path, IfMatch: new("etag")}
+
+	// the object changed in cloud storage, so the cached entry is stale
+	suite.addPathToCache(path)
+	suite.mock.EXPECT().
+		CopyFromFile(options).
+		Return(common.NewConflictError(errors.New("precondition failed")))
+
+	err := suite.attrCache.CopyFromFile(options)
+	suite.assert.ErrorIs(err, &common.ConflictError{})
+	entry, found := suite.attrCache.cache.get(path)
+	suite.assert.True(found)
+	suite.assert.False(entry.valid())
+}
+
 // GetAttr
 func (suite *attrCacheTestSuite) TestGetAttrExistsDeleted() {
 	defer suite.cleanupTest()
@@ -1692,6 +1711,35 @@ func (suite *attrCacheTestSuite) TestGetAttrWithCompleteParentListing() {
 	suite.assert.Nil(result)
 }
 
+func (suite *attrCacheTestSuite) TestGetAttrVirtualPath() {
+	defer suite.cleanupTest()
+	internal.RegisterVirtualDir(".virtual")
+
+	parentPath := "dir/"
+	childPath := "dir/.virtual/file"
+
+	suite.addPathToCache(parentPath)
+	parentItem, found := suite.attrCache.cache.get(parentPath)
+	suite.assert.True(found)
+	parentItem.listingComplete = true
+
+	// virtual paths are not in the parent's listing, and are not cached
+	options := internal.GetAttrOptions{Name: childPath}
+	suite.mock.EXPECT().GetAttr(options).Return(internal.CreateObjAttrDir(childPath), nil).Times(2)
+	listOptions := internal.StreamDirOptions{Name: childPath}
+	suite.mock.EXPECT().StreamDir(listOptions).Return([]*internal.ObjAttr{}, "", nil)
+
+	for range 2 {
+		result, err := suite.attrCache.GetAttr(options)
+		suite.assert.NoError(err)
+		suite.assert.Equal(childPath, result.Path)
+	}
+	_, _, err := suite.attrCache.StreamDir(listOptions)
+	suite.assert.NoError(err)
+	_, found = suite.attrCache.cache.get("dir/.virtual")
+	suite.assert.False(found)
+}
+
 func (suite *attrCacheTestSuite) TestGetAttrOfflineExpired() {
 	defer suite.cleanupTest()
 
@@ -2055,6 +2103,70 @@ func (suite *attrCacheTestSuite) TestChmod() {
 	}
 }
 
+// Tests Utimens
+func (suite *attrCacheTestSuite) TestUtimens() {
+	defer suite.cleanupTest()
+	var paths = []string{"a", "a/"}
+	mtime := time.Unix(1600000000, 0)
+
+	for _, path := range paths {
+		// This is a little janky but required since testify suite does not support running setup or clean up for subtests.
+		suite.cleanupTest()
+		suite.SetupTest()
+		suite.Run(path, func() {
+			truncatedPath := internal.TruncateDirName(path)
+			options := internal.UtimensOptions{Name: path, Mtime: mtime}
+
+			// Error
+			suite.addPathToCache(path)
+			suite.mock.EXPECT().Utimens(options).Return(errors.New("Failed to set times"))
+
+			err := suite.attrCache.Utimens(options)
+			suite.assert.Error(err)
+			checkItem, found := suite.attrCache.cache.get(truncatedPath)
+			suite.assert.True(found)
+			suite.assert.False(checkItem.attr.Mtime.Equal(mtime))
+
+			// Success
+			atime := checkItem.attr.Atime
+			suite.mock.EXPECT().Utimens(options).Return(nil)
+
+			err = suite.attrCache.Utimens(options)
+			suite.assert.NoError(err)
+			checkItem, found = suite.attrCache.cache.get(truncatedPath)
+			suite.assert.True(found)
+			suite.assert.True(checkItem.attr.Mtime.Equal(mtime))
+			suite.assert.True(checkItem.attr.Atime.Equal(atime)) // zero atime is left unchanged
+			suite.assert.True(checkItem.valid())
+		})
+	}
+}
+
+// Tests SetMetadata
+func (suite *attrCacheTestSuite) TestSetMetadata() {
+	defer suite.cleanupTest()
+	path := "a"
+	options := internal.SetMetadataOptions{
+		Name:     path,
+		Metadata: map[string]*string{"label": new("value")},
+	}
+
+	// Error
+	suite.addPathToCache(path)
+	suite.mock.EXPECT().SetMetadata(options).Return(errors.New("Failed to set metadata"))
+
+	err := suite.attrCache.SetMetadata(options)
+	suite.assert.Error(err)
+	suite.assertUntouched(path)
+
+	// Success
+	suite.mock.EXPECT().SetMetadata(options).Return(nil)
+
+	err = suite.attrCache.SetMetadata(options)
+	suite.assert.NoError(err)
+	suite.assertInvalid(path)
+}
+
 // Tests Chown
 func (suite *attrCacheTestSuite) TestChown() {
 	defer suite.cleanupTest()
@@ -281,6 +281,18 @@ func (value *attrCacheItem) touchModifyAndChangeTimes(changedAt time.Time) {
 	value.cachedAt = changedAt
 }
 
+// setTimes updates the access and modification times, zero times are left unchanged
+func (value *attrCacheItem) setTimes(atime time.Time, mtime time.Time) {
+	if !atime.IsZero() {
+		value.attr.Atime = atime
+	}
+	if !mtime.IsZero() {
+		value.attr.Mtime = mtime
+	}
+	value.attr.Ctime = time.Now()
+	value.cachedAt = time.Now()
+}
+
 func (value *attrCacheItem) setMode(mode os.FileMode) {
 	currentType := value.attr.Mode & os.ModeType
 	if currentType == 0 {
@@ -0,0 +1,330 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package attr_cache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+)
+
+// The attribute cache can be saved to persist-path, and restored when the component starts.
+// Entries keep the time they were cached, so they expire as if the mount had never stopped, and
+// expired entries are still served while cloud storage is unreachable.
+// A snapshot saved at unmount is trusted. A checkpoint saved while mounted may be missing
+// changes made after it was saved, so its entries are restored as already expired.
+
+const (
+	// Snapshot saved at unmount, relative to persist-path
+	snapshotPath = ".attrCacheSnapshot.gob"
+	// Snapshot saved periodically while mounted, relative to persist-path
+	checkpointPath = ".attrCacheCheckpoint.gob"
+)
+
+// attrCacheSnapshot represents the persisted state of the attribute cache
+type attrCacheSnapshot struct {
+	Items []itemSnapshot // sorted by path, so parents come before their children
+}
+
+type itemSnapshot struct {
+	Attr            attrSnapshot
+	CachedAt        time.Time
+	Flags           uint64
+	ListingComplete bool
+	ListCache       map[string]listSegmentSnapshot
+}
+
+// the fields of internal.ObjAttr, with metadata values that gob can encode
+type attrSnapshot struct {
+	Mtime    time.Time
+	Atime    time.Time
+	Ctime    time.Time
+	Crtime   time.Time
+	Size     int64
+	Mode     os.FileMode
+	Flags    uint64
+	Path     string
+	Name     string
+	MD5      []byte
+	ETag     string
+	Tier     string
+	Metadata map[string]string
+}
+
+type listSegmentSnapshot struct {
+	Paths     []string
+	NextToken string
+	CachedAt  time.Time
+}
+
+func newAttrSnapshot(attr *internal.ObjAttr) attrSnapshot {
+	ss := attrSnapshot{
+		Mtime:  attr.Mtime,
+		Atime:  attr.Atime,
+		Ctime:  attr.Ctime,
+		Crtime: attr.Crtime,
+		Size:   attr.Size,
+		Mode:   attr.Mode,
+		Flags:  uint64(attr.Flags),
+		Path:   attr.Path,
+		Name:   attr.Name,
+		MD5:    attr.MD5,
+		ETag:   attr.ETag,
+		Tier:   attr.Tier,
+	}
+	if attr.Metadata != nil {
+		ss.Metadata = make(map[string]string, len(attr.Metadata))
+		for key, value := range attr.Metadata {
+			if value != nil {
+				ss.Metadata[key] = *value
+			}
+		}
+	}
+	return ss
+}
+
+func (ss attrSnapshot) objAttr() *internal.ObjAttr {
+	attr := &internal.ObjAttr{
+		Mtime:  ss.Mtime,
+		Atime:  ss.Atime,
+		Ctime:  ss.Ctime,
+		Crtime: ss.Crtime,
+		Size:   ss.Size,
+		Mode:   ss.Mode,
+		Flags:  common.BitMap64(ss.Flags),
+		Path:   ss.Path,
+		Name:   ss.Name,
+		MD5:    ss.MD5,
+		ETag:   ss.ETag,
+		Tier:   ss.Tier,
+	}
+	if ss.Metadata != nil {
+		attr.Metadata = make(map[string]*string, len(ss.Metadata))
+		for key, value := range ss.Metadata {
+			attr.Metadata[key] = &value
+		}
+	}
+	return attr
+}
+
+// createSnapshot copies the cache. The caller must hold at least a read lock.
+func (ctm *cacheTreeMap) createSnapshot() *attrCacheSnapshot {
+	snapshot := &attrCacheSnapshot{Items: make([]itemSnapshot, 0, len(ctm.cacheMap))}
+	for _, item := range ctm.cacheMap {
+		itemSS := itemSnapshot{
+			Attr:            newAttrSnapshot(item.attr),
+			CachedAt:        item.cachedAt,
+			Flags:           uint64(item.attrFlag),
+			ListingComplete: item.listingComplete,
+		}
+		if item.listCache != nil {
+			itemSS.ListCache = make(map[string]listSegmentSnapshot, len(item.listCache))
+			for token, segment := range item.listCache {
+				paths := make([]string, len(segment.entries))
+				for i, entry := range segment.entries {
+					paths[i] = entry.Path
+				}
+				itemSS.ListCache[token] = listSegmentSnapshot{
+					Paths:     paths,
+					NextToken: segment.nextToken,
+					CachedAt:  segment.cachedAt,
+				}
+			}
+		}
+		snapshot.Items = append(snapshot.Items, itemSS)
+	}
+	slices.SortFunc(snapshot.Items, func(a, b itemSnapshot) int {
+		return strings.Compare(a.Attr.Path, b.Attr.Path)
+	})
+	return snapshot
+}
+
+// loadSnapshot fills an empty cache with the snapshot.
+// When expired is set, every entry is restored as expired.
+func (ctm *cacheTreeMap) loadSnapshot(snapshot *attrCacheSnapshot, expired bool) {
+	restore := func(at time.Time) time.Time {
+		if expired {
+			return time.Time{}
+		}
+		return at
+	}
+	// add the items, then the listings that refer to them
+	for _, itemSS := range snapshot.Items {
+		item := ctm.cacheTree
+		if itemSS.Attr.Path != "" {
+			item = ctm.insert(insertOptions{
+				attr:        itemSS.Attr.objAttr(),
+				exists:      true,
+				cachedAt:    restore(itemSS.CachedAt),
+				fromDirList: true,
+			})
+			if item == nil {
+				log.Warn("AttrCache::loadSnapshot : Cache is full, dropping remaining entries")
+				break
+			}
+		}
+		item.attrFlag = common.BitMap64(itemSS.Flags)
+		item.cachedAt = restore(itemSS.CachedAt)
+		item.listingComplete = itemSS.ListingComplete
+	}
+	for _, itemSS := range snapshot.Items {
+		item, found := ctm.get(itemSS.Attr.Path)
+		if !found || len(itemSS.ListCache) == 0 {
+			continue
+		}
+		item.listCache = make(map[string]listCacheSegment, len(itemSS.ListCache))
+		for token, segmentSS := range itemSS.ListCache {
+			segment, ok := ctm.restoreListSegment(segmentSS)
+			if ok {
+				segment.cachedAt = restore(segment.cachedAt)
+				item.listCache[token] = segment
+			}
+		}
+	}
+}
+
+// restoreListSegment rebuilds a listing from cached entries, failing if any is missing
+func (ctm *cacheTreeMap) restoreListSegment(ss listSegmentSnapshot) (listCacheSegment, bool) {
+	segment := listCacheSegment{
+		entries:   make([]*internal.ObjAttr, 0, len(ss.Paths)),
+		nextToken: ss.NextToken,
+		cachedAt:  ss.CachedAt,
+	}
+	for _, entryPath := range ss.Paths {
+		item, found := ctm.get(entryPath)
+		if !found {
+			return segment, false
+		}
+		segment.entries = append(segment.entries, item.attr)
+	}
+	return segment, true
+}
+
+// saveSnapshot writes the cache to persist-path. Pass checkpoint while the cache is in use.
+func (ac *AttrCache) saveSnapshot(checkpoint bool) error {
+	ac.cacheLock.RLock()
+	snapshot := ac.cache.createSnapshot()
+	ac.cacheLock.RUnlock()
+
+	if checkpoint {
+		return snapshot.writeToFile(filepath.Join(ac.persistPath, checkpointPath))
+	}
+	err := snapshot.writeToFile(filepath.Join(ac.persistPath, snapshotPath))
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(ac.persistPath, checkpointPath))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// restoreSnapshot loads the snapshot or checkpoint saved by the last mount, if any
+func (ac *AttrCache) restoreSnapshot() error {
+	path := filepath.Join(ac.persistPath, snapshotPath)
+	snapshot, err := readSnapshotFromFile(path)
+	expired := false
+	if errors.Is(err, os.ErrNotExist) {
+		// the last mount did not stop cleanly
+		path = filepath.Join(ac.persistPath, checkpointPath)
+		snapshot, err = readSnapshotFromFile(path)
+		expired = true
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	ac.cacheLock.Lock()
+	ac.cache.loadSnapshot(snapshot, expired)
+	ac.cacheLock.Unlock()
+	log.Info(
+		"AttrCache::restoreSnapshot : Restored %d entries from %s (expired %t)",
+		len(snapshot.Items),
+		path,
+		expired,
+	)
+
+	// if we crash before the next checkpoint, the snapshot will be out of date
+	if !expired {
+		err = os.Rename(path, filepath.Join(ac.persistPath, checkpointPath))
+	}
+	return err
+}
+
+func (ss *attrCacheSnapshot) writeToFile(path string) error {
+	// write next to the destination, and atomically replace it
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(ss)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
+
+func readSnapshotFromFile(path string) (*attrCacheSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var snapshot attrCacheSnapshot
+	err = gob.NewDecoder(bufio.NewReader(f)).Decode(&snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
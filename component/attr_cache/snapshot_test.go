@@ -0,0 +1,151 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package attr_cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/internal"
+)
+
+// setup an attr cache that persists to a temporary directory, and return its config
+func (suite *attrCacheTestSuite) setupPersistentCache() string {
+	suite.cleanupTest() // clean up the default attr cache generated
+	config := fmt.Sprintf("attr_cache:\n  persist-path: %s", suite.T().TempDir())
+	suite.setupTestHelper(config)
+	return config
+}
+
+// stop the attr cache, and start a new one with the same config and mock
+func (suite *attrCacheTestSuite) restartAttrCache(config string, crash bool) {
+	if crash {
+		suite.assert.NoError(suite.attrCache.saveSnapshot(true))
+		suite.attrCache.cleanupStop()
+		<-suite.attrCache.cleanupDone
+	} else {
+		suite.assert.NoError(suite.attrCache.Stop())
+	}
+	suite.attrCache = newTestAttrCache(suite.mock, config)
+	suite.assert.NoError(suite.attrCache.Start(context.Background()))
+}
+
+func (suite *attrCacheTestSuite) TestConfigPersist() {
+	defer suite.cleanupTest()
+	suite.assert.Empty(suite.attrCache.persistPath)
+	suite.cleanupTest()
+	dir := suite.T().TempDir()
+	config := fmt.Sprintf(
+		"attr_cache:\n  persist-path: %s/sub/..\n  persist-interval-sec: 60",
+		dir,
+	)
+	suite.setupTestHelper(config)
+
+	suite.assert.Equal(dir, suite.attrCache.persistPath)
+	suite.assert.EqualValues(60, suite.attrCache.persistInterval)
+}
+
+func (suite *attrCacheTestSuite) TestPersistAcrossRemount() {
+	defer suite.cleanupTest()
+	config := suite.setupPersistentCache()
+
+	// list a directory, and look up a file that does not exist
+	entries := []*internal.ObjAttr{
+		getDirPathAttr("dir/sub"),
+		getPathAttr("dir/file", 5, fs.FileMode(defaultMode)),
+	}
+	value := "value"
+	entries[1].Metadata = map[string]*string{"key": &value}
+	listOptions := internal.StreamDirOptions{Name: "dir"}
+	suite.mock.EXPECT().StreamDir(listOptions).Return(entries, "", nil)
+	_, _, err := suite.attrCache.StreamDir(listOptions)
+	suite.assert.NoError(err)
+	missing := internal.GetAttrOptions{Name: "missing"}
+	suite.mock.EXPECT().GetAttr(missing).Return(nil, syscall.ENOENT)
+	_, err = suite.attrCache.GetAttr(missing)
+	suite.assert.Equal(syscall.ENOENT, err)
+
+	suite.restartAttrCache(config, false)
+	suite.assert.FileExists(filepath.Join(suite.attrCache.persistPath, checkpointPath))
+	suite.assert.NoFileExists(filepath.Join(suite.attrCache.persistPath, snapshotPath))
+
+	// everything is served from the cache, without calling the mock
+	result, token, err := suite.attrCache.StreamDir(listOptions)
+	suite.assert.NoError(err)
+	suite.assert.Empty(token)
+	suite.assert.Len(result, 2)
+	attr, err := suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/file"})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(5, attr.Size)
+	suite.assert.Equal("value", *attr.Metadata["key"])
+	suite.assert.Same(attr, result[0])
+	attr, err = suite.attrCache.GetAttr(internal.GetAttrOptions{Name: "dir/sub"})
+	suite.assert.NoError(err)
+	suite.assert.True(attr.IsDir())
+	_, err = suite.attrCache.GetAttr(missing)
+	suite.assert.Equal(syscall.ENOENT, err)
+}
+
+func (suite *attrCacheTestSuite) TestPersistAfterCrash() {
+	defer suite.cleanupTest()
+	config := suite.setupPersistentCache()
+
+	path := "file"
+	options := internal.GetAttrOptions{Name: path}
+	fileAttr := getPathAttr(path, 5, fs.FileMode(defaultMode))
+	suite.mock.EXPECT().GetAttr(options).Return(fileAttr, nil)
+	_, err := suite.attrCache.GetAttr(options)
+	suite.assert.NoError(err)
+
+	suite.restartAttrCache(config, true)
+
+	// changes since the checkpoint may be lost, so the entry has expired,
+	// but it is still served while offline
+	cloudErr := common.NewCloudUnreachableError(errors.New("network unavailable"))
+	suite.mock.EXPECT().GetAttr(options).Return(nil, cloudErr)
+	attr, err := suite.attrCache.GetAttr(options)
+	suite.assert.ErrorIs(err, &common.CloudUnreachableError{})
+	suite.assert.NotNil(attr)
+	suite.assert.EqualValues(5, attr.Size)
+}
+
+func (suite *attrCacheTestSuite) TestPersistCorruptSnapshot() {
+	defer suite.cleanupTest()
+	config := suite.setupPersistentCache()
+	persistPath := suite.attrCache.persistPath
+	err := os.WriteFile(filepath.Join(persistPath, snapshotPath), []byte("corrupt"), 0600)
+	suite.assert.NoError(err)
+
+	// start with an empty cache
+	suite.restartAttrCache(config, true)
+	suite.assert.Len(suite.attrCache.cache.cacheMap, 1)
+}
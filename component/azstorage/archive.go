@@ -0,0 +1,92 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+)
+
+// Blobs in the Archive tier must be rehydrated to an online tier before they can be read.
+// Rehydration moves the blob to rehydrate-tier, which can take hours.
+
+// archiveStatus returns the restore status of a blob, or an empty string if it is not archived
+func archiveStatus(accessTier *string, status *string) string {
+	if accessTier == nil || !strings.EqualFold(*accessTier, string(blob.AccessTierArchive)) {
+		return ""
+	}
+	if status != nil && strings.HasPrefix(*status, "rehydrate-pending") {
+		return internal.RestoreStatusRestoring
+	}
+	return internal.RestoreStatusArchived
+}
+
+// archiveMetadata adds the archive control keys of a blob to its metadata
+func archiveMetadata(accessTier *string, status *string, metadata map[string]*string) {
+	if accessTier != nil {
+		metadata[internal.TierMetadataKey] = accessTier
+	}
+	if restoreStatus := archiveStatus(accessTier, status); restoreStatus != "" {
+		metadata[internal.RestoreMetadataKey] = &restoreStatus
+	}
+}
+
+// rehydrate requests the rehydration of an archived blob
+func (bb *BlockBlob) rehydrate(ctx context.Context, name string) error {
+	blobClient := bb.getBlobClient(name)
+	prop, err := blobClient.GetProperties(ctx, &blob.GetPropertiesOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+	if err != nil {
+		return bb.metadataError(ctx, name, err, "rehydrate")
+	}
+	switch archiveStatus(prop.AccessTier, prop.ArchiveStatus) {
+	case "":
+		log.Info("BlockBlob::rehydrate : %s is not archived", name)
+		return nil
+	case internal.RestoreStatusRestoring:
+		log.Info("BlockBlob::rehydrate : %s is already being rehydrated", name)
+		return nil
+	}
+
+	_, err = blobClient.SetTier(ctx, bb.Config.rehydrateTier, &blob.SetTierOptions{
+		RehydratePriority: &bb.Config.rehydratePriority,
+	})
+	if err != nil {
+		return bb.metadataError(ctx, name, err, "rehydrate")
+	}
+	log.Info(
+		"BlockBlob::rehydrate : Requested rehydration of %s to %s",
+		name,
+		bb.Config.rehydrateTier,
+	)
+	return nil
+}
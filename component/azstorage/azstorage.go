@@ -29,6 +29,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -100,6 +101,10 @@ func (az *AzStorage) Configure(isParent bool) error {
 		return err
 	}
 
+	if conf.EnableSnapshots {
+		internal.RegisterVirtualDir(snapshotsDirName)
+	}
+
 reconfigure:
 
 	err = ParseAndValidateConfig(az, conf)
@@ -205,6 +210,34 @@ func (az *AzStorage) Start(ctx context.Context) error {
 	// create stats collector for azstorage
 	azStatsCollector = stats_manager.NewStatsCollector(az.Name())
 	log.Debug("Starting azstorage stats collector")
+	azStatsCollector.AddStats(
+		stats_manager.Counter,
+		bytesDownloaded,
+		bytesUploaded,
+		createDir,
+		deleteDir,
+		streamDir,
+		renameDir,
+		deleteFile,
+		renameFile,
+		truncateFile,
+		createLink,
+		readLink,
+		setMetadata,
+		utimens,
+		chmod,
+	)
+	azStatsCollector.AddStats(stats_manager.Gauge, openHandles)
+	azStatsCollector.UpdateStats(stats_manager.Replace, openHandles, (int64)(0))
+	azStatsCollector.AddGauge(cloudConnected, func() float64 {
+		// report the state without checking the connection
+		az.state.Lock()
+		defer az.state.Unlock()
+		if az.state.firstOffline != nil {
+			return 0
+		}
+		return 1
+	})
 	// create a shared context for all cloud operations, with ability to cancel
 	az.ctx, az.cancelFn = context.WithCancel(ctx)
 	// create the retry ticker
@@ -352,6 +385,9 @@ func (az *AzStorage) ListContainers() ([]string, error) {
 // Directory operations
 func (az *AzStorage) CreateDir(options internal.CreateDirOptions) error {
 	log.Trace("AzStorage::CreateDir : %s", options.Name)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
 
 	err := az.storage.CreateDirectory(az.ctx, internal.TruncateDirName(options.Name))
 	err = az.handleStorageError(err)
@@ -370,6 +406,9 @@ func (az *AzStorage) CreateDir(options internal.CreateDirOptions) error {
 
 func (az *AzStorage) DeleteDir(options internal.DeleteDirOptions) error {
 	log.Trace("AzStorage::DeleteDir : %s", options.Name)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
 
 	err := az.storage.DeleteDirectory(az.ctx, internal.TruncateDirName(options.Name))
 	err = az.handleStorageError(err)
@@ -484,6 +523,9 @@ func (az *AzStorage) StreamDir(
 
 func (az *AzStorage) RenameDir(options internal.RenameDirOptions) error {
 	log.Trace("AzStorage::RenameDir : %s to %s", options.Src, options.Dst)
+	if err := az.checkWritable(options.Src, options.Dst); err != nil {
+		return err
+	}
 	options.Src = internal.TruncateDirName(options.Src)
 	options.Dst = internal.TruncateDirName(options.Dst)
 
@@ -504,6 +546,9 @@ func (az *AzStorage) RenameDir(options internal.RenameDirOptions) error {
 // File operations
 func (az *AzStorage) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
 	log.Trace("AzStorage::CreateFile : %s", options.Name)
+	if err := az.checkWritable(options.Name); err != nil {
+		return nil, err
+	}
 
 	// Create a handle object for the file being created
 	// This handle will be added to handlemap by the first component in pipeline
@@ -534,6 +579,11 @@ func (az *AzStorage) CreateFile(options internal.CreateFileOptions) (*handlemap.
 
 func (az *AzStorage) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
 	log.Trace("AzStorage::OpenFile : %s", options.Name)
+	if options.Flags&(os.O_WRONLY|os.O_RDWR|os.O_TRUNC) != 0 {
+		if err := az.checkWritable(options.Name); err != nil {
+			return nil, err
+		}
+	}
 
 	attr, err := az.storage.GetAttr(az.ctx, options.Name)
 	err = az.handleStorageError(err)
@@ -568,6 +618,9 @@ func (az *AzStorage) ReleaseFile(options internal.ReleaseFileOptions) error {
 
 func (az *AzStorage) DeleteFile(options internal.DeleteFileOptions) error {
 	log.Trace("AzStorage::DeleteFile : %s", options.Name)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
 
 	err := az.storage.DeleteFile(az.ctx, options.Name)
 	err = az.handleStorageError(err)
@@ -582,6 +635,9 @@ func (az *AzStorage) DeleteFile(options internal.DeleteFileOptions) error {
 
 func (az *AzStorage) RenameFile(options internal.RenameFileOptions) error {
 	log.Trace("AzStorage::RenameFile : %s to %s", options.Src, options.Dst)
+	if err := az.checkWritable(options.Src, options.Dst); err != nil {
+		return err
+	}
 
 	err := az.storage.RenameFile(az.ctx, options.Src, options.Dst, options.SrcAttr)
 	err = az.handleStorageError(err)
@@ -628,7 +684,9 @@ func (az *AzStorage) ReadInBuffer(options *internal.ReadInBufferOptions) (length
 	}
 
 	length = int(dataLen)
-	err = az.storage.ReadInBuffer(az.ctx, path, options.Offset, dataLen, options.Data, options.Etag)
+	ctx, cancel := options.Context(az.ctx)
+	defer cancel()
+	err = az.storage.ReadInBuffer(ctx, path, options.Offset, dataLen, options.Data, options.Etag)
 	if err != nil {
 		log.Err("AzStorage::ReadInBuffer : Failed to read %s [%s]", path, err.Error())
 		length = 0
@@ -638,6 +696,9 @@ func (az *AzStorage) ReadInBuffer(options *internal.ReadInBufferOptions) (length
 }
 
 func (az *AzStorage) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	if err := az.checkWritable(options.Handle.Path); err != nil {
+		return 0, err
+	}
 	err := az.storage.Write(az.ctx, options)
 	err = az.handleStorageError(err)
 	return len(options.Data), err
@@ -653,6 +714,9 @@ func (az *AzStorage) GetFileBlockOffsets(
 
 func (az *AzStorage) TruncateFile(options internal.TruncateFileOptions) error {
 	log.Trace("AzStorage::TruncateFile : %s to %d bytes", options.Name, options.NewSize)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
 	err := az.storage.TruncateFile(az.ctx, options)
 	err = az.handleStorageError(err)
 
@@ -676,7 +740,17 @@ func (az *AzStorage) CopyToFile(options internal.CopyToFileOptions) error {
 
 func (az *AzStorage) CopyFromFile(options internal.CopyFromFileOptions) error {
 	log.Trace("AzStorage::CopyFromFile : Upload file %s", options.Name)
-	err := az.storage.WriteFromFile(az.ctx, options.Name, options.Metadata, options.File)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
+	err := az.storage.WriteFromFile(
+		az.ctx,
+		options.Name,
+		options.Metadata,
+		options.File,
+		options.IfMatch,
+		options.NewETag,
+	)
 	err = az.handleStorageError(err)
 	return err
 }
@@ -692,6 +766,9 @@ func (az *AzStorage) CreateLink(options internal.CreateLinkOptions) error {
 		return syscall.ENOTSUP
 	}
 	log.Trace("AzStorage::CreateLink : Create symlink %s -> %s", options.Name, options.Target)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
 	err := az.storage.CreateLink(az.ctx, options.Name, options.Target)
 	err = az.handleStorageError(err)
 
@@ -732,8 +809,51 @@ func (az *AzStorage) GetAttr(options internal.GetAttrOptions) (attr *internal.Ob
 	return attr, err
 }
 
+// Metadata operations
+func (az *AzStorage) GetMetadata(options internal.GetMetadataOptions) (map[string]*string, error) {
+	log.Trace("AzStorage::GetMetadata : Get metadata of %s", options.Name)
+	metadata, err := az.storage.GetMetadata(az.ctx, options.Name)
+	err = az.handleStorageError(err)
+	return metadata, err
+}
+
+func (az *AzStorage) SetMetadata(options internal.SetMetadataOptions) error {
+	log.Trace("AzStorage::SetMetadata : Set metadata of %s", options.Name)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
+	err := az.storage.SetMetadata(az.ctx, options.Name, options.Metadata)
+	err = az.handleStorageError(err)
+
+	if err == nil {
+		azStatsCollector.PushEvents(setMetadata, options.Name, nil)
+		azStatsCollector.UpdateStats(stats_manager.Increment, setMetadata, (int64)(1))
+	}
+
+	return err
+}
+
+func (az *AzStorage) Utimens(options internal.UtimensOptions) error {
+	log.Trace("AzStorage::Utimens : Change times of %s", options.Name)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
+	err := az.storage.Utimens(az.ctx, options.Name, options.Atime, options.Mtime)
+	err = az.handleStorageError(err)
+
+	if err == nil {
+		azStatsCollector.PushEvents(utimens, options.Name, nil)
+		azStatsCollector.UpdateStats(stats_manager.Increment, utimens, (int64)(1))
+	}
+
+	return err
+}
+
 func (az *AzStorage) Chmod(options internal.ChmodOptions) error {
 	log.Trace("AzStorage::Chmod : Change mod of file %s", options.Name)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
 	err := az.storage.ChangeMod(az.ctx, options.Name, options.Mode)
 	err = az.handleStorageError(err)
 
@@ -756,6 +876,9 @@ func (az *AzStorage) Chown(options internal.ChownOptions) error {
 		options.Owner,
 		options.Group,
 	)
+	if err := az.checkWritable(options.Name); err != nil {
+		return err
+	}
 	err := az.storage.ChangeOwner(az.ctx, options.Name, options.Owner, options.Group)
 	err = az.handleStorageError(err)
 	return err
@@ -763,6 +886,9 @@ func (az *AzStorage) Chown(options internal.ChownOptions) error {
 
 func (az *AzStorage) FlushFile(options internal.FlushFileOptions) error {
 	log.Trace("AzStorage::FlushFile : Flush file %s", options.Handle.Path)
+	if err := az.checkWritable(options.Handle.Path); err != nil {
+		return err
+	}
 	err := az.storage.StageAndCommit(
 		az.ctx,
 		options.Handle.Path,
@@ -779,13 +905,19 @@ func (az *AzStorage) GetCommittedBlockList(name string) (*internal.CommittedBloc
 }
 
 func (az *AzStorage) StageData(opt internal.StageDataOptions) error {
+	if err := az.checkWritable(opt.Name); err != nil {
+		return err
+	}
 	err := az.storage.StageBlock(az.ctx, opt.Name, opt.Data, opt.Id)
 	err = az.handleStorageError(err)
 	return err
 }
 
 func (az *AzStorage) CommitData(opt internal.CommitDataOptions) error {
-	err := az.storage.CommitBlocks(az.ctx, opt.Name, opt.List, opt.NewETag)
+	if err := az.checkWritable(opt.Name); err != nil {
+		return err
+	}
+	err := az.storage.CommitBlocks(az.ctx, opt.Name, opt.List, opt.NewETag, opt.Metadata)
 	err = az.handleStorageError(err)
 	return err
 }
@@ -43,6 +43,10 @@ const (
 	createLink   = "CreateLink"
 	readLink     = "ReadLink"
 	chmod        = "Chmod"
+	setMetadata  = "SetMetadata"
+	utimens      = "Utimens"
+
+	cloudConnected = "CloudConnected"
 
 	openHandles = "OpenFileHandles"
 	mode        = "Mode"
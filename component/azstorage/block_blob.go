@@ -31,6 +31,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"math"
 	"os"
 	"path/filepath"
@@ -72,6 +73,7 @@ type BlockBlob struct {
 	downloadOptions *blob.DownloadFileOptions
 	listDetails     container.ListBlobsInclude
 	blockLocks      common.KeyedMutex
+	snapshotTimes   snapshotTimes
 }
 
 // Verify that BlockBlob implements AzConnection interface
@@ -113,6 +115,8 @@ func (bb *BlockBlob) UpdateConfig(cfg AzStorageConfig) error {
 	bb.Config.blockSize = cfg.blockSize
 	bb.Config.maxConcurrency = cfg.maxConcurrency
 	bb.Config.defaultTier = cfg.defaultTier
+	bb.Config.tierRules = cfg.tierRules
+	bb.Config.headerRules = cfg.headerRules
 	bb.Config.ignoreAccessModifiers = cfg.ignoreAccessModifiers
 	return nil
 }
@@ -390,7 +394,7 @@ func (bb *BlockBlob) RenameFile(
 		ctx,
 		blobClient.URL(),
 		&blob.StartCopyFromURLOptions{
-			Tier: bb.Config.defaultTier,
+			Tier: bb.copyTier(target, srcAttr),
 		},
 	)
 
@@ -434,6 +438,13 @@ func (bb *BlockBlob) RenameFile(
 	}
 
 	if copyStatus != nil && *copyStatus == blob.CopyStatusTypeSuccess {
+		// a copy keeps the headers of its source
+		lmt, etag, err := bb.applyHeaderRules(ctx, target)
+		if err != nil {
+			log.Warn("BlockBlob::RenameFile : Failed to set headers of %s [%s]", target, err.Error())
+		} else if lmt != nil {
+			dstLMT, dstETag = lmt, etag
+		}
 		modifyLMTandEtag(srcAttr, dstLMT, dstETag)
 	}
 
@@ -570,6 +581,9 @@ func (bb *BlockBlob) getAttrUsingRest(
 		MD5:    prop.ContentMD5,
 		ETag:   sanitizeEtag(prop.ETag),
 	}
+	if prop.AccessTier != nil {
+		attr.Tier = *prop.AccessTier
+	}
 
 	parseMetadata(attr, prop.Metadata)
 
@@ -651,6 +665,15 @@ func (bb *BlockBlob) getAttrUsingList(
 func (bb *BlockBlob) GetAttr(ctx context.Context, name string) (attr *internal.ObjAttr, err error) {
 	log.Trace("BlockBlob::GetAttr : name %s", name)
 
+	if bb.Config.enableSnapshots {
+		if sp, inTree, err := parseSnapshotPath(name); inTree {
+			if err != nil {
+				return nil, err
+			}
+			return bb.getSnapshotAttr(ctx, sp)
+		}
+	}
+
 	// To support virtual directories with no marker blob, we call list instead of get properties since list will not return a 404
 	if bb.Config.virtualDirectory {
 		attr, err = bb.getAttrUsingList(ctx, name)
@@ -689,6 +712,17 @@ func (bb *BlockBlob) List(
 		}
 	}(marker))
 
+	if bb.Config.enableSnapshots {
+		if sp, inTree, err := parseSnapshotPath(prefix); inTree {
+			if err != nil {
+				return nil, nil, err
+			}
+			// a .snapshots tree is listed at once
+			list, err := bb.listSnapshotTree(ctx, sp)
+			return list, nil, err
+		}
+	}
+
 	if count == 0 {
 		count = common.MaxDirListCount
 	}
@@ -824,6 +858,9 @@ func (bb *BlockBlob) getBlobAttr(
 		MD5:   blobInfo.Properties.ContentMD5,
 		ETag:  sanitizeEtag(blobInfo.Properties.ETag),
 	}
+	if blobInfo.Properties.AccessTier != nil {
+		attr.Tier = string(*blobInfo.Properties.AccessTier)
+	}
 
 	parseMetadata(attr, blobInfo.Metadata)
 	if !bb.listDetails.Permissions {
@@ -977,7 +1014,10 @@ func (bb *BlockBlob) ReadToFile(
 	log.Trace("BlockBlob::ReadToFile : name %s, offset : %d, count %d", name, offset, count)
 	//defer exectime.StatTimeCurrentBlock("BlockBlob::ReadToFile")()
 
-	blobClient := bb.getBlobClient(name)
+	blobClient, err := bb.getReadBlobClient(name)
+	if err != nil {
+		return err
+	}
 
 	downloadPtr := new(int64(1))
 
@@ -999,9 +1039,13 @@ func (bb *BlockBlob) ReadToFile(
 
 	if err != nil {
 		e := storeBlobErrToErr(err)
-		if e == ErrFileNotFound {
+		switch e {
+		case ErrFileNotFound:
 			return syscall.ENOENT
-		} else {
+		case BlobArchived, BlobRehydrating:
+			log.Err("BlockBlob::ReadToFile : %s is archived [%s]", name, err.Error())
+			return common.NewArchivedError(err, e == BlobRehydrating)
+		default:
 			log.Err("BlockBlob::ReadToFile : Failed to download blob %s [%s]", name, err.Error())
 			return err
 		}
@@ -1063,8 +1107,11 @@ func (bb *BlockBlob) ReadBuffer(
 		length = attr.Size - offset
 	}
 
+	blobClient, err := bb.getReadBlobClient(name)
+	if err != nil {
+		return buff, err
+	}
 	buff = make([]byte, length)
-	blobClient := bb.getBlobClient(name)
 
 	dlOpts := (blob.DownloadBufferOptions)(*bb.downloadOptions)
 	dlOpts.Range = blob.HTTPRange{
@@ -1072,7 +1119,7 @@ func (bb *BlockBlob) ReadBuffer(
 		Count:  length,
 	}
 
-	_, err := blobClient.DownloadBuffer(ctx, buff, &dlOpts)
+	_, err = blobClient.DownloadBuffer(ctx, buff, &dlOpts)
 
 	if err != nil {
 		e := storeBlobErrToErr(err)
@@ -1081,6 +1128,9 @@ func (bb *BlockBlob) ReadBuffer(
 			return buff, syscall.ENOENT
 		case InvalidRange:
 			return buff, syscall.ERANGE
+		case BlobArchived, BlobRehydrating:
+			log.Err("BlockBlob::ReadBuffer : %s is archived [%s]", name, err.Error())
+			return buff, common.NewArchivedError(err, e == BlobRehydrating)
 		}
 
 		log.Err("BlockBlob::ReadBuffer : Failed to download blob %s [%s]", name, err.Error())
@@ -1104,7 +1154,10 @@ func (bb *BlockBlob) ReadInBuffer(
 		*etag = ""
 	}
 
-	blobClient := bb.getBlobClient(name)
+	blobClient, err := bb.getReadBlobClient(name)
+	if err != nil {
+		return err
+	}
 	ctx, cancel := context.WithTimeout(ctx, max_context_timeout*time.Minute)
 	defer cancel()
 
@@ -1125,6 +1178,9 @@ func (bb *BlockBlob) ReadInBuffer(
 			return syscall.ENOENT
 		case InvalidRange:
 			return syscall.ERANGE
+		case BlobArchived, BlobRehydrating:
+			log.Err("BlockBlob::ReadInBuffer : %s is archived [%s]", name, err.Error())
+			return common.NewArchivedError(err, e == BlobRehydrating)
 		}
 
 		log.Err(
@@ -1236,12 +1292,14 @@ func trackUpload(name string, bytesTransferred int64, count int64, uploadPtr *in
 	}
 }
 
-// WriteFromFile : Upload local file to blob
+// WriteFromFile : Upload local file to blob, on the condition in ifMatch if it is set
 func (bb *BlockBlob) WriteFromFile(
 	ctx context.Context,
 	name string,
 	metadata map[string]*string,
 	fi *os.File,
+	ifMatch *string,
+	newETag *string,
 ) (err error) {
 	log.Trace("BlockBlob::WriteFromFile : name %s", name)
 	//defer exectime.StatTimeCurrentBlock("WriteFromFile::WriteFromFile")()
@@ -1281,36 +1339,55 @@ func (bb *BlockBlob) WriteFromFile(
 		}
 	}
 
+	metadata, err = bb.keepMetadata(ctx, blobClient, metadata)
+	if err != nil {
+		log.Err("BlockBlob::WriteFromFile : Failed to get metadata of %s [%s]", name, err.Error())
+		return err
+	}
+
+	httpHeaders := bb.httpHeaders(name)
+	httpHeaders.BlobContentMD5 = md5sum
 	uploadOptions := &blockblob.UploadFileOptions{
 		BlockSize:   blockSize,
 		Concurrency: bb.Config.maxConcurrency,
-		Metadata:    metadata,
-		AccessTier:  bb.Config.defaultTier,
-		HTTPHeaders: &blob.HTTPHeaders{
-			BlobContentType: new(getContentType(name)),
-			BlobContentMD5:  md5sum,
-		},
-		CPKInfo: bb.blobCPKOpt,
+		Metadata:    bb.withRuleMetadata(name, metadata),
+		AccessTier:  bb.uploadTier(name),
+		HTTPHeaders: httpHeaders,
+		CPKInfo:     bb.blobCPKOpt,
 	}
 	if common.MonitorCfs() && stat.Size() > 0 {
 		uploadOptions.Progress = func(bytesTransferred int64) {
 			trackUpload(name, bytesTransferred, stat.Size(), uploadPtr)
 		}
 	}
+	if ifMatch != nil {
+		conditions := &blob.ModifiedAccessConditions{IfNoneMatch: to.Ptr(azcore.ETagAny)}
+		if *ifMatch != "" {
+			conditions = &blob.ModifiedAccessConditions{
+				IfMatch: to.Ptr(azcore.ETag(`"` + *ifMatch + `"`)),
+			}
+		}
+		uploadOptions.AccessConditions = &blob.AccessConditions{
+			ModifiedAccessConditions: conditions,
+		}
+	}
 
-	_, err = blobClient.UploadFile(ctx, fi, uploadOptions)
+	resp, err := blobClient.UploadFile(ctx, fi, uploadOptions)
 
 	if err != nil {
 		serr := storeBlobErrToErr(err)
-		switch serr {
-		case BlobIsUnderLease:
+		switch {
+		case ifMatch != nil && (serr == ConditionNotMet || serr == ErrFileAlreadyExists):
+			log.Warn("BlockBlob::WriteFromFile : %s was changed, not uploading [%s]", name, err)
+			return common.NewConflictError(err)
+		case serr == BlobIsUnderLease:
 			log.Err(
 				"BlockBlob::WriteFromFile : %s is under a lease, can not update file [%s]",
 				name,
 				err.Error(),
 			)
 			return syscall.EIO
-		case InvalidPermission:
+		case serr == InvalidPermission:
 			log.Err(
 				"BlockBlob::WriteFromFile : Insufficient permissions for %s [%s]",
 				name,
@@ -1323,6 +1400,9 @@ func (bb *BlockBlob) WriteFromFile(
 		return err
 	} else {
 		log.Debug("BlockBlob::WriteFromFile : Upload complete of blob %v", name)
+		if newETag != nil {
+			*newETag = sanitizeEtag(resp.ETag)
+		}
 
 		// store total bytes uploaded so far
 		if stat.Size() > 0 {
@@ -1348,12 +1428,10 @@ func (bb *BlockBlob) WriteFromBuffer(
 	_, err := blobClient.UploadBuffer(ctx, data, &blockblob.UploadBufferOptions{
 		BlockSize:   bb.Config.blockSize,
 		Concurrency: bb.Config.maxConcurrency,
-		Metadata:    metadata,
-		AccessTier:  bb.Config.defaultTier,
-		HTTPHeaders: &blob.HTTPHeaders{
-			BlobContentType: new(getContentType(name)),
-		},
-		CPKInfo: bb.blobCPKOpt,
+		Metadata:    bb.withRuleMetadata(name, metadata),
+		AccessTier:  bb.uploadTier(name),
+		HTTPHeaders: bb.httpHeaders(name),
+		CPKInfo:     bb.blobCPKOpt,
 	})
 
 	if err != nil {
@@ -1998,11 +2076,10 @@ func (bb *BlockBlob) stageAndCommitModifiedBlocks(
 	_, err := blobClient.CommitBlockList(ctx,
 		blockIDList,
 		&blockblob.CommitBlockListOptions{
-			HTTPHeaders: &blob.HTTPHeaders{
-				BlobContentType: new(getContentType(name)),
-			},
-			Tier:    bb.Config.defaultTier,
-			CPKInfo: bb.blobCPKOpt,
+			HTTPHeaders: bb.httpHeaders(name),
+			Metadata:    bb.withRuleMetadata(name, nil),
+			Tier:        bb.uploadTier(name),
+			CPKInfo:     bb.blobCPKOpt,
 		})
 
 	if err != nil {
@@ -2087,11 +2164,10 @@ func (bb *BlockBlob) StageAndCommit(
 		_, err := blobClient.CommitBlockList(ctx,
 			blockIDList,
 			&blockblob.CommitBlockListOptions{
-				HTTPHeaders: &blob.HTTPHeaders{
-					BlobContentType: new(getContentType(name)),
-				},
-				Tier:    bb.Config.defaultTier,
-				CPKInfo: bb.blobCPKOpt,
+				HTTPHeaders: bb.httpHeaders(name),
+				Metadata:    bb.withRuleMetadata(name, nil),
+				Tier:        bb.uploadTier(name),
+				CPKInfo:     bb.blobCPKOpt,
 				// AccessConditions: &blob.AccessConditions{ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: bol.Etag}},
 			})
 		if err != nil {
@@ -2108,6 +2184,173 @@ func (bb *BlockBlob) StageAndCommit(
 	return nil
 }
 
+// GetMetadata : Get the user metadata of a blob, internal keys are not returned
+func (bb *BlockBlob) GetMetadata(ctx context.Context, name string) (map[string]*string, error) {
+	log.Trace("BlockBlob::GetMetadata : name %s", name)
+
+	prop, err := bb.getBlobClient(name).GetProperties(ctx, &blob.GetPropertiesOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+	if err != nil {
+		err = bb.metadataError(ctx, name, err, "GetMetadata")
+		if err == syscall.ENOTSUP {
+			// directories without a marker blob simply have no metadata
+			return map[string]*string{}, nil
+		}
+		return nil, err
+	}
+
+	metadata := make(map[string]*string, len(prop.Metadata))
+	for key, value := range prop.Metadata {
+		if !isInternalMetadataKey(key) {
+			metadata[key] = value
+		}
+	}
+	archiveMetadata(prop.AccessTier, prop.ArchiveStatus, metadata)
+	return metadata, nil
+}
+
+// SetMetadata : Merge the given user metadata into the metadata of a blob.
+// Keys are case-insensitive and a nil value removes the key.
+func (bb *BlockBlob) SetMetadata(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+) error {
+	log.Trace("BlockBlob::SetMetadata : name %s", name)
+
+	// control keys trigger an action instead of being stored
+	isSnapshotKey := func(key string) bool {
+		return bb.Config.enableSnapshots && strings.EqualFold(key, snapshotMetadataKey)
+	}
+	snapshot, restore := false, false
+	for key, value := range metadata {
+		switch {
+		case isSnapshotKey(key):
+			snapshot = snapshot || value != nil
+		case strings.EqualFold(key, internal.RestoreMetadataKey) && value != nil:
+			restore = true
+		case isInternalMetadataKey(key) || !isValidMetadataKey(key):
+			log.Err("BlockBlob::SetMetadata : Invalid metadata key %s for %s", key, name)
+			return syscall.EINVAL
+		}
+	}
+
+	if snapshot {
+		err := bb.createSnapshot(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+	if restore {
+		err := bb.rehydrate(ctx, name)
+		if err != nil {
+			return err
+		}
+	}
+	if snapshot || restore {
+		metadata = maps.Clone(metadata)
+		maps.DeleteFunc(metadata, func(key string, _ *string) bool {
+			return isSnapshotKey(key) || internal.IsArchiveMetadataKey(key)
+		})
+		if len(metadata) == 0 {
+			return nil
+		}
+	}
+
+	return bb.updateMetadata(ctx, name, metadata, "SetMetadata")
+}
+
+// Utimens : Store the access and modification times in the metadata of a blob.
+// A zero time is left unchanged. A directory without a marker blob has nowhere to keep them.
+func (bb *BlockBlob) Utimens(ctx context.Context, name string, atime, mtime time.Time) error {
+	log.Trace("BlockBlob::Utimens : name %s", name)
+
+	metadata := make(map[string]*string, 2)
+	if !atime.IsZero() {
+		metadata[internal.AtimeMetadataKey] = new(internal.FormatMetadataTime(atime))
+	}
+	if !mtime.IsZero() {
+		metadata[internal.MtimeMetadataKey] = new(internal.FormatMetadataTime(mtime))
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	err := bb.updateMetadata(ctx, name, metadata, "Utimens")
+	if err == syscall.ENOTSUP {
+		return nil
+	}
+	return err
+}
+
+// updateMetadata merges the given metadata into the metadata of a blob.
+// Keys are case-insensitive and a nil value removes the key.
+func (bb *BlockBlob) updateMetadata(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+	op string,
+) error {
+	blobClient := bb.getBlobClient(name)
+	prop, err := blobClient.GetProperties(ctx, &blob.GetPropertiesOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+	if err != nil {
+		return bb.metadataError(ctx, name, err, op)
+	}
+
+	newMetadata := make(map[string]*string, len(prop.Metadata)+len(metadata))
+	for key, value := range prop.Metadata {
+		newMetadata[key] = value
+	}
+	for key, value := range metadata {
+		for existing := range newMetadata {
+			if strings.EqualFold(existing, key) {
+				delete(newMetadata, existing)
+			}
+		}
+		if value != nil {
+			newMetadata[key] = value
+		}
+	}
+
+	// fail instead of overwriting the metadata if the blob changed since we read it
+	_, err = blobClient.SetMetadata(ctx, newMetadata, &blob.SetMetadataOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: prop.ETag},
+		},
+		CPKInfo: bb.blobCPKOpt,
+	})
+	if err != nil {
+		return bb.metadataError(ctx, name, err, op)
+	}
+	return nil
+}
+
+// metadataError converts the error from a metadata operation on a blob.
+// A directory with no marker blob exists but has nowhere to keep metadata.
+func (bb *BlockBlob) metadataError(ctx context.Context, name string, err error, op string) error {
+	switch storeBlobErrToErr(err) {
+	case ErrFileNotFound:
+		attr, attrErr := bb.GetAttr(ctx, name)
+		if attrErr != nil {
+			return attrErr
+		}
+		if attr.IsDir() {
+			log.Debug("BlockBlob::%s : %s is a directory without a marker blob", op, name)
+			return syscall.ENOTSUP
+		}
+		return syscall.ENOENT
+	case InvalidPermission:
+		log.Err("BlockBlob::%s : Insufficient permissions for %s [%s]", op, name, err.Error())
+		return syscall.EACCES
+	default:
+		log.Err("BlockBlob::%s : Failed for %s [%s]", op, name, err.Error())
+		return err
+	}
+}
+
 // ChangeMod : Change mode of a blob
 func (bb *BlockBlob) ChangeMod(ctx context.Context, name string, _ os.FileMode) error {
 	log.Trace("BlockBlob::ChangeMod : name %s", name)
@@ -2210,12 +2453,13 @@ func (bb *BlockBlob) StageBlock(ctx context.Context, name string, data []byte, i
 	return nil
 }
 
-// CommitBlocks : persists the block list
+// CommitBlocks : persists the block list, with the given metadata
 func (bb *BlockBlob) CommitBlocks(
 	ctx context.Context,
 	name string,
 	blockList []string,
 	newEtag *string,
+	metadata map[string]*string,
 ) error {
 	log.Trace("BlockBlob::CommitBlocks : name %s", name)
 
@@ -2225,14 +2469,18 @@ func (bb *BlockBlob) CommitBlocks(
 	blobClient := bb.Container.NewBlockBlobClient(
 		common.JoinUnixFilepath(bb.Config.prefixPath, name),
 	)
+	metadata, err := bb.keepMetadata(ctx, blobClient, metadata)
+	if err != nil {
+		log.Err("BlockBlob::CommitBlocks : Failed to get metadata of %s [%s]", name, err.Error())
+		return err
+	}
 	resp, err := blobClient.CommitBlockList(ctx,
 		blockList,
 		&blockblob.CommitBlockListOptions{
-			HTTPHeaders: &blob.HTTPHeaders{
-				BlobContentType: new(getContentType(name)),
-			},
-			Tier:    bb.Config.defaultTier,
-			CPKInfo: bb.blobCPKOpt,
+			HTTPHeaders: bb.httpHeaders(name),
+			Metadata:    bb.withRuleMetadata(name, metadata),
+			Tier:        bb.uploadTier(name),
+			CPKInfo:     bb.blobCPKOpt,
 		})
 
 	if err != nil {
@@ -2251,6 +2499,30 @@ func (bb *BlockBlob) CommitBlocks(
 	return nil
 }
 
+// keepMetadata returns the metadata of an upload replacing a blob, with the metadata of the blob
+// being replaced carried over, as Azure replaces all the metadata of a blob on upload
+func (bb *BlockBlob) keepMetadata(
+	ctx context.Context,
+	blobClient *blockblob.Client,
+	metadata map[string]*string,
+) (map[string]*string, error) {
+	existing := make(map[string]*string)
+	prop, err := blobClient.GetProperties(ctx, &blob.GetPropertiesOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+	switch {
+	case err == nil:
+		for key, value := range prop.Metadata {
+			if !strings.EqualFold(key, folderKey) && !strings.EqualFold(key, symlinkKey) {
+				existing[key] = value
+			}
+		}
+	case storeBlobErrToErr(err) != ErrFileNotFound:
+		return nil, err
+	}
+	return internal.KeepMetadata(existing, metadata), nil
+}
+
 // getBlobClient returns a new blob url. On Windows this will also convert special characters.
 func (bb *BlockBlob) getBlobClient(name string) *blob.Client {
 	return bb.Container.NewBlobClient(bb.getFormattedPath(name))
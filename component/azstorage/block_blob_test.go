@@ -1409,6 +1409,144 @@ func (s *blockBlobTestSuite) TestRenameFileMetadataConservation() {
 	s.assert.True(checkMetadata(props.Metadata, "foo", "bar"))
 }
 
+func (s *blockBlobTestSuite) TestGetSetMetadata() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	blobClient := s.containerClient.NewBlobClient(name)
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+	_, err = blobClient.SetMetadata(ctx, map[string]*string{"Foo": new("bar")}, nil)
+	s.assert.NoError(err)
+
+	metadata, err := s.az.GetMetadata(internal.GetMetadataOptions{Name: name})
+	s.assert.NoError(err)
+	s.assert.True(checkMetadata(metadata, "foo", "bar"))
+
+	// keys are case-insensitive, so this replaces Foo
+	err = s.az.SetMetadata(internal.SetMetadataOptions{
+		Name:     name,
+		Metadata: map[string]*string{"foo": new("baz"), "label": new("a")},
+	})
+	s.assert.NoError(err)
+	props, err := blobClient.GetProperties(ctx, nil)
+	s.assert.NoError(err)
+	s.assert.Len(props.Metadata, 2)
+	s.assert.True(checkMetadata(props.Metadata, "foo", "baz"))
+	s.assert.True(checkMetadata(props.Metadata, "label", "a"))
+
+	err = s.az.SetMetadata(internal.SetMetadataOptions{
+		Name:     name,
+		Metadata: map[string]*string{"FOO": nil},
+	})
+	s.assert.NoError(err)
+	props, err = blobClient.GetProperties(ctx, nil)
+	s.assert.NoError(err)
+	s.assert.Len(props.Metadata, 1)
+}
+
+func (s *blockBlobTestSuite) TestUtimens() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+
+	atime := time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC)
+	mtime := atime.Add(time.Hour)
+	err = s.az.Utimens(internal.UtimensOptions{Name: name, Atime: atime, Mtime: mtime})
+	s.assert.NoError(err)
+
+	attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: name})
+	s.assert.NoError(err)
+	s.assert.True(atime.Equal(attr.Atime))
+	s.assert.True(mtime.Equal(attr.Mtime))
+	// the times are not user metadata
+	metadata, err := s.az.GetMetadata(internal.GetMetadataOptions{Name: name})
+	s.assert.NoError(err)
+	s.assert.Empty(metadata)
+
+	// the file is written and flushed again, which keeps the access time
+	f, err := os.CreateTemp("", name+".tmp")
+	s.assert.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write([]byte("new data"))
+	s.assert.NoError(err)
+	newMtime := mtime.Add(time.Hour)
+	err = s.az.CopyFromFile(internal.CopyFromFileOptions{
+		Name: name,
+		File: f,
+		Metadata: map[string]*string{
+			internal.MtimeMetadataKey: new(internal.FormatMetadataTime(newMtime)),
+		},
+	})
+	s.assert.NoError(err)
+	attr, err = s.az.GetAttr(internal.GetAttrOptions{Name: name})
+	s.assert.NoError(err)
+	s.assert.True(atime.Equal(attr.Atime))
+	s.assert.True(newMtime.Equal(attr.Mtime))
+
+	err = s.az.Utimens(internal.UtimensOptions{Name: generateFileName(), Mtime: mtime})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *blockBlobTestSuite) TestCopyFromFileKeepsMetadata() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+	err = s.az.SetMetadata(internal.SetMetadataOptions{
+		Name:     name,
+		Metadata: map[string]*string{"label": new("a")},
+	})
+	s.assert.NoError(err)
+
+	// the file is written and flushed again, with the modification time of the local copy
+	f, err := os.CreateTemp("", name+".tmp")
+	s.assert.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write([]byte("new data"))
+	s.assert.NoError(err)
+	mtime := time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC)
+	err = s.az.CopyFromFile(internal.CopyFromFileOptions{
+		Name: name,
+		File: f,
+		Metadata: map[string]*string{
+			internal.MtimeMetadataKey: new(internal.FormatMetadataTime(mtime)),
+		},
+	})
+	s.assert.NoError(err)
+
+	metadata, err := s.az.GetMetadata(internal.GetMetadataOptions{Name: name})
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+	attr, err := s.az.GetAttr(internal.GetAttrOptions{Name: name})
+	s.assert.NoError(err)
+	s.assert.True(mtime.Equal(attr.Mtime))
+}
+
+func (s *blockBlobTestSuite) TestSetMetadataInvalid() {
+	defer s.cleanupTest()
+	// Setup
+	name := generateFileName()
+	_, err := s.az.CreateFile(internal.CreateFileOptions{Name: name})
+	s.assert.NoError(err)
+
+	for _, key := range []string{"bad-key", "1key", folderKey, symlinkKey, "Mtime"} {
+		err = s.az.SetMetadata(internal.SetMetadataOptions{
+			Name:     name,
+			Metadata: map[string]*string{key: new("value")},
+		})
+		s.assert.Equal(syscall.EINVAL, err, key)
+	}
+
+	_, err = s.az.GetMetadata(internal.GetMetadataOptions{Name: generateFileName()})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
 func (s *blockBlobTestSuite) TestRenameFileError() {
 	defer s.cleanupTest()
 	// Setup
@@ -3704,7 +3842,7 @@ func (s *blockBlobTestSuite) TestMD5SetOnUpload() {
 			s.assert.Equal(blockblob.MaxUploadBlobBytes+1, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 
 			prop, err := s.az.storage.GetAttr(ctx, name)
@@ -3767,7 +3905,7 @@ func (s *blockBlobTestSuite) TestMD5NotSetOnUpload() {
 			s.assert.Equal(blockblob.MaxUploadBlobBytes+1, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 
 			prop, err := s.az.storage.GetAttr(ctx, name)
@@ -3825,7 +3963,7 @@ func (s *blockBlobTestSuite) TestMD5AutoSetOnUpload() {
 			s.assert.Equal(100, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 
 			prop, err := s.az.storage.GetAttr(ctx, name)
@@ -3888,7 +4026,7 @@ func (s *blockBlobTestSuite) TestInvalidateMD5PostUpload() {
 			s.assert.Equal(100, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 
 			blobClient := s.containerClient.NewBlobClient(name)
@@ -3958,7 +4096,7 @@ func (s *blockBlobTestSuite) TestValidateAutoMD5OnRead() {
 			s.assert.Equal(100, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 			_ = f.Close()
 			_ = os.Remove(name)
@@ -4024,7 +4162,7 @@ func (s *blockBlobTestSuite) TestValidateManualMD5OnRead() {
 			s.assert.Equal(blockblob.MaxUploadBlobBytes+1, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 			_ = f.Close()
 			_ = os.Remove(name)
@@ -4090,7 +4228,7 @@ func (s *blockBlobTestSuite) TestInvalidMD5OnRead() {
 			s.assert.Equal(100, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 			_ = f.Close()
 			_ = os.Remove(name)
@@ -4164,7 +4302,7 @@ func (s *blockBlobTestSuite) TestInvalidMD5OnReadNoVaildate() {
 			s.assert.Equal(100, n)
 			_, _ = f.Seek(0, 0)
 
-			err = s.az.storage.WriteFromFile(ctx, name, nil, f)
+			err = s.az.storage.WriteFromFile(ctx, name, nil, f, nil, nil)
 			s.assert.NoError(err)
 			_ = f.Close()
 			_ = os.Remove(name)
@@ -4268,7 +4406,7 @@ func (s *blockBlobTestSuite) TestInvalidMD5OnReadNoVaildate() {
 // 	s.assert.Nil(err)
 // 	_, _ = f.Seek(0, 0)
 
-// 	err = s.az.storage.WriteFromFile(ctx, name1, nil, f)
+// 	err = s.az.storage.WriteFromFile(ctx, name1, nil, f, nil, nil)
 //  s.assert.Nil(err)
 
 // 	file := s.containerClient.NewBlobClient(name1)
@@ -28,12 +28,16 @@ package azstorage
 import (
 	"errors"
 	"fmt"
+	"path"
+	"slices"
 	"strings"
 
 	"github.com/Seagate/cloudfuse/common/config"
 	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
 	"github.com/awnumar/memguard"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
 	"github.com/vibhansa-msft/blobfilter"
 )
@@ -183,50 +187,61 @@ const (
 )
 
 type AzStorageOptions struct {
-	AccountType             string `config:"type"                          yaml:"type,omitempty"`
-	UseHTTP                 bool   `config:"use-http"                      yaml:"use-http,omitempty"`
-	AccountName             string `config:"account-name"                  yaml:"account-name,omitempty"`
-	AccountKey              string `config:"account-key"                   yaml:"account-key,omitempty"`
-	SaSKey                  string `config:"sas"                           yaml:"sas,omitempty"`
-	ApplicationID           string `config:"appid"                         yaml:"appid,omitempty"`
-	ResourceID              string `config:"resid"                         yaml:"resid,omitempty"`
-	ObjectID                string `config:"objid"                         yaml:"objid,omitempty"`
-	TenantID                string `config:"tenantid"                      yaml:"tenantid,omitempty"`
-	ClientID                string `config:"clientid"                      yaml:"clientid,omitempty"`
-	ClientSecret            string `config:"clientsecret"                  yaml:"clientsecret,omitempty"`
-	OAuthTokenFilePath      string `config:"oauth-token-path"              yaml:"oauth-token-path,omitempty"`
-	WorkloadIdentityToken   string `config:"workload-identity-token"       yaml:"workload-identity-token,omitempty"`
-	ActiveDirectoryEndpoint string `config:"aadendpoint"                   yaml:"aadendpoint,omitempty"`
-	Endpoint                string `config:"endpoint"                      yaml:"endpoint,omitempty"`
-	AuthMode                string `config:"mode"                          yaml:"mode,omitempty"`
-	Container               string `config:"container"                     yaml:"container,omitempty"`
-	PrefixPath              string `config:"subdirectory"                  yaml:"subdirectory,omitempty"`
-	BlockSize               int64  `config:"block-size-mb"                 yaml:"block-size-mb,omitempty"`
-	MaxConcurrency          uint16 `config:"max-concurrency"               yaml:"max-concurrency,omitempty"`
-	DefaultTier             string `config:"tier"                          yaml:"tier,omitempty"`
-	CancelListForSeconds    uint16 `config:"block-list-on-mount-sec"       yaml:"block-list-on-mount-sec,omitempty"`
-	MaxRetries              int32  `config:"max-retries"                   yaml:"max-retries,omitempty"`
-	MaxTimeout              int32  `config:"max-retry-timeout-sec"         yaml:"max-retry-timeout-sec,omitempty"`
-	BackoffTime             int32  `config:"retry-backoff-sec"             yaml:"retry-backoff-sec,omitempty"`
-	MaxRetryDelay           int32  `config:"max-retry-delay-sec"           yaml:"max-retry-delay-sec,omitempty"`
-	HttpProxyAddress        string `config:"http-proxy"                    yaml:"http-proxy,omitempty"`
-	HttpsProxyAddress       string `config:"https-proxy"                   yaml:"https-proxy,omitempty"`
-	FailUnsupportedOp       bool   `config:"fail-unsupported-op"           yaml:"fail-unsupported-op,omitempty"`
-	AuthResourceString      string `config:"auth-resource"                 yaml:"auth-resource,omitempty"`
-	UpdateMD5               bool   `config:"update-md5"                    yaml:"update-md5"`
-	ValidateMD5             bool   `config:"validate-md5"                  yaml:"validate-md5"`
-	VirtualDirectory        bool   `config:"virtual-directory"             yaml:"virtual-directory"`
-	MaxResultsForList       int32  `config:"max-results-for-list"          yaml:"max-results-for-list"`
-	DisableCompression      bool   `config:"disable-compression"           yaml:"disable-compression"`
-	Telemetry               string `config:"telemetry"                     yaml:"telemetry"`
-	HonourACL               bool   `config:"honour-acl"                    yaml:"honour-acl"`
-	RestrictedCharsWin      bool   `config:"restricted-characters-windows" yaml:"-"`
-	CPKEnabled              bool   `config:"cpk-enabled"                   yaml:"cpk-enabled"`
-	CPKEncryptionKey        string `config:"cpk-encryption-key"            yaml:"cpk-encryption-key"`
-	CPKEncryptionKeySha256  string `config:"cpk-encryption-key-sha256"     yaml:"cpk-encryption-key-sha256"`
-	PreserveACL             bool   `config:"preserve-acl"                  yaml:"preserve-acl"`
-	Filter                  string `config:"filter"                        yaml:"filter"`
-	UserAssertion           string `config:"user-assertion"                yaml:"user-assertions"`
+	AccountType             string                `config:"type"                          yaml:"type,omitempty"`
+	UseHTTP                 bool                  `config:"use-http"                      yaml:"use-http,omitempty"`
+	AccountName             string                `config:"account-name"                  yaml:"account-name,omitempty"`
+	AccountKey              string                `config:"account-key"                   yaml:"account-key,omitempty"`
+	SaSKey                  string                `config:"sas"                           yaml:"sas,omitempty"`
+	ApplicationID           string                `config:"appid"                         yaml:"appid,omitempty"`
+	ResourceID              string                `config:"resid"                         yaml:"resid,omitempty"`
+	ObjectID                string                `config:"objid"                         yaml:"objid,omitempty"`
+	TenantID                string                `config:"tenantid"                      yaml:"tenantid,omitempty"`
+	ClientID                string                `config:"clientid"                      yaml:"clientid,omitempty"`
+	ClientSecret            string                `config:"clientsecret"                  yaml:"clientsecret,omitempty"`
+	OAuthTokenFilePath      string                `config:"oauth-token-path"              yaml:"oauth-token-path,omitempty"`
+	WorkloadIdentityToken   string                `config:"workload-identity-token"       yaml:"workload-identity-token,omitempty"`
+	ActiveDirectoryEndpoint string                `config:"aadendpoint"                   yaml:"aadendpoint,omitempty"`
+	Endpoint                string                `config:"endpoint"                      yaml:"endpoint,omitempty"`
+	AuthMode                string                `config:"mode"                          yaml:"mode,omitempty"`
+	Container               string                `config:"container"                     yaml:"container,omitempty"`
+	PrefixPath              string                `config:"subdirectory"                  yaml:"subdirectory,omitempty"`
+	BlockSize               int64                 `config:"block-size-mb"                 yaml:"block-size-mb,omitempty"`
+	MaxConcurrency          uint16                `config:"max-concurrency"               yaml:"max-concurrency,omitempty"`
+	DefaultTier             string                `config:"tier"                          yaml:"tier,omitempty"`
+	CancelListForSeconds    uint16                `config:"block-list-on-mount-sec"       yaml:"block-list-on-mount-sec,omitempty"`
+	MaxRetries              int32                 `config:"max-retries"                   yaml:"max-retries,omitempty"`
+	MaxTimeout              int32                 `config:"max-retry-timeout-sec"         yaml:"max-retry-timeout-sec,omitempty"`
+	BackoffTime             int32                 `config:"retry-backoff-sec"             yaml:"retry-backoff-sec,omitempty"`
+	MaxRetryDelay           int32                 `config:"max-retry-delay-sec"           yaml:"max-retry-delay-sec,omitempty"`
+	HttpProxyAddress        string                `config:"http-proxy"                    yaml:"http-proxy,omitempty"`
+	HttpsProxyAddress       string                `config:"https-proxy"                   yaml:"https-proxy,omitempty"`
+	FailUnsupportedOp       bool                  `config:"fail-unsupported-op"           yaml:"fail-unsupported-op,omitempty"`
+	AuthResourceString      string                `config:"auth-resource"                 yaml:"auth-resource,omitempty"`
+	UpdateMD5               bool                  `config:"update-md5"                    yaml:"update-md5"`
+	ValidateMD5             bool                  `config:"validate-md5"                  yaml:"validate-md5"`
+	VirtualDirectory        bool                  `config:"virtual-directory"             yaml:"virtual-directory"`
+	MaxResultsForList       int32                 `config:"max-results-for-list"          yaml:"max-results-for-list"`
+	DisableCompression      bool                  `config:"disable-compression"           yaml:"disable-compression"`
+	Telemetry               string                `config:"telemetry"                     yaml:"telemetry"`
+	HonourACL               bool                  `config:"honour-acl"                    yaml:"honour-acl"`
+	RestrictedCharsWin      bool                  `config:"restricted-characters-windows" yaml:"-"`
+	CPKEnabled              bool                  `config:"cpk-enabled"                   yaml:"cpk-enabled"`
+	CPKEncryptionKey        string                `config:"cpk-encryption-key"            yaml:"cpk-encryption-key"`
+	CPKEncryptionKeySha256  string                `config:"cpk-encryption-key-sha256"     yaml:"cpk-encryption-key-sha256"`
+	PreserveACL             bool                  `config:"preserve-acl"                  yaml:"preserve-acl"`
+	Filter                  string                `config:"filter"                        yaml:"filter"`
+	UserAssertion           string                `config:"user-assertion"                yaml:"user-assertions"`
+	EnableSnapshots         bool                  `config:"enable-snapshots"              yaml:"enable-snapshots"`
+	RehydrateTier           string                `config:"rehydrate-tier"                yaml:"rehydrate-tier,omitempty"`
+	RehydratePriority       string                `config:"rehydrate-priority"            yaml:"rehydrate-priority,omitempty"`
+	AccessTierRules         []AccessTierRule      `config:"access-tier-rules"             yaml:"access-tier-rules,omitempty"`
+	HeaderRules             []internal.HeaderRule `config:"header-rules"                  yaml:"header-rules,omitempty"`
+}
+
+// AccessTierRule sets the access tier of the blobs uploaded to paths matching Pattern
+type AccessTierRule struct {
+	Pattern    string `config:"pattern"     yaml:"pattern"`
+	AccessTier string `config:"access-tier" yaml:"access-tier"`
 }
 
 // RegisterEnvVariables : Register environment variables
@@ -580,6 +595,30 @@ func ParseAndValidateConfig(az *AzStorage, opt AzStorageOptions) error {
 	}
 
 	az.stConfig.preserveACL = opt.PreserveACL
+	az.stConfig.enableSnapshots = opt.EnableSnapshots
+
+	az.stConfig.rehydrateTier = blob.AccessTierHot
+	if opt.RehydrateTier != "" {
+		tier := getAccessTierType(opt.RehydrateTier)
+		if tier == nil || !slices.Contains(
+			[]blob.AccessTier{blob.AccessTierHot, blob.AccessTierCool, blob.AccessTierCold},
+			*tier,
+		) {
+			return errors.New("invalid rehydrate-tier, valid values are hot, cool, cold")
+		}
+		az.stConfig.rehydrateTier = *tier
+	}
+	az.stConfig.rehydratePriority = blob.RehydratePriorityStandard
+	if opt.RehydratePriority != "" {
+		priorities := blob.PossibleRehydratePriorityValues()
+		i := slices.IndexFunc(priorities, func(priority blob.RehydratePriority) bool {
+			return strings.EqualFold(string(priority), opt.RehydratePriority)
+		})
+		if i < 0 {
+			return errors.New("invalid rehydrate-priority, valid values are standard, high")
+		}
+		az.stConfig.rehydratePriority = priorities[i]
+	}
 	if opt.Filter != "" {
 		err = configureBlobFilter(az, opt)
 		if err != nil {
@@ -666,6 +705,28 @@ func ParseAndReadDynamicConfig(az *AzStorage, opt AzStorageOptions, reload bool)
 	if opt.DefaultTier != "" {
 		az.stConfig.defaultTier = getAccessTierType(opt.DefaultTier)
 	}
+	az.stConfig.tierRules = nil
+	for _, rule := range opt.AccessTierRules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil || rule.Pattern == "" {
+			return fmt.Errorf("invalid access-tier-rules pattern %q", rule.Pattern)
+		}
+		tier := getAccessTierType(rule.AccessTier)
+		if tier == nil {
+			return fmt.Errorf("invalid access-tier %q for pattern %q", rule.AccessTier, rule.Pattern)
+		}
+		az.stConfig.tierRules = append(az.stConfig.tierRules, tierRule{rule.Pattern, tier})
+	}
+	if err := internal.ValidateHeaderRules(opt.HeaderRules); err != nil {
+		return err
+	}
+	for _, rule := range opt.HeaderRules {
+		for key := range rule.Metadata {
+			if !isValidMetadataKey(key) || isInternalMetadataKey(key) {
+				return fmt.Errorf("invalid metadata key %q for pattern %q", key, rule.Pattern)
+			}
+		}
+	}
+	az.stConfig.headerRules = opt.HeaderRules
 
 	az.stConfig.ignoreAccessModifiers = !opt.FailUnsupportedOp
 	az.stConfig.validateMD5 = opt.ValidateMD5
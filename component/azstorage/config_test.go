@@ -27,12 +27,16 @@ package azstorage
 
 import (
 	"fmt"
+	"syscall"
 	"testing"
 
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/config"
 	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -453,6 +457,127 @@ func (s *configTestSuite) TestSASRefresh() {
 	assert.NoError(err)
 }
 
+func (s *configTestSuite) TestEnableSnapshots() {
+	defer config.ResetConfig()
+	assert := assert.New(s.T())
+	az := &AzStorage{}
+	opt := AzStorageOptions{
+		AccountName: "abcd",
+		Container:   "abcd",
+		AuthMode:    "key",
+		AccountKey:  "abc",
+	}
+
+	err := ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.False(az.stConfig.enableSnapshots)
+	assert.NoError(az.checkWritable(".snapshots/20240102T030405.1234567Z/a.txt"))
+
+	opt.EnableSnapshots = true
+	err = ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.True(az.stConfig.enableSnapshots)
+	assert.NoError(az.checkWritable("dir/a.txt", "dir/b.txt"))
+	assert.Equal(syscall.EROFS, az.checkWritable("dir/a.txt", "dir/.snapshots"))
+	assert.Equal(
+		syscall.EROFS,
+		az.checkWritable("dir/.snapshots/20240102T030405.1234567Z/a.txt"),
+	)
+}
+
+func (s *configTestSuite) TestRehydrateOptions() {
+	defer config.ResetConfig()
+	assert := assert.New(s.T())
+	az := &AzStorage{}
+	opt := AzStorageOptions{
+		AccountName: "abcd",
+		Container:   "abcd",
+		AuthMode:    "key",
+		AccountKey:  "abc",
+	}
+
+	err := ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.Equal(blob.AccessTierHot, az.stConfig.rehydrateTier)
+	assert.Equal(blob.RehydratePriorityStandard, az.stConfig.rehydratePriority)
+
+	opt.RehydrateTier = "cool"
+	opt.RehydratePriority = "HIGH"
+	err = ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.Equal(blob.AccessTierCool, az.stConfig.rehydrateTier)
+	assert.Equal(blob.RehydratePriorityHigh, az.stConfig.rehydratePriority)
+
+	opt.RehydrateTier = "archive"
+	err = ParseAndValidateConfig(az, opt)
+	assert.Error(err)
+
+	opt.RehydrateTier = "hot"
+	opt.RehydratePriority = "urgent"
+	err = ParseAndValidateConfig(az, opt)
+	assert.Error(err)
+}
+
+func (s *configTestSuite) TestAccessTierRules() {
+	defer config.ResetConfig()
+	assert := assert.New(s.T())
+	az := &AzStorage{}
+	opt := AzStorageOptions{
+		AccountName: "abcd",
+		Container:   "abcd",
+		AuthMode:    "key",
+		AccountKey:  "abc",
+		DefaultTier: "hot",
+		AccessTierRules: []AccessTierRule{
+			{Pattern: "logs/", AccessTier: "cool"},
+			{Pattern: "scratch/", AccessTier: "Cold"},
+		},
+	}
+
+	err := ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.Equal([]tierRule{
+		{"logs/", to.Ptr(blob.AccessTierCool)},
+		{"scratch/", to.Ptr(blob.AccessTierCold)},
+	}, az.stConfig.tierRules)
+
+	opt.AccessTierRules = []AccessTierRule{{Pattern: "logs/", AccessTier: "freezing"}}
+	err = ParseAndValidateConfig(az, opt)
+	assert.Error(err)
+
+	opt.AccessTierRules = []AccessTierRule{{Pattern: "[logs/", AccessTier: "cool"}}
+	err = ParseAndValidateConfig(az, opt)
+	assert.Error(err)
+}
+
+func (s *configTestSuite) TestHeaderRules() {
+	defer config.ResetConfig()
+	assert := assert.New(s.T())
+	az := &AzStorage{}
+	opt := AzStorageOptions{
+		AccountName: "abcd",
+		Container:   "abcd",
+		AuthMode:    "key",
+		AccountKey:  "abc",
+		HeaderRules: []internal.HeaderRule{
+			{
+				Pattern:      "*.html",
+				CacheControl: "no-cache",
+				Metadata:     map[string]string{"team": "web"},
+			},
+		},
+	}
+
+	err := ParseAndValidateConfig(az, opt)
+	assert.NoError(err)
+	assert.Equal(opt.HeaderRules, az.stConfig.headerRules)
+
+	// metadata keys must be C# identifiers
+	opt.HeaderRules[0].Metadata = map[string]string{"web-team": "yes"}
+	err = ParseAndValidateConfig(az, opt)
+	assert.Error(err)
+}
+
 func TestConfigTestSuite(t *testing.T) {
 	suite.Run(t, new(configTestSuite))
 }
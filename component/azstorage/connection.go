@@ -28,6 +28,7 @@ package azstorage
 import (
 	"context"
 	"os"
+	"time"
 
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/log"
@@ -47,8 +48,10 @@ type AzStorageConfig struct {
 	blockSize      int64
 	maxConcurrency uint16
 
-	// tier to be set on every upload
+	// tier to be set on every upload, unless a tier rule matches
 	defaultTier *blob.AccessTier
+	tierRules   []tierRule
+	headerRules []internal.HeaderRule
 
 	// Return back readDir on mount for given amount of time
 	cancelListForSeconds uint16
@@ -73,6 +76,11 @@ type AzStorageConfig struct {
 	honourACL          bool
 	disableSymlink     bool
 	preserveACL        bool
+	enableSnapshots    bool
+
+	// Archive related config
+	rehydrateTier     blob.AccessTier
+	rehydratePriority blob.RehydratePriority
 
 	// CPK related config
 	cpkEnabled             bool
@@ -112,6 +120,9 @@ type AzConnection interface {
 	RenameDirectory(context.Context, string, string) error
 
 	GetAttr(ctx context.Context, name string) (attr *internal.ObjAttr, err error)
+	GetMetadata(ctx context.Context, name string) (map[string]*string, error)
+	SetMetadata(ctx context.Context, name string, metadata map[string]*string) error
+	Utimens(ctx context.Context, name string, atime time.Time, mtime time.Time) error
 
 	// Standard operations to be supported by any account type
 	List(
@@ -132,7 +143,14 @@ type AzConnection interface {
 		etag *string,
 	) error
 
-	WriteFromFile(ctx context.Context, name string, metadata map[string]*string, fi *os.File) error
+	WriteFromFile(
+		ctx context.Context,
+		name string,
+		metadata map[string]*string,
+		fi *os.File,
+		ifMatch *string,
+		newETag *string,
+	) error
 	WriteFromBuffer(
 		ctx context.Context,
 		name string,
@@ -149,7 +167,7 @@ type AzConnection interface {
 
 	GetCommittedBlockList(context.Context, string) (*internal.CommittedBlockList, error)
 	StageBlock(context.Context, string, []byte, string) error
-	CommitBlocks(context.Context, string, []string, *string) error
+	CommitBlocks(context.Context, string, []string, *string, map[string]*string) error
 
 	UpdateServiceClient(_, _ string) error
 
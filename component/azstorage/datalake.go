@@ -35,6 +35,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/log"
@@ -113,6 +114,8 @@ func (dl *Datalake) UpdateConfig(cfg AzStorageConfig) error {
 	dl.Config.blockSize = cfg.blockSize
 	dl.Config.maxConcurrency = cfg.maxConcurrency
 	dl.Config.defaultTier = cfg.defaultTier
+	dl.Config.tierRules = cfg.tierRules
+	dl.Config.headerRules = cfg.headerRules
 	dl.Config.ignoreAccessModifiers = cfg.ignoreAccessModifiers
 	return dl.BlockBlob.UpdateConfig(cfg)
 }
@@ -408,7 +411,22 @@ func (dl *Datalake) RenameFile(
 			return err
 		}
 	}
-	modifyLMTandEtag(srcAttr, renameResponse.LastModified, sanitizeEtag(renameResponse.ETag))
+	// a rename keeps the tier of the blob, unless a rule sets another one for the target
+	if tier := dl.BlockBlob.ruleTier(target); tier != nil &&
+		(srcAttr == nil || !strings.EqualFold(srcAttr.Tier, string(*tier))) {
+		_, err = dl.BlockBlob.getBlobClient(target).SetTier(ctx, *tier, nil)
+		if err != nil {
+			log.Warn("Datalake::RenameFile : Failed to set tier of %s [%s]", target, err.Error())
+		}
+	}
+	dstLMT, dstETag := renameResponse.LastModified, sanitizeEtag(renameResponse.ETag)
+	lmt, etag, err := dl.BlockBlob.applyHeaderRules(ctx, target)
+	if err != nil {
+		log.Warn("Datalake::RenameFile : Failed to set headers of %s [%s]", target, err.Error())
+	} else if lmt != nil {
+		dstLMT, dstETag = lmt, etag
+	}
+	modifyLMTandEtag(srcAttr, dstLMT, dstETag)
 	return nil
 }
 
@@ -450,6 +468,12 @@ func (dl *Datalake) GetAttr(
 ) (blobAttr *internal.ObjAttr, err error) {
 	log.Trace("Datalake::GetAttr : name %s", name)
 
+	if dl.Config.enableSnapshots {
+		if _, inTree, _ := parseSnapshotPath(name); inTree {
+			return dl.BlockBlob.GetAttr(ctx, name)
+		}
+	}
+
 	fileClient := dl.getFileClient(name)
 	prop, err := fileClient.GetProperties(ctx, &file.GetPropertiesOptions{
 		CPKInfo: dl.datalakeCPKOpt,
@@ -490,6 +514,9 @@ func (dl *Datalake) GetAttr(
 		Flags:  internal.NewFileBitMap(),
 		ETag:   sanitizeEtag(prop.ETag),
 	}
+	if prop.AccessTier != nil {
+		blobAttr.Tier = *prop.AccessTier
+	}
 	parseMetadata(blobAttr, prop.Metadata)
 
 	if *prop.ResourceType == "directory" {
@@ -581,6 +608,8 @@ func (dl *Datalake) WriteFromFile(
 	name string,
 	metadata map[string]*string,
 	fi *os.File,
+	ifMatch *string,
+	newETag *string,
 ) (err error) {
 	// File in DataLake may have permissions and ACL set. Just uploading the file will override them.
 	// So, we need to get the existing permissions and ACL and set them back after uploading the file.
@@ -599,7 +628,7 @@ func (dl *Datalake) WriteFromFile(
 	}
 
 	// Upload the file, which will override the permissions and ACL
-	retCode := dl.BlockBlob.WriteFromFile(ctx, name, metadata, fi)
+	retCode := dl.BlockBlob.WriteFromFile(ctx, name, metadata, fi, ifMatch, newETag)
 
 	if acl != "" {
 		// Cannot set both permissions and ACL in one call. ACL includes permission as well so just setting those back
@@ -722,6 +751,25 @@ func (dl *Datalake) ChangeOwner(ctx context.Context, name string, _ int, _ int)
 	return syscall.ENOTSUP
 }
 
+// GetMetadata : Get the user metadata of a path
+func (dl *Datalake) GetMetadata(ctx context.Context, name string) (map[string]*string, error) {
+	return dl.BlockBlob.GetMetadata(ctx, name)
+}
+
+// SetMetadata : Merge the given user metadata into the metadata of a path
+func (dl *Datalake) SetMetadata(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+) error {
+	return dl.BlockBlob.SetMetadata(ctx, name, metadata)
+}
+
+// Utimens : Store the access and modification times in the metadata of a path
+func (dl *Datalake) Utimens(ctx context.Context, name string, atime, mtime time.Time) error {
+	return dl.BlockBlob.Utimens(ctx, name, atime, mtime)
+}
+
 // GetCommittedBlockList : Get the list of committed blocks
 func (dl *Datalake) GetCommittedBlockList(
 	ctx context.Context,
@@ -735,14 +783,15 @@ func (dl *Datalake) StageBlock(ctx context.Context, name string, data []byte, id
 	return dl.BlockBlob.StageBlock(ctx, name, data, id)
 }
 
-// CommitBlocks : persists the block list
+// CommitBlocks : persists the block list, with the given metadata
 func (dl *Datalake) CommitBlocks(
 	ctx context.Context,
 	name string,
 	blockList []string,
 	newEtag *string,
+	metadata map[string]*string,
 ) error {
-	return dl.BlockBlob.CommitBlocks(ctx, name, blockList, newEtag)
+	return dl.BlockBlob.CommitBlocks(ctx, name, blockList, newEtag, metadata)
 }
 
 func (dl *Datalake) SetFilter(filter string) error {
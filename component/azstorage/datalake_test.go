@@ -3226,7 +3226,7 @@ func (s *datalakeTestSuite) TestUploadWithCPKEnabled() {
 	s.assert.NoError(err)
 	_, _ = f.Seek(0, 0)
 
-	err = s.az.storage.WriteFromFile(ctx, name1, nil, f)
+	err = s.az.storage.WriteFromFile(ctx, name1, nil, f, nil, nil)
 	s.assert.NoError(err)
 
 	// Blob should have updated data
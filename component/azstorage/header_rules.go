@@ -0,0 +1,146 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"cmp"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+)
+
+// Header rules set the Content-Type, Cache-Control, Content-Encoding and metadata of uploaded
+// blobs from their path. Blobs matching no rule get the content type of their extension.
+
+// httpHeaders returns the HTTP headers of a blob uploaded to name
+func (bb *BlockBlob) httpHeaders(name string) *blob.HTTPHeaders {
+	headers, _ := internal.MatchHeaderRules(bb.Config.headerRules, name)
+	return &blob.HTTPHeaders{
+		BlobContentType:     new(cmp.Or(headers.ContentType, getContentType(name))),
+		BlobCacheControl:    optionalString(headers.CacheControl),
+		BlobContentEncoding: optionalString(headers.ContentEncoding),
+	}
+}
+
+// withRuleMetadata returns the metadata of a blob uploaded to name with the keys set by the
+// rules added. Metadata already on the blob takes precedence.
+func (bb *BlockBlob) withRuleMetadata(
+	name string,
+	metadata map[string]*string,
+) map[string]*string {
+	headers, _ := internal.MatchHeaderRules(bb.Config.headerRules, name)
+	return addRuleMetadata(metadata, headers)
+}
+
+func addRuleMetadata(
+	metadata map[string]*string,
+	headers internal.ObjectHeaders,
+) map[string]*string {
+	if len(headers.Metadata) == 0 {
+		return metadata
+	}
+	merged := make(map[string]*string, len(metadata)+len(headers.Metadata))
+	present := make(map[string]bool, len(metadata))
+	for key, value := range metadata {
+		merged[key] = value
+		present[strings.ToLower(key)] = true
+	}
+	for key, value := range headers.Metadata {
+		if !present[strings.ToLower(key)] {
+			merged[key] = &value
+		}
+	}
+	return merged
+}
+
+// applyHeaderRules sets the headers and metadata of the rules matching name on an existing blob,
+// such as the target of a copy, keeping the ones the rules do not set. It returns the new last
+// modified time and ETag, or a nil time when no rule matches.
+func (bb *BlockBlob) applyHeaderRules(
+	ctx context.Context,
+	name string,
+) (*time.Time, string, error) {
+	headers, matched := internal.MatchHeaderRules(bb.Config.headerRules, name)
+	if !matched {
+		return nil, "", nil
+	}
+	blobClient := bb.getBlobClient(name)
+	prop, err := blobClient.GetProperties(ctx, &blob.GetPropertiesOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	// setting the headers replaces all of them, so the ones the rules do not set are carried over
+	contentEncoding := cmp.Or(optionalString(headers.ContentEncoding), prop.ContentEncoding)
+	resp, err := blobClient.SetHTTPHeaders(ctx, blob.HTTPHeaders{
+		BlobContentType:        cmp.Or(optionalString(headers.ContentType), prop.ContentType),
+		BlobCacheControl:       cmp.Or(optionalString(headers.CacheControl), prop.CacheControl),
+		BlobContentEncoding:    contentEncoding,
+		BlobContentDisposition: prop.ContentDisposition,
+		BlobContentLanguage:    prop.ContentLanguage,
+		BlobContentMD5:         prop.ContentMD5,
+	}, &blob.SetHTTPHeadersOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: prop.ETag},
+		},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	lastModified, etag := resp.LastModified, resp.ETag
+
+	if len(headers.Metadata) > 0 {
+		metadataResp, err := blobClient.SetMetadata(
+			ctx,
+			addRuleMetadata(prop.Metadata, headers),
+			&blob.SetMetadataOptions{
+				AccessConditions: &blob.AccessConditions{
+					ModifiedAccessConditions: &blob.ModifiedAccessConditions{IfMatch: etag},
+				},
+				CPKInfo: bb.blobCPKOpt,
+			},
+		)
+		if err != nil {
+			return nil, "", err
+		}
+		lastModified, etag = metadataResp.LastModified, metadataResp.ETag
+	}
+	return lastModified, sanitizeEtag(etag), nil
+}
+
+// optionalString returns nil for an empty string, so the header is not sent
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
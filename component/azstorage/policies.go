@@ -28,6 +28,7 @@ package azstorage
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Seagate/cloudfuse/common"
@@ -71,3 +72,16 @@ func (r *serviceVersionPolicy) Do(req *policy.Request) (*http.Response, error) {
 	req.Raw().Header["x-ms-version"] = []string{r.serviceApiVersion}
 	return req.Next()
 }
+
+// ---------------------------------------------------------------------------------------------------------------------------------------------------
+// Policy to record the latency of each attempt of a request for the metrics listener
+type requestLatencyPolicy struct{}
+
+func (requestLatencyPolicy) Do(req *policy.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := req.Next()
+	if azStatsCollector != nil {
+		azStatsCollector.ObserveLatency(req.Raw().Method, time.Since(start))
+	}
+	return resp, err
+}
@@ -0,0 +1,388 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+)
+
+// With enable-snapshots, every directory has a hidden, read-only .snapshots directory, which holds
+// a directory for each snapshot time of the files in it, listing the files snapshotted then:
+//
+//	<dir>/.snapshots/<time>/<file>
+//
+// Snapshot times are named without colons, so they are valid file names on Windows too.
+// Setting the snapshotMetadataKey metadata on a file creates a snapshot of it.
+
+const snapshotsDirName = ".snapshots"
+
+// snapshotMetadataKey is the control key that creates a snapshot when it is set on a file,
+// e.g. with setfattr -n user.cloudfuse_snapshot -v 1 <file>. It is never stored.
+const snapshotMetadataKey = "cloudfuse_snapshot"
+
+const (
+	snapshotTimeFormat      = "20060102T150405.0000000Z"
+	azureSnapshotTimeFormat = "2006-01-02T15:04:05.0000000Z"
+)
+
+const (
+	// how long the snapshot times found by listing a .snapshots directory are used for
+	snapshotTimesTimeout = 30 * time.Second
+	// the cached snapshot times are dropped once this many directories are cached
+	maxSnapshotTimesDirs = 1000
+)
+
+// errStopListing is returned by a handleSnapshot function to end listSnapshots early
+var errStopListing = errors.New("stop listing snapshots")
+
+// snapshotTimes caches the snapshot times of the files in each directory, so looking up each
+// directory of a .snapshots listing does not list the subtree again
+type snapshotTimes struct {
+	sync.Mutex
+	dirs map[string]snapshotTimesEntry
+}
+
+type snapshotTimesEntry struct {
+	times   map[string]time.Time // by snapshot name
+	expires time.Time
+}
+
+// set replaces the snapshot times cached for dir
+func (st *snapshotTimes) set(dir string, times map[string]time.Time) {
+	st.Lock()
+	defer st.Unlock()
+	if st.dirs == nil || len(st.dirs) >= maxSnapshotTimesDirs {
+		st.dirs = make(map[string]snapshotTimesEntry)
+	}
+	st.dirs[dir] = snapshotTimesEntry{times: times, expires: time.Now().Add(snapshotTimesTimeout)}
+}
+
+// get returns the time of a snapshot of the files in dir, if it is cached
+func (st *snapshotTimes) get(dir string, snapshot string) (time.Time, bool) {
+	st.Lock()
+	defer st.Unlock()
+	entry, found := st.dirs[dir]
+	if !found || time.Now().After(entry.expires) {
+		return time.Time{}, false
+	}
+	t, found := entry.times[snapshot]
+	return t, found
+}
+
+// snapshotPath is a path in a .snapshots tree
+type snapshotPath struct {
+	dir      string // directory holding the files
+	snapshot string // empty for the .snapshots directory
+	file     string // empty for the directory listing the files of a snapshot
+}
+
+// parseSnapshotPath splits name around its .snapshots element, and reports whether it has one.
+// Paths too deep to exist in a .snapshots tree return ENOENT.
+func parseSnapshotPath(name string) (snapshotPath, bool, error) {
+	var sp snapshotPath
+	elems := strings.Split(strings.Trim(name, "/"), "/")
+	i := slices.Index(elems, snapshotsDirName)
+	if i < 0 {
+		return sp, false, nil
+	}
+	sp.dir = strings.Join(elems[:i], "/")
+	switch rest := elems[i+1:]; len(rest) {
+	case 0:
+	case 1:
+		sp.snapshot = rest[0]
+	case 2:
+		sp.snapshot = rest[0]
+		sp.file = rest[1]
+	default:
+		return sp, true, syscall.ENOENT
+	}
+	return sp, true, nil
+}
+
+// path returns the path of sp in the file system
+func (sp snapshotPath) path() string {
+	return path.Join(sp.dir, snapshotsDirName, sp.snapshot, sp.file)
+}
+
+// filePath returns the path of the file sp refers to
+func (sp snapshotPath) filePath() string {
+	return path.Join(sp.dir, sp.file)
+}
+
+// snapshotName converts a snapshot time, as returned by Azure, to its name in a .snapshots tree
+func snapshotName(snapshot string) (string, time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, snapshot)
+	if err != nil {
+		return "", t, err
+	}
+	return t.UTC().Format(snapshotTimeFormat), t, nil
+}
+
+// parseSnapshotName converts a name made by snapshotName back to the snapshot time Azure expects
+func parseSnapshotName(name string) (string, time.Time, error) {
+	t, err := time.Parse(snapshotTimeFormat, name)
+	if err != nil {
+		return "", t, syscall.ENOENT
+	}
+	return t.Format(azureSnapshotTimeFormat), t, nil
+}
+
+// createSnapshotDirAttr creates the attributes of a directory in a .snapshots tree
+func createSnapshotDirAttr(name string, mtime time.Time) *internal.ObjAttr {
+	return &internal.ObjAttr{
+		Path:   name,
+		Name:   path.Base(name),
+		Size:   4096,
+		Mode:   os.ModeDir | 0555,
+		Mtime:  mtime,
+		Atime:  mtime,
+		Ctime:  mtime,
+		Crtime: mtime,
+		Flags:  internal.NewDirBitMap(),
+	}
+}
+
+// createSnapshotAttr creates the attributes of a snapshotted file, which cannot be modified
+func createSnapshotAttr(name string, size int64, lastModified time.Time) *internal.ObjAttr {
+	return &internal.ObjAttr{
+		Path:   name,
+		Name:   path.Base(name),
+		Size:   size,
+		Mode:   0444,
+		Mtime:  lastModified,
+		Atime:  lastModified,
+		Ctime:  lastModified,
+		Crtime: lastModified,
+		Flags:  internal.NewFileBitMap(),
+	}
+}
+
+// getReadBlobClient returns the client to download name with.
+// Names in a .snapshots tree get a client for the snapshot of the file.
+func (bb *BlockBlob) getReadBlobClient(name string) (*blob.Client, error) {
+	if !bb.Config.enableSnapshots {
+		return bb.getBlobClient(name), nil
+	}
+	sp, inTree, err := parseSnapshotPath(name)
+	if !inTree {
+		return bb.getBlobClient(name), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sp.file == "" {
+		return nil, syscall.EISDIR
+	}
+	snapshot, _, err := parseSnapshotName(sp.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return bb.getBlobClient(sp.filePath()).WithSnapshot(snapshot)
+}
+
+// listSnapshots calls handleSnapshot with each snapshot of the files in dir, until it returns
+// errStopListing. Snapshots can only be listed without a delimiter, so every blob under dir is
+// listed and the ones deeper in dir are skipped, which is slow for a large subtree.
+func (bb *BlockBlob) listSnapshots(
+	ctx context.Context,
+	dir string,
+	handleSnapshot func(file string, item *container.BlobItem) error,
+) error {
+	listPath := bb.getFormattedPath(dir)
+	if listPath != "" {
+		listPath += "/"
+	}
+	pager := bb.Container.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix:  &listPath,
+		Include: container.ListBlobsInclude{Snapshots: true, Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			log.Err("BlockBlob::listSnapshots : Failed to list %s [%s]", dir, err.Error())
+			return err
+		}
+		for _, item := range page.Segment.BlobItems {
+			file := strings.TrimPrefix(*item.Name, listPath)
+			if item.Snapshot == nil || *item.Snapshot == "" || strings.Contains(file, "/") {
+				continue
+			}
+			if isFolder, ok := item.Metadata[folderKey]; ok && *isFolder == "true" {
+				continue
+			}
+			err = handleSnapshot(*bb.getFileName(file), item)
+			if err == errStopListing {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// listSnapshotTree lists a directory in a .snapshots tree
+func (bb *BlockBlob) listSnapshotTree(
+	ctx context.Context,
+	sp snapshotPath,
+) ([]*internal.ObjAttr, error) {
+	list := make([]*internal.ObjAttr, 0)
+	if sp.snapshot == "" {
+		// one directory per snapshot time
+		times := make(map[string]time.Time)
+		err := bb.listSnapshots(ctx, sp.dir, func(_ string, item *container.BlobItem) error {
+			name, t, err := snapshotName(*item.Snapshot)
+			if _, seen := times[name]; err != nil || seen {
+				return nil
+			}
+			times[name] = t
+			sp.snapshot = name
+			list = append(list, createSnapshotDirAttr(sp.path(), t))
+			return nil
+		})
+		if err == nil {
+			bb.snapshotTimes.set(sp.dir, times)
+		}
+		return list, err
+	}
+
+	snapshot, _, err := parseSnapshotName(sp.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	err = bb.listSnapshots(ctx, sp.dir, func(file string, item *container.BlobItem) error {
+		if *item.Snapshot != snapshot {
+			return nil
+		}
+		sp.file = file
+		list = append(list, createSnapshotAttr(
+			sp.path(),
+			*item.Properties.ContentLength,
+			*item.Properties.LastModified,
+		))
+		return nil
+	})
+	return list, err
+}
+
+// getSnapshotAttr returns the attributes of a path in a .snapshots tree
+func (bb *BlockBlob) getSnapshotAttr(
+	ctx context.Context,
+	sp snapshotPath,
+) (*internal.ObjAttr, error) {
+	if sp.snapshot == "" {
+		return createSnapshotDirAttr(sp.path(), time.Now()), nil
+	}
+	snapshot, t, err := parseSnapshotName(sp.snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	if sp.file == "" {
+		// the directory of a snapshot time exists while a file has a snapshot then.
+		// The times are usually cached by the listing of the .snapshots directory.
+		if _, found := bb.snapshotTimes.get(sp.dir, sp.snapshot); found {
+			return createSnapshotDirAttr(sp.path(), t), nil
+		}
+		found := false
+		err = bb.listSnapshots(ctx, sp.dir, func(_ string, item *container.BlobItem) error {
+			if *item.Snapshot == snapshot {
+				found = true
+				return errStopListing
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, syscall.ENOENT
+		}
+		return createSnapshotDirAttr(sp.path(), t), nil
+	}
+
+	blobClient, err := bb.getBlobClient(sp.filePath()).WithSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	prop, err := blobClient.GetProperties(ctx, &blob.GetPropertiesOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+	if err != nil {
+		if storeBlobErrToErr(err) == ErrFileNotFound {
+			return nil, syscall.ENOENT
+		}
+		log.Err(
+			"BlockBlob::getSnapshotAttr : Failed to get properties of %s [%s]",
+			sp.path(),
+			err.Error(),
+		)
+		return nil, err
+	}
+	return createSnapshotAttr(sp.path(), *prop.ContentLength, *prop.LastModified), nil
+}
+
+// createSnapshot creates a snapshot of the blob of a file
+func (bb *BlockBlob) createSnapshot(ctx context.Context, name string) error {
+	resp, err := bb.getBlobClient(name).CreateSnapshot(ctx, &blob.CreateSnapshotOptions{
+		CPKInfo: bb.blobCPKOpt,
+	})
+	if err != nil {
+		return bb.metadataError(ctx, name, err, "createSnapshot")
+	}
+	if snapshot, _, err := snapshotName(*resp.Snapshot); err == nil {
+		log.Info("BlockBlob::createSnapshot : Created snapshot %s of %s", snapshot, name)
+	}
+	return nil
+}
+
+// checkWritable returns EROFS when a name is in a .snapshots tree, which is read-only
+func (az *AzStorage) checkWritable(names ...string) error {
+	if !az.stConfig.enableSnapshots {
+		return nil
+	}
+	for _, name := range names {
+		if _, inTree, _ := parseSnapshotPath(name); inTree {
+			log.Err("AzStorage::checkWritable : %s is read-only", name)
+			return syscall.EROFS
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package azstorage
+
+import (
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+)
+
+// Tier rules choose the access tier of uploaded blobs from their path. The first rule matching a
+// blob applies, and blobs matching no rule get the tier option, or the account default tier.
+
+// tierRule sets the access tier of the blobs whose path matches pattern
+type tierRule struct {
+	pattern string
+	tier    *blob.AccessTier
+}
+
+// ruleTier returns the tier of the first rule matching name, or nil
+func (bb *BlockBlob) ruleTier(name string) *blob.AccessTier {
+	for _, rule := range bb.Config.tierRules {
+		if common.MatchPath(rule.pattern, name) {
+			return rule.tier
+		}
+	}
+	return nil
+}
+
+// uploadTier returns the access tier of a blob uploaded to name
+func (bb *BlockBlob) uploadTier(name string) *blob.AccessTier {
+	if tier := bb.ruleTier(name); tier != nil {
+		return tier
+	}
+	return bb.Config.defaultTier
+}
+
+// copyTier returns the access tier of a copy of a blob. A copy gets the account default tier
+// unless told otherwise, so the tier of the source is kept when no rule matches the target.
+func (bb *BlockBlob) copyTier(target string, srcAttr *internal.ObjAttr) *blob.AccessTier {
+	if tier := bb.ruleTier(target); tier != nil {
+		return tier
+	}
+	if srcAttr != nil {
+		if tier := getAccessTierType(srcAttr.Tier); tier != nil {
+			return tier
+		}
+	}
+	return bb.Config.defaultTier
+}
@@ -115,10 +115,11 @@ func getAzStorageClientOptions(conf *AzStorageConfig) (azcore.ClientOptions, err
 	}
 
 	return azcore.ClientOptions{
-		Retry:           retryOptions,
-		Logging:         logOptions,
-		PerCallPolicies: perCallPolicies,
-		Transport:       transportOptions,
+		Retry:            retryOptions,
+		Logging:          logOptions,
+		PerCallPolicies:  perCallPolicies,
+		PerRetryPolicies: []policy.Policy{requestLatencyPolicy{}},
+		Transport:        transportOptions,
 	}, err
 }
 
@@ -240,6 +241,9 @@ const (
 	InvalidRange
 	BlobIsUnderLease
 	InvalidPermission
+	ConditionNotMet
+	BlobArchived
+	BlobRehydrating
 )
 
 // For detailed error list refer below link,
@@ -260,6 +264,12 @@ func storeBlobErrToErr(err error) uint16 {
 			return BlobIsUnderLease
 		case bloberror.InsufficientAccountPermissions, bloberror.AuthorizationPermissionMismatch:
 			return InvalidPermission
+		case bloberror.ConditionNotMet:
+			return ConditionNotMet
+		case bloberror.BlobArchived:
+			return BlobArchived
+		case bloberror.BlobBeingRehydrated:
+			return BlobRehydrating
 		default:
 			return ErrUnknown
 		}
@@ -309,6 +319,31 @@ func parseMetadata(attr *internal.ObjAttr, metadata map[string]*string) {
 			}
 		}
 	}
+	// times set with utimens take precedence over LastModified
+	attr.SetTimesFromMetadata()
+}
+
+// isInternalMetadataKey returns true for the metadata keys used to mark directories and symlinks,
+// and to keep the times set with utimens
+func isInternalMetadataKey(key string) bool {
+	return strings.EqualFold(key, folderKey) || strings.EqualFold(key, symlinkKey) ||
+		internal.IsTimeMetadataKey(key) || internal.IsArchiveMetadataKey(key)
+}
+
+// isValidMetadataKey returns true if the key is an ASCII C# identifier, as required by Azure
+func isValidMetadataKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i, c := range key {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && c >= '0' && c <= '9':
+		default:
+			return false
+		}
+	}
+	return true
 }
 
 //    ----------- Content-type handling  ---------------
@@ -31,10 +31,13 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
@@ -69,6 +72,20 @@ func (s *utilsTestSuite) TestContentType() {
 	assert.Equal("video/mp4", val)
 }
 
+func (s *utilsTestSuite) TestIsValidMetadataKey() {
+	assert := assert.New(s.T())
+
+	for _, key := range []string{"key", "_key", "Key_1", "k"} {
+		assert.True(isValidMetadataKey(key), key)
+	}
+	for _, key := range []string{"", "1key", "bad-key", "bad.key", "kéy"} {
+		assert.False(isValidMetadataKey(key), key)
+	}
+	assert.True(isInternalMetadataKey("HDI_IsFolder"))
+	assert.True(isInternalMetadataKey("Mtime"))
+	assert.False(isInternalMetadataKey("label"))
+}
+
 type contentTypeVal struct {
 	val    string
 	result string
@@ -787,6 +804,164 @@ func (s *utilsTestSuite) TestRemovePrefixPath() {
 	}
 }
 
+func (s *utilsTestSuite) TestParseSnapshotPath() {
+	assert := assert.New(s.T())
+
+	var inputs = []struct {
+		name   string
+		inTree bool
+		err    error
+		result snapshotPath
+	}{
+		{name: "dir/file.txt"},
+		{name: "dir/.snapshotsfile"},
+		{name: ".snapshots", inTree: true},
+		{name: "dir/sub/.snapshots/", inTree: true, result: snapshotPath{dir: "dir/sub"}},
+		{
+			name:   "/.snapshots/20240102T030405.1234567Z",
+			inTree: true,
+			result: snapshotPath{snapshot: "20240102T030405.1234567Z"},
+		},
+		{
+			name:   "dir/.snapshots/20240102T030405.1234567Z/a.txt",
+			inTree: true,
+			result: snapshotPath{dir: "dir", snapshot: "20240102T030405.1234567Z", file: "a.txt"},
+		},
+		{name: "dir/.snapshots/20240102T030405.1234567Z/a.txt/b", inTree: true, err: syscall.ENOENT},
+	}
+
+	for _, i := range inputs {
+		s.Run(i.name, func() {
+			sp, inTree, err := parseSnapshotPath(i.name)
+			assert.Equal(i.inTree, inTree)
+			assert.Equal(i.err, err)
+			if i.inTree && i.err == nil {
+				assert.Equal(i.result, sp)
+			}
+		})
+	}
+
+	sp := snapshotPath{dir: "dir", snapshot: "20240102T030405.1234567Z", file: "a.txt"}
+	assert.Equal("dir/.snapshots/20240102T030405.1234567Z/a.txt", sp.path())
+	assert.Equal("dir/a.txt", sp.filePath())
+	sp = snapshotPath{snapshot: "20240102T030405.1234567Z"}
+	assert.Equal(".snapshots/20240102T030405.1234567Z", sp.path())
+}
+
+func (s *utilsTestSuite) TestTierRules() {
+	assert := assert.New(s.T())
+
+	bb := &BlockBlob{}
+	bb.Config.defaultTier = to.Ptr(blob.AccessTierHot)
+	bb.Config.tierRules = []tierRule{
+		{"logs/", to.Ptr(blob.AccessTierCool)},
+		{"scratch/", to.Ptr(blob.AccessTierCold)},
+	}
+	assert.Equal(blob.AccessTierCool, *bb.uploadTier("logs/2024/app.log"))
+	assert.Equal(blob.AccessTierCold, *bb.uploadTier("scratch/a"))
+	assert.Equal(blob.AccessTierHot, *bb.uploadTier("data/a.csv"))
+
+	// copies take the tier of the first rule matching the target, or keep the source's
+	srcAttr := &internal.ObjAttr{Tier: "Cool"}
+	assert.Equal(blob.AccessTierCold, *bb.copyTier("scratch/a.log", srcAttr))
+	assert.Equal(blob.AccessTierCool, *bb.copyTier("data/a.log", srcAttr))
+	assert.Equal(blob.AccessTierHot, *bb.copyTier("data/a.log", &internal.ObjAttr{}))
+	assert.Equal(blob.AccessTierHot, *bb.copyTier("data/a.log", nil))
+}
+
+func (s *utilsTestSuite) TestHeaderRules() {
+	assert := assert.New(s.T())
+
+	bb := &BlockBlob{}
+	bb.Config.headerRules = []internal.HeaderRule{
+		{Pattern: "site/", CacheControl: "max-age=60", Metadata: map[string]string{"team": "web"}},
+	}
+	headers := bb.httpHeaders("site/index.html")
+	assert.Equal("text/html", *headers.BlobContentType)
+	assert.Equal("max-age=60", *headers.BlobCacheControl)
+	assert.Nil(headers.BlobContentEncoding)
+	metadata := bb.withRuleMetadata("site/index.html", map[string]*string{"Team": new("ops")})
+	assert.Equal(map[string]*string{"Team": new("ops")}, metadata)
+	metadata = bb.withRuleMetadata("site/index.html", nil)
+	assert.Equal(map[string]*string{"team": new("web")}, metadata)
+
+	headers = bb.httpHeaders("data/a.csv")
+	assert.Equal("text/csv", *headers.BlobContentType)
+	assert.Nil(headers.BlobCacheControl)
+	assert.Nil(bb.withRuleMetadata("data/a.csv", nil))
+}
+
+func (s *utilsTestSuite) TestArchiveStatus() {
+	assert := assert.New(s.T())
+
+	assert.Empty(archiveStatus(nil, nil))
+	assert.Empty(archiveStatus(new("Cool"), nil))
+	assert.Equal(internal.RestoreStatusArchived, archiveStatus(new("Archive"), nil))
+	assert.Equal(
+		internal.RestoreStatusRestoring,
+		archiveStatus(new("Archive"), new("rehydrate-pending-to-hot")),
+	)
+
+	metadata := map[string]*string{}
+	archiveMetadata(new("Archive"), nil, metadata)
+	assert.Equal("Archive", *metadata[internal.TierMetadataKey])
+	assert.Equal(internal.RestoreStatusArchived, *metadata[internal.RestoreMetadataKey])
+}
+
+func (s *utilsTestSuite) TestSnapshotTimes() {
+	assert := assert.New(s.T())
+
+	var st snapshotTimes
+	_, found := st.get("dir", "20240102T030405.1234567Z")
+	assert.False(found)
+
+	t := time.Date(2024, 1, 2, 3, 4, 5, 123456700, time.UTC)
+	st.set("dir", map[string]time.Time{"20240102T030405.1234567Z": t})
+	cached, found := st.get("dir", "20240102T030405.1234567Z")
+	assert.True(found)
+	assert.Equal(t, cached)
+	_, found = st.get("dir", "20240103T030405.1234567Z")
+	assert.False(found)
+	_, found = st.get("other", "20240102T030405.1234567Z")
+	assert.False(found)
+
+	// expired times are not used
+	entry := st.dirs["dir"]
+	entry.expires = time.Now().Add(-time.Second)
+	st.dirs["dir"] = entry
+	_, found = st.get("dir", "20240102T030405.1234567Z")
+	assert.False(found)
+}
+
+func (s *utilsTestSuite) TestSnapshotName() {
+	assert := assert.New(s.T())
+
+	name, t, err := snapshotName("2024-01-02T03:04:05.1234567Z")
+	assert.NoError(err)
+	assert.Equal("20240102T030405.1234567Z", name)
+	assert.Equal(time.Date(2024, 1, 2, 3, 4, 5, 123456700, time.UTC), t)
+
+	snapshot, parsed, err := parseSnapshotName(name)
+	assert.NoError(err)
+	assert.Equal("2024-01-02T03:04:05.1234567Z", snapshot)
+	assert.True(t.Equal(parsed))
+
+	// trailing zeros are kept, as Azure expects exactly seven digits
+	name, _, err = snapshotName("2024-01-02T03:04:05.1000000Z")
+	assert.NoError(err)
+	assert.Equal("20240102T030405.1000000Z", name)
+	snapshot, _, err = parseSnapshotName(name)
+	assert.NoError(err)
+	assert.Equal("2024-01-02T03:04:05.1000000Z", snapshot)
+
+	_, _, err = snapshotName("not a time")
+	assert.Error(err)
+	for _, name := range []string{"2024-01-02T03:04:05.1234567Z", "20240102T030405Z", "a.txt"} {
+		_, _, err = parseSnapshotName(name)
+		assert.Equal(syscall.ENOENT, err)
+	}
+}
+
 func TestUtilsTestSuite(t *testing.T) {
 	suite.Run(t, new(utilsTestSuite))
 }
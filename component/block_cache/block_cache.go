@@ -37,6 +37,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Seagate/cloudfuse/common"
@@ -44,6 +45,7 @@ import (
 	"github.com/Seagate/cloudfuse/common/log"
 	"github.com/Seagate/cloudfuse/internal"
 	"github.com/Seagate/cloudfuse/internal/handlemap"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
 
 	"github.com/vibhansa-msft/tlru"
 )
@@ -79,6 +81,11 @@ type BlockCache struct {
 	// stream          *Stream // TODO: Replace when stream is deprecated
 	lazyWrite    bool           // Flag to indicate if lazy write is enabled
 	fileCloseOpt sync.WaitGroup // Wait group to wait for all async close operations to complete
+
+	hedgePercentile float64         // Latency percentile after which a download is hedged
+	latencies       *latencyTracker // Recent download latencies, nil when hedging is disabled
+	downloads       atomic.Int64    // Number of downloads seen while hedging is enabled
+	hedges          atomic.Int64    // Number of downloads that were hedged
 }
 
 // Structure defining your config parameters
@@ -93,6 +100,7 @@ type BlockCacheOptions struct {
 	PrefetchOnOpen bool    `config:"prefetch-on-open" yaml:"prefetch-on-open,omitempty"`
 	Consistency    bool    `config:"consistency"      yaml:"consistency,omitempty"`
 	CleanupOnStart bool    `config:"cleanup-on-start" yaml:"cleanup-on-start,omitempty"`
+	HedgePercent   float64 `config:"hedge-percentile" yaml:"hedge-percentile,omitempty"`
 }
 
 const (
@@ -107,11 +115,14 @@ const (
 	MIN_RANDREAD                   = 10
 	MAX_FAIL_CNT                   = 3
 	MAX_BLOCKS                     = 50000
+	poolUsage                      = "Pool Usage Percent"
 )
 
 // Verification to check satisfaction criteria with Component Interface
 var _ internal.Component = &BlockCache{}
 
+var blockCacheStatsCollector *stats_manager.StatsCollector
+
 func (bc *BlockCache) Name() string {
 	return compName
 }
@@ -142,6 +153,15 @@ func (bc *BlockCache) Start(ctx context.Context) error {
 		return fmt.Errorf("config error in %s [failed to init thread pool]", bc.Name())
 	}
 
+	// create stats collector for block cache
+	blockCacheStatsCollector = stats_manager.NewStatsCollector(bc.Name())
+	log.Debug("Starting block cache stats collector")
+	blockCacheStatsCollector.AddStats(stats_manager.Counter, hedgedReads, hedgeWins)
+	blockCacheStatsCollector.AddStats(stats_manager.Gauge, hedgeRate)
+	blockCacheStatsCollector.AddGauge(poolUsage, func() float64 {
+		return float64(bc.blockPool.Usage())
+	})
+
 	// Start the thread pool and keep it ready for download
 	log.Debug("BlockCache::Start : Starting thread pool")
 	bc.threadPool.Start()
@@ -171,6 +191,8 @@ func (bc *BlockCache) Stop() error {
 	// Wait for thread pool to stop
 	bc.threadPool.Stop()
 
+	blockCacheStatsCollector.Destroy()
+
 	// Clear the disk cache on exit
 	if bc.tmpPath != "" {
 		_ = bc.diskPolicy.Stop()
@@ -278,6 +300,18 @@ func (bc *BlockCache) Configure(_ bool) error {
 
 	bc.maxDiskUsageHit = false
 
+	bc.hedgePercentile = conf.HedgePercent
+	if bc.hedgePercentile < 0 || bc.hedgePercentile >= 100 {
+		log.Err(
+			"BlockCache::Configure : Hedge percentile must be between 0 and 100, got %v",
+			bc.hedgePercentile,
+		)
+		return fmt.Errorf("config error in %s [invalid hedge percentile]", bc.Name())
+	}
+	if bc.hedgePercentile > 0 {
+		bc.latencies = newLatencyTracker(latencyWindow)
+	}
+
 	bc.workers = uint32(3 * runtime.NumCPU())
 	if config.IsSet(compName + ".parallelism") {
 		bc.workers = conf.Workers
@@ -354,7 +388,8 @@ func (bc *BlockCache) Configure(_ bool) error {
 
 	log.Crit(
 		"BlockCache::Configure : block size %v, mem size %v, worker %v, prefetch %v, disk path %v, max size %v, "+
-			"disk timeout %v, prefetch-on-open %t, maxDiskUsageHit %v, noPrefetch %v, consistency %v, lazy-write: %v, cleanup-on-start %t",
+			"disk timeout %v, prefetch-on-open %t, maxDiskUsageHit %v, noPrefetch %v, consistency %v, lazy-write: %v, "+
+			"cleanup-on-start %t, hedge-percentile %v",
 		bc.blockSize,
 		bc.memSize,
 		bc.workers,
@@ -368,6 +403,7 @@ func (bc *BlockCache) Configure(_ bool) error {
 		bc.consistency,
 		bc.lazyWrite,
 		conf.CleanupOnStart,
+		bc.hedgePercentile,
 	)
 
 	return nil
@@ -1295,12 +1331,7 @@ func (bc *BlockCache) download(item *workItem) {
 
 	var etag string
 	// If file does not exists then download the block from the container
-	n, err := bc.NextComponent().ReadInBuffer(&internal.ReadInBufferOptions{
-		Handle: item.handle,
-		Offset: int64(item.block.offset),
-		Data:   item.block.data,
-		Etag:   &etag,
-	})
+	n, err := bc.readBlock(item, &etag)
 
 	if item.failCnt > MAX_FAIL_CNT {
 		// If we failed to read the data 3 times then just give up
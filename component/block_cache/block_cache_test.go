@@ -421,6 +421,25 @@ func (suite *blockCacheTestSuite) TestManualConfig() {
 	suite.assert.NotNil(tobj.blockCache.blockPool)
 }
 
+func (suite *blockCacheTestSuite) TestHedgeConfig() {
+	cfg := "read-only: true\n\nblock_cache:\n  block-size-mb: 1\n  mem-size-mb: 20\n  prefetch: 12\n  parallelism: 10"
+	tobj, err := setupPipeline(cfg)
+	suite.assert.NoError(err)
+	suite.assert.Zero(tobj.blockCache.hedgePercentile)
+	suite.assert.Nil(tobj.blockCache.latencies)
+	tobj.cleanupPipeline()
+
+	tobj, err = setupPipeline(cfg + "\n  hedge-percentile: 95")
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(95, tobj.blockCache.hedgePercentile)
+	suite.assert.NotNil(tobj.blockCache.latencies)
+	tobj.cleanupPipeline()
+
+	_, err = setupPipeline(cfg + "\n  hedge-percentile: 100")
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid hedge percentile")
+}
+
 func (suite *blockCacheTestSuite) TestOpenFileFail() {
 	tobj, err := setupPipeline("")
 	defer tobj.cleanupPipeline()
@@ -0,0 +1,215 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
+)
+
+const (
+	latencyWindow     = 200 // Number of recent download latencies the hedge delay is taken from
+	minLatencySamples = 20  // Number of downloads to see before hedging any of them
+
+	// stats published for hedged reads
+	hedgedReads = "Hedged Reads"
+	hedgeWins   = "Hedge Wins"
+	hedgeRate   = "Hedge Rate"
+)
+
+// latencyTracker keeps the durations of the most recent block downloads
+type latencyTracker struct {
+	sync.Mutex
+	samples []time.Duration
+	next    int // Index of the oldest sample once the window is full
+}
+
+func newLatencyTracker(size int) *latencyTracker {
+	return &latencyTracker{samples: make([]time.Duration, 0, size)}
+}
+
+// add records the duration of a download, replacing the oldest one once the window is full
+func (lt *latencyTracker) add(d time.Duration) {
+	lt.Lock()
+	defer lt.Unlock()
+
+	if len(lt.samples) < cap(lt.samples) {
+		lt.samples = append(lt.samples, d)
+		return
+	}
+	lt.samples[lt.next] = d
+	lt.next = (lt.next + 1) % len(lt.samples)
+}
+
+// percentile returns the duration within which p percent of the recent downloads completed,
+// or false while too few downloads have been seen
+func (lt *latencyTracker) percentile(p float64) (time.Duration, bool) {
+	lt.Lock()
+	sorted := slices.Clone(lt.samples)
+	lt.Unlock()
+
+	if len(sorted) < minLatencySamples {
+		return 0, false
+	}
+	slices.Sort(sorted)
+	i := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(i, 0)], true
+}
+
+// readResult is the outcome of one ranged read of a block
+type readResult struct {
+	n       int
+	err     error
+	etag    string
+	hedge   bool          // Read was the second, hedged, one
+	elapsed time.Duration // Time since the first read started
+}
+
+// succeeded tells whether the read returned data
+func (r readResult) succeeded() bool {
+	return (r.err == nil || r.err == io.EOF) && r.n > 0
+}
+
+// readBlock reads the data of the block in item from the next component.
+// When hedging is enabled and the read takes longer than the configured percentile of recent
+// download latencies, a second read of the same range is issued into a spare block from the pool.
+// Whichever completes first is used and the other one is cancelled.
+func (bc *BlockCache) readBlock(item *workItem, etag *string) (int, error) {
+	if bc.latencies == nil {
+		return bc.NextComponent().ReadInBuffer(&internal.ReadInBufferOptions{
+			Handle: item.handle,
+			Offset: int64(item.block.offset),
+			Data:   item.block.data,
+			Etag:   etag,
+		})
+	}
+
+	// both reads are timed from the start of the first one, so a hedge that wins records how long
+	// the block took to arrive, not just how long the hedge took
+	results := make(chan readResult, 2)
+	start := time.Now()
+	read := func(ctx context.Context, data []byte, hedge bool) {
+		var tag string
+		n, err := bc.NextComponent().ReadInBuffer(&internal.ReadInBufferOptions{
+			Handle: item.handle,
+			Offset: int64(item.block.offset),
+			Data:   data,
+			Etag:   &tag,
+			Ctx:    ctx,
+		})
+		results <- readResult{n: n, err: err, etag: tag, hedge: hedge, elapsed: time.Since(start)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go read(ctx, item.block.data, false)
+	downloads := bc.downloads.Add(1)
+
+	// wait for the read until it becomes slower than most, then hedge it if a spare block is free
+	var hedgeBlock *Block
+	if delay, ok := bc.latencies.percentile(bc.hedgePercentile); ok {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case result := <-results:
+			bc.publishHedgeRate(bc.hedges.Load(), downloads)
+			return bc.useRead(result, etag)
+		case <-timer.C:
+			hedgeBlock = bc.blockPool.TryGet()
+		}
+	}
+	if hedgeBlock == nil {
+		bc.publishHedgeRate(bc.hedges.Load(), downloads)
+		return bc.useRead(<-results, etag)
+	}
+	defer bc.blockPool.Release(hedgeBlock)
+
+	log.Debug(
+		"BlockCache::readBlock : Hedging read of %v=>%s (index %v, offset %v)",
+		item.handle.ID,
+		item.handle.Path,
+		item.block.id,
+		item.block.offset,
+	)
+	hedgeCtx, cancelHedge := context.WithCancel(context.Background())
+	defer cancelHedge()
+	go read(hedgeCtx, hedgeBlock.data[:len(item.block.data)], true)
+	blockCacheStatsCollector.UpdateStats(stats_manager.Increment, hedgedReads, (int64)(1))
+	bc.publishHedgeRate(bc.hedges.Add(1), downloads)
+
+	// use the first read to succeed, and wait for the other one to let go of its block
+	result := <-results
+	if result.succeeded() {
+		cancel()
+		cancelHedge()
+		// a first read that completed anyway is still a sample of how long downloads take
+		if other := <-results; !other.hedge && other.succeeded() {
+			bc.latencies.add(other.elapsed)
+		}
+	} else {
+		result = <-results
+	}
+
+	if result.hedge && result.succeeded() {
+		log.Debug(
+			"BlockCache::readBlock : Hedged read of %v=>%s (index %v, offset %v) won",
+			item.handle.ID,
+			item.handle.Path,
+			item.block.id,
+			item.block.offset,
+		)
+		blockCacheStatsCollector.UpdateStats(stats_manager.Increment, hedgeWins, (int64)(1))
+		copy(item.block.data, hedgeBlock.data[:result.n])
+	}
+	return bc.useRead(result, etag)
+}
+
+// useRead records the latency of a successful read and returns its outcome
+func (bc *BlockCache) useRead(result readResult, etag *string) (int, error) {
+	if result.succeeded() {
+		bc.latencies.add(result.elapsed)
+	}
+	*etag = result.etag
+	return result.n, result.err
+}
+
+// publishHedgeRate updates the percentage of downloads that were hedged
+func (bc *BlockCache) publishHedgeRate(hedges int64, downloads int64) {
+	blockCacheStatsCollector.UpdateStats(
+		stats_manager.Replace,
+		hedgeRate,
+		fmt.Sprintf("%f%%", float64(hedges)*100/float64(downloads)),
+	)
+}
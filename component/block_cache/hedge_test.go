@@ -0,0 +1,222 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package block_cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/handlemap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/mock/gomock"
+)
+
+type hedgeTestSuite struct {
+	suite.Suite
+	assert   *assert.Assertions
+	mockCtrl *gomock.Controller
+	mock     *internal.MockComponent
+	bc       *BlockCache
+	item     *workItem
+}
+
+func (suite *hedgeTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	suite.assert.NoError(err)
+
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mock = internal.NewMockComponent(suite.mockCtrl)
+	suite.bc = &BlockCache{
+		hedgePercentile: 95,
+		latencies:       newLatencyTracker(latencyWindow),
+		blockPool:       NewBlockPool(_1MB, 4*_1MB),
+	}
+	suite.bc.SetNextComponent(suite.mock)
+
+	block, err := AllocateBlock(_1MB)
+	suite.assert.NoError(err)
+	handle := handlemap.NewHandle("file")
+	handle.Size = int64(_1MB)
+	suite.item = &workItem{handle: handle, block: block}
+}
+
+func (suite *hedgeTestSuite) TearDownTest() {
+	_ = suite.item.block.Delete()
+	suite.bc.blockPool.Terminate()
+	suite.mockCtrl.Finish()
+}
+
+// warmUp records enough fast downloads for reads to be hedged
+func (suite *hedgeTestSuite) warmUp() {
+	for range minLatencySamples {
+		suite.bc.latencies.add(10 * time.Millisecond)
+	}
+}
+
+// fill returns a read that fills the block with b after the given delay
+func fill(
+	b byte,
+	delay time.Duration,
+	etag string,
+) func(*internal.ReadInBufferOptions) (int, error) {
+	return func(options *internal.ReadInBufferOptions) (int, error) {
+		time.Sleep(delay)
+		copy(options.Data, bytes.Repeat([]byte{b}, len(options.Data)))
+		*options.Etag = etag
+		return len(options.Data), nil
+	}
+}
+
+// hang returns a read that never completes until it is cancelled
+func hang(options *internal.ReadInBufferOptions) (int, error) {
+	<-options.Ctx.Done()
+	return 0, context.Canceled
+}
+
+func (suite *hedgeTestSuite) TestLatencyTracker() {
+	lt := newLatencyTracker(100)
+	for i := range minLatencySamples - 1 {
+		lt.add(time.Duration(i+1) * time.Millisecond)
+	}
+	_, ok := lt.percentile(95)
+	suite.assert.False(ok)
+
+	for i := minLatencySamples - 1; i < 100; i++ {
+		lt.add(time.Duration(i+1) * time.Millisecond)
+	}
+	p, ok := lt.percentile(95)
+	suite.assert.True(ok)
+	suite.assert.Equal(95*time.Millisecond, p)
+	p, _ = lt.percentile(50)
+	suite.assert.Equal(50*time.Millisecond, p)
+
+	// the oldest samples are replaced once the window is full
+	for range 99 {
+		lt.add(time.Millisecond)
+	}
+	p, _ = lt.percentile(99)
+	suite.assert.Equal(time.Millisecond, p)
+	p, _ = lt.percentile(99.9)
+	suite.assert.Equal(100*time.Millisecond, p)
+}
+
+func (suite *hedgeTestSuite) TestReadBlockNotHedged() {
+	// too few downloads have been seen to hedge
+	suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(fill('p', 50*time.Millisecond, "p"))
+
+	var etag string
+	n, err := suite.bc.readBlock(suite.item, &etag)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int(_1MB), n)
+	suite.assert.Equal("p", etag)
+	suite.assert.Equal(bytes.Repeat([]byte{'p'}, int(_1MB)), suite.item.block.data)
+	suite.assert.EqualValues(1, suite.bc.downloads.Load())
+	suite.assert.Zero(suite.bc.hedges.Load())
+	suite.assert.Len(suite.bc.latencies.samples, 1)
+}
+
+func (suite *hedgeTestSuite) TestReadBlockFast() {
+	suite.warmUp()
+	suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(fill('p', 0, "p"))
+
+	var etag string
+	n, err := suite.bc.readBlock(suite.item, &etag)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int(_1MB), n)
+	suite.assert.Zero(suite.bc.hedges.Load())
+	suite.assert.Len(suite.bc.latencies.samples, minLatencySamples+1)
+}
+
+func (suite *hedgeTestSuite) TestReadBlockHedgeWins() {
+	suite.warmUp()
+	gomock.InOrder(
+		suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(hang),
+		suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(fill('h', 0, "h")),
+	)
+
+	var etag string
+	n, err := suite.bc.readBlock(suite.item, &etag)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int(_1MB), n)
+	suite.assert.Equal("h", etag)
+	suite.assert.Equal(bytes.Repeat([]byte{'h'}, int(_1MB)), suite.item.block.data)
+	suite.assert.EqualValues(1, suite.bc.hedges.Load())
+	// the hedge is timed from the start of the first read, which waited out the hedge delay
+	suite.assert.Len(suite.bc.latencies.samples, minLatencySamples+1)
+	suite.assert.GreaterOrEqual(suite.bc.latencies.samples[minLatencySamples], 10*time.Millisecond)
+}
+
+func (suite *hedgeTestSuite) TestReadBlockPrimaryWins() {
+	suite.warmUp()
+	slow := fill('p', 50*time.Millisecond, "p")
+	gomock.InOrder(
+		suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(slow),
+		suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(hang),
+	)
+
+	var etag string
+	n, err := suite.bc.readBlock(suite.item, &etag)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int(_1MB), n)
+	suite.assert.Equal("p", etag)
+	suite.assert.Equal(bytes.Repeat([]byte{'p'}, int(_1MB)), suite.item.block.data)
+	suite.assert.EqualValues(1, suite.bc.hedges.Load())
+	suite.assert.Len(suite.bc.latencies.samples, minLatencySamples+1)
+	suite.assert.GreaterOrEqual(suite.bc.latencies.samples[minLatencySamples], 50*time.Millisecond)
+}
+
+func (suite *hedgeTestSuite) TestReadBlockPrimaryFails() {
+	suite.warmUp()
+	slowHedge := fill('h', 100*time.Millisecond, "h")
+	gomock.InOrder(
+		suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(
+			func(*internal.ReadInBufferOptions) (int, error) {
+				time.Sleep(50 * time.Millisecond)
+				return 0, common.NewCloudUnreachableError(context.DeadlineExceeded)
+			}),
+		suite.mock.EXPECT().ReadInBuffer(gomock.Any()).DoAndReturn(slowHedge),
+	)
+
+	// the hedge is still used when it completes after the first read failed
+	var etag string
+	n, err := suite.bc.readBlock(suite.item, &etag)
+	suite.assert.NoError(err)
+	suite.assert.Equal(int(_1MB), n)
+	suite.assert.Equal("h", etag)
+	suite.assert.Equal(bytes.Repeat([]byte{'h'}, int(_1MB)), suite.item.block.data)
+}
+
+func TestHedgeTestSuite(t *testing.T) {
+	suite.Run(t, new(hedgeTestSuite))
+}
@@ -0,0 +1,520 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package compression
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression compresses files with zstd when file_cache uploads them,
+// and decompresses them when it downloads them
+type Compression struct {
+	internal.BaseComponent
+	level    zstd.EncoderLevel
+	include  []string
+	exclude  []string
+	tempPath string
+}
+
+// Structure defining your config parameters
+type CompressionOptions struct {
+	Level    string   `config:"level"     yaml:"level,omitempty"`
+	Include  []string `config:"include"   yaml:"include,omitempty"`
+	Exclude  []string `config:"exclude"   yaml:"exclude,omitempty"`
+	TempPath string   `config:"temp-path" yaml:"temp-path,omitempty"`
+}
+
+const compName = "compression"
+
+// Compressed objects are marked with these metadata keys,
+// so objects without them are read as they are
+const (
+	algorithmMetadataKey = "cloudfuse_compression"
+	sizeMetadataKey      = "cloudfuse_uncompressed_size"
+	algorithmZstd        = "zstd"
+)
+
+// Files that are already compressed are skipped unless exclude is set
+var defaultExclude = []string{
+	"*.7z", "*.avi", "*.br", "*.bz2", "*.docx", "*.flac", "*.gif", "*.gz", "*.jar", "*.jpeg",
+	"*.jpg", "*.lz4", "*.mkv", "*.mov", "*.mp3", "*.mp4", "*.ogg", "*.parquet", "*.pdf",
+	"*.png", "*.pptx", "*.rar", "*.tgz", "*.webm", "*.webp", "*.xlsx", "*.xz", "*.zip", "*.zst",
+}
+
+// Listings are completed with this many lookups at a time
+const lookupConcurrency = 16
+
+// Verification to check satisfaction criteria with Component Interface
+var _ internal.Component = &Compression{}
+
+func (c *Compression) Name() string {
+	return compName
+}
+
+func (c *Compression) SetName(name string) {
+	c.BaseComponent.SetName(name)
+}
+
+func (c *Compression) SetNextComponent(nc internal.Component) {
+	c.BaseComponent.SetNextComponent(nc)
+}
+
+func (c *Compression) Priority() internal.ComponentPriority {
+	return internal.EComponentPriority.LevelThree()
+}
+
+// Start : Pipeline calls this method to start the component functionality
+//
+//	this shall not block the call otherwise pipeline will not start
+func (c *Compression) Start(ctx context.Context) error {
+	log.Trace("Compression::Start : Starting component %s", c.Name())
+	return nil
+}
+
+// Stop : Stop the component functionality and kill all threads started
+func (c *Compression) Stop() error {
+	log.Trace("Compression::Stop : Stopping component %s", c.Name())
+	return nil
+}
+
+// Configure : Pipeline will call this method after constructor so that you can read config and initialize yourself
+//
+//	Return failure if any config is not valid to exit the process
+func (c *Compression) Configure(_ bool) error {
+	log.Trace("Compression::Configure : %s", c.Name())
+
+	conf := CompressionOptions{}
+	err := config.UnmarshalKey(c.Name(), &conf)
+	if err != nil {
+		log.Err("Compression::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("Compression: config error [invalid config attributes]")
+	}
+
+	c.level = zstd.SpeedDefault
+	if conf.Level != "" {
+		ok, level := zstd.EncoderLevelFromString(conf.Level)
+		if !ok {
+			log.Err("Compression::Configure : config error [invalid level %s]", conf.Level)
+			return fmt.Errorf("Compression: config error [invalid level %s]", conf.Level)
+		}
+		c.level = level
+	}
+
+	c.include = conf.Include
+	c.exclude = defaultExclude
+	if config.IsSet(compName + ".exclude") {
+		c.exclude = conf.Exclude
+	}
+	for _, pattern := range append(c.include, c.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.Err("Compression::Configure : config error [invalid pattern %s]", pattern)
+			return fmt.Errorf("Compression: config error [invalid pattern %s]", pattern)
+		}
+	}
+
+	c.tempPath = os.TempDir()
+	if conf.TempPath != "" {
+		c.tempPath = common.ExpandPath(conf.TempPath)
+	}
+
+	log.Crit(
+		"Compression::Configure : level %s, include %v, exclude %v, temp-path %s",
+		c.level,
+		c.include,
+		c.exclude,
+		c.tempPath,
+	)
+	return nil
+}
+
+// OnConfigChange : If component has registered, on config file change this method is called
+func (c *Compression) OnConfigChange() {
+}
+
+// matches returns true if a pattern matches name. Patterns with a slash match the whole path,
+// others match the base name, and both ignore case.
+func matches(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		target := path.Base(name)
+		if strings.Contains(pattern, "/") {
+			target = name
+		}
+		if ok, _ := path.Match(pattern, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldCompress returns true if the include and exclude lists allow name to be compressed
+func (c *Compression) shouldCompress(name string) bool {
+	if len(c.include) > 0 && !matches(c.include, name) {
+		return false
+	}
+	return !matches(c.exclude, name)
+}
+
+func isInternalMetadataKey(key string) bool {
+	return strings.EqualFold(key, algorithmMetadataKey) || strings.EqualFold(key, sizeMetadataKey)
+}
+
+// uncompressedSize returns the size of the contents of a compressed object
+func uncompressedSize(metadata map[string]*string) (int64, bool) {
+	var algorithm, size *string
+	for key, value := range metadata {
+		switch {
+		case strings.EqualFold(key, algorithmMetadataKey):
+			algorithm = value
+		case strings.EqualFold(key, sizeMetadataKey):
+			size = value
+		}
+	}
+	if algorithm == nil || *algorithm != algorithmZstd || size == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(*size, 10, 64)
+	return n, err == nil
+}
+
+// decompressedAttr changes the attributes of a compressed object to describe its contents
+func decompressedAttr(attr *internal.ObjAttr) {
+	if attr.IsDir() || attr.IsSymlink() {
+		return
+	}
+	size, ok := uncompressedSize(attr.Metadata)
+	if !ok {
+		return
+	}
+	attr.Size = size
+	// the checksum is of the compressed object, not of the contents
+	attr.MD5 = nil
+	for key := range attr.Metadata {
+		if isInternalMetadataKey(key) {
+			delete(attr.Metadata, key)
+		}
+	}
+}
+
+func (c *Compression) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := c.NextComponent().GetAttr(options)
+	if err == nil {
+		decompressedAttr(attr)
+	}
+	return attr, err
+}
+
+// StreamDir reports the size of the contents of compressed files. Listings from S3 do not hold
+// metadata, so each file without any that could have been compressed is looked up.
+func (c *Compression) StreamDir(
+	options internal.StreamDirOptions,
+) ([]*internal.ObjAttr, string, error) {
+	attrs, token, err := c.NextComponent().StreamDir(options)
+
+	var wg sync.WaitGroup
+	lookups := make(chan struct{}, lookupConcurrency)
+	for idx, attr := range attrs {
+		if len(attr.Metadata) > 0 || attr.IsDir() || attr.IsSymlink() ||
+			!c.shouldCompress(attr.Path) {
+			decompressedAttr(attr)
+			continue
+		}
+		wg.Go(func() {
+			lookups <- struct{}{}
+			defer func() { <-lookups }()
+			fullAttr, err := c.NextComponent().GetAttr(
+				internal.GetAttrOptions{Name: attr.Path, RetrieveMetadata: true},
+			)
+			if err != nil {
+				log.Warn("Compression::StreamDir : Failed to look up %s [%s]", attr.Path, err)
+				return
+			}
+			decompressedAttr(fullAttr)
+			attrs[idx] = fullAttr
+		})
+	}
+	wg.Wait()
+
+	return attrs, token, err
+}
+
+// uploadMetadata returns metadata removing the keys that mark compressed objects, as the storage
+// keeps the metadata of the object an upload replaces
+func uploadMetadata(metadata map[string]*string) map[string]*string {
+	newMetadata := make(map[string]*string, len(metadata)+2)
+	for key, value := range metadata {
+		if !isInternalMetadataKey(key) {
+			newMetadata[key] = value
+		}
+	}
+	newMetadata[algorithmMetadataKey] = nil
+	newMetadata[sizeMetadataKey] = nil
+	return newMetadata
+}
+
+// compress writes the compressed contents of file to a new temp file, which the caller removes
+func (c *Compression) compress(file *os.File) (*os.File, error) {
+	tempFile, err := os.CreateTemp(c.tempPath, "cloudfuse-compression-*")
+	if err != nil {
+		return nil, err
+	}
+
+	err = func() error {
+		encoder, err := zstd.NewWriter(tempFile, zstd.WithEncoderLevel(c.level))
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(encoder, io.NewSectionReader(file, 0, 1<<63-1))
+		if err != nil {
+			_ = encoder.Close()
+			return err
+		}
+		err = encoder.Close()
+		if err != nil {
+			return err
+		}
+		_, err = tempFile.Seek(0, io.SeekStart)
+		return err
+	}()
+	if err != nil {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+		return nil, err
+	}
+	return tempFile, nil
+}
+
+func (c *Compression) CopyFromFile(options internal.CopyFromFileOptions) error {
+	log.Trace("Compression::CopyFromFile : Upload file %s", options.Name)
+
+	metadata := uploadMetadata(options.Metadata)
+	info, err := options.File.Stat()
+	if err != nil || !c.shouldCompress(options.Name) {
+		return c.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+			Name:     options.Name,
+			File:     options.File,
+			Metadata: metadata,
+			IfMatch:  options.IfMatch,
+			NewETag:  options.NewETag,
+		})
+	}
+
+	tempFile, err := c.compress(options.File)
+	if err != nil {
+		log.Err("Compression::CopyFromFile : Failed to compress %s [%s]", options.Name, err)
+		return err
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	// data that does not compress is uploaded as it is
+	compressedInfo, err := tempFile.Stat()
+	if err != nil || compressedInfo.Size() >= info.Size() {
+		log.Debug("Compression::CopyFromFile : %s does not compress", options.Name)
+		return c.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+			Name:     options.Name,
+			File:     options.File,
+			Metadata: metadata,
+			IfMatch:  options.IfMatch,
+			NewETag:  options.NewETag,
+		})
+	}
+
+	log.Debug(
+		"Compression::CopyFromFile : %s compressed from %d to %d bytes",
+		options.Name,
+		info.Size(),
+		compressedInfo.Size(),
+	)
+	metadata[algorithmMetadataKey] = new(algorithmZstd)
+	metadata[sizeMetadataKey] = new(strconv.FormatInt(info.Size(), 10))
+	return c.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+		Name:     options.Name,
+		File:     tempFile,
+		Metadata: metadata,
+		IfMatch:  options.IfMatch,
+		NewETag:  options.NewETag,
+	})
+}
+
+// download writes the decompressed contents of a compressed object to file
+func (c *Compression) download(name string, offset int64, count int64, file *os.File) error {
+	tempFile, err := os.CreateTemp(c.tempPath, "cloudfuse-compression-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	err = c.NextComponent().CopyToFile(internal.CopyToFileOptions{Name: name, File: tempFile})
+	if err != nil {
+		return err
+	}
+	_, err = tempFile.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	decoder, err := zstd.NewReader(tempFile)
+	if err != nil {
+		return err
+	}
+	defer decoder.Close()
+
+	// zstd streams can not be read from an offset, so everything before it is decompressed too
+	_, err = io.CopyN(io.Discard, decoder, offset)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	var reader io.Reader = decoder
+	if count > 0 {
+		reader = io.LimitReader(decoder, count)
+	}
+	_, err = io.Copy(file, reader)
+	return err
+}
+
+func (c *Compression) CopyToFile(options internal.CopyToFileOptions) error {
+	log.Trace("Compression::CopyToFile : Read file %s", options.Name)
+
+	attr, err := c.NextComponent().GetAttr(
+		internal.GetAttrOptions{Name: options.Name, RetrieveMetadata: true},
+	)
+	if err != nil {
+		return err
+	}
+	if _, ok := uncompressedSize(attr.Metadata); !ok {
+		return c.NextComponent().CopyToFile(options)
+	}
+
+	err = c.download(options.Name, options.Offset, options.Count, options.File)
+	if err != nil {
+		log.Err("Compression::CopyToFile : Failed to decompress %s [%s]", options.Name, err)
+	}
+	return err
+}
+
+// TruncateFile can not change a compressed object in place, so it is downloaded, truncated and
+// uploaded again
+func (c *Compression) TruncateFile(options internal.TruncateFileOptions) error {
+	log.Trace("Compression::TruncateFile : %s to %d bytes", options.Name, options.NewSize)
+
+	attr, err := c.NextComponent().GetAttr(
+		internal.GetAttrOptions{Name: options.Name, RetrieveMetadata: true},
+	)
+	if err != nil {
+		return err
+	}
+	size, ok := uncompressedSize(attr.Metadata)
+	if !ok {
+		return c.NextComponent().TruncateFile(options)
+	}
+
+	tempFile, err := os.CreateTemp(c.tempPath, "cloudfuse-compression-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	err = c.download(options.Name, 0, min(size, options.NewSize), tempFile)
+	if err == nil {
+		err = tempFile.Truncate(options.NewSize)
+	}
+	if err != nil {
+		log.Err("Compression::TruncateFile : Failed to truncate %s [%s]", options.Name, err)
+		return err
+	}
+
+	metadata := make(map[string]*string, len(attr.Metadata))
+	for key, value := range attr.Metadata {
+		if !internal.IsTimeMetadataKey(key) {
+			metadata[key] = value
+		}
+	}
+	return c.CopyFromFile(internal.CopyFromFileOptions{
+		Name:     options.Name,
+		File:     tempFile,
+		Metadata: metadata,
+	})
+}
+
+func (c *Compression) GetMetadata(options internal.GetMetadataOptions) (map[string]*string, error) {
+	metadata, err := c.NextComponent().GetMetadata(options)
+	for key := range metadata {
+		if isInternalMetadataKey(key) {
+			delete(metadata, key)
+		}
+	}
+	return metadata, err
+}
+
+func (c *Compression) SetMetadata(options internal.SetMetadataOptions) error {
+	for key := range options.Metadata {
+		if isInternalMetadataKey(key) {
+			log.Err("Compression::SetMetadata : %s is reserved [%s]", key, options.Name)
+			return syscall.EINVAL
+		}
+	}
+	return c.NextComponent().SetMetadata(options)
+}
+
+// ------------------------- Factory -------------------------------------------
+
+// Pipeline will call this method to create your object, initialize your variables here
+// << DO NOT DELETE ANY AUTO GENERATED CODE HERE >>
+func NewCompressionComponent() internal.Component {
+	comp := &Compression{}
+	comp.SetName(compName)
+	return comp
+}
+
+// On init register this component to pipeline and supply your constructor
+func init() {
+	internal.AddComponent(compName, NewCompressionComponent)
+}
@@ -0,0 +1,340 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package compression
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/component/loopback"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// metadataStore keeps the metadata loopback does not, and like S3 leaves it out of listings and
+// keeps the metadata of the object an upload replaces
+type metadataStore struct {
+	internal.Component
+	mu       sync.Mutex
+	metadata map[string]map[string]*string
+}
+
+func (ms *metadataStore) CopyFromFile(options internal.CopyFromFileOptions) error {
+	ms.mu.Lock()
+	ms.metadata[options.Name] = internal.KeepMetadata(ms.metadata[options.Name], options.Metadata)
+	ms.mu.Unlock()
+	return ms.Component.CopyFromFile(options)
+}
+
+func (ms *metadataStore) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := ms.Component.GetAttr(options)
+	if err == nil {
+		ms.mu.Lock()
+		attr.Metadata = maps.Clone(ms.metadata[options.Name])
+		ms.mu.Unlock()
+	}
+	return attr, err
+}
+
+func (ms *metadataStore) GetMetadata(
+	options internal.GetMetadataOptions,
+) (map[string]*string, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return maps.Clone(ms.metadata[options.Name]), nil
+}
+
+type compressionTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	compression *Compression
+	storage     *metadataStore
+	storagePath string
+}
+
+func (suite *compressionTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{})
+	if err != nil {
+		panic(fmt.Sprintf("Unable to set silent logger as default: %v", err))
+	}
+	suite.assert = assert.New(suite.T())
+	suite.storagePath = suite.T().TempDir()
+	suite.setupTestHelper("")
+}
+
+func (suite *compressionTestSuite) setupTestHelper(configuration string) {
+	configuration += fmt.Sprintf("loopbackfs:\n  path: %s\n", suite.storagePath)
+	suite.assert.NoError(config.ReadConfigFromReader(strings.NewReader(configuration)))
+
+	lb := loopback.NewLoopbackFSComponent()
+	suite.assert.NoError(lb.Configure(true))
+	suite.storage = &metadataStore{Component: lb, metadata: map[string]map[string]*string{}}
+	compression := NewCompressionComponent()
+	compression.SetNextComponent(suite.storage)
+	suite.assert.NoError(compression.Configure(true))
+	suite.compression = compression.(*Compression)
+
+	_ = suite.compression.Start(context.Background())
+}
+
+func (suite *compressionTestSuite) TearDownTest() {
+	_ = suite.compression.Stop()
+	config.ResetConfig()
+}
+
+func textData(size int) []byte {
+	line := []byte("2026-10-17T12:00:00Z INFO request handled in 12ms\n")
+	return bytes.Repeat(line, size/len(line)+1)[:size]
+}
+
+// upload writes data to name through CopyFromFile, the way file_cache uploads
+func (suite *compressionTestSuite) upload(name string, data []byte) {
+	file, err := os.CreateTemp(suite.T().TempDir(), "upload")
+	suite.assert.NoError(err)
+	defer file.Close()
+	_, err = file.Write(data)
+	suite.assert.NoError(err)
+	_, _ = file.Seek(0, 0)
+
+	err = suite.compression.CopyFromFile(internal.CopyFromFileOptions{
+		Name:     name,
+		File:     file,
+		Metadata: map[string]*string{"owner": new("test")},
+	})
+	suite.assert.NoError(err)
+}
+
+// download reads name through CopyToFile, the way file_cache downloads
+func (suite *compressionTestSuite) download(name string) []byte {
+	file, err := os.CreateTemp(suite.T().TempDir(), "download")
+	suite.assert.NoError(err)
+	defer file.Close()
+
+	err = suite.compression.CopyToFile(internal.CopyToFileOptions{Name: name, File: file})
+	suite.assert.NoError(err)
+	data, err := os.ReadFile(file.Name())
+	suite.assert.NoError(err)
+	return data
+}
+
+func (suite *compressionTestSuite) storedSize(name string) int64 {
+	info, err := os.Stat(filepath.Join(suite.storagePath, name))
+	suite.assert.NoError(err)
+	return info.Size()
+}
+
+func (suite *compressionTestSuite) TestDefault() {
+	suite.assert.Equal("compression", suite.compression.Name())
+	suite.assert.Equal(zstd.SpeedDefault, suite.compression.level)
+	suite.assert.Empty(suite.compression.include)
+	suite.assert.Equal(defaultExclude, suite.compression.exclude)
+	suite.assert.Equal(os.TempDir(), suite.compression.tempPath)
+	suite.assert.Equal(internal.EComponentPriority.LevelThree(), suite.compression.Priority())
+}
+
+func (suite *compressionTestSuite) TestConfig() {
+	suite.setupTestHelper(
+		"compression:\n  level: best\n  include:\n    - \"*.log\"\n  exclude:\n    - \"tmp/*\"\n",
+	)
+	suite.assert.Equal(zstd.SpeedBestCompression, suite.compression.level)
+	suite.assert.Equal([]string{"*.log"}, suite.compression.include)
+	suite.assert.Equal([]string{"tmp/*"}, suite.compression.exclude)
+}
+
+func (suite *compressionTestSuite) TestConfigInvalid() {
+	configs := []string{
+		"compression:\n  level: smallest\n",
+		"compression:\n  include:\n    - \"[\"\n",
+	}
+	for _, cfg := range configs {
+		suite.assert.NoError(config.ReadConfigFromReader(strings.NewReader(cfg)))
+		compression := NewCompressionComponent()
+		suite.assert.Error(compression.Configure(true), cfg)
+	}
+}
+
+func (suite *compressionTestSuite) TestShouldCompress() {
+	suite.setupTestHelper(
+		"compression:\n  include:\n    - \"*.log\"\n    - \"data/*\"\n" +
+			"  exclude:\n    - \"*.gz\"\n    - \"data/raw*\"\n",
+	)
+	suite.assert.True(suite.compression.shouldCompress("app.log"))
+	suite.assert.True(suite.compression.shouldCompress("dir/APP.LOG"))
+	suite.assert.True(suite.compression.shouldCompress("data/table"))
+	suite.assert.False(suite.compression.shouldCompress("data/raw.bin"))
+	suite.assert.False(suite.compression.shouldCompress("dir/data/table"))
+	suite.assert.False(suite.compression.shouldCompress("app.txt"))
+	suite.assert.False(suite.compression.shouldCompress("data/old.gz"))
+}
+
+func (suite *compressionTestSuite) TestRoundTrip() {
+	name := "app.log"
+	data := textData(1024 * 1024)
+	suite.upload(name, data)
+
+	// the object is compressed, but its attributes describe the contents
+	suite.assert.Less(suite.storedSize(name), int64(len(data)/5))
+	suite.assert.Equal(algorithmZstd, *suite.storage.metadata[name][algorithmMetadataKey])
+	suite.assert.Equal("test", *suite.storage.metadata[name]["owner"])
+
+	attr, err := suite.compression.GetAttr(internal.GetAttrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+	suite.assert.Equal(map[string]*string{"owner": new("test")}, attr.Metadata)
+
+	metadata, err := suite.compression.GetMetadata(internal.GetMetadataOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.Equal(map[string]*string{"owner": new("test")}, metadata)
+
+	suite.assert.Equal(data, suite.download(name))
+
+	// a part of the contents
+	file, err := os.CreateTemp(suite.T().TempDir(), "part")
+	suite.assert.NoError(err)
+	defer file.Close()
+	err = suite.compression.CopyToFile(
+		internal.CopyToFileOptions{Name: name, Offset: 1000, Count: 500, File: file},
+	)
+	suite.assert.NoError(err)
+	part, _ := os.ReadFile(file.Name())
+	suite.assert.Equal(data[1000:1500], part)
+}
+
+func (suite *compressionTestSuite) TestExcluded() {
+	name := "archive.gz"
+	data := textData(100 * 1024)
+	suite.upload(name, data)
+
+	suite.assert.EqualValues(len(data), suite.storedSize(name))
+	suite.assert.NotContains(suite.storage.metadata[name], algorithmMetadataKey)
+	suite.assert.Equal(data, suite.download(name))
+}
+
+func (suite *compressionTestSuite) TestReplaceCompressed() {
+	name := "file.bin"
+	suite.upload(name, textData(100*1024))
+	suite.assert.Contains(suite.storage.metadata[name], algorithmMetadataKey)
+
+	// the object replacing a compressed one does not keep its compression keys
+	random := make([]byte, 64*1024)
+	_, _ = rand.Read(random)
+	suite.upload(name, random)
+	suite.assert.NotContains(suite.storage.metadata[name], algorithmMetadataKey)
+	suite.assert.NotContains(suite.storage.metadata[name], sizeMetadataKey)
+	suite.assert.Equal(random, suite.download(name))
+}
+
+func (suite *compressionTestSuite) TestIncompressible() {
+	name := "random.bin"
+	random := make([]byte, 64*1024)
+	_, _ = rand.Read(random)
+	suite.upload(name, random)
+
+	suite.assert.EqualValues(len(random), suite.storedSize(name))
+	suite.assert.NotContains(suite.storage.metadata[name], algorithmMetadataKey)
+	suite.assert.Equal(random, suite.download(name))
+}
+
+func (suite *compressionTestSuite) TestUncompressedObject() {
+	// objects written by other tools are read as they are
+	name := "plain.log"
+	data := textData(10 * 1024)
+	suite.assert.NoError(os.WriteFile(filepath.Join(suite.storagePath, name), data, 0644))
+
+	attr, err := suite.compression.GetAttr(internal.GetAttrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+	suite.assert.Equal(data, suite.download(name))
+}
+
+func (suite *compressionTestSuite) TestStreamDir() {
+	data := textData(100 * 1024)
+	suite.upload("a.log", data)
+	suite.upload("b.gz", data[:1000])
+	suite.assert.NoError(os.Mkdir(filepath.Join(suite.storagePath, "dir"), 0755))
+
+	attrs, _, err := suite.compression.StreamDir(internal.StreamDirOptions{Name: ""})
+	suite.assert.NoError(err)
+	suite.assert.Len(attrs, 3)
+	sizes := map[string]int64{}
+	for _, attr := range attrs {
+		if !attr.IsDir() {
+			sizes[attr.Name] = attr.Size
+		}
+	}
+	suite.assert.Equal(map[string]int64{"a.log": int64(len(data)), "b.gz": 1000}, sizes)
+}
+
+func (suite *compressionTestSuite) TestTruncateFile() {
+	name := "app.log"
+	data := textData(200 * 1024)
+	suite.upload(name, data)
+
+	for _, size := range []int{150 * 1024, 300 * 1024, 0} {
+		err := suite.compression.TruncateFile(
+			internal.TruncateFileOptions{Name: name, NewSize: int64(size)},
+		)
+		suite.assert.NoError(err)
+		if size < len(data) {
+			data = data[:size]
+		} else {
+			data = append(data, make([]byte, size-len(data))...)
+		}
+
+		attr, err := suite.compression.GetAttr(internal.GetAttrOptions{Name: name})
+		suite.assert.NoError(err)
+		suite.assert.EqualValues(size, attr.Size)
+		suite.assert.Equal(data, suite.download(name))
+		suite.assert.Equal("test", *suite.storage.metadata[name]["owner"])
+	}
+}
+
+func (suite *compressionTestSuite) TestSetMetadataReserved() {
+	err := suite.compression.SetMetadata(internal.SetMetadataOptions{
+		Name:     "app.log",
+		Metadata: map[string]*string{"Cloudfuse_Uncompressed_Size": new("1")},
+	})
+	suite.assert.Equal(syscall.EINVAL, err)
+}
+
+func TestCompression(t *testing.T) {
+	suite.Run(t, new(compressionTestSuite))
+}
@@ -0,0 +1,907 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/handlemap"
+)
+
+// Encryption encrypts the contents of files before they are handed to the storage component
+type Encryption struct {
+	internal.BaseComponent
+	keys     *keyring
+	tempPath string
+
+	// the object IDs of files read recently, so every read does not have to fetch them
+	objectIDs   map[string][]byte
+	objectIDsMu sync.Mutex
+
+	// block uploads in progress, by file name
+	uploads sync.Map
+}
+
+// blockUpload is the state of a file being uploaded in blocks
+type blockUpload struct {
+	sync.Mutex
+	objectID []byte
+	// the size of each staged block once encrypted, by block ID
+	sizes map[string]int64
+}
+
+// Structure defining your config parameters
+type EncryptionOptions struct {
+	KeyFile             string   `config:"key-file"             yaml:"key-file,omitempty"`
+	Passphrase          string   `config:"passphrase"           yaml:"passphrase,omitempty"`
+	PreviousKeyFiles    []string `config:"previous-key-files"   yaml:"previous-key-files,omitempty"`
+	PreviousPassphrases []string `config:"previous-passphrases" yaml:"previous-passphrases,omitempty"`
+	TempPath            string   `config:"temp-path"            yaml:"temp-path,omitempty"`
+}
+
+const compName = "encryption"
+
+// The ID of the key an object was last written with, so objects still using an old key can be found
+const keyIDMetadataKey = "cloudfuse_key_id"
+
+// The random ID of an object, every record of the object is bound to it
+const objectIDMetadataKey = "cloudfuse_object_id"
+
+// The object IDs remembered are dropped once there are this many of them
+const maxObjectIDs = 10000
+
+// Large transfers are split into windows of this many bytes of contents
+const copyWindow = 64 * chunkSize
+
+// Verification to check satisfaction criteria with Component Interface
+var _ internal.Component = &Encryption{}
+
+func (e *Encryption) Name() string {
+	return compName
+}
+
+func (e *Encryption) SetName(name string) {
+	e.BaseComponent.SetName(name)
+}
+
+func (e *Encryption) SetNextComponent(nc internal.Component) {
+	e.BaseComponent.SetNextComponent(nc)
+}
+
+func (e *Encryption) Priority() internal.ComponentPriority {
+	return internal.EComponentPriority.LevelThree()
+}
+
+// Start : Pipeline calls this method to start the component functionality
+//
+//	this shall not block the call otherwise pipeline will not start
+func (e *Encryption) Start(ctx context.Context) error {
+	log.Trace("Encryption::Start : Starting component %s", e.Name())
+	return nil
+}
+
+// Stop : Stop the component functionality and kill all threads started
+func (e *Encryption) Stop() error {
+	log.Trace("Encryption::Stop : Stopping component %s", e.Name())
+	return nil
+}
+
+// Configure : Pipeline will call this method after constructor so that you can read config and initialize yourself
+//
+//	Return failure if any config is not valid to exit the process
+func (e *Encryption) Configure(_ bool) error {
+	log.Trace("Encryption::Configure : %s", e.Name())
+
+	conf := EncryptionOptions{}
+	err := config.UnmarshalKey(e.Name(), &conf)
+	if err != nil {
+		log.Err("Encryption::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("Encryption: config error [invalid config attributes]")
+	}
+
+	if (conf.KeyFile == "") == (conf.Passphrase == "") {
+		log.Err("Encryption::Configure : config error [exactly one of key-file or passphrase]")
+		return fmt.Errorf("Encryption: config error [set exactly one of key-file or passphrase]")
+	}
+
+	// the current key goes in first, it is the one used to encrypt
+	e.keys = newKeyring()
+	keyFiles := conf.PreviousKeyFiles
+	passphrases := conf.PreviousPassphrases
+	if conf.KeyFile != "" {
+		keyFiles = append([]string{conf.KeyFile}, keyFiles...)
+	} else {
+		passphrases = append([]string{conf.Passphrase}, passphrases...)
+	}
+	for _, keyFile := range keyFiles {
+		key, err := readKeyFile(keyFile)
+		if err == nil {
+			_, err = e.keys.add(key)
+		}
+		if err != nil {
+			log.Err("Encryption::Configure : config error [%s]", err.Error())
+			return fmt.Errorf("Encryption: config error [%s]", err.Error())
+		}
+	}
+	for _, passphrase := range passphrases {
+		_, err = e.keys.add(passphraseKey(passphrase))
+		if err != nil {
+			log.Err("Encryption::Configure : config error [%s]", err.Error())
+			return fmt.Errorf("Encryption: config error [%s]", err.Error())
+		}
+	}
+
+	e.tempPath = os.TempDir()
+	if conf.TempPath != "" {
+		e.tempPath = common.ExpandPath(conf.TempPath)
+	}
+
+	log.Crit(
+		"Encryption::Configure : key id %s, %d previous keys, temp-path %s",
+		e.keys.current,
+		len(e.keys.keys)-1,
+		e.tempPath,
+	)
+	return nil
+}
+
+// OnConfigChange : If component has registered, on config file change this method is called
+func (e *Encryption) OnConfigChange() {
+}
+
+// decryptedAttr changes the attributes of an encrypted object to describe its contents
+func decryptedAttr(attr *internal.ObjAttr) {
+	if attr.IsDir() || attr.IsSymlink() {
+		return
+	}
+	attr.Size = plainSize(attr.Size)
+	// the checksum is of the encrypted object, not of the contents
+	attr.MD5 = nil
+}
+
+// isReservedKey returns true for the metadata keys kept by this component
+func isReservedKey(key string) bool {
+	return strings.EqualFold(key, keyIDMetadataKey) || strings.EqualFold(key, objectIDMetadataKey)
+}
+
+// writeMetadata returns the metadata to store with an object written with the current key
+func (e *Encryption) writeMetadata(
+	metadata map[string]*string,
+	objectID []byte,
+) map[string]*string {
+	newMetadata := make(map[string]*string, len(metadata)+2)
+	for key, value := range metadata {
+		if !isReservedKey(key) {
+			newMetadata[key] = value
+		}
+	}
+	newMetadata[keyIDMetadataKey] = new(e.keys.current.String())
+	newMetadata[objectIDMetadataKey] = new(hex.EncodeToString(objectID))
+	return newMetadata
+}
+
+// newObjectID returns a random ID for an object being written from the start
+func newObjectID() ([]byte, error) {
+	objectID := make([]byte, objectIDSize)
+	_, err := io.ReadFull(rand.Reader, objectID)
+	return objectID, err
+}
+
+// objectIDOf returns the object ID kept in metadata, or nil if there is none
+func objectIDOf(metadata map[string]*string) []byte {
+	for key, value := range metadata {
+		if strings.EqualFold(key, objectIDMetadataKey) && value != nil {
+			objectID, err := hex.DecodeString(*value)
+			if err == nil && len(objectID) == objectIDSize {
+				return objectID
+			}
+		}
+	}
+	return nil
+}
+
+// writeObjectID returns the object ID in metadata, or a new one if the object does not have one
+func writeObjectID(metadata map[string]*string) ([]byte, error) {
+	if objectID := objectIDOf(metadata); objectID != nil {
+		return objectID, nil
+	}
+	return newObjectID()
+}
+
+// contentsID returns the object ID of an encrypted object. Every object written by this
+// component has one and holds at least one record, anything else has been tampered with.
+func contentsID(attr *internal.ObjAttr) ([]byte, error) {
+	objectID := objectIDOf(attr.Metadata)
+	if objectID == nil && attr.Size == 0 {
+		// created empty and never written
+		return nil, nil
+	}
+	if objectID == nil || attr.Size < recordOverhead {
+		return nil, syscall.EIO
+	}
+	return objectID, nil
+}
+
+// rememberObjectID keeps the object ID of path for the reads that follow
+func (e *Encryption) rememberObjectID(path string, objectID []byte) {
+	e.objectIDsMu.Lock()
+	defer e.objectIDsMu.Unlock()
+	if len(e.objectIDs) >= maxObjectIDs {
+		clear(e.objectIDs)
+	}
+	e.objectIDs[path] = objectID
+}
+
+// objectID returns the object ID of path, fetching it again when refresh is set
+func (e *Encryption) objectID(path string, refresh bool) ([]byte, error) {
+	if !refresh {
+		e.objectIDsMu.Lock()
+		objectID, found := e.objectIDs[path]
+		e.objectIDsMu.Unlock()
+		if found {
+			return objectID, nil
+		}
+	}
+
+	attr, err := e.NextComponent().GetAttr(
+		internal.GetAttrOptions{Name: path, RetrieveMetadata: true},
+	)
+	if err != nil {
+		return nil, err
+	}
+	objectID, err := contentsID(attr)
+	if err != nil {
+		log.Err("Encryption::objectID : %s has no object ID or is truncated", path)
+		return nil, err
+	}
+	e.rememberObjectID(path, objectID)
+	return objectID, nil
+}
+
+func (e *Encryption) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := e.NextComponent().GetAttr(options)
+	if err == nil {
+		decryptedAttr(attr)
+	}
+	return attr, err
+}
+
+func (e *Encryption) StreamDir(
+	options internal.StreamDirOptions,
+) ([]*internal.ObjAttr, string, error) {
+	attrs, token, err := e.NextComponent().StreamDir(options)
+	for _, attr := range attrs {
+		decryptedAttr(attr)
+	}
+	return attrs, token, err
+}
+
+func (e *Encryption) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
+	log.Trace("Encryption::OpenFile : %s", options.Name)
+	handle, err := e.NextComponent().OpenFile(options)
+	if err == nil {
+		handle.Size = plainSize(handle.Size)
+	}
+	return handle, err
+}
+
+// readAt reads the contents of path at offset into data, size is the size of the contents
+func (e *Encryption) readAt(
+	ctx context.Context,
+	path string,
+	objectID []byte,
+	size int64,
+	offset int64,
+	data []byte,
+	etag *string,
+) (int, error) {
+	end := min(size, offset+int64(len(data)))
+	if end <= offset {
+		return 0, nil
+	}
+
+	// read every record that holds part of the range, and the record ending the object
+	// when the range reaches the end, so a truncated object is not taken for a shorter one
+	first := offset / chunkSize
+	last := (end - 1) / chunkSize
+	if end == size {
+		last = size / chunkSize
+	}
+	encryptedStart := first * recordSize
+	encrypted := make([]byte, min(encryptedSize(size), (last+1)*recordSize)-encryptedStart)
+
+	handle := handlemap.NewHandle(path)
+	handle.Size = encryptedSize(size)
+	n, err := e.NextComponent().ReadInBuffer(&internal.ReadInBufferOptions{
+		Handle: handle,
+		Offset: encryptedStart,
+		Data:   encrypted,
+		Etag:   etag,
+		Path:   path,
+		Size:   handle.Size,
+		Ctx:    ctx,
+	})
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if n < len(encrypted) {
+		log.Err("Encryption::readAt : %s is shorter than %d bytes", path, encryptedSize(size))
+		return 0, syscall.EIO
+	}
+
+	plain, isLast, err := e.keys.open(
+		make([]byte, 0, (last-first+1)*chunkSize),
+		encrypted,
+		objectID,
+		first,
+	)
+	if err == nil && end == size && !isLast {
+		err = errCorruptRecord
+	}
+	if err != nil {
+		log.Err("Encryption::readAt : Failed to decrypt %s [%s]", path, err.Error())
+		return 0, syscall.EIO
+	}
+	return copy(data, plain[offset-first*chunkSize:]), nil
+}
+
+func (e *Encryption) ReadInBuffer(options *internal.ReadInBufferOptions) (int, error) {
+	path, size := options.Path, options.Size
+	if options.Handle != nil {
+		path, size = options.Handle.Path, atomic.LoadInt64(&options.Handle.Size)
+	}
+	if options.Offset > size {
+		return 0, syscall.ERANGE
+	}
+	if options.Offset == size || len(options.Data) == 0 {
+		return 0, nil
+	}
+
+	ctx := cmp.Or(options.Ctx, context.Background())
+	objectID, err := e.objectID(path, false)
+	if err != nil {
+		return 0, err
+	}
+	n, err := e.readAt(ctx, path, objectID, size, options.Offset, options.Data, options.Etag)
+	if err == syscall.EIO {
+		// the object may have been written again since its ID was fetched
+		newID, idErr := e.objectID(path, true)
+		if idErr == nil && !bytes.Equal(newID, objectID) {
+			n, err = e.readAt(ctx, path, newID, size, options.Offset, options.Data, options.Etag)
+		}
+	}
+	return n, err
+}
+
+// writeAt encrypts data into path at offset, size is the size of the contents.
+// The chunks being written are read and encrypted again, and any gap before offset is zero filled.
+// A write reaching the end of the contents writes the record ending the object again.
+func (e *Encryption) writeAt(
+	handle *handlemap.Handle,
+	objectID []byte,
+	size int64,
+	offset int64,
+	data []byte,
+	metadata map[string]*string,
+) error {
+	// zero fill a large gap in windows, so it is never held in memory all at once
+	for offset-size > copyWindow {
+		err := e.writeAt(handle, objectID, size, size, make([]byte, copyWindow), metadata)
+		if err != nil {
+			return err
+		}
+		size += copyWindow
+	}
+
+	start := min(offset, size)
+	end := offset + int64(len(data))
+	newSize := max(size, end)
+	final := end >= size
+	first := start / chunkSize
+	last := (end - 1) / chunkSize
+	if final {
+		last = newSize / chunkSize
+	}
+	plain := make([]byte, min(newSize, (last+1)*chunkSize)-first*chunkSize)
+	if first*chunkSize < size {
+		_, err := e.readAt(
+			context.Background(),
+			handle.Path,
+			objectID,
+			size,
+			first*chunkSize,
+			plain,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	copy(plain[offset-first*chunkSize:], data)
+
+	encrypted, err := e.keys.seal(
+		make([]byte, 0, encryptedSize(int64(len(plain)))),
+		plain,
+		objectID,
+		first,
+		final,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = e.NextComponent().WriteFile(&internal.WriteFileOptions{
+		Handle:   handle,
+		Offset:   first * recordSize,
+		Data:     encrypted,
+		Metadata: e.writeMetadata(metadata, objectID),
+	})
+	if err == nil {
+		e.rememberObjectID(handle.Path, objectID)
+	}
+	return err
+}
+
+// contentsAttr returns the size of the contents of name, and its metadata less the times
+// kept in it, as they describe the contents before they are written
+func (e *Encryption) contentsAttr(name string) (int64, map[string]*string, error) {
+	attr, err := e.NextComponent().GetAttr(
+		internal.GetAttrOptions{Name: name, RetrieveMetadata: true},
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	metadata := make(map[string]*string, len(attr.Metadata))
+	for key, value := range attr.Metadata {
+		if !internal.IsTimeMetadataKey(key) {
+			metadata[key] = value
+		}
+	}
+	return plainSize(attr.Size), metadata, nil
+}
+
+func (e *Encryption) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	if len(options.Data) == 0 {
+		return 0, nil
+	}
+
+	size, metadata, err := e.contentsAttr(options.Handle.Path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Err(
+			"Encryption::WriteFile : Failed to get size of %s [%s]",
+			options.Handle.Path,
+			err.Error(),
+		)
+		return 0, err
+	}
+
+	// the object is written again, so keep what is already stored with it
+	if metadata == nil {
+		metadata = make(map[string]*string, len(options.Metadata))
+	}
+	maps.Copy(metadata, options.Metadata)
+
+	objectID, err := writeObjectID(metadata)
+	if err == nil {
+		err = e.writeAt(options.Handle, objectID, size, options.Offset, options.Data, metadata)
+	}
+	if err != nil {
+		log.Err("Encryption::WriteFile : Failed to write %s [%s]", options.Handle.Path, err.Error())
+		return 0, err
+	}
+	return len(options.Data), nil
+}
+
+func (e *Encryption) TruncateFile(options internal.TruncateFileOptions) error {
+	log.Trace("Encryption::TruncateFile : %s to %d bytes", options.Name, options.NewSize)
+
+	size, metadata, err := e.contentsAttr(options.Name)
+	if err != nil {
+		log.Err(
+			"Encryption::TruncateFile : Failed to get size of %s [%s]",
+			options.Name,
+			err.Error(),
+		)
+		return err
+	}
+
+	objectID, err := writeObjectID(metadata)
+	if err != nil {
+		return err
+	}
+
+	handle := options.Handle
+	if handle == nil {
+		handle = handlemap.NewHandle(options.Name)
+	}
+	if options.NewSize > size {
+		// the new contents are zeros, write the last one and everything before it is filled in
+		return e.writeAt(handle, objectID, size, options.NewSize-1, []byte{0}, metadata)
+	}
+
+	// keep the whole chunks, and encrypt what is left of the last one again
+	last := options.NewSize / chunkSize
+	tail := make([]byte, options.NewSize%chunkSize)
+	if len(tail) > 0 {
+		_, err = e.readAt(
+			context.Background(),
+			options.Name,
+			objectID,
+			size,
+			last*chunkSize,
+			tail,
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	options.OldSize = encryptedSize(size)
+	options.NewSize = last * recordSize
+	err = e.NextComponent().TruncateFile(options)
+	if err != nil {
+		return err
+	}
+	// the tail is written even when it is empty, it holds the record ending the object
+	return e.writeAt(handle, objectID, last*chunkSize, last*chunkSize, tail, metadata)
+}
+
+func (e *Encryption) CopyToFile(options internal.CopyToFileOptions) error {
+	log.Trace("Encryption::CopyToFile : Read file %s", options.Name)
+
+	attr, err := e.NextComponent().GetAttr(
+		internal.GetAttrOptions{Name: options.Name, RetrieveMetadata: true},
+	)
+	if err != nil {
+		return err
+	}
+	objectID, err := contentsID(attr)
+	if err != nil {
+		log.Err("Encryption::CopyToFile : %s has no object ID or is truncated", options.Name)
+		return err
+	}
+	size := plainSize(attr.Size)
+	end := size
+	if options.Count > 0 {
+		end = min(size, options.Offset+options.Count)
+	}
+
+	data := make([]byte, copyWindow)
+	for offset := options.Offset; offset < end; offset += copyWindow {
+		n, err := e.readAt(
+			context.Background(),
+			options.Name,
+			objectID,
+			size,
+			offset,
+			data[:min(copyWindow, end-offset)],
+			nil,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = options.File.Write(data[:n])
+		if err != nil {
+			log.Err("Encryption::CopyToFile : Failed to write %s [%s]", options.Name, err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encryption) CopyFromFile(options internal.CopyFromFileOptions) error {
+	log.Trace("Encryption::CopyFromFile : Upload file %s", options.Name)
+
+	// the storage component uploads from a file, so the encrypted copy is staged in one
+	tempFile, err := os.CreateTemp(e.tempPath, "cloudfuse-encryption-*")
+	if err != nil {
+		log.Err("Encryption::CopyFromFile : Failed to create temp file [%s]", err.Error())
+		return err
+	}
+	defer func() {
+		_ = tempFile.Close()
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	// the whole object is written again, so it gets a new ID
+	objectID, err := newObjectID()
+	if err != nil {
+		return err
+	}
+
+	reader := io.NewSectionReader(options.File, 0, 1<<63-1)
+	data := make([]byte, copyWindow)
+	encrypted := make([]byte, 0, encryptedSize(copyWindow))
+	for index := int64(0); ; index += copyWindow / chunkSize {
+		n, readErr := io.ReadFull(reader, data)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			log.Err("Encryption::CopyFromFile : Failed to read %s [%s]", options.Name, readErr)
+			return readErr
+		}
+		encrypted, err = e.keys.seal(encrypted[:0], data[:n], objectID, index, readErr != nil)
+		if err == nil {
+			_, err = tempFile.Write(encrypted)
+		}
+		if err != nil {
+			log.Err("Encryption::CopyFromFile : Failed to encrypt %s [%s]", options.Name, err)
+			return err
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	if _, err = tempFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	err = e.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+		Name:     options.Name,
+		File:     tempFile,
+		Metadata: e.writeMetadata(options.Metadata, objectID),
+		IfMatch:  options.IfMatch,
+		NewETag:  options.NewETag,
+	})
+	if err == nil {
+		e.rememberObjectID(options.Name, objectID)
+	}
+	return err
+}
+
+// StageData encrypts a block on its own, so blocks must start on a chunk boundary
+func (e *Encryption) StageData(options internal.StageDataOptions) error {
+	if options.Offset%chunkSize != 0 {
+		log.Err(
+			"Encryption::StageData : Block %s of %s is not a multiple of %d bytes",
+			options.Id,
+			options.Name,
+			chunkSize,
+		)
+		return syscall.EINVAL
+	}
+
+	upload, err := e.blockUpload(options.Name)
+	if err != nil {
+		return err
+	}
+	encrypted, err := e.keys.seal(
+		make([]byte, 0, sealedSize(int64(len(options.Data)))),
+		options.Data,
+		upload.objectID,
+		int64(options.Offset/chunkSize),
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	options.Data = encrypted
+	options.Offset = options.Offset / chunkSize * recordSize
+	err = e.NextComponent().StageData(options)
+	if err == nil {
+		upload.Lock()
+		upload.sizes[options.Id] = int64(len(encrypted))
+		upload.Unlock()
+	}
+	return err
+}
+
+// blockUpload returns the upload of name, blocks are staged with the ID the object already has
+// as the blocks which are not staged again are kept
+func (e *Encryption) blockUpload(name string) (*blockUpload, error) {
+	if upload, found := e.uploads.Load(name); found {
+		return upload.(*blockUpload), nil
+	}
+
+	_, metadata, err := e.contentsAttr(name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	objectID, err := writeObjectID(metadata)
+	if err != nil {
+		return nil, err
+	}
+	upload, _ := e.uploads.LoadOrStore(
+		name,
+		&blockUpload{objectID: objectID, sizes: make(map[string]int64)},
+	)
+	return upload.(*blockUpload), nil
+}
+
+// CommitData adds a block holding the record ending the object when the last block does not
+func (e *Encryption) CommitData(options internal.CommitDataOptions) error {
+	upload, err := e.blockUpload(options.Name)
+	if err != nil {
+		return err
+	}
+	defer e.uploads.Delete(options.Name)
+
+	_, metadata, err := e.contentsAttr(options.Name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if metadata == nil {
+		metadata = make(map[string]*string, len(options.Metadata))
+	}
+	maps.Copy(metadata, options.Metadata)
+
+	// the blocks which were not staged again are as large as they were when committed
+	upload.Lock()
+	sizes := maps.Clone(upload.sizes)
+	upload.Unlock()
+	for _, id := range options.List {
+		if _, found := sizes[id]; found {
+			continue
+		}
+		blockList, err := e.NextComponent().GetCommittedBlockList(options.Name)
+		if err != nil {
+			return err
+		}
+		if blockList != nil {
+			for _, block := range *blockList {
+				sizes[block.Id] = int64(block.Size)
+			}
+		}
+		break
+	}
+
+	var size int64
+	for _, id := range options.List {
+		size += plainSize(sizes[id])
+	}
+	list := options.List
+	if len(list) == 0 || sizes[list[len(list)-1]]%recordSize == 0 {
+		terminator, err := e.keys.seal(
+			make([]byte, 0, recordOverhead),
+			nil,
+			upload.objectID,
+			size/chunkSize,
+			true,
+		)
+		if err != nil {
+			return err
+		}
+		// a block list has to use IDs of one length
+		idLength := int64(common.BlockIDLength)
+		if len(list) > 0 {
+			idLength = common.GetIdLength(list[0])
+		}
+		id := common.GetBlockID(idLength)
+		err = e.NextComponent().StageData(internal.StageDataOptions{
+			Name:   options.Name,
+			Id:     id,
+			Data:   terminator,
+			Offset: uint64(size / chunkSize * recordSize),
+		})
+		if err != nil {
+			return err
+		}
+		list = append(slices.Clip(list), id)
+	}
+
+	options.List = list
+	options.BlockSize = uint64(sealedSize(int64(options.BlockSize)))
+	options.Metadata = e.writeMetadata(metadata, upload.objectID)
+	err = e.NextComponent().CommitData(options)
+	if err == nil {
+		e.rememberObjectID(options.Name, upload.objectID)
+	}
+	return err
+}
+
+func (e *Encryption) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
+	blockList, err := e.NextComponent().GetCommittedBlockList(name)
+	if err != nil || blockList == nil {
+		return blockList, err
+	}
+
+	// the block holding only the record ending the object is not part of the contents
+	blocks := *blockList
+	if len(blocks) > 0 && blocks[len(blocks)-1].Size == recordOverhead {
+		blocks = blocks[:len(blocks)-1]
+	}
+
+	plainList := make(internal.CommittedBlockList, 0, len(blocks))
+	var offset int64
+	for idx, block := range blocks {
+		// every block but the last has to end on a record boundary for the offsets to line up
+		if idx < len(blocks)-1 && block.Size%recordSize != 0 {
+			log.Err(
+				"Encryption::GetCommittedBlockList : Block %s of %s does not hold whole chunks",
+				block.Id,
+				name,
+			)
+			return nil, syscall.EIO
+		}
+		size := plainSize(int64(block.Size))
+		plainList = append(plainList, internal.CommittedBlock{
+			Id:     block.Id,
+			Offset: offset,
+			Size:   uint64(size),
+		})
+		offset += size
+	}
+	return &plainList, nil
+}
+
+// GetFileBlockOffsets is only used to upload blocks directly, which would bypass encryption
+func (e *Encryption) GetFileBlockOffsets(
+	options internal.GetFileBlockOffsetsOptions,
+) (*common.BlockOffsetList, error) {
+	return nil, syscall.ENOTSUP
+}
+
+func (e *Encryption) GetMetadata(options internal.GetMetadataOptions) (map[string]*string, error) {
+	metadata, err := e.NextComponent().GetMetadata(options)
+	for key := range metadata {
+		if isReservedKey(key) {
+			delete(metadata, key)
+		}
+	}
+	return metadata, err
+}
+
+func (e *Encryption) SetMetadata(options internal.SetMetadataOptions) error {
+	for key := range options.Metadata {
+		if isReservedKey(key) {
+			log.Err("Encryption::SetMetadata : %s is reserved [%s]", key, options.Name)
+			return syscall.EINVAL
+		}
+	}
+	return e.NextComponent().SetMetadata(options)
+}
+
+// ------------------------- Factory -------------------------------------------
+
+// Pipeline will call this method to create your object, initialize your variables here
+// << DO NOT DELETE ANY AUTO GENERATED CODE HERE >>
+func NewEncryptionComponent() internal.Component {
+	comp := &Encryption{objectIDs: make(map[string][]byte)}
+	comp.SetName(compName)
+	return comp
+}
+
+// On init register this component to pipeline and supply your constructor
+func init() {
+	internal.AddComponent(compName, NewEncryptionComponent)
+}
@@ -0,0 +1,571 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/component/loopback"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/handlemap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// metadataStore keeps object metadata in memory, as loopback has nowhere to store it
+type metadataStore struct {
+	internal.Component
+	mu       sync.Mutex
+	metadata map[string]map[string]*string
+}
+
+func (m *metadataStore) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := m.Component.GetAttr(options)
+	if err == nil && options.RetrieveMetadata {
+		m.mu.Lock()
+		attr.Metadata = maps.Clone(m.metadata[options.Name])
+		m.mu.Unlock()
+	}
+	return attr, err
+}
+
+func (m *metadataStore) store(name string, metadata map[string]*string) {
+	if metadata != nil {
+		m.mu.Lock()
+		m.metadata[name] = maps.Clone(metadata)
+		m.mu.Unlock()
+	}
+}
+
+func (m *metadataStore) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	n, err := m.Component.WriteFile(options)
+	if err == nil {
+		m.store(options.Handle.Path, options.Metadata)
+	}
+	return n, err
+}
+
+func (m *metadataStore) CopyFromFile(options internal.CopyFromFileOptions) error {
+	err := m.Component.CopyFromFile(options)
+	if err == nil {
+		m.store(options.Name, options.Metadata)
+	}
+	return err
+}
+
+func (m *metadataStore) CommitData(options internal.CommitDataOptions) error {
+	err := m.Component.CommitData(options)
+	if err == nil {
+		m.store(options.Name, options.Metadata)
+	}
+	return err
+}
+
+func (m *metadataStore) GetMetadata(
+	options internal.GetMetadataOptions,
+) (map[string]*string, error) {
+	if _, err := m.Component.GetMetadata(options); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return maps.Clone(m.metadata[options.Name]), nil
+}
+
+// SetMetadata adds to the metadata of an object, as the storage components do
+func (m *metadataStore) SetMetadata(options internal.SetMetadataOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.metadata[options.Name] == nil {
+		m.metadata[options.Name] = make(map[string]*string)
+	}
+	maps.Copy(m.metadata[options.Name], options.Metadata)
+	return nil
+}
+
+type encryptionTestSuite struct {
+	suite.Suite
+	assert      *assert.Assertions
+	encryption  *Encryption
+	loopback    internal.Component
+	metadata    map[string]map[string]*string
+	storagePath string
+	keyPath     string
+}
+
+func (suite *encryptionTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{})
+	if err != nil {
+		panic(fmt.Sprintf("Unable to set silent logger as default: %v", err))
+	}
+	suite.assert = assert.New(suite.T())
+
+	dir := suite.T().TempDir()
+	suite.storagePath = filepath.Join(dir, "storage")
+	suite.assert.NoError(os.Mkdir(suite.storagePath, 0755))
+	suite.keyPath = suite.writeKeyFile(dir, "key")
+	suite.metadata = make(map[string]map[string]*string)
+
+	suite.setupTestHelper(fmt.Sprintf("encryption:\n  key-file: %s\n", suite.keyPath))
+}
+
+func (suite *encryptionTestSuite) setupTestHelper(configuration string) {
+	configuration += fmt.Sprintf("loopbackfs:\n  path: %s\n", suite.storagePath)
+	suite.assert.NoError(config.ReadConfigFromReader(strings.NewReader(configuration)))
+
+	suite.loopback = &metadataStore{
+		Component: loopback.NewLoopbackFSComponent(),
+		metadata:  suite.metadata,
+	}
+	suite.assert.NoError(suite.loopback.Configure(true))
+	encryption := NewEncryptionComponent()
+	encryption.SetNextComponent(suite.loopback)
+	suite.assert.NoError(encryption.Configure(true))
+	suite.encryption = encryption.(*Encryption)
+
+	_ = suite.loopback.Start(context.Background())
+	_ = suite.encryption.Start(context.Background())
+}
+
+func (suite *encryptionTestSuite) TearDownTest() {
+	_ = suite.encryption.Stop()
+	_ = suite.loopback.Stop()
+	config.ResetConfig()
+}
+
+func (suite *encryptionTestSuite) writeKeyFile(dir string, name string) string {
+	key := make([]byte, common.KeyLength)
+	_, _ = rand.Read(key)
+	path := filepath.Join(dir, name)
+	suite.assert.NoError(os.WriteFile(path, []byte(hex.EncodeToString(key)), 0600))
+	return path
+}
+
+func randomData(size int) []byte {
+	data := make([]byte, size)
+	_, _ = rand.Read(data)
+	return data
+}
+
+// upload writes data to name through CopyFromFile, the way file_cache uploads
+func (suite *encryptionTestSuite) upload(name string, data []byte) {
+	file, err := os.CreateTemp(suite.T().TempDir(), "upload")
+	suite.assert.NoError(err)
+	defer file.Close()
+	_, err = file.Write(data)
+	suite.assert.NoError(err)
+	_, _ = file.Seek(0, 0)
+
+	err = suite.encryption.CopyFromFile(internal.CopyFromFileOptions{Name: name, File: file})
+	suite.assert.NoError(err)
+}
+
+// download reads name through CopyToFile, the way file_cache downloads
+func (suite *encryptionTestSuite) download(name string) []byte {
+	file, err := os.CreateTemp(suite.T().TempDir(), "download")
+	suite.assert.NoError(err)
+	defer file.Close()
+
+	err = suite.encryption.CopyToFile(internal.CopyToFileOptions{Name: name, File: file})
+	suite.assert.NoError(err)
+	data, err := os.ReadFile(file.Name())
+	suite.assert.NoError(err)
+	return data
+}
+
+func (suite *encryptionTestSuite) TestDefault() {
+	suite.assert.Equal("encryption", suite.encryption.Name())
+	suite.assert.Equal(os.TempDir(), suite.encryption.tempPath)
+	suite.assert.Len(suite.encryption.keys.keys, 1)
+	suite.assert.Equal(internal.EComponentPriority.LevelThree(), suite.encryption.Priority())
+}
+
+func (suite *encryptionTestSuite) TestConfigPassphrase() {
+	suite.setupTestHelper("encryption:\n  passphrase: correct horse battery staple\n")
+	key, err := newEncryptionKey(passphraseKey("correct horse battery staple"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(key.String(), suite.encryption.keys.current.String())
+}
+
+func (suite *encryptionTestSuite) TestConfigInvalid() {
+	configs := []string{
+		"encryption:\n  temp-path: /tmp\n",
+		fmt.Sprintf("encryption:\n  key-file: %s\n  passphrase: secret\n", suite.keyPath),
+		"encryption:\n  key-file: /does/not/exist\n",
+	}
+	for _, cfg := range configs {
+		suite.assert.NoError(config.ReadConfigFromReader(strings.NewReader(cfg)))
+		encryption := NewEncryptionComponent()
+		suite.assert.Error(encryption.Configure(true), cfg)
+	}
+}
+
+func (suite *encryptionTestSuite) TestRoundTrip() {
+	name := "file"
+	data := randomData(3*chunkSize + 123)
+	suite.upload(name, data)
+
+	// the object is encrypted, but its attributes describe the contents
+	stored, err := os.ReadFile(filepath.Join(suite.storagePath, name))
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(encryptedSize(int64(len(data))), len(stored))
+	suite.assert.False(bytes.Contains(stored, data[:64]))
+
+	attr, err := suite.encryption.GetAttr(internal.GetAttrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+
+	attrs, _, err := suite.encryption.StreamDir(internal.StreamDirOptions{Name: ""})
+	suite.assert.NoError(err)
+	suite.assert.Len(attrs, 1)
+	suite.assert.EqualValues(len(data), attrs[0].Size)
+
+	suite.assert.Equal(data, suite.download(name))
+}
+
+func (suite *encryptionTestSuite) TestReadInBuffer() {
+	name := "file"
+	data := randomData(4*chunkSize + 10)
+	suite.upload(name, data)
+
+	handle, err := suite.encryption.OpenFile(
+		internal.OpenFileOptions{Name: name, Flags: os.O_RDONLY},
+	)
+	suite.assert.NoError(err)
+	// loopback does not size its handles the way the storage components do
+	handle.Size = int64(len(data))
+
+	// ranges within a chunk, across chunks and past the end of the file
+	ranges := [][2]int{{0, 10}, {chunkSize - 5, 10}, {100, 2*chunkSize + 1}, {len(data) - 4, 100}}
+	for _, r := range ranges {
+		buf := make([]byte, r[1])
+		n, err := suite.encryption.ReadInBuffer(
+			&internal.ReadInBufferOptions{Handle: handle, Offset: int64(r[0]), Data: buf},
+		)
+		suite.assert.NoError(err)
+		end := min(r[0]+r[1], len(data))
+		suite.assert.Equal(end-r[0], n)
+		suite.assert.Equal(data[r[0]:end], buf[:n])
+	}
+
+	// without a handle, the way xload reads
+	buf := make([]byte, 20)
+	n, err := suite.encryption.ReadInBuffer(&internal.ReadInBufferOptions{
+		Path:   name,
+		Size:   int64(len(data)),
+		Offset: chunkSize,
+		Data:   buf,
+	})
+	suite.assert.NoError(err)
+	suite.assert.Equal(20, n)
+	suite.assert.Equal(data[chunkSize:chunkSize+20], buf)
+
+	_, err = suite.encryption.ReadInBuffer(
+		&internal.ReadInBufferOptions{Handle: handle, Offset: int64(len(data) + 1), Data: buf},
+	)
+	suite.assert.Equal(syscall.ERANGE, err)
+	suite.assert.NoError(suite.encryption.ReleaseFile(internal.ReleaseFileOptions{Handle: handle}))
+}
+
+func (suite *encryptionTestSuite) TestReadCorrupt() {
+	name := "file"
+	suite.upload(name, randomData(100))
+
+	path := filepath.Join(suite.storagePath, name)
+	stored, err := os.ReadFile(path)
+	suite.assert.NoError(err)
+	stored[len(stored)-1] ^= 1
+	suite.assert.NoError(os.WriteFile(path, stored, 0644))
+
+	buf := make([]byte, 100)
+	_, err = suite.encryption.ReadInBuffer(
+		&internal.ReadInBufferOptions{Path: name, Size: 100, Data: buf},
+	)
+	suite.assert.Equal(syscall.EIO, err)
+}
+
+// copyToFile reads name through CopyToFile and returns the error
+func (suite *encryptionTestSuite) copyToFile(name string) error {
+	file, err := os.CreateTemp(suite.T().TempDir(), "download")
+	suite.assert.NoError(err)
+	defer file.Close()
+	return suite.encryption.CopyToFile(internal.CopyToFileOptions{Name: name, File: file})
+}
+
+func (suite *encryptionTestSuite) TestReadTruncated() {
+	name := "file"
+	path := filepath.Join(suite.storagePath, name)
+
+	// cut at a record boundary, with and without a partial last chunk, and to nothing
+	cases := []struct{ size, cut int }{
+		{2 * chunkSize, 2 * recordSize},
+		{2 * chunkSize, recordSize},
+		{2*chunkSize + 10, 2 * recordSize},
+		{100, 0},
+	}
+	for _, c := range cases {
+		suite.upload(name, randomData(c.size))
+		suite.assert.NoError(os.Truncate(path, int64(c.cut)))
+		suite.assert.Equal(syscall.EIO, suite.copyToFile(name), c)
+
+		buf := make([]byte, 10)
+		_, err := suite.encryption.ReadInBuffer(&internal.ReadInBufferOptions{
+			Path:   name,
+			Size:   plainSize(int64(c.cut)),
+			Offset: max(plainSize(int64(c.cut))-10, 0),
+			Data:   buf,
+		})
+		if c.cut > 0 {
+			suite.assert.Equal(syscall.EIO, err, c)
+		}
+	}
+}
+
+func (suite *encryptionTestSuite) TestReadReordered() {
+	name := "file"
+	path := filepath.Join(suite.storagePath, name)
+	data := randomData(3*chunkSize + 10)
+	suite.upload(name, data)
+	stored, err := os.ReadFile(path)
+	suite.assert.NoError(err)
+
+	// swap the first two records
+	swapped := slices.Concat(
+		stored[recordSize:2*recordSize],
+		stored[:recordSize],
+		stored[2*recordSize:],
+	)
+	suite.assert.NoError(os.WriteFile(path, swapped, 0644))
+	suite.assert.Equal(syscall.EIO, suite.copyToFile(name))
+
+	// a record taken from the same place in another object
+	suite.upload("other", randomData(len(data)))
+	other, err := os.ReadFile(filepath.Join(suite.storagePath, "other"))
+	suite.assert.NoError(err)
+	copy(stored[recordSize:], other[recordSize:2*recordSize])
+	suite.assert.NoError(os.WriteFile(path, stored, 0644))
+
+	buf := make([]byte, 10)
+	_, err = suite.encryption.ReadInBuffer(&internal.ReadInBufferOptions{
+		Path:   name,
+		Size:   int64(len(data)),
+		Offset: chunkSize,
+		Data:   buf,
+	})
+	suite.assert.Equal(syscall.EIO, err)
+	_, err = suite.encryption.ReadInBuffer(
+		&internal.ReadInBufferOptions{Path: name, Size: int64(len(data)), Data: buf},
+	)
+	suite.assert.NoError(err)
+}
+
+func (suite *encryptionTestSuite) openFile(name string) *handlemap.Handle {
+	handle, err := suite.encryption.OpenFile(
+		internal.OpenFileOptions{Name: name, Flags: os.O_RDWR, Mode: 0644},
+	)
+	suite.assert.NoError(err)
+	return handle
+}
+
+func (suite *encryptionTestSuite) TestWriteFile() {
+	name := "file"
+	data := randomData(2*chunkSize + 50)
+	suite.upload(name, data)
+	handle := suite.openFile(name)
+
+	// overwrite across a chunk boundary, then write past the end leaving a gap
+	patch := randomData(100)
+	copy(data[chunkSize-50:], patch)
+	n, err := suite.encryption.WriteFile(
+		&internal.WriteFileOptions{Handle: handle, Offset: chunkSize - 50, Data: patch},
+	)
+	suite.assert.NoError(err)
+	suite.assert.Equal(len(patch), n)
+
+	offset := len(data) + chunkSize + 7
+	data = append(data, make([]byte, chunkSize+7)...)
+	data = append(data, patch...)
+	_, err = suite.encryption.WriteFile(
+		&internal.WriteFileOptions{Handle: handle, Offset: int64(offset), Data: patch},
+	)
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.encryption.ReleaseFile(internal.ReleaseFileOptions{Handle: handle}))
+
+	attr, err := suite.encryption.GetAttr(internal.GetAttrOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.EqualValues(len(data), attr.Size)
+	suite.assert.Equal(data, suite.download(name))
+}
+
+func (suite *encryptionTestSuite) TestWriteFileKeepsMetadata() {
+	name := "file"
+	suite.upload(name, randomData(100))
+	err := suite.encryption.SetMetadata(internal.SetMetadataOptions{
+		Name:     name,
+		Metadata: map[string]*string{"user.key": new("value")},
+	})
+	suite.assert.NoError(err)
+
+	handle := suite.openFile(name)
+	_, err = suite.encryption.WriteFile(
+		&internal.WriteFileOptions{Handle: handle, Offset: 10, Data: []byte("data")},
+	)
+	suite.assert.NoError(err)
+	suite.assert.NoError(suite.encryption.ReleaseFile(internal.ReleaseFileOptions{Handle: handle}))
+
+	metadata, err := suite.encryption.GetMetadata(internal.GetMetadataOptions{Name: name})
+	suite.assert.NoError(err)
+	suite.assert.Len(metadata, 1)
+	suite.assert.Equal("value", *metadata["user.key"])
+}
+
+func (suite *encryptionTestSuite) TestTruncateFile() {
+	name := "file"
+	data := randomData(3*chunkSize + 50)
+	suite.upload(name, data)
+	handle := suite.openFile(name)
+
+	sizes := []int{2*chunkSize + 10, 2 * chunkSize, 2*chunkSize + 100, 5*chunkSize + 1, 0}
+	for _, size := range sizes {
+		err := suite.encryption.TruncateFile(
+			internal.TruncateFileOptions{Name: name, Handle: handle, NewSize: int64(size)},
+		)
+		suite.assert.NoError(err)
+		if size < len(data) {
+			data = data[:size]
+		} else {
+			data = append(data, make([]byte, size-len(data))...)
+		}
+
+		attr, err := suite.encryption.GetAttr(internal.GetAttrOptions{Name: name})
+		suite.assert.NoError(err)
+		suite.assert.EqualValues(size, attr.Size)
+		suite.assert.Equal(data, suite.download(name))
+	}
+	suite.assert.NoError(suite.encryption.ReleaseFile(internal.ReleaseFileOptions{Handle: handle}))
+}
+
+func (suite *encryptionTestSuite) TestRotation() {
+	name := "file"
+	data := randomData(chunkSize + 1)
+	suite.upload(name, data)
+
+	// a new key can only read the file once the old one is configured as a previous key
+	newKeyPath := suite.writeKeyFile(suite.T().TempDir(), "new")
+	suite.setupTestHelper(fmt.Sprintf("encryption:\n  key-file: %s\n", newKeyPath))
+	buf := make([]byte, 10)
+	_, err := suite.encryption.ReadInBuffer(
+		&internal.ReadInBufferOptions{Path: name, Size: int64(len(data)), Data: buf},
+	)
+	suite.assert.Equal(syscall.EIO, err)
+
+	suite.setupTestHelper(fmt.Sprintf(
+		"encryption:\n  key-file: %s\n  previous-key-files:\n    - %s\n",
+		newKeyPath,
+		suite.keyPath,
+	))
+	suite.assert.Len(suite.encryption.keys.keys, 2)
+	suite.assert.Equal(data, suite.download(name))
+
+	// uploading the file again encrypts it with the new key
+	suite.upload(name, data)
+	suite.setupTestHelper(fmt.Sprintf("encryption:\n  key-file: %s\n", newKeyPath))
+	suite.assert.Equal(data, suite.download(name))
+}
+
+func (suite *encryptionTestSuite) TestStageAndCommitData() {
+	name := "file"
+	data := randomData(4 * chunkSize)
+	ids := []string{common.GetBlockID(common.BlockIDLength), common.GetBlockID(common.BlockIDLength)}
+	for i, id := range ids {
+		err := suite.encryption.StageData(internal.StageDataOptions{
+			Name:   name,
+			Id:     id,
+			Data:   data[i*2*chunkSize : (i+1)*2*chunkSize],
+			Offset: uint64(i * 2 * chunkSize),
+		})
+		suite.assert.NoError(err)
+	}
+	err := suite.encryption.CommitData(
+		internal.CommitDataOptions{Name: name, List: ids, BlockSize: 2 * chunkSize},
+	)
+	suite.assert.NoError(err)
+
+	// the last block ends on a chunk boundary, so a block ending the object was added
+	info, err := os.Stat(filepath.Join(suite.storagePath, name))
+	suite.assert.NoError(err)
+	suite.assert.Equal(encryptedSize(int64(len(data))), info.Size())
+	suite.assert.Equal(data, suite.download(name))
+}
+
+func (suite *encryptionTestSuite) TestStageData() {
+	err := suite.encryption.StageData(
+		internal.StageDataOptions{Name: "file", Offset: chunkSize + 1, Data: []byte("data")},
+	)
+	suite.assert.Equal(syscall.EINVAL, err)
+}
+
+func (suite *encryptionTestSuite) TestSetMetadataReserved() {
+	err := suite.encryption.SetMetadata(internal.SetMetadataOptions{
+		Name:     "file",
+		Metadata: map[string]*string{"Cloudfuse_Key_Id": new("0011223344556677")},
+	})
+	suite.assert.Equal(syscall.EINVAL, err)
+}
+
+func (suite *encryptionTestSuite) TestWriteMetadata() {
+	objectID, err := newObjectID()
+	suite.assert.NoError(err)
+	metadata := suite.encryption.writeMetadata(map[string]*string{
+		"key":                 new("value"),
+		keyIDMetadataKey:      new("stale"),
+		"Cloudfuse_Object_Id": new("stale"),
+	}, objectID)
+	suite.assert.Len(metadata, 3)
+	suite.assert.Equal("value", *metadata["key"])
+	suite.assert.Equal(suite.encryption.keys.current.String(), *metadata[keyIDMetadataKey])
+	suite.assert.Equal(objectID, objectIDOf(metadata))
+}
+
+func TestEncryption(t *testing.T) {
+	suite.Run(t, new(encryptionTestSuite))
+}
@@ -0,0 +1,264 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Seagate/cloudfuse/common"
+)
+
+const (
+	// Contents are encrypted in chunks of this size, so any range can be read on its own
+	chunkSize = 64 * 1024
+
+	keyIDSize    = 8
+	nonceSize    = 12
+	tagSize      = 16
+	objectIDSize = 16
+
+	// Each chunk is stored as a record of the key ID, a random nonce, the ciphertext and the tag
+	recordOverhead = keyIDSize + nonceSize + tagSize
+	recordSize     = chunkSize + recordOverhead
+
+	keyIDLabel     = "cloudfuse encryption key id"
+	passphraseSalt = "cloudfuse encryption passphrase"
+)
+
+var errUnknownKey = errors.New("record was encrypted with an unknown key")
+var errCorruptRecord = errors.New("record is corrupt")
+
+// encryptionKey is an AES-256-GCM key and the ID recorded with everything it encrypts
+type encryptionKey struct {
+	id   [keyIDSize]byte
+	aead cipher.AEAD
+}
+
+func newEncryptionKey(key []byte) (*encryptionKey, error) {
+	if len(key) != common.KeyLength {
+		return nil, fmt.Errorf("key must be %d bytes long", common.KeyLength)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// the ID is a MAC so it can be published without revealing anything about the key
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(keyIDLabel))
+	k := &encryptionKey{aead: aead}
+	copy(k.id[:], mac.Sum(nil))
+	return k, nil
+}
+
+func (k *encryptionKey) String() string {
+	return hex.EncodeToString(k.id[:])
+}
+
+// readKeyFile reads a key stored as raw bytes, hex or base64
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(common.ExpandPath(path))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == common.KeyLength {
+		return data, nil
+	}
+
+	text := strings.TrimSpace(string(data))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == common.KeyLength {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil &&
+		len(key) == common.KeyLength {
+		return key, nil
+	}
+	return nil, fmt.Errorf(
+		"key file %s must hold a %d byte key as raw bytes, hex or base64",
+		path,
+		common.KeyLength,
+	)
+}
+
+// passphraseKey derives a key from a passphrase the same way secure config files are encrypted.
+// The salt is fixed so the same passphrase gives the same key on every mount.
+func passphraseKey(passphrase string) []byte {
+	salt := sha256.Sum256([]byte(passphraseSalt))
+	return common.DeriveKey([]byte(passphrase), salt[:common.SaltLength])
+}
+
+// keyring encrypts with the current key and decrypts with any key it holds
+type keyring struct {
+	current *encryptionKey
+	keys    map[[keyIDSize]byte]*encryptionKey
+}
+
+func newKeyring() *keyring {
+	return &keyring{keys: make(map[[keyIDSize]byte]*encryptionKey)}
+}
+
+// add puts a key in the keyring, the first key added is used to encrypt
+func (kr *keyring) add(key []byte) (*encryptionKey, error) {
+	k, err := newEncryptionKey(key)
+	clear(key)
+	if err != nil {
+		return nil, err
+	}
+	if kr.current == nil {
+		kr.current = k
+	}
+	kr.keys[k.id] = k
+	return k, nil
+}
+
+// additionalData is authenticated with a record, so it cannot be moved to another object or
+// another position, and the last record of an object cannot be dropped unnoticed
+func additionalData(keyID []byte, objectID []byte, index int64, last bool) []byte {
+	data := make([]byte, 0, keyIDSize+objectIDSize+9)
+	data = append(data, keyID...)
+	data = append(data, objectID...)
+	data = binary.BigEndian.AppendUint64(data, uint64(index))
+	if last {
+		return append(data, 1)
+	}
+	return append(data, 0)
+}
+
+// seal appends the records holding plain to dst, starting at chunk index of the object.
+// A chunk shorter than chunkSize is the last of the object. When final is set and plain ends
+// on a chunk boundary, an empty record is added to mark the end.
+func (kr *keyring) seal(
+	dst []byte,
+	plain []byte,
+	objectID []byte,
+	index int64,
+	final bool,
+) ([]byte, error) {
+	var nonce [nonceSize]byte
+	for len(plain) > 0 || final {
+		n := min(len(plain), chunkSize)
+		if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+			return nil, err
+		}
+		last := n < chunkSize
+		dst = append(dst, kr.current.id[:]...)
+		dst = append(dst, nonce[:]...)
+		dst = kr.current.aead.Seal(
+			dst,
+			nonce[:],
+			plain[:n],
+			additionalData(kr.current.id[:], objectID, index, last),
+		)
+		plain = plain[n:]
+		index++
+		if last {
+			break
+		}
+	}
+	return dst, nil
+}
+
+// open appends the contents of the records in data to dst, data must hold whole records and
+// start at chunk index of the object. It reports whether the last record read ends the object.
+func (kr *keyring) open(
+	dst []byte,
+	data []byte,
+	objectID []byte,
+	index int64,
+) ([]byte, bool, error) {
+	last := false
+	for len(data) > 0 {
+		n := min(len(data), recordSize)
+		if n < recordOverhead || last {
+			return nil, false, errCorruptRecord
+		}
+		record := data[:n]
+		key, found := kr.keys[[keyIDSize]byte(record[:keyIDSize])]
+		if !found {
+			return nil, false, fmt.Errorf(
+				"%w [%s]",
+				errUnknownKey,
+				hex.EncodeToString(record[:keyIDSize]),
+			)
+		}
+
+		var err error
+		last = n < recordSize
+		dst, err = key.aead.Open(
+			dst,
+			record[keyIDSize:keyIDSize+nonceSize],
+			record[keyIDSize+nonceSize:],
+			additionalData(record[:keyIDSize], objectID, index, last),
+		)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w [%s]", errCorruptRecord, err.Error())
+		}
+		data = data[n:]
+		index++
+	}
+	return dst, last, nil
+}
+
+// plainSize converts the size of an encrypted object into the size of its contents
+func plainSize(size int64) int64 {
+	plain := size / recordSize * chunkSize
+	if rest := size % recordSize; rest > recordOverhead {
+		plain += rest - recordOverhead
+	}
+	return plain
+}
+
+// sealedSize converts the size of contents into the size of their records, without the empty
+// record marking the end of an object
+func sealedSize(size int64) int64 {
+	encrypted := size / chunkSize * recordSize
+	if rest := size % chunkSize; rest > 0 {
+		encrypted += rest + recordOverhead
+	}
+	return encrypted
+}
+
+// encryptedSize converts the size of the contents into the size of the encrypted object
+func encryptedSize(size int64) int64 {
+	return size/chunkSize*recordSize + size%chunkSize + recordOverhead
+}
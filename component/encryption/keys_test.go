@@ -0,0 +1,192 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func randomKey(t *testing.T) []byte {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestSizes(t *testing.T) {
+	sizes := []int64{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 10*chunkSize + 7}
+	for _, size := range sizes {
+		assert.Equal(t, size, plainSize(encryptedSize(size)))
+	}
+	// an object always ends with a record shorter than a whole chunk
+	assert.EqualValues(t, recordOverhead, encryptedSize(0))
+	assert.EqualValues(t, recordSize+recordOverhead, encryptedSize(chunkSize))
+	assert.EqualValues(t, recordSize+recordOverhead+1, encryptedSize(chunkSize+1))
+	assert.EqualValues(t, 0, sealedSize(0))
+	assert.EqualValues(t, recordSize, sealedSize(chunkSize))
+	assert.EqualValues(t, recordSize+recordOverhead+1, sealedSize(chunkSize+1))
+}
+
+// testObjectID returns a new object ID
+func testObjectID(t *testing.T) []byte {
+	objectID, err := newObjectID()
+	assert.NoError(t, err)
+	return objectID
+}
+
+func TestSealOpen(t *testing.T) {
+	kr := newKeyring()
+	_, err := kr.add(randomKey(t))
+	assert.NoError(t, err)
+
+	objectID := testObjectID(t)
+	plain := make([]byte, 3*chunkSize+100)
+	_, _ = rand.Read(plain)
+	encrypted, err := kr.seal(nil, plain, objectID, 0, true)
+	assert.NoError(t, err)
+	assert.EqualValues(t, encryptedSize(int64(len(plain))), len(encrypted))
+	assert.False(t, bytes.Contains(encrypted, plain[:64]))
+
+	opened, last, err := kr.open(nil, encrypted, objectID, 0)
+	assert.NoError(t, err)
+	assert.True(t, last)
+	assert.Equal(t, plain, opened)
+
+	// records can be read from the middle of an object, at their own index only
+	opened, last, err = kr.open(nil, encrypted[recordSize:2*recordSize], objectID, 1)
+	assert.NoError(t, err)
+	assert.False(t, last)
+	assert.Equal(t, plain[chunkSize:2*chunkSize], opened)
+	_, _, err = kr.open(nil, encrypted[recordSize:2*recordSize], objectID, 2)
+	assert.ErrorIs(t, err, errCorruptRecord)
+	_, _, err = kr.open(nil, encrypted, testObjectID(t), 0)
+	assert.ErrorIs(t, err, errCorruptRecord)
+
+	// any change to a record is detected
+	encrypted[recordSize+keyIDSize+nonceSize] ^= 1
+	_, _, err = kr.open(nil, encrypted, objectID, 0)
+	assert.ErrorIs(t, err, errCorruptRecord)
+
+	_, _, err = kr.open(nil, encrypted[:recordSize+10], objectID, 0)
+	assert.ErrorIs(t, err, errCorruptRecord)
+}
+
+func TestSealFinal(t *testing.T) {
+	kr := newKeyring()
+	_, err := kr.add(randomKey(t))
+	assert.NoError(t, err)
+	objectID := testObjectID(t)
+
+	// contents ending on a chunk boundary get an empty record marking the end
+	plain := make([]byte, 2*chunkSize)
+	encrypted, err := kr.seal(nil, plain, objectID, 0, true)
+	assert.NoError(t, err)
+	assert.Len(t, encrypted, 2*recordSize+recordOverhead)
+	_, last, err := kr.open(nil, encrypted, objectID, 0)
+	assert.NoError(t, err)
+	assert.True(t, last)
+
+	// without it the object reads as cut short
+	_, last, err = kr.open(nil, encrypted[:2*recordSize], objectID, 0)
+	assert.NoError(t, err)
+	assert.False(t, last)
+
+	// nothing may follow the last record
+	_, _, err = kr.open(nil, append(encrypted[2*recordSize:], encrypted...), objectID, 2)
+	assert.ErrorIs(t, err, errCorruptRecord)
+
+	encrypted, err = kr.seal(nil, nil, objectID, 0, true)
+	assert.NoError(t, err)
+	assert.Len(t, encrypted, recordOverhead)
+	encrypted, err = kr.seal(nil, plain, objectID, 0, false)
+	assert.NoError(t, err)
+	assert.Len(t, encrypted, 2*recordSize)
+}
+
+func TestRotation(t *testing.T) {
+	oldKey, newKey := randomKey(t), randomKey(t)
+
+	oldRing := newKeyring()
+	_, err := oldRing.add(bytes.Clone(oldKey))
+	assert.NoError(t, err)
+	objectID := testObjectID(t)
+	encrypted, err := oldRing.seal(nil, []byte("written with the old key"), objectID, 0, true)
+	assert.NoError(t, err)
+
+	// the new key can not read it on its own
+	newRing := newKeyring()
+	current, err := newRing.add(bytes.Clone(newKey))
+	assert.NoError(t, err)
+	_, _, err = newRing.open(nil, encrypted, objectID, 0)
+	assert.ErrorIs(t, err, errUnknownKey)
+
+	// until the old key is added as a previous key
+	_, err = newRing.add(bytes.Clone(oldKey))
+	assert.NoError(t, err)
+	assert.Equal(t, current, newRing.current)
+	opened, _, err := newRing.open(nil, encrypted, objectID, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "written with the old key", string(opened))
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	key := randomKey(t)
+	encodings := map[string][]byte{
+		"raw":    key,
+		"hex":    []byte(hex.EncodeToString(key) + "\n"),
+		"base64": []byte(base64.StdEncoding.EncodeToString(key) + "\n"),
+	}
+	for name, contents := range encodings {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, contents, 0600))
+		read, err := readKeyFile(path)
+		assert.NoError(t, err, name)
+		assert.Equal(t, key, read, name)
+	}
+
+	path := filepath.Join(dir, "short")
+	assert.NoError(t, os.WriteFile(path, []byte("too short"), 0600))
+	_, err := readKeyFile(path)
+	assert.Error(t, err)
+
+	_, err = readKeyFile(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestPassphraseKey(t *testing.T) {
+	assert.Equal(t, passphraseKey("passphrase"), passphraseKey("passphrase"))
+	assert.NotEqual(t, passphraseKey("passphrase"), passphraseKey("other"))
+	assert.Len(t, passphraseKey("passphrase"), 32)
+}
@@ -208,7 +208,65 @@ func (fc *FileCache) startScheduler() {
 // flock must be locked
 func (fc *FileCache) addPendingOp(name string, value pendingFlags) {
 	log.Trace("FileCache::addPendingOp : %s", name)
-	fc.pendingOps.Store(name, value)
+	fc.updatePendingOps(newJournalRecord(name, value, &fc.etags))
+	fc.notifyPendingOp()
+}
+
+// flock must be locked
+func (fc *FileCache) removePendingOp(name string) {
+	fc.updatePendingOps(journalRecord{Op: journalDone, Name: name})
+}
+
+// updatePendingOps applies the record to pendingOps, and then writes it to the journal.
+// A record that cannot be journaled is still applied. Returns whether pendingOps changed.
+func (fc *FileCache) updatePendingOps(record journalRecord) bool {
+	if fc.journal == nil {
+		return record.apply(&fc.pendingOps)
+	}
+	fc.journal.Lock()
+	defer fc.journal.Unlock()
+	if !record.apply(&fc.pendingOps) {
+		return false
+	}
+	fc.appendJournal(record)
+	return true
+}
+
+// journalETag writes the pending operation of name to the journal again after its ETag changed,
+// so it is replayed with the right one. flock must be locked.
+func (fc *FileCache) journalETag(name string) {
+	if fc.journal == nil {
+		return
+	}
+	fc.journal.Lock()
+	defer fc.journal.Unlock()
+	value, pending := fc.pendingOps.Load(name)
+	if pending && !value.(pendingFlags).isDeletion {
+		fc.appendJournal(newJournalRecord(name, value.(pendingFlags), &fc.etags))
+	}
+}
+
+// appendJournal writes the record to the journal, and compacts it when it is due.
+// fc.journal must be locked.
+func (fc *FileCache) appendJournal(record journalRecord) {
+	err := fc.journal.append(record)
+	if err != nil {
+		log.Err(
+			"FileCache::appendJournal : %s failed to journal %s. Here's why: %v",
+			record.Name,
+			record.Op,
+			err,
+		)
+	} else if fc.journal.records >= fc.journal.compactAt {
+		err = fc.journal.compact(&fc.pendingOps, &fc.etags)
+		if err != nil {
+			log.Err("FileCache::appendJournal : Failed to compact journal. Here's why: %v", err)
+		}
+	}
+}
+
+// wake up servicePendingOps
+func (fc *FileCache) notifyPendingOp() {
 	select {
 	case fc.pendingOpAdded <- struct{}{}:
 	default: // do not block
@@ -221,7 +279,6 @@ func (fc *FileCache) servicePendingOps() {
 		select {
 		case <-fc.componentStopping:
 			log.Crit("FileCache::servicePendingOps : Stopping")
-			// TODO: Persist pending ops
 			return
 		case <-fc.startScheduledUploads:
 			// check if we're connected
@@ -295,7 +352,7 @@ func (fc *FileCache) updateObject(name string, flags pendingFlags) error {
 	// in case of inconsistency, local state takes precedence (except to prevent incorrect deletions)
 	if !flags.isDeletion && localErr != nil {
 		log.Err("FileCache::updateObject : %s stat failed. Here's why: %v", name, localErr)
-		fc.pendingOps.Delete(name)
+		fc.removePendingOp(name)
 		return localErr
 	}
 	if flags.isDeletion && !localMissing {
@@ -312,7 +369,7 @@ func (fc *FileCache) updateObject(name string, flags pendingFlags) error {
 	if localMissing {
 		if flags.isDeletion && fc.notInCloud(name) {
 			log.Info("FileCache::updateObject : %s skipping cloud deletion (not in cloud)", name)
-			fc.pendingOps.Delete(name)
+			fc.removePendingOp(name)
 			return nil
 		}
 		if flags.isDir {
@@ -345,7 +402,7 @@ func (fc *FileCache) updateObject(name string, flags pendingFlags) error {
 
 	// update state
 	log.Info("FileCache::updateObject : %s sync successful", name)
-	fc.pendingOps.Delete(name)
+	fc.removePendingOp(name)
 
 	return nil
 }
@@ -48,6 +48,7 @@ type cachePolicyConfig struct {
 
 	fileLocks  *common.LockMap // uses object name (common.JoinUnixFilepath)
 	pendingOps *sync.Map
+	etags      *sync.Map // ETags uploads of pendingOps must match, saved with them
 
 	policyTrace bool
 }
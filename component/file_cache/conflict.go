@@ -0,0 +1,156 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+)
+
+// Conflict policies decide what happens to local changes to an object that was also changed in
+// cloud storage since it was downloaded
+const (
+	conflictKeepBoth   = "keep-both"
+	conflictRemoteWins = "remote-wins"
+	conflictLocalWins  = "local-wins"
+)
+
+func validConflictPolicy(policy string) bool {
+	switch policy {
+	case conflictKeepBoth, conflictRemoteWins, conflictLocalWins:
+		return true
+	}
+	return false
+}
+
+// conflictCopyName names the copy of a conflicting local file,
+// e.g. "a.txt.conflict-host-20260102T030405Z"
+func conflictCopyName(name string, now time.Time) string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s.conflict-%s-%s", name, host, now.UTC().Format("20060102T150405Z"))
+}
+
+// ifMatch returns the ETag an upload of the object must match, or nil for an unconditional upload
+func (fc *FileCache) ifMatch(name string) *string {
+	if fc.conflictPolicy == conflictLocalWins {
+		return nil
+	}
+	etag, found := fc.etags.Load(name)
+	if !found {
+		return nil
+	}
+	return new(etag.(string))
+}
+
+// setETag records the ETag of the object as last downloaded or uploaded
+func (fc *FileCache) setETag(name string, etag string) {
+	if etag == "" {
+		fc.forgetETag(name)
+	} else {
+		fc.storeETag(name, etag)
+	}
+}
+
+// expectNoObject records that the object did not exist, so an upload must not find one
+func (fc *FileCache) expectNoObject(name string) {
+	fc.storeETag(name, "")
+}
+
+// storeETag records the ETag uploads must match, and journals it if the object is pending
+func (fc *FileCache) storeETag(name string, etag string) {
+	previous, found := fc.etags.Swap(name, etag)
+	if !found || previous.(string) != etag {
+		fc.journalETag(name)
+	}
+}
+
+// forgetETag is called when we change an object in cloud storage without learning its new ETag,
+// so the next upload cannot tell our change apart from someone else's. flock must be locked.
+func (fc *FileCache) forgetETag(name string) {
+	if _, found := fc.etags.LoadAndDelete(name); found {
+		fc.journalETag(name)
+	}
+}
+
+// flock must be locked for both src and dst
+func (fc *FileCache) renameConflictState(srcName, dstName string) {
+	fc.forgetETag(srcName)
+	fc.forgetETag(dstName)
+	if _, found := fc.conflicts.LoadAndDelete(srcName); found {
+		fc.conflicts.Store(dstName, true)
+	}
+}
+
+// resolveConflict handles an upload from f that was rejected because the object changed in cloud
+// storage. flock must be locked.
+func (fc *FileCache) resolveConflict(name string, f *os.File, metadata map[string]*string) error {
+	if fc.conflictPolicy == conflictRemoteWins {
+		log.Warn("FileCache::resolveConflict : %s changed in cloud, discarding local changes", name)
+		return nil
+	}
+
+	copyName := conflictCopyName(name, time.Now())
+	log.Warn(
+		"FileCache::resolveConflict : %s changed in cloud storage, uploading local copy as %s",
+		name,
+		copyName,
+	)
+	_, err := f.Seek(0, io.SeekStart)
+	if err == nil {
+		err = fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+			Name:     copyName,
+			File:     f,
+			Metadata: metadata,
+			IfMatch:  new(""),
+		})
+	}
+	if err != nil {
+		log.Err("FileCache::resolveConflict : %s failed to upload %s [%v]", name, copyName, err)
+	}
+	return err
+}
+
+// discardLocalCopy drops the cached copy of an object that lost a conflict, so it is downloaded
+// again on the next open. flock must be locked.
+func (fc *FileCache) discardLocalCopy(name string) {
+	if fc.fileLocks.Get(name).Count() > 0 {
+		// open handles still use the local copy, so it is purged when the last one is released
+		fc.conflicts.Store(name, true)
+		return
+	}
+	fc.conflicts.Delete(name)
+	fc.forgetETag(name)
+	fc.policy.CachePurge(filepath.Join(fc.tmpPath, name))
+}
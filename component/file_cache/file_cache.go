@@ -69,6 +69,8 @@ type FileCache struct {
 	missedChmodList sync.Map      // uses object name (common.JoinUnixFilepath)
 	pendingOps      sync.Map      // uses object name (common.JoinUnixFilepath)
 	pendingOpAdded  chan struct{} // signals when an offline operation is queued
+	etags           sync.Map      // uses object name (common.JoinUnixFilepath)
+	conflicts       sync.Map      // uses object name (common.JoinUnixFilepath)
 	mountPath       string        // uses os.Separator (filepath.Join)
 	allowOther      bool
 	offloadIO       bool
@@ -80,6 +82,9 @@ type FileCache struct {
 	refreshSec        uint32
 	hardLimit         bool
 	diskHighWaterMark float64
+	conflictPolicy    string
+	journal           *pendingOpJournal
+	restoreWait       time.Duration
 
 	lazyWrite    bool
 	fileCloseOpt sync.WaitGroup
@@ -114,6 +119,10 @@ type FileCacheOptions struct {
 
 	RefreshSec uint32 `config:"refresh-sec" yaml:"refresh-sec,omitempty"`
 	HardLimit  bool   `config:"hard-limit"  yaml:"hard-limit,omitempty"`
+
+	ConflictPolicy string `config:"conflict-policy" yaml:"conflict-policy,omitempty"`
+
+	RestoreWaitSec uint32 `config:"restore-wait-sec" yaml:"restore-wait-sec,omitempty"`
 }
 
 type openFileOptions struct {
@@ -148,6 +157,9 @@ var _ internal.Component = &FileCache{}
 
 var fileCacheStatsCollector *stats_manager.StatsCollector
 
+// how often an object that is being restored from an archive tier is polled
+var restorePollInterval = 30 * time.Second
+
 func (fc *FileCache) Name() string {
 	return compName
 }
@@ -179,9 +191,28 @@ func (fc *FileCache) Start(ctx context.Context) error {
 		return fmt.Errorf("config error in %s error [fail to start policy]", fc.Name())
 	}
 
+	// recover operations that were still pending when we last stopped or crashed
+	fc.journal, err = openJournal(fc.tmpPath, &fc.pendingOps, &fc.etags)
+	if err != nil {
+		log.Err("FileCache::Start : Failed to open pending operations journal [%v]", err)
+		return fmt.Errorf("config error in %s error [fail to open journal]", fc.Name())
+	}
+
 	// create stats collector for file cache
 	fileCacheStatsCollector = stats_manager.NewStatsCollector(fc.Name())
 	log.Debug("Starting file cache stats collector")
+	fileCacheStatsCollector.AddStats(stats_manager.Counter, dlFiles)
+	fileCacheStatsCollector.AddStats(stats_manager.Gauge, cacheUsage, usgPer)
+	fileCacheStatsCollector.AddGauge(pendingUploads, func() float64 {
+		count := 0
+		fc.pendingOps.Range(func(_, value any) bool {
+			if flags, ok := value.(pendingFlags); ok && !flags.isDeletion {
+				count++
+			}
+			return true
+		})
+		return float64(count)
+	})
 
 	// setup async uploads
 	fc.startScheduledUploads = make(chan struct{})
@@ -221,6 +252,10 @@ func (fc *FileCache) Stop() error {
 	}
 
 	_ = fc.policy.ShutdownPolicy()
+	err := fc.journal.close()
+	if err != nil {
+		log.Err("FileCache::Stop : Failed to close pending operations journal [%v]", err)
+	}
 	if !fc.allowNonEmpty {
 		_ = common.TempCacheCleanup(fc.tmpPath)
 	}
@@ -288,6 +323,20 @@ func (fc *FileCache) Configure(_ bool) error {
 	fc.offlineAccess = !conf.BlockOfflineAccess
 	fc.refreshSec = conf.RefreshSec
 	fc.hardLimit = conf.HardLimit
+	fc.restoreWait = time.Duration(conf.RestoreWaitSec) * time.Second
+
+	fc.conflictPolicy = conflictKeepBoth
+	if config.IsSet(compName + ".conflict-policy") {
+		fc.conflictPolicy = strings.ToLower(conf.ConflictPolicy)
+		if !validConflictPolicy(fc.conflictPolicy) {
+			log.Err("FileCache: config error [invalid conflict-policy %s]", conf.ConflictPolicy)
+			return fmt.Errorf(
+				"config error in %s [invalid conflict-policy %s]",
+				fc.Name(),
+				conf.ConflictPolicy,
+			)
+		}
+	}
 
 	err = config.UnmarshalKey("lazy-write", &fc.lazyWrite)
 	if err != nil {
@@ -394,7 +443,7 @@ func (fc *FileCache) Configure(_ bool) error {
 	}
 
 	log.Crit(
-		"FileCache::Configure : create-empty %t, cache-timeout %d, tmp-path %s, max-size-mb %d, high-mark %d, low-mark %d, refresh-sec %v, max-eviction %v, hard-limit %v, policy %s, allow-non-empty-temp %t, cleanup-on-start %t, policy-trace %t, offload-io %t, !block-offline-access %t, defaultPermission %v, diskHighWaterMark %v, maxCacheSize %v, mountPath %v, schedule-len %v",
+		"FileCache::Configure : create-empty %t, cache-timeout %d, tmp-path %s, max-size-mb %d, high-mark %d, low-mark %d, refresh-sec %v, max-eviction %v, hard-limit %v, policy %s, allow-non-empty-temp %t, cleanup-on-start %t, policy-trace %t, offload-io %t, !block-offline-access %t, defaultPermission %v, diskHighWaterMark %v, maxCacheSize %v, mountPath %v, schedule-len %v, conflict-policy %s",
 		fc.createEmptyFile,
 		int(fc.cacheTimeout),
 		fc.tmpPath,
@@ -415,6 +464,7 @@ func (fc *FileCache) Configure(_ bool) error {
 		fc.maxCacheSizeMB,
 		fc.mountPath,
 		len(fc.schedule),
+		fc.conflictPolicy,
 	)
 
 	return nil
@@ -466,6 +516,7 @@ func (fc *FileCache) GetPolicyConfig(conf FileCacheOptions) cachePolicyConfig {
 		fileLocks:     fc.fileLocks,
 		policyTrace:   conf.EnablePolicyTrace,
 		pendingOps:    &fc.pendingOps,
+		etags:         &fc.etags,
 	}
 
 	return cacheConfig
@@ -683,6 +734,10 @@ func (fc *FileCache) StreamDir(
 	if localErr != nil && !isNotExist(localErr) {
 		log.Err("FileCache::StreamDir : %s os.ReadDir failed [%v]", options.Name, localErr)
 	}
+	dirents = slices.DeleteFunc(dirents, func(entry os.DirEntry) bool {
+		// virtual directories are found by name, and never listed
+		return fc.isJournalFile(localPath, entry.Name()) || internal.IsVirtualPath(entry.Name())
+	})
 
 	i := 0 // Index for cloud
 	j := 0 // Index for local cache
@@ -884,6 +939,9 @@ func (fc *FileCache) deleteEmptyDirs(options internal.DeleteDirOptions) (bool, e
 	}
 
 	for _, entry := range entries {
+		if fc.isJournalFile(localPath, entry.Name()) {
+			continue
+		}
 		if entry.IsDir() {
 			val, err := fc.deleteEmptyDirs(internal.DeleteDirOptions{
 				Name: filepath.Join(localPath, entry.Name()),
@@ -1280,6 +1338,10 @@ func (fc *FileCache) CreateFile(options internal.CreateFileOptions) (*handlemap.
 	// If an empty file is created in cloud storage then there is no need to upload if FlushFile is called immediately after CreateFile.
 	if !fc.createEmptyFile {
 		fc.setHandleDirty(handle)
+		// the upload must not replace an object someone else created in the meantime
+		fc.expectNoObject(options.Name)
+	} else {
+		fc.forgetETag(options.Name)
 	}
 
 	// update state
@@ -1362,6 +1424,8 @@ func (fc *FileCache) DeleteFile(options internal.DeleteFileOptions) error {
 
 	// delete file from cache
 	fc.policy.CachePurge(localPath)
+	fc.forgetETag(options.Name)
+	fc.conflicts.Delete(options.Name)
 
 	// update file state
 	flock.LazyOpen = false
@@ -1382,6 +1446,38 @@ func openCompleted(handle *handlemap.Handle) bool {
 	return !found
 }
 
+// download copies the entire object to file. An archived object that is being restored is
+// polled until it can be read, for up to restore-wait-sec.
+func (fc *FileCache) download(name string, file *os.File) error {
+	deadline := time.Now().Add(fc.restoreWait)
+	for {
+		// We pass a count of 0 to get the entire object
+		err := fc.NextComponent().CopyToFile(
+			internal.CopyToFileOptions{
+				Name:   name,
+				Offset: 0,
+				Count:  0,
+				File:   file,
+			})
+		archived, isArchived := errors.AsType[common.ArchivedError](err)
+		remaining := time.Until(deadline)
+		if !isArchived || !archived.Restoring || remaining <= 0 {
+			return err
+		}
+
+		log.Info("FileCache::download : %s is being restored, waiting", name)
+		select {
+		case <-time.After(min(restorePollInterval, remaining)):
+		case <-fc.componentStopping:
+			return err
+		}
+		err = file.Truncate(0)
+		if err != nil {
+			return err
+		}
+	}
+}
+
 // flock must already be locked before calling this function
 func (fc *FileCache) openFileInternal(handle *handlemap.Handle, flock *common.LockMapItem) error {
 	log.Trace("FileCache::openFileInternal : name=%s", handle.Path)
@@ -1405,7 +1501,11 @@ func (fc *FileCache) openFileInternal(handle *handlemap.Handle, flock *common.Lo
 	localPath := filepath.Join(fc.tmpPath, handle.Path)
 
 	fc.policy.CacheValid(localPath)
-	downloadRequired, fileExists, attr, _ := fc.isDownloadRequired(localPath, handle.Path, flock)
+	downloadRequired, fileExists, attr, attrErr := fc.isDownloadRequired(
+		localPath,
+		handle.Path,
+		flock,
+	)
 
 	// handle offline cases
 	if !fc.NextComponent().CloudConnected() {
@@ -1454,13 +1554,7 @@ func (fc *FileCache) openFileInternal(handle *handlemap.Handle, flock *common.Lo
 		if attr != nil && !overwrite {
 			// Download/Copy the file from storage to the local file.
 			// We pass a count of 0 to get the entire object
-			dlErr := fc.NextComponent().CopyToFile(
-				internal.CopyToFileOptions{
-					Name:   handle.Path,
-					Offset: 0,
-					Count:  0,
-					File:   downloadHandle,
-				})
+			dlErr := fc.download(handle.Path, downloadHandle)
 			if dlErr != nil {
 				// File was created locally and now download has failed so we need to delete it back from local cache
 				log.Err("FileCache::openFileInternal : %s download failed [%v]", handle.Path, dlErr)
@@ -1475,6 +1569,13 @@ func (fc *FileCache) openFileInternal(handle *handlemap.Handle, flock *common.Lo
 
 		// Update the last download time of this file
 		flock.SetDownloadTime()
+		// uploads must find the object as it was when it was downloaded
+		switch {
+		case attr != nil:
+			fc.setETag(handle.Path, attr.ETag)
+		case isNotExist(attrErr):
+			fc.expectNoObject(handle.Path)
+		}
 		downloadHandle.Close()
 		log.Debug("FileCache::openFileInternal : %s download complete", handle.Path)
 
@@ -1829,6 +1930,11 @@ func (fc *FileCache) releaseFileInternal(
 		flock.LazyOpen = false
 	}
 
+	// a local copy that lost a conflict while open can be dropped now
+	if _, found := fc.conflicts.Load(options.Handle.Path); found {
+		fc.discardLocalCopy(options.Handle.Path)
+	}
+
 	return nil
 }
 
@@ -2061,7 +2167,7 @@ func (fc *FileCache) flushFileCloud(options internal.FlushFileOptions) error {
 	switch {
 	case err == nil:
 		fc.clearHandleDirty(options.Handle)
-		fc.pendingOps.Delete(options.Handle.Path)
+		fc.removePendingOp(options.Handle.Path)
 	case isOffline(err) && fc.offlineAccess:
 		log.Warn("FileCache::flushFileCloud : %s upload delayed (offline)", options.Handle.Path)
 		// add file to upload queue
@@ -2104,8 +2210,23 @@ func (fc *FileCache) uploadFile(name string) error {
 		log.Err("FileCache::FlushFile : %s unable to open upload handle [%v]", name, openErr)
 		return openErr
 	}
-	// upload file data
-	uploadErr := fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{Name: name, File: f})
+	// upload file data, preserving the modification time of the local copy.
+	// The storage merges it into the metadata of the object, which keeps the access time.
+	metadata := map[string]*string{
+		internal.MtimeMetadataKey: new(internal.FormatMetadataTime(info.ModTime())),
+	}
+	newETag := ""
+	uploadErr := fc.NextComponent().CopyFromFile(internal.CopyFromFileOptions{
+		Name:     name,
+		File:     f,
+		Metadata: metadata,
+		IfMatch:  fc.ifMatch(name),
+		NewETag:  &newETag,
+	})
+	conflict := errors.Is(uploadErr, &common.ConflictError{})
+	if conflict {
+		uploadErr = fc.resolveConflict(name, f, metadata)
+	}
 	f.Close()
 	// change mode back
 	if modeChanged {
@@ -2114,8 +2235,13 @@ func (fc *FileCache) uploadFile(name string) error {
 			log.Err("FileCache::FlushFile : %s Failed to remove read mode [%v]", name, err)
 		}
 	}
+	if conflict && uploadErr == nil {
+		fc.discardLocalCopy(name)
+		return nil
+	}
 	// update the mode as well
 	if uploadErr == nil {
+		fc.setETag(name, newETag)
 		// If chmod was done on the file before it was uploaded to container then setting up mode would have been missed
 		// Such file names are added to this map and here post upload we try to set the mode correctly
 		// Delete the entry from map so that any further flush do not try to update the mode again
@@ -2159,6 +2285,9 @@ func (fc *FileCache) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr
 	if localErr != nil && !isNotExist(localErr) {
 		log.Warn("FileCache::GetAttr : %s unexpected stat error [%v]", options.Name, localErr)
 	}
+	if fc.isJournalFile(filepath.Dir(localPath), filepath.Base(localPath)) {
+		localErr = os.ErrNotExist
+	}
 	if flock.Count() > 0 && flock.DirtyCount() > 0 {
 		if localErr == nil && !info.IsDir() {
 			flock.RUnlock()
@@ -2272,8 +2401,9 @@ func (fc *FileCache) RenameFile(options internal.RenameFileOptions) error {
 		} else {
 			log.Debug("FileCache::RenameFile : %s Offline rename allowed", options.Src)
 			// make sure src is in pendingOps so renamePendingOp works correctly
-			_, srcAlreadyPending := fc.pendingOps.LoadOrStore(options.Src, pendingFlags{})
+			_, srcAlreadyPending := fc.pendingOps.Load(options.Src)
 			if !srcAlreadyPending {
+				fc.updatePendingOps(newJournalRecord(options.Src, pendingFlags{}, &fc.etags))
 				log.Info("FileCache::RenameFile : %s Added src to pendingOps", options.Src)
 			}
 			err = nil
@@ -2341,17 +2471,17 @@ func (fc *FileCache) renameLocalFile(
 	fc.renameOpenHandles(srcName, dstName, sflock, dflock)
 	// update pending cloud ops
 	fc.renamePendingOp(fc.getObjectName(localSrcPath), fc.getObjectName(localDstPath))
+	fc.renameConflictState(fc.getObjectName(localSrcPath), fc.getObjectName(localDstPath))
 
 	return nil
 }
 
 // flock must be locked for both src and dst
 func (fc *FileCache) renamePendingOp(srcName, dstName string) {
-	value, operationPending := fc.pendingOps.LoadAndDelete(srcName)
-	if operationPending {
-		opFlags := value.(pendingFlags)
-		fc.addPendingOp(srcName, pendingFlags{isDir: opFlags.isDir, isDeletion: true})
-		fc.addPendingOp(dstName, opFlags)
+	// src and dst are updated together, so a crash can't leave src deleted without dst uploaded
+	record := journalRecord{Op: journalRename, Name: srcName, Dst: dstName}
+	if fc.updatePendingOps(record) {
+		fc.notifyPendingOp()
 	}
 }
 
@@ -2495,6 +2625,9 @@ func (fc *FileCache) TruncateFile(options internal.TruncateFileOptions) error {
 		log.Err("FileCache::TruncateFile : %s failed to truncate [%v]", options.Name, cloudErr)
 		return cloudErr
 	}
+	if cloudErr == nil {
+		fc.forgetETag(options.Name)
+	}
 
 	// Update the size of the file in the local cache
 	if localErr == nil {
@@ -2553,6 +2686,8 @@ func (fc *FileCache) chmodInternal(options internal.ChmodOptions) error {
 	case cloudErr != nil:
 		log.Err("FileCache::Chmod : %s failed [%v]", options.Name, cloudErr)
 		return cloudErr
+	default:
+		fc.forgetETag(options.Name)
 	}
 
 	// Cloud succeeded (or offline with local file)
@@ -2587,6 +2722,90 @@ func (fc *FileCache) chmodInternal(options internal.ChmodOptions) error {
 	return nil
 }
 
+// Utimens : Update the access and modification times of the file
+func (fc *FileCache) Utimens(options internal.UtimensOptions) error {
+	log.Trace("FileCache::Utimens : Change times of path %s", options.Name)
+
+	flock := fc.fileLocks.Get(options.Name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	// check local file
+	localPath := filepath.Join(fc.tmpPath, options.Name)
+	info, localErr := os.Stat(localPath)
+
+	// Update the file in cloud storage
+	cloudErr := fc.NextComponent().Utimens(options)
+	// a file pending upload takes the times of the local copy when it is uploaded
+	cloudErr = fc.resolveCloudNotFoundError(options.Name, cloudErr, "Utimens", true)
+	offlineOkay := false
+	switch {
+	case isOffline(cloudErr) && fc.offlineAccess && localErr == nil:
+		log.Debug("FileCache::Utimens : %s operating on cache (offline)", options.Name)
+		offlineOkay = true
+	case cloudErr != nil:
+		log.Err("FileCache::Utimens : %s failed [%v]", options.Name, cloudErr)
+		return cloudErr
+	default:
+		fc.forgetETag(options.Name)
+	}
+
+	if localErr != nil {
+		// nothing cached locally, cloud storage has been updated
+		return nil
+	}
+
+	// Update the times of the file in the local cache, so later uploads keep them
+	fc.policy.CacheValid(localPath)
+	err := os.Chtimes(localPath, options.Atime, options.Mtime)
+	if err != nil {
+		log.Err("FileCache::Utimens : %s local chtimes failed [%v]", options.Name, err)
+		return err
+	}
+	if offlineOkay && !info.IsDir() {
+		log.Warn("FileCache::Utimens : %s operation queued (offline)", options.Name)
+		fc.addPendingOp(options.Name, pendingFlags{})
+	}
+
+	return nil
+}
+
+// GetMetadata : Get the metadata of the object from cloud storage
+func (fc *FileCache) GetMetadata(options internal.GetMetadataOptions) (map[string]*string, error) {
+	log.Trace("FileCache::GetMetadata : %s", options.Name)
+
+	flock := fc.fileLocks.Get(options.Name)
+	flock.RLock()
+	defer flock.RUnlock()
+
+	metadata, err := fc.NextComponent().GetMetadata(options)
+	// a file pending upload has no metadata yet
+	err = fc.resolveCloudNotFoundError(options.Name, err, "GetMetadata", true)
+	if err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// SetMetadata : Update the metadata of the object in cloud storage
+func (fc *FileCache) SetMetadata(options internal.SetMetadataOptions) error {
+	log.Trace("FileCache::SetMetadata : %s", options.Name)
+
+	flock := fc.fileLocks.Get(options.Name)
+	flock.Lock()
+	defer flock.Unlock()
+
+	err := fc.NextComponent().SetMetadata(options)
+	// EIO means local-only file (pending upload), there is no object to set metadata on yet
+	err = fc.resolveCloudNotFoundError(options.Name, err, "SetMetadata", false)
+	if err != nil {
+		log.Err("FileCache::SetMetadata : %s failed [%v]", options.Name, err)
+		return err
+	}
+	fc.forgetETag(options.Name)
+	return nil
+}
+
 // Chown : Update the file with its new owner and group
 func (fc *FileCache) Chown(options internal.ChownOptions) error {
 	log.Trace("FileCache::Chown : Change owner of path %s", options.Name)
@@ -2618,6 +2837,8 @@ func (fc *FileCache) Chown(options internal.ChownOptions) error {
 	case err != nil:
 		log.Err("FileCache::Chown : %s failed to change owner [%s]", options.Name, err.Error())
 		return err
+	default:
+		fc.forgetETag(options.Name)
 	}
 
 	// Cloud succeeded (or offline with local file)
@@ -30,4 +30,6 @@ const (
 	usgPer      = "Usage Percent"
 	dlFiles     = "Files Downloaded"
 	cacheServed = "Files served from cache"
+
+	pendingUploads = "Pending Uploads"
 )
@@ -90,3 +90,13 @@ func (fc *FileCache) syncFile(f *os.File, path string) error {
 	}
 	return nil
 }
+
+// syncDir makes changes to the entries of a directory durable
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
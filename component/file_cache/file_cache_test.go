@@ -29,6 +29,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io/fs"
 	"math"
@@ -397,6 +398,21 @@ func (suite *fileCacheTestSuite) TestConfigPolicyDefaultTimeout() {
 	suite.assert.Equal(int(suite.fileCache.cacheTimeout), cacheTimeout)
 }
 
+func (suite *fileCacheTestSuite) TestConfigConflictPolicy() {
+	defer suite.cleanupTest()
+	suite.assert.Equal(conflictKeepBoth, suite.fileCache.conflictPolicy)
+
+	cfg := fmt.Sprintf(
+		"file_cache:\n  path: %s\n  conflict-policy: newest-wins\n\nloopbackfs:\n  path: %s",
+		suite.cache_path,
+		suite.fake_storage_path,
+	)
+	err := config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.assert.NoError(err)
+	err = NewFileCacheComponent().Configure(true)
+	suite.assert.ErrorContains(err, "conflict-policy")
+}
+
 func (suite *fileCacheTestSuite) TestConfigZero() {
 	defer suite.cleanupTest()
 	suite.cleanupTest() // teardown the default file cache generated
@@ -679,6 +695,51 @@ func (suite *fileCacheTestSuite) TestOfflineCreateThenDeleteDirKeepsPendingKeyAs
 	}
 }
 
+func (suite *fileCacheTestSuite) TestPendingOpsSurviveCrash() {
+	// enable mock component
+	suite.cleanupTest()
+	defaultConfig := fmt.Sprintf(
+		"file_cache:\n  path: %s\n  offload-io: true\n  allow-non-empty-temp: true",
+		suite.cache_path,
+	)
+	suite.useMock = true
+	suite.setupTestHelper(defaultConfig)
+	defer suite.cleanupTest()
+
+	dir := "journaled-dir"
+	suite.mock.EXPECT().
+		GetAttr(internal.GetAttrOptions{Name: dir}).
+		Return(nil, os.ErrNotExist)
+	err := suite.fileCache.CreateDir(internal.CreateDirOptions{Name: dir, Mode: 0777})
+	suite.assert.NoError(err)
+
+	// the journal is not part of the file system
+	suite.mock.EXPECT().
+		StreamDir(internal.StreamDirOptions{Name: "", Token: ""}).
+		Return([]*internal.ObjAttr{}, "", &common.CloudUnreachableError{})
+	suite.mock.EXPECT().
+		GetAttr(internal.GetAttrOptions{Name: dir}).
+		Return(nil, &common.CloudUnreachableError{})
+	attrs, _, err := suite.fileCache.StreamDir(internal.StreamDirOptions{Name: ""})
+	suite.assert.NoError(err)
+	suite.assert.Len(attrs, 1)
+	suite.assert.Equal(dir, attrs[0].Name)
+
+	// crash: start a new file cache without stopping the first one
+	crashed := suite.fileCache
+	suite.assert.NoError(crashed.journal.close())
+	suite.fileCache = newTestFileCache(suite.mock)
+	err = suite.fileCache.Start(context.Background())
+	suite.assert.NoError(err)
+	defer crashed.Stop()
+
+	op, found := suite.fileCache.pendingOps.Load(dir)
+	suite.assert.True(found, "journaled directory creation should be replayed")
+	if found {
+		suite.assert.Equal(pendingFlags{isDir: true}, op)
+	}
+}
+
 func (suite *fileCacheTestSuite) TestStreamDirError() {
 	defer suite.cleanupTest()
 	// Setup
@@ -1613,6 +1674,56 @@ func (suite *fileCacheTestSuite) TestChmodNonexistentCreateEmptyFile() {
 	suite.assert.ErrorIs(err, os.ErrNotExist)
 }
 
+func (suite *fileCacheTestSuite) TestMetadataPendingUpload() {
+	defer suite.cleanupTest()
+	path := "file"
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+
+	// the file has not been uploaded yet, so it has no metadata and none can be set
+	metadata, err := suite.fileCache.GetMetadata(internal.GetMetadataOptions{Name: path})
+	suite.assert.NoError(err)
+	suite.assert.Empty(metadata)
+	err = suite.fileCache.SetMetadata(internal.SetMetadataOptions{
+		Name:     path,
+		Metadata: map[string]*string{"label": new("value")},
+	})
+	suite.assert.ErrorIs(err, syscall.EIO)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	metadata, err = suite.fileCache.GetMetadata(internal.GetMetadataOptions{Name: path})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(metadata)
+
+	_, err = suite.fileCache.GetMetadata(internal.GetMetadataOptions{Name: "missing"})
+	suite.assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func (suite *fileCacheTestSuite) TestUtimens() {
+	defer suite.cleanupTest()
+	path := "file"
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	mtime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	err = suite.fileCache.Utimens(internal.UtimensOptions{Name: path, Mtime: mtime})
+	suite.assert.NoError(err)
+
+	// both the cached copy and cloud storage should have the new time
+	info, err := os.Stat(filepath.Join(suite.cache_path, path))
+	suite.assert.NoError(err)
+	suite.assert.True(mtime.Equal(info.ModTime()))
+	info, err = os.Stat(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.True(mtime.Equal(info.ModTime()))
+
+	err = suite.fileCache.Utimens(internal.UtimensOptions{Name: "missing", Mtime: mtime})
+	suite.assert.ErrorIs(err, os.ErrNotExist)
+}
+
 func (suite *fileCacheTestSuite) TestSyncFile() {
 	defer suite.cleanupTest()
 	// Setup
@@ -2072,6 +2183,39 @@ func (suite *fileCacheTestSuite) TestOfflineToConnected() {
 	suite.assert.False(pending, "file should not be queued after successful upload on reconnect")
 }
 
+func (suite *fileCacheTestSuite) TestDownloadWaitsForRestore() {
+	suite.cleanupTest()
+	configuration := fmt.Sprintf(
+		"file_cache:\n  path: %s\n  offload-io: true\n  restore-wait-sec: 60",
+		suite.cache_path,
+	)
+	suite.useMock = true
+	suite.setupTestHelper(configuration)
+	defer suite.cleanupTest()
+	defer func(interval time.Duration) { restorePollInterval = interval }(restorePollInterval)
+	restorePollInterval = time.Millisecond
+
+	err := os.MkdirAll(suite.cache_path, 0777)
+	suite.assert.NoError(err)
+	file, err := os.Create(filepath.Join(suite.cache_path, "archived"))
+	suite.assert.NoError(err)
+	defer file.Close()
+
+	restoring := common.NewArchivedError(errors.New("archived"), true)
+	gomock.InOrder(
+		suite.mock.EXPECT().CopyToFile(gomock.Any()).Return(restoring).Times(2),
+		suite.mock.EXPECT().CopyToFile(gomock.Any()).Return(nil),
+	)
+	err = suite.fileCache.download("archived", file)
+	suite.assert.NoError(err)
+
+	// archived objects that are not being restored are not waited for
+	archived := common.NewArchivedError(errors.New("archived"), false)
+	suite.mock.EXPECT().CopyToFile(gomock.Any()).Return(archived)
+	err = suite.fileCache.download("archived", file)
+	suite.assert.ErrorIs(err, &common.ArchivedError{})
+}
+
 func (suite *fileCacheTestSuite) TestConnectedToOffline() {
 	// Set up mock manually so CloudConnected can change during the test
 	suite.cleanupTest()
@@ -4119,6 +4263,179 @@ func (suite *fileCacheTestSuite) TestReadFileWithRefresh() {
 	suite.assert.NoError(err)
 }
 
+// setupConflictPolicy restarts file cache with the given conflict policy
+func (suite *fileCacheTestSuite) setupConflictPolicy(policy string) {
+	suite.cleanupTest()
+	cfg := fmt.Sprintf(
+		"file_cache:\n  path: %s\n  offload-io: true\n  conflict-policy: %s\n\nloopbackfs:\n  path: %s",
+		suite.cache_path,
+		policy,
+		suite.fake_storage_path,
+	)
+	suite.setupTestHelper(cfg)
+}
+
+// writeConflictingFile changes a downloaded file both locally and in cloud storage, then closes it
+func (suite *fileCacheTestSuite) writeConflictingFile(path string) {
+	remotePath := filepath.Join(suite.fake_storage_path, path)
+	err := os.WriteFile(remotePath, []byte("original"), 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(
+		internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777},
+	)
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(
+		&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("local")},
+	)
+	suite.assert.NoError(err)
+
+	// someone else uploads a new version after ours was downloaded
+	err = os.WriteFile(remotePath, []byte("remote change"), 0777)
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+}
+
+// conflictCopies returns the contents of the conflict copies of path in cloud storage
+func (suite *fileCacheTestSuite) conflictCopies(path string) []string {
+	names, err := filepath.Glob(filepath.Join(suite.fake_storage_path, path+".conflict-*"))
+	suite.assert.NoError(err)
+	contents := make([]string, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		suite.assert.NoError(err)
+		contents = append(contents, string(data))
+	}
+	return contents
+}
+
+func (suite *fileCacheTestSuite) TestUploadConflictKeepBoth() {
+	defer suite.cleanupTest()
+	path := "conflict-keep-both"
+
+	suite.writeConflictingFile(path)
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("remote change", string(remote))
+	suite.assert.Equal([]string{"localnal"}, suite.conflictCopies(path))
+	// the stale local copy is dropped, so the next open downloads the new version
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, path))
+	_, pending := suite.fileCache.pendingOps.Load(path)
+	suite.assert.False(pending)
+}
+
+func (suite *fileCacheTestSuite) TestUploadConflictRemoteWins() {
+	suite.setupConflictPolicy(conflictRemoteWins)
+	defer suite.cleanupTest()
+	path := "conflict-remote-wins"
+
+	suite.writeConflictingFile(path)
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("remote change", string(remote))
+	suite.assert.Empty(suite.conflictCopies(path))
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, path))
+}
+
+func (suite *fileCacheTestSuite) TestUploadConflictLocalWins() {
+	suite.setupConflictPolicy(conflictLocalWins)
+	defer suite.cleanupTest()
+	path := "conflict-local-wins"
+
+	suite.writeConflictingFile(path)
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("localnal", string(remote))
+	suite.assert.Empty(suite.conflictCopies(path))
+}
+
+func (suite *fileCacheTestSuite) TestUploadConflictNewFile() {
+	defer suite.cleanupTest()
+	path := "conflict-new-file"
+
+	handle, err := suite.fileCache.CreateFile(internal.CreateFileOptions{Name: path, Mode: 0777})
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(
+		&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("local")},
+	)
+	suite.assert.NoError(err)
+
+	// someone else creates the object before ours is uploaded
+	err = os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("remote"), 0777)
+	suite.assert.NoError(err)
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("remote", string(remote))
+	suite.assert.Equal([]string{"local"}, suite.conflictCopies(path))
+}
+
+func (suite *fileCacheTestSuite) TestUploadConflictOpenHandle() {
+	defer suite.cleanupTest()
+	path := "conflict-open-handle"
+	remotePath := filepath.Join(suite.fake_storage_path, path)
+	err := os.WriteFile(remotePath, []byte("original"), 0777)
+	suite.assert.NoError(err)
+
+	options := internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777}
+	handle, err := suite.fileCache.OpenFile(options)
+	suite.assert.NoError(err)
+	reader, err := suite.fileCache.OpenFile(options)
+	suite.assert.NoError(err)
+	_, err = suite.fileCache.WriteFile(
+		&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte("local")},
+	)
+	suite.assert.NoError(err)
+	err = os.WriteFile(remotePath, []byte("remote change"), 0777)
+	suite.assert.NoError(err)
+
+	// the local copy is still in use, so it is kept until the last handle is released
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+	suite.assert.Equal([]string{"localnal"}, suite.conflictCopies(path))
+	suite.assert.FileExists(filepath.Join(suite.cache_path, path))
+
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: reader})
+	suite.assert.NoError(err)
+	suite.assert.NoFileExists(filepath.Join(suite.cache_path, path))
+}
+
+func (suite *fileCacheTestSuite) TestUploadTracksETag() {
+	defer suite.cleanupTest()
+	path := "tracks-etag"
+	err := os.WriteFile(filepath.Join(suite.fake_storage_path, path), []byte("original"), 0777)
+	suite.assert.NoError(err)
+
+	handle, err := suite.fileCache.OpenFile(
+		internal.OpenFileOptions{Name: path, Flags: os.O_RDWR, Mode: 0777},
+	)
+	suite.assert.NoError(err)
+	// each upload must match the object left by the one before it
+	for _, data := range []string{"first", "second"} {
+		_, err = suite.fileCache.WriteFile(
+			&internal.WriteFileOptions{Handle: handle, Offset: 0, Data: []byte(data)},
+		)
+		suite.assert.NoError(err)
+		err = suite.fileCache.FlushFile(internal.FlushFileOptions{Handle: handle})
+		suite.assert.NoError(err)
+	}
+	err = suite.fileCache.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	remote, err := os.ReadFile(filepath.Join(suite.fake_storage_path, path))
+	suite.assert.NoError(err)
+	suite.assert.Equal("secondal", string(remote))
+	suite.assert.Empty(suite.conflictCopies(path))
+}
+
 func (suite *fileCacheTestSuite) TestHardLimitOnSize() {
 	defer suite.cleanupTest()
 	// Configure to create empty files so we create the file in cloud storage
@@ -87,3 +87,9 @@ func (fc *FileCache) syncFile(f *os.File, path string) error {
 	}
 	return nil
 }
+
+// syncDir makes changes to the entries of a directory durable.
+// Windows does not support syncing a directory, and NTFS journals its metadata.
+func syncDir(_ string) error {
+	return nil
+}
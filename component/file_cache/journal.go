@@ -0,0 +1,307 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+)
+
+// The journal is an append-only log of changes to pendingOps, kept in the cache directory.
+// Each change is applied and synced to disk under the journal lock, before the operation that made
+// it returns, so pending uploads and deletions survive a crash or power loss. A change that cannot
+// be journaled is logged and still applied, so it is only lost if cloudfuse stops before it is
+// synchronized with cloud storage. Each line holds the CRC-32 of a JSON record followed by the
+// record, so a line torn by a crash is detected and dropped when the journal is replayed.
+
+const (
+	// Journal relative filepath
+	journalPath = ".fileCachePendingOps.journal"
+	// Smallest number of records that triggers compaction
+	minJournalCompaction = 1024
+)
+
+type journalOp string
+
+const (
+	journalUpload journalOp = "upload" // file or directory created or modified
+	journalDelete journalOp = "delete"
+	journalRename journalOp = "rename"
+	journalDone   journalOp = "done" // operation synchronized with cloud storage
+)
+
+type journalRecord struct {
+	Op    journalOp `json:"op"`
+	Name  string    `json:"name"`
+	Dst   string    `json:"dst,omitempty"`
+	IsDir bool      `json:"dir,omitempty"`
+	ETag  *string   `json:"etag,omitempty"` // the ETag the upload must match
+}
+
+type pendingOpJournal struct {
+	sync.Mutex
+	path      string
+	file      *os.File // opened on the first append after compaction
+	closed    bool
+	records   int // number of records in the journal
+	compactAt int // number of records that triggers compaction
+}
+
+// newJournalRecord records the pending operation of name, with the ETag from etags its upload
+// must match. Deletions are never checked, so they have no ETag.
+func newJournalRecord(name string, flags pendingFlags, etags *sync.Map) journalRecord {
+	if flags.isDeletion {
+		return journalRecord{Op: journalDelete, Name: name, IsDir: flags.isDir}
+	}
+	r := journalRecord{Op: journalUpload, Name: name, IsDir: flags.isDir}
+	if etags != nil {
+		if etag, found := etags.Load(name); found {
+			r.ETag = new(etag.(string))
+		}
+	}
+	return r
+}
+
+// apply updates ops with the record, and reports whether it changed anything
+func (r journalRecord) apply(ops *sync.Map) bool {
+	switch r.Op {
+	case journalUpload, journalDelete:
+		flags := pendingFlags{isDir: r.IsDir, isDeletion: r.Op == journalDelete}
+		previous, found := ops.Swap(r.Name, flags)
+		return !found || previous.(pendingFlags) != flags
+	case journalRename:
+		// the source must be deleted from cloud storage, and the destination uploaded instead
+		value, found := ops.LoadAndDelete(r.Name)
+		if !found {
+			return false
+		}
+		flags := value.(pendingFlags)
+		ops.Store(r.Name, pendingFlags{isDir: flags.isDir, isDeletion: true})
+		ops.Store(r.Dst, flags)
+		return true
+	case journalDone:
+		_, found := ops.LoadAndDelete(r.Name)
+		return found
+	}
+	return false
+}
+
+func (r journalRecord) encode(buf *bytes.Buffer) {
+	data, _ := json.Marshal(r)
+	fmt.Fprintf(buf, "%08x %s\n", crc32.ChecksumIEEE(data), data)
+}
+
+func decodeJournalRecord(line []byte) (journalRecord, error) {
+	var r journalRecord
+	checksum, data, found := bytes.Cut(line, []byte(" "))
+	if !found {
+		return r, fmt.Errorf("malformed record")
+	}
+	crc, err := strconv.ParseUint(string(checksum), 16, 32)
+	if err != nil || uint32(crc) != crc32.ChecksumIEEE(data) {
+		return r, fmt.Errorf("checksum mismatch")
+	}
+	err = json.Unmarshal(data, &r)
+	return r, err
+}
+
+// restoreETag updates etags with the record as it is replayed, the way FileCache did when the
+// record was written
+func (r journalRecord) restoreETag(etags *sync.Map) {
+	switch r.Op {
+	case journalUpload:
+		if r.ETag != nil {
+			etags.Store(r.Name, *r.ETag)
+		} else {
+			etags.Delete(r.Name)
+		}
+	case journalRename:
+		etags.Delete(r.Name)
+		etags.Delete(r.Dst)
+	}
+}
+
+// openJournal replays the journal in tmpPath into ops and etags, then compacts it.
+// Without a journal, ops is left alone and becomes the content of the new journal.
+func openJournal(tmpPath string, ops *sync.Map, etags *sync.Map) (*pendingOpJournal, error) {
+	j := &pendingOpJournal{path: filepath.Join(tmpPath, journalPath)}
+	// a compaction interrupted by a crash leaves the old journal intact
+	_ = os.Remove(j.path + ".tmp")
+
+	data, err := os.ReadFile(j.path)
+	switch {
+	case err == nil:
+		ops.Clear()
+		replayed := 0
+		for line := range bytes.Lines(data) {
+			if !bytes.HasSuffix(line, []byte("\n")) {
+				log.Warn("FileCache::openJournal : Dropping incomplete record %d", replayed+1)
+				break
+			}
+			r, err := decodeJournalRecord(bytes.TrimSuffix(line, []byte("\n")))
+			if err != nil {
+				log.Err(
+					"FileCache::openJournal : Dropping records from %d on. Here's why: %v",
+					replayed+1,
+					err,
+				)
+				break
+			}
+			r.apply(ops)
+			r.restoreETag(etags)
+			replayed++
+		}
+		log.Info("FileCache::openJournal : Replayed %d records", replayed)
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+
+	err = j.compact(ops, etags)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// append writes the record to the journal and waits until it is on disk. j must be locked.
+func (j *pendingOpJournal) append(r journalRecord) error {
+	if j.closed {
+		return os.ErrClosed
+	}
+	if j.file == nil {
+		file, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		j.file = file
+		err = syncDir(filepath.Dir(j.path))
+		if err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	r.encode(&buf)
+	_, err := j.file.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	j.records++
+	return j.file.Sync()
+}
+
+// compact replaces the journal with just the records needed to recreate ops and their ETags.
+// j must be locked. There is no journal while there are no pending operations.
+func (j *pendingOpJournal) compact(ops *sync.Map, etags *sync.Map) error {
+	var buf bytes.Buffer
+	records := 0
+	ops.Range(func(key, value any) bool {
+		newJournalRecord(key.(string), value.(pendingFlags), etags).encode(&buf)
+		records++
+		return true
+	})
+
+	// the journal cannot be replaced on Windows while it is open
+	if j.file != nil {
+		_ = j.file.Close()
+		j.file = nil
+	}
+	var err error
+	if records == 0 {
+		err = os.Remove(j.path)
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		// write the new journal next to the old one, and atomically replace it
+		tmpPath := j.path + ".tmp"
+		err = writeFileSynced(tmpPath, buf.Bytes())
+		if err == nil {
+			err = os.Rename(tmpPath, j.path)
+		}
+		if err != nil {
+			_ = os.Remove(tmpPath)
+		}
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(j.path))
+	}
+	if err != nil {
+		return err
+	}
+
+	j.records = records
+	j.compactAt = max(minJournalCompaction, 2*records)
+	return nil
+}
+
+// close closes the journal, leaving it on disk to be replayed on the next start
+func (j *pendingOpJournal) close() error {
+	if j == nil {
+		return nil
+	}
+	j.Lock()
+	defer j.Unlock()
+	j.closed = true
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+func writeFileSynced(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// isJournalFile reports whether name in the local directory dir belongs to the journal,
+// or to the state of resumable uploads kept by the storage component
+func (fc *FileCache) isJournalFile(dir string, name string) bool {
+	return filepath.Clean(dir) == fc.tmpPath &&
+		(strings.HasPrefix(name, journalPath) || name == common.UploadStateDirName)
+}
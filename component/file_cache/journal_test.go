@@ -0,0 +1,276 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package file_cache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type journalTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *journalTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic(fmt.Sprintf("Unable to set silent logger as default: %v", err))
+	}
+	suite.assert = assert.New(suite.T())
+	err = os.Mkdir(cache_path, fs.FileMode(0777))
+	suite.assert.NoError(err)
+}
+
+func (suite *journalTestSuite) cleanupTest() {
+	err := os.RemoveAll(cache_path)
+	if err != nil {
+		fmt.Printf(
+			"journalTestSuite::cleanupTest : os.RemoveAll(%s) failed [%v]\n",
+			cache_path,
+			err,
+		)
+	}
+}
+
+// record applies and journals the record, the way FileCache.updatePendingOps does
+func (suite *journalTestSuite) record(j *pendingOpJournal, ops *sync.Map, r journalRecord) {
+	j.Lock()
+	defer j.Unlock()
+	if r.apply(ops) {
+		suite.assert.NoError(j.append(r))
+	}
+}
+
+func (suite *journalTestSuite) replay() *sync.Map {
+	ops := &sync.Map{}
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.assert.NoError(j.close())
+	return ops
+}
+
+func pendingOpsMap(ops *sync.Map) map[string]pendingFlags {
+	result := make(map[string]pendingFlags)
+	ops.Range(func(key, value any) bool {
+		result[key.(string)] = value.(pendingFlags)
+		return true
+	})
+	return result
+}
+
+func (suite *journalTestSuite) TestReplay() {
+	defer suite.cleanupTest()
+	ops := &sync.Map{}
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+
+	suite.record(j, ops, newJournalRecord("dir", pendingFlags{isDir: true}, nil))
+	suite.record(j, ops, newJournalRecord("dir/a", pendingFlags{}, nil))
+	suite.record(j, ops, newJournalRecord("b", pendingFlags{}, nil))
+	suite.record(j, ops, newJournalRecord("c", pendingFlags{isDeletion: true}, nil))
+	suite.record(j, ops, journalRecord{Op: journalDone, Name: "b"})
+	suite.record(j, ops, journalRecord{Op: journalRename, Name: "dir/a", Dst: "dir/d"})
+
+	// crash without closing the journal
+	expected := map[string]pendingFlags{
+		"dir":   {isDir: true},
+		"dir/a": {isDeletion: true},
+		"dir/d": {},
+		"c":     {isDeletion: true},
+	}
+	suite.assert.Equal(expected, pendingOpsMap(ops))
+	suite.assert.Equal(expected, pendingOpsMap(suite.replay()))
+	suite.assert.NoError(j.close())
+}
+
+func (suite *journalTestSuite) TestReplayReplacesSnapshot() {
+	defer suite.cleanupTest()
+	ops := &sync.Map{}
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.record(j, ops, newJournalRecord("a", pendingFlags{}, nil))
+	suite.assert.NoError(j.close())
+
+	// the journal is newer than any snapshot of pending operations
+	snapshot := &sync.Map{}
+	snapshot.Store("stale", pendingFlags{})
+	j, err = openJournal(cache_path, snapshot, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.assert.Equal(map[string]pendingFlags{"a": {}}, pendingOpsMap(snapshot))
+	suite.assert.NoError(j.close())
+}
+
+func (suite *journalTestSuite) TestSnapshotWithoutJournal() {
+	defer suite.cleanupTest()
+	ops := &sync.Map{}
+	ops.Store("a", pendingFlags{})
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.assert.NoError(j.close())
+
+	suite.assert.FileExists(filepath.Join(cache_path, journalPath))
+	suite.assert.Equal(map[string]pendingFlags{"a": {}}, pendingOpsMap(suite.replay()))
+}
+
+func (suite *journalTestSuite) TestTornRecord() {
+	defer suite.cleanupTest()
+	ops := &sync.Map{}
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.record(j, ops, newJournalRecord("a", pendingFlags{}, nil))
+	suite.record(j, ops, newJournalRecord("b", pendingFlags{}, nil))
+	suite.assert.NoError(j.close())
+
+	// cut the last record short
+	path := filepath.Join(cache_path, journalPath)
+	info, err := os.Stat(path)
+	suite.assert.NoError(err)
+	suite.assert.NoError(os.Truncate(path, info.Size()-3))
+
+	suite.assert.Equal(map[string]pendingFlags{"a": {}}, pendingOpsMap(suite.replay()))
+}
+
+func (suite *journalTestSuite) TestCorruptRecord() {
+	defer suite.cleanupTest()
+	ops := &sync.Map{}
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.record(j, ops, newJournalRecord("a", pendingFlags{}, nil))
+	suite.record(j, ops, newJournalRecord("b", pendingFlags{}, nil))
+	suite.record(j, ops, newJournalRecord("c", pendingFlags{}, nil))
+	suite.assert.NoError(j.close())
+
+	// flip a byte in the second record, which drops it and every record after it
+	path := filepath.Join(cache_path, journalPath)
+	data, err := os.ReadFile(path)
+	suite.assert.NoError(err)
+	second := len(data) / 3
+	data[second+len(data)/6] ^= 0x01
+	suite.assert.NoError(os.WriteFile(path, data, 0644))
+
+	suite.assert.Equal(map[string]pendingFlags{"a": {}}, pendingOpsMap(suite.replay()))
+}
+
+func (suite *journalTestSuite) TestNoJournalWithoutPendingOps() {
+	defer suite.cleanupTest()
+	ops := &sync.Map{}
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.assert.NoFileExists(filepath.Join(cache_path, journalPath))
+
+	suite.record(j, ops, newJournalRecord("a", pendingFlags{}, nil))
+	suite.assert.FileExists(filepath.Join(cache_path, journalPath))
+	suite.record(j, ops, journalRecord{Op: journalDone, Name: "a"})
+	suite.assert.NoError(j.close())
+
+	suite.assert.Empty(pendingOpsMap(suite.replay()))
+	suite.assert.NoFileExists(filepath.Join(cache_path, journalPath))
+}
+
+func (suite *journalTestSuite) TestCompact() {
+	defer suite.cleanupTest()
+	ops := &sync.Map{}
+	j, err := openJournal(cache_path, ops, &sync.Map{})
+	suite.assert.NoError(err)
+	suite.assert.Equal(minJournalCompaction, j.compactAt)
+
+	for i := range minJournalCompaction / 2 {
+		suite.record(j, ops, newJournalRecord(fmt.Sprintf("file%d", i), pendingFlags{}, nil))
+		suite.record(j, ops, journalRecord{Op: journalDone, Name: fmt.Sprintf("file%d", i)})
+	}
+	suite.record(j, ops, newJournalRecord("a", pendingFlags{}, nil))
+	suite.assert.Equal(minJournalCompaction+1, j.records)
+
+	j.Lock()
+	suite.assert.NoError(j.compact(ops, &sync.Map{}))
+	j.Unlock()
+	suite.assert.Equal(1, j.records)
+	suite.record(j, ops, newJournalRecord("b", pendingFlags{}, nil))
+	suite.assert.NoError(j.close())
+
+	expected := map[string]pendingFlags{"a": {}, "b": {}}
+	suite.assert.Equal(expected, pendingOpsMap(suite.replay()))
+}
+
+func (suite *journalTestSuite) TestETags() {
+	defer suite.cleanupTest()
+	ops, etags := &sync.Map{}, &sync.Map{}
+	j, err := openJournal(cache_path, ops, etags)
+	suite.assert.NoError(err)
+
+	etags.Store("modified", "etag")
+	etags.Store("new", "")
+	etags.Store("deleted", "etag")
+	etags.Store("renamed", "etag")
+	suite.record(j, ops, newJournalRecord("modified", pendingFlags{}, etags))
+	suite.record(j, ops, newJournalRecord("new", pendingFlags{}, etags))
+	suite.record(j, ops, newJournalRecord("deleted", pendingFlags{isDeletion: true}, etags))
+	suite.record(j, ops, newJournalRecord("renamed", pendingFlags{}, etags))
+	suite.record(j, ops, journalRecord{Op: journalRename, Name: "renamed", Dst: "dst"})
+	suite.assert.NoError(j.close())
+
+	expected := map[string]any{"modified": "etag", "new": ""}
+	replayed := &sync.Map{}
+	replayed.Store("dst", "stale")
+	for range 2 {
+		// the second time the journal holds just the records written by compaction
+		j, err = openJournal(cache_path, &sync.Map{}, replayed)
+		suite.assert.NoError(err)
+		suite.assert.NoError(j.close())
+		result := make(map[string]any)
+		replayed.Range(func(key, value any) bool {
+			result[key.(string)] = value
+			return true
+		})
+		suite.assert.Equal(expected, result)
+		replayed = &sync.Map{}
+	}
+}
+
+func (suite *journalTestSuite) TestIsJournalFile() {
+	defer suite.cleanupTest()
+	fc := &FileCache{tmpPath: filepath.Clean(cache_path)}
+	suite.assert.True(fc.isJournalFile(cache_path, journalPath))
+	suite.assert.True(fc.isJournalFile(cache_path, journalPath+".tmp"))
+	suite.assert.True(fc.isJournalFile(cache_path, common.UploadStateDirName))
+	suite.assert.False(fc.isJournalFile(filepath.Join(cache_path, "dir"), journalPath))
+	suite.assert.False(fc.isJournalFile(cache_path, "file"))
+}
+
+func TestJournalTestSuite(t *testing.T) {
+	suite.Run(t, new(journalTestSuite))
+}
@@ -82,6 +82,8 @@ type lruPolicy struct {
 type pendingOpSnapshot struct {
 	IsDir      bool
 	IsDeletion bool
+	ETag       string // the ETag the upload must match, empty if the object must not exist
+	HasETag    bool   // gob drops empty strings, so whether there is an ETag is kept apart
 }
 
 // lruPolicySnapshot represents the *persisted state* of lruPolicy.
@@ -203,10 +205,14 @@ func (p *lruPolicy) createSnapshot() *lruPolicySnapshot {
 	snapshot.PendingOps = make(map[string]pendingOpSnapshot)
 	p.pendingOps.Range(func(key, value any) bool {
 		flags := value.(pendingFlags)
-		snapshot.PendingOps[key.(string)] = pendingOpSnapshot{
+		opSnapshot := pendingOpSnapshot{
 			IsDir:      flags.isDir,
 			IsDeletion: flags.isDeletion,
 		}
+		if etag, found := p.etags.Load(key); found {
+			opSnapshot.ETag, opSnapshot.HasETag = etag.(string), true
+		}
+		snapshot.PendingOps[key.(string)] = opSnapshot
 		return true
 	})
 
@@ -224,6 +230,9 @@ func (p *lruPolicy) loadSnapshot(snapshot *lruPolicySnapshot) {
 	if len(snapshot.PendingOps) > 0 {
 		for key, value := range snapshot.PendingOps {
 			p.pendingOps.Store(key, pendingFlags{isDir: value.IsDir, isDeletion: value.IsDeletion})
+			if value.HasETag {
+				p.etags.Store(key, value.ETag)
+			}
 		}
 	} else {
 		// Backward compatibility: use SyncPendingFlags if PendingOps is not available
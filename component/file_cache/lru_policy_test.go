@@ -70,6 +70,7 @@ func (suite *lruPolicyTestSuite) SetupTest() {
 		lowThreshold:  defaultMinThreshold,
 		fileLocks:     &common.LockMap{},
 		pendingOps:    &sync.Map{},
+		etags:         &sync.Map{},
 	}
 
 	suite.setupTestHelper(config)
@@ -183,6 +184,7 @@ func (suite *lruPolicyTestSuite) TestUpdateConfig() {
 		lowThreshold:  20,
 		fileLocks:     &common.LockMap{},
 		pendingOps:    &sync.Map{},
+		etags:         &sync.Map{},
 	}
 	err := suite.policy.UpdateConfig(config)
 	suite.assert.NoError(err)
@@ -219,6 +221,7 @@ func (suite *lruPolicyTestSuite) TestCachePurge() {
 		lowThreshold:  defaultMinThreshold,
 		fileLocks:     &common.LockMap{},
 		pendingOps:    &sync.Map{},
+		etags:         &sync.Map{},
 	}
 	suite.setupTestHelper(config)
 
@@ -322,6 +325,7 @@ func (suite *lruPolicyTestSuite) TestMaxEviction() {
 		lowThreshold:  defaultMinThreshold,
 		fileLocks:     &common.LockMap{},
 		pendingOps:    &sync.Map{},
+		etags:         &sync.Map{},
 	}
 
 	suite.setupTestHelper(config)
@@ -544,7 +548,8 @@ func (suite *lruPolicyTestSuite) TestSnapshotSerialization() {
 		LastMarkerPosition: 2,
 		SyncPendingFlags:   []bool{true, false, false},
 		PendingOps: map[string]pendingOpSnapshot{
-			"a":              {},
+			"a":              {ETag: "etag", HasETag: true},
+			"new-file":       {HasETag: true},
 			"deleted-file":   {IsDeletion: true},
 			"deleted-folder": {IsDir: true, IsDeletion: true},
 		},
@@ -570,11 +575,23 @@ func (suite *lruPolicyTestSuite) TestLoadSnapshotRestoresExplicitPendingOps() {
 			"cached-file": {IsDeletion: true},
 			"cached-dir":  {IsDir: true},
 			"deleted-dir": {IsDir: true, IsDeletion: true},
+			"new-file":    {HasETag: true},
+			"modified":    {ETag: "etag", HasETag: true},
 		},
 	}
 
 	suite.policy.loadSnapshot(snapshot)
 
+	// the ETags uploads must match are restored with the operations
+	etag, found := suite.policy.etags.Load("modified")
+	suite.assert.True(found)
+	suite.assert.Equal("etag", etag)
+	etag, found = suite.policy.etags.Load("new-file")
+	suite.assert.True(found)
+	suite.assert.Empty(etag)
+	_, found = suite.policy.etags.Load("cached-file")
+	suite.assert.False(found)
+
 	for name, expected := range snapshot.PendingOps {
 		value, found := suite.policy.pendingOps.Load(name)
 		suite.assert.True(found, "expected pending op %s to be restored", name)
@@ -590,6 +607,21 @@ func (suite *lruPolicyTestSuite) TestLoadSnapshotRestoresExplicitPendingOps() {
 	suite.assert.True(suite.policy.IsCached(filepath.Join(cache_path, "cached-dir")))
 }
 
+func (suite *lruPolicyTestSuite) TestSnapshotSavesETags() {
+	defer suite.cleanupTest()
+
+	suite.policy.pendingOps.Store("modified", pendingFlags{})
+	suite.policy.etags.Store("modified", "etag")
+	suite.policy.pendingOps.Store("unknown", pendingFlags{})
+	suite.policy.etags.Store("synced", "other")
+
+	snapshot := suite.policy.createSnapshot()
+	suite.assert.Equal(
+		map[string]pendingOpSnapshot{"modified": {ETag: "etag", HasETag: true}, "unknown": {}},
+		snapshot.PendingOps,
+	)
+}
+
 func (suite *lruPolicyTestSuite) TestLoadSnapshotFallsBackToSyncPendingFlags() {
 	defer suite.cleanupTest()
 
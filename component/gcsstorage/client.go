@@ -0,0 +1,1174 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const storageScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// stagedBlock is a block uploaded by StageBlock as a temporary object
+type stagedBlock struct {
+	key  string
+	size int64
+}
+
+// committedBlocks describes the blocks making up an object as handed out by GetCommittedBlockList
+type committedBlocks struct {
+	blocks map[string]internal.CommittedBlock
+	size   int64
+}
+
+type Client struct {
+	Connection
+	httpClient        *http.Client
+	blockLocks        common.KeyedMutex
+	stagedBlocks      map[string]map[string]stagedBlock // map[fileName]map[blockId]block
+	committedBlocks   map[string]*committedBlocks       // map[fileName]blocks
+	stagedBlocksMutex sync.Mutex
+}
+
+// Verify that Client implements GcsConnection interface
+var _ GcsConnection = &Client{}
+
+// The text before the : symbol is a magic keyword
+// It cannot change as it is parsed by our plugin for network optix to provide more clear errors to the user
+var (
+	errBucketDoesNotExist = errors.New(
+		"Bucket Error: GCS bucket does not exist or you do not have permission to access it, please check your bucket name and endpoint are correct",
+	)
+	errInvalidEndpoint = errors.New(
+		"Endpoint Error: Provided GCS endpoint is invalid, please check endpoint is correct",
+	)
+	errInvalidCredential = errors.New(
+		"Credential Error: GCS credentials are invalid or could not be found, please check your key file or application default credentials",
+	)
+	errNoBucketInAccount = errors.New(
+		"Bucket Error: No bucket exists in GCS project, please create a bucket in your project",
+	)
+	errNoProjectID = errors.New(
+		"Project Error: GCS project ID is required to list buckets, please set project-id",
+	)
+)
+
+// Configure : Initialize the http client used to call the GCS JSON API
+func (cl *Client) Configure(cfg Config) error {
+	log.Trace("Client::Configure : initialize GCS client")
+	cl.Config = cfg
+
+	var err error
+	cl.Endpoint, err = url.Parse(cl.Config.AuthConfig.Endpoint)
+	if err != nil || cl.Endpoint.Host == "" {
+		log.Err("Client::Configure : Failed to parse endpoint %s", cl.Config.AuthConfig.Endpoint)
+		return errInvalidEndpoint
+	}
+
+	cl.httpClient, err = cl.newHttpClient()
+	if err != nil {
+		log.Err("Client::Configure : Failed to create credentials. Here's why: %v", err)
+		return errInvalidCredential
+	}
+
+	ctx := context.Background()
+
+	// if no bucket-name was set, default to the first bucket in the project
+	if cl.Config.AuthConfig.BucketName == "" {
+		bucketList, err := cl.ListBuckets(ctx)
+		if err != nil {
+			log.Err("Client::Configure : listing buckets failed. Here's why: %v", err)
+			return err
+		}
+		if len(bucketList) == 0 {
+			log.Err("Client::Configure : Error no bucket exists in project")
+			return errNoBucketInAccount
+		}
+		slices.Sort(bucketList)
+		cl.Config.AuthConfig.BucketName = bucketList[0]
+		log.Warn(
+			"Client::Configure : Bucket defaulted to the first one, alphabetically: %s",
+			cl.Config.AuthConfig.BucketName,
+		)
+	}
+
+	// Check that the provided bucket exists and that user has access to bucket
+	err = cl.getBucket(ctx, cl.Config.AuthConfig.BucketName)
+	if err != nil {
+		log.Err("Client::Configure : Error finding bucket. Here's why: %v", err)
+		if isUnreachable(err) {
+			return parseGcsErr(err, "GetBucket "+cl.Config.AuthConfig.BucketName)
+		}
+		return errBucketDoesNotExist
+	}
+
+	// Use list objects validate user can list objects
+	_, _, err = cl.List(ctx, "", nil, 1)
+	if err != nil {
+		log.Err("Client::Configure : listing objects failed. Here's why: %v", err)
+		return err
+	}
+
+	return nil
+}
+
+// newHttpClient creates the http client used for all requests.
+// Requests are authorized with the key file when one is configured, otherwise with the
+// application default credentials. Anonymous clients are meant for emulators and public buckets.
+func (cl *Client) newHttpClient() (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   Timeout,
+			KeepAlive: KeepAlive,
+		}).DialContext,
+		MaxIdleConnsPerHost: MaxIdleConnsPerHost,
+		IdleConnTimeout:     IdleConnTimeout,
+		TLSHandshakeTimeout: TLSHandshakeTimeout,
+		ForceAttemptHTTP2:   true,
+	}
+	if cl.Config.AuthConfig.Anonymous {
+		return &http.Client{Transport: transport}, nil
+	}
+
+	// fetch tokens through the same transport
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: transport})
+
+	var creds *google.Credentials
+	var err error
+	if cl.Config.AuthConfig.KeyFile != "" {
+		var keyData []byte
+		keyData, err = os.ReadFile(cl.Config.AuthConfig.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var keyType struct {
+			Type string `json:"type"`
+		}
+		if err = json.Unmarshal(keyData, &keyType); err != nil {
+			return nil, err
+		}
+		credType := google.CredentialsType(keyType.Type)
+		switch credType {
+		case google.ServiceAccount, google.AuthorizedUser, google.ExternalAccount:
+		default:
+			return nil, fmt.Errorf("unsupported credential type %q in key file", keyType.Type)
+		}
+		creds, err = google.CredentialsFromJSONWithType(ctx, keyData, credType, storageScope)
+	} else {
+		creds, err = google.FindDefaultCredentials(ctx, storageScope)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cl.Config.AuthConfig.ProjectID == "" {
+		cl.Config.AuthConfig.ProjectID = creds.ProjectID
+	}
+
+	return &http.Client{
+		Transport: &oauth2.Transport{Source: creds.TokenSource, Base: transport},
+	}, nil
+}
+
+// For dynamic configuration, update the config here.
+func (cl *Client) UpdateConfig(cfg Config) error {
+	cl.Config.maxRetries = cfg.maxRetries
+	cl.Config.disableSymlink = cfg.disableSymlink
+	return nil
+}
+
+// Set the prefix path - this overrides "subdirectory" in config.yaml.
+// This is only used for testing.
+func (cl *Client) SetPrefixPath(path string) error {
+	log.Trace("Client::SetPrefixPath : path %s", path)
+	cl.Config.prefixPath = path
+	return nil
+}
+
+// CreateFile : Create a new file in the bucket/virtual directory
+func (cl *Client) CreateFile(ctx context.Context, name string, mode os.FileMode) error {
+	log.Trace("Client::CreateFile : name %s", name)
+	return cl.WriteFromBuffer(ctx, name, nil, nil)
+}
+
+// CreateDirectory : Create a new directory in the bucket/virtual directory
+func (cl *Client) CreateDirectory(ctx context.Context, name string) error {
+	log.Trace("Client::CreateDirectory : name %s", name)
+
+	// Without directory markers there is nothing to create,
+	// the directory will exist once an object is created in it.
+	if cl.Config.enableDirMarker {
+		_, err := cl.putObject(ctx, putObjectOptions{name: name, isDir: true})
+		if err != nil {
+			log.Err("Client::CreateDirectory : putObject(%s) failed. Here's why: %v", name, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CreateLink : Create a symlink in the bucket/virtual directory
+func (cl *Client) CreateLink(ctx context.Context, source string, target string) error {
+	log.Trace("Client::CreateLink : %s -> %s", source, target)
+	metadata := map[string]*string{symlinkKey: new("true")}
+	return cl.WriteFromBuffer(ctx, source, metadata, []byte(target))
+}
+
+// DeleteFile : Delete an object.
+// if the file does not exist, this returns an error (ENOENT).
+func (cl *Client) DeleteFile(ctx context.Context, name string) error {
+	log.Trace("Client::DeleteFile : name %s", name)
+
+	err := cl.deleteObject(ctx, name, false)
+	if err != nil {
+		log.Err("Client::DeleteFile : Failed to delete object %s. Here's why: %v", name, err)
+	}
+	return err
+}
+
+// DeleteDirectory : Delete the directory marker in the bucket
+// If the directory marker does not exist, no error will be returned.
+func (cl *Client) DeleteDirectory(ctx context.Context, name string) error {
+	log.Trace("Client::DeleteDirectory : name %s", name)
+
+	err := cl.deleteObject(ctx, name, true)
+	if err == syscall.ENOENT {
+		return nil
+	}
+	if err != nil {
+		log.Err(
+			"Client::DeleteDirectory : Failed to delete directory %s. Here's why: %v",
+			name,
+			err,
+		)
+	}
+	return err
+}
+
+// RenameFile : Rename the object (copy then delete).
+func (cl *Client) RenameFile(ctx context.Context, source string, target string) error {
+	log.Trace("Client::RenameFile : %s -> %s", source, target)
+
+	err := cl.renameObject(ctx, source, target, false)
+	if err != nil {
+		log.Err(
+			"Client::RenameFile : renameObject(%s->%s) failed. Here's why: %v",
+			source,
+			target,
+			err,
+		)
+	}
+	return err
+}
+
+// RenameDirectory : Rename every object under the source directory
+func (cl *Client) RenameDirectory(ctx context.Context, source string, target string) error {
+	log.Trace("Client::RenameDirectory : %s -> %s", source, target)
+
+	listPath := internal.ExtendDirName(cl.getKey(source, false))
+	pageToken := ""
+	for {
+		output, err := cl.listObjects(ctx, listObjectsOptions{
+			prefix:    listPath,
+			pageToken: pageToken,
+			fields:    "items(name),nextPageToken",
+		})
+		if err != nil {
+			log.Err(
+				"Client::RenameDirectory : Failed to list objects with prefix %s. Here's why: %v",
+				source,
+				err,
+			)
+			return err
+		}
+
+		for _, item := range output.Items {
+			srcPath := split(cl.Config.prefixPath, cl.getFile(item.Name))
+			dstPath := target + strings.TrimPrefix(srcPath, source)
+			isDir := strings.HasSuffix(srcPath, "/")
+			err = cl.renameObject(
+				ctx,
+				internal.TruncateDirName(srcPath),
+				internal.TruncateDirName(dstPath),
+				isDir,
+			)
+			if err != nil {
+				log.Err(
+					"Client::RenameDirectory : Failed to rename %s -> %s. Here's why: %v",
+					srcPath,
+					dstPath,
+					err,
+				)
+				return err
+			}
+		}
+
+		if output.NextPageToken == "" {
+			break
+		}
+		pageToken = output.NextPageToken
+	}
+
+	return nil
+}
+
+// GetAttr : Get attributes for a given file or folder.
+// If name is a file, it should not have a trailing slash.
+// If name is a directory, the trailing slash is optional.
+func (cl *Client) GetAttr(ctx context.Context, name string) (*internal.ObjAttr, error) {
+	log.Trace("Client::GetAttr : name %s", name)
+
+	// first let's suppose the caller is looking for a file
+	// so if this was called with a trailing slash, don't look for an object
+	if !strings.HasSuffix(name, "/") {
+		attr, err := cl.getFileAttr(ctx, name)
+		if err != syscall.ENOENT {
+			return attr, err
+		}
+	}
+
+	// now search for that as a directory
+	return cl.getDirectoryAttr(ctx, internal.TruncateDirName(name))
+}
+
+// Get attributes for the given file path.
+// Return ENOENT if there is no corresponding object in the bucket.
+func (cl *Client) getFileAttr(ctx context.Context, name string) (*internal.ObjAttr, error) {
+	object, err := cl.getObjectResource(ctx, name, false)
+	if err != nil {
+		return nil, err
+	}
+	attr := cl.createObjAttr(object)
+	attr.Path = name
+	attr.Name = path.Base(name)
+	return attr, nil
+}
+
+// GetMetadata : Get the user metadata of a file or directory.
+// Directories without a marker object have no metadata.
+func (cl *Client) GetMetadata(ctx context.Context, name string) (map[string]*string, error) {
+	log.Trace("Client::GetMetadata : name %s", name)
+	object, _, err := cl.findObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]*string)
+	if object == nil {
+		return metadata, nil
+	}
+	for key, value := range object.Metadata {
+		if key != symlinkKey && !internal.IsTimeMetadataKey(key) {
+			metadata[key] = value
+		}
+	}
+	return metadata, nil
+}
+
+// SetMetadata : Merge the given user metadata into the metadata of a file or directory marker.
+// A nil value removes the key.
+func (cl *Client) SetMetadata(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+) error {
+	log.Trace("Client::SetMetadata : name %s", name)
+	for key := range metadata {
+		if key == symlinkKey || internal.IsTimeMetadataKey(key) {
+			log.Err("Client::SetMetadata : %s is reserved [%s]", key, name)
+			return syscall.EINVAL
+		}
+	}
+
+	object, isDir, err := cl.findObject(ctx, name)
+	if err != nil {
+		return err
+	}
+	if object == nil {
+		log.Err("Client::SetMetadata : %s is a directory without a marker object", name)
+		return syscall.ENOTSUP
+	}
+
+	return cl.patchObjectMetadata(ctx, internal.TruncateDirName(name), isDir, metadata)
+}
+
+// Utimens : Store the access and modification times in the metadata of a file or directory marker.
+// A zero time is left unchanged. A directory without a marker object has nowhere to keep them.
+func (cl *Client) Utimens(ctx context.Context, name string, atime, mtime time.Time) error {
+	log.Trace("Client::Utimens : name %s", name)
+	metadata := make(map[string]*string, 2)
+	if !atime.IsZero() {
+		metadata[internal.AtimeMetadataKey] = new(internal.FormatMetadataTime(atime))
+	}
+	if !mtime.IsZero() {
+		metadata[internal.MtimeMetadataKey] = new(internal.FormatMetadataTime(mtime))
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	object, isDir, err := cl.findObject(ctx, name)
+	if err != nil {
+		return err
+	}
+	if object == nil {
+		log.Debug("Client::Utimens : %s is a directory without a marker object", name)
+		return nil
+	}
+
+	return cl.patchObjectMetadata(ctx, internal.TruncateDirName(name), isDir, metadata)
+}
+
+// findObject looks up the object that holds the metadata for name, first as a file
+// and then as a directory marker. A directory with no marker object is returned as nil.
+func (cl *Client) findObject(ctx context.Context, name string) (*objectResource, bool, error) {
+	name = internal.TruncateDirName(name)
+	object, err := cl.getObjectResource(ctx, name, false)
+	if err != syscall.ENOENT {
+		return object, false, err
+	}
+	object, err = cl.getObjectResource(ctx, name, true)
+	if err != syscall.ENOENT {
+		return object, true, err
+	}
+	_, err = cl.getDirectoryAttr(ctx, name)
+	return nil, true, err
+}
+
+func (cl *Client) getDirectoryAttr(ctx context.Context, dirName string) (*internal.ObjAttr, error) {
+	log.Trace("Client::getDirectoryAttr : name %s", dirName)
+
+	// look for a directory marker first, it is a single key lookup
+	if cl.Config.enableDirMarker {
+		_, err := cl.getObjectResource(ctx, dirName, true)
+		if err == nil {
+			return internal.CreateObjAttrDir(dirName), nil
+		}
+		if err != syscall.ENOENT {
+			return nil, err
+		}
+	}
+
+	// otherwise the directory exists if any object has it as a prefix
+	output, err := cl.listObjects(ctx, listObjectsOptions{
+		prefix:     internal.ExtendDirName(cl.getKey(dirName, false)),
+		maxResults: 1,
+		fields:     "items(name)",
+	})
+	if err != nil {
+		log.Err("Client::getDirectoryAttr : List(%s) failed. Here's why: %v", dirName, err)
+		return nil, err
+	}
+	if len(output.Items) > 0 {
+		return internal.CreateObjAttrDir(dirName), nil
+	}
+
+	// directory not found in bucket
+	log.Debug("Client::getDirectoryAttr : not found: %s", dirName)
+	return nil, syscall.ENOENT
+}
+
+// Download object data to a file handle.
+// Read starting at a byte offset from the start of the object, with length in bytes = count.
+// count = 0 reads to the end of the object.
+func (cl *Client) ReadToFile(
+	ctx context.Context,
+	name string,
+	offset int64,
+	count int64,
+	fi *os.File,
+) error {
+	log.Trace(
+		"Client::ReadToFile : name %s, offset : %d, count %d -> file %s",
+		name,
+		offset,
+		count,
+		fi.Name(),
+	)
+
+	objectDataReader, err := cl.getObject(
+		ctx,
+		getObjectOptions{name: name, offset: offset, count: count},
+	)
+	if err != nil {
+		log.Err("Client::ReadToFile : getObject(%s) failed. Here's why: %v", name, err)
+		return err
+	}
+	defer objectDataReader.Close()
+
+	written, err := io.Copy(fi, objectDataReader)
+	if err != nil {
+		log.Err("Client::ReadToFile : Couldn't read object data from %v. Here's why: %v", name, err)
+		return parseGcsErr(err, fmt.Sprintf("read object data from %s", name))
+	}
+
+	if written > 0 {
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, bytesDownloaded, written)
+	}
+	return nil
+}
+
+// Download object with the given name and return the data as a byte array.
+// Reads starting at a byte offset from the start of the object, with length in bytes = len.
+// len = 0 reads to the end of the object.
+func (cl *Client) ReadBuffer(
+	ctx context.Context,
+	name string,
+	offset int64,
+	length int64,
+) ([]byte, error) {
+	log.Trace("Client::ReadBuffer : name %s (%d+%d)", name, offset, length)
+	objectDataReader, err := cl.getObject(
+		ctx,
+		getObjectOptions{name: name, offset: offset, count: length},
+	)
+	if err != nil {
+		log.Err("Client::ReadBuffer : getObject(%s) failed. Here's why: %v", name, err)
+		return nil, err
+	}
+	defer objectDataReader.Close()
+
+	buff, err := io.ReadAll(objectDataReader)
+	if err != nil {
+		log.Err("Client::ReadBuffer : Failed to read data from %s. Here's why: %v", name, err)
+		return nil, parseGcsErr(err, fmt.Sprintf("read object data from %s", name))
+	}
+
+	return buff, nil
+}
+
+// Download object to provided byte array.
+// Reads starting at a byte offset from the start of the object, with length in bytes = len.
+// len = 0 reads to the end of the object.
+func (cl *Client) ReadInBuffer(
+	ctx context.Context,
+	name string,
+	offset int64,
+	length int64,
+	data []byte,
+) error {
+	log.Trace("Client::ReadInBuffer : name %s offset %d len %d", name, offset, length)
+	objectDataReader, err := cl.getObject(
+		ctx,
+		getObjectOptions{name: name, offset: offset, count: length},
+	)
+	if err != nil {
+		log.Err("Client::ReadInBuffer : getObject(%s) failed. Here's why: %v", name, err)
+		return err
+	}
+	defer objectDataReader.Close()
+
+	_, err = io.ReadFull(objectDataReader, data)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		// If we reached the EOF then all the data was correctly read
+		return nil
+	}
+	if err != nil {
+		return parseGcsErr(err, fmt.Sprintf("read object data from %s", name))
+	}
+
+	return nil
+}
+
+// Upload from a file handle to an object.
+// ifMatch makes the upload conditional (see internal.CopyFromFileOptions),
+// and newETag receives the ETag of the uploaded object.
+func (cl *Client) WriteFromFile(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+	fi *os.File,
+	ifMatch *string,
+	newETag *string,
+) error {
+	log.Trace("Client::WriteFromFile : file %s -> name %s", fi.Name(), name)
+	defer log.TimeTrack(time.Now(), "Client::WriteFromFile", name)
+
+	stat, err := fi.Stat()
+	if err != nil {
+		log.Err("Client::WriteFromFile : Failed to get file size %s. Here's why: %v", name, err)
+		return err
+	}
+
+	metadata, err = cl.keepMetadata(ctx, name, metadata)
+	if err != nil {
+		log.Err("Client::WriteFromFile : Failed to get metadata of %s. Here's why: %v", name, err)
+		return err
+	}
+
+	options := putObjectOptions{name: name, objectData: fi, size: stat.Size(), metadata: metadata}
+	if ifMatch != nil {
+		options.ifGenerationMatch, err = cl.generationMatch(ctx, name, *ifMatch)
+		if err != nil {
+			return err
+		}
+	}
+
+	result, err := cl.putObject(ctx, options)
+	if err != nil {
+		log.Err("Client::WriteFromFile : putObject(%s) failed. Here's why: %v", name, err)
+		return err
+	}
+	if newETag != nil {
+		*newETag = result.ETag
+	}
+
+	log.Debug("Client::WriteFromFile : Upload complete of object %v", name)
+
+	// store total bytes uploaded so far
+	if stat.Size() > 0 {
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, bytesUploaded, stat.Size())
+	}
+
+	return nil
+}
+
+// keepMetadata returns the metadata of an upload replacing name, with the metadata of the object
+// being replaced carried over, as GCS replaces all the metadata of an object on upload
+func (cl *Client) keepMetadata(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+) (map[string]*string, error) {
+	object, err := cl.getObjectResource(ctx, name, false)
+	if err != nil && !errors.Is(err, syscall.ENOENT) {
+		return nil, err
+	}
+	return keptMetadata(object, metadata), nil
+}
+
+// keptMetadata merges metadata into the metadata of object, which may be nil
+func keptMetadata(object *objectResource, metadata map[string]*string) map[string]*string {
+	existing := make(map[string]*string)
+	if object != nil {
+		for key, value := range object.Metadata {
+			if key != symlinkKey {
+				existing[key] = value
+			}
+		}
+	}
+	return internal.KeepMetadata(existing, metadata)
+}
+
+// generationMatch returns the generation an object must still have for an upload to replace it.
+// GCS only makes uploads conditional on the generation, so the ETag is checked against it first.
+func (cl *Client) generationMatch(ctx context.Context, name string, etag string) (*int64, error) {
+	if etag == "" {
+		return new(int64(0)), nil
+	}
+	object, err := cl.getObjectResource(ctx, name, false)
+	if errors.Is(err, syscall.ENOENT) {
+		return nil, common.NewConflictError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if object.ETag != etag {
+		log.Warn("Client::WriteFromFile : %s was changed, not uploading", name)
+		return nil, common.NewConflictError(
+			fmt.Errorf("ETag of %s is %s, not %s", name, object.ETag, etag),
+		)
+	}
+	return &object.Generation, nil
+}
+
+// WriteFromBuffer : Upload from a buffer to an object.
+// name is the file path.
+func (cl *Client) WriteFromBuffer(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+	data []byte,
+) error {
+	log.Trace("Client::WriteFromBuffer : name %s", name)
+
+	_, err := cl.putObject(
+		ctx,
+		putObjectOptions{
+			name:       name,
+			objectData: bytes.NewReader(data),
+			size:       int64(len(data)),
+			metadata:   metadata,
+		},
+	)
+	if err != nil {
+		log.Err("Client::WriteFromBuffer : putObject(%s) failed. Here's why: %v", name, err)
+	}
+	return err
+}
+
+// GetFileBlockOffsets : GCS objects can not be modified in place, so they have no blocks.
+func (cl *Client) GetFileBlockOffsets(
+	ctx context.Context,
+	name string,
+) (*common.BlockOffsetList, error) {
+	log.Trace("Client::GetFileBlockOffsets : name %s", name)
+	blockList := common.BlockOffsetList{}
+	_, err := cl.getObjectResource(ctx, name, false)
+	if err != nil {
+		log.Err("Client::GetFileBlockOffsets : Unable to get object %v", name)
+		return &blockList, err
+	}
+
+	blockList.Flags.Set(common.BlobFlagHasNoBlocks)
+	return &blockList, nil
+}
+
+// Write : write data at given offset to an object.
+// Data written at or past the end of the object is appended with a compose,
+// anything else rewrites the whole object.
+func (cl *Client) Write(ctx context.Context, options *internal.WriteFileOptions) error {
+	name := options.Handle.Path
+	offset := options.Offset
+	data := options.Data
+	defer log.TimeTrack(time.Now(), "Client::Write", name)
+	log.Trace("Client::Write : name %s offset %v", name, offset)
+
+	object, err := cl.getObjectResource(ctx, name, false)
+	if err != nil && err != syscall.ENOENT {
+		return err
+	}
+
+	metadata := options.Metadata
+	if object == nil {
+		object = &objectResource{}
+	} else if metadata == nil {
+		metadata = object.Metadata
+	}
+
+	if object.Size > 0 && offset >= object.Size {
+		// append only, there is no need to download the existing data
+		return cl.appendToObject(
+			ctx,
+			name,
+			newPaddedReaderAt(bytes.NewReader(data), offset-object.Size),
+			offset-object.Size+int64(len(data)),
+			metadata,
+		)
+	}
+
+	oldData := []byte{}
+	if object.Size > 0 {
+		oldData, err = cl.ReadBuffer(ctx, name, 0, 0)
+		if err != nil {
+			return err
+		}
+	}
+	newSize := max(int64(len(oldData)), offset+int64(len(data)))
+	if newSize > int64(len(oldData)) {
+		newData := make([]byte, newSize)
+		copy(newData, oldData)
+		oldData = newData
+	}
+	copy(oldData[offset:], data)
+
+	err = cl.WriteFromBuffer(ctx, name, metadata, oldData)
+	if err != nil {
+		log.Err("Client::Write : Failed to upload to object %s. Here's why: %v", name, err)
+	}
+	return err
+}
+
+// appendToObject uploads data to a temporary object and composes it at the end of the object
+func (cl *Client) appendToObject(
+	ctx context.Context,
+	name string,
+	data io.ReaderAt,
+	size int64,
+	metadata map[string]*string,
+) error {
+	key := cl.getKey(name, false)
+	tempKey := cl.getKey(path.Join(stagingDir, name, "append."+randomBoundary()), false)
+	_, err := cl.insertObject(ctx, tempKey, putObjectOptions{objectData: data, size: size})
+	if err != nil {
+		log.Err("Client::appendToObject : Failed to stage data for %s. Here's why: %v", name, err)
+		return err
+	}
+	defer func() {
+		_ = cl.deleteKey(context.WithoutCancel(ctx), tempKey)
+	}()
+
+	_, err = cl.composeObject(ctx, key, []string{key, tempKey}, metadata)
+	if err != nil {
+		log.Err("Client::appendToObject : Failed to compose %s. Here's why: %v", name, err)
+	}
+	return err
+}
+
+// Truncate object to size in bytes.
+// name is the file path.
+func (cl *Client) TruncateFile(ctx context.Context, name string, size int64) error {
+	log.Trace("Client::TruncateFile : Truncating %s to %dB.", name, size)
+
+	object, err := cl.getObjectResource(ctx, name, false)
+	if err != nil {
+		log.Err("Client::TruncateFile : Failed to get object %s. Here's why: %v", name, err)
+		return err
+	}
+
+	switch {
+	case size == object.Size:
+		return nil
+
+	case object.Size == 0 || size == 0:
+		// the new object only holds zeros
+		_, err = cl.putObject(ctx, putObjectOptions{
+			name:       name,
+			objectData: zeroReaderAt{},
+			size:       size,
+			metadata:   object.Metadata,
+		})
+
+	case size > object.Size:
+		// pad the object with zeros
+		err = cl.appendToObject(ctx, name, zeroReaderAt{}, size-object.Size, object.Metadata)
+
+	default:
+		// download the data we keep to a temporary file and upload it again
+		var tempFile *os.File
+		tempFile, err = os.CreateTemp("", "cloudfuse-gcs-truncate-*")
+		if err != nil {
+			log.Err("Client::TruncateFile : Failed to create temp file. Here's why: %v", err)
+			return err
+		}
+		defer func() {
+			_ = tempFile.Close()
+			_ = os.Remove(tempFile.Name())
+		}()
+
+		err = cl.ReadToFile(ctx, name, 0, size, tempFile)
+		if err != nil {
+			return err
+		}
+		_, err = cl.putObject(ctx, putObjectOptions{
+			name:       name,
+			objectData: tempFile,
+			size:       size,
+			metadata:   object.Metadata,
+		})
+	}
+
+	if err != nil {
+		log.Err("Client::TruncateFile : Failed to write truncated data to object %s", name)
+	}
+	return err
+}
+
+// GetCommittedBlockList : GCS objects have no blocks, so the object is split into blocks of
+// the configured block size. CommitBlocks can reuse these blocks as long as the object is unchanged.
+func (cl *Client) GetCommittedBlockList(
+	ctx context.Context,
+	name string,
+) (*internal.CommittedBlockList, error) {
+	log.Trace("Client::GetCommittedBlockList : name %s", name)
+
+	object, err := cl.getObjectResource(ctx, name, false)
+	if err != nil {
+		log.Err("Client::GetCommittedBlockList : Unable to get object %v", name)
+		return nil, err
+	}
+
+	blockList := make(internal.CommittedBlockList, 0)
+	committed := &committedBlocks{
+		blocks: make(map[string]internal.CommittedBlock),
+		size:   object.Size,
+	}
+	for offset := int64(0); offset < object.Size; offset += cl.Config.blockSize {
+		blk := internal.CommittedBlock{
+			Id:     common.GetBlockID(common.BlockIDLength),
+			Offset: offset,
+			Size:   uint64(min(cl.Config.blockSize, object.Size-offset)),
+		}
+		blockList = append(blockList, blk)
+		committed.blocks[blk.Id] = blk
+	}
+
+	cl.stagedBlocksMutex.Lock()
+	if cl.committedBlocks == nil {
+		cl.committedBlocks = make(map[string]*committedBlocks)
+	}
+	cl.committedBlocks[name] = committed
+	cl.stagedBlocksMutex.Unlock()
+
+	return &blockList, nil
+}
+
+// StageBlock : Upload the block as a temporary object, to be composed by CommitBlocks.
+func (cl *Client) StageBlock(ctx context.Context, name string, data []byte, id string) error {
+	log.Trace("Client::StageBlock : name %s, ID %s, length %d", name, id, len(data))
+
+	key := cl.getStagingKey(name, id)
+	_, err := cl.insertObject(
+		ctx,
+		key,
+		putObjectOptions{objectData: bytes.NewReader(data), size: int64(len(data))},
+	)
+	if err != nil {
+		log.Err("Client::StageBlock : Failed to stage block %s for %s. Here's why: %v", id, name, err)
+		return err
+	}
+
+	cl.stagedBlocksMutex.Lock()
+	defer cl.stagedBlocksMutex.Unlock()
+	if cl.stagedBlocks == nil {
+		cl.stagedBlocks = make(map[string]map[string]stagedBlock)
+	}
+	if _, ok := cl.stagedBlocks[name]; !ok {
+		cl.stagedBlocks[name] = make(map[string]stagedBlock)
+	}
+	cl.stagedBlocks[name][id] = stagedBlock{key: key, size: int64(len(data))}
+
+	return nil
+}
+
+// CommitBlocks : Compose the staged blocks into the object.
+// Blocks which were committed before and not staged again are read back from the current object.
+// The metadata of the current object is kept, under the given metadata.
+func (cl *Client) CommitBlocks(
+	ctx context.Context,
+	name string,
+	blockList []string,
+	newEtag *string,
+	metadata map[string]*string,
+) error {
+	log.Trace("Client::CommitBlocks : name %s, %d blocks", name, len(blockList))
+
+	// lock on the object name so that no stage and commit race condition occur causing failure
+	objectMtx := cl.blockLocks.GetLock(name)
+	objectMtx.Lock()
+	defer objectMtx.Unlock()
+
+	cl.stagedBlocksMutex.Lock()
+	staged := cl.stagedBlocks[name]
+	committed := cl.committedBlocks[name]
+	cl.stagedBlocksMutex.Unlock()
+
+	// keep the metadata of the object being replaced
+	object, err := cl.getObjectResource(ctx, name, false)
+	if err != nil && err != syscall.ENOENT {
+		return err
+	}
+	metadata = keptMetadata(object, metadata)
+
+	key := cl.getKey(name, false)
+	if len(blockList) == 0 {
+		_, err = cl.putObject(ctx, putObjectOptions{name: name, metadata: metadata})
+		if err == nil {
+			cl.cleanupStagedBlocks(ctx, name, nil)
+		}
+		return err
+	}
+
+	sources := make([]string, 0, len(blockList))
+	newCommitted := &committedBlocks{blocks: make(map[string]internal.CommittedBlock)}
+	restaged := make([]string, 0)
+	index := 0
+
+	// when the list starts with the whole current object, compose from the object itself
+	if committed != nil && object != nil && object.Size == committed.size {
+		var offset int64
+		for index < len(blockList) {
+			blk, ok := committed.blocks[blockList[index]]
+			if _, isStaged := staged[blockList[index]]; !ok || isStaged || blk.Offset != offset {
+				break
+			}
+			newCommitted.blocks[blk.Id] = blk
+			offset += int64(blk.Size)
+			index++
+		}
+		if offset == committed.size && offset > 0 {
+			sources = append(sources, key)
+			newCommitted.size = offset
+		} else {
+			index = 0
+			clear(newCommitted.blocks)
+		}
+	}
+
+	for _, id := range blockList[index:] {
+		blk, isStaged := staged[id]
+		if !isStaged {
+			oldBlk, isCommitted := committed.get(id)
+			if !isCommitted || object == nil {
+				err = fmt.Errorf("block ID %s not found for %s", id, name)
+				log.Err("Client::CommitBlocks : %v", err)
+				return err
+			}
+			// copy the committed data into a new temporary object
+			blk, err = cl.restageBlock(ctx, name, oldBlk)
+			if err != nil {
+				return err
+			}
+			restaged = append(restaged, blk.key)
+		}
+		sources = append(sources, blk.key)
+		newCommitted.blocks[id] = internal.CommittedBlock{
+			Id:     id,
+			Offset: newCommitted.size,
+			Size:   uint64(blk.size),
+		}
+		newCommitted.size += blk.size
+	}
+
+	result, err := cl.composeObject(ctx, key, sources, metadata)
+	for _, restagedKey := range restaged {
+		_ = cl.deleteKey(context.WithoutCancel(ctx), restagedKey)
+	}
+	if err != nil {
+		log.Err("Client::CommitBlocks : Failed to compose %s. Here's why: %v", name, err)
+		return err
+	}
+
+	if newEtag != nil {
+		*newEtag = result.ETag
+	}
+	cl.cleanupStagedBlocks(ctx, name, newCommitted)
+
+	return nil
+}
+
+// get looks up a block committed earlier
+func (c *committedBlocks) get(id string) (internal.CommittedBlock, bool) {
+	if c == nil {
+		return internal.CommittedBlock{}, false
+	}
+	blk, ok := c.blocks[id]
+	return blk, ok
+}
+
+// restageBlock copies a range of the current object into a temporary object
+func (cl *Client) restageBlock(
+	ctx context.Context,
+	name string,
+	blk internal.CommittedBlock,
+) (stagedBlock, error) {
+	data, err := cl.ReadBuffer(ctx, name, blk.Offset, int64(blk.Size))
+	if err != nil {
+		log.Err("Client::restageBlock : Failed to read block %s of %s. Here's why: %v", blk.Id, name, err)
+		return stagedBlock{}, err
+	}
+	key := cl.getKey(path.Join(stagingDir, name, "restage."+randomBoundary()), false)
+	_, err = cl.insertObject(
+		ctx,
+		key,
+		putObjectOptions{objectData: bytes.NewReader(data), size: int64(len(data))},
+	)
+	if err != nil {
+		log.Err("Client::restageBlock : Failed to stage block %s of %s. Here's why: %v", blk.Id, name, err)
+		return stagedBlock{}, err
+	}
+	return stagedBlock{key: key, size: int64(len(data))}, nil
+}
+
+// cleanupStagedBlocks deletes the staged objects of a file and records its committed blocks
+func (cl *Client) cleanupStagedBlocks(
+	ctx context.Context,
+	name string,
+	committed *committedBlocks,
+) {
+	cl.stagedBlocksMutex.Lock()
+	staged := cl.stagedBlocks[name]
+	delete(cl.stagedBlocks, name)
+	if committed != nil {
+		if cl.committedBlocks == nil {
+			cl.committedBlocks = make(map[string]*committedBlocks)
+		}
+		cl.committedBlocks[name] = committed
+	} else {
+		delete(cl.committedBlocks, name)
+	}
+	cl.stagedBlocksMutex.Unlock()
+
+	for _, blk := range staged {
+		err := cl.deleteKey(context.WithoutCancel(ctx), blk.key)
+		if err != nil {
+			log.Warn("Client::cleanupStagedBlocks : Failed to delete staged block %s", blk.key)
+		}
+	}
+}
+
+// getStagingKey returns the key of the temporary object holding a staged block
+func (cl *Client) getStagingKey(name string, id string) string {
+	return cl.getKey(
+		path.Join(stagingDir, name, base64.RawURLEncoding.EncodeToString([]byte(id))),
+		false,
+	)
+}
+
+// GetUsedSize : Sum the size of all objects in the bucket (or under the subdirectory)
+func (cl *Client) GetUsedSize(ctx context.Context) (uint64, error) {
+	prefix := ""
+	if cl.Config.prefixPath != "" {
+		prefix = internal.ExtendDirName(cl.Config.prefixPath)
+	}
+
+	var usedSize uint64
+	pageToken := ""
+	for {
+		output, err := cl.listObjects(ctx, listObjectsOptions{
+			prefix:    prefix,
+			pageToken: pageToken,
+			fields:    "items(size),nextPageToken",
+		})
+		if err != nil {
+			return 0, err
+		}
+		for _, item := range output.Items {
+			usedSize += uint64(item.Size)
+		}
+		if output.NextPageToken == "" {
+			break
+		}
+		pageToken = output.NextPageToken
+	}
+
+	return usedSize, nil
+}
@@ -0,0 +1,208 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+)
+
+var errInvalidConfigField = errors.New("config field is invalid")
+
+type Options struct {
+	BucketName             string  `config:"bucket-name"                   yaml:"bucket-name,omitempty"`
+	ProjectID              string  `config:"project-id"                    yaml:"project-id,omitempty"`
+	KeyFile                string  `config:"key-file"                      yaml:"key-file,omitempty"`
+	Endpoint               string  `config:"endpoint"                      yaml:"endpoint,omitempty"`
+	Anonymous              bool    `config:"anonymous"                     yaml:"anonymous,omitempty"`
+	PrefixPath             string  `config:"subdirectory"                  yaml:"subdirectory,omitempty"`
+	RestrictedCharsWin     bool    `config:"restricted-characters-windows" yaml:"-"`
+	MaxRetries             int     `config:"max-retries"                   yaml:"max-retries,omitempty"`
+	DisableUsage           bool    `config:"disable-usage"                 yaml:"disable-usage,omitempty"`
+	EnableDirMarker        bool    `config:"enable-dir-marker"             yaml:"enable-dir-marker,omitempty"`
+	HealthCheckIntervalSec int     `config:"health-check-interval-sec"     yaml:"health-check-interval-sec,omitempty"`
+	BlockSizeMb            float64 `config:"block-size-mb"                 yaml:"block-size-mb,omitempty"`
+}
+
+const (
+	defaultHealthCheckInterval = 2 * time.Second
+	maxHealthCheckInterval     = 30 * time.Second
+
+	// Size of the blocks reported by GetCommittedBlockList, matches block_cache
+	defaultBlockSize = 16 * common.MbToBytes
+
+	// Emulators such as fake-gcs-server advertise themselves through this variable
+	emulatorHostEnv = "STORAGE_EMULATOR_HOST"
+)
+
+// ParseAndValidateConfig : Parse and validate config
+func ParseAndValidateConfig(gcs *GcsStorage, opt Options) error {
+	log.Trace("ParseAndValidateConfig : Parsing config")
+
+	// Validate bucket name
+	if opt.BucketName == "" {
+		log.Warn("ParseAndValidateConfig : bucket name not provided")
+	}
+
+	// Set authentication config
+	gcs.stConfig.AuthConfig.BucketName = opt.BucketName
+	gcs.stConfig.AuthConfig.ProjectID = opt.ProjectID
+	gcs.stConfig.AuthConfig.KeyFile = common.ExpandPath(opt.KeyFile)
+	gcs.stConfig.AuthConfig.Endpoint = opt.Endpoint
+	gcs.stConfig.AuthConfig.Anonymous = opt.Anonymous
+
+	if gcs.stConfig.AuthConfig.Endpoint == "" {
+		if emulatorHost := os.Getenv(emulatorHostEnv); emulatorHost != "" {
+			// emulators do not check credentials
+			gcs.stConfig.AuthConfig.Endpoint = emulatorHost
+			gcs.stConfig.AuthConfig.Anonymous = true
+			log.Info("ParseAndValidateConfig : Using emulator endpoint %s", emulatorHost)
+		} else {
+			gcs.stConfig.AuthConfig.Endpoint = DefaultEndpoint
+		}
+	}
+	if !strings.Contains(gcs.stConfig.AuthConfig.Endpoint, "://") {
+		gcs.stConfig.AuthConfig.Endpoint = "http://" + gcs.stConfig.AuthConfig.Endpoint
+	}
+	gcs.stConfig.AuthConfig.Endpoint = strings.TrimSuffix(gcs.stConfig.AuthConfig.Endpoint, "/")
+
+	if gcs.stConfig.AuthConfig.KeyFile != "" {
+		if _, err := os.Stat(gcs.stConfig.AuthConfig.KeyFile); err != nil {
+			return fmt.Errorf("%w: key-file %s can not be read [%s]",
+				errInvalidConfigField, gcs.stConfig.AuthConfig.KeyFile, err.Error())
+		}
+		if gcs.stConfig.AuthConfig.Anonymous {
+			return fmt.Errorf("%w: key-file and anonymous can not be used together",
+				errInvalidConfigField)
+		}
+	}
+
+	gcs.stConfig.restrictedCharsWin = opt.RestrictedCharsWin
+	gcs.stConfig.disableUsage = opt.DisableUsage
+	gcs.stConfig.enableDirMarker = opt.EnableDirMarker
+
+	// If subdirectory is mounted, take the prefix path
+	gcs.stConfig.prefixPath = removeLeadingSlashes(opt.PrefixPath)
+
+	gcs.stConfig.maxRetries = DefaultMaxRetries
+	if config.IsSet(compName + ".max-retries") {
+		if opt.MaxRetries < 0 {
+			return fmt.Errorf("%w: max-retries can not be negative", errInvalidConfigField)
+		}
+		gcs.stConfig.maxRetries = opt.MaxRetries
+	}
+
+	// Borrow block-size-mb from block cache when it is not set, so committed block lists line up
+	blockSizeMb := opt.BlockSizeMb
+	if !config.IsSet(compName+".block-size-mb") && config.IsSet("block_cache.block-size-mb") {
+		err := config.UnmarshalKey("block_cache.block-size-mb", &blockSizeMb)
+		if err != nil {
+			log.Err("ParseAndValidateConfig : Failed to unmarshal block_cache.block-size-mb")
+		}
+	}
+	if blockSizeMb < 0 {
+		return fmt.Errorf("%w: block-size-mb can not be negative", errInvalidConfigField)
+	}
+	gcs.stConfig.blockSize = defaultBlockSize
+	if blockSizeMb > 0 {
+		gcs.stConfig.blockSize = int64(blockSizeMb * float64(common.MbToBytes))
+	}
+
+	// by default symlink will be disabled
+	enableSymlinks := false
+	// Borrow enable-symlinks flag from attribute cache
+	if config.IsSet("attr_cache.enable-symlinks") {
+		err := config.UnmarshalKey("attr_cache.enable-symlinks", &enableSymlinks)
+		if err != nil {
+			enableSymlinks = false
+			log.Err("ParseAndValidateConfig : Failed to unmarshal attr_cache.enable-symlinks")
+		}
+	}
+	gcs.stConfig.disableSymlink = !enableSymlinks
+
+	gcs.stConfig.healthCheckInterval = defaultHealthCheckInterval
+	if config.IsSet(compName + ".health-check-interval-sec") {
+		specifiedInterval := time.Duration(opt.HealthCheckIntervalSec) * time.Second
+		switch {
+		case specifiedInterval < 1*time.Second:
+			log.Warn(
+				"GcsStorage : health-check-interval-sec=%d... using 1s instead",
+				opt.HealthCheckIntervalSec,
+			)
+			gcs.stConfig.healthCheckInterval = 1 * time.Second
+		case specifiedInterval > maxHealthCheckInterval:
+			log.Warn(
+				"GcsStorage : health-check-interval-sec=%d... using %.0fs instead",
+				opt.HealthCheckIntervalSec,
+				maxHealthCheckInterval.Seconds(),
+			)
+			gcs.stConfig.healthCheckInterval = maxHealthCheckInterval
+		default:
+			gcs.stConfig.healthCheckInterval = specifiedInterval
+		}
+	}
+
+	log.Crit(
+		"ParseAndValidateConfig : bucket %s, project %s, endpoint %s, subdirectory %s, anonymous %t",
+		gcs.stConfig.AuthConfig.BucketName,
+		gcs.stConfig.AuthConfig.ProjectID,
+		gcs.stConfig.AuthConfig.Endpoint,
+		gcs.stConfig.prefixPath,
+		gcs.stConfig.AuthConfig.Anonymous,
+	)
+
+	return nil
+}
+
+// ParseAndReadDynamicConfig : On config change read only the required config
+func ParseAndReadDynamicConfig(gcs *GcsStorage, opt Options, reload bool) error {
+	log.Trace("ParseAndReadDynamicConfig : Reparsing config")
+
+	if config.IsSet(compName+".max-retries") && opt.MaxRetries >= 0 {
+		gcs.stConfig.maxRetries = opt.MaxRetries
+	}
+
+	// by default symlink will be disabled
+	enableSymlinks := false
+	// Borrow enable-symlinks flag from attribute cache
+	if config.IsSet("attr_cache.enable-symlinks") {
+		err := config.UnmarshalKey("attr_cache.enable-symlinks", &enableSymlinks)
+		if err != nil {
+			enableSymlinks = false
+			log.Err("ParseAndReadDynamicConfig : Failed to unmarshal attr_cache.enable-symlinks")
+		}
+	}
+	gcs.stConfig.disableSymlink = !enableSymlinks
+
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type configTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+	gcs    *GcsStorage
+	opt    Options
+}
+
+func (s *configTestSuite) SetupTest() {
+	// Silent logger
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic(fmt.Sprintf("Unable to set silent logger as default: %v", err))
+	}
+
+	s.assert = assert.New(s.T())
+	s.gcs = &GcsStorage{}
+	s.opt = Options{
+		BucketName: "testBucketName",
+		ProjectID:  "testProject",
+		Endpoint:   "https://storage.example.com/",
+		PrefixPath: "/testPrefixPath",
+	}
+	config.ResetConfig()
+}
+
+func (s *configTestSuite) readConfig(configuration string) {
+	err := config.ReadConfigFromReader(strings.NewReader(configuration))
+	s.Require().NoError(err)
+}
+
+func (s *configTestSuite) TestConfigParse() {
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal("testBucketName", s.gcs.stConfig.AuthConfig.BucketName)
+	s.assert.Equal("testProject", s.gcs.stConfig.AuthConfig.ProjectID)
+	s.assert.Equal("https://storage.example.com", s.gcs.stConfig.AuthConfig.Endpoint)
+	s.assert.Equal("testPrefixPath", s.gcs.stConfig.prefixPath)
+	s.assert.Equal(DefaultMaxRetries, s.gcs.stConfig.maxRetries)
+	s.assert.EqualValues(defaultBlockSize, s.gcs.stConfig.blockSize)
+	s.assert.Equal(defaultHealthCheckInterval, s.gcs.stConfig.healthCheckInterval)
+	s.assert.True(s.gcs.stConfig.disableSymlink)
+	s.assert.False(s.gcs.stConfig.AuthConfig.Anonymous)
+}
+
+func (s *configTestSuite) TestDefaultEndpoint() {
+	s.T().Setenv(emulatorHostEnv, "")
+	s.opt.Endpoint = ""
+
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal(DefaultEndpoint, s.gcs.stConfig.AuthConfig.Endpoint)
+}
+
+func (s *configTestSuite) TestEmulatorEndpoint() {
+	s.T().Setenv(emulatorHostEnv, "localhost:4443")
+	s.opt.Endpoint = ""
+
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal("http://localhost:4443", s.gcs.stConfig.AuthConfig.Endpoint)
+	s.assert.True(s.gcs.stConfig.AuthConfig.Anonymous)
+}
+
+func (s *configTestSuite) TestKeyFile() {
+	s.opt.KeyFile = filepath.Join(s.T().TempDir(), "missing.json")
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	s.opt.KeyFile = filepath.Join(s.T().TempDir(), "key.json")
+	s.Require().NoError(os.WriteFile(s.opt.KeyFile, []byte("{}"), 0600))
+	err = ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal(s.opt.KeyFile, s.gcs.stConfig.AuthConfig.KeyFile)
+
+	s.opt.Anonymous = true
+	err = ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestMaxRetries() {
+	s.readConfig("gcsstorage:\n  max-retries: -1\n")
+	s.opt.MaxRetries = -1
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	s.readConfig("gcsstorage:\n  max-retries: 0\n")
+	s.opt.MaxRetries = 0
+	err = ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal(0, s.gcs.stConfig.maxRetries)
+}
+
+func (s *configTestSuite) TestBlockSize() {
+	s.readConfig("block_cache:\n  block-size-mb: 8\n")
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.EqualValues(8*common.MbToBytes, s.gcs.stConfig.blockSize)
+
+	s.readConfig("gcsstorage:\n  block-size-mb: 0.5\nblock_cache:\n  block-size-mb: 8\n")
+	s.opt.BlockSizeMb = 0.5
+	err = ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.EqualValues(common.MbToBytes/2, s.gcs.stConfig.blockSize)
+
+	s.opt.BlockSizeMb = -1
+	err = ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestHealthCheckInterval() {
+	s.readConfig("gcsstorage:\n  health-check-interval-sec: 0\n")
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal(1*time.Second, s.gcs.stConfig.healthCheckInterval)
+
+	s.opt.HealthCheckIntervalSec = 600
+	err = ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal(maxHealthCheckInterval, s.gcs.stConfig.healthCheckInterval)
+
+	s.opt.HealthCheckIntervalSec = 5
+	err = ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.Equal(5*time.Second, s.gcs.stConfig.healthCheckInterval)
+}
+
+func (s *configTestSuite) TestEnableSymlinks() {
+	s.readConfig("attr_cache:\n  enable-symlinks: true\n")
+	err := ParseAndValidateConfig(s.gcs, s.opt)
+	s.assert.NoError(err)
+	s.assert.False(s.gcs.stConfig.disableSymlink)
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(configTestSuite))
+}
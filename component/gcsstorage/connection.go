@@ -0,0 +1,138 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/internal"
+)
+
+type Connection struct {
+	Config   Config
+	Endpoint *url.URL
+}
+
+type Config struct {
+	AuthConfig          gcsAuthConfig
+	prefixPath          string
+	restrictedCharsWin  bool
+	disableSymlink      bool
+	disableUsage        bool
+	enableDirMarker     bool
+	maxRetries          int
+	blockSize           int64
+	healthCheckInterval time.Duration
+}
+
+// gcsAuthConfig : Config to authenticate to storage
+type gcsAuthConfig struct {
+	BucketName string
+	ProjectID  string
+	KeyFile    string
+	Endpoint   string
+	Anonymous  bool
+}
+
+// NewConnection : Create GcsConnection Object
+func NewConnection(cfg Config) (GcsConnection, error) {
+	stg := &Client{}
+	err := stg.Configure(cfg)
+	return stg, err
+}
+
+type GcsConnection interface {
+	Configure(cfg Config) error
+	UpdateConfig(cfg Config) error
+
+	ConnectionOkay(ctx context.Context) error
+	ListBuckets(ctx context.Context) ([]string, error)
+
+	// This is just for test, shall not be used otherwise
+	SetPrefixPath(string) error
+
+	CreateFile(ctx context.Context, name string, mode os.FileMode) error
+	CreateDirectory(ctx context.Context, name string) error
+	CreateLink(ctx context.Context, source string, target string) error
+
+	DeleteFile(ctx context.Context, name string) error
+	DeleteDirectory(ctx context.Context, name string) error
+
+	RenameFile(ctx context.Context, source string, target string) error
+	RenameDirectory(ctx context.Context, source string, target string) error
+
+	GetAttr(ctx context.Context, name string) (attr *internal.ObjAttr, err error)
+	GetMetadata(ctx context.Context, name string) (map[string]*string, error)
+	SetMetadata(ctx context.Context, name string, metadata map[string]*string) error
+	Utimens(ctx context.Context, name string, atime time.Time, mtime time.Time) error
+
+	// Standard operations to be supported by any account type
+	List(
+		ctx context.Context,
+		prefix string,
+		marker *string,
+		count int32,
+	) ([]*internal.ObjAttr, *string, error)
+
+	ReadToFile(ctx context.Context, name string, offset int64, count int64, fi *os.File) error
+	ReadBuffer(ctx context.Context, name string, offset int64, length int64) ([]byte, error)
+	ReadInBuffer(ctx context.Context, name string, offset int64, length int64, data []byte) error
+
+	WriteFromFile(
+		ctx context.Context,
+		name string,
+		metadata map[string]*string,
+		fi *os.File,
+		ifMatch *string,
+		newETag *string,
+	) error
+	WriteFromBuffer(
+		ctx context.Context,
+		name string,
+		metadata map[string]*string,
+		data []byte,
+	) error
+	Write(ctx context.Context, options *internal.WriteFileOptions) error
+	GetFileBlockOffsets(ctx context.Context, name string) (*common.BlockOffsetList, error)
+
+	TruncateFile(ctx context.Context, name string, size int64) error
+
+	GetCommittedBlockList(ctx context.Context, name string) (*internal.CommittedBlockList, error)
+	StageBlock(ctx context.Context, name string, data []byte, id string) error
+	CommitBlocks(
+		ctx context.Context,
+		name string,
+		blockList []string,
+		newEtag *string,
+		metadata map[string]*string,
+	) error
+
+	GetUsedSize(ctx context.Context) (uint64, error)
+}
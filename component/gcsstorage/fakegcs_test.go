@@ -0,0 +1,502 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	json "encoding/json/v2"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fakeObject is an object stored by the fake GCS server
+type fakeObject struct {
+	data           []byte
+	contentType    string
+	metadata       map[string]*string
+	generation     int64
+	componentCount int
+	created        time.Time
+	updated        time.Time
+}
+
+// fakeGcsServer is a minimal in-process implementation of the GCS JSON API.
+// It implements the calls used by the client: buckets list/get,
+// objects list/get/insert/patch/delete, rewrite and compose.
+type fakeGcsServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	project    string
+	buckets    map[string]map[string]*fakeObject
+	generation int64
+	// number of requests to fail with 503 before serving normally
+	failNext int
+	// requests served, by method and API call
+	requests map[string]int
+}
+
+func newFakeGcsServer(project string, buckets ...string) *fakeGcsServer {
+	f := &fakeGcsServer{
+		project:  project,
+		buckets:  make(map[string]map[string]*fakeObject),
+		requests: make(map[string]int),
+	}
+	for _, bucket := range buckets {
+		f.buckets[bucket] = make(map[string]*fakeObject)
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	return f
+}
+
+// putObject stores an object directly, bypassing the API
+func (f *fakeGcsServer) putObject(bucket string, key string, data []byte, metadata map[string]*string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.storeObject(bucket, key, &fakeObject{data: data, metadata: metadata, componentCount: 1})
+}
+
+// getObject returns the object stored under key, or nil
+func (f *fakeGcsServer) getObject(bucket string, key string) *fakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.buckets[bucket][key]
+}
+
+// keys returns the sorted keys in a bucket
+func (f *fakeGcsServer) keys(bucket string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Sorted(maps.Keys(f.buckets[bucket]))
+}
+
+func (f *fakeGcsServer) requestCount(call string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[call]
+}
+
+func (f *fakeGcsServer) storeObject(bucket string, key string, obj *fakeObject) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if existing, ok := f.buckets[bucket][key]; ok {
+		obj.created = existing.created
+	} else {
+		obj.created = now
+	}
+	f.generation++
+	obj.generation = f.generation
+	obj.updated = now
+	if obj.contentType == "" {
+		obj.contentType = "application/octet-stream"
+	}
+	f.buckets[bucket][key] = obj
+}
+
+func (f *fakeGcsServer) resource(bucket string, key string, obj *fakeObject) *objectResource {
+	md5sum := md5.Sum(obj.data)
+	return &objectResource{
+		Name:           key,
+		Bucket:         bucket,
+		Generation:     obj.generation,
+		ContentType:    obj.contentType,
+		Size:           int64(len(obj.data)),
+		MD5Hash:        base64.StdEncoding.EncodeToString(md5sum[:]),
+		ETag:           strconv.FormatInt(obj.generation, 36),
+		Updated:        obj.updated,
+		TimeCreated:    obj.created,
+		ComponentCount: obj.componentCount,
+		Metadata:       obj.metadata,
+	}
+}
+
+func writeFakeError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintf(w, `{"error":{"code":%d,"message":%q,"errors":[{"reason":"fake","message":%q}]}}`,
+		code, message, message)
+}
+
+func writeFakeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.MarshalWrite(w, v)
+}
+
+// pathSegments splits the escaped request path, so that object names may contain slashes
+func pathSegments(r *http.Request) []string {
+	segments := strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/")
+	for i, segment := range segments {
+		if unescaped, err := url.PathUnescape(segment); err == nil {
+			segments[i] = unescaped
+		}
+	}
+	return segments
+}
+
+func (f *fakeGcsServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failNext > 0 {
+		f.failNext--
+		writeFakeError(w, http.StatusServiceUnavailable, "try again")
+		return
+	}
+
+	segments := pathSegments(r)
+	upload := len(segments) > 0 && segments[0] == "upload"
+	if upload {
+		segments = segments[1:]
+	}
+	if len(segments) < 3 || segments[0] != "storage" || segments[1] != "v1" || segments[2] != "b" {
+		writeFakeError(w, http.StatusNotFound, "unknown path")
+		return
+	}
+	segments = segments[3:]
+
+	switch {
+	case len(segments) == 0:
+		f.requests["buckets.list"]++
+		f.listBuckets(w, r)
+		return
+	case len(segments) == 1:
+		f.requests["buckets.get"]++
+		if _, ok := f.buckets[segments[0]]; !ok {
+			writeFakeError(w, http.StatusNotFound, "bucket not found")
+			return
+		}
+		writeFakeJSON(w, &bucketResource{Name: segments[0]})
+		return
+	}
+
+	bucket := segments[0]
+	objects, ok := f.buckets[bucket]
+	if !ok || segments[1] != "o" {
+		writeFakeError(w, http.StatusNotFound, "bucket not found")
+		return
+	}
+
+	switch {
+	case len(segments) == 2 && upload && r.Method == http.MethodPost:
+		f.requests["objects.insert"]++
+		f.insertObject(w, r, bucket)
+	case len(segments) == 2 && r.Method == http.MethodGet:
+		f.requests["objects.list"]++
+		f.listObjects(w, r, bucket, objects)
+	case len(segments) == 3 && r.Method == http.MethodGet:
+		obj, ok := objects[segments[2]]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "No such object: "+segments[2])
+			return
+		}
+		if r.URL.Query().Get("alt") == "media" {
+			f.requests["objects.download"]++
+			serveMedia(w, r, obj)
+		} else {
+			f.requests["objects.get"]++
+			writeFakeJSON(w, f.resource(bucket, segments[2], obj))
+		}
+	case len(segments) == 3 && r.Method == http.MethodPatch:
+		f.requests["objects.patch"]++
+		f.patchObject(w, r, bucket, segments[2])
+	case len(segments) == 3 && r.Method == http.MethodDelete:
+		f.requests["objects.delete"]++
+		if _, ok := objects[segments[2]]; !ok {
+			writeFakeError(w, http.StatusNotFound, "No such object: "+segments[2])
+			return
+		}
+		delete(objects, segments[2])
+		w.WriteHeader(http.StatusNoContent)
+	case len(segments) == 4 && segments[3] == "compose" && r.Method == http.MethodPost:
+		f.requests["objects.compose"]++
+		f.composeObject(w, r, bucket, segments[2])
+	case len(segments) == 8 && segments[3] == "rewriteTo" && r.Method == http.MethodPost:
+		f.requests["objects.rewrite"]++
+		f.rewriteObject(w, bucket, segments[2], segments[5], segments[7])
+	default:
+		writeFakeError(w, http.StatusBadRequest, "unsupported request")
+	}
+}
+
+func (f *fakeGcsServer) listBuckets(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("project") != f.project {
+		writeFakeError(w, http.StatusForbidden, "unknown project")
+		return
+	}
+	result := listBucketsResponse{}
+	for _, name := range slices.Sorted(maps.Keys(f.buckets)) {
+		result.Items = append(result.Items, bucketResource{Name: name})
+	}
+	writeFakeJSON(w, &result)
+}
+
+func (f *fakeGcsServer) listObjects(
+	w http.ResponseWriter,
+	r *http.Request,
+	bucket string,
+	objects map[string]*fakeObject,
+) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	maxResults := maxResultsPerListCall
+	if value := query.Get("maxResults"); value != "" {
+		maxResults, _ = strconv.Atoi(value)
+	}
+
+	// collect items and prefixes in a single ordered list, as GCS pages through both
+	type entry struct {
+		name     string
+		isPrefix bool
+	}
+	seen := make(map[string]bool)
+	entries := make([]entry, 0)
+	for _, key := range slices.Sorted(maps.Keys(objects)) {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if delimiter != "" {
+			if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+				dir := key[:len(prefix)+i+len(delimiter)]
+				if !seen[dir] {
+					seen[dir] = true
+					entries = append(entries, entry{name: dir, isPrefix: true})
+				}
+				continue
+			}
+		}
+		entries = append(entries, entry{name: key})
+	}
+
+	start := 0
+	if token := query.Get("pageToken"); token != "" {
+		start = slices.IndexFunc(entries, func(e entry) bool { return e.name > token })
+		if start < 0 {
+			start = len(entries)
+		}
+	}
+	end := min(len(entries), start+maxResults)
+
+	result := listObjectsResponse{}
+	for _, e := range entries[start:end] {
+		if e.isPrefix {
+			result.Prefixes = append(result.Prefixes, e.name)
+		} else {
+			result.Items = append(result.Items, f.resource(bucket, e.name, objects[e.name]))
+		}
+	}
+	if end < len(entries) {
+		result.NextPageToken = entries[end-1].name
+	}
+	writeFakeJSON(w, &result)
+}
+
+func serveMedia(w http.ResponseWriter, r *http.Request, obj *fakeObject) {
+	data := obj.data
+	if rangeHeader := r.Header.Get("Range"); rangeHeader != "" {
+		var start, end int64
+		spec := strings.TrimPrefix(rangeHeader, "bytes=")
+		startStr, endStr, _ := strings.Cut(spec, "-")
+		start, _ = strconv.ParseInt(startStr, 10, 64)
+		end = int64(len(data)) - 1
+		if endStr != "" {
+			end, _ = strconv.ParseInt(endStr, 10, 64)
+		}
+		if start >= int64(len(data)) && len(data) > 0 {
+			writeFakeError(w, http.StatusRequestedRangeNotSatisfiable, "invalid range")
+			return
+		}
+		end = min(end, int64(len(data))-1)
+		if start <= end {
+			data = data[start : end+1]
+		} else {
+			data = nil
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		w.WriteHeader(http.StatusPartialContent)
+	} else {
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	}
+	_, _ = w.Write(data)
+}
+
+func (f *fakeGcsServer) insertObject(w http.ResponseWriter, r *http.Request, bucket string) {
+	if r.URL.Query().Get("uploadType") != "multipart" {
+		writeFakeError(w, http.StatusBadRequest, "only multipart uploads are supported")
+		return
+	}
+	mediaType, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/related" {
+		writeFakeError(w, http.StatusBadRequest, "bad content type")
+		return
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+
+	part, err := reader.NextPart()
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, "missing metadata part")
+		return
+	}
+	resource := objectResource{}
+	if err = json.UnmarshalRead(part, &resource); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "bad metadata: "+err.Error())
+		return
+	}
+	part, err = reader.NextPart()
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, "missing media part")
+		return
+	}
+	data, err := io.ReadAll(part)
+	if err != nil {
+		writeFakeError(w, http.StatusBadRequest, "bad media part")
+		return
+	}
+
+	if value := r.URL.Query().Get("ifGenerationMatch"); value != "" {
+		var generation int64
+		if existing, ok := f.buckets[bucket][resource.Name]; ok {
+			generation = existing.generation
+		}
+		if value != strconv.FormatInt(generation, 10) {
+			writeFakeError(w, http.StatusPreconditionFailed, "generation does not match")
+			return
+		}
+	}
+
+	obj := &fakeObject{
+		data:           data,
+		contentType:    resource.ContentType,
+		metadata:       resource.Metadata,
+		componentCount: 1,
+	}
+	f.storeObject(bucket, resource.Name, obj)
+	writeFakeJSON(w, f.resource(bucket, resource.Name, obj))
+}
+
+// patchObject merges the metadata in the request into the object, null values remove keys
+func (f *fakeGcsServer) patchObject(
+	w http.ResponseWriter,
+	r *http.Request,
+	bucket string,
+	key string,
+) {
+	obj, ok := f.buckets[bucket][key]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "No such object: "+key)
+		return
+	}
+	request := objectResource{}
+	if err := json.UnmarshalRead(r.Body, &request); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "bad patch request")
+		return
+	}
+	if obj.metadata == nil {
+		obj.metadata = make(map[string]*string)
+	}
+	for k, v := range request.Metadata {
+		if v == nil {
+			delete(obj.metadata, k)
+		} else {
+			obj.metadata[k] = v
+		}
+	}
+	writeFakeJSON(w, f.resource(bucket, key, obj))
+}
+
+func (f *fakeGcsServer) composeObject(
+	w http.ResponseWriter,
+	r *http.Request,
+	bucket string,
+	key string,
+) {
+	request := composeRequest{}
+	if err := json.UnmarshalRead(r.Body, &request); err != nil {
+		writeFakeError(w, http.StatusBadRequest, "bad compose request")
+		return
+	}
+	if len(request.SourceObjects) == 0 || len(request.SourceObjects) > maxComposeSources {
+		writeFakeError(w, http.StatusBadRequest, "invalid number of source objects")
+		return
+	}
+
+	var data bytes.Buffer
+	componentCount := 0
+	for _, source := range request.SourceObjects {
+		obj, ok := f.buckets[bucket][source.Name]
+		if !ok {
+			writeFakeError(w, http.StatusNotFound, "No such object: "+source.Name)
+			return
+		}
+		data.Write(obj.data)
+		componentCount += obj.componentCount
+	}
+
+	obj := &fakeObject{data: data.Bytes(), componentCount: componentCount}
+	if request.Destination != nil {
+		obj.contentType = request.Destination.ContentType
+		obj.metadata = request.Destination.Metadata
+	}
+	f.storeObject(bucket, key, obj)
+	writeFakeJSON(w, f.resource(bucket, key, obj))
+}
+
+func (f *fakeGcsServer) rewriteObject(
+	w http.ResponseWriter,
+	bucket string,
+	key string,
+	dstBucket string,
+	dstKey string,
+) {
+	source, ok := f.buckets[bucket][key]
+	if !ok {
+		writeFakeError(w, http.StatusNotFound, "No such object: "+key)
+		return
+	}
+	if _, ok := f.buckets[dstBucket]; !ok {
+		writeFakeError(w, http.StatusNotFound, "bucket not found")
+		return
+	}
+	obj := &fakeObject{
+		data:           slices.Clone(source.data),
+		contentType:    source.contentType,
+		metadata:       maps.Clone(source.metadata),
+		componentCount: source.componentCount,
+	}
+	f.storeObject(dstBucket, dstKey, obj)
+	writeFakeJSON(w, &rewriteResponse{Done: true, Resource: f.resource(dstBucket, dstKey, obj)})
+}
@@ -0,0 +1,824 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/handlemap"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
+)
+
+// GcsStorage Wrapper type around the Google Cloud Storage JSON API
+type GcsStorage struct {
+	internal.BaseComponent
+	Storage  GcsConnection
+	stConfig Config
+	state    connectionState
+	ctx      context.Context
+	cancelFn context.CancelFunc
+}
+
+type connectionState struct {
+	sync.Mutex
+	lastConnectionAttempt *time.Time
+	firstOffline          *time.Time
+	retryTicker           *time.Ticker
+}
+
+const compName = "gcsstorage"
+
+// Verification to check satisfaction criteria with Component Interface
+var _ internal.Component = &GcsStorage{}
+
+var gcsStatsCollector *stats_manager.StatsCollector
+
+func (gcs *GcsStorage) Name() string {
+	return gcs.BaseComponent.Name()
+}
+
+func (gcs *GcsStorage) SetName(name string) {
+	gcs.BaseComponent.SetName(name)
+}
+
+func (gcs *GcsStorage) SetNextComponent(c internal.Component) {
+	gcs.BaseComponent.SetNextComponent(c)
+}
+
+// GenConfig : Generate the default config for the component
+func (gcs *GcsStorage) GenConfig() string {
+	log.Info("GcsStorage::Configure : config generation started")
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "\n%s:", gcs.Name())
+
+	var bucketName, projectID, keyFile string
+	_ = config.UnmarshalKey(compName+".bucket-name", &bucketName)
+	_ = config.UnmarshalKey(compName+".project-id", &projectID)
+	_ = config.UnmarshalKey(compName+".key-file", &keyFile)
+
+	fmt.Fprintf(&sb, "\n  bucket-name: %v", bucketName)
+	if projectID != "" {
+		fmt.Fprintf(&sb, "\n  project-id: %v", projectID)
+	}
+	if keyFile != "" {
+		fmt.Fprintf(&sb, "\n  key-file: %v", common.ExpandPath(keyFile))
+	}
+	fmt.Fprintf(&sb, "\n  endpoint: %v", DefaultEndpoint)
+	fmt.Fprintf(&sb, "\n  max-retries: %v", DefaultMaxRetries)
+	fmt.Fprintf(&sb, "\n  health-check-interval-sec: %v", int(defaultHealthCheckInterval.Seconds()))
+
+	return sb.String()
+}
+
+// Configure : Pipeline will call this method after constructor so that you can read config and initialize yourself
+func (gcs *GcsStorage) Configure(isParent bool) error {
+	log.Trace("GcsStorage::Configure : %s", gcs.Name())
+
+	conf := Options{}
+	err := config.UnmarshalKey(gcs.Name(), &conf)
+	if err != nil {
+		log.Err("GcsStorage::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("config error in %s [%s]", gcs.Name(), err.Error())
+	}
+
+	err = config.UnmarshalKey("restricted-characters-windows", &conf.RestrictedCharsWin)
+	if err != nil {
+		log.Err(
+			"GcsStorage::Configure : config error [unable to obtain restricted-characters-windows]",
+		)
+		return err
+	}
+
+	err = ParseAndValidateConfig(gcs, conf)
+	if err != nil {
+		log.Err("GcsStorage::Configure : Config validation failed [%s]", err.Error())
+		return fmt.Errorf("config error in %s [%s]", gcs.Name(), err.Error())
+	}
+
+	err = gcs.configureAndTest(isParent)
+	if err != nil {
+		log.Err("GcsStorage::Configure : Failed to validate bucket [%s]", err.Error())
+		return err
+	}
+	// first connection attempt is now
+	currentTime := time.Now()
+	gcs.state.lastConnectionAttempt = &currentTime
+
+	return nil
+}
+
+func (gcs *GcsStorage) Priority() internal.ComponentPriority {
+	return internal.EComponentPriority.Consumer()
+}
+
+// OnConfigChange : When config file is changed, this will be called by pipeline. Refresh required config here
+func (gcs *GcsStorage) OnConfigChange() {
+	log.Trace("GcsStorage::OnConfigChange : %s", gcs.Name())
+
+	conf := Options{}
+	err := config.UnmarshalKey(gcs.Name(), &conf)
+	if err != nil {
+		log.Err("GcsStorage::OnConfigChange : Config error [invalid config attributes]")
+		return
+	}
+
+	err = ParseAndReadDynamicConfig(gcs, conf, true)
+	if err != nil {
+		log.Err("GcsStorage::OnConfigChange : failed to reparse config [%s]", err.Error())
+		return
+	}
+
+	err = gcs.Storage.UpdateConfig(gcs.stConfig)
+	if err != nil {
+		log.Err("GcsStorage::OnConfigChange : failed to UpdateConfig [%s]", err.Error())
+		return
+	}
+}
+
+func (gcs *GcsStorage) configureAndTest(isParent bool) error {
+	var err error
+	gcs.Storage, err = NewConnection(gcs.stConfig)
+	if client, ok := gcs.Storage.(*Client); ok {
+		// the client may have defaulted the bucket and project
+		gcs.stConfig.AuthConfig = client.Config.AuthConfig
+	}
+	return err
+}
+
+// Start : Initialize the stats collector and the shared context used by all requests
+func (gcs *GcsStorage) Start(ctx context.Context) error {
+	log.Trace("GcsStorage::Start : Starting component %s", gcs.Name())
+	// create stats collector for gcsstorage
+	gcsStatsCollector = stats_manager.NewStatsCollector(gcs.Name())
+	log.Debug("Starting gcs stats collector")
+	gcsStatsCollector.AddStats(
+		stats_manager.Counter,
+		bytesDownloaded,
+		bytesUploaded,
+		createDir,
+		deleteDir,
+		streamDir,
+		renameDir,
+		deleteFile,
+		renameFile,
+		truncateFile,
+		createLink,
+		readLink,
+		setMetadata,
+		utimens,
+		chmod,
+	)
+	gcsStatsCollector.AddStats(stats_manager.Gauge, openHandles)
+	gcsStatsCollector.UpdateStats(stats_manager.Replace, openHandles, (int64)(0))
+	gcsStatsCollector.AddGauge(cloudConnected, func() float64 {
+		// report the state without checking the connection
+		gcs.state.Lock()
+		defer gcs.state.Unlock()
+		if gcs.state.firstOffline != nil {
+			return 0
+		}
+		return 1
+	})
+	// create a shared context for all cloud operations, with ability to cancel
+	gcs.ctx, gcs.cancelFn = context.WithCancel(ctx)
+	// create the retry ticker
+	gcs.state.retryTicker = time.NewTicker(gcs.stConfig.healthCheckInterval)
+	gcs.state.retryTicker.Stop() // stop it for now, we will start it when we are offline
+	go func() {
+		for range gcs.state.retryTicker.C {
+			gcs.CloudConnected()
+		}
+	}()
+
+	return nil
+}
+
+// Stop : Disconnect all running operations here
+func (gcs *GcsStorage) Stop() error {
+	log.Trace("GcsStorage::Stop : Stopping component %s", gcs.Name())
+	gcsStatsCollector.Destroy()
+	return nil
+}
+
+// Online check
+func (gcs *GcsStorage) CloudConnected() bool {
+	connected := gcs.state.firstOffline == nil
+	// don't check the connection when it's up, or if we are not ready to retry
+	if connected || !gcs.timeToRetry() {
+		return connected
+	}
+	// check connection
+	log.Info("GcsStorage::CloudConnected : Checking connection...")
+	ctx, cancelFun := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancelFun()
+	err := gcs.Storage.ConnectionOkay(ctx)
+	nowConnected := gcs.updateConnectionState(err)
+	return nowConnected
+}
+
+func (gcs *GcsStorage) timeToRetry() bool {
+	timeSinceLastAttempt := time.Since(*gcs.state.lastConnectionAttempt)
+	switch {
+	case timeSinceLastAttempt < gcs.stConfig.healthCheckInterval:
+		// minimum delay before retrying
+		return false
+	case timeSinceLastAttempt > maxHealthCheckInterval:
+		// maximum delay
+		return true
+	default:
+		// when between the minimum and maximum delay, we use an exponential backoff
+		timeOfflineAtLastAttempt := gcs.state.lastConnectionAttempt.Sub(*gcs.state.firstOffline)
+		return timeSinceLastAttempt > timeOfflineAtLastAttempt
+	}
+}
+
+func (gcs *GcsStorage) updateConnectionState(err error) bool {
+	gcs.state.Lock()
+	defer gcs.state.Unlock()
+
+	// A context.Canceled error means gcs.ctx was already cancelled by us when we went offline.
+	// It carries no new connectivity information, so we must not update state.
+	if errors.Is(err, context.Canceled) {
+		return gcs.state.firstOffline == nil
+	}
+
+	currentTime := time.Now()
+	gcs.state.lastConnectionAttempt = &currentTime
+	connected := !errors.Is(err, &common.CloudUnreachableError{})
+	wasConnected := gcs.state.firstOffline == nil
+	stateChanged := connected != wasConnected
+	if stateChanged {
+		log.Warn("GcsStorage::updateConnectionState : connected is now: %t", connected)
+		if connected {
+			gcs.state.firstOffline = nil
+			// reset the context to allow new requests
+			gcs.ctx, gcs.cancelFn = context.WithCancel(context.Background())
+			// stop the retry ticker
+			gcs.state.retryTicker.Stop()
+		} else {
+			gcs.state.firstOffline = &currentTime
+			// cancel all outstanding requests
+			gcs.cancelFn()
+			log.Warn("GcsStorage::updateConnectionState : cancelled all outstanding requests")
+			// reset the ticker to retry the connection
+			gcs.state.retryTicker.Reset(gcs.stConfig.healthCheckInterval)
+		}
+	}
+	return connected
+}
+
+// ------------------------- Bucket listing -------------------------------------------
+func (gcs *GcsStorage) ListBuckets() ([]string, error) {
+	return gcs.Storage.ListBuckets(gcs.ctx)
+}
+
+// ------------------------- Core Operations -------------------------------------------
+
+// Directory operations
+func (gcs *GcsStorage) CreateDir(options internal.CreateDirOptions) error {
+	log.Trace("GcsStorage::CreateDir : %s", options.Name)
+
+	err := gcs.Storage.CreateDirectory(gcs.ctx, internal.TruncateDirName(options.Name))
+	if gcs.stConfig.enableDirMarker {
+		gcs.updateConnectionState(err)
+	}
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(
+			createDir,
+			options.Name,
+			map[string]any{mode: options.Mode.String()},
+		)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, createDir, (int64)(1))
+	}
+
+	return err
+}
+
+func (gcs *GcsStorage) DeleteDir(options internal.DeleteDirOptions) error {
+	log.Trace("GcsStorage::DeleteDir : %s", options.Name)
+
+	err := gcs.Storage.DeleteDirectory(gcs.ctx, internal.TruncateDirName(options.Name))
+	gcs.updateConnectionState(err)
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(deleteDir, options.Name, nil)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, deleteDir, (int64)(1))
+	}
+
+	return err
+}
+
+func formatListDirName(path string) string {
+	// If we check the root directory, make sure we pass "" instead of "/"
+	// If we aren't checking the root directory, then we want to extend the directory name so List returns all children and does not include the path itself.
+	if path == "/" {
+		path = ""
+	} else if path != "" {
+		path = internal.ExtendDirName(path)
+	}
+	return path
+}
+
+func (gcs *GcsStorage) IsDirEmpty(options internal.IsDirEmptyOptions) bool {
+	log.Trace("GcsStorage::IsDirEmpty : %s", options.Name)
+	// List up to two objects, since one could be the directory marker
+	list, _, err := gcs.Storage.List(gcs.ctx, formatListDirName(options.Name), nil, 2)
+	gcs.updateConnectionState(err)
+	if err != nil {
+		log.Err("GcsStorage::IsDirEmpty : error listing [%s]", err)
+		return false
+	}
+
+	return len(list) == 0
+}
+
+func (gcs *GcsStorage) StreamDir(
+	options internal.StreamDirOptions,
+) ([]*internal.ObjAttr, string, error) {
+	log.Trace(
+		"GcsStorage::StreamDir : %s, offset %d, count %d",
+		options.Name,
+		options.Offset,
+		options.Count,
+	)
+	objectList := make([]*internal.ObjAttr, 0)
+
+	path := formatListDirName(options.Name)
+	var iteration int           // = 0
+	var marker = &options.Token // = nil
+	var totalEntriesFetched int32
+	entriesRemaining := options.Count
+	if options.Count == 0 {
+		entriesRemaining = maxResultsPerListCall
+	}
+	for entriesRemaining > 0 {
+		newList, nextMarker, err := gcs.Storage.List(gcs.ctx, path, marker, entriesRemaining)
+		gcs.updateConnectionState(err)
+		if err != nil {
+			log.Err("GcsStorage::StreamDir : %s Failed to read dir [%s]", options.Name, err)
+			return objectList, "", err
+		}
+		objectList = append(objectList, newList...)
+		marker = nextMarker
+		iteration++
+		totalEntriesFetched += int32(len(newList))
+
+		log.Debug("GcsStorage::StreamDir : %s So far retrieved %d objects in %d iterations",
+			options.Name, totalEntriesFetched, iteration)
+		if marker == nil || *marker == "" || len(newList) == 0 {
+			break
+		} else {
+			log.Debug("GcsStorage::StreamDir : %s List iteration %d nextMarker=\"%s\"",
+				options.Name, iteration, *nextMarker)
+		}
+		// decrement and loop
+		entriesRemaining -= int32(len(newList))
+	}
+
+	if marker == nil {
+		blnkStr := ""
+		marker = &blnkStr
+	}
+
+	// if path is empty, it means it is the root, relative to the mounted directory
+	if len(path) == 0 {
+		path = "/"
+	}
+	gcsStatsCollector.PushEvents(streamDir, path, map[string]any{count: totalEntriesFetched})
+
+	// increment streamDir call count
+	gcsStatsCollector.UpdateStats(stats_manager.Increment, streamDir, (int64)(1))
+
+	return objectList, *marker, nil
+}
+
+func (gcs *GcsStorage) RenameDir(options internal.RenameDirOptions) error {
+	log.Trace("GcsStorage::RenameDir : %s to %s", options.Src, options.Dst)
+	options.Src = internal.TruncateDirName(options.Src)
+	options.Dst = internal.TruncateDirName(options.Dst)
+
+	err := gcs.Storage.RenameDirectory(gcs.ctx, options.Src, options.Dst)
+	gcs.updateConnectionState(err)
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(
+			renameDir,
+			options.Src,
+			map[string]any{src: options.Src, dest: options.Dst},
+		)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, renameDir, (int64)(1))
+	}
+	return err
+}
+
+// File operations
+func (gcs *GcsStorage) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
+	log.Trace("GcsStorage::CreateFile : %s", options.Name)
+
+	// Create a handle object for the file being created
+	// This handle will be added to handlemap by the first component in pipeline
+	handle := handlemap.NewHandle(options.Name)
+	if handle == nil {
+		log.Err("GcsStorage::CreateFile : Failed to create handle for %s", options.Name)
+		return nil, syscall.EFAULT
+	}
+
+	err := gcs.Storage.CreateFile(gcs.ctx, options.Name, options.Mode)
+	gcs.updateConnectionState(err)
+	if err != nil {
+		return nil, err
+	}
+	handle.Mtime = time.Now()
+
+	gcsStatsCollector.PushEvents(
+		createFile,
+		options.Name,
+		map[string]any{mode: options.Mode.String()},
+	)
+
+	// increment open file handles count
+	gcsStatsCollector.UpdateStats(stats_manager.Increment, openHandles, (int64)(1))
+
+	return handle, nil
+}
+
+func (gcs *GcsStorage) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
+	log.Trace("GcsStorage::OpenFile : %s", options.Name)
+
+	attr, err := gcs.Storage.GetAttr(gcs.ctx, options.Name)
+	gcs.updateConnectionState(err)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a handle object for the file being opened
+	// This handle will be added to handlemap by the first component in pipeline
+	handle := handlemap.NewHandle(options.Name)
+	if handle == nil {
+		log.Err("GcsStorage::OpenFile : Failed to create handle for %s", options.Name)
+		return nil, syscall.EFAULT
+	}
+	handle.Size = int64(attr.Size)
+	handle.Mtime = attr.Mtime
+
+	// increment open file handles count
+	gcsStatsCollector.UpdateStats(stats_manager.Increment, openHandles, (int64)(1))
+
+	return handle, nil
+}
+
+func (gcs *GcsStorage) ReleaseFile(options internal.ReleaseFileOptions) error {
+	log.Trace("GcsStorage::ReleaseFile : %s", options.Handle.Path)
+
+	// decrement open file handles count
+	gcsStatsCollector.UpdateStats(stats_manager.Decrement, openHandles, (int64)(1))
+
+	return nil
+}
+
+func (gcs *GcsStorage) DeleteFile(options internal.DeleteFileOptions) error {
+	log.Trace("GcsStorage::DeleteFile : %s", options.Name)
+
+	err := gcs.Storage.DeleteFile(gcs.ctx, options.Name)
+	gcs.updateConnectionState(err)
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(deleteFile, options.Name, nil)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, deleteFile, (int64)(1))
+	}
+
+	return err
+}
+
+func (gcs *GcsStorage) RenameFile(options internal.RenameFileOptions) error {
+	log.Trace("GcsStorage::RenameFile : %s to %s", options.Src, options.Dst)
+
+	err := gcs.Storage.RenameFile(gcs.ctx, options.Src, options.Dst)
+	gcs.updateConnectionState(err)
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(
+			renameFile,
+			options.Src,
+			map[string]any{src: options.Src, dest: options.Dst},
+		)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, renameFile, (int64)(1))
+	}
+	return err
+}
+
+// Read file data into the buffer given in options.Data.
+func (gcs *GcsStorage) ReadInBuffer(options *internal.ReadInBufferOptions) (int, error) {
+	if options.Offset > atomic.LoadInt64(&options.Handle.Size) {
+		return 0, syscall.ERANGE
+	}
+
+	var dataLen = int64(len(options.Data))
+	if atomic.LoadInt64(&options.Handle.Size) < (options.Offset + int64(len(options.Data))) {
+		dataLen = options.Handle.Size - options.Offset
+	}
+
+	if dataLen == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := options.Context(gcs.ctx)
+	defer cancel()
+	err := gcs.Storage.ReadInBuffer(
+		ctx,
+		options.Handle.Path,
+		options.Offset,
+		dataLen,
+		options.Data,
+	)
+	gcs.updateConnectionState(err)
+	if err != nil {
+		log.Err(
+			"GcsStorage::ReadInBuffer : Failed to read %s [%s]",
+			options.Handle.Path,
+			err.Error(),
+		)
+	}
+
+	length := int(dataLen)
+	return length, err
+}
+
+func (gcs *GcsStorage) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	err := gcs.Storage.Write(gcs.ctx, options)
+	gcs.updateConnectionState(err)
+	return len(options.Data), err
+}
+
+func (gcs *GcsStorage) GetFileBlockOffsets(
+	options internal.GetFileBlockOffsetsOptions,
+) (*common.BlockOffsetList, error) {
+	blockList, err := gcs.Storage.GetFileBlockOffsets(gcs.ctx, options.Name)
+	gcs.updateConnectionState(err)
+	return blockList, err
+}
+
+func (gcs *GcsStorage) TruncateFile(options internal.TruncateFileOptions) error {
+	log.Trace("GcsStorage::TruncateFile : %s to %d bytes", options.Name, options.NewSize)
+	err := gcs.Storage.TruncateFile(gcs.ctx, options.Name, options.NewSize)
+	gcs.updateConnectionState(err)
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(
+			truncateFile,
+			options.Name,
+			map[string]any{size: options.NewSize},
+		)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, truncateFile, (int64)(1))
+	}
+	return err
+}
+
+func (gcs *GcsStorage) CopyToFile(options internal.CopyToFileOptions) error {
+	log.Trace("GcsStorage::CopyToFile : Read file %s", options.Name)
+	err := gcs.Storage.ReadToFile(
+		gcs.ctx,
+		options.Name,
+		options.Offset,
+		options.Count,
+		options.File,
+	)
+	gcs.updateConnectionState(err)
+	return err
+}
+
+func (gcs *GcsStorage) CopyFromFile(options internal.CopyFromFileOptions) error {
+	log.Trace("GcsStorage::CopyFromFile : Upload file %s", options.Name)
+	err := gcs.Storage.WriteFromFile(
+		gcs.ctx,
+		options.Name,
+		options.Metadata,
+		options.File,
+		options.IfMatch,
+		options.NewETag,
+	)
+	gcs.updateConnectionState(err)
+	return err
+}
+
+// Symlink operations
+func (gcs *GcsStorage) CreateLink(options internal.CreateLinkOptions) error {
+	if gcs.stConfig.disableSymlink {
+		log.Err(
+			"GcsStorage::CreateLink : %s -> %s - Symlink support not enabled",
+			options.Name,
+			options.Target,
+		)
+		return syscall.ENOTSUP
+	}
+	log.Trace("GcsStorage::CreateLink : Create symlink %s -> %s", options.Name, options.Target)
+	err := gcs.Storage.CreateLink(gcs.ctx, options.Name, options.Target)
+	gcs.updateConnectionState(err)
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(
+			createLink,
+			options.Name,
+			map[string]any{target: options.Target},
+		)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, createLink, (int64)(1))
+	}
+
+	return err
+}
+
+func (gcs *GcsStorage) ReadLink(options internal.ReadLinkOptions) (string, error) {
+	if gcs.stConfig.disableSymlink {
+		log.Err("GcsStorage::ReadLink : %s - Symlink support not enabled", options.Name)
+		return "", syscall.ENOENT
+	}
+	log.Trace("GcsStorage::ReadLink : Read symlink %s", options.Name)
+
+	data, err := gcs.Storage.ReadBuffer(gcs.ctx, options.Name, 0, 0)
+	gcs.updateConnectionState(err)
+
+	if err == nil {
+		gcsStatsCollector.PushEvents(readLink, options.Name, nil)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, readLink, (int64)(1))
+	}
+
+	return string(data), err
+}
+
+// Attribute operations
+func (gcs *GcsStorage) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
+	attr, err := gcs.Storage.GetAttr(gcs.ctx, options.Name)
+	gcs.updateConnectionState(err)
+	return attr, err
+}
+
+// Metadata operations
+func (gcs *GcsStorage) GetMetadata(
+	options internal.GetMetadataOptions,
+) (map[string]*string, error) {
+	log.Trace("GcsStorage::GetMetadata : Get metadata of %s", options.Name)
+	metadata, err := gcs.Storage.GetMetadata(gcs.ctx, options.Name)
+	gcs.updateConnectionState(err)
+	return metadata, err
+}
+
+func (gcs *GcsStorage) SetMetadata(options internal.SetMetadataOptions) error {
+	log.Trace("GcsStorage::SetMetadata : Set metadata of %s", options.Name)
+	err := gcs.Storage.SetMetadata(gcs.ctx, options.Name, options.Metadata)
+	gcs.updateConnectionState(err)
+	if err == nil {
+		gcsStatsCollector.PushEvents(setMetadata, options.Name, nil)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, setMetadata, (int64)(1))
+	}
+	return err
+}
+
+func (gcs *GcsStorage) Utimens(options internal.UtimensOptions) error {
+	log.Trace("GcsStorage::Utimens : Change times of %s", options.Name)
+	err := gcs.Storage.Utimens(gcs.ctx, options.Name, options.Atime, options.Mtime)
+	gcs.updateConnectionState(err)
+	if err == nil {
+		gcsStatsCollector.PushEvents(utimens, options.Name, nil)
+		gcsStatsCollector.UpdateStats(stats_manager.Increment, utimens, (int64)(1))
+	}
+	return err
+}
+
+func (gcs *GcsStorage) Chmod(options internal.ChmodOptions) error {
+	log.Trace("GcsStorage::Chmod : Change mode of file %s", options.Name)
+
+	gcsStatsCollector.PushEvents(
+		chmod,
+		options.Name,
+		map[string]any{mode: options.Mode.String()},
+	)
+	gcsStatsCollector.UpdateStats(stats_manager.Increment, chmod, (int64)(1))
+
+	return nil
+}
+
+func (gcs *GcsStorage) Chown(options internal.ChownOptions) error {
+	log.Trace(
+		"GcsStorage::Chown : Change ownership of file %s to %d-%d",
+		options.Name,
+		options.Owner,
+		options.Group,
+	)
+	return nil
+}
+
+func (gcs *GcsStorage) GetCommittedBlockList(name string) (*internal.CommittedBlockList, error) {
+	cbl, err := gcs.Storage.GetCommittedBlockList(gcs.ctx, name)
+	gcs.updateConnectionState(err)
+	return cbl, err
+}
+
+func (gcs *GcsStorage) StageData(opt internal.StageDataOptions) error {
+	err := gcs.Storage.StageBlock(gcs.ctx, opt.Name, opt.Data, opt.Id)
+	gcs.updateConnectionState(err)
+	return err
+}
+
+func (gcs *GcsStorage) CommitData(opt internal.CommitDataOptions) error {
+	err := gcs.Storage.CommitBlocks(gcs.ctx, opt.Name, opt.List, opt.NewETag, opt.Metadata)
+	gcs.updateConnectionState(err)
+	return err
+}
+
+const blockSize = 4096
+
+func (gcs *GcsStorage) StatFs() (*common.Statfs_t, bool, error) {
+	if gcs.stConfig.disableUsage {
+		return nil, false, nil
+	}
+
+	log.Trace("GcsStorage::StatFs")
+	sizeUsed, err := gcs.Storage.GetUsedSize(gcs.ctx)
+	gcs.updateConnectionState(err)
+	if err != nil {
+		return nil, true, err
+	}
+
+	stat := common.Statfs_t{
+		Blocks: sizeUsed / blockSize,
+		// there is no set capacity limit in cloud storage
+		// so we use zero for free and avail
+		// this zero value is used in the libfuse component to recognize that cloud storage responded
+		Bavail:  0,
+		Bfree:   0,
+		Bsize:   blockSize,
+		Ffree:   1e9,
+		Files:   1e9,
+		Frsize:  blockSize,
+		Namemax: 255,
+	}
+
+	log.Debug(
+		"GcsStorage::StatFs : responding with free=%d avail=%d blocks=%d (bsize=%d)",
+		stat.Bfree,
+		stat.Bavail,
+		stat.Blocks,
+		stat.Bsize,
+	)
+
+	return &stat, true, nil
+}
+
+// ------------------------- Factory methods to create objects -------------------------------------------
+
+// Constructor to create object of this component
+func NewgcsstorageComponent() internal.Component {
+	// Init the component with default config
+	gcs := &GcsStorage{
+		stConfig: Config{
+			maxRetries:          DefaultMaxRetries,
+			blockSize:           defaultBlockSize,
+			healthCheckInterval: defaultHealthCheckInterval,
+		},
+	}
+
+	gcs.SetName(compName)
+	config.AddConfigChangeEventListener(gcs)
+	return gcs
+}
+
+// On init register this component to pipeline and supply your constructor
+func init() {
+	internal.AddComponent(compName, NewgcsstorageComponent)
+}
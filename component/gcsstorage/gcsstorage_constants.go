@@ -0,0 +1,55 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+const (
+	bytesDownloaded = "Bytes Downloaded"
+	bytesUploaded   = "Bytes Uploaded"
+
+	createDir    = "CreateDir"
+	deleteDir    = "DeleteDir"
+	streamDir    = "StreamDir"
+	renameDir    = "RenameDir"
+	createFile   = "CreateFile"
+	deleteFile   = "DeleteFile"
+	renameFile   = "RenameFile"
+	truncateFile = "TruncateFile"
+	createLink   = "CreateLink"
+	readLink     = "ReadLink"
+	chmod        = "Chmod"
+	setMetadata  = "SetMetadata"
+	utimens      = "Utimens"
+
+	cloudConnected = "CloudConnected"
+
+	openHandles = "OpenFileHandles"
+	mode        = "Mode"
+	count       = "Count"
+	src         = "Src"
+	dest        = "Dest"
+	size        = "Size"
+	target      = "Target"
+)
@@ -0,0 +1,827 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/handlemap"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testProject = "test-project"
+	testBucket  = "test-bucket"
+	MB          = 1024 * 1024
+)
+
+type gcsStorageTestSuite struct {
+	suite.Suite
+	assert     *assert.Assertions
+	fake       *fakeGcsServer
+	gcsStorage *GcsStorage
+}
+
+func randomBytes(length int) []byte {
+	data := make([]byte, length)
+	_, _ = rand.Read(data)
+	return data
+}
+
+func newTestGcsStorage(configuration string) (*GcsStorage, error) {
+	err := config.ReadConfigFromReader(strings.NewReader(configuration))
+	if err != nil {
+		fmt.Printf("newTestGcsStorage : ReadConfigFromReader failed. Here's why: %v\n", err)
+		return nil, err
+	}
+	gcs := NewgcsstorageComponent()
+	err = gcs.Configure(true)
+
+	return gcs.(*GcsStorage), err
+}
+
+func (s *gcsStorageTestSuite) generateConfigYaml(extra string) string {
+	return fmt.Sprintf(
+		"gcsstorage:\n  bucket-name: %s\n  project-id: %s\n  endpoint: %s\n  anonymous: true\n%s",
+		testBucket,
+		testProject,
+		s.fake.URL,
+		extra,
+	)
+}
+
+func (s *gcsStorageTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic(fmt.Sprintf("Unable to set silent logger as default: %v", err))
+	}
+	s.assert = assert.New(s.T())
+	s.fake = newFakeGcsServer(testProject, testBucket, "other-bucket")
+	s.setupTestHelper("  enable-dir-marker: true\n  block-size-mb: 1\n")
+}
+
+func (s *gcsStorageTestSuite) setupTestHelper(extra string) {
+	var err error
+	s.gcsStorage, err = newTestGcsStorage(s.generateConfigYaml(extra))
+	s.Require().NoError(err)
+	s.Require().NoError(s.gcsStorage.Start(context.Background()))
+}
+
+func (s *gcsStorageTestSuite) TearDownTest() {
+	_ = s.gcsStorage.Stop()
+	s.fake.Close()
+	config.ResetConfig()
+}
+
+func (s *gcsStorageTestSuite) TestDefault() {
+	s.assert.Equal(testBucket, s.gcsStorage.stConfig.AuthConfig.BucketName)
+	s.assert.Equal(s.fake.URL, s.gcsStorage.stConfig.AuthConfig.Endpoint)
+	s.assert.Empty(s.gcsStorage.stConfig.prefixPath)
+	s.assert.Equal(DefaultMaxRetries, s.gcsStorage.stConfig.maxRetries)
+	s.assert.EqualValues(1*MB, s.gcsStorage.stConfig.blockSize)
+	s.assert.True(s.gcsStorage.stConfig.disableSymlink)
+}
+
+func (s *gcsStorageTestSuite) TestDefaultBucket() {
+	_ = s.gcsStorage.Stop()
+	config.ResetConfig()
+
+	var err error
+	s.gcsStorage, err = newTestGcsStorage(fmt.Sprintf(
+		"gcsstorage:\n  project-id: %s\n  endpoint: %s\n  anonymous: true\n",
+		testProject,
+		s.fake.URL,
+	))
+	s.Require().NoError(err)
+	s.Require().NoError(s.gcsStorage.Start(context.Background()))
+	// first bucket alphabetically
+	s.assert.Equal("other-bucket", s.gcsStorage.stConfig.AuthConfig.BucketName)
+}
+
+func (s *gcsStorageTestSuite) TestInvalidBucket() {
+	config.ResetConfig()
+	_, err := newTestGcsStorage(fmt.Sprintf(
+		"gcsstorage:\n  bucket-name: missing\n  endpoint: %s\n  anonymous: true\n",
+		s.fake.URL,
+	))
+	s.assert.ErrorIs(err, errBucketDoesNotExist)
+}
+
+func (s *gcsStorageTestSuite) TestGenConfig() {
+	genConfig := s.gcsStorage.GenConfig()
+	s.assert.Contains(genConfig, "\ngcsstorage:")
+	s.assert.Contains(genConfig, "\n  bucket-name: "+testBucket)
+	s.assert.Contains(genConfig, "\n  project-id: "+testProject)
+	s.assert.Contains(genConfig, "\n  endpoint: "+DefaultEndpoint)
+	s.assert.Contains(genConfig, "\n  health-check-interval-sec: 2")
+}
+
+func (s *gcsStorageTestSuite) TestListBuckets() {
+	buckets, err := s.gcsStorage.ListBuckets()
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"other-bucket", testBucket}, buckets)
+}
+
+func (s *gcsStorageTestSuite) TestCloudConnected() {
+	s.assert.True(s.gcsStorage.CloudConnected())
+}
+
+func (s *gcsStorageTestSuite) TestUpdateConnectionState() {
+	connected := s.gcsStorage.updateConnectionState(&common.CloudUnreachableError{})
+	s.assert.False(connected)
+	s.assert.False(s.gcsStorage.CloudConnected())
+	connected = s.gcsStorage.updateConnectionState(nil)
+	s.assert.True(connected)
+	s.assert.True(s.gcsStorage.CloudConnected())
+}
+
+func (s *gcsStorageTestSuite) TestCloudOfflineContext() {
+	s.gcsStorage.updateConnectionState(&common.CloudUnreachableError{})
+	h, err := s.gcsStorage.CreateFile(internal.CreateFileOptions{Name: "file"})
+	s.assert.Nil(h)
+	s.assert.ErrorIs(err, &common.CloudUnreachableError{})
+	s.gcsStorage.updateConnectionState(nil)
+}
+
+func (s *gcsStorageTestSuite) TestServerUnreachable() {
+	s.gcsStorage.stConfig.maxRetries = 0
+	s.Require().NoError(s.gcsStorage.Storage.UpdateConfig(s.gcsStorage.stConfig))
+	s.fake.Close()
+
+	_, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "file"})
+	s.assert.ErrorIs(err, &common.CloudUnreachableError{})
+	s.assert.False(s.gcsStorage.CloudConnected())
+}
+
+func (s *gcsStorageTestSuite) TestRetry() {
+	s.fake.putObject(testBucket, "file", []byte("data"), nil)
+	s.fake.failNext = 2
+
+	attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.EqualValues(4, attr.Size)
+}
+
+func (s *gcsStorageTestSuite) TestCreateDir() {
+	for _, name := range []string{"dir", "dir2/"} {
+		err := s.gcsStorage.CreateDir(internal.CreateDirOptions{Name: name})
+		s.assert.NoError(err)
+
+		s.assert.NotNil(s.fake.getObject(testBucket, internal.ExtendDirName(name)))
+		attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: name})
+		s.assert.NoError(err)
+		s.assert.True(attr.IsDir())
+	}
+}
+
+func (s *gcsStorageTestSuite) TestDeleteDir() {
+	s.Require().NoError(s.gcsStorage.CreateDir(internal.CreateDirOptions{Name: "dir"}))
+
+	err := s.gcsStorage.DeleteDir(internal.DeleteDirOptions{Name: "dir"})
+	s.assert.NoError(err)
+	_, err = s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "dir"})
+	s.assert.Equal(syscall.ENOENT, err)
+
+	// deleting a directory without a marker is not an error
+	err = s.gcsStorage.DeleteDir(internal.DeleteDirOptions{Name: "dir"})
+	s.assert.NoError(err)
+}
+
+func (s *gcsStorageTestSuite) TestIsDirEmpty() {
+	s.Require().NoError(s.gcsStorage.CreateDir(internal.CreateDirOptions{Name: "dir"}))
+	s.assert.True(s.gcsStorage.IsDirEmpty(internal.IsDirEmptyOptions{Name: "dir"}))
+
+	s.fake.putObject(testBucket, "dir/file", []byte("data"), nil)
+	s.assert.False(s.gcsStorage.IsDirEmpty(internal.IsDirEmptyOptions{Name: "dir"}))
+}
+
+func (s *gcsStorageTestSuite) TestImplicitDir() {
+	s.fake.putObject(testBucket, "a/b/file", []byte("data"), nil)
+
+	for _, name := range []string{"a", "a/", "a/b"} {
+		attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: name})
+		s.assert.NoError(err)
+		s.assert.True(attr.IsDir())
+	}
+	_, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "a/c"})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *gcsStorageTestSuite) TestStreamDir() {
+	s.Require().NoError(s.gcsStorage.CreateDir(internal.CreateDirOptions{Name: "dir"}))
+	s.Require().NoError(s.gcsStorage.CreateDir(internal.CreateDirOptions{Name: "dir/empty"}))
+	s.fake.putObject(testBucket, "dir/b", []byte("data"), nil)
+	s.fake.putObject(testBucket, "dir/a", []byte("da"), nil)
+	s.fake.putObject(testBucket, "dir/sub/c", []byte("d"), nil)
+	s.fake.putObject(testBucket, "dirx", []byte("d"), nil)
+
+	entries, token, err := s.gcsStorage.StreamDir(internal.StreamDirOptions{Name: "dir"})
+	s.assert.NoError(err)
+	s.assert.Empty(token)
+	s.Require().Len(entries, 4)
+	s.assert.Equal("dir/a", entries[0].Path)
+	s.assert.EqualValues(2, entries[0].Size)
+	s.assert.Equal("a", entries[0].Name)
+	s.assert.Equal("dir/b", entries[1].Path)
+	s.assert.Equal("dir/empty", entries[2].Path)
+	s.assert.True(entries[2].IsDir())
+	s.assert.Equal("dir/sub", entries[3].Path)
+	s.assert.True(entries[3].IsDir())
+
+	// root listing
+	entries, _, err = s.gcsStorage.StreamDir(internal.StreamDirOptions{Name: "/"})
+	s.assert.NoError(err)
+	s.Require().Len(entries, 2)
+	s.assert.Equal("dir", entries[0].Path)
+	s.assert.Equal("dirx", entries[1].Path)
+}
+
+func (s *gcsStorageTestSuite) TestStreamDirPaged() {
+	for i := range 5 {
+		s.fake.putObject(testBucket, fmt.Sprintf("dir/file%d", i), nil, nil)
+	}
+
+	names := make([]string, 0)
+	token := ""
+	for {
+		entries, nextToken, err := s.gcsStorage.StreamDir(
+			internal.StreamDirOptions{Name: "dir", Token: token, Count: 2},
+		)
+		s.Require().NoError(err)
+		s.assert.LessOrEqual(len(entries), 2)
+		for _, entry := range entries {
+			names = append(names, entry.Name)
+		}
+		if nextToken == "" {
+			break
+		}
+		token = nextToken
+	}
+	s.assert.Equal([]string{"file0", "file1", "file2", "file3", "file4"}, names)
+}
+
+func (s *gcsStorageTestSuite) TestStreamDirSkipsStaging() {
+	s.Require().NoError(s.gcsStorage.StageData(
+		internal.StageDataOptions{Name: "file", Data: []byte("data"), Id: "AAAA"},
+	))
+
+	entries, _, err := s.gcsStorage.StreamDir(internal.StreamDirOptions{Name: "/"})
+	s.assert.NoError(err)
+	s.assert.Empty(entries)
+}
+
+func (s *gcsStorageTestSuite) TestRenameDir() {
+	s.Require().NoError(s.gcsStorage.CreateDir(internal.CreateDirOptions{Name: "src"}))
+	s.fake.putObject(testBucket, "src/a", []byte("a"), nil)
+	s.fake.putObject(testBucket, "src/sub/b", []byte("b"), nil)
+	s.fake.putObject(testBucket, "srcx", []byte("c"), nil)
+
+	err := s.gcsStorage.RenameDir(internal.RenameDirOptions{Src: "src", Dst: "dst"})
+	s.assert.NoError(err)
+	s.assert.Equal([]string{"dst/", "dst/a", "dst/sub/b", "srcx"}, s.fake.keys(testBucket))
+}
+
+func (s *gcsStorageTestSuite) TestCreateFile() {
+	h, err := s.gcsStorage.CreateFile(internal.CreateFileOptions{Name: "file.txt"})
+	s.assert.NoError(err)
+	s.assert.Equal("file.txt", h.Path)
+	s.assert.EqualValues(0, h.Size)
+
+	obj := s.fake.getObject(testBucket, "file.txt")
+	s.Require().NotNil(obj)
+	s.assert.Empty(obj.data)
+	s.assert.Equal("text/plain", obj.contentType)
+}
+
+func (s *gcsStorageTestSuite) TestOpenFile() {
+	s.fake.putObject(testBucket, "file", []byte("data"), nil)
+
+	h, err := s.gcsStorage.OpenFile(internal.OpenFileOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.EqualValues(4, h.Size)
+
+	_, err = s.gcsStorage.OpenFile(internal.OpenFileOptions{Name: "missing"})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *gcsStorageTestSuite) TestGetAttrFile() {
+	metadata := map[string]*string{"foo": new("bar")}
+	s.fake.putObject(testBucket, "file", []byte("data"), metadata)
+
+	attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.Equal("file", attr.Path)
+	s.assert.EqualValues(4, attr.Size)
+	s.assert.False(attr.IsDir())
+	s.assert.NotEmpty(attr.ETag)
+	s.assert.Len(attr.MD5, 16)
+	s.assert.Equal("bar", *attr.Metadata["foo"])
+	s.assert.False(attr.Mtime.IsZero())
+}
+
+func (s *gcsStorageTestSuite) TestDeleteFile() {
+	s.fake.putObject(testBucket, "file", []byte("data"), nil)
+
+	err := s.gcsStorage.DeleteFile(internal.DeleteFileOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.Nil(s.fake.getObject(testBucket, "file"))
+
+	err = s.gcsStorage.DeleteFile(internal.DeleteFileOptions{Name: "file"})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *gcsStorageTestSuite) TestRenameFile() {
+	metadata := map[string]*string{"foo": new("bar")}
+	s.fake.putObject(testBucket, "src", []byte("data"), metadata)
+
+	err := s.gcsStorage.RenameFile(internal.RenameFileOptions{Src: "src", Dst: "dst"})
+	s.assert.NoError(err)
+	s.assert.Nil(s.fake.getObject(testBucket, "src"))
+	obj := s.fake.getObject(testBucket, "dst")
+	s.Require().NotNil(obj)
+	s.assert.Equal([]byte("data"), obj.data)
+	s.assert.Equal("bar", *obj.metadata["foo"])
+
+	err = s.gcsStorage.RenameFile(internal.RenameFileOptions{Src: "src", Dst: "dst"})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *gcsStorageTestSuite) TestReadInBuffer() {
+	data := randomBytes(100)
+	s.fake.putObject(testBucket, "file", data, nil)
+	h := handlemap.NewHandle("file")
+	h.Size = int64(len(data))
+
+	output := make([]byte, 10)
+	n, err := s.gcsStorage.ReadInBuffer(
+		&internal.ReadInBufferOptions{Handle: h, Offset: 5, Data: output},
+	)
+	s.assert.NoError(err)
+	s.assert.Equal(10, n)
+	s.assert.Equal(data[5:15], output)
+
+	// reads are clipped to the size of the file
+	output = make([]byte, 20)
+	n, err = s.gcsStorage.ReadInBuffer(
+		&internal.ReadInBufferOptions{Handle: h, Offset: 90, Data: output},
+	)
+	s.assert.NoError(err)
+	s.assert.Equal(10, n)
+	s.assert.Equal(data[90:], output[:n])
+
+	_, err = s.gcsStorage.ReadInBuffer(
+		&internal.ReadInBufferOptions{Handle: h, Offset: 101, Data: output},
+	)
+	s.assert.Equal(syscall.ERANGE, err)
+}
+
+func (s *gcsStorageTestSuite) TestCopyFromFile() {
+	data := randomBytes(3 * MB)
+	f, err := os.CreateTemp("", "gcs-test-*")
+	s.Require().NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(data)
+	s.Require().NoError(err)
+
+	metadata := map[string]*string{"foo": new("bar")}
+	err = s.gcsStorage.CopyFromFile(
+		internal.CopyFromFileOptions{Name: "dir/file.bin", File: f, Metadata: metadata},
+	)
+	s.assert.NoError(err)
+
+	obj := s.fake.getObject(testBucket, "dir/file.bin")
+	s.Require().NotNil(obj)
+	s.assert.Equal(data, obj.data)
+	s.assert.Equal("bar", *obj.metadata["foo"])
+}
+
+func (s *gcsStorageTestSuite) TestCopyFromFileKeepsMetadata() {
+	s.fake.putObject(testBucket, "file", []byte("data"), nil)
+	err := s.gcsStorage.SetMetadata(internal.SetMetadataOptions{
+		Name:     "file",
+		Metadata: map[string]*string{"label": new("a")},
+	})
+	s.Require().NoError(err)
+
+	// the file is written and flushed again, with the modification time of the local copy
+	f, err := os.CreateTemp("", "gcs-test-*")
+	s.Require().NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write([]byte("new data"))
+	s.Require().NoError(err)
+	mtime := time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC)
+	err = s.gcsStorage.CopyFromFile(internal.CopyFromFileOptions{
+		Name: "file",
+		File: f,
+		Metadata: map[string]*string{
+			internal.MtimeMetadataKey: new(internal.FormatMetadataTime(mtime)),
+		},
+	})
+	s.assert.NoError(err)
+
+	s.assert.Equal([]byte("new data"), s.fake.getObject(testBucket, "file").data)
+	metadata, err := s.gcsStorage.GetMetadata(internal.GetMetadataOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+	attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.True(mtime.Equal(attr.Mtime))
+}
+
+func (s *gcsStorageTestSuite) TestCopyToFile() {
+	data := randomBytes(1000)
+	s.fake.putObject(testBucket, "file", data, nil)
+	f, err := os.CreateTemp("", "gcs-test-*")
+	s.Require().NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = s.gcsStorage.CopyToFile(
+		internal.CopyToFileOptions{Name: "file", Offset: 100, Count: 200, File: f},
+	)
+	s.assert.NoError(err)
+	output, err := os.ReadFile(f.Name())
+	s.assert.NoError(err)
+	s.assert.Equal(data[100:300], output)
+}
+
+func (s *gcsStorageTestSuite) TestWriteFile() {
+	s.fake.putObject(testBucket, "file", []byte("hello world"), map[string]*string{"foo": new("bar")})
+	h := handlemap.NewHandle("file")
+
+	// overwrite in the middle
+	_, err := s.gcsStorage.WriteFile(
+		&internal.WriteFileOptions{Handle: h, Offset: 6, Data: []byte("there")},
+	)
+	s.assert.NoError(err)
+	s.assert.Equal([]byte("hello there"), s.fake.getObject(testBucket, "file").data)
+
+	// append past the end composes the object with the new data
+	_, err = s.gcsStorage.WriteFile(
+		&internal.WriteFileOptions{Handle: h, Offset: 13, Data: []byte("!")},
+	)
+	s.assert.NoError(err)
+	obj := s.fake.getObject(testBucket, "file")
+	s.assert.Equal([]byte("hello there\x00\x00!"), obj.data)
+	s.assert.Equal("bar", *obj.metadata["foo"])
+	s.assert.Equal(1, s.fake.requestCount("objects.compose"))
+	// the staged data was cleaned up
+	s.assert.Equal([]string{"file"}, s.fake.keys(testBucket))
+}
+
+func (s *gcsStorageTestSuite) TestTruncateFile() {
+	metadata := map[string]*string{"foo": new("bar")}
+	s.fake.putObject(testBucket, "file", []byte("hello world"), metadata)
+
+	err := s.gcsStorage.TruncateFile(internal.TruncateFileOptions{Name: "file", NewSize: 5})
+	s.assert.NoError(err)
+	s.assert.Equal([]byte("hello"), s.fake.getObject(testBucket, "file").data)
+
+	err = s.gcsStorage.TruncateFile(internal.TruncateFileOptions{Name: "file", NewSize: 8})
+	s.assert.NoError(err)
+	obj := s.fake.getObject(testBucket, "file")
+	s.assert.Equal([]byte("hello\x00\x00\x00"), obj.data)
+	s.assert.Equal("bar", *obj.metadata["foo"])
+
+	err = s.gcsStorage.TruncateFile(internal.TruncateFileOptions{Name: "file", NewSize: 0})
+	s.assert.NoError(err)
+	s.assert.Empty(s.fake.getObject(testBucket, "file").data)
+
+	err = s.gcsStorage.TruncateFile(internal.TruncateFileOptions{Name: "missing", NewSize: 0})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *gcsStorageTestSuite) TestGetFileBlockOffsets() {
+	s.fake.putObject(testBucket, "file", []byte("data"), nil)
+
+	blockList, err := s.gcsStorage.GetFileBlockOffsets(
+		internal.GetFileBlockOffsetsOptions{Name: "file"},
+	)
+	s.assert.NoError(err)
+	s.assert.True(blockList.HasNoBlocks())
+}
+
+func (s *gcsStorageTestSuite) TestStageAndCommitData() {
+	ids := []string{
+		common.GetBlockID(common.BlockIDLength),
+		common.GetBlockID(common.BlockIDLength),
+		common.GetBlockID(common.BlockIDLength),
+	}
+	blocks := [][]byte{randomBytes(MB), randomBytes(MB), randomBytes(100)}
+	for i, id := range ids {
+		err := s.gcsStorage.StageData(
+			internal.StageDataOptions{Name: "file", Data: blocks[i], Id: id},
+		)
+		s.assert.NoError(err)
+	}
+
+	etag := ""
+	err := s.gcsStorage.CommitData(
+		internal.CommitDataOptions{Name: "file", List: ids, BlockSize: MB, NewETag: &etag},
+	)
+	s.assert.NoError(err)
+	obj := s.fake.getObject(testBucket, "file")
+	s.Require().NotNil(obj)
+	s.assert.Equal(bytes.Join(blocks, nil), obj.data)
+	s.assert.NotEmpty(etag)
+	s.assert.Equal(3, obj.componentCount)
+	// staged blocks are removed after the commit
+	s.assert.Equal([]string{"file"}, s.fake.keys(testBucket))
+}
+
+func (s *gcsStorageTestSuite) TestCommitDataAppend() {
+	data := randomBytes(2*MB + 10)
+	s.fake.putObject(testBucket, "file", data, map[string]*string{"foo": new("bar")})
+
+	blockList, err := s.gcsStorage.GetCommittedBlockList("file")
+	s.assert.NoError(err)
+	s.Require().Len(*blockList, 3)
+	s.assert.EqualValues(MB, (*blockList)[0].Size)
+	s.assert.EqualValues(2*MB, (*blockList)[2].Offset)
+	s.assert.EqualValues(10, (*blockList)[2].Size)
+
+	// append a block to the existing blocks, the object is used as the first source
+	newId := common.GetBlockID(common.BlockIDLength)
+	newData := randomBytes(500)
+	s.Require().NoError(s.gcsStorage.StageData(
+		internal.StageDataOptions{Name: "file", Data: newData, Id: newId},
+	))
+	list := []string{(*blockList)[0].Id, (*blockList)[1].Id, (*blockList)[2].Id, newId}
+	err = s.gcsStorage.CommitData(internal.CommitDataOptions{Name: "file", List: list})
+	s.assert.NoError(err)
+
+	obj := s.fake.getObject(testBucket, "file")
+	s.assert.Equal(append(data, newData...), obj.data)
+	s.assert.Equal("bar", *obj.metadata["foo"])
+	s.assert.Equal(0, s.fake.requestCount("objects.download"))
+
+	// replace the middle block, the other committed blocks are read back from the object
+	replaceId := common.GetBlockID(common.BlockIDLength)
+	replaceData := randomBytes(MB)
+	s.Require().NoError(s.gcsStorage.StageData(
+		internal.StageDataOptions{Name: "file", Data: replaceData, Id: replaceId},
+	))
+	list = []string{list[0], replaceId, list[2], list[3]}
+	err = s.gcsStorage.CommitData(internal.CommitDataOptions{Name: "file", List: list})
+	s.assert.NoError(err)
+
+	expected := bytes.Join([][]byte{data[:MB], replaceData, data[2*MB:], newData}, nil)
+	s.assert.Equal(expected, s.fake.getObject(testBucket, "file").data)
+	s.assert.Equal([]string{"file"}, s.fake.keys(testBucket))
+
+	// metadata given with the commit is merged into the metadata of the object
+	err = s.gcsStorage.CommitData(internal.CommitDataOptions{
+		Name:     "file",
+		List:     list,
+		Metadata: map[string]*string{"owner": new("me"), "foo": nil},
+	})
+	s.assert.NoError(err)
+	obj = s.fake.getObject(testBucket, "file")
+	s.assert.Equal(expected, obj.data)
+	s.assert.Equal(map[string]*string{"owner": new("me")}, obj.metadata)
+
+	// unknown block IDs are rejected
+	err = s.gcsStorage.CommitData(
+		internal.CommitDataOptions{Name: "file", List: []string{"unknown"}},
+	)
+	s.assert.Error(err)
+}
+
+func (s *gcsStorageTestSuite) TestCommitDataManyBlocks() {
+	ids := make([]string, 0)
+	expected := make([]byte, 0)
+	for i := range 2*maxComposeSources + 3 {
+		id := common.GetBlockID(common.BlockIDLength)
+		data := []byte(fmt.Sprintf("block%03d", i))
+		s.Require().NoError(s.gcsStorage.StageData(
+			internal.StageDataOptions{Name: "file", Data: data, Id: id},
+		))
+		ids = append(ids, id)
+		expected = append(expected, data...)
+	}
+
+	err := s.gcsStorage.CommitData(internal.CommitDataOptions{Name: "file", List: ids})
+	s.assert.NoError(err)
+	s.assert.Equal(expected, s.fake.getObject(testBucket, "file").data)
+	// intermediate objects are removed
+	s.assert.Equal([]string{"file"}, s.fake.keys(testBucket))
+}
+
+func (s *gcsStorageTestSuite) TestCommitDataEmpty() {
+	err := s.gcsStorage.CommitData(internal.CommitDataOptions{Name: "file", List: []string{}})
+	s.assert.NoError(err)
+	obj := s.fake.getObject(testBucket, "file")
+	s.Require().NotNil(obj)
+	s.assert.Empty(obj.data)
+}
+
+func (s *gcsStorageTestSuite) TestSymlink() {
+	err := s.gcsStorage.CreateLink(internal.CreateLinkOptions{Name: "link", Target: "target"})
+	s.assert.Equal(syscall.ENOTSUP, err)
+
+	config.ResetConfig()
+	_ = s.gcsStorage.Stop()
+	s.setupTestHelper("attr_cache:\n  enable-symlinks: true\n")
+
+	err = s.gcsStorage.CreateLink(internal.CreateLinkOptions{Name: "link", Target: "target"})
+	s.assert.NoError(err)
+
+	attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "link"})
+	s.assert.NoError(err)
+	s.assert.True(attr.IsSymlink())
+
+	target, err := s.gcsStorage.ReadLink(internal.ReadLinkOptions{Name: "link"})
+	s.assert.NoError(err)
+	s.assert.Equal("target", target)
+}
+
+func (s *gcsStorageTestSuite) TestMetadata() {
+	s.fake.putObject(testBucket, "file", []byte("data"), map[string]*string{
+		"label":    new("a"),
+		symlinkKey: new("false"),
+	})
+
+	metadata, err := s.gcsStorage.GetMetadata(internal.GetMetadataOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+
+	err = s.gcsStorage.SetMetadata(internal.SetMetadataOptions{
+		Name:     "file",
+		Metadata: map[string]*string{"label": nil, "owner": new("b")},
+	})
+	s.assert.NoError(err)
+	obj := s.fake.getObject(testBucket, "file")
+	s.assert.Equal(map[string]*string{"owner": new("b"), symlinkKey: new("false")}, obj.metadata)
+	s.assert.Equal([]byte("data"), obj.data)
+
+	// internal keys can not be changed
+	err = s.gcsStorage.SetMetadata(internal.SetMetadataOptions{
+		Name:     "file",
+		Metadata: map[string]*string{symlinkKey: new("true")},
+	})
+	s.assert.Equal(syscall.EINVAL, err)
+
+	_, err = s.gcsStorage.GetMetadata(internal.GetMetadataOptions{Name: "missing"})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *gcsStorageTestSuite) TestMetadataDir() {
+	s.Require().NoError(s.gcsStorage.CreateDir(internal.CreateDirOptions{Name: "dir"}))
+	err := s.gcsStorage.SetMetadata(internal.SetMetadataOptions{
+		Name:     "dir",
+		Metadata: map[string]*string{"label": new("a")},
+	})
+	s.assert.NoError(err)
+	metadata, err := s.gcsStorage.GetMetadata(internal.GetMetadataOptions{Name: "dir"})
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+
+	// a directory without a marker has no metadata and nowhere to store it
+	s.fake.putObject(testBucket, "implicit/file", []byte("data"), nil)
+	metadata, err = s.gcsStorage.GetMetadata(internal.GetMetadataOptions{Name: "implicit"})
+	s.assert.NoError(err)
+	s.assert.Empty(metadata)
+	err = s.gcsStorage.SetMetadata(internal.SetMetadataOptions{
+		Name:     "implicit",
+		Metadata: map[string]*string{"label": new("a")},
+	})
+	s.assert.Equal(syscall.ENOTSUP, err)
+}
+
+func (s *gcsStorageTestSuite) TestUtimens() {
+	s.fake.putObject(testBucket, "file", []byte("data"), map[string]*string{"label": new("a")})
+
+	atime := time.Date(2019, time.January, 2, 3, 4, 5, 6, time.UTC)
+	mtime := time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC)
+	err := s.gcsStorage.Utimens(internal.UtimensOptions{Name: "file", Atime: atime, Mtime: mtime})
+	s.assert.NoError(err)
+
+	attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.True(mtime.Equal(attr.Mtime))
+	entries, _, err := s.gcsStorage.StreamDir(internal.StreamDirOptions{Name: "/"})
+	s.assert.NoError(err)
+	s.Require().Len(entries, 1)
+	s.assert.True(mtime.Equal(entries[0].Mtime))
+
+	// the times are not user metadata
+	metadata, err := s.gcsStorage.GetMetadata(internal.GetMetadataOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+	err = s.gcsStorage.SetMetadata(internal.SetMetadataOptions{
+		Name:     "file",
+		Metadata: map[string]*string{internal.MtimeMetadataKey: new("0")},
+	})
+	s.assert.Equal(syscall.EINVAL, err)
+
+	// the file is written and flushed again, which keeps the access time
+	f, err := os.CreateTemp("", "gcs-test-*")
+	s.Require().NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write([]byte("new data"))
+	s.Require().NoError(err)
+	newMtime := mtime.Add(time.Hour)
+	err = s.gcsStorage.CopyFromFile(internal.CopyFromFileOptions{
+		Name: "file",
+		File: f,
+		Metadata: map[string]*string{
+			internal.MtimeMetadataKey: new(internal.FormatMetadataTime(newMtime)),
+		},
+	})
+	s.assert.NoError(err)
+	attr, err = s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "file"})
+	s.assert.NoError(err)
+	s.assert.True(atime.Equal(attr.Atime))
+	s.assert.True(newMtime.Equal(attr.Mtime))
+
+	// a directory without a marker has nowhere to keep the times
+	s.fake.putObject(testBucket, "implicit/file", []byte("data"), nil)
+	err = s.gcsStorage.Utimens(internal.UtimensOptions{Name: "implicit", Mtime: mtime})
+	s.assert.NoError(err)
+
+	err = s.gcsStorage.Utimens(internal.UtimensOptions{Name: "missing", Mtime: mtime})
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *gcsStorageTestSuite) TestSubdirectory() {
+	config.ResetConfig()
+	_ = s.gcsStorage.Stop()
+	s.setupTestHelper("  subdirectory: /prefix\n")
+	s.fake.putObject(testBucket, "prefix/dir/file", []byte("data"), nil)
+	s.fake.putObject(testBucket, "outside", []byte("data"), nil)
+
+	entries, _, err := s.gcsStorage.StreamDir(internal.StreamDirOptions{Name: "/"})
+	s.assert.NoError(err)
+	s.Require().Len(entries, 1)
+	s.assert.Equal("dir", entries[0].Path)
+
+	attr, err := s.gcsStorage.GetAttr(internal.GetAttrOptions{Name: "dir/file"})
+	s.assert.NoError(err)
+	s.assert.Equal("dir/file", attr.Path)
+
+	_, err = s.gcsStorage.CreateFile(internal.CreateFileOptions{Name: "new"})
+	s.assert.NoError(err)
+	s.assert.NotNil(s.fake.getObject(testBucket, "prefix/new"))
+}
+
+func (s *gcsStorageTestSuite) TestStatFs() {
+	s.fake.putObject(testBucket, "a", randomBytes(4096), nil)
+	s.fake.putObject(testBucket, "dir/b", randomBytes(8192), nil)
+
+	stat, populated, err := s.gcsStorage.StatFs()
+	s.assert.NoError(err)
+	s.assert.True(populated)
+	s.assert.EqualValues(3, stat.Blocks)
+	s.assert.EqualValues(blockSize, stat.Bsize)
+	s.assert.EqualValues(0, stat.Bavail)
+
+	s.gcsStorage.stConfig.disableUsage = true
+	stat, populated, err = s.gcsStorage.StatFs()
+	s.assert.NoError(err)
+	s.assert.False(populated)
+	s.assert.Nil(stat)
+}
+
+func TestGcsStorage(t *testing.T) {
+	suite.Run(t, new(gcsStorageTestSuite))
+}
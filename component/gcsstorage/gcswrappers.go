@@ -0,0 +1,771 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	json "encoding/json/v2"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/convertname"
+)
+
+// objectResource is the object representation used by the GCS JSON API
+type objectResource struct {
+	Name           string             `json:"name,omitempty"`
+	Bucket         string             `json:"bucket,omitempty"`
+	Generation     int64              `json:"generation,string,omitzero"`
+	ContentType    string             `json:"contentType,omitempty"`
+	Size           int64              `json:"size,string,omitzero"`
+	MD5Hash        string             `json:"md5Hash,omitempty"`
+	ETag           string             `json:"etag,omitempty"`
+	Updated        time.Time          `json:"updated,omitzero"`
+	TimeCreated    time.Time          `json:"timeCreated,omitzero"`
+	StorageClass   string             `json:"storageClass,omitempty"`
+	ComponentCount int                `json:"componentCount,omitzero"`
+	Metadata       map[string]*string `json:"metadata,omitempty"`
+}
+
+type listObjectsResponse struct {
+	Items         []*objectResource `json:"items"`
+	Prefixes      []string          `json:"prefixes"`
+	NextPageToken string            `json:"nextPageToken"`
+}
+
+type bucketResource struct {
+	Name string `json:"name"`
+}
+
+type listBucketsResponse struct {
+	Items         []bucketResource `json:"items"`
+	NextPageToken string           `json:"nextPageToken"`
+}
+
+type rewriteResponse struct {
+	Done         bool            `json:"done"`
+	RewriteToken string          `json:"rewriteToken"`
+	Resource     *objectResource `json:"resource"`
+}
+
+type composeSource struct {
+	Name string `json:"name"`
+}
+
+type composeRequest struct {
+	SourceObjects []composeSource `json:"sourceObjects"`
+	Destination   *objectResource `json:"destination"`
+}
+
+type getObjectOptions struct {
+	name   string
+	offset int64
+	count  int64
+	isDir  bool
+}
+
+type putObjectOptions struct {
+	name       string
+	objectData io.ReaderAt
+	size       int64
+	metadata   map[string]*string
+	isDir      bool
+	// ifGenerationMatch uploads only if the object has this generation, 0 if it does not exist
+	ifGenerationMatch *int64
+}
+
+type listObjectsOptions struct {
+	prefix     string
+	delimiter  string
+	pageToken  string
+	maxResults int32
+	fields     string
+}
+
+// gcsRequest describes a single call to the JSON API
+type gcsRequest struct {
+	method string
+	path   string
+	query  url.Values
+	header http.Header
+	// body is called once per attempt so that the request can be retried
+	body func() io.Reader
+	size int64
+}
+
+const symlinkKey = "is_symlink"
+const maxResultsPerListCall = 1000
+
+// stagingDir holds blocks staged by StageBlock until they are composed into their object
+const stagingDir = ".cloudfuse-staging"
+
+// do sends a request to GCS, retrying transient failures with an exponential backoff.
+// On success the caller owns the response body.
+func (cl *Client) do(ctx context.Context, req gcsRequest) (*http.Response, error) {
+	endpoint := cl.Config.AuthConfig.Endpoint + req.path
+	if len(req.query) > 0 {
+		endpoint += "?" + req.query.Encode()
+	}
+
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		var body io.Reader
+		if req.body != nil {
+			body = req.body()
+		}
+		httpReq, err := http.NewRequestWithContext(ctx, req.method, endpoint, body)
+		if err != nil {
+			return nil, err
+		}
+		for key, values := range req.header {
+			httpReq.Header[key] = values
+		}
+		httpReq.Header.Set("User-Agent", UserAgent())
+		if req.body != nil {
+			httpReq.ContentLength = req.size
+		}
+
+		resp, err := cl.httpClient.Do(httpReq)
+		if err == nil && resp.StatusCode >= http.StatusBadRequest {
+			err = newGcsError(resp)
+			resp.Body.Close()
+		}
+		if err == nil {
+			return resp, nil
+		}
+
+		if attempt >= cl.Config.maxRetries || !isRetryable(err) {
+			return nil, err
+		}
+		log.Debug("Client::do : %s %s failed, retrying in %v [%v]", req.method, req.path, backoff, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// doJSON sends a request and decodes the JSON response into out (when it is not nil)
+func (cl *Client) doJSON(ctx context.Context, req gcsRequest, out any) error {
+	resp, err := cl.do(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.UnmarshalRead(resp.Body, out)
+}
+
+// jsonBody marshals v and returns a body usable by gcsRequest
+func jsonBody(v any) (func() io.Reader, int64, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, 0, err
+	}
+	return func() io.Reader { return bytes.NewReader(data) }, int64(len(data)), nil
+}
+
+func bucketPath(bucket string) string {
+	return "/storage/v1/b/" + url.PathEscape(bucket)
+}
+
+func objectPath(bucket string, key string) string {
+	return bucketPath(bucket) + "/o/" + url.PathEscape(key)
+}
+
+// check the connection to the GCS service by getting the bucket.
+func (cl *Client) ConnectionOkay(ctx context.Context) error {
+	log.Trace("Client::ConnectionOkay : checking connection to GCS service")
+	err := cl.getBucket(ctx, cl.Config.AuthConfig.BucketName)
+	return parseGcsErr(err, "GetBucket "+cl.Config.AuthConfig.BucketName)
+}
+
+// Wrapper for buckets.get
+func (cl *Client) getBucket(ctx context.Context, bucketName string) error {
+	return cl.doJSON(ctx, gcsRequest{method: http.MethodGet, path: bucketPath(bucketName)}, nil)
+}
+
+// Wrapper for buckets.list. This requires a project ID.
+func (cl *Client) ListBuckets(ctx context.Context) ([]string, error) {
+	log.Trace("Client::ListBuckets : Listing buckets")
+
+	bucketList := make([]string, 0)
+	if cl.Config.AuthConfig.ProjectID == "" {
+		return bucketList, errNoProjectID
+	}
+
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("project", cl.Config.AuthConfig.ProjectID)
+		query.Set("fields", "items(name),nextPageToken")
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		result := listBucketsResponse{}
+		err := cl.doJSON(
+			ctx,
+			gcsRequest{method: http.MethodGet, path: "/storage/v1/b", query: query},
+			&result,
+		)
+		if err != nil {
+			log.Err("Client::ListBuckets : Failed to list buckets. Here's why: %v", err)
+			return bucketList, parseGcsErr(err, "list buckets")
+		}
+		for _, bucket := range result.Items {
+			bucketList = append(bucketList, bucket.Name)
+		}
+		if result.NextPageToken == "" {
+			break
+		}
+		pageToken = result.NextPageToken
+	}
+
+	return bucketList, nil
+}
+
+// Wrapper for objects.get with alt=media.
+// Set count = 0 to read to the end of the object.
+// name is the path to the file.
+func (cl *Client) getObject(ctx context.Context, options getObjectOptions) (io.ReadCloser, error) {
+	key := cl.getKey(options.name, options.isDir)
+	log.Trace("Client::getObject : get object %s (%d+%d)", key, options.offset, options.count)
+
+	header := http.Header{}
+	if options.count != 0 {
+		endRange := options.offset + options.count - 1
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", options.offset, endRange))
+	} else if options.offset != 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", options.offset))
+	}
+
+	resp, err := cl.do(ctx, gcsRequest{
+		method: http.MethodGet,
+		path:   objectPath(cl.Config.AuthConfig.BucketName, key),
+		query:  url.Values{"alt": []string{"media"}},
+		header: header,
+	})
+	if err != nil {
+		attemptedAction := fmt.Sprintf("download object %s", key)
+		return nil, parseGcsErr(err, attemptedAction)
+	}
+
+	return resp.Body, nil
+}
+
+// Wrapper for objects.get, which returns the object resource (attributes) of an object.
+// name is the path to the file.
+func (cl *Client) getObjectResource(
+	ctx context.Context,
+	name string,
+	isDir bool,
+) (*objectResource, error) {
+	key := cl.getKey(name, isDir)
+	log.Trace("Client::getObjectResource : object %s", key)
+
+	result := &objectResource{}
+	err := cl.doJSON(
+		ctx,
+		gcsRequest{method: http.MethodGet, path: objectPath(cl.Config.AuthConfig.BucketName, key)},
+		result,
+	)
+	if err != nil {
+		// Make sure the attempted action starts with "GetObject", or else parseGcsErr will log to Err
+		attemptedAction := fmt.Sprintf("GetObject(%s)", key)
+		return nil, parseGcsErr(err, attemptedAction)
+	}
+	return result, nil
+}
+
+// Wrapper for objects.patch that updates the metadata of an object.
+// Keys with a nil value are sent as null, which removes them. Other keys are left unchanged.
+func (cl *Client) patchObjectMetadata(
+	ctx context.Context,
+	name string,
+	isDir bool,
+	metadata map[string]*string,
+) error {
+	key := cl.getKey(name, isDir)
+	log.Trace("Client::patchObjectMetadata : object %s", key)
+
+	body, bodySize, err := jsonBody(&objectResource{Metadata: metadata})
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	query.Set("fields", "name")
+	err = cl.doJSON(ctx, gcsRequest{
+		method: http.MethodPatch,
+		path:   objectPath(cl.Config.AuthConfig.BucketName, key),
+		query:  query,
+		header: http.Header{"Content-Type": []string{"application/json"}},
+		body:   body,
+		size:   bodySize,
+	}, nil)
+	if err != nil {
+		attemptedAction := fmt.Sprintf("patch metadata of %s", key)
+		return parseGcsErr(err, attemptedAction)
+	}
+	return nil
+}
+
+// Wrapper for a multipart objects.insert.
+// The object data and its metadata are sent in a single request.
+func (cl *Client) putObject(
+	ctx context.Context,
+	options putObjectOptions,
+) (*objectResource, error) {
+	key := cl.getKey(options.name, options.isDir)
+	return cl.insertObject(ctx, key, options)
+}
+
+// insertObject uploads object data to the given key
+func (cl *Client) insertObject(
+	ctx context.Context,
+	key string,
+	options putObjectOptions,
+) (*objectResource, error) {
+	log.Trace("Client::insertObject : putting object %s", key)
+
+	if len(key) > maxObjectNameLength {
+		log.Err("Client::insertObject : object name %s is too long", key)
+		return nil, syscall.ENAMETOOLONG
+	}
+
+	contentType := getContentType(key)
+	metadata, err := json.Marshal(&objectResource{
+		Name:        key,
+		ContentType: contentType,
+		Metadata:    options.metadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	boundary := randomBoundary()
+	head := []byte("--" + boundary + "\r\nContent-Type: application/json; charset=UTF-8\r\n\r\n" +
+		string(metadata) + "\r\n--" + boundary + "\r\nContent-Type: " + contentType + "\r\n\r\n")
+	tail := []byte("\r\n--" + boundary + "--\r\n")
+
+	data := options.objectData
+	if data == nil {
+		data = bytes.NewReader([]byte{})
+		options.size = 0
+	}
+
+	query := url.Values{}
+	query.Set("uploadType", "multipart")
+	if options.ifGenerationMatch != nil {
+		query.Set("ifGenerationMatch", strconv.FormatInt(*options.ifGenerationMatch, 10))
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "multipart/related; boundary="+boundary)
+
+	result := &objectResource{}
+	err = cl.doJSON(ctx, gcsRequest{
+		method: http.MethodPost,
+		path:   "/upload" + bucketPath(cl.Config.AuthConfig.BucketName) + "/o",
+		query:  query,
+		header: header,
+		body: func() io.Reader {
+			return io.MultiReader(
+				bytes.NewReader(head),
+				io.NewSectionReader(data, 0, options.size),
+				bytes.NewReader(tail),
+			)
+		},
+		size: int64(len(head)) + options.size + int64(len(tail)),
+	}, result)
+
+	attemptedAction := fmt.Sprintf("upload object %s", key)
+	return result, parseGcsErr(err, attemptedAction)
+}
+
+func randomBoundary() string {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Wrapper for objects.delete.
+// name is the path to the file.
+func (cl *Client) deleteObject(ctx context.Context, name string, isDir bool) error {
+	key := cl.getKey(name, isDir)
+	return cl.deleteKey(ctx, key)
+}
+
+// deleteKey deletes the object with the given key
+func (cl *Client) deleteKey(ctx context.Context, key string) error {
+	log.Trace("Client::deleteKey : deleting object %s", key)
+
+	err := cl.doJSON(
+		ctx,
+		gcsRequest{method: http.MethodDelete, path: objectPath(cl.Config.AuthConfig.BucketName, key)},
+		nil,
+	)
+
+	attemptedAction := fmt.Sprintf("delete object %s", key)
+	return parseGcsErr(err, attemptedAction)
+}
+
+// Wrapper for objects.rewrite. Rewrite is a server side copy that may take several calls
+// to complete for large objects. The metadata of the source object is preserved.
+func (cl *Client) copyObject(ctx context.Context, source string, target string, isDir bool) error {
+	sourceKey := cl.getKey(source, isDir)
+	targetKey := cl.getKey(target, isDir)
+	log.Trace("Client::copyObject : %s -> %s", sourceKey, targetKey)
+
+	if len(targetKey) > maxObjectNameLength {
+		log.Err("Client::copyObject : object name %s is too long", targetKey)
+		return syscall.ENAMETOOLONG
+	}
+
+	bucket := cl.Config.AuthConfig.BucketName
+	body, bodySize, err := jsonBody(struct{}{})
+	if err != nil {
+		return err
+	}
+
+	rewriteToken := ""
+	for {
+		query := url.Values{}
+		query.Set("fields", "done,rewriteToken")
+		if rewriteToken != "" {
+			query.Set("rewriteToken", rewriteToken)
+		}
+		result := rewriteResponse{}
+		err = cl.doJSON(ctx, gcsRequest{
+			method: http.MethodPost,
+			path:   objectPath(bucket, sourceKey) + "/rewriteTo/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(targetKey),
+			query:  query,
+			header: http.Header{"Content-Type": []string{"application/json"}},
+			body:   body,
+			size:   bodySize,
+		}, &result)
+		if err != nil {
+			attemptedAction := fmt.Sprintf("copy %s to %s", sourceKey, targetKey)
+			return parseGcsErr(err, attemptedAction)
+		}
+		if result.Done {
+			return nil
+		}
+		rewriteToken = result.RewriteToken
+	}
+}
+
+func (cl *Client) renameObject(ctx context.Context, source string, target string, isDir bool) error {
+	err := cl.copyObject(ctx, source, target, isDir)
+	if err != nil {
+		log.Err(
+			"Client::renameObject : copyObject(%s->%s) failed. Here's why: %v",
+			source,
+			target,
+			err,
+		)
+		return err
+	}
+	// Copy of the file is done so now delete the older file
+	err = cl.deleteObject(ctx, source, isDir)
+	if err != nil {
+		log.Err(
+			"Client::renameObject : deleteObject(%s) failed. Here's why: %v",
+			source,
+			err,
+		)
+	}
+
+	return err
+}
+
+// Wrapper for objects.compose. GCS accepts at most 32 sources per call,
+// so longer lists are composed in rounds through intermediate objects.
+// The intermediate objects are deleted before returning.
+func (cl *Client) composeObject(
+	ctx context.Context,
+	targetKey string,
+	sourceKeys []string,
+	metadata map[string]*string,
+) (*objectResource, error) {
+	log.Trace("Client::composeObject : %s from %d sources", targetKey, len(sourceKeys))
+
+	intermediates := make([]string, 0)
+	defer func() {
+		for _, key := range intermediates {
+			_ = cl.deleteKey(context.WithoutCancel(ctx), key)
+		}
+	}()
+
+	round := 0
+	for len(sourceKeys) > maxComposeSources {
+		nextRound := make([]string, 0, (len(sourceKeys)+maxComposeSources-1)/maxComposeSources)
+		for chunk := range slices.Chunk(sourceKeys, maxComposeSources) {
+			intermediateKey := cl.getKey(path.Join(
+				stagingDir,
+				"compose",
+				fmt.Sprintf("%s.%d.%d", randomBoundary(), round, len(nextRound)),
+			), false)
+			_, err := cl.composeKeys(ctx, intermediateKey, chunk, nil)
+			if err != nil {
+				return nil, err
+			}
+			intermediates = append(intermediates, intermediateKey)
+			nextRound = append(nextRound, intermediateKey)
+		}
+		sourceKeys = nextRound
+		round++
+	}
+
+	return cl.composeKeys(ctx, targetKey, sourceKeys, metadata)
+}
+
+// composeKeys sends a single compose request
+func (cl *Client) composeKeys(
+	ctx context.Context,
+	targetKey string,
+	sourceKeys []string,
+	metadata map[string]*string,
+) (*objectResource, error) {
+	request := composeRequest{
+		SourceObjects: make([]composeSource, 0, len(sourceKeys)),
+		Destination: &objectResource{
+			ContentType: getContentType(targetKey),
+			Metadata:    metadata,
+		},
+	}
+	for _, key := range sourceKeys {
+		request.SourceObjects = append(request.SourceObjects, composeSource{Name: key})
+	}
+	body, bodySize, err := jsonBody(request)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &objectResource{}
+	err = cl.doJSON(ctx, gcsRequest{
+		method: http.MethodPost,
+		path:   objectPath(cl.Config.AuthConfig.BucketName, targetKey) + "/compose",
+		header: http.Header{"Content-Type": []string{"application/json"}},
+		body:   body,
+		size:   bodySize,
+	}, result)
+	if err != nil {
+		attemptedAction := fmt.Sprintf("compose %d objects into %s", len(sourceKeys), targetKey)
+		return nil, parseGcsErr(err, attemptedAction)
+	}
+	return result, nil
+}
+
+// Wrapper for objects.list
+func (cl *Client) listObjects(
+	ctx context.Context,
+	options listObjectsOptions,
+) (*listObjectsResponse, error) {
+	query := url.Values{}
+	query.Set("prefix", options.prefix)
+	if options.delimiter != "" {
+		query.Set("delimiter", options.delimiter)
+	}
+	if options.pageToken != "" {
+		query.Set("pageToken", options.pageToken)
+	}
+	if options.maxResults > 0 {
+		query.Set("maxResults", strconv.Itoa(int(options.maxResults)))
+	}
+	if options.fields != "" {
+		query.Set("fields", options.fields)
+	}
+
+	result := &listObjectsResponse{}
+	err := cl.doJSON(ctx, gcsRequest{
+		method: http.MethodGet,
+		path:   bucketPath(cl.Config.AuthConfig.BucketName) + "/o",
+		query:  query,
+	}, result)
+	if err != nil {
+		attemptedAction := fmt.Sprintf(
+			"list objects in bucket %v with prefix %v",
+			cl.Config.AuthConfig.BucketName,
+			options.prefix,
+		)
+		return nil, parseGcsErr(err, attemptedAction)
+	}
+	return result, nil
+}
+
+// List : Get a list of objects matching the given prefix, up to the next "/", similar to listing a directory.
+// For predictable results, include the trailing slash in the prefix.
+// This fetches the list using a marker so the caller code should handle marker logic.
+// If count=0 - fetch max entries.
+// the *string being returned is the token / marker and will be nil when the listing is complete.
+func (cl *Client) List(
+	ctx context.Context,
+	prefix string,
+	marker *string,
+	count int32,
+) ([]*internal.ObjAttr, *string, error) {
+	log.Trace("Client::List : prefix %s, count %d", prefix, count)
+
+	if count == 0 {
+		count = maxResultsPerListCall
+	}
+
+	// combine the configured prefix and the prefix being given to List to get a full listPath
+	listPath := cl.getKey(prefix, false)
+	// replace any trailing forward slash stripped by common.JoinUnixFilepath
+	if strings.HasSuffix(prefix, "/") || (prefix == "" && cl.Config.prefixPath != "") {
+		listPath += "/"
+	}
+
+	pageToken := ""
+	if marker != nil {
+		pageToken = *marker
+	}
+	output, err := cl.listObjects(ctx, listObjectsOptions{
+		prefix:     listPath,
+		delimiter:  "/",
+		pageToken:  pageToken,
+		maxResults: count,
+	})
+	objectAttrList := make([]*internal.ObjAttr, 0)
+	if err != nil {
+		return objectAttrList, nil, err
+	}
+
+	var nextMarker *string
+	if output.NextPageToken != "" {
+		nextMarker = &output.NextPageToken
+	}
+
+	stagingPath := internal.ExtendDirName(cl.getKey(stagingDir, false))
+	for _, item := range output.Items {
+		// skip the directory marker of the directory being listed
+		if item.Name == listPath {
+			continue
+		}
+		objectAttrList = append(objectAttrList, cl.createObjAttr(item))
+	}
+	for _, dir := range output.Prefixes {
+		if dir == stagingPath {
+			continue
+		}
+		dirName := cl.getFile(dir)
+		attr := internal.CreateObjAttrDir(split(cl.Config.prefixPath, dirName))
+		objectAttrList = append(objectAttrList, attr)
+	}
+
+	// values should be returned in ascending order by key
+	slices.SortFunc(objectAttrList, func(a, b *internal.ObjAttr) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	log.Debug("Client::List : %s returning %d entries", prefix, len(objectAttrList))
+
+	return objectAttrList, nextMarker, nil
+}
+
+// createObjAttr creates an object attributes struct from an object resource
+func (cl *Client) createObjAttr(object *objectResource) *internal.ObjAttr {
+	name := cl.getFile(object.Name)
+	objectPath := split(cl.Config.prefixPath, name)
+	var attr *internal.ObjAttr
+	if strings.HasSuffix(object.Name, "/") {
+		attr = internal.CreateObjAttrDir(objectPath)
+	} else {
+		attr = internal.CreateObjAttr(objectPath, object.Size, object.Updated)
+		if !object.TimeCreated.IsZero() {
+			attr.Crtime = object.TimeCreated
+		}
+		attr.ETag = object.ETag
+		if object.MD5Hash != "" {
+			md5, err := base64.StdEncoding.DecodeString(object.MD5Hash)
+			if err == nil {
+				attr.MD5 = md5
+			}
+		}
+	}
+	for key, value := range object.Metadata {
+		attr.Metadata[key] = value
+	}
+	if !attr.IsDir() && !cl.Config.disableSymlink && isSymlink(object.Metadata) {
+		attr.Flags.Set(internal.PropFlagSymlink)
+	}
+	// times set with utimens take precedence over the update time
+	attr.SetTimesFromMetadata()
+
+	return attr
+}
+
+// isSymlink returns true if the symlink flag is set in the metadata map, false otherwise.
+func isSymlink(metadata map[string]*string) bool {
+	sym, ok := metadata[symlinkKey]
+	return ok && sym != nil && *sym == "true"
+}
+
+// getKey converts a file name to an object name. If it is set to convert names from
+// Linux to Windows then it allows special characters like "*:<>?| to be displayed on Windows.
+func (cl *Client) getKey(name string, isDir bool) string {
+	name = common.JoinUnixFilepath(cl.Config.prefixPath, name)
+	if runtime.GOOS == "windows" && cl.Config.restrictedCharsWin {
+		name = convertname.WindowsFileToCloud(name)
+	}
+
+	// Directory markers in GCS end in a trailing slash
+	if isDir {
+		name = internal.ExtendDirName(name)
+	}
+	return name
+}
+
+// getFile converts an object name to a file name. If it is set to
+// convert names from Linux to Windows then it converts special ASCII characters back to the
+// original special characters.
+func (cl *Client) getFile(name string) string {
+	if runtime.GOOS == "windows" && cl.Config.restrictedCharsWin {
+		name = convertname.WindowsCloudToFile(name)
+	}
+	return name
+}
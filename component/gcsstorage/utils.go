@@ -0,0 +1,339 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"context"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+)
+
+var UserAgent = func() string {
+	return "Seagate-Cloudfuse/" + common.CloudfuseVersion + " (Language=Go)"
+}
+
+const (
+	DefaultEndpoint   = "https://storage.googleapis.com"
+	DefaultMaxRetries = 3
+
+	// GCS allows at most 32 source objects in a single compose request
+	maxComposeSources = 32
+	// GCS object names can be at most 1024 bytes long
+	maxObjectNameLength = 1024
+
+	retryBackoff    = 500 * time.Millisecond
+	maxRetryBackoff = 8 * time.Second
+
+	Timeout             time.Duration = 30 * time.Second
+	KeepAlive           time.Duration = 30 * time.Second
+	MaxIdleConnsPerHost int           = 200
+	IdleConnTimeout     time.Duration = 90 * time.Second
+	TLSHandshakeTimeout time.Duration = 10 * time.Second
+)
+
+// ----------- Cloud Storage error code handling ---------------
+
+// gcsError is the error body returned by the GCS JSON API
+type gcsError struct {
+	StatusCode int    `json:"code"`
+	Message    string `json:"message"`
+	Errors     []struct {
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *gcsError) Error() string {
+	return fmt.Sprintf("googleapi: Error %d: %s, %s", e.StatusCode, e.Message, e.Reason())
+}
+
+// Reason returns the reason of the first error detail, if any
+func (e *gcsError) Reason() string {
+	if len(e.Errors) > 0 {
+		return e.Errors[0].Reason
+	}
+	return ""
+}
+
+// newGcsError builds a gcsError from an unsuccessful HTTP response
+func newGcsError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	apiErr := &gcsError{}
+	wrapper := struct {
+		Error *gcsError `json:"error"`
+	}{Error: apiErr}
+	if err := json.Unmarshal(body, &wrapper); err != nil || wrapper.Error == nil {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	apiErr.StatusCode = resp.StatusCode
+	return apiErr
+}
+
+// isRetryable returns true when the request may succeed if attempted again
+func isRetryable(err error) bool {
+	if apiErr, ok := errors.AsType[*gcsError](err); ok {
+		return apiErr.StatusCode == http.StatusTooManyRequests ||
+			apiErr.StatusCode == http.StatusRequestTimeout ||
+			apiErr.StatusCode >= http.StatusInternalServerError
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	_, isNetErr := errors.AsType[net.Error](err)
+	return isNetErr || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isUnreachable returns true when the error means GCS could not be reached at all
+func isUnreachable(err error) bool {
+	if _, ok := errors.AsType[*gcsError](err); ok {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	_, isNetErr := errors.AsType[net.Error](err)
+	return isNetErr
+}
+
+// This takes an err from a GCS API call, parses the error,
+// prints a helpful error message, and returns the corresponding system error code.
+// attemptedAction describes the action that failed with this error.
+// This function uses the runtime library to look up the name of the function calling it,
+// so there's no need to include that in the attemptedAction.
+func parseGcsErr(err error, attemptedAction string) error {
+	// trivial case
+	if err == nil {
+		return nil
+	}
+
+	// get the name of the function that called this
+	functionName := ""
+	pc, _, _, ok := runtime.Caller(1)
+	if ok {
+		longFuncName := runtime.FuncForPC(pc).Name()
+		funcNameParts := strings.Split(longFuncName, compName)
+		if len(funcNameParts) > 1 {
+			functionName = strings.Trim(funcNameParts[1], ".")
+		}
+	}
+
+	if apiErr, ok := errors.AsType[*gcsError](err); ok {
+		switch apiErr.StatusCode {
+		case http.StatusNotFound:
+			message := fmt.Sprintf(
+				"%s : Failed to %s with error %d because object does not exist",
+				functionName,
+				attemptedAction,
+				apiErr.StatusCode,
+			)
+			if strings.HasPrefix(attemptedAction, "GetObject") {
+				log.Warn("%s", message)
+			} else {
+				log.Err("%s", message)
+			}
+			return syscall.ENOENT
+		case http.StatusPreconditionFailed:
+			log.Warn(
+				"%s : Failed to %s with error %d because the object was changed",
+				functionName,
+				attemptedAction,
+				apiErr.StatusCode,
+			)
+			return common.NewConflictError(err)
+		case http.StatusForbidden, http.StatusUnauthorized:
+			log.Err(
+				"%s : Failed to %s with error %d because access was denied [%s]",
+				functionName,
+				attemptedAction,
+				apiErr.StatusCode,
+				apiErr.Message,
+			)
+			return syscall.EACCES
+		case http.StatusRequestedRangeNotSatisfiable:
+			log.Err(
+				"%s : Failed to %s with error %d because range is invalid",
+				functionName,
+				attemptedAction,
+				apiErr.StatusCode,
+			)
+			return err
+		}
+	}
+
+	if isUnreachable(err) {
+		log.Err(
+			"%s : Failed to %s because cloud storage is unreachable",
+			functionName,
+			attemptedAction,
+		)
+		return common.NewCloudUnreachableError(err)
+	}
+
+	// print and return the original error
+	log.Err("%s : Failed to %s. Here's why: %v", functionName, attemptedAction, err)
+	return err
+}
+
+//    ----------- Content-type handling  ---------------
+
+// ContentTypeMap : Store file extension to content-type mapping
+var ContentTypes = map[string]string{
+	".css":  "text/css",
+	".pdf":  "application/pdf",
+	".xml":  "text/xml",
+	".csv":  "text/csv",
+	".json": "application/json",
+	".rtf":  "application/rtf",
+	".txt":  "text/plain",
+	".java": "text/plain",
+	".dat":  "text/plain",
+
+	".htm":  "text/html",
+	".html": "text/html",
+
+	".gif":  "image/gif",
+	".jpeg": "image/jpeg",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+	".bmp":  "image/bmp",
+
+	".js":   "application/javascript",
+	".mjs":  "application/javascript",
+	".svg":  "image/svg+xml",
+	".wasm": "application/wasm",
+	".webp": "image/webp",
+
+	".wav":  "audio/wav",
+	".mp3":  "audio/mpeg",
+	".mpeg": "video/mpeg",
+	".aac":  "audio/aac",
+	".avi":  "video/x-msvideo",
+	".m3u8": "application/x-mpegURL",
+	".ts":   "video/MP2T",
+	".mid":  "audio/midiaudio/x-midi",
+	".3gp":  "video/3gpp",
+	".mp4":  "video/mp4",
+
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+
+	".gz":   "application/x-gzip",
+	".jar":  "application/java-archive",
+	".rar":  "application/vnd.rar",
+	".tar":  "application/x-tar",
+	".zip":  "application/x-zip-compressed",
+	".7z":   "application/x-7z-compressed",
+	".3g2":  "video/3gpp2",
+	".usdz": "application/zip",
+
+	".sh":  "application/x-sh",
+	".exe": "application/x-msdownload",
+	".dll": "application/x-msdownload",
+}
+
+// getContentType : Based on the file extension retrieve the content type to be set
+func getContentType(key string) string {
+	value, found := ContentTypes[strings.ToLower(filepath.Ext(key))]
+	if found {
+		return value
+	}
+	return "application/octet-stream"
+}
+
+// Strips the prefixPath from the path and returns the joined string
+func split(prefixPath string, path string) string {
+	if prefixPath == "" {
+		return path
+	}
+
+	// remove prefix's trailing slash too
+	prefixPath = internal.ExtendDirName(prefixPath)
+	if strings.HasPrefix(path, prefixPath) {
+		return strings.Replace(path, prefixPath, "", 1)
+	}
+
+	// prefix not found - return the path unaltered
+	return path
+}
+
+func removeLeadingSlashes(s string) string {
+	for strings.HasPrefix(s, "/") {
+		s = strings.TrimLeft(s, "/")
+	}
+	return s
+}
+
+// zeroReaderAt reads zeros, it is used to pad objects when truncating them upwards
+type zeroReaderAt struct{}
+
+func (zeroReaderAt) ReadAt(p []byte, _ int64) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// paddedReaderAt reads zeros for the first padding bytes followed by the wrapped data
+type paddedReaderAt struct {
+	data    io.ReaderAt
+	padding int64
+}
+
+func newPaddedReaderAt(data io.ReaderAt, padding int64) io.ReaderAt {
+	if padding == 0 {
+		return data
+	}
+	return &paddedReaderAt{data: data, padding: padding}
+}
+
+func (r *paddedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	if off < r.padding {
+		n = int(min(int64(len(p)), r.padding-off))
+		clear(p[:n])
+		off += int64(n)
+	}
+	if n == len(p) {
+		return n, nil
+	}
+	m, err := r.data.ReadAt(p[n:], off-r.padding)
+	return n + m, err
+}
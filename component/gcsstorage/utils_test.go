@@ -0,0 +1,170 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package gcsstorage
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/Seagate/cloudfuse/common"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type utilsTestSuite struct {
+	suite.Suite
+}
+
+func newTestResponse(code int, body string) *http.Response {
+	recorder := httptest.NewRecorder()
+	recorder.WriteHeader(code)
+	_, _ = recorder.WriteString(body)
+	return recorder.Result()
+}
+
+func (s *utilsTestSuite) TestNewGcsError() {
+	assert := assert.New(s.T())
+
+	err := newGcsError(newTestResponse(
+		http.StatusNotFound,
+		`{"error":{"code":404,"message":"No such object","errors":[{"reason":"notFound"}]}}`,
+	))
+	apiErr, ok := err.(*gcsError)
+	assert.True(ok)
+	assert.Equal(http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal("No such object", apiErr.Message)
+	assert.Equal("notFound", apiErr.Reason())
+
+	// errors that are not JSON keep the body as message
+	err = newGcsError(newTestResponse(http.StatusBadGateway, "bad gateway\n"))
+	apiErr, ok = err.(*gcsError)
+	assert.True(ok)
+	assert.Equal(http.StatusBadGateway, apiErr.StatusCode)
+	assert.Equal("bad gateway", apiErr.Message)
+}
+
+func (s *utilsTestSuite) TestParseGcsErr() {
+	assert := assert.New(s.T())
+
+	assert.NoError(parseGcsErr(nil, "GetObject(file)"))
+	assert.Equal(
+		syscall.ENOENT,
+		parseGcsErr(&gcsError{StatusCode: http.StatusNotFound}, "GetObject(file)"),
+	)
+	assert.Equal(
+		syscall.EACCES,
+		parseGcsErr(&gcsError{StatusCode: http.StatusForbidden}, "upload object file"),
+	)
+	assert.Equal(
+		syscall.EACCES,
+		parseGcsErr(&gcsError{StatusCode: http.StatusUnauthorized}, "upload object file"),
+	)
+
+	rangeErr := &gcsError{StatusCode: http.StatusRequestedRangeNotSatisfiable}
+	assert.Equal(rangeErr, parseGcsErr(rangeErr, "download object file"))
+
+	netErr := &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}
+	assert.ErrorIs(parseGcsErr(netErr, "list objects"), &common.CloudUnreachableError{})
+	assert.ErrorIs(
+		parseGcsErr(context.DeadlineExceeded, "list objects"),
+		&common.CloudUnreachableError{},
+	)
+}
+
+func (s *utilsTestSuite) TestIsRetryable() {
+	assert := assert.New(s.T())
+
+	assert.True(isRetryable(&gcsError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(isRetryable(&gcsError{StatusCode: http.StatusServiceUnavailable}))
+	assert.False(isRetryable(&gcsError{StatusCode: http.StatusNotFound}))
+	assert.False(isRetryable(&gcsError{StatusCode: http.StatusPreconditionFailed}))
+	assert.True(isRetryable(&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}))
+	assert.True(isRetryable(io.ErrUnexpectedEOF))
+	assert.False(isRetryable(context.Canceled))
+}
+
+func (s *utilsTestSuite) TestPaddedReaderAt() {
+	assert := assert.New(s.T())
+
+	reader := newPaddedReaderAt(strings.NewReader("data"), 3)
+	output, err := io.ReadAll(io.NewSectionReader(reader, 0, 7))
+	assert.NoError(err)
+	assert.Equal([]byte("\x00\x00\x00data"), output)
+
+	// reads that start in the padding and end in the data
+	buf := make([]byte, 3)
+	n, err := reader.ReadAt(buf, 2)
+	assert.NoError(err)
+	assert.Equal(3, n)
+	assert.Equal([]byte("\x00da"), buf)
+
+	// no padding returns the reader itself
+	data := strings.NewReader("data")
+	assert.Equal(io.ReaderAt(data), newPaddedReaderAt(data, 0))
+}
+
+func (s *utilsTestSuite) TestZeroReaderAt() {
+	assert := assert.New(s.T())
+
+	output, err := io.ReadAll(io.NewSectionReader(zeroReaderAt{}, 0, 5))
+	assert.NoError(err)
+	assert.Equal(make([]byte, 5), output)
+}
+
+func (s *utilsTestSuite) TestGetContentType() {
+	assert := assert.New(s.T())
+
+	assert.Equal("text/plain", getContentType("dir/file.txt"))
+	assert.Equal("application/json", getContentType("file.JSON"))
+	assert.Equal("application/octet-stream", getContentType("file"))
+}
+
+func (s *utilsTestSuite) TestSplit() {
+	assert := assert.New(s.T())
+
+	assert.Equal("dir/file", split("", "dir/file"))
+	assert.Equal("dir/file", split("prefix", "prefix/dir/file"))
+	assert.Equal("prefixed/file", split("", "prefixed/file"))
+}
+
+func (s *utilsTestSuite) TestRemoveLeadingSlashes() {
+	assert := assert.New(s.T())
+
+	assert.Equal("prefix", removeLeadingSlashes("//prefix"))
+	assert.Equal("prefix/", removeLeadingSlashes("prefix/"))
+	assert.Empty(removeLeadingSlashes("/"))
+}
+
+func TestUtilsTestSuite(t *testing.T) {
+	suite.Run(t, new(utilsTestSuite))
+}
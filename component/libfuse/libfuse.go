@@ -165,6 +165,25 @@ func (lf *Libfuse) Start(ctx context.Context) error {
 	// create stats collector for libfuse
 	libfuseStatsCollector = stats_manager.NewStatsCollector(lf.Name())
 	log.Debug("Starting libfuse stats collector")
+	libfuseStatsCollector.AddStats(
+		stats_manager.Counter,
+		createDir,
+		deleteDir,
+		renameDir,
+		deleteFile,
+		renameFile,
+		truncateFile,
+		createLink,
+		readLink,
+		syncFile,
+		syncDir,
+		chmod,
+		utimens,
+		setXattr,
+		removeXattr,
+	)
+	libfuseStatsCollector.AddStats(stats_manager.Gauge, openHandles)
+	libfuseStatsCollector.UpdateStats(stats_manager.Replace, openHandles, (int64)(0))
 
 	lf.lsFlags = internal.NewDirBitMap()
 	lf.lsFlags.Set(internal.PropFlagModeDefault)
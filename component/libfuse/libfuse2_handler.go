@@ -31,9 +31,11 @@ import (
 	"hash/fnv"
 	"io"
 	"io/fs"
+	"maps"
 	"os"
 	"path"
 	"runtime"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
@@ -148,6 +150,17 @@ func fileModeFromFuse(mode uint32) os.FileMode {
 	return fileMode
 }
 
+// timeFromFuse converts a fuse timespec, UTIME_OMIT becomes the zero time to leave it unchanged.
+func timeFromFuse(tmsp fuse.Timespec) time.Time {
+	switch tmsp.Nsec {
+	case fuse.UTIME_NOW:
+		return time.Now()
+	case fuse.UTIME_OMIT:
+		return time.Time{}
+	}
+	return tmsp.Time()
+}
+
 // initFuse passes the launch options for fuse and starts the mount.
 // Here are the options for FUSE.
 // LINK: https://man7.org/linux/man-pages/man8/mount.fuse3.8.html
@@ -233,6 +246,12 @@ func (lf *Libfuse) initFuse() error {
 				nameStorage = "azure"
 				log.Err("initFuse : Failed to unmarshal s3storage.bucket-name")
 			}
+		} else if config.IsSet("gcsstorage.bucket-name") {
+			err := config.UnmarshalKey("gcsstorage.bucket-name", &nameStorage)
+			if err != nil {
+				nameStorage = "gcs"
+				log.Err("initFuse : Failed to unmarshal gcsstorage.bucket-name")
+			}
 		}
 
 		volumePrefix := fmt.Sprintf("--VolumePrefix=\\%s\\%s", serverName, nameStorage)
@@ -875,8 +894,12 @@ func fuseErrnoFromError(err error) int {
 		return -fuse.ENOSYS
 	case errors.Is(err, syscall.EOPNOTSUPP):
 		return -fuse.EOPNOTSUPP
+	case errors.Is(err, syscall.ENOTSUP):
+		return -fuse.ENOTSUP
 	case errors.Is(err, syscall.EPERM):
 		return -fuse.EPERM
+	case errors.Is(err, &common.ArchivedError{}):
+		return -fuse.ENODATA
 	case errors.Is(err, fs.ErrNotExist):
 		return -fuse.ENOENT
 	case errors.Is(err, fs.ErrPermission):
@@ -1307,9 +1330,26 @@ func (cf *CgofuseFS) Utimens(path string, tmsp []fuse.Timespec) int {
 		return errno
 	}
 	log.Trace("Libfuse::Utimens : %s", name)
-	// TODO: is the conversion from [2]timespec to *timespec ok?
-	// TODO: Implement
-	// For now this returns 0 to allow touch to work correctly
+
+	options := internal.UtimensOptions{Name: name}
+	if len(tmsp) < 2 {
+		// no times given means both are set to now
+		options.Atime = time.Now()
+		options.Mtime = options.Atime
+	} else {
+		options.Atime = timeFromFuse(tmsp[0])
+		options.Mtime = timeFromFuse(tmsp[1])
+	}
+
+	err := fuseFS.NextComponent().Utimens(options)
+	if err != nil {
+		log.Err("Libfuse::Utimens : error in utimens of %s [%s]", name, err.Error())
+		return fuseErrnoFromError(err)
+	}
+
+	libfuseStatsCollector.PushEvents(utimens, name, nil)
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, utimens, (int64)(1))
+
 	return 0
 }
 
@@ -1318,9 +1358,44 @@ func (cf *CgofuseFS) Access(path string, mask uint32) int {
 	return -fuse.ENOSYS
 }
 
-// Getxattr  is not implemented.
+// Getxattr gets the value of an extended attribute from the object metadata.
 func (cf *CgofuseFS) Getxattr(path string, name string) (int, []byte) {
-	return -fuse.ENOSYS, nil
+	objName, errno := normalizeFusePath(path)
+	if errno != 0 {
+		return errno, nil
+	}
+	log.Trace("Libfuse::Getxattr : %s, %s", objName, name)
+
+	key, ok := strings.CutPrefix(name, xattrUserPrefix)
+	if !ok {
+		return -fuse.ENOATTR, nil
+	}
+
+	metadata, err := fuseFS.NextComponent().GetMetadata(internal.GetMetadataOptions{Name: objName})
+	if err != nil {
+		log.Err("Libfuse::Getxattr : error getting metadata of %s [%s]", objName, err.Error())
+		return fuseErrnoFromError(err), nil
+	}
+
+	value := lookupMetadata(metadata, key)
+	if value == nil {
+		return -fuse.ENOATTR, nil
+	}
+	return 0, []byte(*value)
+}
+
+// lookupMetadata finds key in the metadata, falling back to a case-insensitive match
+// since some cloud providers do not preserve the case of metadata keys.
+func lookupMetadata(metadata map[string]*string, key string) *string {
+	if value, ok := metadata[key]; ok {
+		return value
+	}
+	for k, value := range metadata {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return nil
 }
 
 // Link is not implemented.
@@ -1328,9 +1403,29 @@ func (cf *CgofuseFS) Link(oldpath string, newpath string) int {
 	return -fuse.ENOSYS
 }
 
-// Listxattr is not implemented.
+// Listxattr lists the extended attributes backed by the object metadata.
 func (cf *CgofuseFS) Listxattr(path string, fill func(name string) bool) int {
-	return -fuse.ENOSYS
+	objName, errno := normalizeFusePath(path)
+	if errno != 0 {
+		return errno
+	}
+	log.Trace("Libfuse::Listxattr : %s", objName)
+
+	metadata, err := fuseFS.NextComponent().GetMetadata(internal.GetMetadataOptions{Name: objName})
+	if err != nil {
+		log.Err("Libfuse::Listxattr : error getting metadata of %s [%s]", objName, err.Error())
+		return fuseErrnoFromError(err)
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		if metadata[key] == nil {
+			continue
+		}
+		if !fill(xattrUserPrefix + key) {
+			return -fuse.ERANGE
+		}
+	}
+	return 0
 }
 
 // Mknod is not implemented.
@@ -1338,14 +1433,86 @@ func (cf *CgofuseFS) Mknod(path string, mode uint32, dev uint64) int {
 	return -fuse.ENOSYS
 }
 
-// Removexattr is not implemented.
+// Removexattr removes an extended attribute from the object metadata.
 func (cf *CgofuseFS) Removexattr(path string, name string) int {
-	return -fuse.ENOSYS
+	objName, errno := normalizeFusePath(path)
+	if errno != 0 {
+		return errno
+	}
+	log.Trace("Libfuse::Removexattr : %s, %s", objName, name)
+
+	key, ok := strings.CutPrefix(name, xattrUserPrefix)
+	if !ok {
+		return -fuse.ENOATTR
+	}
+
+	metadata, err := fuseFS.NextComponent().GetMetadata(internal.GetMetadataOptions{Name: objName})
+	if err != nil {
+		log.Err("Libfuse::Removexattr : error getting metadata of %s [%s]", objName, err.Error())
+		return fuseErrnoFromError(err)
+	}
+	if lookupMetadata(metadata, key) == nil {
+		return -fuse.ENOATTR
+	}
+
+	err = fuseFS.NextComponent().SetMetadata(internal.SetMetadataOptions{
+		Name:     objName,
+		Metadata: map[string]*string{key: nil},
+	})
+	if err != nil {
+		log.Err("Libfuse::Removexattr : error removing %s from %s [%s]", name, objName, err.Error())
+		return fuseErrnoFromError(err)
+	}
+
+	libfuseStatsCollector.PushEvents(removeXattr, objName, map[string]any{xattrName: name})
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, removeXattr, (int64)(1))
+
+	return 0
 }
 
-// Setxattr  is not implemented.
+// Setxattr sets an extended attribute in the object metadata.
 func (cf *CgofuseFS) Setxattr(path string, name string, value []byte, flags int) int {
-	return -fuse.ENOSYS
+	objName, errno := normalizeFusePath(path)
+	if errno != 0 {
+		return errno
+	}
+	log.Trace("Libfuse::Setxattr : %s, %s", objName, name)
+
+	key, ok := strings.CutPrefix(name, xattrUserPrefix)
+	if !ok || key == "" {
+		return -fuse.ENOTSUP
+	}
+
+	if flags&(fuse.XATTR_CREATE|fuse.XATTR_REPLACE) != 0 {
+		metadata, err := fuseFS.NextComponent().GetMetadata(
+			internal.GetMetadataOptions{Name: objName},
+		)
+		if err != nil {
+			log.Err("Libfuse::Setxattr : error getting metadata of %s [%s]", objName, err.Error())
+			return fuseErrnoFromError(err)
+		}
+		exists := lookupMetadata(metadata, key) != nil
+		if flags&fuse.XATTR_CREATE != 0 && exists {
+			return -fuse.EEXIST
+		}
+		if flags&fuse.XATTR_REPLACE != 0 && !exists {
+			return -fuse.ENOATTR
+		}
+	}
+
+	err := fuseFS.NextComponent().SetMetadata(internal.SetMetadataOptions{
+		Name:     objName,
+		Metadata: map[string]*string{key: new(string(value))},
+	})
+	if err != nil {
+		log.Err("Libfuse::Setxattr : error setting %s on %s [%s]", name, objName, err.Error())
+		return fuseErrnoFromError(err)
+	}
+
+	libfuseStatsCollector.PushEvents(setXattr, objName, map[string]any{xattrName: name})
+	libfuseStatsCollector.UpdateStats(stats_manager.Increment, setXattr, (int64)(1))
+
+	return 0
 }
 
 // cloudfuse_cache_update refresh the file-cache policy for this file
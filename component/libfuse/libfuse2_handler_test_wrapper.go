@@ -33,6 +33,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/config"
@@ -358,6 +359,7 @@ func testFuseErrnoFromError(suite *libfuseTestSuite) {
 		{err: os.ErrExist, expected: -fuse.EEXIST},
 		{err: syscall.EIO, expected: -fuse.EIO},
 		{err: errors.New("boom"), expected: -fuse.EIO},
+		{err: common.NewArchivedError(errors.New("archived"), false), expected: -fuse.ENODATA},
 	}
 
 	for _, tc := range testCases {
@@ -756,17 +758,112 @@ func testReleaseErrorAccess(suite *libfuseTestSuite) {
 func testUnsupportedOps(suite *libfuseTestSuite) {
 	defer suite.cleanupTest()
 	suite.assert.Equal(-fuse.ENOSYS, cfuseFS.Access("/path", 0))
-	ret, data := cfuseFS.Getxattr("/path", "x")
-	suite.assert.Equal(-fuse.ENOSYS, ret)
-	suite.assert.Nil(data)
 	suite.assert.Equal(-fuse.ENOSYS, cfuseFS.Link("/a", "/b"))
-	suite.assert.Equal(
-		-fuse.ENOSYS,
-		cfuseFS.Listxattr("/path", func(name string) bool { return true }),
-	)
 	suite.assert.Equal(-fuse.ENOSYS, cfuseFS.Mknod("/path", 0, 0))
-	suite.assert.Equal(-fuse.ENOSYS, cfuseFS.Removexattr("/path", "x"))
-	suite.assert.Equal(-fuse.ENOSYS, cfuseFS.Setxattr("/path", "x", []byte("v"), 0))
+}
+
+func testGetxattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	options := internal.GetMetadataOptions{Name: "path"}
+	metadata := map[string]*string{"Label": new("value")}
+	suite.mock.EXPECT().GetMetadata(options).Return(metadata, nil).Times(2)
+
+	ret, data := cfuseFS.Getxattr("/path", "user.label")
+	suite.assert.Equal(0, ret)
+	suite.assert.Equal([]byte("value"), data)
+
+	ret, data = cfuseFS.Getxattr("/path", "user.missing")
+	suite.assert.Equal(-fuse.ENOATTR, ret)
+	suite.assert.Nil(data)
+
+	// only the user namespace is backed by metadata
+	ret, _ = cfuseFS.Getxattr("/path", "security.selinux")
+	suite.assert.Equal(-fuse.ENOATTR, ret)
+}
+
+func testGetxattrError(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	options := internal.GetMetadataOptions{Name: "path"}
+	suite.mock.EXPECT().GetMetadata(options).Return(nil, syscall.ENOENT)
+
+	ret, _ := cfuseFS.Getxattr("/path", "user.label")
+	suite.assert.Equal(-fuse.ENOENT, ret)
+}
+
+func testListxattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	options := internal.GetMetadataOptions{Name: "path"}
+	metadata := map[string]*string{"b": new("2"), "a": new("1")}
+	suite.mock.EXPECT().GetMetadata(options).Return(metadata, nil).Times(2)
+
+	names := []string{}
+	ret := cfuseFS.Listxattr("/path", func(name string) bool {
+		names = append(names, name)
+		return true
+	})
+	suite.assert.Equal(0, ret)
+	suite.assert.Equal([]string{"user.a", "user.b"}, names)
+
+	ret = cfuseFS.Listxattr("/path", func(name string) bool { return false })
+	suite.assert.Equal(-fuse.ERANGE, ret)
+}
+
+func testSetxattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	options := internal.SetMetadataOptions{
+		Name:     "path",
+		Metadata: map[string]*string{"label": new("value")},
+	}
+	suite.mock.EXPECT().SetMetadata(options).Return(nil)
+
+	ret := cfuseFS.Setxattr("/path", "user.label", []byte("value"), 0)
+	suite.assert.Equal(0, ret)
+
+	ret = cfuseFS.Setxattr("/path", "trusted.label", []byte("value"), 0)
+	suite.assert.Equal(-fuse.ENOTSUP, ret)
+}
+
+func testSetxattrFlags(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	getOptions := internal.GetMetadataOptions{Name: "path"}
+	metadata := map[string]*string{"label": new("old")}
+	suite.mock.EXPECT().GetMetadata(getOptions).Return(metadata, nil).Times(2)
+
+	ret := cfuseFS.Setxattr("/path", "user.label", []byte("value"), fuse.XATTR_CREATE)
+	suite.assert.Equal(-fuse.EEXIST, ret)
+
+	ret = cfuseFS.Setxattr("/path", "user.other", []byte("value"), fuse.XATTR_REPLACE)
+	suite.assert.Equal(-fuse.ENOATTR, ret)
+}
+
+func testSetxattrError(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	options := internal.SetMetadataOptions{
+		Name:     "path",
+		Metadata: map[string]*string{"bad-key": new("value")},
+	}
+	suite.mock.EXPECT().SetMetadata(options).Return(syscall.EINVAL)
+
+	ret := cfuseFS.Setxattr("/path", "user.bad-key", []byte("value"), 0)
+	suite.assert.Equal(-fuse.EINVAL, ret)
+}
+
+func testRemovexattr(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	getOptions := internal.GetMetadataOptions{Name: "path"}
+	metadata := map[string]*string{"label": new("value")}
+	suite.mock.EXPECT().GetMetadata(getOptions).Return(metadata, nil).Times(2)
+	options := internal.SetMetadataOptions{
+		Name:     "path",
+		Metadata: map[string]*string{"label": nil},
+	}
+	suite.mock.EXPECT().SetMetadata(options).Return(nil)
+
+	ret := cfuseFS.Removexattr("/path", "user.label")
+	suite.assert.Equal(0, ret)
+
+	ret = cfuseFS.Removexattr("/path", "user.missing")
+	suite.assert.Equal(-fuse.ENOATTR, ret)
 }
 
 // TODO: ReadDir test
@@ -1775,6 +1872,43 @@ func testUtimens(suite *libfuseTestSuite) {
 	name := "path"
 	path := "/" + name
 
-	err := cfuseFS.Utimens(path, nil)
+	atime := time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC)
+	mtime := atime.Add(time.Hour)
+	options := internal.UtimensOptions{Name: name, Atime: atime, Mtime: mtime}
+	suite.mock.EXPECT().Utimens(options).Return(nil)
+
+	err := cfuseFS.Utimens(path, []fuse.Timespec{fuse.NewTimespec(atime), fuse.NewTimespec(mtime)})
 	suite.assert.Equal(0, err)
 }
+
+func testUtimensNowOmit(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := "/" + name
+
+	before := time.Now()
+	suite.mock.EXPECT().
+		Utimens(gomock.AssignableToTypeOf(internal.UtimensOptions{})).
+		DoAndReturn(func(options internal.UtimensOptions) error {
+			suite.assert.Equal(name, options.Name)
+			suite.assert.True(options.Atime.IsZero())
+			suite.assert.False(options.Mtime.Before(before))
+			return nil
+		})
+
+	tmsp := []fuse.Timespec{{Nsec: fuse.UTIME_OMIT}, {Nsec: fuse.UTIME_NOW}}
+	err := cfuseFS.Utimens(path, tmsp)
+	suite.assert.Equal(0, err)
+}
+
+func testUtimensNotExists(suite *libfuseTestSuite) {
+	defer suite.cleanupTest()
+	name := "path"
+	path := "/" + name
+	suite.mock.EXPECT().
+		Utimens(gomock.AssignableToTypeOf(internal.UtimensOptions{})).
+		Return(syscall.ENOENT)
+
+	err := cfuseFS.Utimens(path, nil)
+	suite.assert.Equal(-fuse.ENOENT, err)
+}
@@ -38,6 +38,9 @@ const (
 	syncFile     = "SyncFile"
 	syncDir      = "SyncDir"
 	chmod        = "Chmod"
+	utimens      = "Utimens"
+	setXattr     = "SetXattr"
+	removeXattr  = "RemoveXattr"
 
 	openHandles = "OpenFileHandles"
 	md          = "Mode"
@@ -45,4 +48,8 @@ const (
 	source      = "Src"
 	dest        = "Dest"
 	trgt        = "Target"
+	xattrName   = "Name"
+
+	// only the user namespace of extended attributes is backed by object metadata
+	xattrUserPrefix = "user."
 )
@@ -704,10 +704,46 @@ func (suite *libfuseTestSuite) TestUtimens() {
 	testUtimens(suite)
 }
 
+func (suite *libfuseTestSuite) TestUtimensNowOmit() {
+	testUtimensNowOmit(suite)
+}
+
+func (suite *libfuseTestSuite) TestUtimensNotExists() {
+	testUtimensNotExists(suite)
+}
+
 func (suite *libfuseTestSuite) TestUnsupportedOps() {
 	testUnsupportedOps(suite)
 }
 
+func (suite *libfuseTestSuite) TestGetxattr() {
+	testGetxattr(suite)
+}
+
+func (suite *libfuseTestSuite) TestGetxattrError() {
+	testGetxattrError(suite)
+}
+
+func (suite *libfuseTestSuite) TestListxattr() {
+	testListxattr(suite)
+}
+
+func (suite *libfuseTestSuite) TestSetxattr() {
+	testSetxattr(suite)
+}
+
+func (suite *libfuseTestSuite) TestSetxattrFlags() {
+	testSetxattrFlags(suite)
+}
+
+func (suite *libfuseTestSuite) TestSetxattrError() {
+	testSetxattrError(suite)
+}
+
+func (suite *libfuseTestSuite) TestRemovexattr() {
+	testRemovexattr(suite)
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestLibfuseTestSuite(t *testing.T) {
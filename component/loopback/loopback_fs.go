@@ -166,6 +166,7 @@ func (lfs *LoopbackFS) StreamDir(
 			Atime: info.ModTime(),
 			Ctime: info.ModTime(),
 			MD5:   md5,
+			ETag:  fileETag(info),
 		}
 
 		if info.Mode()&os.ModeSymlink != 0 {
@@ -381,6 +382,14 @@ func (lfs *LoopbackFS) CopyToFile(options internal.CopyToFileOptions) error {
 func (lfs *LoopbackFS) CopyFromFile(options internal.CopyFromFileOptions) error {
 	log.Trace("LoopbackFS::CopyFromFile : name=%s", options.Name)
 	path := filepath.Join(lfs.path, options.Name)
+	if options.IfMatch != nil {
+		info, err := os.Stat(path)
+		if (err == nil && fileETag(info) != *options.IfMatch) ||
+			(os.IsNotExist(err) && *options.IfMatch != "") {
+			log.Warn("LoopbackFS::CopyFromFile : %s does not match %s", path, *options.IfMatch)
+			return common.NewConflictError(fmt.Errorf("%s has changed", options.Name))
+		}
+	}
 	fdst, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.FileMode(0666))
 	if err != nil {
 		log.Err("LoopbackFS::CopyFromFile : error opening [%s]", err)
@@ -396,9 +405,22 @@ func (lfs *LoopbackFS) CopyFromFile(options internal.CopyFromFileOptions) error
 		log.Err("LoopbackFS::CopyFromFile : error closing [%s]", err)
 		return err
 	}
+	if options.NewETag != nil {
+		if info, err := os.Stat(path); err == nil {
+			*options.NewETag = fileETag(info)
+		}
+	}
 	return nil
 }
 
+// fileETag derives an entity tag from the modification time and size of a file
+func fileETag(info os.FileInfo) string {
+	if info.IsDir() {
+		return ""
+	}
+	return fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+}
+
 func (lfs *LoopbackFS) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr, error) {
 	log.Trace("LoopbackFS::GetAttr : name=%s", options.Name)
 	path := filepath.Join(lfs.path, options.Name)
@@ -415,6 +437,7 @@ func (lfs *LoopbackFS) GetAttr(options internal.GetAttrOptions) (*internal.ObjAt
 		Mtime: info.ModTime(),
 		Atime: info.ModTime(),
 		Ctime: info.ModTime(),
+		ETag:  fileETag(info),
 	}
 
 	if info.Mode()&os.ModeSymlink != 0 {
@@ -445,6 +468,34 @@ func (lfs *LoopbackFS) Chown(options internal.ChownOptions) error {
 	return os.Chown(path, options.Owner, options.Group)
 }
 
+// GetMetadata : local files have no object metadata
+func (lfs *LoopbackFS) GetMetadata(
+	options internal.GetMetadataOptions,
+) (map[string]*string, error) {
+	log.Trace("LoopbackFS::GetMetadata : name=%s", options.Name)
+	path := filepath.Join(lfs.path, options.Name)
+	if _, err := os.Lstat(path); err != nil {
+		return nil, err
+	}
+	return map[string]*string{}, nil
+}
+
+// SetMetadata : local files have nowhere to store object metadata
+func (lfs *LoopbackFS) SetMetadata(options internal.SetMetadataOptions) error {
+	log.Trace("LoopbackFS::SetMetadata : name=%s", options.Name)
+	path := filepath.Join(lfs.path, options.Name)
+	if _, err := os.Lstat(path); err != nil {
+		return err
+	}
+	return syscall.ENOTSUP
+}
+
+func (lfs *LoopbackFS) Utimens(options internal.UtimensOptions) error {
+	log.Trace("LoopbackFS::Utimens : name=%s", options.Name)
+	path := filepath.Join(lfs.path, options.Name)
+	return os.Chtimes(path, options.Atime, options.Mtime)
+}
+
 func (lfs *LoopbackFS) StageData(options internal.StageDataOptions) error {
 	log.Trace("LoopbackFS::StageData : name=%s, id=%s", options.Name, options.Id)
 	path := fmt.Sprintf(
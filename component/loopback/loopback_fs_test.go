@@ -28,10 +28,12 @@ package loopback
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/log"
@@ -460,6 +462,74 @@ func (suite *LoopbackFSTestSuite) TestGetAttr() {
 	assert.False(attr.IsModeDefault())
 }
 
+func (suite *LoopbackFSTestSuite) TestUtimens() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	mtime := time.Date(2020, time.January, 2, 3, 4, 5, 0, time.UTC)
+	err := suite.lfs.Utimens(internal.UtimensOptions{Name: fileLorem, Mtime: mtime})
+	assert.NoError(err)
+	info, err := os.Stat(filepath.Join(testPath, fileLorem))
+	assert.NoError(err)
+	assert.True(mtime.Equal(info.ModTime()))
+
+	err = suite.lfs.Utimens(internal.UtimensOptions{Name: "missing", Mtime: mtime})
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func (suite *LoopbackFSTestSuite) TestCopyFromFileIfMatch() {
+	defer suite.cleanupTest()
+	assert := assert.New(suite.T())
+
+	src, err := os.CreateTemp("", "cloudfuselfsupload")
+	assert.NoError(err)
+	defer os.Remove(src.Name())
+	defer src.Close()
+	_, err = src.WriteString(quotesText)
+	assert.NoError(err)
+
+	attr, err := suite.lfs.GetAttr(internal.GetAttrOptions{Name: fileLorem})
+	assert.NoError(err)
+	assert.NotEmpty(attr.ETag)
+
+	// a stale tag is rejected and the object is left alone
+	err = suite.lfs.CopyFromFile(
+		internal.CopyFromFileOptions{Name: fileLorem, File: src, IfMatch: new("stale")},
+	)
+	assert.ErrorIs(err, &common.ConflictError{})
+	info, err := os.Stat(filepath.Join(testPath, fileLorem))
+	assert.NoError(err)
+	assert.Equal(int64(len(loremText)), info.Size())
+
+	// an empty tag only matches a missing object
+	err = suite.lfs.CopyFromFile(
+		internal.CopyFromFileOptions{Name: fileLorem, File: src, IfMatch: new("")},
+	)
+	assert.ErrorIs(err, &common.ConflictError{})
+
+	newETag := ""
+	_, err = src.Seek(0, io.SeekStart)
+	assert.NoError(err)
+	err = suite.lfs.CopyFromFile(internal.CopyFromFileOptions{
+		Name:    fileLorem,
+		File:    src,
+		IfMatch: new(attr.ETag),
+		NewETag: &newETag,
+	})
+	assert.NoError(err)
+	attr, err = suite.lfs.GetAttr(internal.GetAttrOptions{Name: fileLorem})
+	assert.NoError(err)
+	assert.Equal(int64(len(quotesText)), attr.Size)
+	assert.Equal(attr.ETag, newETag)
+
+	_, err = src.Seek(0, io.SeekStart)
+	assert.NoError(err)
+	err = suite.lfs.CopyFromFile(
+		internal.CopyFromFileOptions{Name: "new.txt", File: src, IfMatch: new("")},
+	)
+	assert.NoError(err)
+}
+
 func (suite *LoopbackFSTestSuite) TestStageAndCommitData() {
 	defer suite.cleanupTest()
 	assert := assert.New(suite.T())
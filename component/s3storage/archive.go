@@ -0,0 +1,138 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// Objects in an archive storage class, or in an archive access tier of Intelligent-Tiering,
+// must be restored before they can be read. A restore makes a temporary copy that can be read
+// for restore-days, while the object stays in its storage class.
+
+const defaultRestoreDays = 1
+
+// isArchiveClass returns true for the storage classes that must be restored to be read
+func isArchiveClass(class types.StorageClass) bool {
+	return class == types.StorageClassGlacier || class == types.StorageClassDeepArchive
+}
+
+// restoreStatus returns the restore status of an object, or an empty string if it is not archived
+func restoreStatus(head *s3.HeadObjectOutput) string {
+	if head.ArchiveStatus == "" && !isArchiveClass(head.StorageClass) {
+		return ""
+	}
+	switch {
+	case head.Restore == nil:
+		return internal.RestoreStatusArchived
+	case strings.Contains(*head.Restore, `ongoing-request="true"`):
+		return internal.RestoreStatusRestoring
+	default:
+		return internal.RestoreStatusRestored
+	}
+}
+
+// archiveMetadata adds the archive control keys of an object to its metadata
+func archiveMetadata(head *s3.HeadObjectOutput, metadata map[string]*string) {
+	if head.StorageClass != "" {
+		metadata[internal.TierMetadataKey] = new(string(head.StorageClass))
+	}
+	if status := restoreStatus(head); status != "" {
+		metadata[internal.RestoreMetadataKey] = &status
+	}
+}
+
+// archivedReadError tells whether the object is being restored, when reading it failed because
+// it is archived
+func (cl *Client) archivedReadError(
+	ctx context.Context,
+	options getObjectOptions,
+	err error,
+) error {
+	archivedErr, ok := errors.AsType[common.ArchivedError](err)
+	if !ok || options.versionID != "" {
+		return err
+	}
+	head, headErr := cl.headObjectOutput(ctx, options.name, options.isSymLink, options.isDir)
+	if headErr != nil {
+		return err
+	}
+	restoring := restoreStatus(head) == internal.RestoreStatusRestoring
+	return common.NewArchivedError(archivedErr.CloudStorageError, restoring)
+}
+
+// restoreObject requests a restore of an archived object
+func (cl *Client) restoreObject(ctx context.Context, name string) error {
+	options, head, err := cl.findObject(ctx, name)
+	if err != nil {
+		return err
+	}
+	if head == nil {
+		return syscall.ENOTSUP
+	}
+	if restoreStatus(head) == "" {
+		log.Info("Client::restoreObject : %s is not archived", name)
+		return nil
+	}
+
+	request := &types.RestoreRequest{
+		GlacierJobParameters: &types.GlacierJobParameters{Tier: cl.Config.restoreTier},
+	}
+	// objects in an archive access tier are moved back instead of copied, so they take no days
+	if head.ArchiveStatus == "" {
+		request.Days = aws.Int32(cl.Config.restoreDays)
+	}
+	key := cl.getKey(options.name, options.isSymLink, options.isDir)
+	_, err = cl.AwsS3Client.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket:         aws.String(cl.Config.AuthConfig.BucketName),
+		Key:            aws.String(key),
+		RestoreRequest: request,
+	})
+	if apiErr, ok := errors.AsType[smithy.APIError](err); ok &&
+		apiErr.ErrorCode() == "RestoreAlreadyInProgress" {
+		log.Info("Client::restoreObject : %s is already being restored", name)
+		return nil
+	}
+	if err != nil {
+		attemptedAction := fmt.Sprintf("restore object %s", key)
+		return parseS3Err(err, attemptedAction)
+	}
+	log.Info("Client::restoreObject : Requested restore of %s", name)
+	return nil
+}
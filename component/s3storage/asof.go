@@ -0,0 +1,222 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// With as-of, the bucket is mounted read-only as it was at the given time: every object is read
+// from the newest version written at or before that time, and objects whose newest version is a
+// delete marker, or that did not exist yet, are hidden.
+// The as-of time is in the past, so the version of a key never changes once it is resolved.
+
+// asOfVersion is the newest version of a key at the as-of time
+type asOfVersion struct {
+	versionID    string
+	size         int64
+	lastModified time.Time
+	eTag         *string
+	deleted      bool // the version is a delete marker
+}
+
+// existedAsOf reports whether a version written at lastModified is visible in the mount
+func (cl *Client) existedAsOf(lastModified *time.Time) bool {
+	return cl.Config.asOf.IsZero() || !lastModified.After(cl.Config.asOf)
+}
+
+// resolveAsOf adds the versions in a page of ListObjectVersions to versions, keeping the newest
+// version of each key at the as-of time. Keys without such a version are left out.
+func (cl *Client) resolveAsOf(
+	output *s3.ListObjectVersionsOutput,
+	versions map[string]asOfVersion,
+) {
+	consider := func(key string, candidate asOfVersion) {
+		if !cl.existedAsOf(&candidate.lastModified) {
+			return
+		}
+		if current, found := versions[key]; !found ||
+			candidate.lastModified.After(current.lastModified) {
+			versions[key] = candidate
+		}
+	}
+	for _, version := range output.Versions {
+		consider(*version.Key, asOfVersion{
+			versionID:    *version.VersionId,
+			size:         *version.Size,
+			lastModified: *version.LastModified,
+			eTag:         version.ETag,
+		})
+	}
+	for _, marker := range output.DeleteMarkers {
+		consider(*marker.Key, asOfVersion{
+			versionID:    *marker.VersionId,
+			lastModified: *marker.LastModified,
+			deleted:      true,
+		})
+	}
+}
+
+// versionAsOf returns the ID of the version of key to read at the as-of time.
+// It returns ENOENT if the key was deleted, or did not exist yet.
+func (cl *Client) versionAsOf(ctx context.Context, key string) (string, error) {
+	if value, found := cl.asOfVersions.Load(key); found {
+		return value.(asOfVersion).lookup()
+	}
+	versions := make(map[string]asOfVersion)
+	err := cl.listObjectVersions(ctx, key, func(output *s3.ListObjectVersionsOutput) bool {
+		cl.resolveAsOf(output, versions)
+		// keys are listed in order, and the prefix also matches longer keys
+		return !slices.ContainsFunc(output.Versions, func(v types.ObjectVersion) bool {
+			return *v.Key > key
+		}) && !slices.ContainsFunc(output.DeleteMarkers, func(m types.DeleteMarkerEntry) bool {
+			return *m.Key > key
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+	version, found := versions[key]
+	if !found {
+		version.deleted = true
+	}
+	cl.asOfVersions.Store(key, version)
+	return version.lookup()
+}
+
+func (version asOfVersion) lookup() (string, error) {
+	if version.deleted {
+		return "", syscall.ENOENT
+	}
+	return version.versionID, nil
+}
+
+// versionToRead returns the version of key to read: versionID when it is given, the version at
+// the as-of time in a point-in-time mount, or nil for the current version.
+func (cl *Client) versionToRead(
+	ctx context.Context,
+	key string,
+	versionID string,
+) (*string, error) {
+	if versionID == "" && !cl.Config.asOf.IsZero() {
+		var err error
+		versionID, err = cl.versionAsOf(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if versionID == "" {
+		return nil, nil
+	}
+	return aws.String(versionID), nil
+}
+
+// dirExistsAsOf reports whether any object under the directory key existed at the as-of time
+func (cl *Client) dirExistsAsOf(ctx context.Context, dirKey string) (bool, error) {
+	exists := false
+	versions := make(map[string]asOfVersion)
+	params := &s3.ListObjectVersionsInput{Prefix: aws.String(dirKey)}
+	err := cl.listObjectVersionsWith(ctx, params, func(output *s3.ListObjectVersionsOutput) bool {
+		cl.resolveAsOf(output, versions)
+		// versions of a key are listed from newest to oldest,
+		// so a key resolved on one page is not changed by the next
+		for _, version := range versions {
+			if !version.deleted {
+				exists = true
+				return false
+			}
+		}
+		return true
+	})
+	return exists, err
+}
+
+// listAsOf lists a directory as it was at the as-of time. Like List, it expects a trailing slash.
+// Versions of a key may span pages, so the whole listing is returned at once.
+func (cl *Client) listAsOf(ctx context.Context, prefix string) ([]*internal.ObjAttr, error) {
+	listPath := cl.getKey(prefix, false, false)
+	if strings.HasSuffix(prefix, "/") || (prefix == "" && cl.Config.prefixPath != "") {
+		listPath += "/"
+	}
+
+	versions := make(map[string]asOfVersion)
+	var commonPrefixes []string
+	err := cl.listObjectVersions(ctx, listPath, func(output *s3.ListObjectVersionsOutput) bool {
+		cl.resolveAsOf(output, versions)
+		for _, commonPrefix := range output.CommonPrefixes {
+			commonPrefixes = append(commonPrefixes, *commonPrefix.Prefix)
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	attrList := make([]*internal.ObjAttr, 0, len(versions)+len(commonPrefixes))
+	for key, version := range versions {
+		cl.asOfVersions.Store(key, version)
+		if version.deleted || key == listPath {
+			continue
+		}
+		name, isSymLink := cl.getFile(key)
+		path := split(cl.Config.prefixPath, name)
+		attr := createObjAttr(path, version.size, version.lastModified, isSymLink)
+		attr.ETag = sanitizeETag(version.eTag)
+		attrList = append(attrList, attr)
+	}
+	if strings.HasSuffix(listPath, "/") {
+		// objects may have been written in a directory after the as-of time only
+		for _, dir := range slices.Compact(commonPrefixes) {
+			exists, err := cl.dirExistsAsOf(ctx, dir)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				dirName, _ := cl.getFile(dir)
+				attrList = append(attrList, internal.CreateObjAttrDir(
+					split(cl.Config.prefixPath, dirName),
+				))
+			}
+		}
+	}
+
+	slices.SortFunc(attrList, func(a, b *internal.ObjAttr) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	log.Debug("Client::listAsOf : %s returning %d entries", prefix, len(attrList))
+	return attrList, nil
+}
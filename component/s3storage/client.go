@@ -32,6 +32,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"math"
 	"net/http"
 	"net/url"
@@ -74,6 +75,8 @@ type Client struct {
 	transferManager   *transfermanager.Client
 	stagedBlocks      map[string]map[string][]byte // map[fileName]map[blockId]data
 	stagedBlocksMutex sync.RWMutex                 // Mutex to protect the cache
+	asOfVersions      sync.Map                     // map[key]asOfVersion, when Config.asOf is set
+	endpoints         *endpointSet                 // nil unless there are failover endpoints
 }
 
 // Verify that Client implements S3Connection interface
@@ -109,6 +112,44 @@ func getSymlinkBool(metadata map[string]*string) bool {
 	return isSymlink
 }
 
+// getUserMetadata converts the metadata map into the form sent to S3, dropping internal keys.
+// The symlink flag is not stored as metadata, it is encoded in the object key instead.
+func getUserMetadata(metadata map[string]*string) map[string]string {
+	userMetadata := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if value != nil && !strings.EqualFold(key, symlinkKey) {
+			userMetadata[strings.ToLower(key)] = *value
+		}
+	}
+	if len(userMetadata) == 0 {
+		return nil
+	}
+	return userMetadata
+}
+
+// keepMetadata returns the metadata of an upload replacing name, with the metadata of the object
+// being replaced carried over, as S3 replaces all the metadata of an object on upload
+func (cl *Client) keepMetadata(
+	ctx context.Context,
+	name string,
+	isSymlink bool,
+	metadata map[string]*string,
+) (map[string]*string, error) {
+	existing := make(map[string]*string)
+	head, err := cl.headObjectOutput(ctx, name, isSymlink, false)
+	switch {
+	case err == nil:
+		for key, value := range head.Metadata {
+			if key != symlinkKey {
+				existing[key] = &value
+			}
+		}
+	case err != syscall.ENOENT:
+		return nil, err
+	}
+	return internal.KeepMetadata(existing, metadata), nil
+}
+
 // Configure : Initialize the awsS3Client
 func (cl *Client) Configure(cfg Config) error {
 	log.Trace("Client::Configure : initialize awsS3Client")
@@ -183,20 +224,28 @@ func (cl *Client) Configure(cfg Config) error {
 		}
 	}
 
-	// Create an Amazon S3 service client
-	if cl.Config.usePathStyle {
-		cl.AwsS3Client = s3.NewFromConfig(defaultConfig, func(o *s3.Options) {
-			o.UsePathStyle = true
-			o.BaseEndpoint = aws.String(cl.Config.AuthConfig.Endpoint)
-			o.DisableLogOutputChecksumValidationSkipped = true // Disable warning messages
-		})
-	} else {
-		cl.AwsS3Client = s3.NewFromConfig(defaultConfig, func(o *s3.Options) {
-			o.BaseEndpoint = aws.String(cl.Config.AuthConfig.Endpoint)
-			o.DisableLogOutputChecksumValidationSkipped = true // Disable warning messages
-		})
+	// temporary credentials of the other modes are refreshed by the client as they expire
+	provider := cl.Config.AuthConfig.newCredentialsProvider(defaultConfig)
+	if provider != nil {
+		defaultConfig.Credentials = provider
 	}
 
+	// Create an Amazon S3 service client
+	cl.endpoints = nil
+	if len(cl.Config.endpoints) > 1 {
+		cl.endpoints = newEndpointSet(cl.Config.endpoints, cl.Config.failoverWrites)
+	}
+	cl.AwsS3Client = s3.NewFromConfig(defaultConfig, func(o *s3.Options) {
+		o.UsePathStyle = cl.Config.usePathStyle
+		o.BaseEndpoint = aws.String(cl.Config.AuthConfig.Endpoint)
+		o.DisableLogOutputChecksumValidationSkipped = true // Disable warning messages
+		cl.addRateLimit(o)
+		cl.addRequestLatency(o)
+		if cl.endpoints != nil {
+			cl.addEndpointFailover(o)
+		}
+	})
+
 	// ListBuckets here to test connection to S3 backend
 	bucketList, err := cl.ListBuckets(ctx)
 	if err != nil {
@@ -363,6 +412,21 @@ func (cl *Client) UpdateConfig(cfg Config) error {
 	return nil
 }
 
+// ActiveEndpoint : The endpoint reads are currently sent to
+func (cl *Client) ActiveEndpoint() string {
+	if cl.endpoints == nil {
+		return cl.Config.AuthConfig.Endpoint
+	}
+	return cl.endpoints.activeEndpoint()
+}
+
+// WatchEndpoints : End the health checks of endpoints that are down with ctx
+func (cl *Client) WatchEndpoints(ctx context.Context) {
+	if cl.endpoints != nil {
+		cl.endpoints.setContext(ctx)
+	}
+}
+
 // NewCredentialKey : Update the credential key specified by the user.
 // Currently not implemented.
 func (cl *Client) NewCredentialKey(key, value string) error {
@@ -456,7 +520,8 @@ func (cl *Client) DeleteFile(ctx context.Context, name string) error {
 // DeleteDirectory : Delete the directory marker in the container
 // If name is given without a trailing slash, a slash will be added.
 // If the directory marker does not exist, no error will be returned.
-// Deletion is carried out regardless of the enableDirMarker flag
+// Deletion is carried out regardless of the enableDirMarker flag.
+// The objects under the directory are left alone, see DeleteTree.
 func (cl *Client) DeleteDirectory(ctx context.Context, name string) error {
 	log.Trace("Client::DeleteDirectory : name %s", name)
 
@@ -475,6 +540,50 @@ func (cl *Client) DeleteDirectory(ctx context.Context, name string) error {
 	return err
 }
 
+// DeleteTree : Delete the directory marker and every object under the directory, in batches.
+// Objects written under the directory by other clients are deleted too, so this is only for
+// callers that mean to remove everything under the directory.
+func (cl *Client) DeleteTree(ctx context.Context, name string) error {
+	log.Trace("Client::DeleteTree : name %s", name)
+
+	if internal.TruncateDirName(name) == "" {
+		log.Err("Client::DeleteTree : Refusing to delete the root directory")
+		return syscall.EPERM
+	}
+
+	prefix := cl.getKey(name, false, true)
+	paginator := s3.NewListObjectsV2Paginator(cl.AwsS3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(cl.Config.AuthConfig.BucketName),
+		Prefix: aws.String(prefix),
+	})
+	deleted := 0
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return parseS3Err(err, fmt.Sprintf("list objects under %s", prefix))
+		}
+		keys := make([]string, len(output.Contents))
+		for i, object := range output.Contents {
+			keys[i] = aws.ToString(object.Key)
+		}
+		count, err := cl.deleteObjects(ctx, keys)
+		deleted += count
+		if err != nil {
+			log.Err(
+				"Client::DeleteTree : Failed to delete directory %s. Here's why: %v",
+				name,
+				err,
+			)
+			return err
+		}
+		if aws.ToBool(output.IsTruncated) {
+			log.Info("Client::DeleteTree : Deleted %d objects under %s so far", deleted, name)
+		}
+	}
+
+	return nil
+}
+
 // RenameFile : Rename the object (copy then delete).
 func (cl *Client) RenameFile(
 	ctx context.Context,
@@ -500,74 +609,46 @@ func (cl *Client) RenameFile(
 	return err
 }
 
-// RenameDirectory : Rename the directory
+// RenameDirectory : Rename the directory and everything under it.
+// Objects are copied concurrently, and deleted in batches. Objects that fail to move are left
+// behind, and EIO is returned. With a state directory, an interrupted rename is completed by the
+// next mount.
 func (cl *Client) RenameDirectory(ctx context.Context, source string, target string) error {
 	log.Trace("Client::RenameDirectory : %s -> %s", source, target)
 
 	// TODO: should this fail when the target directory exists?
 	// current behavior merges into the target directory
-	// best to check and see what the azstorage code does
-
-	// first we need a list of all the object's we'll be moving
-	// make sure to pass source with a trailing forward slash
-
-	done := false
-	var marker *string
-
-	for !done {
-		sourceObjects, marker, err := cl.List(ctx, internal.ExtendDirName(source), marker, 0)
-		if err != nil {
-			log.Err(
-				"Client::RenameDirectory : Failed to list objects with prefix %s. Here's why: %v",
-				source,
-				err,
-			)
-			return err
-		}
-		// it's better not to return an error when we don't find any matching objects (see note in DeleteDirectory)
-		for _, srcObject := range sourceObjects {
-			srcPath := srcObject.Path
-			dstPath := strings.Replace(srcPath, source, target, 1)
-			if srcObject.IsDir() {
-				err = cl.RenameDirectory(ctx, srcPath, dstPath)
-			} else {
-				err = cl.RenameFile(
-					ctx,
-					srcPath,
-					dstPath,
-					srcObject.IsSymlink(),
-				) //use sourceObjects to pass along symLink bool
-			}
-			if err != nil {
-				log.Err(
-					"Client::RenameDirectory : Failed to rename %s -> %s. Here's why: %v",
-					srcPath,
-					dstPath,
-					err,
-				)
-			}
-		}
-		if marker == nil {
-			done = true
-
-			// Rename the current directory
-			if cl.Config.enableDirMarker {
-				err := cl.renameObject(
-					ctx,
-					renameObjectOptions{source: source, target: target, isDir: true},
-				)
-				if err != nil {
-					log.Err(
-						"Client::RenameDirectory : Failed to rename %s -> %s. Here's why: %v",
-						source,
-						target,
-						err,
-					)
-				}
-			}
-		}
+	objects, err := cl.listTree(ctx, source)
+	if err != nil {
+		log.Err(
+			"Client::RenameDirectory : Failed to list objects under %s. Here's why: %v",
+			source,
+			err,
+		)
+		return err
 	}
-	return nil
+	keys := make([]string, len(objects))
+	for i, object := range objects {
+		keys[i] = aws.ToString(object.Key)
+	}
+	journal, err := cl.createRenameJournal(source, target, keys)
+	if err != nil {
+		log.Warn(
+			"Client::RenameDirectory : Rename of %s will not be resumable. Here's why: %v",
+			source,
+			err,
+		)
+	}
+	err = cl.moveTree(ctx, source, target, objects, journal, nil)
+	if err != nil {
+		log.Err(
+			"Client::RenameDirectory : Failed to rename %s -> %s. Here's why: %v",
+			source,
+			target,
+			err,
+		)
+	}
+	return err
 }
 
 // GetAttr : Get attributes for a given file or folder.
@@ -575,6 +656,14 @@ func (cl *Client) RenameDirectory(ctx context.Context, source string, target str
 // If name is a directory, the trailing slash is optional.
 func (cl *Client) GetAttr(ctx context.Context, name string) (*internal.ObjAttr, error) {
 	log.Trace("Client::GetAttr : name %s", name)
+	if cl.Config.enableVersions {
+		if vp, inTree, err := parseVersionPath(name); inTree {
+			if err != nil {
+				return nil, err
+			}
+			return cl.getVersionAttr(ctx, vp)
+		}
+	}
 	explicitDirLookup := strings.HasSuffix(name, "/")
 	dirName := internal.ExtendDirName(name)
 
@@ -618,6 +707,17 @@ func (cl *Client) getDirectoryAttr(
 ) (*internal.ObjAttr, error) {
 	log.Trace("Client::getDirectoryAttr : name %s", dirName)
 
+	if !cl.Config.asOf.IsZero() {
+		exists, err := cl.dirExistsAsOf(ctx, cl.getKey(dirName, false, true))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, syscall.ENOENT
+		}
+		return internal.CreateObjAttrDir(dirName), nil
+	}
+
 	// When directory markers are enabled, check for the marker first via
 	// HeadObject (cheap, single-key lookup) before falling back to a List.
 	if cl.Config.enableDirMarker && shouldProbeDirMarker(dirName, explicitDirLookup) {
@@ -706,9 +806,14 @@ func (cl *Client) ReadToFile(
 		fi.Name(),
 	)
 
+	name, versionID, err := cl.resolveVersion(name)
+	if err != nil {
+		return err
+	}
+
 	// If we are reading the entire object, then we can use a multipart download
 	if !cl.Config.disableConcurrentDownload && offset == 0 && count == 0 {
-		err := cl.getObjectMultipartDownload(ctx, name, fi)
+		err := cl.getObjectMultipartDownload(ctx, name, versionID, fi)
 		if err != nil {
 			log.Err(
 				"Client::ReadToFile : getObjectMultipartDownload(%s) failed. Here's why: %v",
@@ -723,7 +828,7 @@ func (cl *Client) ReadToFile(
 	// get object data
 	objectDataReader, err := cl.getObject(
 		ctx,
-		getObjectOptions{name: name, offset: offset, count: count},
+		getObjectOptions{name: name, offset: offset, count: count, versionID: versionID},
 	)
 	if err != nil {
 		log.Err("Client::ReadToFile : getObject(%s) failed. Here's why: %v", name, err)
@@ -801,10 +906,14 @@ func (cl *Client) ReadInBuffer(
 	data []byte,
 ) error {
 	log.Trace("Client::ReadInBuffer : name %s offset %d len %d", name, offset, length)
+	name, versionID, err := cl.resolveVersion(name)
+	if err != nil {
+		return err
+	}
 	// get object data
 	objectDataReader, err := cl.getObject(
 		ctx,
-		getObjectOptions{name: name, offset: offset, count: length},
+		getObjectOptions{name: name, offset: offset, count: length, versionID: versionID},
 	)
 	if err != nil {
 		log.Err("Client::ReadInBuffer : getObject(%s) failed. Here's why: %v", name, err)
@@ -822,12 +931,15 @@ func (cl *Client) ReadInBuffer(
 }
 
 // Upload from a file handle to an object.
-// The metadata parameter is not used.
+// ifMatch makes the upload conditional (see internal.CopyFromFileOptions),
+// and newETag receives the ETag of the uploaded object.
 func (cl *Client) WriteFromFile(
 	ctx context.Context,
 	name string,
 	metadata map[string]*string,
 	fi *os.File,
+	ifMatch *string,
+	newETag *string,
 ) error {
 	isSymlink := getSymlinkBool(metadata)
 
@@ -849,13 +961,35 @@ func (cl *Client) WriteFromFile(
 		return err
 	}
 
+	metadata, err = cl.keepMetadata(ctx, name, isSymlink, metadata)
+	if err != nil {
+		log.Err("Client::WriteFromFile : Failed to get metadata of %s. Here's why: %v", name, err)
+		return err
+	}
+
 	// upload file data
-	err = cl.putObject(
-		ctx,
-		putObjectOptions{name: name, objectData: fi, size: stat.Size(), isSymLink: isSymlink},
-	)
+	if cl.Config.uploadStatePath != "" && !isSymlink && stat.Size() >= cl.Config.uploadCutoff {
+		var eTag string
+		eTag, err = cl.resumableUpload(ctx, name, fi, stat, getUserMetadata(metadata), ifMatch)
+		if err == nil && newETag != nil {
+			*newETag = eTag
+		}
+	} else {
+		err = cl.putObject(
+			ctx,
+			putObjectOptions{
+				name:       name,
+				objectData: fi,
+				size:       stat.Size(),
+				isSymLink:  isSymlink,
+				metadata:   getUserMetadata(metadata),
+				ifMatch:    ifMatch,
+				newETag:    newETag,
+			},
+		)
+	}
 	if err != nil {
-		log.Err("Client::WriteFromFile : putObject(%s) failed. Here's why: %v", name, err)
+		log.Err("Client::WriteFromFile : Failed to upload %s. Here's why: %v", name, err)
 		return err
 	}
 
@@ -889,7 +1023,6 @@ func (cl *Client) WriteFromBuffer(
 	// convert byte array to io.Reader
 	dataReader := bytes.NewReader(data)
 	// upload data to object
-	// TODO: handle metadata with S3
 	err := cl.putObject(
 		ctx,
 		putObjectOptions{
@@ -897,6 +1030,7 @@ func (cl *Client) WriteFromBuffer(
 			objectData: dataReader,
 			size:       int64(len(data)),
 			isSymLink:  isSymlink,
+			metadata:   getUserMetadata(metadata),
 		},
 	)
 	if err != nil {
@@ -905,6 +1039,150 @@ func (cl *Client) WriteFromBuffer(
 	return err
 }
 
+// GetMetadata : Get the user metadata of a file or directory.
+// Directories without a marker object have no metadata.
+func (cl *Client) GetMetadata(ctx context.Context, name string) (map[string]*string, error) {
+	log.Trace("Client::GetMetadata : name %s", name)
+	_, head, err := cl.findObject(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]*string)
+	if head == nil {
+		return metadata, nil
+	}
+	for key, value := range head.Metadata {
+		if key != symlinkKey && !internal.IsTimeMetadataKey(key) {
+			metadata[key] = &value
+		}
+	}
+	archiveMetadata(head, metadata)
+	return metadata, nil
+}
+
+// SetMetadata : Merge the given user metadata into the metadata of a file or directory marker.
+// A nil value removes the key. S3 stores metadata keys in lower case.
+func (cl *Client) SetMetadata(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+) error {
+	log.Trace("Client::SetMetadata : name %s", name)
+	restore := false
+	for key, value := range metadata {
+		switch {
+		case strings.EqualFold(key, internal.RestoreMetadataKey) && value != nil:
+			restore = true
+		case strings.EqualFold(key, symlinkKey) || internal.IsTimeMetadataKey(key) ||
+			internal.IsArchiveMetadataKey(key):
+			log.Err("Client::SetMetadata : %s is reserved [%s]", key, name)
+			return syscall.EINVAL
+		}
+	}
+
+	if restore {
+		err := cl.restoreObject(ctx, name)
+		if err != nil {
+			return err
+		}
+		metadata = maps.Clone(metadata)
+		maps.DeleteFunc(metadata, func(key string, _ *string) bool {
+			return internal.IsArchiveMetadataKey(key)
+		})
+		if len(metadata) == 0 {
+			return nil
+		}
+	}
+
+	err := cl.updateMetadata(ctx, name, metadata)
+	if err == syscall.ENOTSUP {
+		log.Err("Client::SetMetadata : %s is a directory without a marker object", name)
+	}
+	return err
+}
+
+// Utimens : Store the access and modification times in the metadata of a file or directory marker.
+// A zero time is left unchanged. A directory without a marker object has nowhere to keep them.
+func (cl *Client) Utimens(ctx context.Context, name string, atime, mtime time.Time) error {
+	log.Trace("Client::Utimens : name %s", name)
+	metadata := make(map[string]*string, 2)
+	if !atime.IsZero() {
+		metadata[internal.AtimeMetadataKey] = new(internal.FormatMetadataTime(atime))
+	}
+	if !mtime.IsZero() {
+		metadata[internal.MtimeMetadataKey] = new(internal.FormatMetadataTime(mtime))
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	err := cl.updateMetadata(ctx, name, metadata)
+	if err == syscall.ENOTSUP {
+		log.Debug("Client::Utimens : %s is a directory without a marker object", name)
+		return nil
+	}
+	return err
+}
+
+// updateMetadata merges the given metadata into the metadata of the object backing name.
+// A nil value removes the key. S3 stores metadata keys in lower case.
+func (cl *Client) updateMetadata(
+	ctx context.Context,
+	name string,
+	metadata map[string]*string,
+) error {
+	options, head, err := cl.findObject(ctx, name)
+	if err != nil {
+		return err
+	}
+	if head == nil {
+		return syscall.ENOTSUP
+	}
+
+	newMetadata := make(map[string]string, len(head.Metadata)+len(metadata))
+	for key, value := range head.Metadata {
+		if key != symlinkKey {
+			newMetadata[key] = value
+		}
+	}
+	for key, value := range metadata {
+		key = strings.ToLower(key)
+		if value == nil {
+			delete(newMetadata, key)
+		} else {
+			newMetadata[key] = *value
+		}
+	}
+
+	return cl.replaceObjectMetadata(ctx, options, head, newMetadata)
+}
+
+// findObject looks up the object that holds the metadata for name.
+// It checks for a file, then a symlink, then a directory marker.
+// A directory with no marker object is returned with a nil head.
+func (cl *Client) findObject(
+	ctx context.Context,
+	name string,
+) (getObjectOptions, *s3.HeadObjectOutput, error) {
+	options := getObjectOptions{name: internal.TruncateDirName(name)}
+	head, err := cl.headObjectOutput(ctx, options.name, false, false)
+	if err == syscall.ENOENT && !cl.Config.disableSymlink {
+		options.isSymLink = true
+		head, err = cl.headObjectOutput(ctx, options.name, true, false)
+	}
+	if err == syscall.ENOENT {
+		options.isSymLink = false
+		options.isDir = true
+		head, err = cl.headObjectOutput(ctx, options.name, false, true)
+	}
+	if err == syscall.ENOENT {
+		_, err = cl.getDirectoryAttr(ctx, internal.ExtendDirName(options.name), false)
+		return options, nil, err
+	}
+	return options, head, err
+}
+
 // GetFileBlockOffsets: store blocks ids and corresponding offsets.
 func (cl *Client) GetFileBlockOffsets(
 	ctx context.Context,
@@ -1211,10 +1489,24 @@ func (cl *Client) StageAndCommit(
 
 	//send command to start copy and get the upload id as it is needed later
 	var uploadID string
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return err
+	}
+	headers, _ := cl.objectHeaders(name)
 	createMultipartUploadInput := &s3.CreateMultipartUploadInput{
-		Bucket:      aws.String(cl.Config.AuthConfig.BucketName),
-		Key:         aws.String(key),
-		ContentType: aws.String(getContentType(key)),
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		ContentType:          contentType(key, headers),
+		CacheControl:         optionalString(headers.CacheControl),
+		ContentEncoding:      optionalString(headers.ContentEncoding),
+		Metadata:             withRuleMetadata(nil, headers),
+		ServerSideEncryption: sse.serverSideEncryption,
+		SSEKMSKeyId:          sse.kmsKeyID,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+		StorageClass:         cl.storageClass(name),
 	}
 
 	if cl.Config.enableChecksum {
@@ -1265,11 +1557,14 @@ func (cl *Client) StageAndCommit(
 		if blk.Dirty() || len(data) > 0 {
 			// This block has data that is not yet in the bucket
 			uploadPartInput := &s3.UploadPartInput{
-				Bucket:     aws.String(cl.Config.AuthConfig.BucketName),
-				Key:        aws.String(key),
-				PartNumber: &partNumber,
-				UploadId:   &uploadID,
-				Body:       bytes.NewReader(data),
+				Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+				Key:                  aws.String(key),
+				PartNumber:           &partNumber,
+				UploadId:             &uploadID,
+				Body:                 bytes.NewReader(data),
+				SSECustomerAlgorithm: sse.customerAlgorithm,
+				SSECustomerKey:       sse.customerKey,
+				SSECustomerKeyMD5:    sse.customerKeyMD5,
 			}
 
 			if cl.Config.enableChecksum {
@@ -1305,8 +1600,14 @@ func (cl *Client) StageAndCommit(
 				CopySourceRange: aws.String(
 					"bytes=" + fmt.Sprint(blk.StartIndex) + "-" + fmt.Sprint(blk.EndIndex-1),
 				),
-				PartNumber: &partNumber,
-				UploadId:   &uploadID,
+				PartNumber:                     &partNumber,
+				UploadId:                       &uploadID,
+				SSECustomerAlgorithm:           sse.customerAlgorithm,
+				SSECustomerKey:                 sse.customerKey,
+				SSECustomerKeyMD5:              sse.customerKeyMD5,
+				CopySourceSSECustomerAlgorithm: sse.customerAlgorithm,
+				CopySourceSSECustomerKey:       sse.customerKey,
+				CopySourceSSECustomerKeyMD5:    sse.customerKeyMD5,
 			})
 			if err != nil {
 				return err
@@ -1361,6 +1662,9 @@ func (cl *Client) StageAndCommit(
 		MultipartUpload: &types.CompletedMultipartUpload{
 			Parts: parts,
 		},
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
 	})
 	if err != nil {
 		log.Info(
@@ -1525,9 +1829,21 @@ func (cl *Client) GetCommittedBlockList(
 }
 
 // CommitBlocks : Initiates and completes an S3 multipart upload using locally cached blocks.
-func (cl *Client) CommitBlocks(ctx context.Context, name string, blockList []string) error {
+// The object is written with the given metadata.
+func (cl *Client) CommitBlocks(
+	ctx context.Context,
+	name string,
+	blockList []string,
+	metadata map[string]*string,
+) error {
 	log.Trace("Client::CommitBlocks: name %s, %d blocks", name, len(blockList))
 
+	metadata, err := cl.keepMetadata(ctx, name, false, metadata)
+	if err != nil {
+		log.Err("Client::CommitBlocks: Failed to get the metadata of %s. Here's why: %v", name, err)
+		return err
+	}
+
 	//struct for starting a multipart upload
 	key := cl.getKey(name, false, false)
 
@@ -1543,7 +1859,12 @@ func (cl *Client) CommitBlocks(ctx context.Context, name string, blockList []str
 			)
 			return cl.putObject(
 				ctx,
-				putObjectOptions{name: name, objectData: bytes.NewReader([]byte{}), size: 0},
+				putObjectOptions{
+					name:       name,
+					objectData: bytes.NewReader([]byte{}),
+					size:       0,
+					metadata:   getUserMetadata(metadata),
+				},
 			)
 		}
 		log.Err(
@@ -1555,10 +1876,24 @@ func (cl *Client) CommitBlocks(ctx context.Context, name string, blockList []str
 	cl.stagedBlocksMutex.RUnlock()
 
 	var uploadID string
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return err
+	}
+	headers, _ := cl.objectHeaders(name)
 	createMultipartUploadInput := &s3.CreateMultipartUploadInput{
-		Bucket:      aws.String(cl.Config.AuthConfig.BucketName),
-		Key:         aws.String(key),
-		ContentType: aws.String(getContentType(key)),
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		ContentType:          contentType(key, headers),
+		CacheControl:         optionalString(headers.CacheControl),
+		ContentEncoding:      optionalString(headers.ContentEncoding),
+		Metadata:             withRuleMetadata(getUserMetadata(metadata), headers),
+		ServerSideEncryption: sse.serverSideEncryption,
+		SSEKMSKeyId:          sse.kmsKeyID,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+		StorageClass:         cl.storageClass(name),
 	}
 
 	if cl.Config.enableChecksum {
@@ -1620,11 +1955,14 @@ func (cl *Client) CommitBlocks(ctx context.Context, name string, blockList []str
 		}
 
 		uploadPartInput := &s3.UploadPartInput{
-			Bucket:     aws.String(cl.Config.AuthConfig.BucketName),
-			Key:        aws.String(key),
-			UploadId:   aws.String(uploadID),
-			PartNumber: &currentPartNumber,
-			Body:       bytes.NewReader(blockData),
+			Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+			Key:                  aws.String(key),
+			UploadId:             aws.String(uploadID),
+			PartNumber:           &currentPartNumber,
+			Body:                 bytes.NewReader(blockData),
+			SSECustomerAlgorithm: sse.customerAlgorithm,
+			SSECustomerKey:       sse.customerKey,
+			SSECustomerKeyMD5:    sse.customerKeyMD5,
 		}
 		if cl.Config.enableChecksum {
 			uploadPartInput.ChecksumAlgorithm = cl.Config.checksumAlgorithm
@@ -1676,6 +2014,9 @@ func (cl *Client) CommitBlocks(ctx context.Context, name string, blockList []str
 		MultipartUpload: &types.CompletedMultipartUpload{
 			Parts: completedParts,
 		},
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
 	}
 
 	_, err = cl.AwsS3Client.CompleteMultipartUpload(ctx, completeInput)
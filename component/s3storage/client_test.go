@@ -34,6 +34,7 @@ import (
 	"fmt"
 	"io"
 	"math/rand/v2"
+	"net/url"
 	"os"
 	"path"
 	"strings"
@@ -834,6 +835,35 @@ func (s *clientTestSuite) TestDeleteDirectory() {
 	})
 	s.assert.NoError(err)
 }
+func (s *clientTestSuite) TestDeleteTree() {
+	defer s.cleanupTest()
+	// setup
+	dirName := generateDirectoryName()
+	fileName := generateFileName()
+	_, err := s.awsS3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:            aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:               aws.String(path.Join(dirName, fileName)),
+		ChecksumAlgorithm: s.client.Config.checksumAlgorithm,
+	})
+	s.assert.NoError(err)
+
+	err = s.client.DeleteTree(ctx, dirName)
+	s.assert.NoError(err)
+
+	// file in directory should be deleted along with the marker
+	_, err = s.awsS3Client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket:       aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:          aws.String(path.Join(dirName, fileName)),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	s.assert.Error(err)
+}
+func (s *clientTestSuite) TestDeleteTreeRoot() {
+	defer s.cleanupTest()
+
+	err := s.client.DeleteTree(ctx, "")
+	s.assert.ErrorIs(err, syscall.EPERM)
+}
 func (s *clientTestSuite) TestRenameFile() {
 	defer s.cleanupTest()
 	// Setup
@@ -865,6 +895,48 @@ func (s *clientTestSuite) TestRenameFile() {
 	})
 	s.assert.NoError(err)
 }
+func (s *clientTestSuite) TestMultipartCopy() {
+	defer s.cleanupTest()
+	// Setup
+	src := generateFileName()
+	body := []byte(randomString(int(2*s.client.Config.partSize + 1024)))
+	_, err := s.awsS3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:            aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:               aws.String(src),
+		Body:              bytes.NewReader(body),
+		ContentType:       aws.String("application/x-test"),
+		Metadata:          map[string]string{"team": "storage"},
+		ChecksumAlgorithm: s.client.Config.checksumAlgorithm,
+	})
+	s.assert.NoError(err)
+	dst := generateFileName()
+
+	// objects over 5 GB take this path, which works the same for smaller ones
+	err = s.client.multipartCopy(ctx, &s3.CopyObjectInput{
+		Bucket: aws.String(s.client.Config.AuthConfig.BucketName),
+		CopySource: aws.String(
+			fmt.Sprintf("%v/%v", s.client.Config.AuthConfig.BucketName, url.PathEscape(src)),
+		),
+		Key:               aws.String(dst),
+		ContentType:       aws.String("application/x-test"),
+		Metadata:          map[string]string{"team": "storage"},
+		ChecksumAlgorithm: s.client.Config.checksumAlgorithm,
+	}, int64(len(body)))
+	s.assert.NoError(err)
+
+	result, err := s.awsS3Client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:    aws.String(dst),
+	})
+	s.assert.NoError(err)
+	defer result.Body.Close()
+	s.assert.Equal("application/x-test", aws.ToString(result.ContentType))
+	s.assert.Equal("storage", result.Metadata["team"])
+	output, err := io.ReadAll(result.Body)
+	s.assert.NoError(err)
+	s.assert.Equal(body, output)
+}
+
 func (s *clientTestSuite) TestRenameFileError() {
 	defer s.cleanupTest()
 	// Setup
@@ -921,6 +993,68 @@ func (s *clientTestSuite) TestRenameDirectory() {
 	})
 	s.assert.NoError(err)
 }
+func (s *clientTestSuite) TestResumeDirectoryRenames() {
+	defer s.cleanupTest()
+	// setup
+	s.client.Config.uploadStatePath = s.T().TempDir()
+	srcDir := generateDirectoryName()
+	fileNames := []string{generateFileName(), generateFileName(), generateFileName()}
+	for _, fileName := range fileNames {
+		_, err := s.awsS3Client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket:            aws.String(s.client.Config.AuthConfig.BucketName),
+			Key:               aws.String(path.Join(srcDir, fileName)),
+			ChecksumAlgorithm: s.client.Config.checksumAlgorithm,
+		})
+		s.assert.NoError(err)
+	}
+
+	// a rename interrupted after copying its first object
+	dstDir := generateDirectoryName()
+	keys := make([]string, len(fileNames))
+	for i, fileName := range fileNames {
+		keys[i] = s.client.getKey(path.Join(srcDir, fileName), false, false)
+	}
+	journal, err := s.client.createRenameJournal(srcDir, dstDir, keys)
+	s.assert.NoError(err)
+	err = s.client.copyObject(ctx, copyObjectOptions{
+		source: path.Join(srcDir, fileNames[0]),
+		target: path.Join(dstDir, fileNames[0]),
+	})
+	s.assert.NoError(err)
+	s.assert.NoError(journal.append(keys[:1]))
+	s.client.closeRenameJournal(journal, false)
+
+	// a file written after the rename was interrupted is not part of it
+	newFileName := generateFileName()
+	_, err = s.awsS3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:            aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:               aws.String(path.Join(srcDir, newFileName)),
+		ChecksumAlgorithm: s.client.Config.checksumAlgorithm,
+	})
+	s.assert.NoError(err)
+
+	s.client.ResumeDirectoryRenames(ctx)
+	s.assert.NoFileExists(s.client.renameJournalFile(srcDir))
+
+	_, err = s.awsS3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:    aws.String(path.Join(srcDir, newFileName)),
+	})
+	s.assert.NoError(err)
+
+	for _, fileName := range fileNames {
+		_, err = s.awsS3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String(s.client.Config.AuthConfig.BucketName),
+			Key:    aws.String(path.Join(srcDir, fileName)),
+		})
+		s.assert.Error(err)
+		_, err = s.awsS3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String(s.client.Config.AuthConfig.BucketName),
+			Key:    aws.String(path.Join(dstDir, fileName)),
+		})
+		s.assert.NoError(err)
+	}
+}
 func (s *clientTestSuite) TestGetAttrDir() {
 	defer s.cleanupTest()
 	// setup
@@ -1002,6 +1136,109 @@ func (s *clientTestSuite) TestGetAttrFile() {
 
 	s.assert.True(after.Mtime.After(before.Mtime))
 }
+func (s *clientTestSuite) TestGetMetadata() {
+	defer s.cleanupTest()
+	// setup
+	name := generateFileName()
+	_, err := s.awsS3Client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:            aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:               aws.String(name),
+		Body:              bytes.NewReader([]byte("data")),
+		Metadata:          map[string]string{"label": "a"},
+		ChecksumAlgorithm: s.client.Config.checksumAlgorithm,
+	})
+	s.assert.NoError(err)
+
+	metadata, err := s.client.GetMetadata(ctx, name)
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+
+	// metadata is also part of the attributes so it is kept when the file is uploaded again
+	attr, err := s.client.GetAttr(ctx, name)
+	s.assert.NoError(err)
+	s.assert.Equal("a", *attr.Metadata["label"])
+
+	_, err = s.client.GetMetadata(ctx, generateFileName())
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
+func (s *clientTestSuite) TestSetMetadata() {
+	defer s.cleanupTest()
+	// setup
+	name := generateFileName() + ".txt"
+	body := []byte(randomString(20))
+	err := s.client.WriteFromBuffer(ctx, name, map[string]*string{"label": new("a")}, body)
+	s.assert.NoError(err)
+
+	err = s.client.SetMetadata(ctx, name, map[string]*string{"label": nil, "Owner": new("b")})
+	s.assert.NoError(err)
+
+	result, err := s.awsS3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:    aws.String(name),
+	})
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]string{"owner": "b"}, result.Metadata)
+	s.assert.Equal("text/plain", *result.ContentType)
+	s.assert.EqualValues(len(body), *result.ContentLength)
+
+	// the symlink flag is not user metadata
+	err = s.client.SetMetadata(ctx, name, map[string]*string{symlinkKey: new("true")})
+	s.assert.Equal(syscall.EINVAL, err)
+	// neither are the times kept by utimens
+	err = s.client.SetMetadata(ctx, name, map[string]*string{"Mtime": new("0")})
+	s.assert.Equal(syscall.EINVAL, err)
+}
+
+func (s *clientTestSuite) TestUtimens() {
+	defer s.cleanupTest()
+	// setup
+	name := generateFileName()
+	err := s.client.WriteFromBuffer(ctx, name, map[string]*string{"label": new("a")}, []byte("x"))
+	s.assert.NoError(err)
+
+	atime := time.Date(2019, time.January, 2, 3, 4, 5, 6, time.UTC)
+	mtime := time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC)
+	err = s.client.Utimens(ctx, name, atime, mtime)
+	s.assert.NoError(err)
+
+	attr, err := s.client.GetAttr(ctx, name)
+	s.assert.NoError(err)
+	s.assert.True(mtime.Equal(attr.Mtime))
+	// the times are not user metadata, but other user metadata is kept
+	metadata, err := s.client.GetMetadata(ctx, name)
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+
+	// the file is written and flushed again, which keeps the access time
+	f, err := os.CreateTemp("", name+".tmp")
+	s.assert.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write([]byte("new data"))
+	s.assert.NoError(err)
+	newMtime := mtime.Add(time.Hour)
+	metadata = map[string]*string{
+		internal.MtimeMetadataKey: new(internal.FormatMetadataTime(newMtime)),
+	}
+	err = s.client.WriteFromFile(ctx, name, metadata, f, nil, nil)
+	s.assert.NoError(err)
+	attr, err = s.client.GetAttr(ctx, name)
+	s.assert.NoError(err)
+	s.assert.True(atime.Equal(attr.Atime))
+	s.assert.True(newMtime.Equal(attr.Mtime))
+
+	// a directory without a marker has nowhere to keep the times
+	dirName := generateDirectoryName()
+	err = s.client.WriteFromBuffer(ctx, dirName+"/file", nil, []byte("x"))
+	s.assert.NoError(err)
+	err = s.client.Utimens(ctx, dirName, time.Time{}, mtime)
+	s.assert.NoError(err)
+
+	err = s.client.Utimens(ctx, generateFileName(), time.Time{}, mtime)
+	s.assert.Equal(syscall.ENOENT, err)
+}
+
 func (s *clientTestSuite) TestGetAttrError() {
 	defer s.cleanupTest()
 	// setup
@@ -1300,7 +1537,7 @@ func (s *clientTestSuite) TestWriteFromFile() {
 	s.assert.Equal(bodyLen, outputLen)
 	var options internal.WriteFileOptions //stub
 
-	err = s.client.WriteFromFile(ctx, name, options.Metadata, f)
+	err = s.client.WriteFromFile(ctx, name, options.Metadata, f, nil, nil)
 	s.assert.NoError(err)
 	f.Close()
 
@@ -1320,6 +1557,90 @@ func (s *clientTestSuite) TestWriteFromFile() {
 	s.assert.NoError(err)
 	s.assert.Equal(body, output)
 }
+func (s *clientTestSuite) TestWriteFromFileKeepsMetadata() {
+	defer s.cleanupTest()
+	// setup
+	name := generateFileName()
+	err := s.client.WriteFromBuffer(ctx, name, nil, []byte("x"))
+	s.assert.NoError(err)
+	err = s.client.SetMetadata(ctx, name, map[string]*string{"label": new("a")})
+	s.assert.NoError(err)
+
+	// the file is written and flushed again, with the modification time of the local copy
+	f, err := os.CreateTemp("", name+".tmp")
+	s.assert.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write([]byte("new data"))
+	s.assert.NoError(err)
+	mtime := time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC)
+	metadata := map[string]*string{
+		internal.MtimeMetadataKey: new(internal.FormatMetadataTime(mtime)),
+	}
+	err = s.client.WriteFromFile(ctx, name, metadata, f, nil, nil)
+	s.assert.NoError(err)
+
+	metadata, err = s.client.GetMetadata(ctx, name)
+	s.assert.NoError(err)
+	s.assert.Equal(map[string]*string{"label": new("a")}, metadata)
+	attr, err := s.client.GetAttr(ctx, name)
+	s.assert.NoError(err)
+	s.assert.True(mtime.Equal(attr.Mtime))
+}
+
+func (s *clientTestSuite) TestWriteFromFileResumesUpload() {
+	defer s.cleanupTest()
+	// setup
+	name := generateFileName()
+	s.client.Config.uploadStatePath = s.T().TempDir()
+	partSize := s.client.Config.partSize
+	body := []byte(randomString(int(2*partSize + partSize/2)))
+	f, err := os.CreateTemp("", name+".tmp")
+	s.assert.NoError(err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+	_, err = f.Write(body)
+	s.assert.NoError(err)
+	stat, err := f.Stat()
+	s.assert.NoError(err)
+
+	// an upload interrupted after its first part
+	state := &uploadState{
+		Bucket:            s.client.Config.AuthConfig.BucketName,
+		Key:               name,
+		Size:              stat.Size(),
+		ModTime:           stat.ModTime(),
+		PartSize:          partSize,
+		ChecksumAlgorithm: s.client.Config.checksumAlgorithm,
+	}
+	state.UploadID, err = s.client.createUpload(ctx, name, name, nil)
+	s.assert.NoError(err)
+	part, err := s.client.uploadPart(ctx, f, state, 1)
+	s.assert.NoError(err)
+	state.Parts = append(state.Parts, part)
+	statePath := s.client.uploadStateFile(name)
+	s.assert.NoError(state.save(statePath))
+
+	var eTag string
+	err = s.client.WriteFromFile(ctx, name, nil, f, nil, &eTag)
+	s.assert.NoError(err)
+	s.assert.NotEmpty(eTag)
+	s.assert.NoFileExists(statePath)
+
+	// the interrupted upload was the one completed
+	_, err = s.client.listUploadedParts(ctx, name, state.UploadID)
+	s.assert.True(isNoSuchUpload(err))
+	result, err := s.awsS3Client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.client.Config.AuthConfig.BucketName),
+		Key:    aws.String(name),
+	})
+	s.assert.NoError(err)
+	defer result.Body.Close()
+	output, err := io.ReadAll(result.Body)
+	s.assert.NoError(err)
+	s.assert.Equal(body, output)
+}
+
 func (s *clientTestSuite) TestWriteFromBuffer() {
 	defer s.cleanupTest()
 	// setup
@@ -28,11 +28,16 @@ package s3storage
 import (
 	"errors"
 	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/config"
 	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
 	"github.com/awnumar/memguard"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -57,11 +62,48 @@ type Options struct {
 	DisableUsage              bool                    `config:"disable-usage"                 yaml:"disable-usage,omitempty"`
 	EnableDirMarker           bool                    `config:"enable-dir-marker"             yaml:"enable-dir-marker,omitempty"`
 	HealthCheckIntervalSec    int                     `config:"health-check-interval-sec"     yaml:"health-check-interval-sec,omitempty"`
+	ServerSideEncryption      string                  `config:"server-side-encryption"        yaml:"server-side-encryption,omitempty"`
+	SSEKMSKeyID               string                  `config:"sse-kms-key-id"                yaml:"sse-kms-key-id,omitempty"`
+	EnableVersions            bool                    `config:"enable-versions"               yaml:"enable-versions,omitempty"`
+	AsOf                      string                  `config:"as-of"                         yaml:"as-of,omitempty"`
+	RestoreDays               int32                   `config:"restore-days"                  yaml:"restore-days,omitempty"`
+	RestoreTier               types.Tier              `config:"restore-tier"                  yaml:"restore-tier,omitempty"`
+	StorageClassRules         []StorageClassRule      `config:"storage-class-rules"           yaml:"storage-class-rules,omitempty"`
+	HeaderRules               []internal.HeaderRule   `config:"header-rules"                  yaml:"header-rules,omitempty"`
+	UploadStatePath           string                  `config:"upload-state-path"             yaml:"upload-state-path,omitempty"`
+	Mode                      string                  `config:"mode"                          yaml:"mode,omitempty"`
+	RoleARN                   string                  `config:"role-arn"                      yaml:"role-arn,omitempty"`
+	RoleSessionName           string                  `config:"role-session-name"             yaml:"role-session-name,omitempty"`
+	ExternalID                string                  `config:"external-id"                   yaml:"external-id,omitempty"`
+	SessionDurationSec        int                     `config:"session-duration-sec"          yaml:"session-duration-sec,omitempty"`
+	WebIdentityTokenFile      string                  `config:"web-identity-token-file"       yaml:"web-identity-token-file,omitempty"`
+	CredentialProcess         string                  `config:"credential-process"            yaml:"credential-process,omitempty"`
+	STSEndpoint               string                  `config:"sts-endpoint"                  yaml:"sts-endpoint,omitempty"`
+	Endpoints                 []string                `config:"endpoints"                     yaml:"endpoints,omitempty"`
+	FailoverWrites            bool                    `config:"failover-writes"               yaml:"failover-writes,omitempty"`
+	MaxRequestsPerSec         RequestRates            `config:"max-requests-per-sec"          yaml:"max-requests-per-sec,omitempty"`
+	MaxUploadMbPerSec         int64                   `config:"max-upload-mb-per-sec"         yaml:"max-upload-mb-per-sec,omitempty"`
+	MaxDownloadMbPerSec       int64                   `config:"max-download-mb-per-sec"       yaml:"max-download-mb-per-sec,omitempty"`
+}
+
+// StorageClassRule sets the storage class of the objects uploaded to paths matching Pattern
+type StorageClassRule struct {
+	Pattern      string             `config:"pattern"       yaml:"pattern"`
+	StorageClass types.StorageClass `config:"storage-class" yaml:"storage-class"`
+}
+
+// RequestRates caps the requests per second of each type of request. Zero is no cap.
+type RequestRates struct {
+	List int `config:"list" yaml:"list,omitempty"`
+	Head int `config:"head" yaml:"head,omitempty"`
+	Get  int `config:"get"  yaml:"get,omitempty"`
+	Put  int `config:"put"  yaml:"put,omitempty"`
 }
 
 type ConfigSecrets struct {
-	KeyID     *memguard.Enclave
-	SecretKey *memguard.Enclave
+	KeyID          *memguard.Enclave
+	SecretKey      *memguard.Enclave
+	SSECustomerKey *memguard.Enclave
 }
 
 const (
@@ -82,9 +124,29 @@ func ParseAndValidateConfig(s3 *S3Storage, opt Options, secrets ConfigSecrets) e
 	s3.stConfig.AuthConfig.BucketName = opt.BucketName
 	s3.stConfig.AuthConfig.KeyID = secrets.KeyID
 	s3.stConfig.AuthConfig.SecretKey = secrets.SecretKey
+	s3.stConfig.AuthConfig.SSECustomerKey = secrets.SSECustomerKey
 	s3.stConfig.AuthConfig.Region = opt.Region
 	s3.stConfig.AuthConfig.Profile = opt.Profile
 	s3.stConfig.AuthConfig.Endpoint = opt.Endpoint
+	s3.stConfig.endpoints = nil
+	if len(opt.Endpoints) > 0 {
+		if opt.Endpoint != "" {
+			return fmt.Errorf("%w: endpoint and endpoints cannot both be set", errInvalidConfigField)
+		}
+		for i, endpoint := range opt.Endpoints {
+			if endpoint == "" || slices.Contains(opt.Endpoints[:i], endpoint) {
+				return fmt.Errorf("%w: invalid endpoints entry %q", errInvalidConfigField, endpoint)
+			}
+		}
+		// the first endpoint is the primary one
+		s3.stConfig.AuthConfig.Endpoint = opt.Endpoints[0]
+		s3.stConfig.endpoints = opt.Endpoints
+	}
+	s3.stConfig.failoverWrites = opt.FailoverWrites
+	err := parseAuthMode(&s3.stConfig.AuthConfig, opt)
+	if err != nil {
+		return err
+	}
 
 	// Set restricted characters
 	s3.stConfig.restrictedCharsWin = opt.RestrictedCharsWin
@@ -92,6 +154,7 @@ func ParseAndValidateConfig(s3 *S3Storage, opt Options, secrets ConfigSecrets) e
 	s3.stConfig.usePathStyle = opt.UsePathStyle
 	s3.stConfig.disableUsage = opt.DisableUsage
 	s3.stConfig.enableDirMarker = opt.EnableDirMarker
+	s3.stConfig.enableVersions = opt.EnableVersions
 
 	// Part size must be at least 5 MB and smaller than 5GB. Otherwise, set to default.
 	if opt.PartSizeMb < 5 || opt.PartSizeMb > MaxPartSizeMb {
@@ -148,6 +211,111 @@ func ParseAndValidateConfig(s3 *S3Storage, opt Options, secrets ConfigSecrets) e
 		s3.stConfig.checksumAlgorithm = opt.ChecksumAlgorithm
 	}
 
+	sseMode, err := parseSSEMode(opt.ServerSideEncryption)
+	if err != nil {
+		return err
+	}
+	if opt.SSEKMSKeyID != "" && sseMode != sseKMS {
+		return fmt.Errorf(
+			"%w: sse-kms-key-id requires server-side-encryption: %s",
+			errInvalidConfigField,
+			sseKMS,
+		)
+	}
+	if (secrets.SSECustomerKey != nil) != (sseMode == sseC) {
+		return fmt.Errorf(
+			"%w: sse-customer-key is required by, and only used with, server-side-encryption: %s",
+			errInvalidConfigField,
+			sseC,
+		)
+	}
+	s3.stConfig.sseMode = sseMode
+	s3.stConfig.sseKMSKeyID = opt.SSEKMSKeyID
+
+	s3.stConfig.asOf = time.Time{}
+	if opt.AsOf != "" {
+		asOf, err := time.Parse(time.RFC3339, opt.AsOf)
+		if err != nil {
+			return fmt.Errorf("%w: as-of must be an RFC 3339 time", errInvalidConfigField)
+		}
+		// a time in the future would let later versions show up while mounted
+		if asOf.After(time.Now()) {
+			return fmt.Errorf("%w: as-of must not be in the future", errInvalidConfigField)
+		}
+		s3.stConfig.asOf = asOf
+	}
+
+	switch {
+	case opt.RestoreDays < 0:
+		return fmt.Errorf("%w: restore-days must be positive", errInvalidConfigField)
+	case opt.RestoreDays == 0:
+		s3.stConfig.restoreDays = defaultRestoreDays
+	default:
+		s3.stConfig.restoreDays = opt.RestoreDays
+	}
+	if opt.RestoreTier == "" {
+		opt.RestoreTier = types.TierStandard
+	}
+	if !slices.Contains(opt.RestoreTier.Values(), opt.RestoreTier) {
+		return fmt.Errorf(
+			"%w: restore-tier is not valid. valid values are Standard, Bulk, Expedited",
+			errInvalidConfigField,
+		)
+	}
+	s3.stConfig.restoreTier = opt.RestoreTier
+
+	s3.stConfig.storageClassRules = nil
+	for _, rule := range opt.StorageClassRules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil || rule.Pattern == "" {
+			return fmt.Errorf(
+				"%w: invalid storage-class-rules pattern %q",
+				errInvalidConfigField,
+				rule.Pattern,
+			)
+		}
+		if rule.StorageClass == "" {
+			return fmt.Errorf(
+				"%w: storage-class-rules pattern %q has no storage-class",
+				errInvalidConfigField,
+				rule.Pattern,
+			)
+		}
+		rule.StorageClass = types.StorageClass(strings.ToUpper(string(rule.StorageClass)))
+		s3.stConfig.storageClassRules = append(s3.stConfig.storageClassRules, rule)
+	}
+	if err := internal.ValidateHeaderRules(opt.HeaderRules); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidConfigField, err)
+	}
+	s3.stConfig.headerRules = opt.HeaderRules
+
+	// keep the state of resumable uploads in the file cache, unless told otherwise
+	s3.stConfig.uploadStatePath = common.ExpandPath(strings.TrimSpace(opt.UploadStatePath))
+	if s3.stConfig.uploadStatePath == "" && config.IsSet("file_cache.path") {
+		var cachePath string
+		err := config.UnmarshalKey("file_cache.path", &cachePath)
+		if err != nil {
+			log.Err("ParseAndValidateConfig : Failed to unmarshal file_cache.path [%v]", err)
+		} else if cachePath = strings.TrimSpace(cachePath); cachePath != "" {
+			s3.stConfig.uploadStatePath = filepath.Join(
+				common.ExpandPath(cachePath),
+				common.UploadStateDirName,
+			)
+		}
+	}
+
+	rates := opt.MaxRequestsPerSec
+	if rates.List < 0 || rates.Head < 0 || rates.Get < 0 || rates.Put < 0 ||
+		opt.MaxUploadMbPerSec < 0 || opt.MaxDownloadMbPerSec < 0 {
+		return fmt.Errorf(
+			"%w: max-requests-per-sec, max-upload-mb-per-sec and max-download-mb-per-sec "+
+				"must not be negative",
+			errInvalidConfigField,
+		)
+	}
+	s3.stConfig.requestRates = rates
+	s3.stConfig.uploadRate = opt.MaxUploadMbPerSec * common.MbToBytes
+	s3.stConfig.downloadRate = opt.MaxDownloadMbPerSec * common.MbToBytes
+
 	// by default symlink will be disabled
 	enableSymlinks := false
 	// Borrow enable-symlinks flag from attribute cache
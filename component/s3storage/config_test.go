@@ -26,11 +26,19 @@
 package s3storage
 
 import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
 	"fmt"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
 	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
 	"github.com/awnumar/memguard"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -170,6 +178,381 @@ func (s *configTestSuite) TestInvalidChecksum() {
 	s.assert.ErrorIs(err, errInvalidConfigField)
 }
 
+func (s *configTestSuite) TestServerSideEncryption() {
+	// When
+	s.opt.ServerSideEncryption = ""
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(sseNone, s.s3.stConfig.sseMode)
+
+	// When
+	s.opt.ServerSideEncryption = "SSE-S3"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(sseS3, s.s3.stConfig.sseMode)
+	headers, err := (&Client{Config: s.s3.stConfig}).sseHeaders()
+	s.assert.NoError(err)
+	s.assert.Equal(types.ServerSideEncryptionAes256, headers.serverSideEncryption)
+	s.assert.Nil(headers.kmsKeyID)
+	s.assert.Nil(headers.customerKey)
+
+	// When
+	s.opt.ServerSideEncryption = "sse-kms"
+	s.opt.SSEKMSKeyID = "testKeyArn"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(sseKMS, s.s3.stConfig.sseMode)
+	headers, err = (&Client{Config: s.s3.stConfig}).sseHeaders()
+	s.assert.NoError(err)
+	s.assert.Equal(types.ServerSideEncryptionAwsKms, headers.serverSideEncryption)
+	s.assert.Equal("testKeyArn", *headers.kmsKeyID)
+	s.assert.Nil(headers.customerKey)
+}
+
+func (s *configTestSuite) TestInvalidServerSideEncryption() {
+	// When
+	s.opt.ServerSideEncryption = "invalid"
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	// When
+	s.opt.ServerSideEncryption = "sse-s3"
+	s.opt.SSEKMSKeyID = "testKeyArn"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestSSECustomerKey() {
+	// When
+	s.opt.ServerSideEncryption = "sse-c"
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	// When
+	key := bytes.Repeat([]byte{0x42}, sseCustomerKeyLength)
+	decodedKey, err := decodeSSECustomerKey(base64.StdEncoding.EncodeToString(key))
+	s.assert.NoError(err)
+	s.secrets.SSECustomerKey = memguard.NewEnclave(decodedKey)
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(sseC, s.s3.stConfig.sseMode)
+	headers, err := (&Client{Config: s.s3.stConfig}).sseHeaders()
+	s.assert.NoError(err)
+	s.assert.Empty(headers.serverSideEncryption)
+	s.assert.Equal("AES256", *headers.customerAlgorithm)
+	s.assert.Equal(base64.StdEncoding.EncodeToString(key), *headers.customerKey)
+	keyMD5 := md5.Sum(key)
+	s.assert.Equal(base64.StdEncoding.EncodeToString(keyMD5[:]), *headers.customerKeyMD5)
+
+	// When
+	s.opt.ServerSideEncryption = "sse-s3"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestInvalidSSECustomerKey() {
+	_, err := decodeSSECustomerKey("not base64")
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	shortKey := base64.StdEncoding.EncodeToString([]byte("too short"))
+	_, err = decodeSSECustomerKey(shortKey)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestEnableVersions() {
+	// When
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+
+	// Then
+	s.assert.NoError(err)
+	s.assert.False(s.s3.stConfig.enableVersions)
+	s.assert.NoError(s.s3.checkWritable("dir/.versions/file"))
+
+	// When
+	s.opt.EnableVersions = true
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.True(s.s3.stConfig.enableVersions)
+	s.assert.NoError(s.s3.checkWritable("dir/file", "dir/file.versions"))
+	s.assert.Equal(syscall.EROFS, s.s3.checkWritable("dir/file", "dir/.versions/file"))
+}
+
+func (s *configTestSuite) TestAsOf() {
+	// When
+	s.opt.AsOf = "2024-01-02T03:04:05+01:00"
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.True(time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC).Equal(s.s3.stConfig.asOf))
+	s.assert.Equal(syscall.EROFS, s.s3.checkWritable("dir/file"))
+
+	// When
+	s.opt.AsOf = ""
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.True(s.s3.stConfig.asOf.IsZero())
+	s.assert.NoError(s.s3.checkWritable("dir/file"))
+}
+
+func (s *configTestSuite) TestInvalidAsOf() {
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	for _, asOf := range []string{"2024-01-02", "yesterday", future} {
+		// When
+		s.opt.AsOf = asOf
+
+		// Then
+		err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+		s.assert.ErrorIs(err, errInvalidConfigField, asOf)
+	}
+}
+
+func (s *configTestSuite) TestRestoreOptions() {
+	// When
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+
+	// Then
+	s.assert.NoError(err)
+	s.assert.EqualValues(defaultRestoreDays, s.s3.stConfig.restoreDays)
+	s.assert.Equal(types.TierStandard, s.s3.stConfig.restoreTier)
+
+	// When
+	s.opt.RestoreDays = 7
+	s.opt.RestoreTier = types.TierBulk
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.EqualValues(7, s.s3.stConfig.restoreDays)
+	s.assert.Equal(types.TierBulk, s.s3.stConfig.restoreTier)
+}
+
+func (s *configTestSuite) TestInvalidRestoreOptions() {
+	// When
+	s.opt.RestoreDays = -1
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	// When
+	s.opt.RestoreDays = 0
+	s.opt.RestoreTier = "Fast"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestStorageClassRules() {
+	// When
+	s.opt.StorageClassRules = []StorageClassRule{
+		{Pattern: "logs/", StorageClass: "standard_ia"},
+		{Pattern: "scratch/", StorageClass: types.StorageClassGlacierIr},
+	}
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal([]StorageClassRule{
+		{Pattern: "logs/", StorageClass: types.StorageClassStandardIa},
+		{Pattern: "scratch/", StorageClass: types.StorageClassGlacierIr},
+	}, s.s3.stConfig.storageClassRules)
+
+	for _, rule := range []StorageClassRule{
+		{Pattern: "[logs/", StorageClass: types.StorageClassStandardIa},
+		{Pattern: "", StorageClass: types.StorageClassStandardIa},
+		{Pattern: "logs/"},
+	} {
+		// When
+		s.opt.StorageClassRules = []StorageClassRule{rule}
+
+		// Then
+		err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+		s.assert.ErrorIs(err, errInvalidConfigField, rule.Pattern)
+	}
+}
+
+func (s *configTestSuite) TestHeaderRules() {
+	// When
+	s.opt.HeaderRules = []internal.HeaderRule{{Pattern: "*.html", CacheControl: "no-cache"}}
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(s.opt.HeaderRules, s.s3.stConfig.headerRules)
+
+	// When
+	s.opt.HeaderRules = []internal.HeaderRule{{Pattern: "[*.html", CacheControl: "no-cache"}}
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestUploadStatePath() {
+	defer config.ResetConfig()
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Empty(s.s3.stConfig.uploadStatePath)
+
+	// When
+	config.Set("file_cache.path", "/tmp/cache")
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(
+		filepath.Join("/tmp/cache", common.UploadStateDirName),
+		s.s3.stConfig.uploadStatePath,
+	)
+
+	// When
+	s.opt.UploadStatePath = "/tmp/uploads"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal("/tmp/uploads", s.s3.stConfig.uploadStatePath)
+}
+
+func (s *configTestSuite) TestAuthMode() {
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(EAuthType.DEFAULT(), s.s3.stConfig.AuthConfig.Mode)
+
+	// When
+	s.opt.Mode = "assumerole"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	// When
+	s.opt.RoleARN = "arn:aws:iam::123456789012:role/cloudfuse"
+	s.opt.ExternalID = "external"
+	s.opt.SessionDurationSec = 3600
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(EAuthType.ASSUMEROLE(), s.s3.stConfig.AuthConfig.Mode)
+	s.assert.Equal(s.opt.RoleARN, s.s3.stConfig.AuthConfig.RoleARN)
+	s.assert.Equal("external", s.s3.stConfig.AuthConfig.ExternalID)
+	s.assert.Equal(defaultRoleSessionName, s.s3.stConfig.AuthConfig.RoleSessionName)
+	s.assert.Equal(time.Hour, s.s3.stConfig.AuthConfig.SessionDuration)
+
+	// When
+	s.opt.SessionDurationSec = 60
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	// When
+	s.opt.SessionDurationSec = 0
+	s.opt.Mode = "webidentity"
+	s.T().Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "/var/run/secrets/token")
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(EAuthType.WEBIDENTITY(), s.s3.stConfig.AuthConfig.Mode)
+	s.assert.Equal("/var/run/secrets/token", s.s3.stConfig.AuthConfig.WebIdentityTokenFile)
+
+	// When
+	s.opt.Mode = "credentialprocess"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	// When
+	s.opt.CredentialProcess = "/usr/local/bin/get-credentials"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(EAuthType.CREDENTIALPROCESS(), s.s3.stConfig.AuthConfig.Mode)
+
+	// When
+	s.opt.Mode = "sso"
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestEndpoints() {
+	// When
+	s.opt.Endpoints = []string{"https://primary", "https://dr"}
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+
+	// When
+	s.opt.Endpoint = ""
+	s.opt.FailoverWrites = true
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal("https://primary", s.s3.stConfig.AuthConfig.Endpoint)
+	s.assert.Equal(s.opt.Endpoints, s.s3.stConfig.endpoints)
+	s.assert.True(s.s3.stConfig.failoverWrites)
+
+	// When
+	s.opt.Endpoints = []string{"https://primary", "https://primary"}
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
+func (s *configTestSuite) TestRateLimits() {
+	// When
+	s.opt.MaxRequestsPerSec = RequestRates{Put: 50}
+	s.opt.MaxUploadMbPerSec = 20
+
+	// Then
+	err := ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.NoError(err)
+	s.assert.Equal(RequestRates{Put: 50}, s.s3.stConfig.requestRates)
+	s.assert.EqualValues(20*common.MbToBytes, s.s3.stConfig.uploadRate)
+	s.assert.Zero(s.s3.stConfig.downloadRate)
+
+	// When
+	s.opt.MaxRequestsPerSec.Get = -1
+
+	// Then
+	err = ParseAndValidateConfig(s.s3, s.opt, s.secrets)
+	s.assert.ErrorIs(err, errInvalidConfigField)
+}
+
 func TestConfigTestSuite(t *testing.T) {
 	suite.Run(t, new(configTestSuite))
 }
@@ -34,7 +34,6 @@ import (
 	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/internal"
 
-	"github.com/awnumar/memguard"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
@@ -58,20 +57,20 @@ type Config struct {
 	disableUsage              bool
 	enableDirMarker           bool
 	healthCheckInterval       time.Duration
-}
-
-// TODO: move s3AuthConfig to s3auth.go
-// TODO: handle different types of authentication
-// TODO: write tests in s3auth_test.go
-
-// s3AuthConfig : Config to authenticate to storage
-type s3AuthConfig struct {
-	BucketName string
-	KeyID      *memguard.Enclave
-	SecretKey  *memguard.Enclave
-	Region     string
-	Profile    string
-	Endpoint   string
+	sseMode                   string
+	sseKMSKeyID               string
+	enableVersions            bool
+	asOf                      time.Time // zero unless the mount is point-in-time
+	restoreDays               int32
+	restoreTier               types.Tier
+	storageClassRules         []StorageClassRule
+	headerRules               []internal.HeaderRule
+	uploadStatePath           string   // empty unless uploads are resumable
+	endpoints                 []string // empty unless there are failover endpoints
+	failoverWrites            bool
+	requestRates              RequestRates
+	uploadRate                int64 // bytes per second, zero if not capped
+	downloadRate              int64 // bytes per second, zero if not capped
 }
 
 // NewConnection : Create S3Connection Object
@@ -86,6 +85,8 @@ type S3Connection interface {
 	UpdateConfig(cfg Config) error
 
 	ConnectionOkay(ctx context.Context) error
+	ActiveEndpoint() string
+	WatchEndpoints(ctx context.Context)
 	ListBuckets(ctx context.Context) ([]string, error)
 	ListAuthorizedBuckets(ctx context.Context) ([]string, error)
 
@@ -101,8 +102,12 @@ type S3Connection interface {
 
 	RenameFile(ctx context.Context, source string, target string, isSymLink bool) error
 	RenameDirectory(ctx context.Context, source string, target string) error
+	ResumeDirectoryRenames(ctx context.Context)
 
 	GetAttr(ctx context.Context, name string) (attr *internal.ObjAttr, err error)
+	GetMetadata(ctx context.Context, name string) (map[string]*string, error)
+	SetMetadata(ctx context.Context, name string, metadata map[string]*string) error
+	Utimens(ctx context.Context, name string, atime time.Time, mtime time.Time) error
 
 	// Standard operations to be supported by any account type
 	List(
@@ -122,7 +127,14 @@ type S3Connection interface {
 	) ([]byte, error)
 	ReadInBuffer(ctx context.Context, name string, offset int64, length int64, data []byte) error
 
-	WriteFromFile(ctx context.Context, name string, metadata map[string]*string, fi *os.File) error
+	WriteFromFile(
+		ctx context.Context,
+		name string,
+		metadata map[string]*string,
+		fi *os.File,
+		ifMatch *string,
+		newETag *string,
+	) error
 	WriteFromBuffer(
 		ctx context.Context,
 		name string,
@@ -137,7 +149,12 @@ type S3Connection interface {
 
 	GetCommittedBlockList(ctx context.Context, name string) (*internal.CommittedBlockList, error)
 	StageBlock(name string, data []byte, id string) error
-	CommitBlocks(ctx context.Context, name string, blockList []string) error
+	CommitBlocks(
+		ctx context.Context,
+		name string,
+		blockList []string,
+		metadata map[string]*string,
+	) error
 
 	NewCredentialKey(_, _ string) error
 	GetUsedSize(ctx context.Context) (uint64, error)
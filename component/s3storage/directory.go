@@ -0,0 +1,460 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+
+	"github.com/Seagate/cloudfuse/common/log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Directories are deleted a listing page at a time. A directory rename lists every object under
+// the source first, then moves them a thousand at a time: the objects of a batch are copied
+// concurrently, then deleted with a single DeleteObjects request.
+// When there is a state directory, the objects listed are written to a journal before the first
+// copy, and the names of the objects copied are appended before they are deleted. A rename
+// interrupted by a crash is completed when the next mount starts, moving only the objects it
+// listed, and without copying those objects again.
+
+const (
+	// DeleteObjects takes up to 1,000 keys
+	maxDeleteObjects    = 1000
+	renameJournalPrefix = "rename-"
+	renameJournalSuffix = ".journal"
+)
+
+var errInvalidRenameJournal = errors.New("invalid rename journal")
+
+// renameJournalHeader is the first line of a rename journal
+type renameJournalHeader struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// renameJournalRecord is a line of a rename journal after the header. Each object to move is
+// recorded before the first copy, and recorded again once it is copied.
+type renameJournalRecord struct {
+	Key    string `json:"key"`
+	Copied bool   `json:"copied,omitempty"`
+}
+
+type renameJournal struct {
+	path string
+	file *os.File
+}
+
+// renameJournalFile returns the path of the journal of renames of source
+func (cl *Client) renameJournalFile(source string) string {
+	key := cl.getKey(source, false, true)
+	sum := sha256.Sum256([]byte(cl.Config.AuthConfig.BucketName + "/" + key))
+	name := renameJournalPrefix + hex.EncodeToString(sum[:]) + renameJournalSuffix
+	return filepath.Join(cl.Config.uploadStatePath, name)
+}
+
+// createRenameJournal starts the journal of the rename of source to target, which moves the
+// objects with the given keys. The journal is only in place once all of them are recorded.
+// It returns nil when there is no state directory.
+func (cl *Client) createRenameJournal(
+	source string,
+	target string,
+	keys []string,
+) (*renameJournal, error) {
+	if cl.Config.uploadStatePath == "" {
+		return nil, nil
+	}
+	header, err := json.Marshal(renameJournalHeader{
+		Bucket: cl.Config.AuthConfig.BucketName,
+		Prefix: cl.Config.prefixPath,
+		Source: source,
+		Target: target,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Write(header)
+	buf.WriteByte('\n')
+	err = writeRenameRecords(&buf, keys, false)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(cl.Config.uploadStatePath, 0700)
+	if err != nil {
+		return nil, err
+	}
+	path := cl.renameJournalFile(source)
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(buf.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return nil, err
+	}
+	j := &renameJournal{path: path}
+	j.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		cl.removeStateFile(path)
+		return nil, err
+	}
+	return j, nil
+}
+
+// writeRenameRecords writes a journal record for each key
+func writeRenameRecords(buf *bytes.Buffer, keys []string, copied bool) error {
+	for _, key := range keys {
+		line, err := json.Marshal(renameJournalRecord{Key: key, Copied: copied})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return nil
+}
+
+// openRenameJournal opens the journal at path to complete its rename.
+// It returns the header of the journal, the keys of the objects to move, in the order they were
+// listed, and the keys of the objects already copied.
+func openRenameJournal(
+	path string,
+) (*renameJournal, renameJournalHeader, []string, map[string]bool, error) {
+	var header renameJournalHeader
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, header, nil, nil, err
+	}
+	var keys []string
+	listed := make(map[string]bool)
+	copied := make(map[string]bool)
+	lines := 0
+	for line := range bytes.Lines(data) {
+		lines++
+		var err error
+		var record renameJournalRecord
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			err = errors.New("incomplete line")
+		} else if lines == 1 {
+			err = json.Unmarshal(line, &header)
+		} else {
+			err = json.Unmarshal(line, &record)
+		}
+		if err != nil && lines == 1 {
+			return nil, header, nil, nil, fmt.Errorf(
+				"%w: %s [%v]",
+				errInvalidRenameJournal,
+				path,
+				err,
+			)
+		}
+		if err != nil {
+			// the objects are all listed before the journal is in place, so a line torn by a
+			// crash only costs a copy
+			log.Warn("Client::openRenameJournal : Dropping line %d of %s [%v]", lines, path, err)
+			break
+		}
+		switch {
+		case lines == 1:
+		case !record.Copied:
+			if !listed[record.Key] {
+				listed[record.Key] = true
+				keys = append(keys, record.Key)
+			}
+		case listed[record.Key]:
+			copied[record.Key] = true
+		}
+	}
+	if lines == 0 {
+		return nil, header, nil, nil, fmt.Errorf("%w: %s is empty", errInvalidRenameJournal, path)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, header, nil, nil, err
+	}
+	return &renameJournal{path: path, file: file}, header, keys, copied, nil
+}
+
+// append records that the objects with the given keys were copied, and waits until the record
+// is on disk
+func (j *renameJournal) append(keys []string) error {
+	if j == nil || len(keys) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	err := writeRenameRecords(&buf, keys, true)
+	if err != nil {
+		return err
+	}
+	_, err = j.file.Write(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return j.file.Sync()
+}
+
+// closeRenameJournal closes the journal, and removes it once the rename is complete
+func (cl *Client) closeRenameJournal(j *renameJournal, complete bool) {
+	if j == nil {
+		return
+	}
+	_ = j.file.Close()
+	if complete {
+		cl.removeStateFile(j.path)
+	}
+}
+
+// listTree lists every object under the directory
+func (cl *Client) listTree(ctx context.Context, name string) ([]types.Object, error) {
+	prefix := cl.getKey(name, false, true)
+	paginator := s3.NewListObjectsV2Paginator(cl.AwsS3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(cl.Config.AuthConfig.BucketName),
+		Prefix: aws.String(prefix),
+	})
+	var objects []types.Object
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, parseS3Err(err, fmt.Sprintf("list objects under %s", prefix))
+		}
+		objects = append(objects, output.Contents...)
+	}
+	return objects, nil
+}
+
+// moveTree moves the given objects from under the directory source to the directory target.
+// The objects in copied were already copied, and are only deleted. Objects that no longer exist
+// are skipped.
+// Objects that fail to move are logged and left behind, along with the journal, and make it
+// return EIO.
+func (cl *Client) moveTree(
+	ctx context.Context,
+	source string,
+	target string,
+	objects []types.Object,
+	journal *renameJournal,
+	copied map[string]bool,
+) error {
+	moved, failed := 0, 0
+	for batch := range slices.Chunk(objects, maxDeleteObjects) {
+		done := make([]bool, len(batch))
+		copyOne := func(ctx context.Context, number int32) error {
+			i := number - 1
+			key := aws.ToString(batch[i].Key)
+			if copied[key] {
+				done[i] = true
+				return nil
+			}
+			name, isSymLink := cl.getFile(key)
+			name = split(cl.Config.prefixPath, name)
+			dstPath := target + strings.TrimPrefix(name, source)
+			err := cl.copyObject(ctx, copyObjectOptions{
+				source:       name,
+				target:       dstPath,
+				isSymLink:    isSymLink,
+				isDir:        strings.HasSuffix(key, "/"),
+				storageClass: types.StorageClass(batch[i].StorageClass),
+				size:         aws.ToInt64(batch[i].Size),
+			})
+			if errors.Is(err, syscall.ENOENT) {
+				// deleted since it was listed, so there is nothing to move
+				log.Info("Client::moveTree : %s no longer exists", name)
+				done[i] = true
+				return nil
+			}
+			if err != nil {
+				log.Err(
+					"Client::moveTree : Failed to copy %s -> %s. Here's why: %v",
+					name,
+					dstPath,
+					err,
+				)
+				return nil
+			}
+			done[i] = true
+			return nil
+		}
+		err := cl.forEachPart(ctx, int32(len(batch)), nil, copyOne)
+		if err != nil {
+			cl.closeRenameJournal(journal, false)
+			return err
+		}
+
+		var copiedKeys, keys []string
+		for i, object := range batch {
+			if !done[i] {
+				failed++
+				continue
+			}
+			key := aws.ToString(object.Key)
+			if !copied[key] {
+				copiedKeys = append(copiedKeys, key)
+			}
+			keys = append(keys, key)
+		}
+		err = journal.append(copiedKeys)
+		if err != nil {
+			log.Warn("Client::moveTree : Failed to write %s [%v]", journal.path, err)
+		}
+		deleted, err := cl.deleteObjects(ctx, keys)
+		moved += deleted
+		failed += len(keys) - deleted
+		if err != nil {
+			cl.closeRenameJournal(journal, false)
+			return err
+		}
+		log.Info(
+			"Client::moveTree : Moved %d of %d objects from %s to %s so far (%d failed)",
+			moved,
+			len(objects),
+			source,
+			target,
+			failed,
+		)
+	}
+	cl.closeRenameJournal(journal, failed == 0)
+	if failed > 0 {
+		return fmt.Errorf("%w: failed to move %d objects", syscall.EIO, failed)
+	}
+	return nil
+}
+
+// deleteObjects deletes the objects with the given keys, in batches, and returns how many were
+// deleted. The objects that fail to be deleted are logged, and make it return EIO.
+func (cl *Client) deleteObjects(ctx context.Context, keys []string) (int, error) {
+	deleted := 0
+	for batch := range slices.Chunk(keys, maxDeleteObjects) {
+		objects := make([]types.ObjectIdentifier, len(batch))
+		for i, key := range batch {
+			objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+		output, err := cl.AwsS3Client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(cl.Config.AuthConfig.BucketName),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return deleted, parseS3Err(err, fmt.Sprintf("delete %d objects", len(batch)))
+		}
+		for _, deleteErr := range output.Errors {
+			log.Err(
+				"Client::deleteObjects : Failed to delete %s. Here's why: %s %s",
+				aws.ToString(deleteErr.Key),
+				aws.ToString(deleteErr.Code),
+				aws.ToString(deleteErr.Message),
+			)
+		}
+		deleted += len(batch) - len(output.Errors)
+		if len(output.Errors) > 0 {
+			return deleted, fmt.Errorf(
+				"%w: failed to delete %d objects, starting with %s",
+				syscall.EIO,
+				len(output.Errors),
+				aws.ToString(output.Errors[0].Key),
+			)
+		}
+	}
+	return deleted, nil
+}
+
+// ResumeDirectoryRenames completes the directory renames that were interrupted.
+// Only the objects listed by each rename are moved, so this is meant to run before the mount
+// serves requests.
+func (cl *Client) ResumeDirectoryRenames(ctx context.Context) {
+	if cl.Config.uploadStatePath == "" {
+		return
+	}
+	pattern := filepath.Join(
+		cl.Config.uploadStatePath,
+		renameJournalPrefix+"*"+renameJournalSuffix,
+	)
+	paths, _ := filepath.Glob(pattern)
+	for _, path := range paths {
+		journal, header, keys, copied, err := openRenameJournal(path)
+		if errors.Is(err, errInvalidRenameJournal) {
+			// the rename was interrupted before it started
+			log.Warn("Client::ResumeDirectoryRenames : Removing %v", err)
+			cl.removeStateFile(path)
+			continue
+		}
+		if err != nil {
+			log.Err("Client::ResumeDirectoryRenames : Failed to open %s [%v]", path, err)
+			continue
+		}
+		if header.Bucket != cl.Config.AuthConfig.BucketName ||
+			header.Prefix != cl.Config.prefixPath {
+			// the journal belongs to another mount sharing the state directory
+			cl.closeRenameJournal(journal, false)
+			continue
+		}
+		log.Info(
+			"Client::ResumeDirectoryRenames : Completing the rename of %s to %s",
+			header.Source,
+			header.Target,
+		)
+		// the size and storage class of the objects are looked up again as they are copied
+		objects := make([]types.Object, len(keys))
+		for i, key := range keys {
+			objects[i] = types.Object{Key: aws.String(key)}
+		}
+		err = cl.moveTree(ctx, header.Source, header.Target, objects, journal, copied)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Err(
+				"Client::ResumeDirectoryRenames : Failed to rename %s to %s. Here's why: %v",
+				header.Source,
+				header.Target,
+				err,
+			)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsMiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	smithyEndpoints "github.com/aws/smithy-go/endpoints"
+	"github.com/aws/smithy-go/middleware"
+	smithyHttp "github.com/aws/smithy-go/transport/http"
+)
+
+// With several endpoints, each attempt of a request is sent to the first endpoint that is not
+// known to be down. An endpoint is known to be down once a request fails to reach it, and until
+// a health check reaches it again, so the SDK retries the request on the next endpoint, and
+// requests go back to the primary endpoint as soon as it recovers.
+// Writes stay on the primary endpoint, unless failover-writes is set.
+
+// readOperations are the operations that can be sent to any endpoint
+var readOperations = map[string]bool{
+	"GetObject":          true,
+	"HeadObject":         true,
+	"HeadBucket":         true,
+	"ListBuckets":        true,
+	"ListObjectsV2":      true,
+	"ListObjectVersions": true,
+}
+
+type endpointKey struct{}
+
+// endpointSet tracks which of the endpoints, in order of preference, are down
+type endpointSet struct {
+	sync.Mutex
+	urls           []string
+	down           []bool
+	active         int // the first endpoint that is not down, or the last one that was
+	failoverWrites bool
+	ctx            context.Context // health checks of endpoints that are down end with it
+}
+
+func newEndpointSet(urls []string, failoverWrites bool) *endpointSet {
+	return &endpointSet{
+		urls:           urls,
+		down:           make([]bool, len(urls)),
+		failoverWrites: failoverWrites,
+		ctx:            context.Background(),
+	}
+}
+
+// setContext ends the health checks of endpoints that are down, and of those going down later,
+// with ctx
+func (e *endpointSet) setContext(ctx context.Context) {
+	e.Lock()
+	defer e.Unlock()
+	e.ctx = ctx
+}
+
+// context returns the context health checks end with
+func (e *endpointSet) context() context.Context {
+	e.Lock()
+	defer e.Unlock()
+	return e.ctx
+}
+
+// endpoint returns the endpoint a request should be sent to
+func (e *endpointSet) endpoint(write bool) string {
+	e.Lock()
+	defer e.Unlock()
+	if write && !e.failoverWrites {
+		return e.urls[0]
+	}
+	return e.urls[e.active]
+}
+
+// activeEndpoint returns the endpoint reads are sent to
+func (e *endpointSet) activeEndpoint() string {
+	return e.endpoint(false)
+}
+
+// markDown records that url could not be reached, and fails over to the next endpoint that is
+// not down, if there is one. It returns false if url was already known to be down.
+func (e *endpointSet) markDown(url string) bool {
+	e.Lock()
+	defer e.Unlock()
+	i := e.index(url)
+	if i < 0 || e.down[i] {
+		return false
+	}
+	e.down[i] = true
+	if i == e.active {
+		for next := range e.urls {
+			if !e.down[next] {
+				e.setActive(next)
+				break
+			}
+		}
+	}
+	if e.active == i {
+		log.Err("endpointSet::markDown : Endpoint %s is unreachable, as are the others", url)
+	}
+	return true
+}
+
+// markUp records that url was reached again, and fails back to it if it is preferred
+func (e *endpointSet) markUp(url string) {
+	e.Lock()
+	defer e.Unlock()
+	i := e.index(url)
+	if i < 0 {
+		return
+	}
+	e.down[i] = false
+	if i < e.active || e.down[e.active] {
+		e.setActive(i)
+	}
+}
+
+// forget clears the state of url, without failing back to it, so it is marked down again the
+// next time a request fails to reach it
+func (e *endpointSet) forget(url string) {
+	e.Lock()
+	defer e.Unlock()
+	if i := e.index(url); i >= 0 {
+		e.down[i] = false
+	}
+}
+
+func (e *endpointSet) index(url string) int {
+	for i, u := range e.urls {
+		if u == url {
+			return i
+		}
+	}
+	return -1
+}
+
+// setActive switches to the endpoint i, and reports it. Call with the lock held.
+func (e *endpointSet) setActive(i int) {
+	if i == e.active {
+		return
+	}
+	if i < e.active {
+		log.Info("endpointSet::setActive : Failing back from %s to %s", e.urls[e.active], e.urls[i])
+	} else {
+		log.Warn("endpointSet::setActive : Failing over from %s to %s", e.urls[e.active], e.urls[i])
+	}
+	e.active = i
+	if s3StatsCollector != nil {
+		s3StatsCollector.UpdateStats(stats_manager.Replace, activeEndpoint, e.urls[i])
+		s3StatsCollector.UpdateStats(stats_manager.Increment, endpointSwitches, (int64)(1))
+	}
+}
+
+// endpointFailover is the middleware choosing the endpoint of each attempt of a request
+type endpointFailover struct {
+	cl *Client
+}
+
+func (*endpointFailover) ID() string {
+	return "EndpointFailover"
+}
+
+func (m *endpointFailover) HandleFinalize(
+	ctx context.Context,
+	in middleware.FinalizeInput,
+	next middleware.FinalizeHandler,
+) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	// health checks set their endpoint
+	url, ok := ctx.Value(endpointKey{}).(string)
+	if !ok {
+		write := !readOperations[awsMiddleware.GetOperationName(ctx)]
+		url = m.cl.endpoints.endpoint(write)
+		ctx = context.WithValue(ctx, endpointKey{}, url)
+	}
+	out, metadata, err := next.HandleFinalize(ctx, in)
+	if isEndpointUnreachable(ctx, err) && m.cl.endpoints.markDown(url) {
+		go m.cl.watchEndpoint(m.cl.endpoints.context(), url)
+	}
+	return out, metadata, err
+}
+
+// endpointResolver sends requests to the endpoint chosen by endpointFailover
+type endpointResolver struct {
+	base s3.EndpointResolverV2
+}
+
+func (r *endpointResolver) ResolveEndpoint(
+	ctx context.Context,
+	params s3.EndpointParameters,
+) (smithyEndpoints.Endpoint, error) {
+	if url, ok := ctx.Value(endpointKey{}).(string); ok {
+		params.Endpoint = aws.String(url)
+	}
+	return r.base.ResolveEndpoint(ctx, params)
+}
+
+// addEndpointFailover sends the requests of the client to the endpoints of cl.endpoints
+func (cl *Client) addEndpointFailover(o *s3.Options) {
+	o.EndpointResolverV2 = &endpointResolver{base: s3.NewDefaultEndpointResolverV2()}
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		// before the endpoint is resolved, and after the retry middleware, to see every attempt
+		return stack.Finalize.Insert(
+			&endpointFailover{cl: cl},
+			"ResolveEndpointV2",
+			middleware.Before,
+		)
+	})
+}
+
+// isEndpointUnreachable returns true if the request failed to reach its endpoint
+func isEndpointUnreachable(ctx context.Context, err error) bool {
+	_, ok := errors.AsType[*smithyHttp.RequestSendError](err)
+	return ok && ctx.Err() == nil
+}
+
+// watchEndpoint checks the health of url until it can be reached again, or ctx is done
+func (cl *Client) watchEndpoint(ctx context.Context, url string) {
+	interval := cmp.Or(cl.Config.healthCheckInterval, defaultHealthCheckInterval)
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debug("Client::watchEndpoint : Stopped checking endpoint %s", url)
+			cl.endpoints.forget(url)
+			return
+		case <-timer.C:
+		}
+		checkCtx, cancel := context.WithTimeout(
+			context.WithValue(ctx, endpointKey{}, url),
+			maxHealthCheckInterval,
+		)
+		_, err := cl.AwsS3Client.HeadBucket(
+			checkCtx,
+			&s3.HeadBucketInput{Bucket: aws.String(cl.Config.AuthConfig.BucketName)},
+			func(o *s3.Options) { o.RetryMaxAttempts = 1 },
+		)
+		unreachable := isEndpointUnreachable(checkCtx, err) ||
+			errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil
+		cancel()
+		if !unreachable {
+			log.Info("Client::watchEndpoint : Endpoint %s is reachable again", url)
+			cl.endpoints.markUp(url)
+			return
+		}
+		log.Debug("Client::watchEndpoint : Endpoint %s is still unreachable [%v]", url, err)
+		interval = min(2*interval, maxHealthCheckInterval)
+		timer.Reset(interval)
+	}
+}
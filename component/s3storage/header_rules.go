@@ -0,0 +1,70 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"cmp"
+	"maps"
+
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// Header rules set the Content-Type, Cache-Control, Content-Encoding and metadata of uploaded
+// objects from their path. Objects matching no rule get the content type of their extension.
+
+// objectHeaders returns the headers set by the rules matching name, and whether a rule matched
+func (cl *Client) objectHeaders(name string) (internal.ObjectHeaders, bool) {
+	return internal.MatchHeaderRules(cl.Config.headerRules, name)
+}
+
+// contentType returns the content type of an upload
+func contentType(key string, headers internal.ObjectHeaders) *string {
+	return aws.String(cmp.Or(headers.ContentType, getContentType(key)))
+}
+
+// withRuleMetadata returns the metadata of an upload with the keys set by the rules added.
+// Metadata already on the object takes precedence.
+func withRuleMetadata(
+	metadata map[string]string,
+	headers internal.ObjectHeaders,
+) map[string]string {
+	if len(headers.Metadata) == 0 {
+		return metadata
+	}
+	merged := maps.Clone(headers.Metadata)
+	maps.Copy(merged, metadata)
+	return merged
+}
+
+// optionalString returns nil for an empty string, so the header is not sent
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
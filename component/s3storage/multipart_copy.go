@@ -0,0 +1,137 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/Seagate/cloudfuse/common/log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CopyObject is limited to objects up to 5 GB. Larger objects are copied with a multipart upload,
+// each part copied from a range of the source with UploadPartCopy. The data never leaves the
+// bucket, and the parts are copied concurrently.
+
+// multipartCopy performs the copy described by input, of a source of the given size, in parts.
+// input must have every header and metadata of the target set, since parts carry none of them.
+func (cl *Client) multipartCopy(ctx context.Context, input *s3.CopyObjectInput, size int64) error {
+	key := aws.ToString(input.Key)
+	log.Trace("Client::multipartCopy : %s -> %s (%d bytes)", *input.CopySource, key, size)
+
+	createOutput, err := cl.AwsS3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               input.Bucket,
+		Key:                  input.Key,
+		Metadata:             input.Metadata,
+		ContentType:          input.ContentType,
+		CacheControl:         input.CacheControl,
+		ContentDisposition:   input.ContentDisposition,
+		ContentEncoding:      input.ContentEncoding,
+		ContentLanguage:      input.ContentLanguage,
+		StorageClass:         input.StorageClass,
+		ChecksumAlgorithm:    input.ChecksumAlgorithm,
+		ServerSideEncryption: input.ServerSideEncryption,
+		SSEKMSKeyId:          input.SSEKMSKeyId,
+		SSECustomerAlgorithm: input.SSECustomerAlgorithm,
+		SSECustomerKey:       input.SSECustomerKey,
+		SSECustomerKeyMD5:    input.SSECustomerKeyMD5,
+	})
+	if err != nil {
+		return parseS3Err(err, fmt.Sprintf("create multipart upload of %s", key))
+	}
+	uploadID := aws.ToString(createOutput.UploadId)
+
+	partSize := cl.multipartPartSize(size)
+	count := int32((size + partSize - 1) / partSize)
+	parts := make([]types.CompletedPart, 0, count)
+	var partsLock sync.Mutex
+	copyPart := func(ctx context.Context, number int32) error {
+		first := int64(number-1) * partSize
+		last := min(first+partSize, size) - 1
+		output, err := cl.AwsS3Client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:                         input.Bucket,
+			Key:                            input.Key,
+			UploadId:                       aws.String(uploadID),
+			PartNumber:                     aws.Int32(number),
+			CopySource:                     input.CopySource,
+			CopySourceRange:                aws.String(fmt.Sprintf("bytes=%d-%d", first, last)),
+			CopySourceIfMatch:              input.CopySourceIfMatch,
+			SSECustomerAlgorithm:           input.SSECustomerAlgorithm,
+			SSECustomerKey:                 input.SSECustomerKey,
+			SSECustomerKeyMD5:              input.SSECustomerKeyMD5,
+			CopySourceSSECustomerAlgorithm: input.CopySourceSSECustomerAlgorithm,
+			CopySourceSSECustomerKey:       input.CopySourceSSECustomerKey,
+			CopySourceSSECustomerKeyMD5:    input.CopySourceSSECustomerKeyMD5,
+		})
+		if err != nil {
+			return parseS3Err(err, fmt.Sprintf("copy part %d to %s", number, key))
+		}
+		// the checksums that were not requested are nil
+		result := output.CopyPartResult
+		partsLock.Lock()
+		defer partsLock.Unlock()
+		parts = append(parts, types.CompletedPart{
+			ETag:              result.ETag,
+			PartNumber:        aws.Int32(number),
+			ChecksumCRC32:     result.ChecksumCRC32,
+			ChecksumCRC32C:    result.ChecksumCRC32C,
+			ChecksumCRC64NVME: result.ChecksumCRC64NVME,
+			ChecksumSHA1:      result.ChecksumSHA1,
+			ChecksumSHA256:    result.ChecksumSHA256,
+		})
+		return nil
+	}
+	err = cl.forEachPart(ctx, count, nil, copyPart)
+	if err != nil {
+		_ = cl.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return err
+	}
+
+	slices.SortFunc(parts, func(a, b types.CompletedPart) int {
+		return cmp.Compare(aws.ToInt32(a.PartNumber), aws.ToInt32(b.PartNumber))
+	})
+	_, err = cl.AwsS3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:               input.Bucket,
+		Key:                  input.Key,
+		UploadId:             aws.String(uploadID),
+		MultipartUpload:      &types.CompletedMultipartUpload{Parts: parts},
+		SSECustomerAlgorithm: input.SSECustomerAlgorithm,
+		SSECustomerKey:       input.SSECustomerKey,
+		SSECustomerKeyMD5:    input.SSECustomerKeyMD5,
+	})
+	if err != nil {
+		_ = cl.abortMultipartUpload(context.WithoutCancel(ctx), key, uploadID)
+		return parseS3Err(err, fmt.Sprintf("complete multipart copy to %s", key))
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsMiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	smithyHttp "github.com/aws/smithy-go/transport/http"
+)
+
+// Each attempt of a request waits for a token from the bucket of its type of request, so
+// throttled uploads do not slow down reads. A bucket is not limited until the service throttles
+// its requests. Then its rate is halved, to no more than half the rate requests were sent at,
+// and ramped up again while requests succeed, until it is back to its configured maximum.
+// Uploads and downloads also wait for a token per byte when their bandwidth is capped.
+
+// Types of requests, which are rate limited separately
+const (
+	listRequests = "list"
+	headRequests = "head"
+	getRequests  = "get"
+	putRequests  = "put"
+)
+
+const (
+	minRequestRate   = 1.0         // requests per second
+	throttleFactor   = 0.5         // applied to the rate when requests are throttled
+	rampFactor       = 1.2         // applied to the rate every rampInterval without throttling
+	rampInterval     = time.Second // also the minimum time between two decreases of the rate
+	rateWindowLength = time.Second // over which the rate of requests sent is measured
+)
+
+var throttleErrors = retry.IsErrorThrottles(retry.DefaultThrottles)
+
+// requestType returns the type of request of the operation
+func requestType(operation string) string {
+	switch operation {
+	case "ListBuckets", "ListObjectsV2", "ListObjectVersions", "ListParts", "ListMultipartUploads":
+		return listRequests
+	case "HeadBucket", "HeadObject":
+		return headRequests
+	case "GetObject":
+		return getRequests
+	default:
+		return putRequests
+	}
+}
+
+// tokenBucket hands out rate tokens per second, up to a second's worth at once.
+// A rate of zero is unlimited.
+type tokenBucket struct {
+	sync.Mutex
+	maxRate float64 // configured rate
+	rate    float64 // current rate
+	tokens  float64 // negative when waiters are owed tokens
+	filled  time.Time
+
+	// requests sent, to know what rate to throttle an unlimited bucket to
+	sent        int
+	windowStart time.Time
+	sentRate    float64
+	// the rate an unlimited bucket was throttled from
+	ceiling float64
+	changed time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	now := time.Now()
+	return &tokenBucket{
+		maxRate:     rate,
+		rate:        rate,
+		tokens:      rate,
+		filled:      now,
+		windowStart: now,
+	}
+}
+
+// reserve takes n tokens, and returns how long to wait until they are available
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	b.sent++
+	if elapsed := now.Sub(b.windowStart); elapsed >= rateWindowLength {
+		b.sentRate = float64(b.sent) / elapsed.Seconds()
+		b.sent = 0
+		b.windowStart = now
+	}
+	if b.rate == 0 {
+		return 0
+	}
+	b.tokens = min(b.tokens+now.Sub(b.filled).Seconds()*b.rate, b.rate)
+	b.filled = now
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// wait takes n tokens, waiting until they are available
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	delay := b.reserve(n)
+	if delay == 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttled lowers the rate after the service throttled a request.
+// It returns the new rate, or zero if the rate was lowered recently.
+func (b *tokenBucket) throttled() float64 {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	if b.rate != 0 && now.Sub(b.changed) < rampInterval {
+		// the requests in flight when the rate was lowered are throttled too
+		return 0
+	}
+	rate := b.rate
+	if rate == 0 {
+		// the requests of the current window were sent in under a window
+		rate = max(b.sentRate, float64(b.sent)/rateWindowLength.Seconds())
+		b.ceiling = rate
+	}
+	b.rate = max(rate*throttleFactor, minRequestRate)
+	b.tokens = 0
+	b.filled = now
+	b.changed = now
+	return b.rate
+}
+
+// succeeded ramps the rate up after a request succeeded.
+// It returns true once the rate is back to its maximum.
+func (b *tokenBucket) succeeded() bool {
+	b.Lock()
+	defer b.Unlock()
+	now := time.Now()
+	if b.rate == b.maxRate || now.Sub(b.changed) < rampInterval {
+		return false
+	}
+	b.rate *= rampFactor
+	b.changed = now
+	if (b.maxRate != 0 && b.rate >= b.maxRate) || (b.maxRate == 0 && b.rate >= b.ceiling) {
+		b.rate = b.maxRate
+		return true
+	}
+	return false
+}
+
+// rateLimiter holds the token buckets of the requests of a client
+type rateLimiter struct {
+	requests map[string]*tokenBucket
+	upload   *tokenBucket // bytes per second, nil if not capped
+	download *tokenBucket // bytes per second, nil if not capped
+}
+
+func newRateLimiter(rates RequestRates, uploadRate int64, downloadRate int64) *rateLimiter {
+	limiter := &rateLimiter{
+		requests: map[string]*tokenBucket{
+			listRequests: newTokenBucket(float64(rates.List)),
+			headRequests: newTokenBucket(float64(rates.Head)),
+			getRequests:  newTokenBucket(float64(rates.Get)),
+			putRequests:  newTokenBucket(float64(rates.Put)),
+		},
+	}
+	if uploadRate > 0 {
+		limiter.upload = newTokenBucket(float64(uploadRate))
+	}
+	if downloadRate > 0 {
+		limiter.download = newTokenBucket(float64(downloadRate))
+	}
+	return limiter
+}
+
+// isThrottled returns true if the service throttled the request
+func isThrottled(err error) bool {
+	if err == nil {
+		return false
+	}
+	if throttleErrors.IsErrorThrottle(err) == aws.TrueTernary {
+		return true
+	}
+	re, ok := errors.AsType[*awsHttp.ResponseError](err)
+	return ok && (re.HTTPStatusCode() == http.StatusServiceUnavailable ||
+		re.HTTPStatusCode() == http.StatusTooManyRequests)
+}
+
+// rateLimit is the middleware rate limiting each attempt of a request
+type rateLimit struct {
+	limiter *rateLimiter
+}
+
+func (*rateLimit) ID() string {
+	return "RateLimit"
+}
+
+func (m *rateLimit) HandleFinalize(
+	ctx context.Context,
+	in middleware.FinalizeInput,
+	next middleware.FinalizeHandler,
+) (middleware.FinalizeOutput, middleware.Metadata, error) {
+	kind := requestType(awsMiddleware.GetOperationName(ctx))
+	bucket := m.limiter.requests[kind]
+	err := bucket.wait(ctx, 1)
+	if err != nil {
+		return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+	}
+	if req, ok := in.Request.(*smithyHttp.Request); ok && kind == putRequests {
+		err = m.limiter.upload.wait(ctx, float64(req.ContentLength))
+		if err != nil {
+			return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+		}
+	}
+
+	out, metadata, err := next.HandleFinalize(ctx, in)
+	if isThrottled(err) {
+		if s3StatsCollector != nil {
+			s3StatsCollector.UpdateStats(stats_manager.Increment, throttledRequests, (int64)(1))
+		}
+		if rate := bucket.throttled(); rate != 0 {
+			log.Warn(
+				"rateLimit::HandleFinalize : Requests throttled, limiting %s requests to %.1f/s",
+				kind,
+				rate,
+			)
+		}
+		return out, metadata, err
+	}
+	if err == nil && bucket.succeeded() {
+		log.Info("rateLimit::HandleFinalize : %s requests are back to their full rate", kind)
+	}
+	if resp, ok := awsMiddleware.GetRawResponse(metadata).(*smithyHttp.Response); ok &&
+		err == nil && kind == getRequests {
+		// the body is read after this returns, so waiting here slows down the download
+		err = m.limiter.download.wait(ctx, float64(resp.ContentLength))
+	}
+	return out, metadata, err
+}
+
+// addRateLimit rate limits the requests of the client
+func (cl *Client) addRateLimit(o *s3.Options) {
+	limiter := newRateLimiter(cl.Config.requestRates, cl.Config.uploadRate, cl.Config.downloadRate)
+	o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+		// after the retry middleware, to see every attempt
+		return stack.Finalize.Insert(&rateLimit{limiter: limiter}, "Retry", middleware.After)
+	})
+}
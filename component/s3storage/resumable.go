@@ -0,0 +1,491 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	json "encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// Uploads of files above the upload cutoff are resumable when there is an upload state directory.
+// The ID of the multipart upload and the ETags of its completed parts are saved in a state file
+// after each part, so when the upload of the file is retried, after a network failure or a
+// restart, the parts already in the bucket are not sent again.
+
+// uploadState is the content of the state file of a resumable upload
+type uploadState struct {
+	Bucket   string    `json:"bucket"`
+	Key      string    `json:"key"`
+	UploadID string    `json:"uploadId"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"` // the local file must not change between attempts
+	PartSize int64     `json:"partSize"`
+	// empty unless checksums are enabled
+	ChecksumAlgorithm types.ChecksumAlgorithm `json:"checksumAlgorithm,omitempty"`
+	Parts             []types.CompletedPart   `json:"parts"`
+}
+
+func loadUploadState(path string) (*uploadState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	state := &uploadState{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// save atomically replaces the state file at path
+func (state *uploadState) save(path string) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	closeErr := f.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+	}
+	return err
+}
+
+// matches reports whether the state belongs to an upload of the same file to the same object
+func (state *uploadState) matches(other *uploadState) bool {
+	return state.Bucket == other.Bucket && state.Key == other.Key &&
+		state.Size == other.Size && state.ModTime.Equal(other.ModTime) &&
+		state.PartSize == other.PartSize && state.ChecksumAlgorithm == other.ChecksumAlgorithm
+}
+
+// partCount returns the number of parts of the upload
+func (state *uploadState) partCount() int32 {
+	return int32((state.Size + state.PartSize - 1) / state.PartSize)
+}
+
+// uploadStateFile returns the path of the state file of uploads to key
+func (cl *Client) uploadStateFile(key string) string {
+	sum := sha256.Sum256([]byte(cl.Config.AuthConfig.BucketName + "/" + key))
+	return filepath.Join(cl.Config.uploadStatePath, hex.EncodeToString(sum[:])+".json")
+}
+
+// removeStateFile removes the state file, and the state directory once it is empty
+func (cl *Client) removeStateFile(statePath string) {
+	err := os.Remove(statePath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Warn("Client::removeStateFile : Failed to remove %s [%v]", statePath, err)
+	}
+	_ = os.Remove(cl.Config.uploadStatePath)
+}
+
+// resumableUpload uploads the file with a multipart upload that survives failures and restarts,
+// and returns the ETag of the new object
+func (cl *Client) resumableUpload(
+	ctx context.Context,
+	name string,
+	file *os.File,
+	stat os.FileInfo,
+	metadata map[string]string,
+	ifMatch *string,
+) (string, error) {
+	key := cl.getKey(name, false, false)
+	statePath := cl.uploadStateFile(key)
+	wanted := &uploadState{
+		Bucket:   cl.Config.AuthConfig.BucketName,
+		Key:      key,
+		Size:     stat.Size(),
+		ModTime:  stat.ModTime(),
+		PartSize: cl.multipartPartSize(stat.Size()),
+	}
+	if cl.Config.enableChecksum {
+		wanted.ChecksumAlgorithm = cl.Config.checksumAlgorithm
+	}
+
+	state, err := cl.resumeUpload(ctx, statePath, wanted)
+	if err != nil {
+		return "", err
+	}
+	if state == nil {
+		state = wanted
+		state.UploadID, err = cl.createUpload(ctx, name, key, metadata)
+		if err != nil {
+			return "", err
+		}
+		cl.saveUploadState(state, statePath)
+	}
+
+	err = cl.uploadParts(ctx, file, state, statePath)
+	var eTag string
+	if err == nil {
+		eTag, err = cl.completeUpload(ctx, state, ifMatch)
+	}
+	// keep the upload to resume it later, unless it failed for good
+	interrupted := errors.Is(err, &common.CloudUnreachableError{}) ||
+		errors.Is(err, syscall.EBUSY) || ctx.Err() != nil
+	if err != nil && interrupted {
+		log.Info(
+			"Client::resumableUpload : Upload of %s interrupted after %d of %d parts",
+			name,
+			len(state.Parts),
+			state.partCount(),
+		)
+		return "", err
+	}
+	if err != nil {
+		_ = cl.abortMultipartUpload(ctx, key, state.UploadID)
+	}
+	cl.removeStateFile(statePath)
+	return eTag, err
+}
+
+// resumeUpload returns the state of the interrupted upload matching wanted, without the parts
+// missing from the bucket, or nil if the upload has to start over
+func (cl *Client) resumeUpload(
+	ctx context.Context,
+	statePath string,
+	wanted *uploadState,
+) (*uploadState, error) {
+	state, err := loadUploadState(statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Client::resumeUpload : Ignoring unreadable %s [%v]", statePath, err)
+		}
+		return nil, nil
+	}
+	if !state.matches(wanted) {
+		log.Info("Client::resumeUpload : %s changed since its last upload. Restarting.", state.Key)
+		_ = cl.abortMultipartUpload(ctx, state.Key, state.UploadID)
+		return nil, nil
+	}
+
+	uploaded, err := cl.listUploadedParts(ctx, state.Key, state.UploadID)
+	if isNoSuchUpload(err) {
+		log.Info("Client::resumeUpload : Upload of %s no longer exists. Starting over.", state.Key)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, parseS3Err(err, fmt.Sprintf("list parts of %s", state.Key))
+	}
+	state.Parts = slices.DeleteFunc(state.Parts, func(part types.CompletedPart) bool {
+		eTag, found := uploaded[aws.ToInt32(part.PartNumber)]
+		return !found || eTag != sanitizeETag(part.ETag)
+	})
+	log.Info(
+		"Client::resumeUpload : Resuming upload of %s with %d of %d parts done",
+		state.Key,
+		len(state.Parts),
+		state.partCount(),
+	)
+	return state, nil
+}
+
+// listUploadedParts returns the ETags of the parts of the upload, by part number
+func (cl *Client) listUploadedParts(
+	ctx context.Context,
+	key string,
+	uploadID string,
+) (map[int32]string, error) {
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return nil, err
+	}
+	uploaded := make(map[int32]string)
+	paginator := s3.NewListPartsPaginator(cl.AwsS3Client, &s3.ListPartsInput{
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		UploadId:             aws.String(uploadID),
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, part := range output.Parts {
+			uploaded[aws.ToInt32(part.PartNumber)] = sanitizeETag(part.ETag)
+		}
+	}
+	return uploaded, nil
+}
+
+// createUpload starts a multipart upload to key, and returns its ID
+func (cl *Client) createUpload(
+	ctx context.Context,
+	name string,
+	key string,
+	metadata map[string]string,
+) (string, error) {
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return "", err
+	}
+	headers, _ := cl.objectHeaders(name)
+	input := &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		ContentType:          contentType(key, headers),
+		CacheControl:         optionalString(headers.CacheControl),
+		ContentEncoding:      optionalString(headers.ContentEncoding),
+		Metadata:             withRuleMetadata(metadata, headers),
+		ServerSideEncryption: sse.serverSideEncryption,
+		SSEKMSKeyId:          sse.kmsKeyID,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+		StorageClass:         cl.storageClass(name),
+	}
+	if cl.Config.enableChecksum {
+		input.ChecksumAlgorithm = cl.Config.checksumAlgorithm
+	}
+
+	output, err := cl.AwsS3Client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return "", parseS3Err(err, fmt.Sprintf("create multipart upload of %s", key))
+	}
+	if aws.ToString(output.UploadId) == "" {
+		return "", fmt.Errorf("no upload ID returned for multipart upload of %s", key)
+	}
+	return *output.UploadId, nil
+}
+
+// uploadParts uploads the parts missing from the state, concurrently, saving the state as each
+// part completes
+func (cl *Client) uploadParts(
+	ctx context.Context,
+	file *os.File,
+	state *uploadState,
+	statePath string,
+) error {
+	done := make(map[int32]bool, len(state.Parts))
+	for _, part := range state.Parts {
+		done[aws.ToInt32(part.PartNumber)] = true
+	}
+
+	var stateLock sync.Mutex
+	upload := func(ctx context.Context, number int32) error {
+		part, err := cl.uploadPart(ctx, file, state, number)
+		if err != nil {
+			return err
+		}
+		stateLock.Lock()
+		defer stateLock.Unlock()
+		state.Parts = append(state.Parts, part)
+		cl.saveUploadState(state, statePath)
+		return nil
+	}
+	return cl.forEachPart(ctx, state.partCount(), done, upload)
+}
+
+// uploadPart uploads one part of the file
+func (cl *Client) uploadPart(
+	ctx context.Context,
+	file *os.File,
+	state *uploadState,
+	number int32,
+) (types.CompletedPart, error) {
+	offset := int64(number-1) * state.PartSize
+	size := min(state.PartSize, state.Size-offset)
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return types.CompletedPart{}, err
+	}
+	input := &s3.UploadPartInput{
+		Bucket:               aws.String(state.Bucket),
+		Key:                  aws.String(state.Key),
+		UploadId:             aws.String(state.UploadID),
+		PartNumber:           aws.Int32(number),
+		Body:                 io.NewSectionReader(file, offset, size),
+		ContentLength:        aws.Int64(size),
+		ChecksumAlgorithm:    state.ChecksumAlgorithm,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+	}
+	output, err := cl.AwsS3Client.UploadPart(ctx, input)
+	if err != nil {
+		attemptedAction := fmt.Sprintf("upload part %d of %s", number, state.Key)
+		return types.CompletedPart{}, parseS3Err(err, attemptedAction)
+	}
+	// the checksums that were not requested are nil
+	return types.CompletedPart{
+		ETag:              aws.String(sanitizeETag(output.ETag)),
+		PartNumber:        aws.Int32(number),
+		ChecksumCRC32:     output.ChecksumCRC32,
+		ChecksumCRC32C:    output.ChecksumCRC32C,
+		ChecksumCRC64NVME: output.ChecksumCRC64NVME,
+		ChecksumSHA1:      output.ChecksumSHA1,
+		ChecksumSHA256:    output.ChecksumSHA256,
+	}, nil
+}
+
+// saveUploadState saves the state. Failing to do so only costs the ability to resume the upload.
+func (cl *Client) saveUploadState(state *uploadState, statePath string) {
+	err := state.save(statePath)
+	if err != nil {
+		log.Warn("Client::saveUploadState : Failed to save %s [%v]", statePath, err)
+	}
+}
+
+// completeUpload completes the upload, and returns the ETag of the new object.
+// ifMatch makes the upload conditional (see internal.CopyFromFileOptions).
+func (cl *Client) completeUpload(
+	ctx context.Context,
+	state *uploadState,
+	ifMatch *string,
+) (string, error) {
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return "", err
+	}
+	parts := slices.SortedFunc(slices.Values(state.Parts), func(a, b types.CompletedPart) int {
+		return cmp.Compare(aws.ToInt32(a.PartNumber), aws.ToInt32(b.PartNumber))
+	})
+	input := &s3.CompleteMultipartUploadInput{
+		Bucket:               aws.String(state.Bucket),
+		Key:                  aws.String(state.Key),
+		UploadId:             aws.String(state.UploadID),
+		MultipartUpload:      &types.CompletedMultipartUpload{Parts: parts},
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+	}
+	switch {
+	case ifMatch == nil:
+	case *ifMatch == "":
+		input.IfNoneMatch = aws.String("*")
+	default:
+		input.IfMatch = aws.String(quoteETag(*ifMatch))
+	}
+
+	output, err := cl.AwsS3Client.CompleteMultipartUpload(ctx, input)
+	if err != nil {
+		return "", parseS3Err(err, fmt.Sprintf("complete multipart upload of %s", state.Key))
+	}
+	return sanitizeETag(output.ETag), nil
+}
+
+// isNoSuchUpload reports whether err is caused by a multipart upload that was completed or aborted
+func isNoSuchUpload(err error) bool {
+	apiErr, ok := errors.AsType[smithy.APIError](err)
+	return ok && apiErr.ErrorCode() == "NoSuchUpload"
+}
+
+// AbortStaleUploads aborts the multipart uploads under the subdirectory that were started before
+// the given time, and removes their resumable upload state. It returns the keys of the uploads.
+// With dryRun, the uploads are only listed.
+func (cl *Client) AbortStaleUploads(
+	ctx context.Context,
+	before time.Time,
+	dryRun bool,
+) ([]string, error) {
+	prefix := cl.Config.prefixPath
+	if prefix != "" {
+		prefix += "/"
+	}
+	var keys []string
+	paginator := s3.NewListMultipartUploadsPaginator(
+		cl.AwsS3Client,
+		&s3.ListMultipartUploadsInput{
+			Bucket: aws.String(cl.Config.AuthConfig.BucketName),
+			Prefix: aws.String(prefix),
+		},
+	)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return keys, parseS3Err(err, "list multipart uploads")
+		}
+		for _, upload := range output.Uploads {
+			if upload.Initiated == nil || !upload.Initiated.Before(before) {
+				continue
+			}
+			key := aws.ToString(upload.Key)
+			keys = append(keys, key)
+			if dryRun {
+				continue
+			}
+			_, err := cl.AwsS3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(cl.Config.AuthConfig.BucketName),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			if err != nil && !isNoSuchUpload(err) {
+				return keys, parseS3Err(err, fmt.Sprintf("abort multipart upload of %s", key))
+			}
+			log.Info("Client::AbortStaleUploads : Aborted upload of %s", key)
+			if cl.Config.uploadStatePath == "" {
+				continue
+			}
+			statePath := cl.uploadStateFile(key)
+			state, err := loadUploadState(statePath)
+			if err == nil && state.UploadID == aws.ToString(upload.UploadId) {
+				cl.removeStateFile(statePath)
+			}
+		}
+	}
+	return keys, nil
+}
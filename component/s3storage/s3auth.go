@@ -0,0 +1,293 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/awnumar/memguard"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/processcreds"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// AuthType Enum
+type AuthType int
+
+var EAuthType = AuthType(0).DEFAULT()
+
+// DEFAULT uses the key-id and secret-key, the profile, or the default credential chain
+func (AuthType) DEFAULT() AuthType {
+	return AuthType(0)
+}
+
+// ASSUMEROLE assumes role-arn with the credentials of the DEFAULT mode
+func (AuthType) ASSUMEROLE() AuthType {
+	return AuthType(1)
+}
+
+// WEBIDENTITY assumes role-arn with the token in web-identity-token-file
+func (AuthType) WEBIDENTITY() AuthType {
+	return AuthType(2)
+}
+
+// CREDENTIALPROCESS gets credentials from the output of credential-process
+func (AuthType) CREDENTIALPROCESS() AuthType {
+	return AuthType(3)
+}
+
+var authTypeNames = map[AuthType]string{
+	AuthType(0): "DEFAULT",
+	AuthType(1): "ASSUMEROLE",
+	AuthType(2): "WEBIDENTITY",
+	AuthType(3): "CREDENTIALPROCESS",
+}
+
+var authTypeValues = map[string]AuthType{
+	"DEFAULT":           AuthType(0),
+	"ASSUMEROLE":        AuthType(1),
+	"WEBIDENTITY":       AuthType(2),
+	"CREDENTIALPROCESS": AuthType(3),
+}
+
+func (a AuthType) String() string {
+	if name, ok := authTypeNames[a]; ok {
+		return name
+	}
+	return "DEFAULT"
+}
+
+func (a *AuthType) Parse(s string) error {
+	if val, ok := authTypeValues[strings.ToUpper(s)]; ok {
+		*a = val
+		return nil
+	}
+	return fmt.Errorf("invalid AuthType: %s", s)
+}
+
+const (
+	// STS accepts session durations from 15 minutes to 12 hours
+	minSessionDuration = 15 * time.Minute
+	maxSessionDuration = 12 * time.Hour
+	// temporary credentials are refreshed this long before they expire, less up to half of it
+	credentialsExpiryWindow = 5 * time.Minute
+	// a failed refresh is retried after this long while the previous credentials are valid
+	credentialsRetryInterval = 30 * time.Second
+	defaultRoleSessionName   = "cloudfuse"
+)
+
+// s3AuthConfig : Config to authenticate to storage
+type s3AuthConfig struct {
+	BucketName     string
+	KeyID          *memguard.Enclave
+	SecretKey      *memguard.Enclave
+	Region         string
+	Profile        string
+	Endpoint       string
+	SSECustomerKey *memguard.Enclave
+
+	Mode                 AuthType
+	RoleARN              string
+	RoleSessionName      string
+	ExternalID           string
+	SessionDuration      time.Duration // zero for the STS default
+	WebIdentityTokenFile string
+	CredentialProcess    string
+	STSEndpoint          string // empty for the AWS STS endpoint of the region
+}
+
+// parseAuthMode validates the options of the authentication mode, and sets them in auth.
+// The web identity options default to the variables set by EKS and other Kubernetes platforms.
+func parseAuthMode(auth *s3AuthConfig, opt Options) error {
+	auth.Mode = EAuthType.DEFAULT()
+	if opt.Mode != "" {
+		err := auth.Mode.Parse(opt.Mode)
+		if err != nil {
+			return fmt.Errorf(
+				"%w: mode must be one of default, assumerole, webidentity or credentialprocess",
+				errInvalidConfigField,
+			)
+		}
+	}
+	auth.RoleARN = opt.RoleARN
+	auth.RoleSessionName = cmp.Or(opt.RoleSessionName, defaultRoleSessionName)
+	auth.ExternalID = opt.ExternalID
+	auth.WebIdentityTokenFile = opt.WebIdentityTokenFile
+	auth.CredentialProcess = opt.CredentialProcess
+	auth.STSEndpoint = opt.STSEndpoint
+
+	auth.SessionDuration = time.Duration(opt.SessionDurationSec) * time.Second
+	if auth.SessionDuration != 0 &&
+		(auth.SessionDuration < minSessionDuration || auth.SessionDuration > maxSessionDuration) {
+		return fmt.Errorf(
+			"%w: session-duration-sec must be between %.0f and %.0f",
+			errInvalidConfigField,
+			minSessionDuration.Seconds(),
+			maxSessionDuration.Seconds(),
+		)
+	}
+
+	switch auth.Mode {
+	case EAuthType.ASSUMEROLE():
+		if auth.RoleARN == "" {
+			return fmt.Errorf("%w: mode assumerole requires role-arn", errInvalidConfigField)
+		}
+	case EAuthType.WEBIDENTITY():
+		auth.RoleARN = cmp.Or(auth.RoleARN, os.Getenv("AWS_ROLE_ARN"))
+		auth.WebIdentityTokenFile = cmp.Or(
+			auth.WebIdentityTokenFile,
+			os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"),
+		)
+		if opt.RoleSessionName == "" {
+			auth.RoleSessionName = cmp.Or(os.Getenv("AWS_ROLE_SESSION_NAME"), auth.RoleSessionName)
+		}
+		if auth.RoleARN == "" || auth.WebIdentityTokenFile == "" {
+			return fmt.Errorf(
+				"%w: mode webidentity requires role-arn and web-identity-token-file",
+				errInvalidConfigField,
+			)
+		}
+	case EAuthType.CREDENTIALPROCESS():
+		if auth.CredentialProcess == "" {
+			return fmt.Errorf(
+				"%w: mode credentialprocess requires credential-process",
+				errInvalidConfigField,
+			)
+		}
+	}
+	return nil
+}
+
+// newCredentialsProvider returns the provider of the temporary credentials of the mode, or nil in
+// the DEFAULT mode. The credentials of cfg are the ones used to assume a role.
+func (auth s3AuthConfig) newCredentialsProvider(cfg aws.Config) aws.CredentialsProvider {
+	stsClient := func() *sts.Client {
+		return sts.NewFromConfig(cfg, func(o *sts.Options) {
+			if auth.STSEndpoint != "" {
+				o.BaseEndpoint = aws.String(auth.STSEndpoint)
+			}
+		})
+	}
+
+	var provider aws.CredentialsProvider
+	switch auth.Mode {
+	case EAuthType.ASSUMEROLE():
+		provider = stscreds.NewAssumeRoleProvider(
+			stsClient(),
+			auth.RoleARN,
+			func(o *stscreds.AssumeRoleOptions) {
+				o.RoleSessionName = auth.RoleSessionName
+				o.Duration = auth.SessionDuration
+				if auth.ExternalID != "" {
+					o.ExternalID = aws.String(auth.ExternalID)
+				}
+			},
+		)
+	case EAuthType.WEBIDENTITY():
+		provider = stscreds.NewWebIdentityRoleProvider(
+			stsClient(),
+			auth.RoleARN,
+			stscreds.IdentityTokenFile(auth.WebIdentityTokenFile),
+			func(o *stscreds.WebIdentityRoleOptions) {
+				o.RoleSessionName = auth.RoleSessionName
+				o.Duration = auth.SessionDuration
+			},
+		)
+	case EAuthType.CREDENTIALPROCESS():
+		provider = processcreds.NewProvider(auth.CredentialProcess)
+	default:
+		return nil
+	}
+
+	return aws.NewCredentialsCache(
+		&refreshingProvider{provider: provider, mode: auth.Mode},
+		func(o *aws.CredentialsCacheOptions) {
+			o.ExpiryWindow = credentialsExpiryWindow
+			o.ExpiryWindowJitterFrac = 0.5
+		},
+	)
+}
+
+// refreshingProvider logs the refreshes of temporary credentials. The credentials cache calls it
+// ahead of their expiry, so a failed refresh is retried while the previous credentials are valid.
+type refreshingProvider struct {
+	provider aws.CredentialsProvider
+	mode     AuthType
+	expires  time.Time // when the last credentials retrieved actually expire
+}
+
+func (p *refreshingProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	creds, err := p.provider.Retrieve(ctx)
+	if err != nil {
+		log.Err(
+			"refreshingProvider::Retrieve : Failed to refresh %s credentials. Here's why: %v",
+			p.mode,
+			err,
+		)
+		return creds, err
+	}
+	if creds.CanExpire {
+		p.expires = creds.Expires
+		log.Info(
+			"refreshingProvider::Retrieve : Refreshed %s credentials, which expire at %s",
+			p.mode,
+			creds.Expires.Format(time.RFC3339),
+		)
+	}
+	return creds, nil
+}
+
+// HandleFailToRefresh keeps using the previous credentials until they expire, trying to refresh
+// them again every credentialsRetryInterval
+func (p *refreshingProvider) HandleFailToRefresh(
+	_ context.Context,
+	prev aws.Credentials,
+	err error,
+) (aws.Credentials, error) {
+	now := time.Now()
+	if !prev.HasKeys() || !now.Before(p.expires) {
+		return aws.Credentials{}, err
+	}
+	log.Warn(
+		"refreshingProvider::HandleFailToRefresh : Using %s credentials until they expire at %s",
+		p.mode,
+		p.expires.Format(time.RFC3339),
+	)
+	// the cache takes the expiry window off again
+	prev.Expires = now.Add(credentialsExpiryWindow + credentialsRetryInterval)
+	if prev.Expires.After(p.expires) {
+		prev.Expires = p.expires
+	}
+	return prev, nil
+}
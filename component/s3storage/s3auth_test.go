@@ -0,0 +1,136 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type s3authTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (s *s3authTestSuite) SetupTest() {
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	if err != nil {
+		panic(fmt.Sprintf("Unable to set silent logger as default: %v", err))
+	}
+	s.assert = assert.New(s.T())
+}
+
+// fakeProvider returns creds, or err once it is set
+type fakeProvider struct {
+	creds aws.Credentials
+	err   error
+	calls int
+}
+
+func (p *fakeProvider) Retrieve(context.Context) (aws.Credentials, error) {
+	p.calls++
+	if p.err != nil {
+		return aws.Credentials{}, p.err
+	}
+	return p.creds, nil
+}
+
+func (s *s3authTestSuite) TestAuthTypeParse() {
+	var mode AuthType
+	s.assert.NoError(mode.Parse("AssumeRole"))
+	s.assert.Equal(EAuthType.ASSUMEROLE(), mode)
+	s.assert.Equal("ASSUMEROLE", mode.String())
+	s.assert.NoError(mode.Parse("credentialprocess"))
+	s.assert.Equal(EAuthType.CREDENTIALPROCESS(), mode)
+	s.assert.Error(mode.Parse("key"))
+	s.assert.Equal(EAuthType.CREDENTIALPROCESS(), mode)
+}
+
+func (s *s3authTestSuite) TestNewCredentialsProvider() {
+	auth := s3AuthConfig{}
+	s.assert.Nil(auth.newCredentialsProvider(aws.Config{}))
+
+	for _, mode := range []AuthType{
+		EAuthType.ASSUMEROLE(),
+		EAuthType.WEBIDENTITY(),
+		EAuthType.CREDENTIALPROCESS(),
+	} {
+		auth.Mode = mode
+		provider := auth.newCredentialsProvider(aws.Config{Region: "us-east-1"})
+		s.assert.IsType(&aws.CredentialsCache{}, provider, mode)
+	}
+}
+
+func (s *s3authTestSuite) TestRefreshingProvider() {
+	// credentials expiring within the expiry window are refreshed on the next request
+	fake := &fakeProvider{creds: aws.Credentials{
+		AccessKeyID:     "id",
+		SecretAccessKey: "secret",
+		CanExpire:       true,
+		Expires:         time.Now().Add(credentialsExpiryWindow / 2),
+	}}
+	cache := aws.NewCredentialsCache(
+		&refreshingProvider{provider: fake},
+		func(o *aws.CredentialsCacheOptions) { o.ExpiryWindow = credentialsExpiryWindow },
+	)
+
+	creds, err := cache.Retrieve(context.Background())
+	s.assert.NoError(err)
+	s.assert.Equal("id", creds.AccessKeyID)
+	s.assert.Equal(1, fake.calls)
+
+	// a failed refresh keeps the previous credentials while they are valid
+	fake.err = errors.New("sts is unreachable")
+	creds, err = cache.Retrieve(context.Background())
+	s.assert.NoError(err)
+	s.assert.Equal("id", creds.AccessKeyID)
+	s.assert.Equal(2, fake.calls)
+
+	// and the refresh is tried again
+	fake.err = nil
+	_, err = cache.Retrieve(context.Background())
+	s.assert.NoError(err)
+	s.assert.Equal(3, fake.calls)
+
+	// unless they have expired
+	provider := &refreshingProvider{provider: fake, expires: time.Now().Add(-time.Second)}
+	_, err = provider.HandleFailToRefresh(context.Background(), creds, fake.err)
+	s.assert.ErrorIs(err, fake.err)
+}
+
+func TestS3authTestSuite(t *testing.T) {
+	suite.Run(t, new(s3authTestSuite))
+}
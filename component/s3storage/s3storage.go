@@ -29,6 +29,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -123,12 +124,32 @@ func (s3 *S3Storage) Configure(isParent bool) error {
 		secrets.SecretKey = encryptedSecretKey
 	}
 
+	if viper.GetString("s3storage.sse-customer-key") != "" {
+		key, err := decodeSSECustomerKey(viper.GetString("s3storage.sse-customer-key"))
+		if err != nil {
+			log.Err("S3Storage::Configure : %s", err.Error())
+			return fmt.Errorf("config error in %s [%s]", s3.Name(), err.Error())
+		}
+		encryptedSSECustomerKey := memguard.NewEnclave(key)
+
+		if encryptedSSECustomerKey == nil {
+			err := errors.New("unable to store sse-customer-key securely")
+			log.Err("S3Storage::Configure : %s", err.Error())
+			return err
+		}
+		secrets.SSECustomerKey = encryptedSSECustomerKey
+	}
+
 	err = ParseAndValidateConfig(s3, conf, secrets)
 	if err != nil {
 		log.Err("S3Storage::Configure : Config validation failed [%s]", err.Error())
 		return fmt.Errorf("config error in %s [%s]", s3.Name(), err.Error())
 	}
 
+	if s3.stConfig.enableVersions {
+		internal.RegisterVirtualDir(versionsDirName)
+	}
+
 	err = s3.configureAndTest(isParent)
 	if err != nil {
 		log.Err("S3Storage::Configure : Failed to validate storage account [%s]", err.Error())
@@ -181,8 +202,45 @@ func (s3 *S3Storage) Start(ctx context.Context) error {
 	// create stats collector for s3storage
 	s3StatsCollector = stats_manager.NewStatsCollector(s3.Name())
 	log.Debug("Starting s3 stats collector")
+	s3StatsCollector.AddStats(
+		stats_manager.Counter,
+		bytesDownloaded,
+		bytesUploaded,
+		createDir,
+		deleteDir,
+		streamDir,
+		renameDir,
+		deleteFile,
+		renameFile,
+		truncateFile,
+		createLink,
+		readLink,
+		setMetadata,
+		utimens,
+		chmod,
+		endpointSwitches,
+		throttledRequests,
+	)
+	s3StatsCollector.AddStats(stats_manager.Gauge, openHandles)
+	s3StatsCollector.UpdateStats(stats_manager.Replace, openHandles, (int64)(0))
+	s3StatsCollector.AddGauge(cloudConnected, func() float64 {
+		// report the state without checking the connection
+		s3.state.Lock()
+		defer s3.state.Unlock()
+		if s3.state.firstOffline != nil {
+			return 0
+		}
+		return 1
+	})
+	s3StatsCollector.UpdateStats(stats_manager.Replace, activeEndpoint, s3.Storage.ActiveEndpoint())
 	// create a shared context for all cloud operations, with ability to cancel
 	s3.ctx, s3.cancelFn = context.WithCancel(ctx)
+	s3.Storage.WatchEndpoints(s3.ctx)
+	// finish any directory renames that were interrupted by a crash or unmount, before the mount
+	// serves requests. Other commands starting the component leave them for the next mount.
+	if common.MountPath != "" {
+		s3.Storage.ResumeDirectoryRenames(s3.ctx)
+	}
 	// create the retry ticker
 	s3.state.retryTicker = time.NewTicker(s3.stConfig.healthCheckInterval)
 	s3.state.retryTicker.Stop() // stop it for now, we will start it when we are offline
@@ -198,6 +256,9 @@ func (s3 *S3Storage) Start(ctx context.Context) error {
 // Stop : Disconnect all running operations here
 func (s3 *S3Storage) Stop() error {
 	log.Trace("S3Storage::Stop : Stopping component %s", s3.Name())
+	if s3.cancelFn != nil {
+		s3.cancelFn()
+	}
 	s3StatsCollector.Destroy()
 	return nil
 }
@@ -255,6 +316,7 @@ func (s3 *S3Storage) updateConnectionState(err error) bool {
 			s3.state.firstOffline = nil
 			// reset the context to allow new requests
 			s3.ctx, s3.cancelFn = context.WithCancel(context.Background())
+			s3.Storage.WatchEndpoints(s3.ctx)
 			// stop the retry ticker
 			s3.state.retryTicker.Stop()
 		} else {
@@ -283,6 +345,9 @@ func (s3 *S3Storage) ListAuthorizedBuckets() ([]string, error) {
 // Directory operations
 func (s3 *S3Storage) CreateDir(options internal.CreateDirOptions) error {
 	log.Trace("S3Storage::CreateDir : %s", options.Name)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
 
 	err := s3.Storage.CreateDirectory(s3.ctx, internal.TruncateDirName(options.Name))
 	if s3.stConfig.enableDirMarker {
@@ -303,6 +368,9 @@ func (s3 *S3Storage) CreateDir(options internal.CreateDirOptions) error {
 
 func (s3 *S3Storage) DeleteDir(options internal.DeleteDirOptions) error {
 	log.Trace("S3Storage::DeleteDir : %s", options.Name)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
 
 	err := s3.Storage.DeleteDirectory(s3.ctx, internal.TruncateDirName(options.Name))
 	s3.updateConnectionState(err)
@@ -406,6 +474,9 @@ func (s3 *S3Storage) StreamDir(
 
 func (s3 *S3Storage) RenameDir(options internal.RenameDirOptions) error {
 	log.Trace("S3Storage::RenameDir : %s to %s", options.Src, options.Dst)
+	if err := s3.checkWritable(options.Src, options.Dst); err != nil {
+		return err
+	}
 	options.Src = internal.TruncateDirName(options.Src)
 	options.Dst = internal.TruncateDirName(options.Dst)
 
@@ -426,6 +497,9 @@ func (s3 *S3Storage) RenameDir(options internal.RenameDirOptions) error {
 // File operations
 func (s3 *S3Storage) CreateFile(options internal.CreateFileOptions) (*handlemap.Handle, error) {
 	log.Trace("S3Storage::CreateFile : %s", options.Name)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return nil, err
+	}
 
 	// Create a handle object for the file being created
 	// This handle will be added to handlemap by the first component in pipeline
@@ -456,6 +530,11 @@ func (s3 *S3Storage) CreateFile(options internal.CreateFileOptions) (*handlemap.
 
 func (s3 *S3Storage) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle, error) {
 	log.Trace("S3Storage::OpenFile : %s", options.Name)
+	if options.Flags&(os.O_WRONLY|os.O_RDWR|os.O_TRUNC) != 0 {
+		if err := s3.checkWritable(options.Name); err != nil {
+			return nil, err
+		}
+	}
 
 	attr, err := s3.Storage.GetAttr(s3.ctx, options.Name)
 	s3.updateConnectionState(err)
@@ -490,6 +569,9 @@ func (s3 *S3Storage) ReleaseFile(options internal.ReleaseFileOptions) error {
 
 func (s3 *S3Storage) DeleteFile(options internal.DeleteFileOptions) error {
 	log.Trace("S3Storage::DeleteFile : %s", options.Name)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
 
 	err := s3.Storage.DeleteFile(s3.ctx, options.Name)
 	s3.updateConnectionState(err)
@@ -504,6 +586,9 @@ func (s3 *S3Storage) DeleteFile(options internal.DeleteFileOptions) error {
 
 func (s3 *S3Storage) RenameFile(options internal.RenameFileOptions) error {
 	log.Trace("S3Storage::RenameFile : %s to %s", options.Src, options.Dst)
+	if err := s3.checkWritable(options.Src, options.Dst); err != nil {
+		return err
+	}
 
 	isSymLink := options.SrcAttr != nil && options.SrcAttr.IsSymlink()
 	err := s3.Storage.RenameFile(s3.ctx, options.Src, options.Dst, isSymLink)
@@ -537,8 +622,10 @@ func (s3 *S3Storage) ReadInBuffer(options *internal.ReadInBufferOptions) (int, e
 		return 0, nil
 	}
 
+	ctx, cancel := options.Context(s3.ctx)
+	defer cancel()
 	err := s3.Storage.ReadInBuffer(
-		s3.ctx,
+		ctx,
 		options.Handle.Path,
 		options.Offset,
 		dataLen,
@@ -558,6 +645,9 @@ func (s3 *S3Storage) ReadInBuffer(options *internal.ReadInBufferOptions) (int, e
 }
 
 func (s3 *S3Storage) WriteFile(options *internal.WriteFileOptions) (int, error) {
+	if err := s3.checkWritable(options.Handle.Path); err != nil {
+		return 0, err
+	}
 	err := s3.Storage.Write(s3.ctx, options)
 	s3.updateConnectionState(err)
 	return len(options.Data), err
@@ -572,6 +662,9 @@ func (s3 *S3Storage) GetFileBlockOffsets(
 
 func (s3 *S3Storage) TruncateFile(options internal.TruncateFileOptions) error {
 	log.Trace("S3Storage::TruncateFile : %s to %d bytes", options.Name, options.NewSize)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
 	err := s3.Storage.TruncateFile(s3.ctx, options.Name, options.NewSize)
 	s3.updateConnectionState(err)
 
@@ -595,7 +688,17 @@ func (s3 *S3Storage) CopyToFile(options internal.CopyToFileOptions) error {
 
 func (s3 *S3Storage) CopyFromFile(options internal.CopyFromFileOptions) error {
 	log.Trace("S3Storage::CopyFromFile : Upload file %s", options.Name)
-	err := s3.Storage.WriteFromFile(s3.ctx, options.Name, options.Metadata, options.File)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
+	err := s3.Storage.WriteFromFile(
+		s3.ctx,
+		options.Name,
+		options.Metadata,
+		options.File,
+		options.IfMatch,
+		options.NewETag,
+	)
 	s3.updateConnectionState(err)
 	return err
 }
@@ -611,6 +714,9 @@ func (s3 *S3Storage) CreateLink(options internal.CreateLinkOptions) error {
 		return syscall.ENOTSUP
 	}
 	log.Trace("S3Storage::CreateLink : Create symlink %s -> %s", options.Name, options.Target)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
 	err := s3.Storage.CreateLink(s3.ctx, options.Name, options.Target, true)
 
 	s3.updateConnectionState(err)
@@ -652,8 +758,33 @@ func (s3 *S3Storage) GetAttr(options internal.GetAttrOptions) (*internal.ObjAttr
 	return attr, err
 }
 
+// Metadata operations
+func (s3 *S3Storage) GetMetadata(options internal.GetMetadataOptions) (map[string]*string, error) {
+	log.Trace("S3Storage::GetMetadata : Get metadata of %s", options.Name)
+	metadata, err := s3.Storage.GetMetadata(s3.ctx, options.Name)
+	s3.updateConnectionState(err)
+	return metadata, err
+}
+
+func (s3 *S3Storage) SetMetadata(options internal.SetMetadataOptions) error {
+	log.Trace("S3Storage::SetMetadata : Set metadata of %s", options.Name)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
+	err := s3.Storage.SetMetadata(s3.ctx, options.Name, options.Metadata)
+	s3.updateConnectionState(err)
+	if err == nil {
+		s3StatsCollector.PushEvents(setMetadata, options.Name, nil)
+		s3StatsCollector.UpdateStats(stats_manager.Increment, setMetadata, (int64)(1))
+	}
+	return err
+}
+
 func (s3 *S3Storage) Chmod(options internal.ChmodOptions) error {
 	log.Trace("S3Storage::Chmod : Change mode of file %s", options.Name)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
 
 	s3StatsCollector.PushEvents(
 		chmod,
@@ -675,8 +806,25 @@ func (s3 *S3Storage) Chown(options internal.ChownOptions) error {
 	return nil
 }
 
+func (s3 *S3Storage) Utimens(options internal.UtimensOptions) error {
+	log.Trace("S3Storage::Utimens : Change times of %s", options.Name)
+	if err := s3.checkWritable(options.Name); err != nil {
+		return err
+	}
+	err := s3.Storage.Utimens(s3.ctx, options.Name, options.Atime, options.Mtime)
+	s3.updateConnectionState(err)
+	if err == nil {
+		s3StatsCollector.PushEvents(utimens, options.Name, nil)
+		s3StatsCollector.UpdateStats(stats_manager.Increment, utimens, (int64)(1))
+	}
+	return err
+}
+
 func (s3 *S3Storage) FlushFile(options internal.FlushFileOptions) error {
 	log.Trace("S3Storage::FlushFile : Flush file %s", options.Handle.Path)
+	if err := s3.checkWritable(options.Handle.Path); err != nil {
+		return err
+	}
 	err := s3.Storage.StageAndCommit(
 		s3.ctx,
 		options.Handle.Path,
@@ -693,11 +841,17 @@ func (s3 *S3Storage) GetCommittedBlockList(name string) (*internal.CommittedBloc
 }
 
 func (s3 *S3Storage) StageData(opt internal.StageDataOptions) error {
+	if err := s3.checkWritable(opt.Name); err != nil {
+		return err
+	}
 	return s3.Storage.StageBlock(opt.Name, opt.Data, opt.Id)
 }
 
 func (s3 *S3Storage) CommitData(opt internal.CommitDataOptions) error {
-	err := s3.Storage.CommitBlocks(s3.ctx, opt.Name, opt.List)
+	if err := s3.checkWritable(opt.Name); err != nil {
+		return err
+	}
+	err := s3.Storage.CommitBlocks(s3.ctx, opt.Name, opt.List, opt.Metadata)
 	s3.updateConnectionState(err)
 	return err
 }
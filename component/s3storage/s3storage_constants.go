@@ -43,6 +43,13 @@ const (
 	createLink   = "CreateLink"
 	readLink     = "ReadLink"
 	chmod        = "Chmod"
+	setMetadata  = "SetMetadata"
+	utimens      = "Utimens"
+
+	activeEndpoint    = "ActiveEndpoint"
+	endpointSwitches  = "EndpointSwitches"
+	throttledRequests = "ThrottledRequests"
+	cloudConnected    = "CloudConnected"
 
 	openHandles = "OpenFileHandles"
 	mode        = "Mode"
@@ -27,6 +27,7 @@ package s3storage
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -58,6 +59,7 @@ type getObjectOptions struct {
 	count     int64
 	isSymLink bool
 	isDir     bool
+	versionID string // empty for the current version
 }
 
 type putObjectOptions struct {
@@ -66,20 +68,27 @@ type putObjectOptions struct {
 	size       int64
 	isSymLink  bool
 	isDir      bool
+	metadata   map[string]string
+	ifMatch    *string
+	newETag    *string
 }
 
 type copyObjectOptions struct {
-	source    string
-	target    string
-	isSymLink bool
-	isDir     bool
+	source       string
+	target       string
+	isSymLink    bool
+	isDir        bool
+	storageClass types.StorageClass // of the source, looked up when empty
+	size         int64              // of the source, looked up when zero
 }
 
 type renameObjectOptions struct {
-	source    string
-	target    string
-	isSymLink bool
-	isDir     bool
+	source       string
+	target       string
+	isSymLink    bool
+	isDir        bool
+	storageClass types.StorageClass // of the source, looked up when empty
+	size         int64              // of the source, looked up when zero
 }
 
 const symlinkStr = ".rclonelink"
@@ -96,26 +105,44 @@ func (cl *Client) ConnectionOkay(ctx context.Context) error {
 }
 
 // getObjectMultipartDownload downloads an object to a file using multipart download
-// which can be much faster for large objects.
-func (cl *Client) getObjectMultipartDownload(ctx context.Context, name string, fi *os.File) error {
+// which can be much faster for large objects. An empty versionID downloads the current version.
+func (cl *Client) getObjectMultipartDownload(
+	ctx context.Context,
+	name string,
+	versionID string,
+	fi *os.File,
+) error {
 	key := cl.getKey(name, false, false)
 	log.Trace("Client::getObjectMultipartDownload : get object %s", key)
 
+	version, err := cl.versionToRead(ctx, key, versionID)
+	if err != nil {
+		return err
+	}
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return err
+	}
 	downloadInput := &transfermanager.DownloadObjectInput{
-		Bucket:   aws.String(cl.Config.AuthConfig.BucketName),
-		Key:      aws.String(key),
-		WriterAt: fi,
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		WriterAt:             fi,
+		VersionID:            version,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
 	}
 
 	if cl.Config.enableChecksum {
 		downloadInput.ChecksumMode = tmtypes.ChecksumModeEnabled
 	}
 
-	_, err := cl.transferManager.DownloadObject(ctx, downloadInput)
+	_, err = cl.transferManager.DownloadObject(ctx, downloadInput)
 	// check for errors
 	if err != nil {
 		attemptedAction := fmt.Sprintf("GetObject(%s)", key)
-		return parseS3Err(err, attemptedAction)
+		options := getObjectOptions{name: name, versionID: versionID}
+		return cl.archivedReadError(ctx, options, parseS3Err(err, attemptedAction))
 	}
 	return nil
 }
@@ -158,13 +185,26 @@ func (cl *Client) getObject(ctx context.Context, options getObjectOptions) (io.R
 	if cl.Config.enableChecksum {
 		getObjectInput.ChecksumMode = types.ChecksumModeEnabled
 	}
+	versionID, err := cl.versionToRead(ctx, key, options.versionID)
+	if err != nil {
+		return nil, err
+	}
+	getObjectInput.VersionId = versionID
+
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return nil, err
+	}
+	getObjectInput.SSECustomerAlgorithm = sse.customerAlgorithm
+	getObjectInput.SSECustomerKey = sse.customerKey
+	getObjectInput.SSECustomerKeyMD5 = sse.customerKeyMD5
 
 	result, err := cl.AwsS3Client.GetObject(ctx, getObjectInput)
 
 	// check for errors
 	if err != nil {
 		attemptedAction := fmt.Sprintf("GetObject(%s)", key)
-		return nil, parseS3Err(err, attemptedAction)
+		return nil, cl.archivedReadError(ctx, options, parseS3Err(err, attemptedAction))
 	}
 
 	// return body, err
@@ -184,11 +224,25 @@ func (cl *Client) putObject(ctx context.Context, options putObjectOptions) error
 		body = bytes.NewReader([]byte{})
 	}
 
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return err
+	}
+	headers, _ := cl.objectHeaders(options.name)
 	uploadInput := &transfermanager.UploadObjectInput{
-		Bucket:      aws.String(cl.Config.AuthConfig.BucketName),
-		Key:         aws.String(key),
-		Body:        body,
-		ContentType: aws.String(getContentType(key)),
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		Body:                 body,
+		ContentType:          contentType(key, headers),
+		CacheControl:         optionalString(headers.CacheControl),
+		ContentEncoding:      optionalString(headers.ContentEncoding),
+		Metadata:             withRuleMetadata(options.metadata, headers),
+		ServerSideEncryption: tmtypes.ServerSideEncryption(sse.serverSideEncryption),
+		SSEKMSKeyID:          sse.kmsKeyID,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+		StorageClass:         tmtypes.StorageClass(cl.storageClass(options.name)),
 	}
 
 	if cl.Config.enableChecksum {
@@ -197,7 +251,18 @@ func (cl *Client) putObject(ctx context.Context, options putObjectOptions) error
 		)
 	}
 
-	_, err := cl.transferManager.UploadObject(ctx, uploadInput)
+	switch {
+	case options.ifMatch == nil:
+	case *options.ifMatch == "":
+		uploadInput.IfNoneMatch = aws.String("*")
+	default:
+		uploadInput.IfMatch = aws.String(quoteETag(*options.ifMatch))
+	}
+
+	output, err := cl.transferManager.UploadObject(ctx, uploadInput)
+	if err == nil && options.newETag != nil {
+		*options.newETag = sanitizeETag(output.ETag)
+	}
 
 	attemptedAction := fmt.Sprintf("upload object %s", key)
 	return parseS3Err(err, attemptedAction)
@@ -231,9 +296,21 @@ func (cl *Client) headObject(
 	key := cl.getKey(name, isSymlink, isDir)
 	log.Trace("Client::headObject : object %s", key)
 
+	versionID, err := cl.versionToRead(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return nil, err
+	}
 	result, err := cl.AwsS3Client.HeadObject(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(cl.Config.AuthConfig.BucketName),
-		Key:    aws.String(key),
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		VersionId:            versionID,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
 	})
 	if err != nil {
 		// Make sure the attempted starts with "HeadObject",  or else parseS3Err will log to Err
@@ -247,11 +324,106 @@ func (cl *Client) headObject(
 		object = createObjAttrDir(name)
 	} else {
 		object = createObjAttr(name, *result.ContentLength, *result.LastModified, isSymlink)
+		object.ETag = sanitizeETag(result.ETag)
+		object.Tier = string(result.StorageClass)
+	}
+	// keep user metadata so it survives when the object is uploaded again
+	for key, value := range result.Metadata {
+		if key != symlinkKey {
+			object.Metadata[key] = &value
+		}
 	}
+	// times set with utimens take precedence over LastModified
+	object.SetTimesFromMetadata()
 
 	return object, nil
 }
 
+// Wrapper for awsS3Client.HeadObject that returns the raw response.
+// This is used when the caller needs the user metadata and system properties of an object.
+func (cl *Client) headObjectOutput(
+	ctx context.Context,
+	name string,
+	isSymlink bool,
+	isDir bool,
+) (*s3.HeadObjectOutput, error) {
+	key := cl.getKey(name, isSymlink, isDir)
+	log.Trace("Client::headObjectOutput : object %s", key)
+
+	versionID, err := cl.versionToRead(ctx, key, "")
+	if err != nil {
+		return nil, err
+	}
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return nil, err
+	}
+	result, err := cl.AwsS3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		VersionId:            versionID,
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+	})
+	if err != nil {
+		attemptedAction := fmt.Sprintf("HeadObject(%s)", name)
+		return nil, parseS3Err(err, attemptedAction)
+	}
+
+	return result, nil
+}
+
+// Wrapper for awsS3Client.CopyObject that copies an object onto itself to replace its metadata.
+// S3 does not allow metadata to be changed in place, and a copy with the REPLACE directive
+// resets every system property not given, so those are carried over from the head response.
+func (cl *Client) replaceObjectMetadata(
+	ctx context.Context,
+	options getObjectOptions,
+	head *s3.HeadObjectOutput,
+	metadata map[string]string,
+) error {
+	key := cl.getKey(options.name, options.isSymLink, options.isDir)
+	log.Trace("Client::replaceObjectMetadata : object %s", key)
+
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return err
+	}
+	copyObjectInput := &s3.CopyObjectInput{
+		Bucket: aws.String(cl.Config.AuthConfig.BucketName),
+		CopySource: aws.String(
+			fmt.Sprintf("%v/%v", cl.Config.AuthConfig.BucketName, url.PathEscape(key)),
+		),
+		Key:                aws.String(key),
+		CopySourceIfMatch:  head.ETag,
+		Metadata:           metadata,
+		MetadataDirective:  types.MetadataDirectiveReplace,
+		ContentType:        head.ContentType,
+		CacheControl:       head.CacheControl,
+		ContentDisposition: head.ContentDisposition,
+		ContentEncoding:    head.ContentEncoding,
+		ContentLanguage:    head.ContentLanguage,
+		StorageClass:       head.StorageClass,
+	}
+	sse.applyToCopy(copyObjectInput)
+
+	if cl.Config.enableChecksum {
+		copyObjectInput.ChecksumAlgorithm = cl.Config.checksumAlgorithm
+	}
+	if size := aws.ToInt64(head.ContentLength); size > maxCopyObjectSize {
+		return cl.multipartCopy(ctx, copyObjectInput, size)
+	}
+
+	_, err = cl.AwsS3Client.CopyObject(ctx, copyObjectInput)
+	if err != nil {
+		attemptedAction := fmt.Sprintf("replace metadata of %s", key)
+		return parseS3Err(err, attemptedAction)
+	}
+
+	return nil
+}
+
 // Wrapper for awsS3Client.HeadBucket
 func (cl *Client) headBucket(ctx context.Context, bucketName string) (*s3.HeadBucketOutput, error) {
 	headBucketOutput, err := cl.AwsS3Client.HeadBucket(ctx, &s3.HeadBucketInput{
@@ -266,19 +438,61 @@ func (cl *Client) copyObject(ctx context.Context, options copyObjectOptions) err
 	sourceKey := cl.getKey(options.source, options.isSymLink, options.isDir)
 	targetKey := cl.getKey(options.target, options.isSymLink, options.isDir)
 
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return err
+	}
+	// a copy keeps the headers of its source unless all of them are replaced, so the source is
+	// looked up to carry over the ones the rules do not set. It is also looked up when its size
+	// is not known, as a source too large for CopyObject has to be copied in parts.
+	headers, replaceHeaders := cl.objectHeaders(options.target)
+	storageClass := cl.copyStorageClass(options)
+	var head *s3.HeadObjectOutput
+	if storageClass == "" || replaceHeaders || options.size == 0 ||
+		options.size > maxCopyObjectSize {
+		head, err = cl.headObjectOutput(ctx, options.source, options.isSymLink, options.isDir)
+		if err != nil {
+			return err
+		}
+		storageClass = cmp.Or(storageClass, head.StorageClass)
+	}
+	// objects too large for CopyObject are copied in parts, which keep none of the source's
+	// headers, so they are set like when the rules replace them
+	multipart := head != nil && aws.ToInt64(head.ContentLength) > maxCopyObjectSize
 	copyObjectInput := &s3.CopyObjectInput{
 		Bucket: aws.String(cl.Config.AuthConfig.BucketName),
 		CopySource: aws.String(
 			fmt.Sprintf("%v/%v", cl.Config.AuthConfig.BucketName, url.PathEscape(sourceKey)),
 		),
-		Key: aws.String(targetKey),
+		Key:          aws.String(targetKey),
+		StorageClass: storageClass,
+	}
+	if replaceHeaders || multipart {
+		copyObjectInput.CopySourceIfMatch = head.ETag
+		copyObjectInput.MetadataDirective = types.MetadataDirectiveReplace
+		copyObjectInput.Metadata = withRuleMetadata(head.Metadata, headers)
+		copyObjectInput.ContentType = cmp.Or(optionalString(headers.ContentType), head.ContentType)
+		copyObjectInput.CacheControl = cmp.Or(
+			optionalString(headers.CacheControl),
+			head.CacheControl,
+		)
+		copyObjectInput.ContentEncoding = cmp.Or(
+			optionalString(headers.ContentEncoding),
+			head.ContentEncoding,
+		)
+		copyObjectInput.ContentDisposition = head.ContentDisposition
+		copyObjectInput.ContentLanguage = head.ContentLanguage
 	}
+	sse.applyToCopy(copyObjectInput)
 
 	if cl.Config.enableChecksum {
 		copyObjectInput.ChecksumAlgorithm = cl.Config.checksumAlgorithm
 	}
+	if multipart {
+		return cl.multipartCopy(ctx, copyObjectInput, aws.ToInt64(head.ContentLength))
+	}
 
-	_, err := cl.AwsS3Client.CopyObject(ctx, copyObjectInput)
+	_, err = cl.AwsS3Client.CopyObject(ctx, copyObjectInput)
 	// check for errors on copy
 	if err != nil {
 		attemptedAction := fmt.Sprintf("copy %s to %s", sourceKey, targetKey)
@@ -393,6 +607,21 @@ func (cl *Client) List(
 		return ""
 	}(marker), count)
 
+	if cl.Config.enableVersions {
+		if vp, inTree, err := parseVersionPath(prefix); inTree {
+			if err != nil {
+				return nil, nil, err
+			}
+			// version listings are returned in one go
+			attrList, err := cl.listVersionTree(ctx, vp)
+			return attrList, nil, err
+		}
+	}
+	if !cl.Config.asOf.IsZero() {
+		attrList, err := cl.listAsOf(ctx, prefix)
+		return attrList, nil, err
+	}
+
 	// prepare parameters
 	bucketName := cl.Config.AuthConfig.BucketName
 	if count == 0 {
@@ -466,6 +695,8 @@ func (cl *Client) List(
 
 		path := split(cl.Config.prefixPath, name)
 		attr := createObjAttr(path, *value.Size, *value.LastModified, isSymLink)
+		attr.ETag = sanitizeETag(value.ETag)
+		attr.Tier = string(value.StorageClass)
 		objectAttrList = append(objectAttrList, attr)
 	}
 
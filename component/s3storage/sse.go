@@ -0,0 +1,134 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Server-side encryption modes, set with the server-side-encryption option
+const (
+	sseNone = "none"
+	sseS3   = "sse-s3"  // keys managed by S3
+	sseKMS  = "sse-kms" // keys managed by KMS
+	sseC    = "sse-c"   // key provided by the customer with every request
+)
+
+// SSE-C keys are 256-bit AES keys
+const sseCustomerKeyLength = 32
+
+var errInvalidSSECustomerKey = fmt.Errorf(
+	"%w: sse-customer-key must be a base64-encoded 256-bit key",
+	errInvalidConfigField,
+)
+
+// sseHeaders holds the encryption parameters sent with a request.
+// Fields are nil when they are not used by the configured mode.
+type sseHeaders struct {
+	serverSideEncryption types.ServerSideEncryption
+	kmsKeyID             *string
+	customerAlgorithm    *string
+	customerKey          *string
+	customerKeyMD5       *string
+}
+
+// parseSSEMode validates the server-side-encryption option
+func parseSSEMode(mode string) (string, error) {
+	mode = strings.ToLower(mode)
+	switch mode {
+	case "", sseNone:
+		return sseNone, nil
+	case sseS3, sseKMS, sseC:
+		return mode, nil
+	}
+	return "", fmt.Errorf(
+		"%w: server-side-encryption must be one of %s, %s, %s or %s",
+		errInvalidConfigField,
+		sseNone,
+		sseS3,
+		sseKMS,
+		sseC,
+	)
+}
+
+// decodeSSECustomerKey decodes the sse-customer-key option into the raw key
+func decodeSSECustomerKey(encodedKey string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != sseCustomerKeyLength {
+		return nil, errInvalidSSECustomerKey
+	}
+	return key, nil
+}
+
+// sseHeaders returns the encryption parameters to send with a request.
+// The SSE-C key is only decrypted for as long as it takes to encode it.
+func (cl *Client) sseHeaders() (sseHeaders, error) {
+	var headers sseHeaders
+	switch cl.Config.sseMode {
+	case sseS3:
+		headers.serverSideEncryption = types.ServerSideEncryptionAes256
+	case sseKMS:
+		headers.serverSideEncryption = types.ServerSideEncryptionAwsKms
+		if cl.Config.sseKMSKeyID != "" {
+			headers.kmsKeyID = aws.String(cl.Config.sseKMSKeyID)
+		}
+	case sseC:
+		if cl.Config.AuthConfig.SSECustomerKey == nil {
+			return headers, errInvalidSSECustomerKey
+		}
+		key, err := cl.Config.AuthConfig.SSECustomerKey.Open()
+		if err != nil || key == nil {
+			return headers, errors.New("unable to decrypt sse customer key")
+		}
+		defer key.Destroy()
+		keyMD5 := md5.Sum(key.Bytes())
+		headers.customerAlgorithm = aws.String(string(types.ServerSideEncryptionAes256))
+		headers.customerKey = aws.String(base64.StdEncoding.EncodeToString(key.Bytes()))
+		headers.customerKeyMD5 = aws.String(base64.StdEncoding.EncodeToString(keyMD5[:]))
+	}
+	return headers, nil
+}
+
+// applyToCopy sets the encryption of the destination of a copy.
+// The source is expected to be encrypted with the same settings.
+func (headers sseHeaders) applyToCopy(input *s3.CopyObjectInput) {
+	input.ServerSideEncryption = headers.serverSideEncryption
+	input.SSEKMSKeyId = headers.kmsKeyID
+	input.SSECustomerAlgorithm = headers.customerAlgorithm
+	input.SSECustomerKey = headers.customerKey
+	input.SSECustomerKeyMD5 = headers.customerKeyMD5
+	input.CopySourceSSECustomerAlgorithm = headers.customerAlgorithm
+	input.CopySourceSSECustomerKey = headers.customerKey
+	input.CopySourceSSECustomerKeyMD5 = headers.customerKeyMD5
+}
@@ -0,0 +1,54 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"cmp"
+
+	"github.com/Seagate/cloudfuse/common"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Storage class rules choose the storage class of uploaded objects from their path. The first
+// rule matching an object applies, and objects matching no rule use the bucket default.
+
+// storageClass returns the storage class of the first rule matching name, or an empty string
+func (cl *Client) storageClass(name string) types.StorageClass {
+	for _, rule := range cl.Config.storageClassRules {
+		if common.MatchPath(rule.Pattern, name) {
+			return rule.StorageClass
+		}
+	}
+	return ""
+}
+
+// copyStorageClass returns the storage class of a copy, or an empty string when it is the class
+// of the source, which must be looked up. A copy is put in the STANDARD storage class unless told
+// otherwise, so the class of the source is kept when no rule matches the target.
+func (cl *Client) copyStorageClass(options copyObjectOptions) types.StorageClass {
+	return cmp.Or(cl.storageClass(options.target), options.storageClass)
+}
@@ -34,6 +34,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/Seagate/cloudfuse/common"
@@ -58,6 +59,10 @@ const (
 	DefaultUploadCutoff = 100 * common.MbToBytes
 	DefaultConcurrency  = 5
 	MaxPartSizeMb       = 5 * 1024
+	// S3 limits multipart uploads to 10,000 parts
+	maxUploadParts = 10000
+	// Objects larger than this are copied in parts
+	maxCopyObjectSize = 5 * common.GbToBytes
 )
 
 // ----------- Cloud Storage error code handling ---------------
@@ -143,6 +148,35 @@ func parseS3Err(err error, attemptedAction string) error {
 			}
 			return syscall.ENOENT
 		}
+		if errorCode == "PreconditionFailed" || errorCode == "ConditionalRequestConflict" {
+			log.Warn(
+				"%s : Failed to %s with error %s because the object was changed",
+				functionName,
+				attemptedAction,
+				errorCode,
+			)
+			return common.NewConflictError(err)
+		}
+		if errorCode == "InvalidObjectState" {
+			// GetObject and CopyObject of an object that must be restored from an archive first
+			log.Err(
+				"%s : Failed to %s with error %s because the object is archived",
+				functionName,
+				attemptedAction,
+				errorCode,
+			)
+			return common.NewArchivedError(err, false)
+		}
+		if isThrottled(err) {
+			// the rate limiter slowed down and retried the request already
+			log.Warn(
+				"%s : Failed to %s with error %s because requests are throttled",
+				functionName,
+				attemptedAction,
+				errorCode,
+			)
+			return syscall.EBUSY
+		}
 		if errorCode == "KeyTooLongError" {
 			log.Err(
 				"%s : Failed to %s with error %s because key length exceeded backend limit",
@@ -178,6 +212,19 @@ func parseS3Err(err error, attemptedAction string) error {
 	return err
 }
 
+// sanitizeETag returns an ETag without the quotes S3 puts around it
+func sanitizeETag(etag *string) string {
+	if etag == nil {
+		return ""
+	}
+	return strings.Trim(*etag, `"`)
+}
+
+// quoteETag returns an ETag in the quotes conditional requests expect
+func quoteETag(etag string) string {
+	return `"` + etag + `"`
+}
+
 // TODO: handle AWS S3 storage tiers here
 // TODO: write utils_test.go with unit tests
 
@@ -283,6 +330,48 @@ func split(prefixPath string, path string) string {
 	return path
 }
 
+// multipartPartSize returns the configured part size, or the smallest part size that fits an
+// object of the given size in the maximum number of parts
+func (cl *Client) multipartPartSize(size int64) int64 {
+	return max(cl.Config.partSize, (size+maxUploadParts-1)/maxUploadParts)
+}
+
+// forEachPart calls fn with the part numbers from 1 to count that are not done, from as many
+// goroutines as the configured concurrency. It stops at the first error, and returns it.
+func (cl *Client) forEachPart(
+	ctx context.Context,
+	count int32,
+	done map[int32]bool,
+	fn func(ctx context.Context, number int32) error,
+) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	numbers := make(chan int32)
+	var wg sync.WaitGroup
+	for range max(cl.Config.concurrency, 1) {
+		wg.Go(func() {
+			for number := range numbers {
+				if err := fn(ctx, number); err != nil {
+					cancel(err)
+				}
+			}
+		})
+	}
+
+	for number := int32(1); number <= count && ctx.Err() == nil; number++ {
+		if done[number] {
+			continue
+		}
+		select {
+		case numbers <- number:
+		case <-ctx.Done():
+		}
+	}
+	close(numbers)
+	wg.Wait()
+	return context.Cause(ctx)
+}
+
 func removeLeadingSlashes(s string) string {
 	for strings.HasPrefix(s, "/") {
 		s = strings.TrimLeft(s, "/")
@@ -26,13 +26,31 @@
 package s3storage
 
 import (
+	"context"
+	"errors"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"testing"
+	"time"
 
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/stats_manager"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
 	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
 	smithyHttp "github.com/aws/smithy-go/transport/http"
@@ -69,6 +87,30 @@ func (s *utilsTestSuite) TestParseS3errHeadObjectNotFound() {
 	assert.Equal(syscall.ENOENT, err)
 }
 
+func (s *utilsTestSuite) TestParseS3errGetObjectInvalidObjectState() {
+	assert := assert.New(s.T())
+
+	errMessage := "The operation is not valid for the object's storage class"
+	getObjectS3Err := generateS3Error("GetObject", 403, &types.InvalidObjectState{
+		Message: &errMessage,
+	})
+	err := parseS3Err(getObjectS3Err, "test")
+	assert.ErrorIs(err, &common.ArchivedError{})
+}
+
+func (s *utilsTestSuite) TestParseS3errPutObjectSlowDown() {
+	assert := assert.New(s.T())
+
+	putObjectS3Err := generateS3Error("PutObject", 503, &smithy.GenericAPIError{
+		Message: "Please reduce your request rate.",
+		Code:    "SlowDown",
+		Fault:   smithy.FaultServer,
+	})
+	assert.True(isThrottled(putObjectS3Err))
+	err := parseS3Err(putObjectS3Err, "test")
+	assert.Equal(syscall.EBUSY, err)
+}
+
 func (s *utilsTestSuite) TestParseS3errCopyObjectNoSuchKey() {
 	assert := assert.New(s.T())
 
@@ -272,6 +314,641 @@ func (s *utilsTestSuite) TestRemoveLeadingSlashes() {
 	}
 }
 
+func (s *utilsTestSuite) TestParseVersionPath() {
+	assert := assert.New(s.T())
+
+	var inputs = []struct {
+		name   string
+		vp     versionPath
+		inTree bool
+		err    error
+	}{
+		{name: "dir/file", inTree: false},
+		{name: "dir/file.versions", inTree: false},
+		{name: ".versions", vp: versionPath{}, inTree: true},
+		{name: "dir/.versions/", vp: versionPath{dir: "dir"}, inTree: true},
+		{name: "a/b/.versions/f", vp: versionPath{dir: "a/b", file: "f"}, inTree: true},
+		{
+			name:   "/.versions/f/20240102T030405Z-abc",
+			vp:     versionPath{file: "f", version: "20240102T030405Z-abc"},
+			inTree: true,
+		},
+		{name: "dir/.versions/f/v/x", inTree: true, err: syscall.ENOENT},
+	}
+
+	for _, i := range inputs {
+		vp, inTree, err := parseVersionPath(i.name)
+		assert.Equal(i.inTree, inTree, i.name)
+		assert.Equal(i.err, err, i.name)
+		if i.err == nil {
+			assert.Equal(i.vp, vp, i.name)
+		}
+	}
+	vp, _, _ := parseVersionPath("a/b/.versions/f/v")
+	assert.Equal("a/b/.versions/f/v", vp.path())
+	assert.Equal("a/b/f", vp.filePath())
+}
+
+func (s *utilsTestSuite) TestVersionName() {
+	assert := assert.New(s.T())
+
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 6, time.FixedZone("UTC+1", 3600))
+	name := versionName(lastModified, "3sL4k-Qz.8_null")
+	assert.Equal("20240102T020405Z-3sL4k-Qz.8_null", name)
+	versionID, err := parseVersionName(name)
+	assert.NoError(err)
+	assert.Equal("3sL4k-Qz.8_null", versionID)
+
+	for _, name := range []string{"", "20240102T020405Z", "20240102T020405Z-", "2024-abc"} {
+		_, err = parseVersionName(name)
+		assert.Equal(syscall.ENOENT, err, name)
+	}
+}
+
+func (s *utilsTestSuite) TestResolveVersion() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	name, versionID, err := cl.resolveVersion("dir/.versions/f/20240102T020405Z-abc")
+	assert.NoError(err)
+	assert.Equal("dir/.versions/f/20240102T020405Z-abc", name)
+	assert.Empty(versionID)
+
+	cl.Config.enableVersions = true
+	name, versionID, err = cl.resolveVersion("dir/.versions/f/20240102T020405Z-abc")
+	assert.NoError(err)
+	assert.Equal("dir/f", name)
+	assert.Equal("abc", versionID)
+
+	name, versionID, err = cl.resolveVersion("dir/f")
+	assert.NoError(err)
+	assert.Equal("dir/f", name)
+	assert.Empty(versionID)
+
+	_, _, err = cl.resolveVersion("dir/.versions/f")
+	assert.Equal(syscall.EISDIR, err)
+}
+
+func (s *utilsTestSuite) TestResolveAsOf() {
+	assert := assert.New(s.T())
+
+	asOf := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	cl := &Client{}
+	cl.Config.asOf = asOf
+	at := func(hours int) *time.Time {
+		t := asOf.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+	version := func(key string, id string, hours int) types.ObjectVersion {
+		return types.ObjectVersion{
+			Key:          &key,
+			VersionId:    &id,
+			LastModified: at(hours),
+			Size:         new(int64(hours)),
+		}
+	}
+	marker := func(key string, id string, hours int) types.DeleteMarkerEntry {
+		return types.DeleteMarkerEntry{Key: &key, VersionId: &id, LastModified: at(hours)}
+	}
+
+	versions := make(map[string]asOfVersion)
+	cl.resolveAsOf(&s3.ListObjectVersionsOutput{
+		Versions: []types.ObjectVersion{
+			version("changed", "c2", 1),
+			version("changed", "c1", -1),
+			version("deleted", "d1", -2),
+			version("later", "l1", 1),
+			version("restored", "r2", -1),
+		},
+		DeleteMarkers: []types.DeleteMarkerEntry{
+			marker("deleted", "d2", -1),
+			marker("restored", "r1", -2),
+		},
+	}, versions)
+	// versions of a key continue on the next page, from newest to oldest
+	cl.resolveAsOf(&s3.ListObjectVersionsOutput{
+		Versions: []types.ObjectVersion{version("restored", "r0", -3)},
+	}, versions)
+
+	assert.Len(versions, 3)
+	assert.Equal("c1", versions["changed"].versionID)
+	assert.Equal(int64(-1), versions["changed"].size)
+	assert.True(versions["deleted"].deleted)
+	assert.Equal("r2", versions["restored"].versionID)
+	assert.False(versions["restored"].deleted)
+
+	cl.asOfVersions.Store("changed", versions["changed"])
+	cl.asOfVersions.Store("deleted", versions["deleted"])
+	versionID, err := cl.versionToRead(context.Background(), "changed", "")
+	assert.NoError(err)
+	assert.Equal("c1", *versionID)
+	_, err = cl.versionToRead(context.Background(), "deleted", "")
+	assert.Equal(syscall.ENOENT, err)
+	versionID, err = cl.versionToRead(context.Background(), "deleted", "d1")
+	assert.NoError(err)
+	assert.Equal("d1", *versionID)
+
+	cl.Config.asOf = time.Time{}
+	versionID, err = cl.versionToRead(context.Background(), "changed", "")
+	assert.NoError(err)
+	assert.Nil(versionID)
+}
+
+func (s *utilsTestSuite) TestRestoreStatus() {
+	assert := assert.New(s.T())
+
+	assert.Empty(restoreStatus(&s3.HeadObjectOutput{}))
+	assert.Empty(restoreStatus(&s3.HeadObjectOutput{StorageClass: types.StorageClassStandardIa}))
+	assert.Equal(
+		internal.RestoreStatusArchived,
+		restoreStatus(&s3.HeadObjectOutput{StorageClass: types.StorageClassGlacier}),
+	)
+	assert.Equal(internal.RestoreStatusRestoring, restoreStatus(&s3.HeadObjectOutput{
+		StorageClass: types.StorageClassDeepArchive,
+		Restore:      new(`ongoing-request="true"`),
+	}))
+	assert.Equal(internal.RestoreStatusRestored, restoreStatus(&s3.HeadObjectOutput{
+		StorageClass: types.StorageClassGlacier,
+		Restore: new(
+			`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`,
+		),
+	}))
+	assert.Equal(internal.RestoreStatusArchived, restoreStatus(&s3.HeadObjectOutput{
+		StorageClass:  types.StorageClassIntelligentTiering,
+		ArchiveStatus: types.ArchiveStatusArchiveAccess,
+	}))
+
+	metadata := map[string]*string{}
+	archiveMetadata(&s3.HeadObjectOutput{StorageClass: types.StorageClassGlacier}, metadata)
+	assert.Equal("GLACIER", *metadata[internal.TierMetadataKey])
+	assert.Equal(internal.RestoreStatusArchived, *metadata[internal.RestoreMetadataKey])
+}
+
+func (s *utilsTestSuite) TestStorageClass() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	cl.Config.storageClassRules = []StorageClassRule{
+		{Pattern: "logs/", StorageClass: types.StorageClassStandardIa},
+		{Pattern: "*.tmp", StorageClass: types.StorageClassOnezoneIa},
+		{Pattern: "scratch/", StorageClass: types.StorageClassGlacierIr},
+	}
+	assert.Equal(types.StorageClassStandardIa, cl.storageClass("logs/2024/app.log"))
+	assert.Equal(types.StorageClassOnezoneIa, cl.storageClass("app.tmp"))
+	assert.Equal(types.StorageClassStandardIa, cl.storageClass("logs/app.tmp"))
+	assert.Equal(types.StorageClassGlacierIr, cl.storageClass("scratch/a/b"))
+	assert.Empty(cl.storageClass("data/a.csv"))
+
+	// copies take the class of the first rule matching the target, or keep the source's
+	assert.Equal(types.StorageClassGlacierIr, cl.copyStorageClass(copyObjectOptions{
+		source:       "data/a.csv",
+		target:       "scratch/a.csv",
+		storageClass: types.StorageClassStandard,
+	}))
+	assert.Equal(types.StorageClassStandardIa, cl.copyStorageClass(copyObjectOptions{
+		source:       "logs/a.log",
+		target:       "data/a.log",
+		storageClass: types.StorageClassStandardIa,
+	}))
+	assert.Empty(cl.copyStorageClass(copyObjectOptions{source: "a.log", target: "b.log"}))
+}
+
+func (s *utilsTestSuite) TestObjectHeaders() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	cl.Config.headerRules = []internal.HeaderRule{
+		{Pattern: "site/", CacheControl: "max-age=60", Metadata: map[string]string{"team": "web"}},
+	}
+	headers, matched := cl.objectHeaders("site/index.html")
+	assert.True(matched)
+	assert.Equal("text/html", *contentType("site/index.html", headers))
+	assert.Equal(
+		map[string]string{"team": "ops", "owner": "me"},
+		withRuleMetadata(map[string]string{"team": "ops", "owner": "me"}, headers),
+	)
+	assert.Equal(map[string]string{"team": "web"}, withRuleMetadata(nil, headers))
+
+	headers, matched = cl.objectHeaders("data/a.csv")
+	assert.False(matched)
+	assert.Nil(optionalString(headers.CacheControl))
+	assert.Nil(withRuleMetadata(nil, headers))
+}
+
+func (s *utilsTestSuite) TestUploadState() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	cl.Config.AuthConfig.BucketName = "bucket"
+	cl.Config.uploadStatePath = filepath.Join(s.T().TempDir(), common.UploadStateDirName)
+	statePath := cl.uploadStateFile("dir/file")
+	assert.Equal(cl.Config.uploadStatePath, filepath.Dir(statePath))
+	assert.NotEqual(statePath, cl.uploadStateFile("dir/file2"))
+
+	state := &uploadState{
+		Bucket:   "bucket",
+		Key:      "dir/file",
+		UploadID: "id",
+		Size:     25 * common.MbToBytes,
+		ModTime:  time.Now(),
+		PartSize: 8 * common.MbToBytes,
+		Parts: []types.CompletedPart{
+			{ETag: aws.String("etag2"), PartNumber: aws.Int32(2)},
+			{ETag: aws.String("etag1"), PartNumber: aws.Int32(1)},
+		},
+	}
+	assert.EqualValues(4, state.partCount())
+	assert.NoError(state.save(statePath))
+	loaded, err := loadUploadState(statePath)
+	assert.NoError(err)
+	assert.True(loaded.matches(state))
+	assert.Equal("id", loaded.UploadID)
+	assert.Equal("etag1", *loaded.Parts[1].ETag)
+	assert.EqualValues(1, *loaded.Parts[1].PartNumber)
+
+	// the upload starts over if the local file changed
+	changed := *state
+	changed.ModTime = state.ModTime.Add(time.Second)
+	assert.False(loaded.matches(&changed))
+	changed = *state
+	changed.Size++
+	assert.False(loaded.matches(&changed))
+
+	// the state directory goes away with its last state file
+	cl.removeStateFile(statePath)
+	assert.NoDirExists(cl.Config.uploadStatePath)
+	_, err = loadUploadState(statePath)
+	assert.ErrorIs(err, os.ErrNotExist)
+}
+
+func (s *utilsTestSuite) TestRenameJournal() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	cl.Config.AuthConfig.BucketName = "bucket"
+	cl.Config.prefixPath = "mount"
+	cl.Config.uploadStatePath = filepath.Join(s.T().TempDir(), common.UploadStateDirName)
+	journalPath := cl.renameJournalFile("dir")
+	assert.Equal(cl.Config.uploadStatePath, filepath.Dir(journalPath))
+	assert.NotEqual(journalPath, cl.renameJournalFile("dir2"))
+
+	listed := []string{"mount/dir/a", "mount/dir/b", "mount/dir/c", "mount/dir/d"}
+	journal, err := cl.createRenameJournal("dir", "newdir", listed)
+	assert.NoError(err)
+	assert.NoFileExists(journalPath + ".tmp")
+	assert.NoError(journal.append([]string{"mount/dir/a", "mount/dir/b"}))
+	assert.NoError(journal.append(nil))
+	cl.closeRenameJournal(journal, false)
+
+	journal, header, keys, copied, err := openRenameJournal(journalPath)
+	assert.NoError(err)
+	assert.Equal(renameJournalHeader{
+		Bucket: "bucket",
+		Prefix: "mount",
+		Source: "dir",
+		Target: "newdir",
+	}, header)
+	assert.Equal(listed, keys)
+	assert.Equal(map[string]bool{"mount/dir/a": true, "mount/dir/b": true}, copied)
+	assert.NoError(journal.append([]string{"mount/dir/c"}))
+	// objects the rename did not list are ignored
+	assert.NoError(journal.append([]string{"mount/dir/e"}))
+	cl.closeRenameJournal(journal, false)
+
+	// a line torn by a crash is dropped
+	file, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoError(err)
+	_, err = file.WriteString(`{"key":"mount/dir/d","copied":tr`)
+	assert.NoError(err)
+	assert.NoError(file.Close())
+	journal, _, keys, copied, err = openRenameJournal(journalPath)
+	assert.NoError(err)
+	assert.Equal(listed, keys)
+	assert.Len(copied, 3)
+	assert.False(copied["mount/dir/d"])
+	assert.False(copied["mount/dir/e"])
+
+	// the journal and the state directory go away once the rename is complete
+	cl.closeRenameJournal(journal, true)
+	assert.NoDirExists(cl.Config.uploadStatePath)
+
+	// a journal without a header is invalid
+	assert.NoError(os.MkdirAll(cl.Config.uploadStatePath, 0700))
+	assert.NoError(os.WriteFile(journalPath, []byte(`{"bucket":`), 0600))
+	_, _, _, _, err = openRenameJournal(journalPath)
+	assert.ErrorIs(err, errInvalidRenameJournal)
+	assert.NoError(os.WriteFile(journalPath, nil, 0600))
+	_, _, _, _, err = openRenameJournal(journalPath)
+	assert.ErrorIs(err, errInvalidRenameJournal)
+
+	// without a state directory there is no journal
+	cl.Config.uploadStatePath = ""
+	journal, err = cl.createRenameJournal("dir", "newdir", listed)
+	assert.NoError(err)
+	assert.Nil(journal)
+	assert.NoError(journal.append([]string{"mount/dir/a"}))
+	cl.closeRenameJournal(journal, true)
+}
+
+func (s *utilsTestSuite) TestMultipartPartSize() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	cl.Config.partSize = 8 * common.MbToBytes
+	assert.EqualValues(8*common.MbToBytes, cl.multipartPartSize(10*common.GbToBytes))
+	// 100 GB does not fit in 10,000 parts of 8 MB
+	partSize := cl.multipartPartSize(100 * common.GbToBytes)
+	assert.Greater(partSize, int64(8*common.MbToBytes))
+	assert.LessOrEqual((100*common.GbToBytes+partSize-1)/partSize, int64(maxUploadParts))
+}
+
+func (s *utilsTestSuite) TestForEachPart() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	cl.Config.concurrency = 3
+	var lock sync.Mutex
+	var numbers []int32
+	err := cl.forEachPart(
+		context.Background(),
+		5,
+		map[int32]bool{2: true},
+		func(ctx context.Context, number int32) error {
+			lock.Lock()
+			defer lock.Unlock()
+			numbers = append(numbers, number)
+			return nil
+		},
+	)
+	assert.NoError(err)
+	slices.Sort(numbers)
+	assert.Equal([]int32{1, 3, 4, 5}, numbers)
+
+	// the first error stops the parts that have not started
+	failed := errors.New("failed")
+	cl.Config.concurrency = 1
+	numbers = nil
+	failAt2 := func(ctx context.Context, number int32) error {
+		numbers = append(numbers, number)
+		if number == 2 {
+			return failed
+		}
+		return nil
+	}
+	err = cl.forEachPart(context.Background(), 5, nil, failAt2)
+	assert.ErrorIs(err, failed)
+	assert.Less(len(numbers), 5)
+}
+
+func (s *utilsTestSuite) TestEndpointSet() {
+	assert := assert.New(s.T())
+
+	endpoints := newEndpointSet([]string{"primary", "dr1", "dr2"}, false)
+	assert.Equal("primary", endpoints.endpoint(false))
+	assert.True(endpoints.markDown("primary"))
+	assert.False(endpoints.markDown("primary"))
+	assert.Equal("dr1", endpoints.activeEndpoint())
+	// writes stay on the primary endpoint
+	assert.Equal("primary", endpoints.endpoint(true))
+
+	// a recovered endpoint only takes over from a less preferred one
+	assert.True(endpoints.markDown("dr1"))
+	assert.Equal("dr2", endpoints.activeEndpoint())
+	endpoints.markUp("dr1")
+	assert.Equal("dr1", endpoints.activeEndpoint())
+	endpoints.markUp("primary")
+	assert.Equal("primary", endpoints.activeEndpoint())
+
+	// with every endpoint down, the last one stays active
+	for _, url := range []string{"primary", "dr1", "dr2"} {
+		endpoints.markDown(url)
+	}
+	assert.Equal("dr2", endpoints.activeEndpoint())
+	endpoints.markUp("dr1")
+	assert.Equal("dr1", endpoints.activeEndpoint())
+
+	endpoints = newEndpointSet([]string{"primary", "dr"}, true)
+	endpoints.markDown("primary")
+	assert.Equal("dr", endpoints.endpoint(true))
+}
+
+func (s *utilsTestSuite) TestEndpointFailover() {
+	assert := assert.New(s.T())
+
+	// a primary endpoint that is down, and a DR endpoint answering every request
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	primaryAddr := listener.Addr().String()
+	assert.NoError(listener.Close())
+	primary := "http://" + primaryAddr
+	dr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer dr.Close()
+
+	cl := &Client{}
+	cl.Config.AuthConfig.BucketName = "bucket"
+	cl.Config.healthCheckInterval = 10 * time.Millisecond
+	cl.endpoints = newEndpointSet([]string{primary, dr.URL}, false)
+	cl.AwsS3Client = s3.New(s3.Options{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+		BaseEndpoint: aws.String(primary),
+		UsePathStyle: true,
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) {
+				return 0, nil
+			})
+		}),
+	}, cl.addEndpointFailover)
+
+	// the read is retried on the DR endpoint
+	assert.NoError(cl.ConnectionOkay(context.Background()))
+	assert.Equal(dr.URL, cl.ActiveEndpoint())
+
+	// writes stay on the primary endpoint
+	_, err = cl.AwsS3Client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("file"),
+	})
+	assert.Error(err)
+
+	// the primary endpoint takes over again once it is back
+	listener, err = net.Listen("tcp", primaryAddr)
+	if err != nil {
+		s.T().Skipf("Unable to listen on %s again [%v]", primaryAddr, err)
+	}
+	server := httptest.NewUnstartedServer(dr.Config.Handler)
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+	assert.Eventually(func() bool {
+		return cl.ActiveEndpoint() == primary
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func (s *utilsTestSuite) TestWatchEndpointStops() {
+	assert := assert.New(s.T())
+
+	cl := &Client{}
+	cl.endpoints = newEndpointSet([]string{"primary", "dr"}, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	cl.WatchEndpoints(ctx)
+	assert.True(cl.endpoints.markDown("primary"))
+
+	done := make(chan struct{})
+	go func() {
+		cl.watchEndpoint(cl.endpoints.context(), "primary")
+		close(done)
+	}()
+	cancel()
+	assert.Eventually(func() bool {
+		select {
+		case <-done:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+	// the endpoint is checked again the next time it fails, without failing back to it
+	assert.Equal("dr", cl.ActiveEndpoint())
+	assert.True(cl.endpoints.markDown("primary"))
+}
+
+func (s *utilsTestSuite) TestStatTypes() {
+	assert := assert.New(s.T())
+
+	stats_manager.EnableMetrics()
+	s3 := News3storageComponent().(*S3Storage)
+	s3.stConfig.healthCheckInterval = time.Minute
+	s3.Storage = &Client{}
+	assert.NoError(s3.Start(context.Background()))
+	defer s3.Stop()
+
+	recorder := httptest.NewRecorder()
+	stats_manager.MetricsHandler().ServeHTTP(
+		recorder,
+		httptest.NewRequest(http.MethodGet, "/metrics", nil),
+	)
+	body := recorder.Body.String()
+	for _, name := range []string{"bytes_downloaded", "bytes_uploaded", "create_dir"} {
+		assert.Contains(body, "# TYPE cloudfuse_s3storage_"+name+"_total counter\n")
+	}
+	assert.Contains(body, "# TYPE cloudfuse_s3storage_open_file_handles gauge\n")
+}
+
+func (s *utilsTestSuite) TestRequestType() {
+	assert := assert.New(s.T())
+
+	assert.Equal(listRequests, requestType("ListObjectsV2"))
+	assert.Equal(headRequests, requestType("HeadObject"))
+	assert.Equal(getRequests, requestType("GetObject"))
+	assert.Equal(putRequests, requestType("UploadPart"))
+	assert.Equal(putRequests, requestType("DeleteObjects"))
+}
+
+func (s *utilsTestSuite) TestTokenBucket() {
+	assert := assert.New(s.T())
+
+	// a second's worth of tokens is available at once
+	bucket := newTokenBucket(10)
+	for range 10 {
+		assert.Zero(bucket.reserve(1))
+	}
+	assert.InDelta(100*time.Millisecond, bucket.reserve(1), float64(10*time.Millisecond))
+	// and more than that can be taken, at the cost of waiting for them
+	assert.InDelta(time.Second, bucket.reserve(9), float64(10*time.Millisecond))
+
+	// an unlimited bucket is limited once throttled, below the rate requests were sent at
+	bucket = newTokenBucket(0)
+	for range 20 {
+		assert.Zero(bucket.reserve(1))
+	}
+	assert.Equal(10.0, bucket.throttled())
+	assert.Positive(bucket.reserve(1))
+	// requests in flight are throttled too, without lowering the rate again
+	assert.Zero(bucket.throttled())
+	assert.False(bucket.succeeded())
+
+	// the rate ramps up until it is back to the maximum, the rate it was throttled from here
+	ramps := 0
+	for done := false; !done && ramps < 10; ramps++ {
+		bucket.changed = time.Now().Add(-rampInterval)
+		done = bucket.succeeded()
+	}
+	assert.Equal(4, ramps)
+	assert.Zero(bucket.rate)
+	assert.Zero(bucket.reserve(1000))
+
+	bucket = newTokenBucket(100)
+	assert.Equal(50.0, bucket.throttled())
+	bucket.changed = time.Now().Add(-rampInterval)
+	assert.False(bucket.succeeded())
+	assert.InDelta(60.0, bucket.rate, 0.001)
+
+	// waiting stops when the context is done
+	bucket = newTokenBucket(1)
+	bucket.reserve(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(bucket.wait(ctx, 1), context.Canceled)
+	var unlimited *tokenBucket
+	assert.NoError(unlimited.wait(ctx, 1))
+}
+
+func (s *utilsTestSuite) TestRateLimit() {
+	assert := assert.New(s.T())
+
+	// a service throttling the first request
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("<Error><Code>SlowDown</Code></Error>"))
+		}
+	}))
+	defer server.Close()
+
+	cl := &Client{}
+	cl.AwsS3Client = s3.New(s3.Options{
+		Region:       "us-east-1",
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) {
+				return 0, nil
+			})
+		}),
+	}, cl.addRateLimit)
+
+	// the retry is slowed down by the rate limiter, instead of failing
+	start := time.Now()
+	_, err := cl.AwsS3Client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("file"),
+	})
+	assert.NoError(err)
+	assert.EqualValues(2, requests.Load())
+	assert.GreaterOrEqual(time.Since(start), time.Second/time.Duration(minRequestRate))
+
+	// other types of requests are not slowed down
+	start = time.Now()
+	_, err = cl.AwsS3Client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("file"),
+	})
+	assert.NoError(err)
+	assert.Less(time.Since(start), 500*time.Millisecond)
+}
+
+func (s *utilsTestSuite) TestIsNoSuchUpload() {
+	assert := assert.New(s.T())
+
+	assert.True(isNoSuchUpload(&types.NoSuchUpload{}))
+	assert.True(isNoSuchUpload(&smithy.GenericAPIError{Code: "NoSuchUpload"}))
+	assert.False(isNoSuchUpload(&smithy.GenericAPIError{Code: "NoSuchKey"}))
+	assert.False(isNoSuchUpload(nil))
+}
+
 func TestUtilsTestSuite(t *testing.T) {
 	suite.Run(t, new(utilsTestSuite))
 }
@@ -0,0 +1,328 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package s3storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common/log"
+	"github.com/Seagate/cloudfuse/internal"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// With enable-versions, every directory has a hidden, read-only .versions directory, which holds
+// a directory for each file in it, listing the versions of that file:
+//
+//	<dir>/.versions/<file>/<time>-<version ID>
+//
+// The time is when the version was written, so versions sort from oldest to newest. Deleted
+// files keep their versions, so they are still listed.
+
+const versionsDirName = ".versions"
+
+const versionTimeFormat = "20060102T150405Z"
+
+// versionPath is a path in a .versions tree
+type versionPath struct {
+	dir     string // directory holding the file
+	file    string // empty for the .versions directory
+	version string // empty for the directory listing the versions of file
+}
+
+// parseVersionPath splits name around its .versions element, and reports whether it has one.
+// Paths too deep to exist in a .versions tree return ENOENT.
+func parseVersionPath(name string) (versionPath, bool, error) {
+	var vp versionPath
+	elems := strings.Split(strings.Trim(name, "/"), "/")
+	i := slices.Index(elems, versionsDirName)
+	if i < 0 {
+		return vp, false, nil
+	}
+	vp.dir = strings.Join(elems[:i], "/")
+	switch rest := elems[i+1:]; len(rest) {
+	case 0:
+	case 1:
+		vp.file = rest[0]
+	case 2:
+		vp.file = rest[0]
+		vp.version = rest[1]
+	default:
+		return vp, true, syscall.ENOENT
+	}
+	return vp, true, nil
+}
+
+// path returns the path of vp in the file system
+func (vp versionPath) path() string {
+	return path.Join(vp.dir, versionsDirName, vp.file, vp.version)
+}
+
+// filePath returns the path of the file vp refers to
+func (vp versionPath) filePath() string {
+	return path.Join(vp.dir, vp.file)
+}
+
+func versionName(lastModified time.Time, versionID string) string {
+	return lastModified.UTC().Format(versionTimeFormat) + "-" + versionID
+}
+
+// parseVersionName returns the version ID in a name made by versionName
+func parseVersionName(name string) (string, error) {
+	timestamp, versionID, found := strings.Cut(name, "-")
+	if !found || versionID == "" {
+		return "", syscall.ENOENT
+	}
+	if _, err := time.Parse(versionTimeFormat, timestamp); err != nil {
+		return "", syscall.ENOENT
+	}
+	return versionID, nil
+}
+
+// createVersionDirAttr creates the attributes of a directory in a .versions tree
+func createVersionDirAttr(path string) *internal.ObjAttr {
+	attr := createObjAttrDir(path)
+	attr.Mode = os.ModeDir | 0555
+	attr.Flags.Clear(internal.PropFlagModeDefault)
+	return attr
+}
+
+// createVersionAttr creates the attributes of a version, which cannot be modified
+func createVersionAttr(path string, size int64, lastModified time.Time) *internal.ObjAttr {
+	attr := createObjAttr(path, size, lastModified, false)
+	attr.Mode = 0444
+	attr.Flags.Clear(internal.PropFlagModeDefault)
+	return attr
+}
+
+// resolveVersion returns the file and version ID to read for name.
+// Names outside of a .versions tree are returned as they are, with an empty version ID.
+func (cl *Client) resolveVersion(name string) (string, string, error) {
+	if !cl.Config.enableVersions {
+		return name, "", nil
+	}
+	vp, inTree, err := parseVersionPath(name)
+	if !inTree || err != nil {
+		return name, "", err
+	}
+	if vp.version == "" {
+		return name, "", syscall.EISDIR
+	}
+	versionID, err := parseVersionName(vp.version)
+	return vp.filePath(), versionID, err
+}
+
+// listObjectVersions lists the versions of the keys starting with prefix, up to the next slash.
+// It calls handlePage with each page of results, until it returns false.
+func (cl *Client) listObjectVersions(
+	ctx context.Context,
+	prefix string,
+	handlePage func(output *s3.ListObjectVersionsOutput) bool,
+) error {
+	params := &s3.ListObjectVersionsInput{
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+	return cl.listObjectVersionsWith(ctx, params, handlePage)
+}
+
+// Wrapper for awsS3Client.ListObjectVersions.
+// It calls handlePage with each page of results, until it returns false.
+func (cl *Client) listObjectVersionsWith(
+	ctx context.Context,
+	params *s3.ListObjectVersionsInput,
+	handlePage func(output *s3.ListObjectVersionsOutput) bool,
+) error {
+	prefix := aws.ToString(params.Prefix)
+	log.Trace("Client::listObjectVersionsWith : prefix %s", prefix)
+	params.Bucket = aws.String(cl.Config.AuthConfig.BucketName)
+	for {
+		output, err := cl.AwsS3Client.ListObjectVersions(ctx, params)
+		if err != nil {
+			attemptedAction := fmt.Sprintf("list object versions with prefix %s", prefix)
+			return parseS3Err(err, attemptedAction)
+		}
+		if !handlePage(output) || output.IsTruncated == nil || !*output.IsTruncated {
+			return nil
+		}
+		params.KeyMarker = output.NextKeyMarker
+		params.VersionIdMarker = output.NextVersionIdMarker
+	}
+}
+
+// listVersionTree lists a directory in a .versions tree.
+// The .versions directory lists every file in vp.dir that has a version, including deleted ones,
+// and the directory of a file lists its versions.
+func (cl *Client) listVersionTree(
+	ctx context.Context,
+	vp versionPath,
+) ([]*internal.ObjAttr, error) {
+	if vp.version != "" {
+		return nil, syscall.ENOTDIR
+	}
+	if vp.file != "" {
+		versions, err := cl.listFileVersions(ctx, vp)
+		if err != nil {
+			return nil, err
+		}
+		attrList := make([]*internal.ObjAttr, 0, len(versions))
+		for _, version := range versions {
+			vp.version = versionName(*version.LastModified, *version.VersionId)
+			attr := createVersionAttr(vp.path(), *version.Size, *version.LastModified)
+			attr.ETag = sanitizeETag(version.ETag)
+			attrList = append(attrList, attr)
+		}
+		slices.SortFunc(attrList, func(a, b *internal.ObjAttr) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+		return attrList, nil
+	}
+
+	listPath := cl.getKey(vp.dir, false, false)
+	if listPath != "" {
+		listPath += "/"
+	}
+	var names []string
+	err := cl.listObjectVersions(ctx, listPath, func(output *s3.ListObjectVersionsOutput) bool {
+		for _, version := range output.Versions {
+			// versions of keys in subdirectories are grouped in CommonPrefixes
+			name, isSymLink := cl.getFile(strings.TrimPrefix(*version.Key, listPath))
+			if name != "" && !isSymLink && cl.existedAsOf(version.LastModified) {
+				names = append(names, name)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	// versions of a key are listed together, but may span pages
+	slices.Sort(names)
+	names = slices.Compact(names)
+	attrList := make([]*internal.ObjAttr, 0, len(names))
+	for _, name := range names {
+		vp.file = name
+		attrList = append(attrList, createVersionDirAttr(vp.path()))
+	}
+	return attrList, nil
+}
+
+// listFileVersions lists the versions of the file vp refers to, leaving out delete markers
+func (cl *Client) listFileVersions(
+	ctx context.Context,
+	vp versionPath,
+) ([]types.ObjectVersion, error) {
+	key := cl.getKey(vp.filePath(), false, false)
+	var versions []types.ObjectVersion
+	err := cl.listObjectVersions(ctx, key, func(output *s3.ListObjectVersionsOutput) bool {
+		for _, version := range output.Versions {
+			// keys are listed in order, and the prefix also matches longer keys
+			if *version.Key > key {
+				return false
+			}
+			if *version.Key == key && cl.existedAsOf(version.LastModified) {
+				versions = append(versions, version)
+			}
+		}
+		return true
+	})
+	return versions, err
+}
+
+// getVersionAttr gets the attributes of a path in a .versions tree
+func (cl *Client) getVersionAttr(ctx context.Context, vp versionPath) (*internal.ObjAttr, error) {
+	switch {
+	case vp.file == "":
+		return createVersionDirAttr(vp.path()), nil
+	case vp.version == "":
+		versions, err := cl.listFileVersions(ctx, vp)
+		if err != nil {
+			return nil, err
+		}
+		if len(versions) == 0 {
+			return nil, syscall.ENOENT
+		}
+		return createVersionDirAttr(vp.path()), nil
+	}
+
+	versionID, err := parseVersionName(vp.version)
+	if err != nil {
+		return nil, err
+	}
+	key := cl.getKey(vp.filePath(), false, false)
+	sse, err := cl.sseHeaders()
+	if err != nil {
+		return nil, err
+	}
+	result, err := cl.AwsS3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:               aws.String(cl.Config.AuthConfig.BucketName),
+		Key:                  aws.String(key),
+		VersionId:            aws.String(versionID),
+		SSECustomerAlgorithm: sse.customerAlgorithm,
+		SSECustomerKey:       sse.customerKey,
+		SSECustomerKeyMD5:    sse.customerKeyMD5,
+	})
+	if err != nil {
+		attemptedAction := fmt.Sprintf("HeadObject(%s)", vp.path())
+		return nil, parseS3Err(err, attemptedAction)
+	}
+	// the time in the name must match too
+	if versionName(*result.LastModified, versionID) != vp.version ||
+		!cl.existedAsOf(result.LastModified) {
+		return nil, syscall.ENOENT
+	}
+	attr := createVersionAttr(vp.path(), *result.ContentLength, *result.LastModified)
+	attr.ETag = sanitizeETag(result.ETag)
+	return attr, nil
+}
+
+// checkWritable returns EROFS when a name is in a .versions tree, or the mount is point-in-time,
+// as both are read-only
+func (s3 *S3Storage) checkWritable(names ...string) error {
+	if !s3.stConfig.asOf.IsZero() {
+		log.Err("S3Storage::checkWritable : %v is read-only in a point-in-time mount", names)
+		return syscall.EROFS
+	}
+	if !s3.stConfig.enableVersions {
+		return nil
+	}
+	for _, name := range names {
+		if _, inTree, _ := parseVersionPath(name); inTree {
+			log.Err("S3Storage::checkWritable : %s is read-only", name)
+			return syscall.EROFS
+		}
+	}
+	return nil
+}
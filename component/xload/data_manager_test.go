@@ -27,9 +27,16 @@ package xload
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/config"
 	"github.com/Seagate/cloudfuse/component/loopback"
+	"github.com/Seagate/cloudfuse/internal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -69,13 +76,35 @@ func (suite *dataManagerTestSuite) TestNewRemoteDataManager() {
 }
 
 func (suite *dataManagerTestSuite) TestProcessErrors() {
-	rdm := &remoteDataManager{}
+	path := filepath.Join("/tmp/", "xdm_"+randomString(8))
+	defer os.RemoveAll(path)
 
+	cfg := fmt.Sprintf("loopbackfs:\n  path: %s\n", path)
+	err := config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.assert.NoError(err)
+
+	remote := loopback.NewLoopbackFSComponent()
+	err = remote.Configure(true)
+	suite.assert.NoError(err)
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	statsMgr.Start()
+	defer statsMgr.Stop()
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 1,
+		remote:      remote,
+		statsMgr:    statsMgr,
+	})
+	suite.assert.NoError(err)
+
+	// staging fails as the parent directory does not exist in remote
 	ctx, cancel := context.WithCancel(context.Background())
 	item := &WorkItem{
 		CompName: DATA_MANAGER,
-		Path:     "test",
-		Block:    &Block{},
+		Path:     "dir_missing/test",
+		Block:    &Block{Id: common.GetBlockID(common.BlockIDLength)},
 		Download: false,
 		Ctx:      ctx,
 	}
@@ -92,6 +121,54 @@ func (suite *dataManagerTestSuite) TestProcessErrors() {
 	suite.assert.Equal(0, dataLength)
 }
 
+func (suite *dataManagerTestSuite) TestWriteData() {
+	path := filepath.Join("/tmp/", "xdm_"+randomString(8))
+	defer os.RemoveAll(path)
+
+	cfg := fmt.Sprintf("loopbackfs:\n  path: %s\n", path)
+	err := config.ReadConfigFromReader(strings.NewReader(cfg))
+	suite.assert.NoError(err)
+
+	remote := loopback.NewLoopbackFSComponent()
+	err = remote.Configure(true)
+	suite.assert.NoError(err)
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	statsMgr.Start()
+	defer statsMgr.Stop()
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 1,
+		remote:      remote,
+		statsMgr:    statsMgr,
+	})
+	suite.assert.NoError(err)
+
+	data := []byte(randomString(20))
+	block := &Block{Id: common.GetBlockID(common.BlockIDLength), Data: data, Length: 10}
+	dataLength, err := rdm.Process(&WorkItem{
+		CompName: DATA_MANAGER,
+		Path:     "test",
+		Block:    block,
+		Download: false,
+		Ctx:      context.Background(),
+	})
+	suite.assert.NoError(err)
+	suite.assert.Equal(10, dataLength)
+
+	err = remote.CommitData(internal.CommitDataOptions{
+		Name:      "test",
+		List:      []string{block.Id},
+		BlockSize: 10,
+	})
+	suite.assert.NoError(err)
+
+	content, err := os.ReadFile(filepath.Join(path, "test"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(data[:10], content)
+}
+
 func TestDatamanagerSuite(t *testing.T) {
 	suite.Run(t, new(dataManagerTestSuite))
 }
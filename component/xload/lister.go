@@ -26,12 +26,19 @@
 package xload
 
 import (
+	"bytes"
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/Seagate/cloudfuse/common"
 	"github.com/Seagate/cloudfuse/common/config"
 	"github.com/Seagate/cloudfuse/common/log"
 	"github.com/Seagate/cloudfuse/internal"
@@ -40,9 +47,13 @@ import (
 // verify that the below types implement the xcomponent interfaces
 var _ XComponent = &lister{}
 var _ XComponent = &remoteLister{}
+var _ XComponent = &localLister{}
+var _ XComponent = &syncLister{}
 
 // verify that the below types implement the xenumerator interfaces
 var _ enumerator = &remoteLister{}
+var _ enumerator = &localLister{}
+var _ enumerator = &syncLister{}
 
 type lister struct {
 	XBase
@@ -129,27 +140,36 @@ func waitForListTimeout() error {
 	return nil
 }
 
-func (rl *remoteLister) Process(item *WorkItem) (int, error) {
-	relPath := item.Path // TODO:: xload : check this for subdirectory mounting
-
-	log.Debug("remoteLister::Process : Reading remote dir %s", relPath)
-
+// waitForFirstList blocks the first list call as per block-list-on-mount-sec
+func (rl *remoteLister) waitForFirstList() error {
 	// this block will be executed only in the first list call for the remote directory
 	// so haven't made the listBlocked variable atomic
 	if !rl.listBlocked {
 		log.Debug(
-			"remoteLister::Process : Waiting for block-list-on-mount-sec before making the list call",
+			"remoteLister::waitForFirstList : Waiting for block-list-on-mount-sec before making the list call",
 		)
 		err := waitForListTimeout()
 		if err != nil {
 			log.Err(
-				"remoteLister::Process : unable to unmarshal block-list-on-mount-sec [%s]",
+				"remoteLister::waitForFirstList : unable to unmarshal block-list-on-mount-sec [%s]",
 				err.Error(),
 			)
-			return 0, err
+			return err
 		}
 		rl.listBlocked = true
 	}
+	return nil
+}
+
+func (rl *remoteLister) Process(item *WorkItem) (int, error) {
+	relPath := item.Path // TODO:: xload : check this for subdirectory mounting
+
+	log.Debug("remoteLister::Process : Reading remote dir %s", relPath)
+
+	err := rl.waitForFirstList()
+	if err != nil {
+		return 0, err
+	}
 
 	marker := ""
 	var cnt, iteration int
@@ -267,3 +287,576 @@ func (rl *remoteLister) mkdir(name string) error {
 	})
 	return err
 }
+
+// --------------------------------------------------------------------------------------------------------
+
+type localLister struct {
+	lister
+}
+
+type localListerOptions struct {
+	path              string
+	workerCount       uint32
+	defaultPermission os.FileMode
+	remote            internal.Component
+	statsMgr          *StatsManager
+}
+
+func newLocalLister(opts *localListerOptions) (*localLister, error) {
+	if opts == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil ||
+		opts.workerCount == 0 {
+		log.Err("lister::NewLocalLister : invalid parameters sent to create local lister")
+		return nil, fmt.Errorf("invalid parameters sent to create local lister")
+	}
+
+	log.Debug(
+		"lister::NewLocalLister : create new local lister for %s, default permission %v, workers %v",
+		opts.path,
+		opts.defaultPermission,
+		opts.workerCount,
+	)
+
+	ll := &localLister{
+		path:              opts.path,
+		defaultPermission: opts.defaultPermission,
+	}
+
+	ll.SetName(LISTER)
+	ll.SetWorkerCount(opts.workerCount)
+	ll.SetRemote(opts.remote)
+	ll.SetStatsManager(opts.statsMgr)
+	ll.Init()
+	return ll, nil
+}
+
+func (ll *localLister) Init() {
+	ll.SetThreadPool(NewThreadPool(ll.GetWorkerCount(), ll.Process))
+	if ll.GetThreadPool() == nil {
+		log.Err("localLister::Init : fail to init thread pool")
+	}
+}
+
+func (ll *localLister) Start(ctx context.Context) {
+	log.Debug("localLister::Start : start local lister for %s", ll.path)
+	ll.GetThreadPool().Start(ctx)
+	_ = ll.Schedule(&WorkItem{CompName: ll.GetName()})
+}
+
+func (ll *localLister) Stop() {
+	log.Debug("localLister::Stop : stop local lister for %s", ll.path)
+	if ll.GetThreadPool() != nil {
+		ll.GetThreadPool().Stop()
+	}
+	log.Debug("localLister::Stop : stop successful")
+}
+
+func (ll *localLister) Process(item *WorkItem) (int, error) {
+	relPath := item.Path
+
+	log.Debug("localLister::Process : Reading local dir %s", relPath)
+
+	entries, err := os.ReadDir(filepath.Join(ll.path, relPath))
+	if err != nil {
+		log.Err("localLister::Process : Local listing failed for %s [%s]", relPath, err.Error())
+		return 0, err
+	}
+
+	// only directories and regular files are uploaded, the rest are not counted as listed
+	var cnt int
+	for _, entry := range entries {
+		if entry.IsDir() || entry.Type().IsRegular() {
+			cnt++
+		} else {
+			log.Warn(
+				"localLister::Process : Skipping %s as it is not a regular file",
+				filepath.Join(relPath, entry.Name()),
+			)
+		}
+	}
+
+	// send number of items listed to stats manager
+	ll.GetStatsManager().AddStats(&StatsItem{
+		Component:   LISTER,
+		Name:        relPath,
+		ListerCount: uint64(cnt),
+	})
+
+	for _, entry := range entries {
+		name := filepath.ToSlash(filepath.Join(relPath, entry.Name()))
+		log.Debug(
+			"localLister::Process : Iterating: %s, Is directory: %v",
+			name,
+			entry.IsDir(),
+		)
+
+		if entry.IsDir() {
+			// create the directory in remote and then add it to
+			// the input channel of the listing component
+			go func(name string) {
+				err := ll.mkdir(name)
+				if err != nil {
+					log.Err(
+						"localLister::Process : Failed to create directory %s [%s]",
+						name,
+						err.Error(),
+					)
+					return
+				}
+
+				// push the directory to input pool for its listing
+				err = ll.Schedule(&WorkItem{
+					CompName: ll.GetName(),
+					Path:     name,
+				})
+				if err != nil {
+					log.Err(
+						"localLister::Process : Failed to schedule directory listing for %s [%s]",
+						name,
+						err.Error(),
+					)
+				}
+			}(name)
+		} else if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				log.Err(
+					"localLister::Process : Failed to get info of %s [%s]",
+					name,
+					err.Error(),
+				)
+				ll.GetStatsManager().AddStats(&StatsItem{
+					Component: SPLITTER,
+					Name:      name,
+					Success:   false,
+				})
+				continue
+			}
+
+			// send file to the splitter's channel for chunking
+			err = ll.GetNext().Schedule(&WorkItem{
+				CompName: ll.GetNext().GetName(),
+				Path:     name,
+				DataLen:  uint64(info.Size()),
+				Mode:     info.Mode().Perm(),
+				Atime:    info.ModTime(),
+				Mtime:    info.ModTime(),
+			})
+			if err != nil {
+				log.Err(
+					"localLister::Process : Failed to schedule file %s for processing [%s]",
+					name,
+					err.Error(),
+				)
+				return 0, err
+			}
+		}
+	}
+
+	return cnt, nil
+}
+
+// mkdir creates the directory in remote, an already existing directory is not an error
+func (ll *localLister) mkdir(name string) error {
+	log.Debug("localLister::mkdir : Creating remote path: %s, mode %v", name, ll.defaultPermission)
+	return mkdirRemote(&ll.XBase, name, ll.defaultPermission)
+}
+
+// mkdirRemote creates the directory in remote and sends the stats for it
+func mkdirRemote(xb *XBase, name string, perm os.FileMode) error {
+	err := xb.GetRemote().CreateDir(internal.CreateDirOptions{
+		Name: name,
+		Mode: perm,
+	})
+	if errors.Is(err, os.ErrExist) {
+		err = nil
+	}
+
+	// send stats for dir creation
+	xb.GetStatsManager().AddStats(&StatsItem{
+		Component: LISTER,
+		Name:      name,
+		Dir:       true,
+		Success:   err == nil,
+		Download:  false,
+	})
+	return err
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// syncLister lists the local and remote directories side by side and decides
+// in which direction each file has to be transferred
+type syncLister struct {
+	remoteLister
+	validateMD5 bool
+}
+
+type syncListerOptions struct {
+	path              string
+	workerCount       uint32
+	defaultPermission os.FileMode
+	remote            internal.Component
+	statsMgr          *StatsManager
+	validateMD5       bool
+}
+
+// syncAction is the outcome of comparing a local file with its remote copy
+type syncAction int
+
+const (
+	syncSkip syncAction = iota
+	syncDownload
+	syncUpload
+	syncConflict
+)
+
+func newSyncLister(opts *syncListerOptions) (*syncLister, error) {
+	if opts == nil || opts.path == "" || opts.remote == nil || opts.statsMgr == nil ||
+		opts.workerCount == 0 {
+		log.Err("lister::NewSyncLister : invalid parameters sent to create sync lister")
+		return nil, fmt.Errorf("invalid parameters sent to create sync lister")
+	}
+
+	log.Debug(
+		"lister::NewSyncLister : create new sync lister for %s, default permission %v, workers %v, validate md5 %v",
+		opts.path,
+		opts.defaultPermission,
+		opts.workerCount,
+		opts.validateMD5,
+	)
+
+	sl := &syncLister{
+		path:              opts.path,
+		defaultPermission: opts.defaultPermission,
+		validateMD5:       opts.validateMD5,
+	}
+
+	sl.SetName(LISTER)
+	sl.SetWorkerCount(opts.workerCount)
+	sl.SetRemote(opts.remote)
+	sl.SetStatsManager(opts.statsMgr)
+	sl.Init()
+	return sl, nil
+}
+
+func (sl *syncLister) Init() {
+	sl.SetThreadPool(NewThreadPool(sl.GetWorkerCount(), sl.Process))
+	if sl.GetThreadPool() == nil {
+		log.Err("syncLister::Init : fail to init thread pool")
+	}
+}
+
+func (sl *syncLister) Start(ctx context.Context) {
+	log.Debug("syncLister::Start : start sync lister for %s", sl.path)
+	sl.GetThreadPool().Start(ctx)
+	_ = sl.Schedule(&WorkItem{CompName: sl.GetName()})
+}
+
+func (sl *syncLister) Stop() {
+	log.Debug("syncLister::Stop : stop sync lister for %s", sl.path)
+	if sl.GetThreadPool() != nil {
+		sl.GetThreadPool().Stop()
+	}
+	log.Debug("syncLister::Stop : stop successful")
+}
+
+func (sl *syncLister) Process(item *WorkItem) (int, error) {
+	relPath := item.Path
+
+	log.Debug("syncLister::Process : Reconciling dir %s", relPath)
+
+	err := sl.waitForFirstList()
+	if err != nil {
+		return 0, err
+	}
+
+	// list the remote directory completely before comparing it with the local one
+	remoteEntries := make(map[string]*internal.ObjAttr)
+	marker := ""
+	for {
+		entries, newMarker, err := sl.GetRemote().StreamDir(internal.StreamDirOptions{
+			Name:  relPath,
+			Token: marker,
+		})
+		if err != nil {
+			log.Err(
+				"syncLister::Process : Remote listing failed for %s [%s]",
+				relPath,
+				err.Error(),
+			)
+			return 0, err
+		}
+
+		for _, entry := range entries {
+			remoteEntries[entry.Name] = entry
+		}
+
+		marker = newMarker
+		if len(newMarker) == 0 {
+			break
+		}
+	}
+
+	localEntries := make(map[string]fs.FileInfo)
+	dirEntries, err := os.ReadDir(filepath.Join(sl.path, relPath))
+	if err != nil && !os.IsNotExist(err) {
+		log.Err("syncLister::Process : Local listing failed for %s [%s]", relPath, err.Error())
+		return 0, err
+	}
+
+	for _, entry := range dirEntries {
+		if !entry.IsDir() && !entry.Type().IsRegular() {
+			log.Warn(
+				"syncLister::Process : Skipping %s as it is not a regular file",
+				path.Join(relPath, entry.Name()),
+			)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			log.Err(
+				"syncLister::Process : Failed to get info of %s [%s]",
+				path.Join(relPath, entry.Name()),
+				err.Error(),
+			)
+			continue
+		}
+		localEntries[entry.Name()] = info
+	}
+
+	// every name present on either side is counted once
+	cnt := len(remoteEntries)
+	for name := range localEntries {
+		if _, ok := remoteEntries[name]; !ok {
+			cnt++
+		}
+	}
+
+	sl.GetStatsManager().AddStats(&StatsItem{
+		Component:   LISTER,
+		Name:        relPath,
+		ListerCount: uint64(cnt),
+	})
+
+	for name, remoteAttr := range remoteEntries {
+		err = sl.reconcile(path.Join(relPath, name), localEntries[name], remoteAttr)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for name, localInfo := range localEntries {
+		if _, ok := remoteEntries[name]; !ok {
+			err = sl.reconcile(path.Join(relPath, name), localInfo, nil)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return cnt, nil
+}
+
+// reconcile decides what has to be done for a name based on its local and remote state
+func (sl *syncLister) reconcile(
+	name string,
+	localInfo fs.FileInfo,
+	remoteAttr *internal.ObjAttr,
+) error {
+	switch {
+	case localInfo != nil && remoteAttr != nil && localInfo.IsDir() != remoteAttr.IsDir():
+		sl.reportConflict(name, "it is a directory on one side and a file on the other")
+		return nil
+
+	case remoteAttr != nil && remoteAttr.IsDir():
+		if localInfo != nil {
+			// directory is present on both sides, only its contents need to be reconciled
+			sl.GetStatsManager().AddStats(&StatsItem{
+				Component: LISTER,
+				Name:      name,
+				Dir:       true,
+				Success:   true,
+			})
+			return sl.scheduleListing(name)
+		}
+
+		go func() {
+			err := sl.mkdir(filepath.Join(sl.path, name))
+			if err != nil {
+				log.Err(
+					"syncLister::reconcile : Failed to create local directory %s [%s]",
+					name,
+					err.Error(),
+				)
+				return
+			}
+			_ = sl.scheduleListing(name)
+		}()
+		return nil
+
+	case localInfo != nil && localInfo.IsDir():
+		go func() {
+			err := mkdirRemote(&sl.XBase, name, sl.defaultPermission)
+			if err != nil {
+				log.Err(
+					"syncLister::reconcile : Failed to create remote directory %s [%s]",
+					name,
+					err.Error(),
+				)
+				return
+			}
+			_ = sl.scheduleListing(name)
+		}()
+		return nil
+	}
+
+	action := syncDownload
+	if remoteAttr == nil {
+		action = syncUpload
+	} else if localInfo != nil {
+		action = sl.compare(name, localInfo, remoteAttr)
+	}
+
+	switch action {
+	case syncSkip:
+		log.Debug("syncLister::reconcile : %s is in sync", name)
+		sl.GetStatsManager().AddStats(&StatsItem{
+			Component: SPLITTER,
+			Name:      name,
+			Success:   true,
+		})
+		return nil
+
+	case syncConflict:
+		sl.reportConflict(name, "it has the same modified time but different contents")
+		return nil
+
+	case syncDownload:
+		fileMode := sl.defaultPermission
+		if !remoteAttr.IsModeDefault() {
+			fileMode = remoteAttr.Mode
+		}
+
+		return sl.scheduleFile(&WorkItem{
+			Path:     name,
+			DataLen:  uint64(remoteAttr.Size),
+			Mode:     fileMode,
+			Atime:    remoteAttr.Atime,
+			Mtime:    remoteAttr.Mtime,
+			MD5:      remoteAttr.MD5,
+			Download: true,
+		})
+
+	default:
+		return sl.scheduleFile(&WorkItem{
+			Path:     name,
+			DataLen:  uint64(localInfo.Size()),
+			Mode:     localInfo.Mode().Perm(),
+			Atime:    localInfo.ModTime(),
+			Mtime:    localInfo.ModTime(),
+			Download: false,
+		})
+	}
+}
+
+// compare finds out which side of a file present on both sides is newer.
+// Remote modified times are only accurate to the second, so that is the granularity used here.
+func (sl *syncLister) compare(
+	name string,
+	localInfo fs.FileInfo,
+	remoteAttr *internal.ObjAttr,
+) syncAction {
+	localTime := localInfo.ModTime().Truncate(time.Second)
+	remoteTime := remoteAttr.Mtime.Truncate(time.Second)
+
+	switch {
+	case remoteTime.After(localTime):
+		return syncDownload
+	case localTime.After(remoteTime):
+		return syncUpload
+	case localInfo.Size() != remoteAttr.Size:
+		return syncConflict
+	}
+
+	if !sl.validateMD5 {
+		return syncSkip
+	}
+
+	remoteMD5 := getRemoteMD5(remoteAttr)
+	if remoteMD5 == nil {
+		log.Warn("syncLister::compare : Unable to get MD5Sum for blob %s", name)
+		return syncSkip
+	}
+
+	fh, err := os.Open(filepath.Join(sl.path, name))
+	if err != nil {
+		log.Err("syncLister::compare : Failed to open %s [%s]", name, err.Error())
+		return syncConflict
+	}
+	defer fh.Close()
+
+	localMD5, err := common.GetMD5(fh)
+	if err != nil {
+		log.Err("syncLister::compare : Failed to generate MD5Sum for %s [%s]", name, err.Error())
+		return syncConflict
+	}
+
+	if !bytes.Equal(localMD5, remoteMD5) {
+		return syncConflict
+	}
+	return syncSkip
+}
+
+// getRemoteMD5 returns the MD5 of the blob, falling back to the ETag which holds the MD5
+// for objects that were not uploaded in parts
+func getRemoteMD5(attr *internal.ObjAttr) []byte {
+	if attr.MD5 != nil {
+		return attr.MD5
+	}
+
+	md5, err := hex.DecodeString(strings.Trim(attr.ETag, "\""))
+	if err != nil || len(md5) != 16 {
+		return nil
+	}
+	return md5
+}
+
+func (sl *syncLister) reportConflict(name string, reason string) {
+	log.Warn("syncLister::reportConflict : Conflict on %s, %s", name, reason)
+	sl.GetStatsManager().AddStats(&StatsItem{
+		Component: SPLITTER,
+		Name:      name,
+		Conflict:  true,
+	})
+}
+
+func (sl *syncLister) scheduleListing(name string) error {
+	err := sl.Schedule(&WorkItem{
+		CompName: sl.GetName(),
+		Path:     name,
+	})
+	if err != nil {
+		log.Err(
+			"syncLister::scheduleListing : Failed to schedule directory listing for %s [%s]",
+			name,
+			err.Error(),
+		)
+	}
+	return err
+}
+
+func (sl *syncLister) scheduleFile(item *WorkItem) error {
+	item.CompName = sl.GetNext().GetName()
+
+	// send file to the splitter's channel for chunking
+	err := sl.GetNext().Schedule(item)
+	if err != nil {
+		log.Err(
+			"syncLister::scheduleFile : Failed to schedule file %s for processing [%s]",
+			item.Path,
+			err.Error(),
+		)
+	}
+	return err
+}
@@ -27,6 +27,8 @@ package xload
 
 import (
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"fmt"
 	"math/rand"
 	"os"
@@ -286,6 +288,210 @@ func (suite *listTestSuite) TestListerMkdir() {
 	suite.assert.Len(entries, 5)
 }
 
+func (suite *listTestSuite) TestNewLocalLister() {
+	ll, err := newLocalLister(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(ll)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+
+	ll, err = newLocalLister(&localListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          nil,
+	})
+	suite.assert.Error(err)
+	suite.assert.Nil(ll)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	ll, err = newLocalLister(&localListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          statsMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+}
+
+func (suite *listTestSuite) TestLocalListerStartStop() {
+	tl, err := setupTestLister()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	// the remote already has the same directories, which must not be an error
+	suite.createDirsAndFiles(tl.path)
+
+	ll, err := newLocalLister(&localListerOptions{
+		path:              tl.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+
+	testComp := getTestcomponent()
+	ll.SetNext(testComp)
+
+	ll.Start(context.TODO())
+	time.Sleep(5 * time.Second)
+	ll.Stop()
+
+	suite.assert.Equal(int64(60), testComp.ctr.Load())
+}
+
+func (suite *listTestSuite) TestLocalListerMkdir() {
+	tl, err := setupTestLister()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	ll, err := newLocalLister(&localListerOptions{
+		path:              tl.path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+
+	for i := range 5 {
+		dirName := fmt.Sprintf("ldir%v", i)
+		err = ll.mkdir(dirName)
+		suite.assert.NoError(err)
+
+		// creating it again should not fail
+		err = ll.mkdir(dirName)
+		suite.assert.NoError(err)
+
+		info, err := os.Stat(filepath.Join(lb_path, dirName))
+		suite.assert.NoError(err)
+		suite.assert.True(info.IsDir())
+
+		err = os.Remove(filepath.Join(lb_path, dirName))
+		suite.assert.NoError(err)
+	}
+}
+
+func (suite *listTestSuite) TestNewSyncLister() {
+	sl, err := newSyncLister(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(sl)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync lister")
+
+	sl, err = newSyncLister(&syncListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       0,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+	})
+	suite.assert.Error(err)
+	suite.assert.Nil(sl)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync lister")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	sl, err = newSyncLister(&syncListerOptions{
+		path:              "home/user/random_path",
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          statsMgr,
+		validateMD5:       true,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(sl)
+	suite.assert.True(sl.validateMD5)
+}
+
+func (suite *listTestSuite) TestSyncListerCompare() {
+	tl, err := setupTestLister()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(tl)
+
+	defer func() {
+		err = tl.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	sl, err := newSyncLister(&syncListerOptions{
+		path:              tl.path,
+		workerCount:       1,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            lb,
+		statsMgr:          tl.stMgr,
+	})
+	suite.assert.NoError(err)
+
+	data := []byte("some data")
+	err = os.WriteFile(filepath.Join(tl.path, "file"), data, 0644)
+	suite.assert.NoError(err)
+	localInfo, err := os.Stat(filepath.Join(tl.path, "file"))
+	suite.assert.NoError(err)
+
+	mtime := localInfo.ModTime()
+	remoteAttr := &internal.ObjAttr{Size: int64(len(data)), Mtime: mtime}
+	suite.assert.Equal(syncSkip, sl.compare("file", localInfo, remoteAttr))
+
+	remoteAttr.Mtime = mtime.Add(time.Minute)
+	suite.assert.Equal(syncDownload, sl.compare("file", localInfo, remoteAttr))
+
+	remoteAttr.Mtime = mtime.Add(-time.Minute)
+	suite.assert.Equal(syncUpload, sl.compare("file", localInfo, remoteAttr))
+
+	remoteAttr.Mtime = mtime
+	remoteAttr.Size = 1
+	suite.assert.Equal(syncConflict, sl.compare("file", localInfo, remoteAttr))
+
+	// same size and time but different contents is only caught with md5 validation
+	remoteAttr.Size = int64(len(data))
+	remoteAttr.ETag = "\"" + hex.EncodeToString(make([]byte, 16)) + "\""
+	suite.assert.Equal(syncSkip, sl.compare("file", localInfo, remoteAttr))
+
+	sl.validateMD5 = true
+	suite.assert.Equal(syncConflict, sl.compare("file", localInfo, remoteAttr))
+
+	localMD5 := md5.Sum(data)
+	remoteAttr.MD5 = localMD5[:]
+	suite.assert.Equal(syncSkip, sl.compare("file", localInfo, remoteAttr))
+}
+
+func (suite *listTestSuite) TestGetRemoteMD5() {
+	suite.assert.Nil(getRemoteMD5(&internal.ObjAttr{}))
+	suite.assert.Nil(getRemoteMD5(&internal.ObjAttr{ETag: "\"0x8D9A2B3C4D5E6F7\""}))
+	suite.assert.Nil(
+		getRemoteMD5(&internal.ObjAttr{ETag: "\"d41d8cd98f00b204e9800998ecf8427e-2\""}),
+	)
+
+	md5, err := hex.DecodeString("d41d8cd98f00b204e9800998ecf8427e")
+	suite.assert.NoError(err)
+	suite.assert.Equal(
+		md5,
+		getRemoteMD5(&internal.ObjAttr{ETag: "\"d41d8cd98f00b204e9800998ecf8427e\""}),
+	)
+	suite.assert.Equal([]byte{1, 2}, getRemoteMD5(&internal.ObjAttr{MD5: []byte{1, 2}}))
+}
+
 func TestListSuite(t *testing.T) {
 	suite.Run(t, new(listTestSuite))
 }
@@ -42,6 +42,8 @@ import (
 // verify that the below types implement the xcomponent interfaces
 var _ XComponent = &splitter{}
 var _ XComponent = &downloadSplitter{}
+var _ XComponent = &uploadSplitter{}
+var _ XComponent = &syncSplitter{}
 
 type splitter struct {
 	XBase
@@ -148,7 +150,7 @@ func (ds *downloadSplitter) Process(item *WorkItem) (int, error) {
 		if isDir {
 			log.Err("downloadSplitter::Process : %s is a directory", item.Path)
 			return -1, fmt.Errorf("%s is a directory", item.Path)
-		} else if item.DataLen == uint64(size) {
+		} else if item.DataLen == uint64(size) && !isLocalFileStale(localPath, item.Mtime) {
 			log.Debug(
 				"downloadSplitter::Process : %s will be served from local path, priority %v",
 				item.Path,
@@ -390,3 +392,392 @@ func (ds *downloadSplitter) checkConsistency(item *WorkItem) error {
 
 	return nil
 }
+
+// --------------------------------------------------------------------------------------------------------
+
+type uploadSplitter struct {
+	splitter
+}
+
+type uploadSplitterOptions struct {
+	blockPool   *BlockPool
+	path        string
+	workerCount uint32
+	remote      internal.Component
+	statsMgr    *StatsManager
+	fileLocks   *common.LockMap
+}
+
+func newUploadSplitter(opts *uploadSplitterOptions) (*uploadSplitter, error) {
+	if opts == nil || opts.blockPool == nil || opts.path == "" || opts.remote == nil ||
+		opts.statsMgr == nil ||
+		opts.fileLocks == nil ||
+		opts.workerCount == 0 {
+		log.Err("splitter::NewUploadSplitter : invalid parameters sent to create upload splitter")
+		return nil, fmt.Errorf("invalid parameters sent to create upload splitter")
+	}
+
+	log.Debug(
+		"splitter::NewUploadSplitter : create new upload splitter for %s, block size %v, workers %v",
+		opts.path,
+		opts.blockPool.GetBlockSize(),
+		opts.workerCount,
+	)
+
+	us := &uploadSplitter{
+		blockPool: opts.blockPool,
+		path:      opts.path,
+		fileLocks: opts.fileLocks,
+	}
+
+	us.SetName(SPLITTER)
+	us.SetWorkerCount(opts.workerCount)
+	us.SetRemote(opts.remote)
+	us.SetStatsManager(opts.statsMgr)
+	us.Init()
+	return us, nil
+}
+
+func (us *uploadSplitter) Init() {
+	us.SetThreadPool(NewThreadPool(us.GetWorkerCount(), us.Process))
+	if us.GetThreadPool() == nil {
+		log.Err("uploadSplitter::Init : fail to init thread pool")
+	}
+}
+
+func (us *uploadSplitter) Start(ctx context.Context) {
+	log.Debug("uploadSplitter::Start : start upload splitter for %s", us.path)
+	us.GetThreadPool().Start(ctx)
+}
+
+func (us *uploadSplitter) Stop() {
+	log.Debug("uploadSplitter::Stop : stop upload splitter for %s", us.path)
+	if us.GetThreadPool() != nil {
+		us.GetThreadPool().Stop()
+	}
+	log.Debug("uploadSplitter::Stop : stop successful")
+}
+
+// read the local file in chunks, upload them and then commit the block list
+func (us *uploadSplitter) Process(item *WorkItem) (int, error) {
+	log.Debug(
+		"uploadSplitter::Process : Splitting data for %s, size %v, mode %v, modified time %v",
+		item.Path,
+		item.DataLen,
+		item.Mode,
+		item.Mtime.Format(time.DateTime),
+	)
+
+	var err error
+	localPath := filepath.Join(us.path, item.Path)
+
+	flock := us.fileLocks.Get(item.Path)
+	flock.Lock()
+	defer flock.Unlock()
+
+	// skip the file if remote already has the same size and is not older than the local copy
+	attr, err := us.GetRemote().GetAttr(internal.GetAttrOptions{Name: item.Path})
+	if err == nil && !attr.IsDir() && attr.Size == int64(item.DataLen) &&
+		!attr.Mtime.Before(item.Mtime) {
+		log.Debug("uploadSplitter::Process : %s is already present in remote", item.Path)
+		us.GetStatsManager().AddStats(&StatsItem{
+			Component: SPLITTER,
+			Name:      item.Path,
+			Success:   true,
+			Download:  false,
+		})
+		return int(item.DataLen), nil
+	}
+
+	item.FileHandle, err = os.Open(localPath)
+	if err != nil {
+		log.Err("uploadSplitter::Process : Failed to open file %s [%s]", item.Path, err.Error())
+		us.GetStatsManager().AddStats(&StatsItem{
+			Component: SPLITTER,
+			Name:      item.Path,
+			Success:   false,
+			Download:  false,
+		})
+		return -1, fmt.Errorf("failed to open file %s [%s]", item.Path, err.Error())
+	}
+	defer func() {
+		if err := item.FileHandle.Close(); err != nil {
+			log.Err("uploadSplitter::Process : error closing file %s [%v]", item.Path, err)
+		}
+	}()
+
+	numBlocks := uint64(0)
+	if item.DataLen > 0 {
+		numBlocks = ((item.DataLen - 1) / us.blockPool.GetBlockSize()) + 1
+	}
+	blockList := make([]string, numBlocks)
+	offset := int64(0)
+
+	wg := sync.WaitGroup{}
+
+	responseChannel := make(chan *WorkItem, numBlocks)
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	operationSuccess := true
+	wg.Go(func() {
+		for i := 0; i < int(numBlocks); i++ {
+			select {
+			case <-us.GetThreadPool().ctx.Done(): // check if the thread pool is closed
+				operationSuccess = false
+				cancel()
+				return
+			case respSplitItem := <-responseChannel:
+				if respSplitItem.Err != nil {
+					log.Err(
+						"uploadSplitter::Process : Failed to upload data for file %s",
+						item.Path,
+					)
+					operationSuccess = false
+					cancel() // cancel the context to stop upload of other chunks
+				}
+
+				if respSplitItem.Block != nil {
+					us.blockPool.Release(respSplitItem.Block)
+				}
+			}
+		}
+	})
+
+	for i := 0; i < int(numBlocks); i++ {
+		block := us.blockPool.GetBlock(false)
+		if block == nil {
+			responseChannel <- &WorkItem{Err: fmt.Errorf("failed to get block from pool for file %s, offset %v", item.Path, offset)}
+			offset += int64(us.blockPool.GetBlockSize())
+			continue
+		}
+
+		block.Index = i
+		block.Offset = offset
+		block.Length = int64(min(us.blockPool.GetBlockSize(), item.DataLen-uint64(offset)))
+		block.Id = common.GetBlockID(common.BlockIDLength)
+		blockList[i] = block.Id
+
+		n, err := item.FileHandle.ReadAt(block.Data[:block.Length], block.Offset)
+		if int64(n) != block.Length {
+			log.Err(
+				"uploadSplitter::Process : Failed to read data from file %s offset %v [%v]",
+				item.Path,
+				offset,
+				err,
+			)
+			responseChannel <- &WorkItem{Block: block, Err: fmt.Errorf("failed to read file %s at offset %v", item.Path, offset)}
+			offset += int64(us.blockPool.GetBlockSize())
+			continue
+		}
+
+		// send the disk read status to stats manager
+		us.GetStatsManager().AddStats(&StatsItem{
+			Component:        SPLITTER,
+			Name:             item.Path,
+			Success:          false,
+			Download:         false,
+			DiskIO:           true,
+			BytesTransferred: uint64(n),
+		})
+
+		splitItem := &WorkItem{
+			CompName:        us.GetNext().GetName(),
+			Path:            item.Path,
+			DataLen:         item.DataLen,
+			FileHandle:      item.FileHandle,
+			Block:           block,
+			ResponseChannel: responseChannel,
+			Download:        false,
+			Ctx:             ctx,
+		}
+		err = us.GetNext().Schedule(splitItem)
+		if err != nil {
+			log.Err(
+				"uploadSplitter::Process : Failed to schedule upload for %s [%s]",
+				item.Path,
+				err.Error(),
+			)
+			responseChannel <- &WorkItem{Block: block, Err: fmt.Errorf("failed to schedule upload for %s [%s]", item.Path, err.Error())}
+		}
+
+		offset += int64(us.blockPool.GetBlockSize())
+	}
+
+	wg.Wait()
+
+	if operationSuccess {
+		// commit the staged blocks, an empty list creates a 0 byte file
+		err = us.GetRemote().CommitData(internal.CommitDataOptions{
+			Name:      item.Path,
+			List:      blockList,
+			BlockSize: us.blockPool.GetBlockSize(),
+		})
+		if err != nil {
+			log.Err(
+				"uploadSplitter::Process : Failed to commit data for file %s [%s]",
+				item.Path,
+				err.Error(),
+			)
+			operationSuccess = false
+		}
+	}
+
+	// send the upload status to stats manager
+	us.GetStatsManager().AddStats(&StatsItem{
+		Component: SPLITTER,
+		Name:      item.Path,
+		Success:   operationSuccess,
+		Download:  false,
+	})
+
+	if !operationSuccess {
+		log.Err("uploadSplitter::Process : Failed to upload data for file %s", item.Path)
+		return -1, fmt.Errorf("failed to upload data for file %s", item.Path)
+	}
+
+	log.Debug("uploadSplitter::Process : Upload completed for file %s", item.Path)
+	return 0, nil
+}
+
+// --------------------------------------------------------------------------------------------------------
+
+// syncSplitter hands each file over to the download or upload splitter depending on
+// the direction decided by the sync lister
+type syncSplitter struct {
+	splitter
+	ds *downloadSplitter
+	us *uploadSplitter
+}
+
+type syncSplitterOptions struct {
+	blockPool   *BlockPool
+	path        string
+	workerCount uint32
+	remote      internal.Component
+	statsMgr    *StatsManager
+	fileLocks   *common.LockMap
+	validateMD5 bool
+}
+
+func newSyncSplitter(opts *syncSplitterOptions) (*syncSplitter, error) {
+	if opts == nil || opts.blockPool == nil || opts.path == "" || opts.remote == nil ||
+		opts.statsMgr == nil ||
+		opts.fileLocks == nil ||
+		opts.workerCount == 0 {
+		log.Err("splitter::NewSyncSplitter : invalid parameters sent to create sync splitter")
+		return nil, fmt.Errorf("invalid parameters sent to create sync splitter")
+	}
+
+	log.Debug(
+		"splitter::NewSyncSplitter : create new sync splitter for %s, block size %v, workers %v",
+		opts.path,
+		opts.blockPool.GetBlockSize(),
+		opts.workerCount,
+	)
+
+	ds, err := newDownloadSplitter(&downloadSplitterOptions{
+		blockPool:   opts.blockPool,
+		path:        opts.path,
+		workerCount: opts.workerCount,
+		remote:      opts.remote,
+		statsMgr:    opts.statsMgr,
+		fileLocks:   opts.fileLocks,
+		validateMD5: opts.validateMD5,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   opts.blockPool,
+		path:        opts.path,
+		workerCount: opts.workerCount,
+		remote:      opts.remote,
+		statsMgr:    opts.statsMgr,
+		fileLocks:   opts.fileLocks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ss := &syncSplitter{
+		blockPool:   opts.blockPool,
+		path:        opts.path,
+		fileLocks:   opts.fileLocks,
+		validateMD5: opts.validateMD5,
+		ds:          ds,
+		us:          us,
+	}
+
+	ss.SetName(SPLITTER)
+	ss.SetWorkerCount(opts.workerCount)
+	ss.SetRemote(opts.remote)
+	ss.SetStatsManager(opts.statsMgr)
+	ss.Init()
+	return ss, nil
+}
+
+func (ss *syncSplitter) Init() {
+	ss.SetThreadPool(NewThreadPool(ss.GetWorkerCount(), ss.Process))
+	if ss.GetThreadPool() == nil {
+		log.Err("syncSplitter::Init : fail to init thread pool")
+		return
+	}
+
+	// the download and upload splitters run on the workers of this splitter
+	ss.ds.SetThreadPool(ss.GetThreadPool())
+	ss.us.SetThreadPool(ss.GetThreadPool())
+}
+
+func (ss *syncSplitter) Start(ctx context.Context) {
+	log.Debug("syncSplitter::Start : start sync splitter for %s", ss.path)
+	ss.GetThreadPool().Start(ctx)
+}
+
+func (ss *syncSplitter) Stop() {
+	log.Debug("syncSplitter::Stop : stop sync splitter for %s", ss.path)
+	if ss.GetThreadPool() != nil {
+		ss.GetThreadPool().Stop()
+	}
+	log.Debug("syncSplitter::Stop : stop successful")
+}
+
+func (ss *syncSplitter) SetNext(next XComponent) {
+	ss.splitter.SetNext(next)
+	ss.ds.SetNext(next)
+	ss.us.SetNext(next)
+}
+
+func (ss *syncSplitter) Process(item *WorkItem) (int, error) {
+	if item.Download {
+		return ss.ds.Process(item)
+	}
+
+	n, err := ss.us.Process(item)
+	if err != nil {
+		return n, err
+	}
+
+	// the remote copy now carries the upload time, so move the local modified time
+	// along with it to make both sides look the same to the next sync
+	attr, err := ss.GetRemote().GetAttr(internal.GetAttrOptions{Name: item.Path})
+	if err != nil {
+		log.Err(
+			"syncSplitter::Process : Failed to get attr of %s after upload [%s]",
+			item.Path,
+			err.Error(),
+		)
+		return n, nil
+	}
+
+	err = os.Chtimes(filepath.Join(ss.path, item.Path), time.Time{}, attr.Mtime)
+	if err != nil {
+		log.Err(
+			"syncSplitter::Process : Failed to change times of file %s [%s]",
+			item.Path,
+			err.Error(),
+		)
+	}
+	return n, nil
+}
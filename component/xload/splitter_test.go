@@ -321,6 +321,191 @@ func (suite *splitterTestSuite) TestSplitterConsistency() {
 	validateMD5(ts.path, remote_path, suite.assert)
 }
 
+func newTestUploadRemote(path string, assert *assert.Assertions) internal.Component {
+	cfg := fmt.Sprintf("loopbackfs:\n  path: %s\n", path)
+	err := config.ReadConfigFromReader(strings.NewReader(cfg))
+	assert.NoError(err)
+
+	upRemote := loopback.NewLoopbackFSComponent()
+	err = upRemote.Configure(true)
+	assert.NoError(err)
+	return upRemote
+}
+
+func (suite *splitterTestSuite) TestNewUploadSplitter() {
+	us, err := newUploadSplitter(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(us)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+
+	us, err = newUploadSplitter(&uploadSplitterOptions{})
+	suite.assert.Error(err)
+	suite.assert.Nil(us)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	us, err = newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   NewBlockPool(1, 1, context.TODO()),
+		path:        "/home/user/random_path",
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    statsMgr,
+		fileLocks:   common.NewLockMap(),
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(us)
+}
+
+func (suite *splitterTestSuite) TestUploadProcess() {
+	ts, err := setupTestSplitter()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	// upload from the test files into a fresh remote
+	upRemote := newTestUploadRemote(ts.path, suite.assert)
+
+	us, err := newUploadSplitter(
+		&uploadSplitterOptions{ts.blockPool, remote_path, 4, upRemote, ts.stMgr, ts.locks},
+	)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(us)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 4,
+		remote:      upRemote,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.NoError(err)
+	us.SetNext(rdm)
+
+	rdm.Start(context.TODO())
+	us.Start(context.TODO())
+	defer func() {
+		us.Stop()
+		rdm.Stop()
+	}()
+
+	n, err := us.Process(&WorkItem{Path: "file_missing", DataLen: 10})
+	suite.assert.Error(err)
+	suite.assert.Equal(-1, n)
+
+	for i := range 5 {
+		fileName := fmt.Sprintf("file_%v", i)
+		info, err := os.Stat(filepath.Join(remote_path, fileName))
+		suite.assert.NoError(err)
+
+		item := &WorkItem{Path: fileName, DataLen: uint64(info.Size()), Mtime: info.ModTime()}
+		n, err = us.Process(item)
+		suite.assert.NoError(err)
+		suite.assert.Equal(0, n)
+
+		l, err := computeMD5(filepath.Join(remote_path, fileName))
+		suite.assert.NoError(err)
+		r, err := computeMD5(filepath.Join(ts.path, fileName))
+		suite.assert.NoError(err)
+		suite.assert.Equal(l, r)
+
+		// file is already present in remote with the same size and a newer modified time
+		n, err = us.Process(item)
+		suite.assert.NoError(err)
+		suite.assert.Equal(int(info.Size()), n)
+	}
+}
+
+func (suite *splitterTestSuite) TestUploadSplitterStartStop() {
+	ts, err := setupTestSplitter()
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ts)
+
+	defer func() {
+		err = ts.cleanup()
+		suite.assert.NoError(err)
+	}()
+
+	// upload the test files into a fresh remote
+	upRemote := newTestUploadRemote(ts.path, suite.assert)
+
+	ll, err := newLocalLister(&localListerOptions{
+		path:              remote_path,
+		workerCount:       4,
+		defaultPermission: common.DefaultFilePermissionBits,
+		remote:            upRemote,
+		statsMgr:          ts.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ll)
+
+	us, err := newUploadSplitter(
+		&uploadSplitterOptions{ts.blockPool, remote_path, 4, upRemote, ts.stMgr, ts.locks},
+	)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(us)
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: 8,
+		remote:      upRemote,
+		statsMgr:    ts.stMgr,
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(rdm)
+
+	// create chain
+	ll.SetNext(us)
+	us.SetNext(rdm)
+
+	// start components
+	rdm.Start(context.TODO())
+	us.Start(context.TODO())
+	ll.Start(context.TODO())
+
+	time.Sleep(5 * time.Second)
+
+	// stop comoponents
+	ll.Stop()
+
+	validateMD5(ts.path, remote_path, suite.assert)
+}
+
+func (suite *splitterTestSuite) TestNewSyncSplitter() {
+	ss, err := newSyncSplitter(nil)
+	suite.assert.Error(err)
+	suite.assert.Nil(ss)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync splitter")
+
+	statsMgr, err := NewStatsManager(1, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(statsMgr)
+
+	ss, err = newSyncSplitter(&syncSplitterOptions{
+		blockPool:   NewBlockPool(1, 1, context.TODO()),
+		path:        "/home/user/random_path",
+		workerCount: 4,
+		remote:      remote,
+		statsMgr:    statsMgr,
+		fileLocks:   common.NewLockMap(),
+	})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(ss)
+
+	// the download and upload splitters share the workers and the next component
+	suite.assert.Equal(ss.GetThreadPool(), ss.ds.GetThreadPool())
+	suite.assert.Equal(ss.GetThreadPool(), ss.us.GetThreadPool())
+
+	next := getTestcomponent()
+	defer next.Stop()
+	ss.SetNext(next)
+	suite.assert.Equal(next, ss.ds.GetNext())
+	suite.assert.Equal(next, ss.us.GetNext())
+}
+
 func validateMD5(localPath string, remotePath string, assert *assert.Assertions) {
 	entries, err := os.ReadDir(remotePath)
 	assert.NoError(err)
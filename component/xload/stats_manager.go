@@ -44,6 +44,7 @@ type StatsManager struct {
 	totalFiles      uint64          // total number of files that have been scanned so far
 	success         uint64          // number of files that have been successfully processed
 	failed          uint64          // number of files that failed
+	conflicts       uint64          // number of files that changed on both sides in sync mode
 	dirs            uint64          // number of directories processed
 	bytesDownloaded uint64          // total number of bytes downloaded
 	bytesUploaded   uint64          // total number of bytes uploaded
@@ -64,6 +65,7 @@ type StatsItem struct {
 	Success          bool   // flag to indicate if the file has been processed successfully or not
 	Download         bool   // flag to denote upload or download
 	DiskIO           bool   // flag to denote if the item is a disk IO
+	Conflict         bool   // flag to denote that the file could not be reconciled in sync mode
 	BytesTransferred uint64 // bytes uploaded or downloaded for this file
 }
 
@@ -73,6 +75,7 @@ type statsJSONData struct {
 	Total            uint64  `json:"Total"`
 	Done             uint64  `json:"Done"`
 	Failed           uint64  `json:"Failed"`
+	Conflicts        uint64  `json:"Conflicts,omitzero"`
 	Pending          uint64  `json:"Pending"`
 	BytesTransferred uint64  `json:"BytesTransferred"`
 	BandwidthMbps    float64 `json:"Bandwidth(Mbps)"`
@@ -175,6 +178,8 @@ func (sm *StatsManager) statsProcessor() {
 			// log.Debug("statsManager::statsProcessor : splitter: Name %v, success %v, download %v", item.name, item.success, item.download)
 			if item.DiskIO {
 				sm.updateDiskStats(item.BytesTransferred)
+			} else if item.Conflict {
+				sm.conflicts += 1
 			} else {
 				sm.updateSuccessFailedCtr(item.Success)
 			}
@@ -226,7 +231,7 @@ func (sm *StatsManager) calculateBandwidth() {
 	currTime := time.Now().UTC()
 	timeLapsed := currTime.Sub(sm.startTime).Seconds()
 	bytesTransferred := sm.bytesDownloaded + sm.bytesUploaded
-	filesProcessed := sm.success + sm.failed
+	filesProcessed := sm.success + sm.failed + sm.conflicts
 	filesPending := sm.totalFiles - filesProcessed
 	percentCompleted := (float64(filesProcessed) / float64(sm.totalFiles)) * 100
 	bandwidthMbps := float64(bytesTransferred*8) / (timeLapsed * float64(MB))
@@ -241,9 +246,9 @@ func (sm *StatsManager) calculateBandwidth() {
 		sm.pool.Usage()
 	}
 
-	log.Crit("statsManager::calculateBandwidth : timestamp %v, %.2f%%, %v Done, %v Failed, "+
+	log.Crit("statsManager::calculateBandwidth : timestamp %v, %.2f%%, %v Done, %v Failed, %v Conflicts, "+
 		"%v Pending, %v Total, Bytes transferred %v, Throughput (Mbps): %.2f, Disk Speed (Mbps): %.2f, Blockpool usage: %v%%, (%v / %v / %v : %v), Time: %.2f",
-		currTime.Format(time.RFC1123), percentCompleted, sm.success, sm.failed,
+		currTime.Format(time.RFC1123), percentCompleted, sm.success, sm.failed, sm.conflicts,
 		filesPending, sm.totalFiles, bytesTransferred, bandwidthMbps, diskSpeedMbps, poolusage,
 		maximum, pr, reg, waiting, timeLapsed)
 
@@ -254,6 +259,7 @@ func (sm *StatsManager) calculateBandwidth() {
 			Total:            sm.totalFiles,
 			Done:             sm.success,
 			Failed:           sm.failed,
+			Conflicts:        sm.conflicts,
 			Pending:          filesPending,
 			BytesTransferred: bytesTransferred,
 			BandwidthMbps:    RoundFloat(bandwidthMbps, 2),
@@ -134,6 +134,26 @@ func (suite *statsMgrTestSuite) TestStatsManagerStartStop() {
 	suite.assert.Positive(sm.bytesUploaded)
 }
 
+func (suite *statsMgrTestSuite) TestStatsManagerConflicts() {
+	sm, err := NewStatsManager(10, false, nil)
+	suite.assert.NoError(err)
+	suite.assert.NotNil(sm)
+
+	sm.Start()
+
+	sm.AddStats(&StatsItem{Component: LISTER, Name: "", ListerCount: uint64(3)})
+	sm.AddStats(&StatsItem{Component: SPLITTER, Name: "file_0", Success: true})
+	sm.AddStats(&StatsItem{Component: SPLITTER, Name: "file_1", Success: false})
+	sm.AddStats(&StatsItem{Component: SPLITTER, Name: "file_2", Conflict: true})
+
+	sm.Stop()
+
+	suite.assert.Equal(uint64(1), sm.success)
+	suite.assert.Equal(uint64(1), sm.failed)
+	suite.assert.Equal(uint64(1), sm.conflicts)
+	suite.assert.Equal(sm.totalFiles, sm.success+sm.failed+sm.conflicts)
+}
+
 func TestStatsMgrSuite(t *testing.T) {
 	suite.Run(t, new(statsMgrTestSuite))
 }
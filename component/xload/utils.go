@@ -119,6 +119,15 @@ func RoundFloat(val float64, precision int) float64 {
 	return math.Round(val*ratio) / ratio
 }
 
+// isLocalFileStale returns true if the local file was modified before the remote one
+func isLocalFileStale(localPath string, remoteMtime time.Time) bool {
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return true
+	}
+	return fileInfo.ModTime().Truncate(time.Second).Before(remoteMtime.Truncate(time.Second))
+}
+
 // returns if the given path is present, if its a directory and its size
 func isFilePresent(localPath string) (bool, bool, int64) {
 	fileInfo, err := os.Stat(localPath)
@@ -103,24 +103,40 @@ func (xl *Xload) Priority() internal.ComponentPriority {
 func (xl *Xload) Configure(_ bool) error {
 	log.Trace("Xload::Configure : %s", xl.Name())
 
-	// xload component should be used only in readonly mode
-	var readonly bool
-	err := config.UnmarshalKey("read-only", &readonly)
+	conf := XloadOptions{}
+	err := config.UnmarshalKey(xl.Name(), &conf)
 	if err != nil {
-		log.Err("Xload::Configure : config error [unable to obtain read-only]")
-		return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+		log.Err("Xload::Configure : config error [invalid config attributes]")
+		return fmt.Errorf("Xload: config error [invalid config attributes]")
 	}
 
-	if !readonly {
-		log.Err("Xload::Configure : Xload component should be used only in read-only mode")
-		return fmt.Errorf("Xload component should be used in only in read-only mode")
+	var mode = EMode.PRELOAD() // using preload as the default mode
+	if len(conf.Mode) > 0 {
+		err = mode.Parse(conf.Mode)
+		if err != nil {
+			log.Err("Xload::Configure : Failed to parse mode %s [%s]", conf.Mode, err.Error())
+			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+		}
+
+		if mode == EMode.INVALID_MODE() {
+			log.Err("Xload::Configure : Invalid mode : %s", conf.Mode)
+			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+		}
 	}
 
-	conf := XloadOptions{}
-	err = config.UnmarshalKey(xl.Name(), &conf)
-	if err != nil {
-		log.Err("Xload::Configure : config error [invalid config attributes]")
-		return fmt.Errorf("Xload: config error [invalid config attributes]")
+	// preload should be used only in readonly mode
+	if mode == EMode.PRELOAD() {
+		var readonly bool
+		err = config.UnmarshalKey("read-only", &readonly)
+		if err != nil {
+			log.Err("Xload::Configure : config error [unable to obtain read-only]")
+			return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+		}
+
+		if !readonly {
+			log.Err("Xload::Configure : Xload component should be used only in read-only mode")
+			return fmt.Errorf("Xload component should be used in only in read-only mode")
+		}
 	}
 
 	blockSize := (float64)(defaultBlockSize) // 16 MB as default block size
@@ -168,38 +184,41 @@ func (xl *Xload) Configure(_ bool) error {
 			)
 		}
 
-		_, err = os.Stat(xl.path)
-		if os.IsNotExist(err) {
-			log.Info(
-				"Xload::Configure : config error [xload path does not exist, attempting to create path]",
-			)
-			err := os.Mkdir(xl.path, os.FileMode(0755))
-			if err != nil {
+		if mode == EMode.UPLOAD() {
+			// upload pushes the existing contents of the path, so it must already be there
+			info, err := os.Stat(xl.path)
+			if err != nil || !info.IsDir() {
 				log.Err(
-					"Xload::Configure : config error creating directory of xload path [%s]",
-					err.Error(),
+					"Xload::Configure : config error [xload path %s is not a directory]",
+					xl.path,
+				)
+				return fmt.Errorf(
+					"config error in %s [path %s is not a directory]",
+					xl.Name(),
+					xl.path,
 				)
-				return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
 			}
-		}
-
-		if !common.IsDirectoryEmpty(xl.path) {
-			log.Err("Xload::Configure : config error %s directory is not empty", xl.path)
-			return fmt.Errorf("config error in %s [temp directory not empty]", xl.Name())
-		}
-	}
-
-	var mode = EMode.PRELOAD() // using preload as the default mode
-	if len(conf.Mode) > 0 {
-		err = mode.Parse(conf.Mode)
-		if err != nil {
-			log.Err("Xload::Configure : Failed to parse mode %s [%s]", conf.Mode, err.Error())
-			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
-		}
+		} else {
+			_, err = os.Stat(xl.path)
+			if os.IsNotExist(err) {
+				log.Info(
+					"Xload::Configure : config error [xload path does not exist, attempting to create path]",
+				)
+				err := os.Mkdir(xl.path, os.FileMode(0755))
+				if err != nil {
+					log.Err(
+						"Xload::Configure : config error creating directory of xload path [%s]",
+						err.Error(),
+					)
+					return fmt.Errorf("config error in %s [%s]", xl.Name(), err.Error())
+				}
+			}
 
-		if mode == EMode.INVALID_MODE() {
-			log.Err("Xload::Configure : Invalid mode : %s", conf.Mode)
-			return fmt.Errorf("invalid mode in xload : %s", conf.Mode)
+			// sync reconciles whatever is already present in the path
+			if mode == EMode.PRELOAD() && !common.IsDirectoryEmpty(xl.path) {
+				log.Err("Xload::Configure : config error %s directory is not empty", xl.path)
+				return fmt.Errorf("config error in %s [temp directory not empty]", xl.Name())
+			}
 		}
 	}
 
@@ -275,10 +294,18 @@ func (xl *Xload) Start(ctx context.Context) error {
 		}
 	case EMode.UPLOAD():
 		// Start uploader here
-		return fmt.Errorf("uploader is currently unsupported")
+		err = xl.createUploader()
+		if err != nil {
+			log.Err("Xload::Start : Failed to start uploader [%s]", err.Error())
+			return err
+		}
 	case EMode.SYNC():
 		//Start syncer here
-		return fmt.Errorf("sync is currently unsupported")
+		err = xl.createSyncer()
+		if err != nil {
+			log.Err("Xload::Start : Failed to start syncer [%s]", err.Error())
+			return err
+		}
 	default:
 		log.Err("Xload::Start : Invalid mode : %s", xl.mode.String())
 		return fmt.Errorf("invalid mode in xload : %s", xl.mode.String())
@@ -314,6 +341,11 @@ func (xl *Xload) Stop() error {
 		log.Warn("Xload::Stop : Stop timeout")
 	}
 
+	// the local path holds the source data in upload mode, so leave it untouched
+	if xl.mode != EMode.PRELOAD() {
+		return nil
+	}
+
 	// TODO:: xload : should we delete the files from local path
 	err := common.TempCacheCleanup(xl.path)
 	if err != nil {
@@ -367,6 +399,100 @@ func (xl *Xload) createDownloader() error {
 	return nil
 }
 
+func (xl *Xload) createUploader() error {
+	log.Trace("Xload::createUploader : Starting uploader")
+
+	// use at least one lister on single core machines
+	listerCount := max(min(runtime.NumCPU()/2, MAX_LISTER), 1)
+
+	// Create local lister pool to list local files
+	ll, err := newLocalLister(&localListerOptions{
+		path:              xl.path,
+		workerCount:       uint32(listerCount),
+		defaultPermission: xl.defaultPermission,
+		remote:            xl.NextComponent(),
+		statsMgr:          xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : Unable to create local lister [%s]", err.Error())
+		return err
+	}
+
+	us, err := newUploadSplitter(&uploadSplitterOptions{
+		blockPool:   xl.blockPool,
+		path:        xl.path,
+		workerCount: uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+		fileLocks:   xl.fileLocks,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : Unable to create upload splitter [%s]", err.Error())
+		return err
+	}
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: xl.workerCount,
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createUploader : failed to create remote data manager [%s]", err.Error())
+		return err
+	}
+
+	xl.comps = []XComponent{ll, us, rdm}
+	return nil
+}
+
+func (xl *Xload) createSyncer() error {
+	log.Trace("Xload::createSyncer : Starting syncer")
+
+	// use at least one lister on single core machines
+	listerCount := max(min(runtime.NumCPU()/2, MAX_LISTER), 1)
+
+	// Create sync lister pool to compare local and remote files
+	sl, err := newSyncLister(&syncListerOptions{
+		path:              xl.path,
+		workerCount:       uint32(listerCount),
+		defaultPermission: xl.defaultPermission,
+		remote:            xl.NextComponent(),
+		statsMgr:          xl.statsMgr,
+		validateMD5:       xl.validateMD5,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to create sync lister [%s]", err.Error())
+		return err
+	}
+
+	ss, err := newSyncSplitter(&syncSplitterOptions{
+		blockPool:   xl.blockPool,
+		path:        xl.path,
+		workerCount: uint32(math.Min(float64(runtime.NumCPU()), float64(MAX_DATA_SPLITTER))),
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+		fileLocks:   xl.fileLocks,
+		validateMD5: xl.validateMD5,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : Unable to create sync splitter [%s]", err.Error())
+		return err
+	}
+
+	rdm, err := newRemoteDataManager(&remoteDataManagerOptions{
+		workerCount: xl.workerCount,
+		remote:      xl.NextComponent(),
+		statsMgr:    xl.statsMgr,
+	})
+	if err != nil {
+		log.Err("Xload::createSyncer : failed to create remote data manager [%s]", err.Error())
+		return err
+	}
+
+	xl.comps = []XComponent{sl, ss, rdm}
+	return nil
+}
+
 func (xl *Xload) createChain() error {
 	if len(xl.comps) == 0 {
 		log.Err("Xload::createChain : no component initialized in xload")
@@ -466,6 +592,11 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 		common.PrettyOpenFlags(options.Flags),
 		options.Mode,
 	)
+	// only preload serves files from the local path
+	if xl.mode != EMode.PRELOAD() {
+		return xl.NextComponent().OpenFile(options)
+	}
+
 	localPath := filepath.Join(xl.path, options.Name)
 
 	flock := xl.fileLocks.Get(options.Name)
@@ -511,6 +642,10 @@ func (xl *Xload) OpenFile(options internal.OpenFileOptions) (*handlemap.Handle,
 }
 
 func (xl *Xload) ReleaseFile(options internal.ReleaseFileOptions) error {
+	if xl.mode != EMode.PRELOAD() {
+		return xl.NextComponent().ReleaseFile(options)
+	}
+
 	// Lock the file so that while close is in progress no one can open the file again
 	flock := xl.fileLocks.Get(options.Handle.Path)
 	flock.Lock()
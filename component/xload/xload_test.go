@@ -39,6 +39,7 @@ import (
 	"github.com/Seagate/cloudfuse/common/log"
 	"github.com/Seagate/cloudfuse/component/loopback"
 	"github.com/Seagate/cloudfuse/internal"
+	"github.com/Seagate/cloudfuse/internal/handlemap"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
@@ -306,6 +307,32 @@ func (suite *xloadTestSuite) TestConfigPathNotEmpty() {
 	suite.assert.Contains(err.Error(), "temp directory not empty")
 }
 
+func (suite *xloadTestSuite) TestConfigUploadPath() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	// upload does not need read-only, but the path must already exist
+	testConfig := fmt.Sprintf(
+		"xload:\n  path: %s\n  mode: upload\n\nloopbackfs:\n  path: %s",
+		suite.local_path,
+		suite.fake_storage_path,
+	)
+	err := suite.setupTestHelper(testConfig, false)
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "is not a directory")
+
+	// a path with data in it is what upload works on
+	err = os.Mkdir(suite.local_path, 0755)
+	suite.assert.NoError(err)
+	_, err = os.Create(filepath.Join(suite.local_path, "testFile"))
+	suite.assert.NoError(err)
+
+	err = suite.setupTestHelper(testConfig, false)
+	suite.assert.NoError(err)
+	suite.assert.Equal(EMode.UPLOAD(), suite.xload.mode)
+	suite.assert.Equal(suite.local_path, suite.xload.path)
+}
+
 func (suite *xloadTestSuite) TestConfigMode() {
 	defer suite.cleanupTest(false)
 	suite.cleanupTest(false) // teardown the default xload generated
@@ -365,7 +392,7 @@ func (suite *xloadTestSuite) TestUnsupportedModes() {
 	defer suite.cleanupTest(false)
 	suite.cleanupTest(false) // teardown the default xload generated
 
-	modes := []string{"upload", "sync", "invalid_mode"}
+	modes := []string{"invalid_mode"}
 	blockSize := float64(0.001)
 	for _, m := range modes {
 		testConfig := fmt.Sprintf(
@@ -454,6 +481,58 @@ func (suite *xloadTestSuite) TestCreateDownloader() {
 	suite.assert.Len(xl.comps, 3)
 }
 
+func (suite *xloadTestSuite) TestCreateUploader() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	xl := &Xload{}
+	err := xl.createUploader()
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create local lister")
+	suite.assert.Empty(xl.comps)
+
+	xl.path = suite.local_path
+	xl.workerCount = 4
+	xl.SetNextComponent(xl)
+	xl.statsMgr = &StatsManager{}
+	err = xl.createUploader()
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create upload splitter")
+	suite.assert.Empty(xl.comps)
+
+	xl.blockPool = &BlockPool{}
+	xl.fileLocks = common.NewLockMap()
+	err = xl.createUploader()
+	suite.assert.NoError(err)
+	suite.assert.Len(xl.comps, 3)
+}
+
+func (suite *xloadTestSuite) TestCreateSyncer() {
+	defer suite.cleanupTest(false)
+	suite.cleanupTest(false) // teardown the default xload generated
+
+	xl := &Xload{}
+	err := xl.createSyncer()
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync lister")
+	suite.assert.Empty(xl.comps)
+
+	xl.path = suite.local_path
+	xl.workerCount = 4
+	xl.SetNextComponent(xl)
+	xl.statsMgr = &StatsManager{}
+	err = xl.createSyncer()
+	suite.assert.Error(err)
+	suite.assert.Contains(err.Error(), "invalid parameters sent to create sync splitter")
+	suite.assert.Empty(xl.comps)
+
+	xl.blockPool = &BlockPool{}
+	xl.fileLocks = common.NewLockMap()
+	err = xl.createSyncer()
+	suite.assert.NoError(err)
+	suite.assert.Len(xl.comps, 3)
+}
+
 func (suite *xloadTestSuite) TestCreateChain() {
 	defer suite.cleanupTest(false)
 	suite.cleanupTest(false) // teardown the default xload generated
@@ -549,6 +628,136 @@ func (suite *xloadTestSuite) TestXloadStartStop() {
 	validateMD5(suite.local_path, suite.fake_storage_path, suite.assert)
 }
 
+func (suite *xloadTestSuite) TestXloadUploadStartStop() {
+	defer suite.cleanupTest(false)
+	config.ResetConfig()
+
+	err := os.MkdirAll(suite.local_path, 0755)
+	suite.assert.NoError(err)
+	createTestDirsAndFiles(suite.local_path, suite.assert)
+
+	blockSize := (float64)(0.00001)
+	testConfig := fmt.Sprintf(
+		"xload:\n  path: %s\n  mode: upload\n  block-size-mb: %v\n\nloopbackfs:\n  path: %s",
+		suite.local_path,
+		blockSize,
+		suite.fake_storage_path,
+	)
+	err = suite.setupTestHelper(
+		testConfig,
+		true,
+	) // setup a new xload with a custom config (teardown will occur after the test as usual)
+	suite.assert.NoError(err)
+	suite.assert.Equal(EMode.UPLOAD(), suite.xload.mode)
+
+	time.Sleep(5 * time.Second)
+
+	validateMD5(suite.fake_storage_path, suite.local_path, suite.assert)
+
+	// files in upload mode are opened from remote
+	handle, err := suite.xload.OpenFile(internal.OpenFileOptions{Name: "file_1", Flags: os.O_RDONLY})
+	suite.assert.NoError(err)
+	suite.assert.NotNil(handle)
+	suite.assert.False(handle.Flags.IsSet(handlemap.HandleFlagCached))
+	err = suite.xload.ReleaseFile(internal.ReleaseFileOptions{Handle: handle})
+	suite.assert.NoError(err)
+
+	// local data must survive stopping the uploader
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	err = suite.xload.Stop()
+	suite.assert.NoError(err)
+	entries, err := os.ReadDir(suite.local_path)
+	suite.assert.NoError(err)
+	suite.assert.NotEmpty(entries)
+}
+
+func (suite *xloadTestSuite) TestXloadSyncStartStop() {
+	defer suite.cleanupTest(false)
+	config.ResetConfig()
+
+	err := os.MkdirAll(suite.local_path, 0755)
+	suite.assert.NoError(err)
+	err = os.MkdirAll(suite.fake_storage_path, 0755)
+	suite.assert.NoError(err)
+
+	old := time.Now().Add(-time.Hour)
+	writeFile := func(dir string, name string, data string, mtime time.Time) {
+		filePath := filepath.Join(dir, name)
+		err := os.MkdirAll(filepath.Dir(filePath), 0755)
+		suite.assert.NoError(err)
+		err = os.WriteFile(filePath, []byte(data), 0644)
+		suite.assert.NoError(err)
+		err = os.Chtimes(filePath, mtime, mtime)
+		suite.assert.NoError(err)
+	}
+
+	// present on one side only
+	writeFile(suite.fake_storage_path, "remote_dir/remote_file", "remote data", old)
+	writeFile(suite.local_path, "local_dir/local_file", "local data", old)
+
+	// present on both sides with one of them newer
+	writeFile(suite.fake_storage_path, "newer_remote", "new remote data", time.Now())
+	writeFile(suite.local_path, "newer_remote", "old local", old)
+	writeFile(suite.fake_storage_path, "newer_local", "old remote", old)
+	writeFile(suite.local_path, "newer_local", "new local data", time.Now())
+
+	// present on both sides and already in sync
+	writeFile(suite.fake_storage_path, "same", "same data", old)
+	writeFile(suite.local_path, "same", "same data", old)
+
+	// conflicts
+	writeFile(suite.fake_storage_path, "conflict", "remote version", old)
+	writeFile(suite.local_path, "conflict", "local", old)
+	writeFile(suite.fake_storage_path, "mixed", "remote file", old)
+	writeFile(suite.local_path, "mixed/local_file", "local file", old)
+
+	blockSize := (float64)(0.00001)
+	testConfig := fmt.Sprintf(
+		"xload:\n  path: %s\n  mode: sync\n  block-size-mb: %v\n\nloopbackfs:\n  path: %s",
+		suite.local_path,
+		blockSize,
+		suite.fake_storage_path,
+	)
+	err = suite.setupTestHelper(testConfig, true)
+	suite.assert.NoError(err)
+	suite.assert.Equal(EMode.SYNC(), suite.xload.mode)
+
+	time.Sleep(5 * time.Second)
+
+	err = suite.loopback.Stop()
+	suite.assert.NoError(err)
+	err = suite.xload.Stop()
+	suite.assert.NoError(err)
+
+	checkFile := func(dir string, name string, data string) {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		suite.assert.NoError(err)
+		suite.assert.Equal(data, string(content))
+	}
+
+	checkFile(suite.local_path, "remote_dir/remote_file", "remote data")
+	checkFile(suite.fake_storage_path, "local_dir/local_file", "local data")
+	checkFile(suite.local_path, "newer_remote", "new remote data")
+	checkFile(suite.fake_storage_path, "newer_local", "new local data")
+	checkFile(suite.local_path, "same", "same data")
+
+	// conflicts are reported and left untouched
+	checkFile(suite.local_path, "conflict", "local")
+	checkFile(suite.fake_storage_path, "conflict", "remote version")
+	checkFile(suite.local_path, "mixed/local_file", "local file")
+	checkFile(suite.fake_storage_path, "mixed", "remote file")
+	suite.assert.Equal(uint64(2), suite.xload.statsMgr.conflicts)
+	suite.assert.Zero(suite.xload.statsMgr.failed)
+
+	// uploaded files carry the remote modified time locally so the next sync skips them
+	localInfo, err := os.Stat(filepath.Join(suite.local_path, "newer_local"))
+	suite.assert.NoError(err)
+	remoteInfo, err := os.Stat(filepath.Join(suite.fake_storage_path, "newer_local"))
+	suite.assert.NoError(err)
+	suite.assert.Equal(remoteInfo.ModTime().Unix(), localInfo.ModTime().Unix())
+}
+
 func (suite *xloadTestSuite) TestOpenFileAlreadyDownloaded() {
 	defer suite.cleanupTest(true)
 	config.ResetConfig()
@@ -26,13 +26,97 @@
 package internal
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Seagate/cloudfuse/common"
 )
 
+// Metadata keys that preserve the times set with utimens.
+// The names and format match the ones used by rclone and s3fs.
+const (
+	MtimeMetadataKey = "mtime"
+	AtimeMetadataKey = "atime"
+)
+
+// Archive control keys are answered by the storage components instead of being stored.
+// TierMetadataKey holds the storage class or access tier of an object. RestoreMetadataKey holds
+// the restore status of an archived object, and setting it to any value requests a restore.
+const (
+	TierMetadataKey    = "cloudfuse_tier"
+	RestoreMetadataKey = "cloudfuse_restore"
+)
+
+// Restore status of an archived object
+const (
+	RestoreStatusArchived  = "archived"  // must be restored to be read
+	RestoreStatusRestoring = "restoring" // a restore was requested and is in progress
+	RestoreStatusRestored  = "restored"  // a temporary restored copy can be read
+)
+
+// FormatMetadataTime formats t as seconds since the epoch with nanosecond precision
+func FormatMetadataTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// ParseMetadataTime parses a time written by FormatMetadataTime, or an RFC 3339 time
+func ParseMetadataTime(value string) (time.Time, bool) {
+	secStr, fracStr, hasFrac := strings.Cut(value, ".")
+	sec, err := strconv.ParseInt(secStr, 10, 64)
+	if err != nil {
+		t, err := time.Parse(time.RFC3339Nano, value)
+		return t, err == nil
+	}
+	var nsec int64
+	if hasFrac {
+		// keep at most nanosecond precision
+		fracStr = (fracStr + "000000000")[:9]
+		nsec, err = strconv.ParseInt(fracStr, 10, 64)
+		if err != nil || nsec < 0 {
+			return time.Time{}, false
+		}
+	}
+	return time.Unix(sec, nsec), true
+}
+
+// IsTimeMetadataKey returns true for the metadata keys that preserve times
+func IsTimeMetadataKey(key string) bool {
+	return strings.EqualFold(key, MtimeMetadataKey) || strings.EqualFold(key, AtimeMetadataKey)
+}
+
+// KeepMetadata returns the metadata of an upload replacing an object with the existing metadata.
+// Uploads replace all the metadata of an object, so user metadata and the access time are carried
+// over, while the modification time describes the old contents and is dropped. The given metadata
+// takes precedence, keys are case-insensitive, and a nil value removes the key.
+func KeepMetadata(existing map[string]*string, metadata map[string]*string) map[string]*string {
+	merged := make(map[string]*string, len(existing)+len(metadata))
+	for key, value := range existing {
+		if value != nil && !strings.EqualFold(key, MtimeMetadataKey) {
+			merged[key] = value
+		}
+	}
+	for key, value := range metadata {
+		for old := range merged {
+			if strings.EqualFold(old, key) {
+				delete(merged, old)
+			}
+		}
+		if value != nil {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// IsArchiveMetadataKey returns true for the archive control keys
+func IsArchiveMetadataKey(key string) bool {
+	return strings.EqualFold(key, TierMetadataKey) || strings.EqualFold(key, RestoreMetadataKey)
+}
+
 // create an object attributes struct
 func CreateObjAttr(objectPath string, size int64, lastModified time.Time) (attr *ObjAttr) {
 	attr = &ObjAttr{
@@ -110,6 +194,7 @@ type ObjAttr struct {
 	Name     string             // base name of the path
 	MD5      []byte             // MD5 of the blob as per last GetAttr
 	ETag     string             // ETag of the blob as per last GetAttr
+	Tier     string             // storage class or access tier, empty when unknown
 	Metadata map[string]*string // extra information to preserve
 }
 
@@ -123,6 +208,26 @@ func (attr *ObjAttr) IsSymlink() bool {
 	return attr.Flags.IsSet(PropFlagSymlink)
 }
 
+// SetTimesFromMetadata : Replace Mtime and Atime with the times preserved in the metadata, if any.
+// Keys are matched case-insensitively since not all cloud providers preserve their case.
+func (attr *ObjAttr) SetTimesFromMetadata() {
+	for key, value := range attr.Metadata {
+		if value == nil {
+			continue
+		}
+		t, ok := ParseMetadataTime(*value)
+		if !ok {
+			continue
+		}
+		switch {
+		case strings.EqualFold(key, MtimeMetadataKey):
+			attr.Mtime = t
+		case strings.EqualFold(key, AtimeMetadataKey):
+			attr.Atime = t
+		}
+	}
+}
+
 // IsModeDefault : Whether or not to use the default mode.
 // This is set in any storage service that does not support chmod/chown.
 func (attr *ObjAttr) IsModeDefault() bool {
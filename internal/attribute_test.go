@@ -0,0 +1,123 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type attributeTestSuite struct {
+	suite.Suite
+}
+
+func (s *attributeTestSuite) TestMetadataTime() {
+	assert := assert.New(s.T())
+	for _, t := range []time.Time{
+		time.Unix(1700000000, 123456789),
+		time.Unix(0, 0),
+		time.Unix(-1, 500000000),
+	} {
+		value := FormatMetadataTime(t)
+		parsed, ok := ParseMetadataTime(value)
+		assert.True(ok, value)
+		assert.True(t.Equal(parsed), value)
+	}
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{input: "1700000000", expected: time.Unix(1700000000, 0)},
+		{input: "1700000000.5", expected: time.Unix(1700000000, 500000000)},
+		{input: "1700000000.1234567891", expected: time.Unix(1700000000, 123456789)},
+		{input: "2023-11-14T22:13:20Z", expected: time.Unix(1700000000, 0)},
+	}
+	for _, tt := range tests {
+		parsed, ok := ParseMetadataTime(tt.input)
+		assert.True(ok, tt.input)
+		assert.True(tt.expected.Equal(parsed), tt.input)
+	}
+
+	for _, input := range []string{"", "abc", "1700000000.x", "1700000000.-5"} {
+		_, ok := ParseMetadataTime(input)
+		assert.False(ok, input)
+	}
+}
+
+func (s *attributeTestSuite) TestSetTimesFromMetadata() {
+	assert := assert.New(s.T())
+	lastModified := time.Unix(1700000000, 0)
+	attr := CreateObjAttr("file", 1, lastModified)
+	attr.Metadata["Mtime"] = new("1600000000")
+	attr.Metadata[AtimeMetadataKey] = new("invalid")
+
+	attr.SetTimesFromMetadata()
+	assert.True(time.Unix(1600000000, 0).Equal(attr.Mtime))
+	assert.True(lastModified.Equal(attr.Atime))
+	assert.True(lastModified.Equal(attr.Ctime))
+
+	assert.True(IsTimeMetadataKey("ATIME"))
+	assert.False(IsTimeMetadataKey("label"))
+}
+
+func (s *attributeTestSuite) TestKeepMetadata() {
+	assert := assert.New(s.T())
+	existing := map[string]*string{
+		"Label":          new("old"),
+		"other":          new("kept"),
+		"removed":        new("gone"),
+		AtimeMetadataKey: new("1600000000"),
+		"Mtime":          new("1600000000"),
+	}
+	metadata := map[string]*string{
+		"label":   new("new"),
+		"removed": nil,
+		"added":   new("value"),
+	}
+	assert.Equal(map[string]*string{
+		"label":          new("new"),
+		"other":          new("kept"),
+		"added":          new("value"),
+		AtimeMetadataKey: new("1600000000"),
+	}, KeepMetadata(existing, metadata))
+
+	assert.Empty(KeepMetadata(nil, map[string]*string{"removed": nil}))
+}
+
+func (s *attributeTestSuite) TestIsArchiveMetadataKey() {
+	assert := assert.New(s.T())
+	assert.True(IsArchiveMetadataKey("Cloudfuse_Restore"))
+	assert.True(IsArchiveMetadataKey(TierMetadataKey))
+	assert.False(IsArchiveMetadataKey("cloudfuse_snapshot"))
+}
+
+func TestAttributeTestSuite(t *testing.T) {
+	suite.Run(t, new(attributeTestSuite))
+}
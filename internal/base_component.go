@@ -279,6 +279,13 @@ func (base *BaseComponent) Chown(options ChownOptions) error {
 	return nil
 }
 
+func (base *BaseComponent) Utimens(options UtimensOptions) error {
+	if base.next != nil {
+		return base.next.Utimens(options)
+	}
+	return nil
+}
+
 func (base *BaseComponent) FileUsed(name string) error {
 	if base.next != nil {
 		return base.next.FileUsed(name)
@@ -314,3 +321,17 @@ func (base *BaseComponent) CommitData(opt CommitDataOptions) error {
 	}
 	return nil
 }
+
+func (base *BaseComponent) GetMetadata(opt GetMetadataOptions) (map[string]*string, error) {
+	if base.next != nil {
+		return base.next.GetMetadata(opt)
+	}
+	return nil, nil
+}
+
+func (base *BaseComponent) SetMetadata(opt SetMetadataOptions) error {
+	if base.next != nil {
+		return base.next.SetMetadata(opt)
+	}
+	return nil
+}
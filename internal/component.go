@@ -56,6 +56,10 @@ func (ComponentPriority) LevelTwo() ComponentPriority {
 	return ComponentPriority(300)
 }
 
+func (ComponentPriority) LevelThree() ComponentPriority {
+	return ComponentPriority(200)
+}
+
 // Component : Base internal for every component to participate in pipeline
 type Component interface {
 	// Pipeline participation related methods
@@ -129,6 +133,8 @@ type Component interface {
 	// SetAttr is implemented by the following functions in libfuse High level API.
 	Chmod(ChmodOptions) error
 	Chown(ChownOptions) error
+	// Utimens sets the access and modification times, a zero time leaves that time unchanged.
+	Utimens(UtimensOptions) error
 	TruncateFile(TruncateFileOptions) error
 
 	GetFileBlockOffsets(options GetFileBlockOffsetsOptions) (*common.BlockOffsetList, error)
@@ -139,4 +145,13 @@ type Component interface {
 	GetCommittedBlockList(string) (*CommittedBlockList, error)
 	StageData(StageDataOptions) error
 	CommitData(CommitDataOptions) error
+
+	// Metadata operations
+	//GetMetadata: Implementation expectations:
+	//1. must return only the user defined metadata, keys used internally by the storage are hidden
+	GetMetadata(GetMetadataOptions) (map[string]*string, error)
+	//SetMetadata: Implementation expectations:
+	//1. keys with a nil value are removed, other keys are added or replaced
+	//2. keys not in the options and keys used internally by the storage are preserved
+	SetMetadata(SetMetadataOptions) error
 }
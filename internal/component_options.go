@@ -26,8 +26,10 @@
 package internal
 
 import (
+	"context"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/Seagate/cloudfuse/internal/handlemap"
 )
@@ -102,6 +104,23 @@ type ReadInBufferOptions struct {
 	Data   []byte
 	Path   string
 	Size   int64
+	Ctx    context.Context // Cancels this read when done, nil if the read is never cancelled
+}
+
+// Context returns a context for the read, which is done when either parent or options.Ctx is done.
+// The returned cancel function must be called once the read completes.
+func (options *ReadInBufferOptions) Context(
+	parent context.Context,
+) (context.Context, context.CancelFunc) {
+	if options.Ctx == nil || options.Ctx.Done() == nil {
+		return parent, func() {}
+	}
+	ctx, cancel := context.WithCancel(parent)
+	stop := context.AfterFunc(options.Ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
 }
 
 type WriteFileOptions struct {
@@ -132,9 +151,14 @@ type CopyToFileOptions struct {
 }
 
 type CopyFromFileOptions struct {
-	Name     string
-	File     *os.File
+	Name string
+	File *os.File
+	// Metadata is merged into the metadata of the object being replaced, a nil value removes a key
 	Metadata map[string]*string
+	// IfMatch uploads only if the object still has this ETag, or if it does not exist when empty
+	IfMatch *string
+	// NewETag receives the ETag of the uploaded object
+	NewETag *string
 }
 
 type FlushFileOptions struct {
@@ -176,6 +200,12 @@ type ChownOptions struct {
 	Group int
 }
 
+type UtimensOptions struct {
+	Name  string
+	Atime time.Time
+	Mtime time.Time
+}
+
 type StageDataOptions struct {
 	Name   string
 	Id     string
@@ -188,6 +218,17 @@ type CommitDataOptions struct {
 	List      []string
 	BlockSize uint64
 	NewETag   *string
+	// Metadata is merged into the metadata of the object being replaced, a nil value removes a key
+	Metadata map[string]*string
+}
+
+type GetMetadataOptions struct {
+	Name string
+}
+
+type SetMetadataOptions struct {
+	Name     string
+	Metadata map[string]*string
 }
 
 type CommittedBlock struct {
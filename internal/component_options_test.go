@@ -26,6 +26,7 @@
 package internal
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -72,6 +73,44 @@ func (s *componentOptionsTestSuite) TestTruncateDirName() {
 	}
 }
 
+func (s *componentOptionsTestSuite) TestReadInBufferContext() {
+	assert := assert.New(s.T())
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+
+	// without a read context the parent is used as is
+	options := &ReadInBufferOptions{}
+	ctx, cancel := options.Context(parent)
+	assert.Equal(parent, ctx)
+	cancel()
+	assert.NoError(parent.Err())
+	options.Ctx = context.Background()
+	ctx, cancel = options.Context(parent)
+	assert.Equal(parent, ctx)
+	cancel()
+
+	// cancelling the read context cancels the read, but not the parent
+	readCtx, cancelRead := context.WithCancel(context.Background())
+	options.Ctx = readCtx
+	ctx, cancel = options.Context(parent)
+	defer cancel()
+	assert.NoError(ctx.Err())
+	cancelRead()
+	<-ctx.Done()
+	assert.ErrorIs(ctx.Err(), context.Canceled)
+	assert.NoError(parent.Err())
+
+	// cancelling the parent cancels the read
+	readCtx, cancelRead = context.WithCancel(context.Background())
+	defer cancelRead()
+	options.Ctx = readCtx
+	ctx, cancel = options.Context(parent)
+	defer cancel()
+	cancelParent()
+	<-ctx.Done()
+	assert.ErrorIs(ctx.Err(), context.Canceled)
+}
+
 func TestComponentOptionsTestSuite(t *testing.T) {
 	suite.Run(t, new(componentOptionsTestSuite))
 }
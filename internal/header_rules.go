@@ -0,0 +1,94 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package internal
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+
+	"github.com/Seagate/cloudfuse/common"
+)
+
+// HeaderRule sets the headers and metadata of the objects uploaded to paths matching Pattern.
+// Patterns are matched with common.MatchPath.
+type HeaderRule struct {
+	Pattern         string            `config:"pattern"          yaml:"pattern"`
+	ContentType     string            `config:"content-type"     yaml:"content-type,omitempty"`
+	CacheControl    string            `config:"cache-control"    yaml:"cache-control,omitempty"`
+	ContentEncoding string            `config:"content-encoding" yaml:"content-encoding,omitempty"`
+	Metadata        map[string]string `config:"metadata"         yaml:"metadata,omitempty"`
+}
+
+// ObjectHeaders holds the headers and metadata set by the rules matching an object.
+// Empty fields are left to the storage defaults.
+type ObjectHeaders struct {
+	ContentType     string
+	CacheControl    string
+	ContentEncoding string
+	Metadata        map[string]string
+}
+
+// ValidateHeaderRules returns an error for the first rule with an invalid pattern or metadata key
+func ValidateHeaderRules(rules []HeaderRule) error {
+	for _, rule := range rules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil || rule.Pattern == "" {
+			return fmt.Errorf("invalid header-rules pattern %q", rule.Pattern)
+		}
+		for key := range rule.Metadata {
+			if key == "" || IsTimeMetadataKey(key) || IsArchiveMetadataKey(key) {
+				return fmt.Errorf("invalid metadata key %q for pattern %q", key, rule.Pattern)
+			}
+		}
+	}
+	return nil
+}
+
+// MatchHeaderRules returns the headers set by the rules matching name, and whether any rule
+// matched. When several rules match, each header and metadata key comes from the first rule
+// that sets it.
+func MatchHeaderRules(rules []HeaderRule, name string) (ObjectHeaders, bool) {
+	var headers ObjectHeaders
+	matched := false
+	for _, rule := range rules {
+		if !common.MatchPath(rule.Pattern, name) {
+			continue
+		}
+		matched = true
+		headers.ContentType = cmp.Or(headers.ContentType, rule.ContentType)
+		headers.CacheControl = cmp.Or(headers.CacheControl, rule.CacheControl)
+		headers.ContentEncoding = cmp.Or(headers.ContentEncoding, rule.ContentEncoding)
+		for key, value := range rule.Metadata {
+			if headers.Metadata == nil {
+				headers.Metadata = make(map[string]string)
+			}
+			if _, found := headers.Metadata[key]; !found {
+				headers.Metadata[key] = value
+			}
+		}
+	}
+	return headers, matched
+}
@@ -0,0 +1,85 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type headerRulesTestSuite struct {
+	suite.Suite
+}
+
+func (s *headerRulesTestSuite) TestMatchHeaderRules() {
+	assert := assert.New(s.T())
+	rules := []HeaderRule{
+		{Pattern: "*.html", ContentType: "text/html; charset=utf-8", CacheControl: "no-cache"},
+		{
+			Pattern:      "site/",
+			CacheControl: "max-age=3600",
+			Metadata:     map[string]string{"team": "web"},
+		},
+		{Pattern: "*.gz", ContentEncoding: "gzip", Metadata: map[string]string{"team": "ops"}},
+	}
+
+	headers, matched := MatchHeaderRules(rules, "site/index.html")
+	assert.True(matched)
+	assert.Equal(ObjectHeaders{
+		ContentType:  "text/html; charset=utf-8",
+		CacheControl: "no-cache",
+		Metadata:     map[string]string{"team": "web"},
+	}, headers)
+
+	headers, matched = MatchHeaderRules(rules, "site/app.js.gz")
+	assert.True(matched)
+	assert.Equal(ObjectHeaders{
+		CacheControl:    "max-age=3600",
+		ContentEncoding: "gzip",
+		Metadata:        map[string]string{"team": "web"},
+	}, headers)
+
+	headers, matched = MatchHeaderRules(rules, "data/a.csv")
+	assert.False(matched)
+	assert.Equal(ObjectHeaders{}, headers)
+}
+
+func (s *headerRulesTestSuite) TestValidateHeaderRules() {
+	assert := assert.New(s.T())
+	assert.NoError(ValidateHeaderRules(nil))
+	assert.NoError(ValidateHeaderRules([]HeaderRule{{Pattern: "*.css", ContentType: "text/css"}}))
+	assert.Error(ValidateHeaderRules([]HeaderRule{{Pattern: "[a", ContentType: "text/css"}}))
+	assert.Error(ValidateHeaderRules([]HeaderRule{{ContentType: "text/css"}}))
+	assert.Error(ValidateHeaderRules([]HeaderRule{
+		{Pattern: "*", Metadata: map[string]string{"mtime": "0"}},
+	}))
+}
+
+func TestHeaderRulesTestSuite(t *testing.T) {
+	suite.Run(t, new(headerRulesTestSuite))
+}
@@ -650,3 +650,46 @@ func (mr *MockComponentMockRecorder) CommitData(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CommitData", reflect.TypeOf((*MockComponent)(nil).TruncateFile), arg0)
 }
+
+// GetMetadata mocks base method.
+func (m *MockComponent) GetMetadata(arg0 GetMetadataOptions) (map[string]*string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMetadata", arg0)
+	ret0, _ := ret[0].(map[string]*string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMetadata indicates an expected call to GetMetadata.
+func (mr *MockComponentMockRecorder) GetMetadata(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMetadata", reflect.TypeOf((*MockComponent)(nil).GetMetadata), arg0)
+}
+
+// SetMetadata mocks base method.
+func (m *MockComponent) SetMetadata(arg0 SetMetadataOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetMetadata", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetMetadata indicates an expected call to SetMetadata.
+func (mr *MockComponentMockRecorder) SetMetadata(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetMetadata", reflect.TypeOf((*MockComponent)(nil).SetMetadata), arg0)
+}
+
+// Utimens mocks base method.
+func (m *MockComponent) Utimens(arg0 UtimensOptions) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Utimens", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Utimens indicates an expected call of Utimens.
+func (mr *MockComponentMockRecorder) Utimens(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Utimens", reflect.TypeOf((*MockComponent)(nil).Utimens), arg0)
+}
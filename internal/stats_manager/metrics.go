@@ -0,0 +1,393 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package stats_manager
+
+import (
+	"bufio"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/Seagate/cloudfuse/common/log"
+)
+
+// The metrics listener serves the stats of every component in the Prometheus text format.
+// A stat is exported as the type its component declared it with, and as a gauge when it was not
+// declared. Stats that are not numbers are exported as an info metric with the value as a label.
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	// Metric types of stats
+	Counter = "counter" // only ever incremented
+	Gauge   = "gauge"
+)
+
+// latencyBuckets are the upper bounds of the request latency histograms, in seconds
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// metrics holds the metrics of every component, nil when the metrics listener is not enabled
+var metrics atomic.Pointer[metricsRegistry]
+
+type metricsRegistry struct {
+	sync.Mutex
+	components map[string]*componentMetrics
+}
+
+type componentMetrics struct {
+	stats     map[string]*statValue
+	gauges    map[string]func() float64
+	latencies map[string]*histogram // by operation
+}
+
+type statValue struct {
+	value   any
+	counter bool
+}
+
+type histogram struct {
+	counts []uint64 // number of observations in each bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// EnableMetrics starts collecting the stats of every component for the metrics listener.
+// It must be called before the components start.
+func EnableMetrics() {
+	metrics.CompareAndSwap(nil, &metricsRegistry{components: make(map[string]*componentMetrics)})
+}
+
+// ParseMetricsAddress returns the network and address the metrics listener listens on.
+// The address is either a host:port on the loopback interface, or unix:<path> for a unix socket.
+func ParseMetricsAddress(address string) (string, string, error) {
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		if path == "" {
+			return "", "", fmt.Errorf("socket path not given in metrics address %s", address)
+		}
+		return "unix", path, nil
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid metrics address %s [%s]", address, err.Error())
+	}
+	if _, err = strconv.ParseUint(port, 10, 16); err != nil {
+		return "", "", fmt.Errorf("invalid port in metrics address %s", address)
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", "", fmt.Errorf("metrics address %s is not on localhost", address)
+	}
+	return "tcp", address, nil
+}
+
+// ListenMetrics enables metrics and serves them at /metrics on address.
+// Close the returned server to stop listening.
+func ListenMetrics(address string) (*http.Server, error) {
+	network, addr, err := ParseMetricsAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	if network == "unix" {
+		// remove the socket left behind by a mount that did not exit cleanly
+		if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(addr)
+		}
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s [%s]", address, err.Error())
+	}
+
+	EnableMetrics()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", MetricsHandler())
+	server := &http.Server{
+		Handler:      mux,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			log.Err("stats_manager::ListenMetrics : Failed to serve metrics [%v]", err)
+		}
+	}()
+	return server, nil
+}
+
+// MetricsHandler writes the metrics of every component
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		m := metrics.Load()
+		if m == nil {
+			http.Error(w, "metrics are not enabled", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", metricsContentType)
+		bw := bufio.NewWriter(w)
+		m.write(bw)
+		_ = bw.Flush()
+	})
+}
+
+// AddGauge exports the value returned by fn as a gauge of the component, read on every scrape
+func (sc *StatsCollector) AddGauge(name string, fn func() float64) {
+	if m := metrics.Load(); m != nil {
+		m.Lock()
+		defer m.Unlock()
+		m.component(sc.compName).gauges[name] = fn
+	}
+}
+
+// AddStats declares the metric type of the stats of the component, before they are updated
+func (sc *StatsCollector) AddStats(kind string, keys ...string) {
+	if m := metrics.Load(); m != nil {
+		m.Lock()
+		defer m.Unlock()
+		c := m.component(sc.compName)
+		for _, key := range keys {
+			c.stat(key).counter = kind == Counter
+		}
+	}
+}
+
+// ObserveLatency records how long a request for operation op took in the latency histogram
+func (sc *StatsCollector) ObserveLatency(op string, d time.Duration) {
+	m := metrics.Load()
+	if m == nil {
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+
+	latencies := m.component(sc.compName).latencies
+	h, ok := latencies[op]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+		latencies[op] = h
+	}
+	seconds := d.Seconds()
+	i, _ := slices.BinarySearch(latencyBuckets, seconds)
+	h.counts[i]++
+	h.count++
+	h.sum += seconds
+}
+
+// component returns the metrics of the named component, the registry must be locked
+func (m *metricsRegistry) component(name string) *componentMetrics {
+	c, ok := m.components[name]
+	if !ok {
+		c = &componentMetrics{
+			stats:     make(map[string]*statValue),
+			gauges:    make(map[string]func() float64),
+			latencies: make(map[string]*histogram),
+		}
+		m.components[name] = c
+	}
+	return c
+}
+
+// stat returns the named stat of the component, the registry must be locked
+func (c *componentMetrics) stat(key string) *statValue {
+	stat, ok := c.stats[key]
+	if !ok {
+		stat = &statValue{value: (int64)(0)}
+		c.stats[key] = stat
+	}
+	return stat
+}
+
+// update applies a stats operation of the component
+func (m *metricsRegistry) update(compName string, op string, key string, val any) {
+	m.Lock()
+	defer m.Unlock()
+
+	stat := m.component(compName).stat(key)
+	current, _ := stat.value.(int64)
+	delta, _ := val.(int64)
+
+	switch op {
+	case Increment:
+		stat.value = current + delta
+	case Decrement:
+		stat.value = current - delta
+	case Replace:
+		stat.value = val
+	}
+}
+
+// removeGauges drops the gauges of a stopped component, as they read its state
+func (m *metricsRegistry) removeGauges(compName string) {
+	m.Lock()
+	defer m.Unlock()
+	if c, ok := m.components[compName]; ok {
+		clear(c.gauges)
+	}
+}
+
+// write writes every metric in the Prometheus text format
+func (m *metricsRegistry) write(w *bufio.Writer) {
+	// read the gauges without holding the registry, as they take the locks of their components
+	m.Lock()
+	gauges := make(map[string]map[string]func() float64, len(m.components))
+	for compName, c := range m.components {
+		gauges[compName] = maps.Clone(c.gauges)
+	}
+	m.Unlock()
+	gaugeValues := make(map[string]map[string]float64, len(gauges))
+	for compName, fns := range gauges {
+		gaugeValues[compName] = make(map[string]float64, len(fns))
+		for name, fn := range fns {
+			gaugeValues[compName][name] = fn()
+		}
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	for _, compName := range slices.Sorted(maps.Keys(m.components)) {
+		c := m.components[compName]
+
+		for _, key := range slices.Sorted(maps.Keys(c.stats)) {
+			stat := c.stats[key]
+			name := metricName(compName, key)
+			value, ok := numericValue(stat.value)
+			switch {
+			case !ok:
+				label := escapeLabel(fmt.Sprint(stat.value))
+				writeHeader(w, name+"_info", compName, key, Gauge)
+				fmt.Fprintf(w, "%s_info{value=\"%s\"} 1\n", name, label)
+			case stat.counter:
+				writeHeader(w, name+"_total", compName, key, Counter)
+				fmt.Fprintf(w, "%s_total %s\n", name, formatFloat(value))
+			default:
+				writeHeader(w, name, compName, key, Gauge)
+				fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+			}
+		}
+
+		values := gaugeValues[compName]
+		for _, key := range slices.Sorted(maps.Keys(values)) {
+			name := metricName(compName, key)
+			writeHeader(w, name, compName, key, Gauge)
+			fmt.Fprintf(w, "%s %s\n", name, formatFloat(values[key]))
+		}
+
+		if len(c.latencies) == 0 {
+			continue
+		}
+		name := metricName(compName, "request_duration_seconds")
+		writeHeader(w, name, compName, "request latency", "histogram")
+		for _, op := range slices.Sorted(maps.Keys(c.latencies)) {
+			h := c.latencies[op]
+			label := escapeLabel(op)
+			var cumulative uint64
+			for i, bound := range latencyBuckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(w, "%s_bucket{operation=\"%s\",le=\"%s\"} %d\n",
+					name, label, formatFloat(bound), cumulative)
+			}
+			fmt.Fprintf(w, "%s_bucket{operation=\"%s\",le=\"+Inf\"} %d\n", name, label, h.count)
+			fmt.Fprintf(w, "%s_sum{operation=\"%s\"} %s\n", name, label, formatFloat(h.sum))
+			fmt.Fprintf(w, "%s_count{operation=\"%s\"} %d\n", name, label, h.count)
+		}
+	}
+}
+
+func writeHeader(w *bufio.Writer, name string, compName string, key string, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s of %s\n", name, strings.ReplaceAll(key, "\n", " "), compName)
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// metricName returns the name of a metric of the component, with the key in snake case,
+// e.g. "OpenFileHandles" of s3storage is cloudfuse_s3storage_open_file_handles
+func metricName(compName string, key string) string {
+	return "cloudfuse_" + snakeCase(compName) + "_" + snakeCase(key)
+}
+
+// snakeCase returns s in lower case with its words separated by underscores
+func snakeCase(s string) string {
+	var sb strings.Builder
+	var prev rune
+	newWord := false
+	for _, r := range s {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			newWord = true
+		} else {
+			if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+				newWord = true
+			}
+			if newWord && sb.Len() > 0 {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			newWord = false
+		}
+		prev = r
+	}
+	return sb.String()
+}
+
+// numericValue returns the value of a stat as a number.
+// Stats given as text with a unit, e.g. "12.5 MB" or "40%", are exported as their number.
+func numericValue(val any) (float64, bool) {
+	switch v := val.(type) {
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		fields := strings.Fields(v)
+		if len(fields) == 0 {
+			return 0, false
+		}
+		f, err := strconv.ParseFloat(strings.TrimSuffix(fields[0], "%"), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
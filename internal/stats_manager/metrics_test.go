@@ -0,0 +1,225 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package stats_manager
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Seagate/cloudfuse/common"
+	"github.com/Seagate/cloudfuse/common/log"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type metricsTestSuite struct {
+	suite.Suite
+	assert *assert.Assertions
+}
+
+func (suite *metricsTestSuite) SetupTest() {
+	suite.assert = assert.New(suite.T())
+	err := log.SetDefaultLogger("silent", common.LogConfig{Level: common.ELogLevel.LOG_DEBUG()})
+	suite.assert.NoError(err)
+	metrics.Store(nil)
+}
+
+// scrape returns the metrics served by the handler
+func (suite *metricsTestSuite) scrape() string {
+	recorder := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	suite.assert.Equal(http.StatusOK, recorder.Code)
+	suite.assert.Equal(metricsContentType, recorder.Header().Get("Content-Type"))
+	return recorder.Body.String()
+}
+
+func (suite *metricsTestSuite) TestParseMetricsAddress() {
+	tests := []struct {
+		address string
+		network string
+		addr    string
+		valid   bool
+	}{
+		{address: "localhost:9464", network: "tcp", addr: "localhost:9464", valid: true},
+		{address: "127.0.0.1:9464", network: "tcp", addr: "127.0.0.1:9464", valid: true},
+		{address: "[::1]:9464", network: "tcp", addr: "[::1]:9464", valid: true},
+		{address: "unix:/run/cf.sock", network: "unix", addr: "/run/cf.sock", valid: true},
+		{address: "unix:", valid: false},
+		{address: ":9464", valid: false},
+		{address: "0.0.0.0:9464", valid: false},
+		{address: "example.com:9464", valid: false},
+		{address: "localhost", valid: false},
+		{address: "localhost:http", valid: false},
+		{address: "localhost:70000", valid: false},
+	}
+	for _, tt := range tests {
+		suite.Run(tt.address, func() {
+			network, addr, err := ParseMetricsAddress(tt.address)
+			if !tt.valid {
+				suite.assert.Error(err)
+				return
+			}
+			suite.assert.NoError(err)
+			suite.assert.Equal(tt.network, network)
+			suite.assert.Equal(tt.addr, addr)
+		})
+	}
+}
+
+func (suite *metricsTestSuite) TestMetricName() {
+	suite.assert.Equal(
+		"cloudfuse_s3storage_open_file_handles",
+		metricName("s3storage", "OpenFileHandles"),
+	)
+	suite.assert.Equal(
+		"cloudfuse_file_cache_files_served_from_cache",
+		metricName("file_cache", "Files served from cache"),
+	)
+	suite.assert.Equal(
+		"cloudfuse_block_cache_pool_usage_percent",
+		metricName("block_cache", "Pool Usage Percent"),
+	)
+	suite.assert.Equal("cloudfuse_s3storage_etag", metricName("s3storage", "ETag"))
+}
+
+func (suite *metricsTestSuite) TestNumericValue() {
+	for val, expected := range map[any]float64{
+		(int64)(3):     3,
+		2.5:            2.5,
+		"12.500000 MB": 12.5,
+		"40.000000%":   40,
+	} {
+		value, ok := numericValue(val)
+		suite.assert.True(ok)
+		suite.assert.Equal(expected, value)
+	}
+	_, ok := numericValue("https://s3.example.com")
+	suite.assert.False(ok)
+	_, ok = numericValue("")
+	suite.assert.False(ok)
+}
+
+func (suite *metricsTestSuite) TestMetricsDisabled() {
+	sc := NewStatsCollector("test_comp")
+	sc.AddStats(Counter, "Count")
+	sc.UpdateStats(Increment, "Count", (int64)(1))
+	sc.AddGauge("Gauge", func() float64 { return 1 })
+	sc.ObserveLatency("GetObject", time.Second)
+
+	recorder := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	suite.assert.Equal(http.StatusNotFound, recorder.Code)
+}
+
+func (suite *metricsTestSuite) TestMetrics() {
+	EnableMetrics()
+	sc := NewStatsCollector("test_comp")
+
+	// stats are exported as the type they are declared with, and undeclared stats as gauges
+	sc.AddStats(Counter, "Bytes Downloaded", "Bytes Uploaded")
+	sc.AddStats(Gauge, "OpenFileHandles")
+	sc.UpdateStats(Increment, "Bytes Downloaded", (int64)(10))
+	sc.UpdateStats(Increment, "Bytes Downloaded", (int64)(5))
+	sc.UpdateStats(Replace, "OpenFileHandles", (int64)(0))
+	sc.UpdateStats(Increment, "OpenFileHandles", (int64)(2))
+	sc.UpdateStats(Decrement, "OpenFileHandles", (int64)(1))
+	sc.UpdateStats(Increment, "Queued Requests", (int64)(1))
+	sc.UpdateStats(Replace, "Usage Percent", "12.500000%")
+	sc.UpdateStats(Replace, "ActiveEndpoint", `https://s3.example.com/"a"`)
+	sc.AddGauge("Pool Usage Percent", func() float64 { return 40 })
+	sc.ObserveLatency("GetObject", 20*time.Millisecond)
+	sc.ObserveLatency("GetObject", 2*time.Second)
+	sc.ObserveLatency("GetObject", 2*time.Minute)
+
+	body := suite.scrape()
+	suite.assert.Contains(body, "# TYPE cloudfuse_test_comp_bytes_downloaded_total counter\n"+
+		"cloudfuse_test_comp_bytes_downloaded_total 15\n")
+	suite.assert.Contains(body, "cloudfuse_test_comp_bytes_uploaded_total 0\n")
+	suite.assert.Contains(body, "# TYPE cloudfuse_test_comp_open_file_handles gauge\n"+
+		"cloudfuse_test_comp_open_file_handles 1\n")
+	suite.assert.Contains(body, "# TYPE cloudfuse_test_comp_queued_requests gauge\n"+
+		"cloudfuse_test_comp_queued_requests 1\n")
+	suite.assert.Contains(body, "cloudfuse_test_comp_usage_percent 12.5\n")
+	suite.assert.Contains(body,
+		`cloudfuse_test_comp_active_endpoint_info{value="https://s3.example.com/\"a\""} 1`+"\n")
+	suite.assert.Contains(body, "# TYPE cloudfuse_test_comp_pool_usage_percent gauge\n"+
+		"cloudfuse_test_comp_pool_usage_percent 40\n")
+
+	suite.assert.Contains(body, "# TYPE cloudfuse_test_comp_request_duration_seconds histogram\n")
+	latency := "cloudfuse_test_comp_request_duration_seconds"
+	suite.assert.Contains(body, latency+`_bucket{operation="GetObject",le="0.01"} 0`+"\n")
+	suite.assert.Contains(body, latency+`_bucket{operation="GetObject",le="0.025"} 1`+"\n")
+	suite.assert.Contains(body, latency+`_bucket{operation="GetObject",le="2.5"} 2`+"\n")
+	suite.assert.Contains(body, latency+`_bucket{operation="GetObject",le="60"} 2`+"\n")
+	suite.assert.Contains(body, latency+`_bucket{operation="GetObject",le="+Inf"} 3`+"\n")
+	suite.assert.Contains(body, latency+`_sum{operation="GetObject"} 122.02`+"\n")
+	suite.assert.Contains(body, latency+`_count{operation="GetObject"} 3`+"\n")
+
+	// the gauges of a stopped component are dropped, as they read its state
+	sc.Destroy()
+	body = suite.scrape()
+	suite.assert.NotContains(body, "pool_usage_percent")
+	suite.assert.Contains(body, "cloudfuse_test_comp_bytes_downloaded_total 15\n")
+}
+
+func (suite *metricsTestSuite) TestListenMetrics() {
+	_, err := ListenMetrics("0.0.0.0:9464")
+	suite.assert.Error(err)
+	suite.assert.Nil(metrics.Load())
+
+	socket := filepath.Join(suite.T().TempDir(), "metrics.sock")
+	server, err := ListenMetrics("unix:" + socket)
+	suite.assert.NoError(err)
+	defer server.Close()
+	suite.assert.NotNil(metrics.Load())
+	sc := NewStatsCollector("test_comp")
+	sc.AddStats(Counter, "Count")
+	sc.UpdateStats(Increment, "Count", (int64)(1))
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	resp, err := client.Get("http://cloudfuse/metrics")
+	suite.assert.NoError(err)
+	defer resp.Body.Close()
+	suite.assert.Equal(http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	suite.assert.NoError(err)
+	suite.assert.Contains(string(body), "cloudfuse_test_comp_count_total 1\n")
+}
+
+func TestMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(metricsTestSuite))
+}
@@ -45,6 +45,7 @@ type StatsCollector struct {
 	channel    chan ChannelMsg
 	workerDone sync.WaitGroup
 	compIdx    int
+	compName   string
 }
 
 type PipeMsg struct {
@@ -88,7 +89,7 @@ type statsManagerOpt struct {
 var stMgrOpt statsManagerOpt
 
 func NewStatsCollector(componentName string) *StatsCollector {
-	sc := &StatsCollector{}
+	sc := &StatsCollector{compName: componentName}
 
 	if common.MonitorCfs() {
 		sc.channel = make(chan ChannelMsg, 10000)
@@ -128,6 +129,9 @@ func (sc *StatsCollector) Init() {
 }
 
 func (sc *StatsCollector) Destroy() {
+	if m := metrics.Load(); m != nil {
+		m.removeGauges(sc.compName)
+	}
 	if common.MonitorCfs() {
 		close(sc.channel)
 		sc.workerDone.Wait()
@@ -161,6 +165,9 @@ func (sc *StatsCollector) PushEvents(op string, path string, mp map[string]any)
 }
 
 func (sc *StatsCollector) UpdateStats(op string, key string, val any) {
+	if m := metrics.Load(); m != nil {
+		m.update(sc.compName, op, key, val)
+	}
 	if common.MonitorCfs() {
 		st := Stats{
 			Timestamp: time.Now().Format(time.RFC3339),
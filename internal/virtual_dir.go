@@ -0,0 +1,52 @@
+/*
+   Licensed under the MIT License <http://opensource.org/licenses/MIT>.
+
+   Copyright © 2023-2026 Seagate Technology LLC and/or its Affiliates
+   Copyright © 2020-2026 Microsoft Corporation. All rights reserved.
+
+   Permission is hereby granted, free of charge, to any person obtaining a copy
+   of this software and associated documentation files (the "Software"), to deal
+   in the Software without restriction, including without limitation the rights
+   to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+   copies of the Software, and to permit persons to whom the Software is
+   furnished to do so, subject to the following conditions:
+
+   The above copyright notice and this permission notice shall be included in all
+   copies or substantial portions of the Software.
+
+   THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+   IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+   FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+   AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+   LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+   OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+   SOFTWARE
+*/
+
+package internal
+
+import (
+	"strings"
+	"sync"
+)
+
+// A storage component can serve virtual directories, like the .versions tree of s3storage.
+// These are found by name, but are not part of their parent's listing, so components that cache
+// listings must not infer that a path inside a virtual directory does not exist.
+
+var virtualDirNames sync.Map
+
+// RegisterVirtualDir registers name as the name of a virtual directory
+func RegisterVirtualDir(name string) {
+	virtualDirNames.Store(name, struct{}{})
+}
+
+// IsVirtualPath reports whether path is, or is inside, a virtual directory
+func IsVirtualPath(path string) bool {
+	for elem := range strings.SplitSeq(path, "/") {
+		if _, found := virtualDirNames.Load(elem); found {
+			return true
+		}
+	}
+	return false
+}